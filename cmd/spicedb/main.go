@@ -56,6 +56,13 @@ func main() {
 	cmd.RegisterDatastoreRootFlags(datastoreCmd)
 	rootCmd.AddCommand(datastoreCmd)
 
+	// Add schema commands
+	schemaCmd, err := cmd.NewSchemaCommand(rootCmd.Use)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to register schema command")
+	}
+	rootCmd.AddCommand(schemaCmd)
+
 	// Add head command.
 	headCmd := cmd.NewHeadCommand(rootCmd.Use)
 	cmd.RegisterHeadFlags(headCmd)
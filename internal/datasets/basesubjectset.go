@@ -1,6 +1,9 @@
 package datasets
 
 import (
+	"sort"
+
+	"github.com/RoaringBitmap/roaring"
 	"golang.org/x/exp/maps"
 
 	"github.com/authzed/spicedb/internal/caveats"
@@ -42,9 +45,17 @@ type Subject[T any] interface {
 type BaseSubjectSet[T Subject[T]] struct {
 	constructor constructor[T]
 	concrete    map[string]T
+	determined  *determinedSubjects
 	wildcard    *handle[T]
 }
 
+// determinedSubjects holds the IDs of those concrete subjects of a compact set which have no caveat
+// expression, as a bitmap over the IDs assigned to them by the interner.
+type determinedSubjects struct {
+	interner *Interner
+	ids      *roaring.Bitmap
+}
+
 // NewBaseSubjectSet creates a new base subject set for use underneath well-typed implementation.
 //
 // The constructor function returns a new instance of type T for a particular subject ID.
@@ -56,6 +67,22 @@ func NewBaseSubjectSet[T Subject[T]](constructor constructor[T]) BaseSubjectSet[
 	}
 }
 
+// NewCompactBaseSubjectSet creates a new base subject set which holds its concrete subjects without
+// a caveat expression as a bitmap over IDs assigned by the interner, rather than as instances of T.
+// Such subjects are recreated by the constructor when read, so the set must only be used for types
+// T which carry nothing beyond the subject ID for concrete subjects without a caveat expression.
+//
+// Sets which are combined with one another should share an interner, as otherwise the IDs of one
+// set must be remapped to those of the other.
+func NewCompactBaseSubjectSet[T Subject[T]](constructor constructor[T], interner *Interner) BaseSubjectSet[T] {
+	bss := NewBaseSubjectSet(constructor)
+	bss.determined = &determinedSubjects{
+		interner: interner,
+		ids:      roaring.New(),
+	}
+	return bss
+}
+
 // constructor defines a function for constructing a new instance of the Subject type T for
 // a subject ID, its (optional) conditional expression, any excluded subjects, and any sources
 // for bookkeeping. The sources are those other subjects that were combined to create the current
@@ -85,15 +112,18 @@ func (bss BaseSubjectSet[T]) Add(foundSubject T) error {
 
 		bss.wildcard.setOrNil(updated)
 
-		for _, concrete := range bss.concrete {
-			updated = unionWildcardWithConcrete(updated, concrete, bss.constructor)
+		// Only those concrete subjects excluded from the wildcard can change it.
+		for _, excludedSubject := range (*updated).GetExcludedSubjects() {
+			if concrete, ok := bss.getConcrete(excludedSubject.GetSubjectId()); ok {
+				updated = unionWildcardWithConcrete(updated, concrete, bss.constructor)
+			}
 		}
 		bss.wildcard.setOrNil(updated)
 		return nil
 	}
 
 	var updatedOrNil *T
-	if updated, ok := bss.concrete[foundSubject.GetSubjectId()]; ok {
+	if updated, ok := bss.getConcrete(foundSubject.GetSubjectId()); ok {
 		updatedOrNil = &updated
 	}
 	bss.setConcrete(foundSubject.GetSubjectId(), unionConcreteWithConcrete(updatedOrNil, &foundSubject, bss.constructor))
@@ -104,7 +134,34 @@ func (bss BaseSubjectSet[T]) Add(foundSubject T) error {
 	return nil
 }
 
+// getConcrete returns the concrete subject with the given ID, if any.
+func (bss BaseSubjectSet[T]) getConcrete(subjectID string) (T, bool) {
+	if found, ok := bss.concrete[subjectID]; ok {
+		return found, true
+	}
+
+	if bss.determined != nil {
+		if id, ok := bss.determined.interner.Lookup(subjectID); ok && bss.determined.ids.Contains(id) {
+			return bss.constructor(subjectID, nil, nil), true
+		}
+	}
+
+	return *new(T), false
+}
+
 func (bss BaseSubjectSet[T]) setConcrete(subjectID string, subjectOrNil *T) {
+	if bss.determined != nil {
+		if subjectOrNil != nil && (*subjectOrNil).GetCaveatExpression() == nil {
+			delete(bss.concrete, subjectID)
+			bss.determined.ids.Add(bss.determined.interner.Intern(subjectID))
+			return
+		}
+
+		if id, ok := bss.determined.interner.Lookup(subjectID); ok {
+			bss.determined.ids.Remove(id)
+		}
+	}
+
 	if subjectOrNil == nil {
 		delete(bss.concrete, subjectID)
 		return
@@ -114,10 +171,52 @@ func (bss BaseSubjectSet[T]) setConcrete(subjectID string, subjectOrNil *T) {
 	bss.concrete[subject.GetSubjectId()] = subject
 }
 
+// concretes returns all concrete subjects in the set.
+func (bss BaseSubjectSet[T]) concretes() []T {
+	values := maps.Values(bss.concrete)
+	if bss.determined != nil {
+		for _, subjectID := range bss.determined.interner.Values(bss.determined.ids) {
+			values = append(values, bss.constructor(subjectID, nil, nil))
+		}
+	}
+	return values
+}
+
+// concreteCount returns the number of concrete subjects in the set.
+func (bss BaseSubjectSet[T]) concreteCount() int {
+	count := len(bss.concrete)
+	if bss.determined != nil {
+		count += int(bss.determined.ids.GetCardinality())
+	}
+	return count
+}
+
+// clearConcretes removes all concrete subjects from the set.
+func (bss BaseSubjectSet[T]) clearConcretes() {
+	maps.Clear(bss.concrete)
+	if bss.determined != nil {
+		bss.determined.ids.Clear()
+	}
+}
+
+// determinedIDsIn returns the bitmap of concrete subjects without a caveat expression in this
+// compact set, over the IDs assigned by the given interner.
+func (bss BaseSubjectSet[T]) determinedIDsIn(interner *Interner) *roaring.Bitmap {
+	return interner.remap(bss.determined.ids, bss.determined.interner)
+}
+
+// canCombineCompactly returns whether this set can be combined with the other by operating on the
+// bitmaps of their concrete subjects without a caveat expression, which requires that both sets are
+// compact and that neither has a wildcard.
+func (bss BaseSubjectSet[T]) canCombineCompactly(other BaseSubjectSet[T]) bool {
+	return bss.determined != nil && other.determined != nil &&
+		bss.wildcard.getOrNil() == nil && other.wildcard.getOrNil() == nil
+}
+
 // Subtract subtracts the given subject found the set.
 func (bss BaseSubjectSet[T]) Subtract(toRemove T) {
 	if toRemove.GetSubjectId() == tuple.PublicWildcard {
-		for _, concrete := range bss.concrete {
+		for _, concrete := range bss.concretes() {
 			bss.setConcrete(concrete.GetSubjectId(), subtractWildcardFromConcrete(concrete, toRemove, bss.constructor))
 		}
 
@@ -131,7 +230,7 @@ func (bss BaseSubjectSet[T]) Subtract(toRemove T) {
 		return
 	}
 
-	if existing, ok := bss.concrete[toRemove.GetSubjectId()]; ok {
+	if existing, ok := bss.getConcrete(toRemove.GetSubjectId()); ok {
 		bss.setConcrete(toRemove.GetSubjectId(), subtractConcreteFromConcrete(existing, toRemove, bss.constructor))
 	}
 
@@ -144,7 +243,23 @@ func (bss BaseSubjectSet[T]) Subtract(toRemove T) {
 // SubtractAll subtracts the other set of subjects from this set of subtracts, modifying this
 // set *in place*.
 func (bss BaseSubjectSet[T]) SubtractAll(other BaseSubjectSet[T]) {
-	for _, otherSubject := range other.AsSlice() {
+	if !bss.canCombineCompactly(other) {
+		for _, otherSubject := range other.AsSlice() {
+			bss.Subtract(otherSubject)
+		}
+		return
+	}
+
+	// Concrete subjects without a caveat expression remove those in this set entirely.
+	otherIDs := other.determinedIDsIn(bss.determined.interner)
+	bss.determined.ids.AndNot(otherIDs)
+	for subjectID := range bss.concrete {
+		if id, ok := bss.determined.interner.Lookup(subjectID); ok && otherIDs.Contains(id) {
+			delete(bss.concrete, subjectID)
+		}
+	}
+
+	for _, otherSubject := range other.concrete {
 		bss.Subtract(otherSubject)
 	}
 }
@@ -161,6 +276,11 @@ func (bss BaseSubjectSet[T]) MustIntersectionDifference(other BaseSubjectSet[T])
 // IntersectionDifference performs an intersection between this set and the other set, modifying
 // this set *in place*.
 func (bss BaseSubjectSet[T]) IntersectionDifference(other BaseSubjectSet[T]) error {
+	if bss.canCombineCompactly(other) {
+		bss.intersectCompactly(other)
+		return nil
+	}
+
 	// Intersect the wildcards of the sets, if any.
 	existingWildcard := bss.wildcard.getOrNil()
 	otherWildcard := other.wildcard.getOrNil()
@@ -173,11 +293,11 @@ func (bss BaseSubjectSet[T]) IntersectionDifference(other BaseSubjectSet[T]) err
 	bss.wildcard.setOrNil(intersection)
 
 	// Intersect the concretes of each set, as well as with the wildcards.
-	updatedConcretes := make(map[string]T, len(bss.concrete))
+	updatedConcretes := make(map[string]T, bss.concreteCount())
 
-	for _, concreteSubject := range bss.concrete {
+	for _, concreteSubject := range bss.concretes() {
 		var otherConcreteOrNil *T
-		if otherConcrete, ok := other.concrete[concreteSubject.GetSubjectId()]; ok {
+		if otherConcrete, ok := other.getConcrete(concreteSubject.GetSubjectId()); ok {
 			otherConcreteOrNil = &otherConcrete
 		}
 
@@ -194,7 +314,7 @@ func (bss BaseSubjectSet[T]) IntersectionDifference(other BaseSubjectSet[T]) err
 	}
 
 	if existingWildcard != nil {
-		for _, otherSubject := range other.concrete {
+		for _, otherSubject := range other.concretes() {
 			existingWildcardIntersect, err := intersectConcreteWithWildcard(otherSubject, existingWildcard, bss.constructor)
 			if err != nil {
 				return err
@@ -209,9 +329,37 @@ func (bss BaseSubjectSet[T]) IntersectionDifference(other BaseSubjectSet[T]) err
 		}
 	}
 
+	bss.clearConcretes()
+	for subjectID, concrete := range updatedConcretes {
+		concrete := concrete
+		bss.setConcrete(subjectID, &concrete)
+	}
+	return nil
+}
+
+// intersectCompactly performs an intersection between this set and the other set, neither of which
+// has a wildcard, operating on the bitmaps of their concrete subjects without a caveat expression.
+func (bss BaseSubjectSet[T]) intersectCompactly(other BaseSubjectSet[T]) {
+	updatedConcretes := make(map[string]T)
+
+	// Concrete subjects of this set with a caveat expression are kept if found in the other set.
+	for subjectID, concrete := range bss.concrete {
+		if otherConcrete, ok := other.getConcrete(subjectID); ok {
+			updatedConcretes[subjectID] = *intersectConcreteWithConcrete(concrete, &otherConcrete, bss.constructor)
+		}
+	}
+
+	// Concrete subjects of this set without a caveat expression take on the caveat expression of
+	// those found with one in the other set.
+	for subjectID, otherConcrete := range other.concrete {
+		if concrete, ok := bss.getConcrete(subjectID); ok && concrete.GetCaveatExpression() == nil {
+			updatedConcretes[subjectID] = *intersectConcreteWithConcrete(concrete, &otherConcrete, bss.constructor)
+		}
+	}
+
+	bss.determined.ids.And(other.determinedIDsIn(bss.determined.interner))
 	maps.Clear(bss.concrete)
 	maps.Copy(bss.concrete, updatedConcretes)
-	return nil
 }
 
 // UnionWith adds the given subjects to this set, via a union call.
@@ -228,7 +376,38 @@ func (bss BaseSubjectSet[T]) UnionWith(foundSubjects []T) error {
 // UnionWithSet performs a union operation between this set and the other set, modifying this
 // set *in place*.
 func (bss BaseSubjectSet[T]) UnionWithSet(other BaseSubjectSet[T]) error {
-	return bss.UnionWith(other.AsSlice())
+	if bss.determined == nil || other.determined == nil || other.wildcard.getOrNil() != nil {
+		return bss.UnionWith(other.AsSlice())
+	}
+
+	// Concrete subjects without a caveat expression replace any found in this set with one.
+	otherIDs := other.determinedIDsIn(bss.determined.interner)
+	bss.determined.ids.Or(otherIDs)
+	for subjectID := range bss.concrete {
+		if id, ok := bss.determined.interner.Lookup(subjectID); ok && otherIDs.Contains(id) {
+			delete(bss.concrete, subjectID)
+		}
+	}
+
+	// Only those concrete subjects excluded from the wildcard of this set, if any, can change it.
+	if wildcard := bss.wildcard.getOrNil(); wildcard != nil {
+		for _, excludedSubject := range (*wildcard).GetExcludedSubjects() {
+			if id, ok := bss.determined.interner.Lookup(excludedSubject.GetSubjectId()); ok && otherIDs.Contains(id) {
+				wildcard = unionWildcardWithConcrete(wildcard, bss.constructor(excludedSubject.GetSubjectId(), nil, nil), bss.constructor)
+			}
+		}
+		bss.wildcard.setOrNil(wildcard)
+	}
+
+	return bss.UnionWith(maps.Values(other.concrete))
+}
+
+// unionWithDeterminedIDs adds the concrete subjects without a caveat expression with the given
+// IDs to this compact set.
+func (bss BaseSubjectSet[T]) unionWithDeterminedIDs(subjectIDs []string) error {
+	adding := NewCompactBaseSubjectSet(bss.constructor, bss.determined.interner)
+	adding.determined.ids = bss.determined.interner.Bitmap(subjectIDs)
+	return bss.UnionWithSet(adding)
 }
 
 // MustUnionWithSet performs a union operation between this set and the other set, modifying this
@@ -246,18 +425,17 @@ func (bss BaseSubjectSet[T]) Get(id string) (T, bool) {
 		return bss.wildcard.get()
 	}
 
-	found, ok := bss.concrete[id]
-	return found, ok
+	return bss.getConcrete(id)
 }
 
 // IsEmpty returns whether the subject set is empty.
 func (bss BaseSubjectSet[T]) IsEmpty() bool {
-	return bss.wildcard.getOrNil() == nil && len(bss.concrete) == 0
+	return bss.wildcard.getOrNil() == nil && bss.concreteCount() == 0
 }
 
 // AsSlice returns the contents of the subject set as a slice of found subjects.
 func (bss BaseSubjectSet[T]) AsSlice() []T {
-	values := maps.Values(bss.concrete)
+	values := bss.concretes()
 	if wildcard, ok := bss.wildcard.get(); ok {
 		values = append(values, wildcard)
 	}
@@ -270,10 +448,43 @@ func (bss BaseSubjectSet[T]) Clone() BaseSubjectSet[T] {
 	return BaseSubjectSet[T]{
 		constructor: bss.constructor,
 		concrete:    maps.Clone(bss.concrete),
+		determined:  bss.determined.clone(),
 		wildcard:    bss.wildcard.clone(),
 	}
 }
 
+// LimitedAfter returns a copy of the subject set containing only those concrete subjects whose
+// IDs sort after the given subject ID (if non-empty), limited to the first `limit` of them in
+// subject ID order (if non-zero).
+//
+// The wildcard, if any, is always retained along with all of its exclusions, as the concrete
+// subjects found by combining this set with others can depend upon the wildcard.
+func (bss BaseSubjectSet[T]) LimitedAfter(afterSubjectID string, limit uint32) BaseSubjectSet[T] {
+	subjectIDs := make([]string, 0, bss.concreteCount())
+	for _, concrete := range bss.concretes() {
+		if subjectID := concrete.GetSubjectId(); afterSubjectID == "" || subjectID > afterSubjectID {
+			subjectIDs = append(subjectIDs, subjectID)
+		}
+	}
+
+	sort.Strings(subjectIDs)
+	if limit > 0 && len(subjectIDs) > int(limit) {
+		subjectIDs = subjectIDs[:limit]
+	}
+
+	limited := BaseSubjectSet[T]{
+		constructor: bss.constructor,
+		concrete:    make(map[string]T),
+		determined:  bss.determined.empty(),
+		wildcard:    bss.wildcard.clone(),
+	}
+	for _, subjectID := range subjectIDs {
+		concrete, _ := bss.getConcrete(subjectID)
+		limited.setConcrete(subjectID, &concrete)
+	}
+	return limited
+}
+
 // UnsafeRemoveExact removes the *exact* matching subject, with no wildcard handling.
 // This should ONLY be used for testing.
 func (bss BaseSubjectSet[T]) UnsafeRemoveExact(foundSubject T) {
@@ -282,7 +493,7 @@ func (bss BaseSubjectSet[T]) UnsafeRemoveExact(foundSubject T) {
 		return
 	}
 
-	delete(bss.concrete, foundSubject.GetSubjectId())
+	bss.setConcrete(foundSubject.GetSubjectId(), nil)
 }
 
 // WithParentCaveatExpression returns a copy of the subject set with the parent caveat expression applied
@@ -311,6 +522,14 @@ func (bss BaseSubjectSet[T]) WithParentCaveatExpression(parentCaveatExpr *core.C
 		)
 	}
 
+	// Concrete subjects without a caveat expression take on the parent caveat expression, if any.
+	if clone.determined != nil && parentCaveatExpr != nil {
+		for _, subjectID := range clone.determined.interner.Values(clone.determined.ids) {
+			clone.concrete[subjectID] = bss.constructor(subjectID, parentCaveatExpr, nil)
+		}
+		clone.determined.ids.Clear()
+	}
+
 	return clone
 }
 
@@ -846,6 +1065,28 @@ func (h *handle[T]) clone() *handle[T] {
 	}
 }
 
+func (ds *determinedSubjects) clone() *determinedSubjects {
+	if ds == nil {
+		return nil
+	}
+
+	return &determinedSubjects{
+		interner: ds.interner,
+		ids:      ds.ids.Clone(),
+	}
+}
+
+func (ds *determinedSubjects) empty() *determinedSubjects {
+	if ds == nil {
+		return nil
+	}
+
+	return &determinedSubjects{
+		interner: ds.interner,
+		ids:      roaring.New(),
+	}
+}
+
 // exclusionsMapFor creates a map of all the exclusions on a wildcard, by subject ID.
 func exclusionsMapFor[T Subject[T]](wildcard T) map[string]T {
 	exclusions := make(map[string]T, len(wildcard.GetExcludedSubjects()))
@@ -0,0 +1,104 @@
+package datasets
+
+import (
+	"sync"
+
+	"github.com/RoaringBitmap/roaring"
+)
+
+// Interner assigns a dense integer ID to each distinct string given to it, allowing sets of strings
+// to be held as bitmaps over those IDs. IDs are never reclaimed, so an interner should be shared
+// only by sets which are combined with one another and discarded together.
+//
+// Interner is safe for concurrent use.
+type Interner struct {
+	lock   sync.RWMutex
+	ids    map[string]uint32
+	values []string
+}
+
+// NewInterner creates and returns a new, empty interner.
+func NewInterner() *Interner {
+	return &Interner{
+		ids: map[string]uint32{},
+	}
+}
+
+// Intern returns the ID for the given value, assigning one if the value has not been interned.
+func (in *Interner) Intern(value string) uint32 {
+	in.lock.RLock()
+	id, ok := in.ids[value]
+	in.lock.RUnlock()
+	if ok {
+		return id
+	}
+
+	in.lock.Lock()
+	defer in.lock.Unlock()
+	return in.internLocked(value)
+}
+
+func (in *Interner) internLocked(value string) uint32 {
+	if id, ok := in.ids[value]; ok {
+		return id
+	}
+
+	id := uint32(len(in.values))
+	in.ids[value] = id
+	in.values = append(in.values, value)
+	return id
+}
+
+// Lookup returns the ID of the given value, if it has been interned.
+func (in *Interner) Lookup(value string) (uint32, bool) {
+	in.lock.RLock()
+	defer in.lock.RUnlock()
+
+	id, ok := in.ids[value]
+	return id, ok
+}
+
+// Value returns the value interned with the given ID.
+func (in *Interner) Value(id uint32) string {
+	in.lock.RLock()
+	defer in.lock.RUnlock()
+
+	return in.values[id]
+}
+
+// Bitmap interns all of the given values, returning a bitmap of their IDs.
+func (in *Interner) Bitmap(values []string) *roaring.Bitmap {
+	ids := make([]uint32, 0, len(values))
+
+	in.lock.Lock()
+	for _, value := range values {
+		ids = append(ids, in.internLocked(value))
+	}
+	in.lock.Unlock()
+
+	return roaring.BitmapOf(ids...)
+}
+
+// Values returns the values interned with the IDs found in the given bitmap, in ID order.
+func (in *Interner) Values(bitmap *roaring.Bitmap) []string {
+	values := make([]string, 0, bitmap.GetCardinality())
+
+	in.lock.RLock()
+	defer in.lock.RUnlock()
+
+	it := bitmap.Iterator()
+	for it.HasNext() {
+		values = append(values, in.values[it.Next()])
+	}
+	return values
+}
+
+// remap returns the bitmap of IDs assigned by the other interner as a bitmap of the IDs assigned by
+// this interner to the same values.
+func (in *Interner) remap(bitmap *roaring.Bitmap, other *Interner) *roaring.Bitmap {
+	if in == other {
+		return bitmap
+	}
+
+	return in.Bitmap(other.Values(bitmap))
+}
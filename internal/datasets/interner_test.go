@@ -0,0 +1,47 @@
+package datasets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterner(t *testing.T) {
+	interner := NewInterner()
+
+	tom := interner.Intern("tom")
+	sarah := interner.Intern("sarah")
+	require.NotEqual(t, tom, sarah)
+	require.Equal(t, tom, interner.Intern("tom"))
+	require.Equal(t, "sarah", interner.Value(sarah))
+
+	id, ok := interner.Lookup("tom")
+	require.True(t, ok)
+	require.Equal(t, tom, id)
+
+	_, ok = interner.Lookup("fred")
+	require.False(t, ok)
+
+	bitmap := interner.Bitmap([]string{"fred", "tom", "fred"})
+	require.Equal(t, uint64(2), bitmap.GetCardinality())
+	require.True(t, bitmap.Contains(tom))
+	require.ElementsMatch(t, []string{"tom", "fred"}, interner.Values(bitmap))
+}
+
+func TestInternerRemap(t *testing.T) {
+	first := NewInterner()
+	first.Intern("tom")
+	first.Intern("sarah")
+
+	second := NewInterner()
+	bitmap := second.Bitmap([]string{"fred", "sarah"})
+
+	remapped := first.remap(bitmap, second)
+	require.ElementsMatch(t, []string{"fred", "sarah"}, first.Values(remapped))
+
+	sarah, _ := first.Lookup("sarah")
+	require.True(t, remapped.Contains(sarah))
+
+	// Bitmaps from the same interner are returned as-is.
+	require.Same(t, bitmap, second.remap(bitmap, second))
+}
@@ -1,6 +1,8 @@
 package datasets
 
 import (
+	"golang.org/x/exp/maps"
+
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
 )
@@ -15,8 +17,14 @@ type SubjectSet struct {
 
 // NewSubjectSet creates and returns a new subject set.
 func NewSubjectSet() SubjectSet {
+	return newSubjectSet(NewInterner())
+}
+
+// newSubjectSet creates and returns a new subject set, holding the IDs of its concrete subjects
+// without a caveat expression as a bitmap over the IDs assigned by the interner.
+func newSubjectSet(interner *Interner) SubjectSet {
 	return SubjectSet{
-		BaseSubjectSet: NewBaseSubjectSet(subjectSetConstructor),
+		BaseSubjectSet: NewCompactBaseSubjectSet(subjectSetConstructor, interner),
 	}
 }
 
@@ -46,12 +54,45 @@ func (ss SubjectSet) WithParentCaveatExpression(parentCaveatExpr *core.CaveatExp
 	return SubjectSet{ss.BaseSubjectSet.WithParentCaveatExpression(parentCaveatExpr)}
 }
 
+// LimitedAfter returns a copy of the subject set containing only those concrete subjects whose
+// IDs sort after the given subject ID, limited to the first `limit` of them. See
+// BaseSubjectSet.LimitedAfter for more information.
+func (ss SubjectSet) LimitedAfter(afterSubjectID string, limit uint32) SubjectSet {
+	return SubjectSet{ss.BaseSubjectSet.LimitedAfter(afterSubjectID, limit)}
+}
+
+// UnionWithFoundSubjects adds the found subjects to this set, including those returned by ID
+// as compact subjects.
+func (ss SubjectSet) UnionWithFoundSubjects(foundSubjects *v1.FoundSubjects) error {
+	if len(foundSubjects.ConcreteSubjectIds) > 0 {
+		if err := ss.unionWithDeterminedIDs(foundSubjects.ConcreteSubjectIds); err != nil {
+			return err
+		}
+	}
+
+	return ss.UnionWith(foundSubjects.FoundSubjects)
+}
+
 func (ss SubjectSet) AsFoundSubjects() *v1.FoundSubjects {
 	return &v1.FoundSubjects{
 		FoundSubjects: ss.AsSlice(),
 	}
 }
 
+// AsCompactFoundSubjects returns the subjects of the set with the concrete subjects without a
+// caveat expression returned by ID, for a request which allows compact subjects.
+func (ss SubjectSet) AsCompactFoundSubjects() *v1.FoundSubjects {
+	foundSubjects := maps.Values(ss.concrete)
+	if wildcard, ok := ss.wildcard.get(); ok {
+		foundSubjects = append(foundSubjects, wildcard)
+	}
+
+	return &v1.FoundSubjects{
+		FoundSubjects:      foundSubjects,
+		ConcreteSubjectIds: ss.determined.interner.Values(ss.determined.ids),
+	}
+}
+
 func subjectSetConstructor(subjectID string, caveatExpression *core.CaveatExpression, excludedSubjects []*v1.FoundSubject, _ ...*v1.FoundSubject) *v1.FoundSubject {
 	return &v1.FoundSubject{
 		SubjectId:        subjectID,
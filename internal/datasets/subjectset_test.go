@@ -1443,6 +1443,89 @@ func TestSubjectSetGet(t *testing.T) {
 	require.Equal(t, 2, len(found.ExcludedSubjects))
 }
 
+func TestSubjectSetLimitedAfter(t *testing.T) {
+	tcs := []struct {
+		name           string
+		subjects       []*v1.FoundSubject
+		afterSubjectID string
+		limit          uint32
+		expected       []*v1.FoundSubject
+	}{
+		{
+			"no cursor or limit",
+			[]*v1.FoundSubject{sub("c"), sub("a"), sub("b")},
+			"",
+			0,
+			[]*v1.FoundSubject{sub("a"), sub("b"), sub("c")},
+		},
+		{
+			"limit only",
+			[]*v1.FoundSubject{sub("c"), sub("a"), sub("b")},
+			"",
+			2,
+			[]*v1.FoundSubject{sub("a"), sub("b")},
+		},
+		{
+			"cursor only",
+			[]*v1.FoundSubject{sub("c"), sub("a"), sub("b")},
+			"a",
+			0,
+			[]*v1.FoundSubject{sub("b"), sub("c")},
+		},
+		{
+			"cursor and limit",
+			[]*v1.FoundSubject{sub("d"), sub("c"), sub("a"), sub("b")},
+			"a",
+			2,
+			[]*v1.FoundSubject{sub("b"), sub("c")},
+		},
+		{
+			"caveats are retained",
+			[]*v1.FoundSubject{csub("b", caveatexpr("somecaveat")), sub("a")},
+			"a",
+			1,
+			[]*v1.FoundSubject{csub("b", caveatexpr("somecaveat"))},
+		},
+		{
+			"wildcard is always retained with all exclusions",
+			[]*v1.FoundSubject{wc("a", "d"), sub("b"), sub("c")},
+			"b",
+			0,
+			[]*v1.FoundSubject{wc("a", "d"), sub("c")},
+		},
+		{
+			"wildcard does not count against the limit",
+			[]*v1.FoundSubject{wc("e"), sub("b"), sub("c"), sub("d")},
+			"",
+			1,
+			[]*v1.FoundSubject{wc("e"), sub("b")},
+		},
+		{
+			"nothing after the cursor",
+			[]*v1.FoundSubject{sub("a"), sub("b")},
+			"b",
+			10,
+			[]*v1.FoundSubject{},
+		},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ss := NewSubjectSet()
+			for _, subject := range tc.subjects {
+				ss.MustAdd(subject)
+			}
+
+			limited := ss.LimitedAfter(tc.afterSubjectID, tc.limit)
+			testutil.RequireEquivalentSets(t, tc.expected, limited.AsSlice())
+
+			// Ensure the original set is unchanged.
+			testutil.RequireEquivalentSets(t, tc.subjects, ss.AsSlice())
+		})
+	}
+}
+
 var testSets = [][]*v1.FoundSubject{
 	{sub("foo"), sub("bar")},
 	{sub("foo")},
@@ -2618,3 +2701,69 @@ func allSubsets[T any](objs []T, n int) [][]T {
 	}
 	return all
 }
+
+func TestCompactOperationsAcrossInterners(t *testing.T) {
+	first := []*v1.FoundSubject{sub("1"), sub("2"), csub("3", caveatexpr("c1")), sub("4")}
+	second := []*v1.FoundSubject{sub("2"), csub("3", caveatexpr("c2")), csub("4", caveatexpr("c3")), sub("5")}
+
+	tcs := []struct {
+		name     string
+		apply    func(set SubjectSet, other SubjectSet)
+		expected []*v1.FoundSubject
+	}{
+		{
+			"union",
+			func(set SubjectSet, other SubjectSet) { set.MustUnionWithSet(other) },
+			[]*v1.FoundSubject{
+				sub("1"), sub("2"), csub("3", caveatOr(caveatexpr("c1"), caveatexpr("c2"))), sub("4"), sub("5"),
+			},
+		},
+		{
+			"intersection",
+			func(set SubjectSet, other SubjectSet) { set.MustIntersectionDifference(other) },
+			[]*v1.FoundSubject{
+				sub("2"), csub("3", caveatAnd(caveatexpr("c1"), caveatexpr("c2"))), csub("4", caveatexpr("c3")),
+			},
+		},
+		{
+			"subtraction",
+			func(set SubjectSet, other SubjectSet) { set.SubtractAll(other) },
+			[]*v1.FoundSubject{
+				sub("1"), csub("3", caveatAnd(caveatexpr("c1"), caveatInvert(caveatexpr("c2")))), csub("4", caveatInvert(caveatexpr("c3"))),
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			shared := NewInterner()
+			for _, interners := range [][]*Interner{{shared, shared}, {NewInterner(), NewInterner()}} {
+				set := newSubjectSet(interners[0])
+				require.NoError(t, set.UnionWith(first))
+
+				other := newSubjectSet(interners[1])
+				require.NoError(t, other.UnionWith(second))
+
+				tc.apply(set, other)
+				testutil.RequireEquivalentSets(t, tc.expected, set.AsSlice())
+			}
+		})
+	}
+}
+
+func TestCompactFoundSubjects(t *testing.T) {
+	set := NewSubjectSet()
+	set.MustAdd(sub("1"))
+	set.MustAdd(sub("2"))
+	set.MustAdd(csub("3", caveatexpr("c1")))
+	set.MustAdd(wc("4"))
+
+	compact := set.AsCompactFoundSubjects()
+	require.ElementsMatch(t, []string{"1", "2"}, compact.ConcreteSubjectIds)
+	testutil.RequireEquivalentSets(t, []*v1.FoundSubject{csub("3", caveatexpr("c1")), wc("4")}, compact.FoundSubjects)
+
+	roundTripped := NewSubjectSet()
+	require.NoError(t, roundTripped.UnionWithFoundSubjects(compact))
+	testutil.RequireEquivalentSets(t, set.AsSlice(), roundTripped.AsSlice())
+}
@@ -9,8 +9,16 @@ import (
 
 // NewSubjectSetByResourceID creates and returns a map of subject sets, indexed by resource ID.
 func NewSubjectSetByResourceID() SubjectSetByResourceID {
+	return NewSubjectSetByResourceIDWithInterner(NewInterner())
+}
+
+// NewSubjectSetByResourceIDWithInterner creates and returns a map of subject sets, indexed by
+// resource ID, whose sets share the given interner. Maps which are combined with one another
+// should share an interner.
+func NewSubjectSetByResourceIDWithInterner(interner *Interner) SubjectSetByResourceID {
 	return SubjectSetByResourceID{
 		subjectSetByResourceID: map[string]SubjectSet{},
+		interner:               interner,
 	}
 }
 
@@ -18,6 +26,16 @@ func NewSubjectSetByResourceID() SubjectSetByResourceID {
 // subjects, in the form of a subject set per resource ID.
 type SubjectSetByResourceID struct {
 	subjectSetByResourceID map[string]SubjectSet
+	interner               *Interner
+}
+
+func (ssr SubjectSetByResourceID) setFor(resourceID string) SubjectSet {
+	subjectSet, ok := ssr.subjectSetByResourceID[resourceID]
+	if !ok {
+		subjectSet = newSubjectSet(ssr.interner)
+		ssr.subjectSetByResourceID[resourceID] = subjectSet
+	}
+	return subjectSet
 }
 
 func (ssr SubjectSetByResourceID) add(resourceID string, subject *v1.FoundSubject) error {
@@ -25,11 +43,7 @@ func (ssr SubjectSetByResourceID) add(resourceID string, subject *v1.FoundSubjec
 		return fmt.Errorf("cannot add a nil subject to SubjectSetByResourceID")
 	}
 
-	_, ok := ssr.subjectSetByResourceID[resourceID]
-	if !ok {
-		ssr.subjectSetByResourceID[resourceID] = NewSubjectSet()
-	}
-	return ssr.subjectSetByResourceID[resourceID].Add(subject)
+	return ssr.setFor(resourceID).Add(subject)
 }
 
 // AddFromRelationship adds the subject found in the given relationship to this map, indexed at
@@ -49,10 +63,18 @@ func (ssr SubjectSetByResourceID) UnionWith(other map[string]*v1.FoundSubjects)
 		}
 
 		for _, subject := range subjects.FoundSubjects {
-			if err := ssr.add(resourceID, subject); err != nil {
-				return err
+			if subject == nil {
+				return fmt.Errorf("cannot add a nil subject to SubjectSetByResourceID")
 			}
 		}
+
+		if len(subjects.FoundSubjects) == 0 && len(subjects.ConcreteSubjectIds) == 0 {
+			continue
+		}
+
+		if err := ssr.setFor(resourceID).UnionWithFoundSubjects(subjects); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -102,6 +124,20 @@ func (ssr SubjectSetByResourceID) SubtractAll(other SubjectSetByResourceID) {
 	}
 }
 
+// LimitedAfter returns a copy of the map in which each resource's set contains only those concrete
+// subjects whose IDs sort after the given subject ID, limited to the first `limit` of them. See
+// BaseSubjectSet.LimitedAfter for more information.
+func (ssr SubjectSetByResourceID) LimitedAfter(afterSubjectID string, limit uint32) SubjectSetByResourceID {
+	limited := NewSubjectSetByResourceIDWithInterner(ssr.interner)
+	for resourceID, subjectSet := range ssr.subjectSetByResourceID {
+		limitedSet := subjectSet.LimitedAfter(afterSubjectID, limit)
+		if !limitedSet.IsEmpty() {
+			limited.subjectSetByResourceID[resourceID] = limitedSet
+		}
+	}
+	return limited
+}
+
 // IsEmpty returns true if the map is empty.
 func (ssr SubjectSetByResourceID) IsEmpty() bool {
 	return len(ssr.subjectSetByResourceID) == 0
@@ -115,3 +151,13 @@ func (ssr SubjectSetByResourceID) AsMap() map[string]*v1.FoundSubjects {
 	}
 	return mapped
 }
+
+// AsCompactMap converts the map into a map for storage in a proto, with the concrete subjects
+// without a caveat expression returned by ID, for a request which allows compact subjects.
+func (ssr SubjectSetByResourceID) AsCompactMap() map[string]*v1.FoundSubjects {
+	mapped := make(map[string]*v1.FoundSubjects, len(ssr.subjectSetByResourceID))
+	for resourceID, subjectsSet := range ssr.subjectSetByResourceID {
+		mapped[resourceID] = subjectsSet.AsCompactFoundSubjects()
+	}
+	return mapped
+}
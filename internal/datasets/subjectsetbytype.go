@@ -11,13 +11,15 @@ import (
 
 // SubjectByTypeSet is a set of SubjectSet's, grouped by their subject types.
 type SubjectByTypeSet struct {
-	byType map[string]SubjectSet
+	byType   map[string]SubjectSet
+	interner *Interner
 }
 
 // NewSubjectByTypeSet creates and returns a new SubjectByTypeSet.
 func NewSubjectByTypeSet() *SubjectByTypeSet {
 	return &SubjectByTypeSet{
-		byType: map[string]SubjectSet{},
+		byType:   map[string]SubjectSet{},
+		interner: NewInterner(),
 	}
 }
 
@@ -35,7 +37,7 @@ func (s *SubjectByTypeSet) AddConcreteSubject(subject *core.ObjectAndRelation) e
 func (s *SubjectByTypeSet) AddSubject(subject *core.ObjectAndRelation, caveat *core.ContextualizedCaveat) error {
 	key := tuple.JoinRelRef(subject.Namespace, subject.Relation)
 	if _, ok := s.byType[key]; !ok {
-		s.byType[key] = NewSubjectSet()
+		s.byType[key] = newSubjectSet(s.interner)
 	}
 
 	return s.byType[key].Add(&v1.FoundSubject{
@@ -59,7 +61,10 @@ func (s *SubjectByTypeSet) ForEachType(handler func(rr *core.RelationReference,
 // Map runs the mapper function over each type of object in the set, returning a new ONRByTypeSet with
 // the object type replaced by that returned by the mapper function.
 func (s *SubjectByTypeSet) Map(mapper func(rr *core.RelationReference) (*core.RelationReference, error)) (*SubjectByTypeSet, error) {
-	mapped := NewSubjectByTypeSet()
+	mapped := &SubjectByTypeSet{
+		byType:   map[string]SubjectSet{},
+		interner: s.interner,
+	}
 	for key, subjectset := range s.byType {
 		ns, rel := tuple.MustSplitRelRef(key)
 		updatedType, err := mapper(&core.RelationReference{
@@ -10,19 +10,27 @@ import (
 	"github.com/authzed/spicedb/pkg/tuple"
 )
 
-// Changes represents a set of tuple mutations that are kept self-consistent
-// across one or more transaction revisions.
+// Changes represents a set of tuple and schema definition mutations that are
+// kept self-consistent across one or more transaction revisions.
 type Changes[R datastore.Revision, K comparable] struct {
 	records map[K]changeRecord[R]
 	keyFunc func(R) K
 }
 
 type changeRecord[R datastore.Revision] struct {
-	rev          R
-	tupleTouches map[string]*core.RelationTuple
-	tupleDeletes map[string]*core.RelationTuple
+	rev                R
+	tupleTouches       map[string]*core.RelationTuple
+	tupleDeletes       map[string]*core.RelationTuple
+	definitionsChanged map[string]datastore.SchemaDefinition
+	namespacesDeleted  map[string]struct{}
+	caveatsDeleted     map[string]struct{}
 }
 
+const (
+	nsPrefix     = "n$"
+	caveatPrefix = "c$"
+)
+
 // NewChanges creates a new Changes object for change tracking and de-duplication.
 func NewChanges[R datastore.Revision, K comparable](keyFunc func(R) K) Changes[R, K] {
 	return Changes[R, K]{
@@ -38,16 +46,7 @@ func (ch Changes[R, K]) AddChange(
 	tpl *core.RelationTuple,
 	op core.RelationTupleUpdate_Operation,
 ) {
-	k := ch.keyFunc(rev)
-	revisionChanges, ok := ch.records[k]
-	if !ok {
-		revisionChanges = changeRecord[R]{
-			rev,
-			make(map[string]*core.RelationTuple),
-			make(map[string]*core.RelationTuple),
-		}
-		ch.records[k] = revisionChanges
-	}
+	revisionChanges := ch.recordForRevision(rev)
 
 	tplKey := tuple.StringWithoutCaveat(tpl)
 
@@ -68,6 +67,76 @@ func (ch Changes[R, K]) AddChange(
 	}
 }
 
+// AddChangedDefinition adds a change indicating that the schema definition
+// (namespace or caveat) was changed to the definition given.
+func (ch Changes[R, K]) AddChangedDefinition(
+	ctx context.Context,
+	rev R,
+	def datastore.SchemaDefinition,
+) {
+	revisionChanges := ch.recordForRevision(rev)
+
+	switch t := def.(type) {
+	case *core.NamespaceDefinition:
+		delete(revisionChanges.namespacesDeleted, t.Name)
+		revisionChanges.definitionsChanged[nsPrefix+t.Name] = t
+
+	case *core.CaveatDefinition:
+		delete(revisionChanges.caveatsDeleted, t.Name)
+		revisionChanges.definitionsChanged[caveatPrefix+t.Name] = t
+
+	default:
+		log.Ctx(ctx).Fatal().Msg("unknown schema definition kind")
+	}
+}
+
+// AddDeletedNamespace adds a change indicating that the namespace with the
+// name was deleted.
+func (ch Changes[R, K]) AddDeletedNamespace(
+	_ context.Context,
+	rev R,
+	namespaceName string,
+) {
+	revisionChanges := ch.recordForRevision(rev)
+
+	// If the namespace was written at the same revision, the write wins
+	if _, alreadyChanged := revisionChanges.definitionsChanged[nsPrefix+namespaceName]; !alreadyChanged {
+		revisionChanges.namespacesDeleted[namespaceName] = struct{}{}
+	}
+}
+
+// AddDeletedCaveat adds a change indicating that the caveat with the name
+// was deleted.
+func (ch Changes[R, K]) AddDeletedCaveat(
+	_ context.Context,
+	rev R,
+	caveatName string,
+) {
+	revisionChanges := ch.recordForRevision(rev)
+
+	// If the caveat was written at the same revision, the write wins
+	if _, alreadyChanged := revisionChanges.definitionsChanged[caveatPrefix+caveatName]; !alreadyChanged {
+		revisionChanges.caveatsDeleted[caveatName] = struct{}{}
+	}
+}
+
+func (ch Changes[R, K]) recordForRevision(rev R) changeRecord[R] {
+	k := ch.keyFunc(rev)
+	revisionChanges, ok := ch.records[k]
+	if !ok {
+		revisionChanges = changeRecord[R]{
+			rev,
+			make(map[string]*core.RelationTuple),
+			make(map[string]*core.RelationTuple),
+			make(map[string]datastore.SchemaDefinition),
+			make(map[string]struct{}),
+			make(map[string]struct{}),
+		}
+		ch.records[k] = revisionChanges
+	}
+	return revisionChanges
+}
+
 // AsRevisionChanges returns the list of changes processed so far as a datastore watch
 // compatible, ordered, changelist.
 func (ch Changes[R, K]) AsRevisionChanges(lessThanFunc func(lhs, rhs K) bool) []datastore.RevisionChanges {
@@ -96,7 +165,42 @@ func (ch Changes[R, K]) AsRevisionChanges(lessThanFunc func(lhs, rhs K) bool) []
 				Tuple:     tpl,
 			})
 		}
+
+		definitionKeys := make([]string, 0, len(revisionChangeRecord.definitionsChanged))
+		for key := range revisionChangeRecord.definitionsChanged {
+			definitionKeys = append(definitionKeys, key)
+		}
+		sort.Strings(definitionKeys)
+		for _, key := range definitionKeys {
+			changes[i].ChangedDefinitions = append(changes[i].ChangedDefinitions, revisionChangeRecord.definitionsChanged[key])
+		}
+
+		changes[i].DeletedNamespaces = sortedKeys(revisionChangeRecord.namespacesDeleted)
+		changes[i].DeletedCaveats = sortedKeys(revisionChangeRecord.caveatsDeleted)
 	}
 
 	return changes
 }
+
+func sortedKeys(set map[string]struct{}) []string {
+	if len(set) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// SendCheckpoint sends a checkpoint at the given revision on the watch updates channel. As a
+// checkpoint is superseded by any later change or checkpoint, it is dropped rather than
+// blocking or disconnecting the watch if the channel is full.
+func SendCheckpoint(updates chan<- *datastore.RevisionChanges, rev datastore.Revision) {
+	select {
+	case updates <- &datastore.RevisionChanges{Revision: rev, IsCheckpoint: true}:
+	default:
+	}
+}
@@ -194,6 +194,45 @@ func TestChanges(t *testing.T) {
 	}
 }
 
+func TestSchemaChanges(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	nsDef := &core.NamespaceDefinition{Name: "document"}
+	caveatDef := &core.CaveatDefinition{Name: "somecaveat"}
+
+	ch := NewChanges(revision.DecimalKeyFunc)
+	ch.AddChange(ctx, rev1, tuple.MustParse(tuple1), core.RelationTupleUpdate_TOUCH)
+
+	// A rewrite of a definition in the same revision wins over its deletion.
+	ch.AddDeletedNamespace(ctx, rev2, nsDef.Name)
+	ch.AddChangedDefinition(ctx, rev2, nsDef)
+	ch.AddChangedDefinition(ctx, rev2, caveatDef)
+	ch.AddDeletedCaveat(ctx, rev2, caveatDef.Name)
+
+	// Definitions of the different kinds with the same name are tracked separately.
+	ch.AddDeletedNamespace(ctx, revOneMillion, "user")
+	ch.AddChangedDefinition(ctx, revOneMillion, &core.CaveatDefinition{Name: "user"})
+	ch.AddDeletedCaveat(ctx, revOneMillion, caveatDef.Name)
+
+	require.Equal([]datastore.RevisionChanges{
+		{
+			Revision: rev1,
+			Changes:  []*core.RelationTupleUpdate{touch(tuple1)},
+		},
+		{
+			Revision:           rev2,
+			ChangedDefinitions: []datastore.SchemaDefinition{caveatDef, nsDef},
+		},
+		{
+			Revision:           revOneMillion,
+			ChangedDefinitions: []datastore.SchemaDefinition{&core.CaveatDefinition{Name: "user"}},
+			DeletedNamespaces:  []string{"user"},
+			DeletedCaveats:     []string{caveatDef.Name},
+		},
+	}, ch.AsRevisionChanges(revision.DecimalKeyLessThanFunc))
+}
+
 func TestCanonicalize(t *testing.T) {
 	testCases := []struct {
 		name            string
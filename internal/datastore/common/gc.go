@@ -29,6 +29,13 @@ var (
 		Help:      "The number of stale relationships deleted by the datastore garbage collection.",
 	})
 
+	gcExpiredRelationshipsCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "spicedb",
+		Subsystem: "datastore",
+		Name:      "gc_expired_relationships_total",
+		Help:      "The number of expired relationships deleted by the datastore garbage collection.",
+	})
+
 	gcTransactionsCounter = prometheus.NewCounter(prometheus.CounterOpts{
 		Namespace: "spicedb",
 		Subsystem: "datastore",
@@ -57,6 +64,7 @@ func RegisterGCMetrics() error {
 	for _, metric := range []prometheus.Collector{
 		gcDurationHistogram,
 		gcRelationshipsCounter,
+		gcExpiredRelationshipsCounter,
 		gcTransactionsCounter,
 		gcNamespacesCounter,
 		gcFailureCounter,
@@ -76,19 +84,22 @@ type GarbageCollector interface {
 	Now(context.Context) (time.Time, error)
 	TxIDBefore(context.Context, time.Time) (datastore.Revision, error)
 	DeleteBeforeTx(ctx context.Context, txID datastore.Revision) (DeletionCounts, error)
+	DeleteExpiredBefore(ctx context.Context, before time.Time) (int64, error)
 }
 
 // DeletionCounts tracks the amount of deletions that occurred when calling
-// DeleteBeforeTx.
+// DeleteBeforeTx and DeleteExpiredBefore.
 type DeletionCounts struct {
-	Relationships int64
-	Transactions  int64
-	Namespaces    int64
+	Relationships        int64
+	ExpiredRelationships int64
+	Transactions         int64
+	Namespaces           int64
 }
 
 func (g DeletionCounts) MarshalZerologObject(e *zerolog.Event) {
 	e.
 		Int64("relationships", g.Relationships).
+		Int64("expiredRelationships", g.ExpiredRelationships).
 		Int64("transactions", g.Transactions).
 		Int64("namespaces", g.Namespaces)
 }
@@ -172,6 +183,13 @@ func RunGarbageCollection(gc GarbageCollector, window, timeout time.Duration) er
 		return fmt.Errorf("error deleting in gc: %w", err)
 	}
 
+	// Relationships which expired before the window can no longer be read at any
+	// valid revision.
+	collected.ExpiredRelationships, err = gc.DeleteExpiredBefore(ctx, now.Add(-1*window))
+	if err != nil {
+		return fmt.Errorf("error deleting expired relationships in gc: %w", err)
+	}
+
 	collectionDuration := time.Since(startTime)
 	log.Ctx(ctx).Debug().
 		Stringer("highestTxID", watermark).
@@ -182,6 +200,7 @@ func RunGarbageCollection(gc GarbageCollector, window, timeout time.Duration) er
 
 	gcDurationHistogram.Observe(collectionDuration.Seconds())
 	gcRelationshipsCounter.Add(float64(collected.Relationships))
+	gcExpiredRelationshipsCounter.Add(float64(collected.ExpiredRelationships))
 	gcTransactionsCounter.Add(float64(collected.Transactions))
 	gcNamespacesCounter.Add(float64(collected.Namespaces))
 	return nil
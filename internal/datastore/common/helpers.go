@@ -3,10 +3,14 @@ package common
 import (
 	"context"
 	"fmt"
+	"time"
 
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
 	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/options"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 	"github.com/authzed/spicedb/pkg/tuple"
 )
@@ -31,6 +35,56 @@ func UpdateTuplesInDatastore(ctx context.Context, ds datastore.Datastore, update
 	})
 }
 
+// BulkLoadInBatches reads all relationships from the source and writes them as CREATE
+// operations via writeBatch, in batches of at most batchSize relationships. Returns the number
+// of relationships written.
+func BulkLoadInBatches(
+	ctx context.Context,
+	source datastore.BulkWriteRelationshipSource,
+	batchSize uint16,
+	writeBatch func(ctx context.Context, mutations []*core.RelationTupleUpdate) error,
+) (uint64, error) {
+	var numWritten uint64
+	batch := make([]*core.RelationTupleUpdate, 0, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if err := writeBatch(ctx, batch); err != nil {
+			return err
+		}
+
+		numWritten += uint64(len(batch))
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		tpl, err := source.Next(ctx)
+		if err != nil {
+			return numWritten, err
+		}
+		if tpl == nil {
+			break
+		}
+
+		// Sources may reuse the tuple between calls, so a copy must be held in the batch.
+		batch = append(batch, tuple.Create(tpl.CloneVT()))
+		if len(batch) == int(batchSize) {
+			if err := flush(); err != nil {
+				return numWritten, err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return numWritten, err
+	}
+
+	return numWritten, nil
+}
+
 // CreateRelationshipExistsError is an error returned when attempting to CREATE an already-existing
 // relationship.
 type CreateRelationshipExistsError struct {
@@ -69,3 +123,71 @@ func ContextualizedCaveatFrom(name string, context map[string]any) (*core.Contex
 	}
 	return caveat, nil
 }
+
+// ExpirationTimeFrom returns the expiration time of the tuple to be stored, or nil if the tuple
+// does not expire.
+func ExpirationTimeFrom(tpl *core.RelationTuple) *time.Time {
+	if tpl.OptionalExpirationTime == nil {
+		return nil
+	}
+
+	expiration := tpl.OptionalExpirationTime.AsTime()
+	return &expiration
+}
+
+// ExpirationTimestampFrom converts a stored (and possibly null) expiration time into the form
+// found on tuples.
+func ExpirationTimestampFrom(expiration *time.Time) *timestamppb.Timestamp {
+	if expiration == nil {
+		return nil
+	}
+	return timestamppb.New(*expiration)
+}
+
+// RelationshipsRemain returns whether any relationships matching the filter are visible to the
+// given reader. Datastores use it to determine whether a deletion with a limit left matching
+// relationships behind, by reading within the deleting transaction.
+func RelationshipsRemain(ctx context.Context, reader datastore.Reader, filter *v1.RelationshipFilter) (bool, error) {
+	iter, err := reader.QueryRelationships(
+		ctx,
+		datastore.RelationshipsFilterFromPublicFilter(filter),
+		options.WithLimit(options.LimitOne),
+	)
+	if err != nil {
+		return false, err
+	}
+	defer iter.Close()
+
+	found := iter.Next() != nil
+	return found, iter.Err()
+}
+
+// HasExpiringRelationships returns whether any relationship of the relation, visible to the given
+// reader, has an expiration. Relationships expire without a change being reported by the watch of
+// the datastore, so state derived from the relationships of such relations cannot be maintained
+// from the watch alone.
+func HasExpiringRelationships(ctx context.Context, reader datastore.Reader, namespaceName string, relationName string) (bool, error) {
+	iter, err := reader.QueryRelationships(ctx, datastore.RelationshipsFilter{
+		ResourceType:             namespaceName,
+		OptionalResourceRelation: relationName,
+		OnlyWithExpiration:       true,
+	}, options.WithLimit(options.LimitOne))
+	if err != nil {
+		return false, err
+	}
+	defer iter.Close()
+
+	found := iter.Next() != nil
+	return found, iter.Err()
+}
+
+// IdempotencyKeyCommittedErr returns the error for a read-write transaction given the request hash
+// and an idempotency key with which a transaction, given committedRequestHash, has already
+// committed at the revision. An empty hash on either side, such as for a key stored without one,
+// is not compared.
+func IdempotencyKeyCommittedErr(key string, requestHash string, committedRequestHash string, committed datastore.Revision) error {
+	if requestHash != "" && committedRequestHash != "" && requestHash != committedRequestHash {
+		return datastore.NewIdempotencyKeyReusedErr(key)
+	}
+	return datastore.NewIdempotencyKeyAlreadyCommittedErr(key, committed)
+}
@@ -73,6 +73,7 @@ type SchemaInformation struct {
 	colUsersetObjectID   string
 	colUsersetRelation   string
 	colCaveatName        string
+	colExpiration        string
 	paginationFilterType PaginationFilterType
 }
 
@@ -83,7 +84,8 @@ func NewSchemaInformation(
 	colUsersetNamespace,
 	colUsersetObjectID,
 	colUsersetRelation,
-	colCaveatName string,
+	colCaveatName,
+	colExpiration string,
 	paginationFilterType PaginationFilterType,
 ) SchemaInformation {
 	return SchemaInformation{
@@ -94,6 +96,7 @@ func NewSchemaInformation(
 		colUsersetObjectID,
 		colUsersetRelation,
 		colCaveatName,
+		colExpiration,
 		paginationFilterType,
 	}
 }
@@ -287,6 +290,10 @@ func (sqf SchemaQueryFilterer) FilterWithRelationshipsFilter(filter datastore.Re
 		sqf = sqf.FilterWithCaveatName(filter.OptionalCaveatName)
 	}
 
+	if filter.OnlyWithExpiration {
+		sqf = sqf.FilterToExpiringRelationships()
+	}
+
 	return sqf, nil
 }
 
@@ -303,28 +310,40 @@ func (sqf SchemaQueryFilterer) MustFilterWithSubjectsSelectors(selectors ...data
 // FilterWithSubjectsSelectors returns a new SchemaQueryFilterer that is limited to resources with
 // subjects that match the specified selector(s).
 func (sqf SchemaQueryFilterer) FilterWithSubjectsSelectors(selectors ...datastore.SubjectsSelector) (SchemaQueryFilterer, error) {
+	selectorsOrClause, attributes, err := sqf.schema.subjectsSelectorsClause(selectors)
+	if err != nil {
+		return sqf, err
+	}
+
+	sqf.tracerAttributes = append(sqf.tracerAttributes, attributes...)
+	sqf.queryBuilder = sqf.queryBuilder.Where(selectorsOrClause)
+	return sqf, nil
+}
+
+func (si SchemaInformation) subjectsSelectorsClause(selectors []datastore.SubjectsSelector) (sq.Or, []attribute.KeyValue, error) {
 	selectorsOrClause := sq.Or{}
+	var attributes []attribute.KeyValue
 
 	for _, selector := range selectors {
 		selectorClause := sq.And{}
 
 		if len(selector.OptionalSubjectType) > 0 {
-			selectorClause = append(selectorClause, sq.Eq{sqf.schema.colUsersetNamespace: selector.OptionalSubjectType})
-			sqf.tracerAttributes = append(sqf.tracerAttributes, SubNamespaceNameKey.String(selector.OptionalSubjectType))
+			selectorClause = append(selectorClause, sq.Eq{si.colUsersetNamespace: selector.OptionalSubjectType})
+			attributes = append(attributes, SubNamespaceNameKey.String(selector.OptionalSubjectType))
 		}
 
 		if len(selector.OptionalSubjectIds) > 0 {
 			// TODO(jschorr): Change this panic into an automatic query split, if we find it necessary.
 			if len(selector.OptionalSubjectIds) > int(datastore.FilterMaximumIDCount) {
-				return sqf, spiceerrors.MustBugf("cannot have more than %d subject IDs in a single filter", datastore.FilterMaximumIDCount)
+				return nil, nil, spiceerrors.MustBugf("cannot have more than %d subject IDs in a single filter", datastore.FilterMaximumIDCount)
 			}
 
-			inClause := sqf.schema.colUsersetObjectID + " IN ("
+			inClause := si.colUsersetObjectID + " IN ("
 			args := make([]any, 0, len(selector.OptionalSubjectIds))
 
 			for index, subjectID := range selector.OptionalSubjectIds {
 				if len(subjectID) == 0 {
-					return sqf, spiceerrors.MustBugf("got empty subject ID")
+					return nil, nil, spiceerrors.MustBugf("got empty subject ID")
 				}
 
 				if index > 0 {
@@ -334,7 +353,7 @@ func (sqf SchemaQueryFilterer) FilterWithSubjectsSelectors(selectors ...datastor
 				inClause += "?"
 
 				args = append(args, subjectID)
-				sqf.tracerAttributes = append(sqf.tracerAttributes, SubObjectIDKey.String(subjectID))
+				attributes = append(attributes, SubObjectIDKey.String(subjectID))
 			}
 
 			selectorClause = append(selectorClause, sq.Expr(inClause+")", args...))
@@ -342,7 +361,7 @@ func (sqf SchemaQueryFilterer) FilterWithSubjectsSelectors(selectors ...datastor
 
 		if !selector.RelationFilter.IsEmpty() {
 			if selector.RelationFilter.OnlyNonEllipsisRelations {
-				selectorClause = append(selectorClause, sq.NotEq{sqf.schema.colUsersetRelation: datastore.Ellipsis})
+				selectorClause = append(selectorClause, sq.NotEq{si.colUsersetRelation: datastore.Ellipsis})
 			} else {
 				relations := make([]string, 0, 2)
 				if selector.RelationFilter.IncludeEllipsisRelation {
@@ -355,14 +374,14 @@ func (sqf SchemaQueryFilterer) FilterWithSubjectsSelectors(selectors ...datastor
 
 				if len(relations) == 1 {
 					relName := relations[0]
-					sqf.tracerAttributes = append(sqf.tracerAttributes, SubRelationNameKey.String(relName))
-					selectorClause = append(selectorClause, sq.Eq{sqf.schema.colUsersetRelation: relName})
+					attributes = append(attributes, SubRelationNameKey.String(relName))
+					selectorClause = append(selectorClause, sq.Eq{si.colUsersetRelation: relName})
 				} else {
 					orClause := sq.Or{}
 					for _, relationName := range relations {
 						dsRelationName := stringz.DefaultEmpty(relationName, datastore.Ellipsis)
-						orClause = append(orClause, sq.Eq{sqf.schema.colUsersetRelation: dsRelationName})
-						sqf.tracerAttributes = append(sqf.tracerAttributes, SubRelationNameKey.String(dsRelationName))
+						orClause = append(orClause, sq.Eq{si.colUsersetRelation: dsRelationName})
+						attributes = append(attributes, SubRelationNameKey.String(dsRelationName))
 					}
 
 					selectorClause = append(selectorClause, orClause)
@@ -373,8 +392,60 @@ func (sqf SchemaQueryFilterer) FilterWithSubjectsSelectors(selectors ...datastor
 		selectorsOrClause = append(selectorsOrClause, selectorClause)
 	}
 
-	sqf.queryBuilder = sqf.queryBuilder.Where(selectorsOrClause)
-	return sqf, nil
+	return selectorsOrClause, attributes, nil
+}
+
+// RelationshipsFiltersClause returns a clause matching the relationships that match *any* of the
+// specified filters, for use in queries which are not built with a SchemaQueryFilterer, such as
+// those loading changes for watches.
+func (si SchemaInformation) RelationshipsFiltersClause(filters []datastore.RelationshipsFilter) (sq.Sqlizer, error) {
+	filtersOrClause := sq.Or{}
+
+	for _, filter := range filters {
+		filterClause := sq.And{}
+
+		if filter.ResourceType != "" {
+			filterClause = append(filterClause, sq.Eq{si.colNamespace: filter.ResourceType})
+		}
+
+		if filter.OptionalResourceRelation != "" {
+			filterClause = append(filterClause, sq.Eq{si.colRelation: filter.OptionalResourceRelation})
+		}
+
+		if len(filter.OptionalResourceIds) > 0 {
+			if len(filter.OptionalResourceIds) > int(datastore.FilterMaximumIDCount) {
+				return nil, spiceerrors.MustBugf("cannot have more than %d resources IDs in a single filter", datastore.FilterMaximumIDCount)
+			}
+			filterClause = append(filterClause, sq.Eq{si.colObjectID: filter.OptionalResourceIds})
+		}
+
+		if len(filter.OptionalSubjectsSelectors) > 0 {
+			selectorsClause, _, err := si.subjectsSelectorsClause(filter.OptionalSubjectsSelectors)
+			if err != nil {
+				return nil, err
+			}
+			filterClause = append(filterClause, selectorsClause)
+		}
+
+		if filter.OptionalCaveatName != "" {
+			filterClause = append(filterClause, sq.Eq{si.colCaveatName: filter.OptionalCaveatName})
+		}
+
+		if filter.OnlyWithExpiration {
+			filterClause = append(filterClause, sq.NotEq{si.colExpiration: nil})
+		}
+
+		filtersOrClause = append(filtersOrClause, filterClause)
+	}
+
+	return filtersOrClause, nil
+}
+
+// FilterToExpiringRelationships returns a new SchemaQueryFilterer that is limited to
+// relationships with an expiration.
+func (sqf SchemaQueryFilterer) FilterToExpiringRelationships() SchemaQueryFilterer {
+	sqf.queryBuilder = sqf.queryBuilder.Where(sq.NotEq{sqf.schema.colExpiration: nil})
+	return sqf
 }
 
 // FilterToSubjectFilter returns a new SchemaQueryFilterer that is limited to resources with
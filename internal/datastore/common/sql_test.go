@@ -353,6 +353,20 @@ func TestSchemaQueryFilterer(t *testing.T) {
 			"SELECT * WHERE ns = ? AND relation = ? AND object_id IN (?, ?) AND ((subject_ns = ? AND subject_object_id IN (?, ?) AND (subject_relation = ? OR subject_relation = ?)))",
 			[]any{"someresourcetype", "somerelation", "someid", "anotherid", "somesubjectype", "somesubjectid", "anothersubjectid", "...", "somesubrel"},
 		},
+		{
+			"expiring relationships filter",
+			func(filterer SchemaQueryFilterer) SchemaQueryFilterer {
+				return filterer.MustFilterWithRelationshipsFilter(
+					datastore.RelationshipsFilter{
+						ResourceType:             "someresourcetype",
+						OptionalResourceRelation: "somerelation",
+						OnlyWithExpiration:       true,
+					},
+				)
+			},
+			"SELECT * WHERE ns = ? AND relation = ? AND expiration IS NOT NULL",
+			[]any{"someresourcetype", "somerelation"},
+		},
 	}
 
 	for _, test := range tests {
@@ -367,6 +381,7 @@ func TestSchemaQueryFilterer(t *testing.T) {
 				"subject_object_id",
 				"subject_relation",
 				"caveat",
+				"expiration",
 				TupleComparison,
 			), base)
 
@@ -377,3 +392,78 @@ func TestSchemaQueryFilterer(t *testing.T) {
 		})
 	}
 }
+
+func TestRelationshipsFiltersClause(t *testing.T) {
+	tests := []struct {
+		name         string
+		filters      []datastore.RelationshipsFilter
+		expectedSQL  string
+		expectedArgs []any
+	}{
+		{
+			"single resource type",
+			[]datastore.RelationshipsFilter{{ResourceType: "sometype"}},
+			"SELECT * WHERE ((ns = ?))",
+			[]any{"sometype"},
+		},
+		{
+			"resource and subject",
+			[]datastore.RelationshipsFilter{
+				{
+					ResourceType:             "sometype",
+					OptionalResourceIds:      []string{"someid"},
+					OptionalResourceRelation: "somerel",
+					OptionalSubjectsSelectors: []datastore.SubjectsSelector{
+						{
+							OptionalSubjectType: "subtype",
+							RelationFilter:      datastore.SubjectRelationFilter{}.WithEllipsisRelation(),
+						},
+					},
+					OptionalCaveatName: "somecaveat",
+				},
+			},
+			"SELECT * WHERE ((ns = ? AND relation = ? AND object_id IN (?) AND ((subject_ns = ? AND subject_relation = ?)) AND caveat = ?))",
+			[]any{"sometype", "somerel", "someid", "subtype", "...", "somecaveat"},
+		},
+		{
+			"multiple filters",
+			[]datastore.RelationshipsFilter{
+				{ResourceType: "sometype", OptionalResourceRelation: "somerel"},
+				{ResourceType: "anothertype"},
+			},
+			"SELECT * WHERE ((ns = ? AND relation = ?) OR (ns = ?))",
+			[]any{"sometype", "somerel", "anothertype"},
+		},
+		{
+			"expiring relationships",
+			[]datastore.RelationshipsFilter{{ResourceType: "sometype", OnlyWithExpiration: true}},
+			"SELECT * WHERE ((ns = ? AND expiration IS NOT NULL))",
+			[]any{"sometype"},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			schema := NewSchemaInformation(
+				"ns",
+				"object_id",
+				"relation",
+				"subject_ns",
+				"subject_object_id",
+				"subject_relation",
+				"caveat",
+				"expiration",
+				TupleComparison,
+			)
+
+			clause, err := schema.RelationshipsFiltersClause(test.filters)
+			require.NoError(t, err)
+
+			sql, args, err := sq.Select("*").Where(clause).ToSql()
+			require.NoError(t, err)
+			require.Equal(t, test.expectedSQL, sql)
+			require.Equal(t, test.expectedArgs, args)
+		})
+	}
+}
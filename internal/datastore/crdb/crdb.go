@@ -23,6 +23,7 @@ import (
 	"github.com/authzed/spicedb/internal/datastore/proxy"
 	log "github.com/authzed/spicedb/internal/logging"
 	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/options"
 	"github.com/authzed/spicedb/pkg/datastore/revision"
 )
 
@@ -45,6 +46,8 @@ const (
 	tableTransactions = "transactions"
 	tableCaveat       = "caveat"
 
+	tableIdempotencyKey = "idempotency_key"
+
 	colNamespace         = "namespace"
 	colConfig            = "serialized_config"
 	colTimestamp         = "timestamp"
@@ -58,9 +61,13 @@ const (
 	colCaveatDefinition  = "definition"
 	colCaveatContextName = "caveat_name"
 	colCaveatContext     = "caveat_context"
+	colExpiration        = "expiration"
+	colIdempotencyKey    = "key"
+	colRequestHash       = "request_hash"
 
 	errUnableToInstantiate = "unable to instantiate datastore: %w"
 	errRevision            = "unable to find revision: %w"
+	errCheckIdempotencyKey = "unable to check idempotency key: %w"
 
 	querySelectNow      = "SELECT cluster_logical_timestamp()"
 	queryShowZoneConfig = "SHOW ZONE CONFIGURATION FOR RANGE default;"
@@ -226,7 +233,11 @@ func (cds *crdbDatastore) SnapshotReader(rev datastore.Revision) datastore.Reade
 		return query.From(fromStr + " AS OF SYSTEM TIME " + rev.String())
 	}
 
-	return &crdbReader{useImplicitTxFunc, querySplitter, noOverlapKeyer, nil, cds.execute, fromBuilder}
+	// Relationships are expired as of the wall time of the revision being read.
+	readTime := time.Unix(0, rev.(revision.Decimal).IntPart())
+	notExpired := sq.Or{sq.Eq{colExpiration: nil}, sq.Gt{colExpiration: readTime}}
+
+	return &crdbReader{useImplicitTxFunc, querySplitter, noOverlapKeyer, nil, cds.execute, fromBuilder, notExpired}
 }
 
 func noCleanup(context.Context) {}
@@ -234,10 +245,19 @@ func noCleanup(context.Context) {}
 func (cds *crdbDatastore) ReadWriteTx(
 	ctx context.Context,
 	f datastore.TxUserFunc,
+	opts ...options.RWTOptionsOption,
 ) (datastore.Revision, error) {
+	rwtOpts := options.NewRWTOptionsWithOptions(opts...)
+
 	var commitTimestamp revision.Decimal
 	if err := cds.execute(ctx, func(ctx context.Context) error {
 		return pgx.BeginFunc(ctx, cds.writePool, func(tx pgx.Tx) error {
+			if rwtOpts.IdempotencyKey != "" {
+				if err := checkIdempotencyKey(ctx, tx, rwtOpts.IdempotencyKey, rwtOpts.IdempotencyRequestHash); err != nil {
+					return err
+				}
+			}
+
 			longLivedTx := func(context.Context) (pgxcommon.DBReader, common.TxCleanupFunc, error) {
 				return tx, noCleanup, nil
 			}
@@ -257,8 +277,10 @@ func (cds *crdbDatastore) ReadWriteTx(
 					func(query sq.SelectBuilder, fromStr string) sq.SelectBuilder {
 						return query.From(fromStr)
 					},
+					notExpiredClause,
 				},
 				tx,
+				cds.readPool,
 				0,
 			}
 
@@ -274,19 +296,23 @@ func (cds *crdbDatastore) ReadWriteTx(
 				}
 			}
 
+			var err error
 			if cds.disableStats {
-				var err error
 				commitTimestamp, err = readCRDBNow(ctx, tx)
 				if err != nil {
 					return fmt.Errorf("error getting commit timestamp: %w", err)
 				}
-				return nil
+			} else {
+				commitTimestamp, err = updateCounter(ctx, tx, rwt.relCountChange)
+				if err != nil {
+					return fmt.Errorf("error updating relationship counter: %w", err)
+				}
 			}
 
-			var err error
-			commitTimestamp, err = updateCounter(ctx, tx, rwt.relCountChange)
-			if err != nil {
-				return fmt.Errorf("error updating relationship counter: %w", err)
+			if rwtOpts.IdempotencyKey != "" {
+				if _, err := tx.Exec(ctx, queryInsertIdempotencyKey, rwtOpts.IdempotencyKey, commitTimestamp.String(), rwtOpts.IdempotencyRequestHash); err != nil {
+					return fmt.Errorf("error writing idempotency key: %w", err)
+				}
 			}
 
 			return nil
@@ -382,6 +408,25 @@ func (cds *crdbDatastore) Features(ctx context.Context) (*datastore.Features, er
 	return &features, nil
 }
 
+// checkIdempotencyKey returns an ErrIdempotencyKeyAlreadyCommitted if a transaction has already
+// committed with the given key, or an ErrIdempotencyKeyReused if it did so for a different request. CockroachDB does not run the SpiceDB garbage collector, so keys
+// are only removed if row-level TTL is configured on the idempotency key table.
+func checkIdempotencyKey(ctx context.Context, tx pgx.Tx, idempotencyKey string, requestHash string) error {
+	ctx, span := tracer.Start(ctx, "checkIdempotencyKey")
+	defer span.End()
+
+	var committed decimal.Decimal
+	var committedRequestHash string
+	if err := tx.QueryRow(ctx, querySelectIdempotencyKey, idempotencyKey).Scan(&committed, &committedRequestHash); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf(errCheckIdempotencyKey, err)
+	}
+
+	return common.IdempotencyKeyCommittedErr(idempotencyKey, requestHash, committedRequestHash, revision.NewFromDecimal(committed))
+}
+
 func readCRDBNow(ctx context.Context, tx pgx.Tx) (revision.Decimal, error) {
 	ctx, span := tracer.Start(ctx, "readCRDBNow")
 	defer span.End()
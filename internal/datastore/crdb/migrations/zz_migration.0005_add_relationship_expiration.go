@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	addRelationshipExpiration = `ALTER TABLE relation_tuple
+		ADD COLUMN expiration TIMESTAMPTZ;`
+
+	addRelationshipExpirationIndex = `CREATE INDEX IF NOT EXISTS ix_relation_tuple_expired
+		ON relation_tuple (expiration)
+		WHERE expiration IS NOT NULL;`
+)
+
+func init() {
+	err := CRDBMigrations.Register("add-relationship-expiration", "add-caveats", addRelationshipExpirationFunc, noAtomicMigration)
+	if err != nil {
+		panic("failed to register migration: " + err.Error())
+	}
+}
+
+func addRelationshipExpirationFunc(ctx context.Context, conn *pgx.Conn) error {
+	if _, err := conn.Exec(ctx, addRelationshipExpiration); err != nil {
+		return err
+	}
+	if _, err := conn.Exec(ctx, addRelationshipExpirationIndex); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const createIdempotencyKeys = `CREATE TABLE idempotency_key (
+	key VARCHAR(128) PRIMARY KEY,
+	timestamp DECIMAL NOT NULL,
+	request_hash VARCHAR NOT NULL DEFAULT ''
+);`
+
+func init() {
+	if err := CRDBMigrations.Register("add-idempotency-keys", "add-relationship-expiration", noNonAtomicMigration, func(ctx context.Context, tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, createIdempotencyKeys)
+		return err
+	}); err != nil {
+		panic("failed to register migration: " + err.Error())
+	}
+}
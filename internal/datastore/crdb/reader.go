@@ -33,8 +33,14 @@ var (
 		colUsersetRelation,
 		colCaveatContextName,
 		colCaveatContext,
+		colExpiration,
 	)
 
+	// expiredClause and notExpiredClause evaluate expiration against the timestamp of the
+	// current transaction, and are used within read-write transactions.
+	expiredClause    = sq.Expr(colExpiration + " <= now()")
+	notExpiredClause = sq.Or{sq.Eq{colExpiration: nil}, sq.Expr(colExpiration + " > now()")}
+
 	schema = common.NewSchemaInformation(
 		colNamespace,
 		colObjectID,
@@ -43,6 +49,7 @@ var (
 		colUsersetObjectID,
 		colUsersetRelation,
 		colCaveatContextName,
+		colExpiration,
 		common.ExpandedLogicComparison,
 	)
 )
@@ -54,6 +61,7 @@ type crdbReader struct {
 	overlapKeySet keySet
 	execute       executeTxRetryFunc
 	fromBuilder   func(query sq.SelectBuilder, fromStr string) sq.SelectBuilder
+	notExpired    sq.Sqlizer
 }
 
 func (cr *crdbReader) ReadNamespaceByName(
@@ -146,7 +154,7 @@ func (cr *crdbReader) QueryRelationships(
 	filter datastore.RelationshipsFilter,
 	opts ...options.QueryOptionsOption,
 ) (iter datastore.RelationshipIterator, err error) {
-	query := cr.fromBuilder(queryTuples, tableTuple)
+	query := cr.fromBuilder(queryTuples, tableTuple).Where(cr.notExpired)
 	qBuilder, err := common.NewSchemaQueryFilterer(schema, query).FilterWithRelationshipsFilter(filter)
 	if err != nil {
 		return nil, err
@@ -167,7 +175,7 @@ func (cr *crdbReader) ReverseQueryRelationships(
 	subjectsFilter datastore.SubjectsFilter,
 	opts ...options.ReverseQueryOptionsOption,
 ) (iter datastore.RelationshipIterator, err error) {
-	query := cr.fromBuilder(queryTuples, tableTuple)
+	query := cr.fromBuilder(queryTuples, tableTuple).Where(cr.notExpired)
 	qBuilder, err := common.NewSchemaQueryFilterer(schema, query).
 		FilterWithSubjectsSelectors(subjectsFilter.AsSelector())
 	if err != nil {
@@ -187,6 +195,8 @@ func (cr *crdbReader) ReverseQueryRelationships(
 			ctx,
 			qBuilder,
 			options.WithLimit(queryOpts.ReverseLimit),
+			options.WithSort(queryOpts.SortForReverse),
+			options.WithAfter(queryOpts.AfterForReverse),
 		)
 		return err
 	})
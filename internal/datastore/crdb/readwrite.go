@@ -11,9 +11,11 @@ import (
 	"github.com/jzelinskie/stringz"
 	"google.golang.org/protobuf/proto"
 
+	"github.com/authzed/spicedb/internal/datastore/common"
 	pgxcommon "github.com/authzed/spicedb/internal/datastore/postgres/common"
 	log "github.com/authzed/spicedb/internal/logging"
 	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/options"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 )
 
@@ -22,6 +24,7 @@ const (
 	errUnableToDeleteConfig        = "unable to delete namespace config: %w"
 	errUnableToWriteRelationships  = "unable to write relationships: %w"
 	errUnableToDeleteRelationships = "unable to delete relationships: %w"
+	errUnableToCheckChanges        = "unable to check for changed relationships: %w"
 )
 
 var (
@@ -42,12 +45,13 @@ var (
 type crdbReadWriteTXN struct {
 	*crdbReader
 	tx             pgx.Tx
+	readPool       pgxcommon.DBReader
 	relCountChange int64
 }
 
 var (
 	upsertTupleSuffix = fmt.Sprintf(
-		"ON CONFLICT (%s,%s,%s,%s,%s,%s) DO UPDATE SET %s = now(), %s = excluded.%s, %s = excluded.%s",
+		"ON CONFLICT (%s,%s,%s,%s,%s,%s) DO UPDATE SET %s = now(), %s = excluded.%s, %s = excluded.%s, %s = excluded.%s",
 		colNamespace,
 		colObjectID,
 		colRelation,
@@ -59,6 +63,8 @@ var (
 		colCaveatContextName,
 		colCaveatContext,
 		colCaveatContext,
+		colExpiration,
+		colExpiration,
 	)
 
 	queryWriteTuple = psql.Insert(tableTuple).Columns(
@@ -70,12 +76,15 @@ var (
 		colUsersetRelation,
 		colCaveatContextName,
 		colCaveatContext,
+		colExpiration,
 	)
 
 	queryTouchTuple = queryWriteTuple.Suffix(upsertTupleSuffix)
 
 	queryDeleteTuples = psql.Delete(tableTuple)
 
+	queryCountTuples = psql.Select("count(*)")
+
 	queryTouchTransaction = fmt.Sprintf(
 		"INSERT INTO %s (%s) VALUES ($1::text) ON CONFLICT (%s) DO UPDATE SET %s = now()",
 		tableTransactions,
@@ -83,6 +92,22 @@ var (
 		colTransactionKey,
 		colTimestamp,
 	)
+
+	queryInsertIdempotencyKey = fmt.Sprintf(
+		"INSERT INTO %s (%s, %s, %s) VALUES ($1::text, $2::DECIMAL, $3::text)",
+		tableIdempotencyKey,
+		colIdempotencyKey,
+		colTimestamp,
+		colRequestHash,
+	)
+
+	querySelectIdempotencyKey = fmt.Sprintf(
+		"SELECT %s, %s FROM %s WHERE %s = $1::text",
+		colTimestamp,
+		colRequestHash,
+		tableIdempotencyKey,
+		colIdempotencyKey,
+	)
 )
 
 func (rwt *crdbReadWriteTXN) WriteRelationships(ctx context.Context, mutations []*core.RelationTupleUpdate) error {
@@ -92,6 +117,9 @@ func (rwt *crdbReadWriteTXN) WriteRelationships(ctx context.Context, mutations [
 	bulkTouch := queryTouchTuple
 	var bulkTouchCount int64
 
+	// An expired relationship does not prevent the creation of a new one in its place.
+	expiredToReplace := sq.Or{}
+
 	// Process the actual updates
 	for _, mutation := range mutations {
 		rel := mutation.Tuple
@@ -118,6 +146,7 @@ func (rwt *crdbReadWriteTXN) WriteRelationships(ctx context.Context, mutations [
 				rel.Subject.Relation,
 				caveatName,
 				caveatContext,
+				common.ExpirationTimeFrom(rel),
 			)
 			bulkTouchCount++
 		case core.RelationTupleUpdate_CREATE:
@@ -131,8 +160,10 @@ func (rwt *crdbReadWriteTXN) WriteRelationships(ctx context.Context, mutations [
 				rel.Subject.Relation,
 				caveatName,
 				caveatContext,
+				common.ExpirationTimeFrom(rel),
 			)
 			bulkWriteCount++
+			expiredToReplace = append(expiredToReplace, sq.And{exactRelationshipClause(rel), expiredClause})
 		case core.RelationTupleUpdate_DELETE:
 			rwt.relCountChange--
 			sql, args, err := queryDeleteTuples.Where(exactRelationshipClause(rel)).ToSql()
@@ -149,6 +180,19 @@ func (rwt *crdbReadWriteTXN) WriteRelationships(ctx context.Context, mutations [
 		}
 	}
 
+	if len(expiredToReplace) > 0 {
+		sql, args, err := queryDeleteTuples.Where(expiredToReplace).ToSql()
+		if err != nil {
+			return fmt.Errorf(errUnableToWriteRelationships, err)
+		}
+
+		result, err := rwt.tx.Exec(ctx, sql, args...)
+		if err != nil {
+			return fmt.Errorf(errUnableToWriteRelationships, err)
+		}
+		rwt.relCountChange -= result.RowsAffected()
+	}
+
 	bulkUpdateQueries := make([]sq.InsertBuilder, 0, 2)
 	if bulkWriteCount > 0 {
 		bulkUpdateQueries = append(bulkUpdateQueries, bulkWrite)
@@ -177,6 +221,14 @@ func (rwt *crdbReadWriteTXN) WriteRelationships(ctx context.Context, mutations [
 	return nil
 }
 
+// bulkLoadBatchSize is the number of relationships written by each multi-row insert during
+// a bulk load.
+const bulkLoadBatchSize = 1000
+
+func (rwt *crdbReadWriteTXN) BulkLoad(ctx context.Context, iter datastore.BulkWriteRelationshipSource) (uint64, error) {
+	return common.BulkLoadInBatches(ctx, iter, bulkLoadBatchSize, rwt.WriteRelationships)
+}
+
 func exactRelationshipClause(r *core.RelationTuple) sq.Eq {
 	return sq.Eq{
 		colNamespace:        r.ResourceAndRelation.Namespace,
@@ -188,41 +240,111 @@ func exactRelationshipClause(r *core.RelationTuple) sq.Eq {
 	}
 }
 
-func (rwt *crdbReadWriteTXN) DeleteRelationships(ctx context.Context, filter *v1.RelationshipFilter) error {
-	// Add clauses for the ResourceFilter
-	query := queryDeleteTuples.Where(sq.Eq{colNamespace: filter.ResourceType})
-	if filter.OptionalResourceId != "" {
-		query = query.Where(sq.Eq{colObjectID: filter.OptionalResourceId})
-	}
-	if filter.OptionalRelation != "" {
-		query = query.Where(sq.Eq{colRelation: filter.OptionalRelation})
-	}
+func (rwt *crdbReadWriteTXN) DeleteRelationships(ctx context.Context, filter *v1.RelationshipFilter, opts ...options.DeleteOptionsOption) (uint64, bool, error) {
+	query := queryDeleteTuples.Where(relationshipFilterClause(filter))
 	rwt.addOverlapKey(filter.ResourceType)
-
-	// Add clauses for the SubjectFilter
 	if subjectFilter := filter.OptionalSubjectFilter; subjectFilter != nil {
-		query = query.Where(sq.Eq{colUsersetNamespace: subjectFilter.SubjectType})
-		if subjectFilter.OptionalSubjectId != "" {
-			query = query.Where(sq.Eq{colUsersetObjectID: subjectFilter.OptionalSubjectId})
-		}
-		if relationFilter := subjectFilter.OptionalRelation; relationFilter != nil {
-			query = query.Where(sq.Eq{colUsersetRelation: stringz.DefaultEmpty(relationFilter.Relation, datastore.Ellipsis)})
-		}
 		rwt.addOverlapKey(subjectFilter.SubjectType)
 	}
+
+	delOpts := options.NewDeleteOptionsWithOptions(opts...)
+	if delOpts.DeleteLimit != nil {
+		query = query.Limit(*delOpts.DeleteLimit)
+	}
+
 	sql, args, err := query.ToSql()
 	if err != nil {
-		return fmt.Errorf(errUnableToDeleteRelationships, err)
+		return 0, false, fmt.Errorf(errUnableToDeleteRelationships, err)
 	}
 
 	modified, err := rwt.tx.Exec(ctx, sql, args...)
 	if err != nil {
-		return fmt.Errorf(errUnableToDeleteRelationships, err)
+		return 0, false, fmt.Errorf(errUnableToDeleteRelationships, err)
 	}
 
 	rwt.relCountChange -= modified.RowsAffected()
 
-	return nil
+	deleted := uint64(modified.RowsAffected())
+	if delOpts.DeleteLimit == nil || deleted < *delOpts.DeleteLimit {
+		return deleted, false, nil
+	}
+
+	remain, err := common.RelationshipsRemain(ctx, rwt, filter)
+	if err != nil {
+		return 0, false, fmt.Errorf(errUnableToDeleteRelationships, err)
+	}
+
+	return deleted, remain, nil
+}
+
+func (rwt *crdbReadWriteTXN) RelationshipsChangedSince(ctx context.Context, filter *v1.RelationshipFilter, revision datastore.Revision) (bool, error) {
+	filterClause := relationshipFilterClause(filter)
+	rwt.addOverlapKey(filter.ResourceType)
+	if subjectFilter := filter.OptionalSubjectFilter; subjectFilter != nil {
+		rwt.addOverlapKey(subjectFilter.SubjectType)
+	}
+
+	// Relationships written or touched after the revision carry a later MVCC timestamp.
+	currentSQL, currentArgs, err := queryCountTuples.
+		Column(sq.Expr("count(*) FILTER (WHERE crdb_internal_mvcc_timestamp > ?::DECIMAL)", revision.String())).
+		From(tableTuple).
+		Where(filterClause).
+		ToSql()
+	if err != nil {
+		return false, fmt.Errorf(errUnableToCheckChanges, err)
+	}
+
+	var current, changed int64
+	if err := rwt.tx.QueryRow(ctx, currentSQL, currentArgs...).Scan(&current, &changed); err != nil {
+		return false, fmt.Errorf(errUnableToCheckChanges, err)
+	}
+	if changed > 0 {
+		return true, nil
+	}
+
+	// Relationships are deleted in place, so deletions are found by counting the
+	// relationships at the revision: if none have changed since, every current relationship
+	// existed at the revision, and any additional relationships have since been deleted.
+	// Historical reads are not allowed within a transaction, so the count is read outside
+	// of it.
+	historicalSQL, historicalArgs, err := queryCountTuples.
+		From(tableTuple + " AS OF SYSTEM TIME " + revision.String()).
+		Where(filterClause).
+		ToSql()
+	if err != nil {
+		return false, fmt.Errorf(errUnableToCheckChanges, err)
+	}
+
+	var historical int64
+	if err := rwt.readPool.QueryRow(ctx, historicalSQL, historicalArgs...).Scan(&historical); err != nil {
+		return false, fmt.Errorf(errUnableToCheckChanges, err)
+	}
+
+	return historical != current, nil
+}
+
+func relationshipFilterClause(filter *v1.RelationshipFilter) sq.And {
+	// Add clauses for the ResourceFilter
+	clause := sq.And{sq.Eq{colNamespace: filter.ResourceType}}
+	if filter.OptionalResourceId != "" {
+		clause = append(clause, sq.Eq{colObjectID: filter.OptionalResourceId})
+	}
+	if filter.OptionalRelation != "" {
+		clause = append(clause, sq.Eq{colRelation: filter.OptionalRelation})
+	}
+
+	// Add clauses for the SubjectFilter
+	if subjectFilter := filter.OptionalSubjectFilter; subjectFilter != nil {
+		clause = append(clause, sq.Eq{colUsersetNamespace: subjectFilter.SubjectType})
+		if subjectFilter.OptionalSubjectId != "" {
+			clause = append(clause, sq.Eq{colUsersetObjectID: subjectFilter.OptionalSubjectId})
+		}
+		if relationFilter := subjectFilter.OptionalRelation; relationFilter != nil {
+			clause = append(clause, sq.Eq{colUsersetRelation: stringz.DefaultEmpty(relationFilter.Relation, datastore.Ellipsis)})
+		}
+	}
+
+	return clause
 }
 
 func (rwt *crdbReadWriteTXN) WriteNamespaces(ctx context.Context, newConfigs ...*core.NamespaceDefinition) error {
@@ -2,10 +2,12 @@ package crdb
 
 import (
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -26,10 +28,14 @@ type changeDetails struct {
 	After    *struct {
 		CaveatContext map[string]any `json:"caveat_context"`
 		CaveatName    string         `json:"caveat_name"`
+		Expiration    *time.Time     `json:"expiration"`
+
+		NamespaceConfig  string `json:"serialized_config"`
+		CaveatDefinition string `json:"definition"`
 	}
 }
 
-func (cds *crdbDatastore) Watch(ctx context.Context, afterRevision datastore.Revision) (<-chan *datastore.RevisionChanges, <-chan error) {
+func (cds *crdbDatastore) Watch(ctx context.Context, afterRevision datastore.Revision, options datastore.WatchOptions) (<-chan *datastore.RevisionChanges, <-chan error) {
 	updates := make(chan *datastore.RevisionChanges, cds.watchBufferLength)
 	errs := make(chan error, 1)
 
@@ -55,7 +61,8 @@ func (cds *crdbDatastore) Watch(ctx context.Context, afterRevision datastore.Rev
 		return updates, errs
 	}
 
-	interpolated := fmt.Sprintf(cds.beginChangefeedQuery, tableTuple, afterRevision)
+	watchedTables := strings.Join([]string{tableTuple, tableNamespace, tableCaveat}, ", ")
+	interpolated := fmt.Sprintf(cds.beginChangefeedQuery, watchedTables, afterRevision)
 
 	go func() {
 		defer close(updates)
@@ -78,11 +85,11 @@ func (cds *crdbDatastore) Watch(ctx context.Context, afterRevision datastore.Rev
 		defer func() { go changes.Close() }()
 
 		for changes.Next() {
-			var unused interface{}
+			var tableNameBytes []byte
 			var changeJSON []byte
 			var primaryKeyValuesJSON []byte
 
-			if err := changes.Scan(&unused, &primaryKeyValuesJSON, &changeJSON); err != nil {
+			if err := changes.Scan(&tableNameBytes, &primaryKeyValuesJSON, &changeJSON); err != nil {
 				if errors.Is(ctx.Err(), context.Canceled) {
 					errs <- datastore.NewWatchCanceledErr()
 				} else {
@@ -105,9 +112,11 @@ func (cds *crdbDatastore) Watch(ctx context.Context, afterRevision datastore.Rev
 					return
 				}
 
+				// A resolved timestamp guarantees that no further changes at or before it
+				// will be received.
 				var toEmit []*datastore.RevisionChanges
 				for ts, values := range pendingChanges {
-					if resolved.GreaterThan(values.Revision) {
+					if !values.Revision.GreaterThan(resolved) {
 						delete(pendingChanges, ts)
 
 						toEmit = append(toEmit, values)
@@ -127,63 +136,90 @@ func (cds *crdbDatastore) Watch(ctx context.Context, afterRevision datastore.Rev
 					}
 				}
 
+				common.SendCheckpoint(updates, resolved)
 				continue
 			}
 
-			var pkValues [6]string
-			if err := json.Unmarshal(primaryKeyValuesJSON, &pkValues); err != nil {
-				errs <- err
-				return
-			}
-
 			revision, err := cds.RevisionFromString(details.Updated)
 			if err != nil {
 				errs <- fmt.Errorf("malformed update timestamp: %w", err)
 				return
 			}
 
-			var caveatName string
-			var caveatContext map[string]any
-			if details.After != nil && details.After.CaveatName != "" {
-				caveatName = details.After.CaveatName
-				caveatContext = details.After.CaveatContext
-			}
-			ctxCaveat, err := common.ContextualizedCaveatFrom(caveatName, caveatContext)
-			if err != nil {
-				errs <- err
-				return
-			}
+			// Changefeeds cannot be filtered by the relationships filters, so changes to
+			// relationships not matching them are skipped here, before being staged.
+			if string(tableNameBytes) == tableTuple {
+				oneChange, err := tupleChangeFrom(primaryKeyValuesJSON, details)
+				if err != nil {
+					errs <- err
+					return
+				}
 
-			oneChange := &core.RelationTupleUpdate{
-				Tuple: &core.RelationTuple{
-					ResourceAndRelation: &core.ObjectAndRelation{
-						Namespace: pkValues[0],
-						ObjectId:  pkValues[1],
-						Relation:  pkValues[2],
-					},
-					Subject: &core.ObjectAndRelation{
-						Namespace: pkValues[3],
-						ObjectId:  pkValues[4],
-						Relation:  pkValues[5],
-					},
-					Caveat: ctxCaveat,
-				},
+				if options.MatchesRelationship(oneChange.Tuple) {
+					pending := pendingAt(pendingChanges, details.Updated, revision)
+					pending.Changes = append(pending.Changes, oneChange)
+				}
+				continue
 			}
 
-			if details.After == nil {
-				oneChange.Operation = core.RelationTupleUpdate_DELETE
-			} else {
-				oneChange.Operation = core.RelationTupleUpdate_TOUCH
-			}
+			pending := pendingAt(pendingChanges, details.Updated, revision)
+
+			switch tableName := string(tableNameBytes); tableName {
+
+			case tableNamespace:
+				var pkValues [1]string
+				if err := json.Unmarshal(primaryKeyValuesJSON, &pkValues); err != nil {
+					errs <- err
+					return
+				}
+
+				if details.After == nil {
+					pending.DeletedNamespaces = append(pending.DeletedNamespaces, pkValues[0])
+					continue
+				}
 
-			pending, ok := pendingChanges[details.Updated]
-			if !ok {
-				pending = &datastore.RevisionChanges{
-					Revision: revision,
+				defBytes, err := decodeChangefeedBytes(details.After.NamespaceConfig)
+				if err != nil {
+					errs <- fmt.Errorf("malformed namespace config: %w", err)
+					return
+				}
+
+				loaded := &core.NamespaceDefinition{}
+				if err := loaded.UnmarshalVT(defBytes); err != nil {
+					errs <- fmt.Errorf(errUnableToReadConfig, err)
+					return
 				}
-				pendingChanges[details.Updated] = pending
+				pending.ChangedDefinitions = append(pending.ChangedDefinitions, loaded)
+
+			case tableCaveat:
+				var pkValues [1]string
+				if err := json.Unmarshal(primaryKeyValuesJSON, &pkValues); err != nil {
+					errs <- err
+					return
+				}
+
+				if details.After == nil {
+					pending.DeletedCaveats = append(pending.DeletedCaveats, pkValues[0])
+					continue
+				}
+
+				defBytes, err := decodeChangefeedBytes(details.After.CaveatDefinition)
+				if err != nil {
+					errs <- fmt.Errorf("malformed caveat definition: %w", err)
+					return
+				}
+
+				loaded := &core.CaveatDefinition{}
+				if err := loaded.UnmarshalVT(defBytes); err != nil {
+					errs <- fmt.Errorf(errReadCaveat, pkValues[0], err)
+					return
+				}
+				pending.ChangedDefinitions = append(pending.ChangedDefinitions, loaded)
+
+			default:
+				errs <- fmt.Errorf("unexpected table in changefeed: %s", tableName)
+				return
 			}
-			pending.Changes = append(pending.Changes, oneChange)
 		}
 
 		if changes.Err() != nil {
@@ -202,3 +238,68 @@ func (cds *crdbDatastore) Watch(ctx context.Context, afterRevision datastore.Rev
 	}()
 	return updates, errs
 }
+
+// pendingAt returns the pending changes staged for the given changefeed timestamp,
+// staging them if necessary.
+func pendingAt(pendingChanges map[string]*datastore.RevisionChanges, timestamp string, revision datastore.Revision) *datastore.RevisionChanges {
+	pending, ok := pendingChanges[timestamp]
+	if !ok {
+		pending = &datastore.RevisionChanges{
+			Revision: revision,
+		}
+		pendingChanges[timestamp] = pending
+	}
+	return pending
+}
+
+func tupleChangeFrom(primaryKeyValuesJSON []byte, details changeDetails) (*core.RelationTupleUpdate, error) {
+	var pkValues [6]string
+	if err := json.Unmarshal(primaryKeyValuesJSON, &pkValues); err != nil {
+		return nil, err
+	}
+
+	var caveatName string
+	var caveatContext map[string]any
+	if details.After != nil && details.After.CaveatName != "" {
+		caveatName = details.After.CaveatName
+		caveatContext = details.After.CaveatContext
+	}
+	ctxCaveat, err := common.ContextualizedCaveatFrom(caveatName, caveatContext)
+	if err != nil {
+		return nil, err
+	}
+
+	oneChange := &core.RelationTupleUpdate{
+		Tuple: &core.RelationTuple{
+			ResourceAndRelation: &core.ObjectAndRelation{
+				Namespace: pkValues[0],
+				ObjectId:  pkValues[1],
+				Relation:  pkValues[2],
+			},
+			Subject: &core.ObjectAndRelation{
+				Namespace: pkValues[3],
+				ObjectId:  pkValues[4],
+				Relation:  pkValues[5],
+			},
+			Caveat: ctxCaveat,
+		},
+	}
+
+	if details.After != nil {
+		oneChange.Tuple.OptionalExpirationTime = common.ExpirationTimestampFrom(details.After.Expiration)
+	}
+
+	if details.After == nil {
+		oneChange.Operation = core.RelationTupleUpdate_DELETE
+	} else {
+		oneChange.Operation = core.RelationTupleUpdate_TOUCH
+	}
+
+	return oneChange, nil
+}
+
+// decodeChangefeedBytes decodes a BYTES column value, which changefeeds encode
+// in JSON as a hex string with a `\x` prefix.
+func decodeChangefeedBytes(encoded string) ([]byte, error) {
+	return hex.DecodeString(strings.TrimPrefix(encoded, `\x`))
+}
@@ -13,7 +13,9 @@ import (
 	"github.com/hashicorp/go-memdb"
 	"github.com/shopspring/decimal"
 
+	"github.com/authzed/spicedb/internal/datastore/common"
 	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/options"
 	"github.com/authzed/spicedb/pkg/datastore/revision"
 	corev1 "github.com/authzed/spicedb/pkg/proto/core/v1"
 )
@@ -100,11 +102,11 @@ func (mdb *memdbDatastore) SnapshotReader(revisionRaw datastore.Revision) datast
 	defer mdb.RUnlock()
 
 	if len(mdb.revisions) == 0 {
-		return &memdbReader{nil, nil, fmt.Errorf("memdb datastore is not ready")}
+		return &memdbReader{nil, nil, fmt.Errorf("memdb datastore is not ready"), time.Time{}}
 	}
 
 	if err := mdb.checkRevisionLocalCallerMustLock(dr); err != nil {
-		return &memdbReader{nil, nil, err}
+		return &memdbReader{nil, nil, err, time.Time{}}
 	}
 
 	revIndex := sort.Search(len(mdb.revisions), func(i int) bool {
@@ -118,7 +120,7 @@ func (mdb *memdbDatastore) SnapshotReader(revisionRaw datastore.Revision) datast
 
 	rev := mdb.revisions[revIndex]
 	if rev.db == nil {
-		return &memdbReader{nil, nil, fmt.Errorf("memdb datastore is already closed")}
+		return &memdbReader{nil, nil, fmt.Errorf("memdb datastore is already closed"), time.Time{}}
 	}
 
 	roTxn := rev.db.Txn(false)
@@ -126,13 +128,16 @@ func (mdb *memdbDatastore) SnapshotReader(revisionRaw datastore.Revision) datast
 		return roTxn, nil
 	}
 
-	return &memdbReader{noopTryLocker{}, txSrc, nil}
+	return &memdbReader{noopTryLocker{}, txSrc, nil, timeFromRevision(dr)}
 }
 
 func (mdb *memdbDatastore) ReadWriteTx(
 	_ context.Context,
 	f datastore.TxUserFunc,
+	opts ...options.RWTOptionsOption,
 ) (datastore.Revision, error) {
+	rwtOpts := options.NewRWTOptionsWithOptions(opts...)
+
 	for i := 0; i < numRetries; i++ {
 		var tx *memdb.Txn
 		createTxOnce := sync.Once{}
@@ -161,8 +166,14 @@ func (mdb *memdbDatastore) ReadWriteTx(
 		}
 
 		newRevision := mdb.newRevisionID()
-		rwt := &memdbReadWriteTx{memdbReader{&sync.Mutex{}, txSrc, nil}, newRevision}
-		if err := f(rwt); err != nil {
+		rwt := &memdbReadWriteTx{memdbReader{&sync.Mutex{}, txSrc, nil, timeFromRevision(newRevision)}, newRevision}
+
+		txFunc := f
+		if rwtOpts.IdempotencyKey != "" {
+			txFunc = mdb.idempotentTxFunc(txSrc, rwtOpts, newRevision, f)
+		}
+
+		if err := txFunc(rwt); err != nil {
 			mdb.Lock()
 			if tx != nil {
 				tx.Abort()
@@ -194,7 +205,8 @@ func (mdb *memdbDatastore) ReadWriteTx(
 		}
 		if tx != nil {
 			for _, change := range tx.Changes() {
-				if change.Table == tableRelationship {
+				switch change.Table {
+				case tableRelationship:
 					if change.After != nil {
 						rt, err := change.After.(*relationship).RelationTuple()
 						if err != nil {
@@ -215,6 +227,30 @@ func (mdb *memdbDatastore) ReadWriteTx(
 							Tuple:     rt,
 						})
 					}
+
+				case tableNamespace:
+					if change.After != nil {
+						loaded := &corev1.NamespaceDefinition{}
+						if err := loaded.UnmarshalVT(change.After.(*namespace).configBytes); err != nil {
+							return datastore.NoRevision, err
+						}
+						newChanges.ChangedDefinitions = append(newChanges.ChangedDefinitions, loaded)
+					}
+					if change.After == nil && change.Before != nil {
+						newChanges.DeletedNamespaces = append(newChanges.DeletedNamespaces, change.Before.(*namespace).name)
+					}
+
+				case tableCaveats:
+					if change.After != nil {
+						loaded, err := change.After.(*caveat).Unwrap()
+						if err != nil {
+							return datastore.NoRevision, err
+						}
+						newChanges.ChangedDefinitions = append(newChanges.ChangedDefinitions, loaded)
+					}
+					if change.After == nil && change.Before != nil {
+						newChanges.DeletedCaveats = append(newChanges.DeletedCaveats, change.Before.(*caveat).name)
+					}
 				}
 			}
 
@@ -243,6 +279,52 @@ func (mdb *memdbDatastore) ReadWriteTx(
 	return datastore.NoRevision, errors.New("serialization max retries exceeded")
 }
 
+// idempotentTxFunc wraps the transaction function to run only if no transaction with the
+// idempotency key has committed within the GC window, recording the key and request hash for the
+// new revision.
+func (mdb *memdbDatastore) idempotentTxFunc(
+	txSrc func() (*memdb.Txn, error),
+	rwtOpts *options.RWTOptions,
+	newRevision revision.Decimal,
+	f datastore.TxUserFunc,
+) datastore.TxUserFunc {
+	return func(rwt datastore.ReadWriteTransaction) error {
+		tx, err := txSrc()
+		if err != nil {
+			return err
+		}
+
+		key := rwtOpts.IdempotencyKey
+		foundRaw, err := tx.First(tableIdempotencyKey, indexID, key)
+		if err != nil {
+			return fmt.Errorf("error reading idempotency key: %w", err)
+		}
+
+		if foundRaw != nil {
+			committed := foundRaw.(*idempotencyKey)
+
+			// Expired keys are not removed, as memdb does not garbage collect, so they are
+			// ignored and replaced instead.
+			mdb.RLock()
+			expired := mdb.revisionOutsideGCWindow(revisionFromTimestamp(time.Now().UTC()), committed.revision)
+			mdb.RUnlock()
+
+			if !expired {
+				return common.IdempotencyKeyCommittedErr(key, rwtOpts.IdempotencyRequestHash, committed.requestHash, committed.revision)
+			}
+		}
+
+		if err := f(rwt); err != nil {
+			return err
+		}
+
+		if err := tx.Insert(tableIdempotencyKey, &idempotencyKey{key, rwtOpts.IdempotencyRequestHash, newRevision}); err != nil {
+			return fmt.Errorf("error writing idempotency key: %w", err)
+		}
+		return nil
+	}
+}
+
 func (mdb *memdbDatastore) ReadyState(_ context.Context) (datastore.ReadyState, error) {
 	mdb.RLock()
 	defer mdb.RUnlock()
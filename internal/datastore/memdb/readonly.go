@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"runtime"
+	"time"
 
 	"github.com/hashicorp/go-memdb"
 	"github.com/jzelinskie/stringz"
@@ -19,6 +20,9 @@ type memdbReader struct {
 	TryLocker
 	txSource txFactory
 	initErr  error
+
+	// readTime is the time at which the expiration of relationships is evaluated.
+	readTime time.Time
 }
 
 // QueryRelationships reads relationships starting from the resource side.
@@ -56,12 +60,14 @@ func (r *memdbReader) QueryRelationships(
 		filter.OptionalResourceRelation,
 		filter.OptionalSubjectsSelectors,
 		filter.OptionalCaveatName,
+		filter.OnlyWithExpiration,
 		queryOpts.Usersets,
 		makeCursorFilterFn(queryOpts.After, queryOpts.Sort),
 	)
 	filteredIterator := memdb.NewFilterIterator(bestIterator, matchingRelationshipsFilterFunc)
+	unexpiredIterator := memdb.NewFilterIterator(filteredIterator, filterExpiredAt(r.readTime))
 
-	iter := newMemdbTupleIterator(filteredIterator, queryOpts.Limit, queryOpts.Sort)
+	iter := newMemdbTupleIterator(unexpiredIterator, queryOpts.Limit, queryOpts.Sort)
 	return iter, nil
 }
 
@@ -91,6 +97,12 @@ func (r *memdbReader) ReverseQueryRelationships(
 
 	queryOpts := options.NewReverseQueryOptionsWithOptions(opts...)
 
+	if queryOpts.AfterForReverse != nil && queryOpts.SortForReverse == options.Unsorted {
+		return nil, datastore.ErrCursorsWithoutSorting
+	}
+
+	// NOTE: the subject namespace index is non-unique, so entries for a single subject type are
+	// ordered by the ID index, which matches the ByResource sort order.
 	iterator, err := tx.Get(
 		tableRelationship,
 		indexSubjectNamespace,
@@ -112,12 +124,14 @@ func (r *memdbReader) ReverseQueryRelationships(
 		filterRelation,
 		[]datastore.SubjectsSelector{subjectsFilter.AsSelector()},
 		"",
+		false,
 		nil,
-		noopCursorFilter,
+		makeCursorFilterFn(queryOpts.AfterForReverse, queryOpts.SortForReverse),
 	)
 	filteredIterator := memdb.NewFilterIterator(iterator, matchingRelationshipsFilterFunc)
+	unexpiredIterator := memdb.NewFilterIterator(filteredIterator, filterExpiredAt(r.readTime))
 
-	return newMemdbTupleIterator(filteredIterator, queryOpts.ReverseLimit, options.Unsorted), nil
+	return newMemdbTupleIterator(unexpiredIterator, queryOpts.ReverseLimit, queryOpts.SortForReverse), nil
 }
 
 // ReadNamespace reads a namespace definition and version and returns it, and the revision at
@@ -268,6 +282,7 @@ func filterFuncForFilters(
 	optionalRelation string,
 	optionalSubjectsSelectors []datastore.SubjectsSelector,
 	optionalCaveatFilter string,
+	onlyWithExpiration bool,
 	usersets []*core.ObjectAndRelation,
 	cursorFilter func(*relationship) bool,
 ) memdb.FilterFunc {
@@ -283,6 +298,8 @@ func filterFuncForFilters(
 			return true
 		case optionalCaveatFilter != "" && (tuple.caveat == nil || tuple.caveat.caveatName != optionalCaveatFilter):
 			return true
+		case onlyWithExpiration && tuple.expiration == nil:
+			return true
 		}
 
 		applySubjectSelector := func(selector datastore.SubjectsSelector) bool {
@@ -340,6 +357,13 @@ func filterFuncForFilters(
 	}
 }
 
+// filterExpiredAt returns a filter that removes relationships which have expired at the given time.
+func filterExpiredAt(at time.Time) memdb.FilterFunc {
+	return func(tupleRaw interface{}) bool {
+		return tupleRaw.(*relationship).isExpiredAt(at)
+	}
+}
+
 func makeCursorFilterFn(after *core.RelationTuple, order options.SortOrder) func(tpl *relationship) bool {
 	if after != nil {
 		switch order {
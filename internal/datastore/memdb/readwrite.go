@@ -11,6 +11,8 @@ import (
 
 	"github.com/authzed/spicedb/internal/datastore/common"
 	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/options"
+	"github.com/authzed/spicedb/pkg/datastore/revision"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 	"github.com/authzed/spicedb/pkg/tuple"
 )
@@ -44,6 +46,7 @@ func (rwt *memdbReadWriteTx) write(tx *memdb.Txn, mutations ...*core.RelationTup
 			mutation.Tuple.Subject.ObjectId,
 			mutation.Tuple.Subject.Relation,
 			rwt.toCaveatReference(mutation),
+			common.ExpirationTimeFrom(mutation.Tuple),
 		}
 
 		found, err := tx.First(
@@ -67,7 +70,8 @@ func (rwt *memdbReadWriteTx) write(tx *memdb.Txn, mutations ...*core.RelationTup
 
 		switch mutation.Operation {
 		case core.RelationTupleUpdate_CREATE:
-			if existing != nil {
+			// Expired relationships are treated as absent, and are replaced.
+			if existing != nil && !existing.isExpiredAt(rwt.readTime) {
 				rt, err := existing.RelationTuple()
 				if err != nil {
 					return err
@@ -93,6 +97,35 @@ func (rwt *memdbReadWriteTx) write(tx *memdb.Txn, mutations ...*core.RelationTup
 	return nil
 }
 
+func (rwt *memdbReadWriteTx) BulkLoad(ctx context.Context, iter datastore.BulkWriteRelationshipSource) (uint64, error) {
+	rwt.mustLock()
+	defer rwt.Unlock()
+
+	tx, err := rwt.txSource()
+	if err != nil {
+		return 0, err
+	}
+
+	var numWritten uint64
+	update := &core.RelationTupleUpdate{Operation: core.RelationTupleUpdate_CREATE}
+	for {
+		tpl, err := iter.Next(ctx)
+		if err != nil {
+			return numWritten, err
+		}
+		if tpl == nil {
+			return numWritten, nil
+		}
+
+		// The relationship's fields are copied on write, so the tuple can be reused by the source.
+		update.Tuple = tpl
+		if err := rwt.write(tx, update); err != nil {
+			return numWritten, err
+		}
+		numWritten++
+	}
+}
+
 func (rwt *memdbReadWriteTx) toCaveatReference(mutation *core.RelationTupleUpdate) *contextualizedCaveat {
 	var cr *contextualizedCaveat
 	if mutation.Tuple.Caveat != nil {
@@ -104,38 +137,75 @@ func (rwt *memdbReadWriteTx) toCaveatReference(mutation *core.RelationTupleUpdat
 	return cr
 }
 
-func (rwt *memdbReadWriteTx) DeleteRelationships(_ context.Context, filter *v1.RelationshipFilter) error {
+func (rwt *memdbReadWriteTx) DeleteRelationships(_ context.Context, filter *v1.RelationshipFilter, opts ...options.DeleteOptionsOption) (uint64, bool, error) {
 	rwt.mustLock()
 	defer rwt.Unlock()
 
 	tx, err := rwt.txSource()
 	if err != nil {
-		return err
+		return 0, false, err
 	}
 
-	return rwt.deleteWithLock(tx, filter)
+	delOpts := options.NewDeleteOptionsWithOptions(opts...)
+	return rwt.deleteWithLock(tx, filter, delOpts.DeleteLimit)
 }
 
 // caller must already hold the concurrent access lock
-func (rwt *memdbReadWriteTx) deleteWithLock(tx *memdb.Txn, filter *v1.RelationshipFilter) error {
+func (rwt *memdbReadWriteTx) deleteWithLock(tx *memdb.Txn, filter *v1.RelationshipFilter, limit *uint64) (uint64, bool, error) {
 	// Create an iterator to find the relevant tuples
 	bestIter, err := iteratorForFilter(tx, datastore.RelationshipsFilterFromPublicFilter(filter))
 	if err != nil {
-		return err
+		return 0, false, err
 	}
 	filteredIter := memdb.NewFilterIterator(bestIter, relationshipFilterFilterFunc(filter))
 
 	// Collect the tuples into a slice of mutations for the changelog
 	var mutations []*core.RelationTupleUpdate
+	limitReached := false
 	for row := filteredIter.Next(); row != nil; row = filteredIter.Next() {
+		if limit != nil && uint64(len(mutations)) == *limit {
+			limitReached = true
+			break
+		}
+
 		rt, err := row.(*relationship).RelationTuple()
 		if err != nil {
-			return err
+			return 0, false, err
 		}
 		mutations = append(mutations, tuple.Delete(rt))
 	}
 
-	return rwt.write(tx, mutations...)
+	if err := rwt.write(tx, mutations...); err != nil {
+		return 0, false, err
+	}
+
+	return uint64(len(mutations)), limitReached, nil
+}
+
+func (rwt *memdbReadWriteTx) RelationshipsChangedSince(_ context.Context, filter *v1.RelationshipFilter, rev datastore.Revision) (bool, error) {
+	rwt.mustLock()
+	defer rwt.Unlock()
+
+	tx, err := rwt.txSource()
+	if err != nil {
+		return false, err
+	}
+
+	it, err := tx.LowerBound(tableChangelog, indexRevision, rev.(revision.Decimal).IntPart()+1)
+	if err != nil {
+		return false, err
+	}
+
+	dsFilter := datastore.RelationshipsFilterFromPublicFilter(filter)
+	for changeRaw := it.Next(); changeRaw != nil; changeRaw = it.Next() {
+		for _, change := range changeRaw.(*changelog).changes.Changes {
+			if dsFilter.Test(change.Tuple) {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
 }
 
 func (rwt *memdbReadWriteTx) WriteNamespaces(_ context.Context, newConfigs ...*core.NamespaceDefinition) error {
@@ -188,9 +258,9 @@ func (rwt *memdbReadWriteTx) DeleteNamespaces(_ context.Context, nsNames ...stri
 		}
 
 		// Delete the relationships from the namespace
-		if err := rwt.deleteWithLock(tx, &v1.RelationshipFilter{
+		if _, _, err := rwt.deleteWithLock(tx, &v1.RelationshipFilter{
 			ResourceType: nsName,
-		}); err != nil {
+		}, nil); err != nil {
 			return fmt.Errorf("unable to delete relationships from deleted namespace: %w", err)
 		}
 	}
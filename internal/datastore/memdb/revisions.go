@@ -14,6 +14,10 @@ func revisionFromTimestamp(t time.Time) revision.Decimal {
 	return revision.NewFromDecimal(decimal.NewFromInt(t.UnixNano()))
 }
 
+func timeFromRevision(rev revision.Decimal) time.Time {
+	return time.Unix(0, rev.IntPart())
+}
+
 func (mdb *memdbDatastore) newRevisionID() revision.Decimal {
 	mdb.Lock()
 	defer mdb.Unlock()
@@ -1,13 +1,17 @@
 package memdb
 
 import (
+	"time"
+
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
 	"github.com/hashicorp/go-memdb"
 	"github.com/jzelinskie/stringz"
 	"github.com/rs/zerolog"
 	"google.golang.org/protobuf/types/known/structpb"
 
+	"github.com/authzed/spicedb/internal/datastore/common"
 	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/revision"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 )
 
@@ -22,6 +26,8 @@ const (
 
 	tableChangelog = "changelog"
 	indexRevision  = "id"
+
+	tableIdempotencyKey = "idempotencyKey"
 )
 
 type namespace struct {
@@ -42,6 +48,7 @@ type relationship struct {
 	subjectObjectID  string
 	subjectRelation  string
 	caveat           *contextualizedCaveat
+	expiration       *time.Time
 }
 
 type contextualizedCaveat struct {
@@ -69,7 +76,17 @@ func (r relationship) String() string {
 		caveat = "[" + r.caveat.caveatName + "]"
 	}
 
-	return r.namespace + ":" + r.resourceID + "#" + r.relation + "@" + r.subjectNamespace + ":" + r.subjectObjectID + "#" + r.subjectRelation + caveat
+	expiration := ""
+	if r.expiration != nil {
+		expiration = "[expiration:" + r.expiration.Format(time.RFC3339Nano) + "]"
+	}
+
+	return r.namespace + ":" + r.resourceID + "#" + r.relation + "@" + r.subjectNamespace + ":" + r.subjectObjectID + "#" + r.subjectRelation + caveat + expiration
+}
+
+// isExpiredAt returns true iff the relationship has an expiration at or before the given time.
+func (r relationship) isExpiredAt(at time.Time) bool {
+	return r.expiration != nil && !r.expiration.After(at)
 }
 
 func (r relationship) MarshalZerologObject(e *zerolog.Event) {
@@ -98,6 +115,7 @@ func (r relationship) RelationTuple() (*core.RelationTuple, error) {
 	if err != nil {
 		return nil, err
 	}
+
 	return &core.RelationTuple{
 		ResourceAndRelation: &core.ObjectAndRelation{
 			Namespace: r.namespace,
@@ -109,7 +127,8 @@ func (r relationship) RelationTuple() (*core.RelationTuple, error) {
 			ObjectId:  r.subjectObjectID,
 			Relation:  r.subjectRelation,
 		},
-		Caveat: cr,
+		Caveat:                 cr,
+		OptionalExpirationTime: common.ExpirationTimestampFrom(r.expiration),
 	}, nil
 }
 
@@ -118,6 +137,12 @@ type changelog struct {
 	changes       datastore.RevisionChanges
 }
 
+type idempotencyKey struct {
+	key         string
+	requestHash string
+	revision    revision.Decimal
+}
+
 var schema = &memdb.DBSchema{
 	Tables: map[string]*memdb.TableSchema{
 		tableNamespace: {
@@ -179,6 +204,16 @@ var schema = &memdb.DBSchema{
 				},
 			},
 		},
+		tableIdempotencyKey: {
+			Name: tableIdempotencyKey,
+			Indexes: map[string]*memdb.IndexSchema{
+				indexID: {
+					Name:    indexID,
+					Unique:  true,
+					Indexer: &memdb.StringFieldIndex{Field: "key"},
+				},
+			},
+		},
 		tableCaveats: {
 			Name: tableCaveats,
 			Indexes: map[string]*memdb.IndexSchema{
@@ -7,13 +7,14 @@ import (
 
 	"github.com/hashicorp/go-memdb"
 
+	"github.com/authzed/spicedb/internal/datastore/common"
 	"github.com/authzed/spicedb/pkg/datastore"
 	"github.com/authzed/spicedb/pkg/datastore/revision"
 )
 
 const errWatchError = "watch error: %w"
 
-func (mdb *memdbDatastore) Watch(ctx context.Context, afterRevision datastore.Revision) (<-chan *datastore.RevisionChanges, <-chan error) {
+func (mdb *memdbDatastore) Watch(ctx context.Context, afterRevision datastore.Revision, options datastore.WatchOptions) (<-chan *datastore.RevisionChanges, <-chan error) {
 	ar := afterRevision.(revision.Decimal)
 
 	updates := make(chan *datastore.RevisionChanges, mdb.watchBufferLength)
@@ -27,9 +28,10 @@ func (mdb *memdbDatastore) Watch(ctx context.Context, afterRevision datastore.Re
 
 		for {
 			var stagedUpdates []*datastore.RevisionChanges
+			var checkpoint datastore.Revision
 			var watchChan <-chan struct{}
 			var err error
-			stagedUpdates, currentTxn, watchChan, err = mdb.loadChanges(ctx, currentTxn)
+			stagedUpdates, checkpoint, currentTxn, watchChan, err = mdb.loadChanges(ctx, currentTxn, options)
 			if err != nil {
 				errs <- err
 				return
@@ -45,6 +47,10 @@ func (mdb *memdbDatastore) Watch(ctx context.Context, afterRevision datastore.Re
 				}
 			}
 
+			if checkpoint != nil {
+				common.SendCheckpoint(updates, checkpoint)
+			}
+
 			// Wait for new changes
 			ws := memdb.NewWatchSet()
 			ws.Add(watchChan)
@@ -65,7 +71,9 @@ func (mdb *memdbDatastore) Watch(ctx context.Context, afterRevision datastore.Re
 	return updates, errs
 }
 
-func (mdb *memdbDatastore) loadChanges(_ context.Context, currentTxn int64) ([]*datastore.RevisionChanges, int64, <-chan struct{}, error) {
+// loadChanges loads the changes committed after currentTxn, limited to those matching the
+// options, along with the revision through which changes have been loaded, if any.
+func (mdb *memdbDatastore) loadChanges(_ context.Context, currentTxn int64, options datastore.WatchOptions) ([]*datastore.RevisionChanges, datastore.Revision, int64, <-chan struct{}, error) {
 	mdb.RLock()
 	defer mdb.RUnlock()
 
@@ -74,21 +82,30 @@ func (mdb *memdbDatastore) loadChanges(_ context.Context, currentTxn int64) ([]*
 
 	it, err := loadNewTxn.LowerBound(tableChangelog, indexRevision, currentTxn+1)
 	if err != nil {
-		return nil, 0, nil, fmt.Errorf(errWatchError, err)
+		return nil, nil, 0, nil, fmt.Errorf(errWatchError, err)
 	}
 
 	var changes []*datastore.RevisionChanges
+	var checkpoint datastore.Revision
 	lastRevision := currentTxn
 	for changeRaw := it.Next(); changeRaw != nil; changeRaw = it.Next() {
 		change := changeRaw.(*changelog)
-		changes = append(changes, &change.changes)
 		lastRevision = change.revisionNanos
+		checkpoint = change.changes.Revision
+
+		filtered := change.changes
+		filtered.Changes = options.FilterChanges(change.changes.Changes)
+		if len(filtered.Changes) == 0 && len(filtered.ChangedDefinitions) == 0 &&
+			len(filtered.DeletedNamespaces) == 0 && len(filtered.DeletedCaveats) == 0 {
+			continue
+		}
+		changes = append(changes, &filtered)
 	}
 
 	watchChan, _, err := loadNewTxn.LastWatch(tableChangelog, indexRevision)
 	if err != nil {
-		return nil, 0, nil, fmt.Errorf(errWatchError, err)
+		return nil, nil, 0, nil, fmt.Errorf(errWatchError, err)
 	}
 
-	return changes, lastRevision, watchChan, nil
+	return changes, checkpoint, lastRevision, watchChan, nil
 }
@@ -25,6 +25,7 @@ import (
 	"github.com/authzed/spicedb/internal/datastore/proxy"
 	log "github.com/authzed/spicedb/internal/logging"
 	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/options"
 	"github.com/authzed/spicedb/pkg/datastore/revision"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 )
@@ -47,6 +48,9 @@ const (
 	colCaveatDefinition = "definition"
 	colCaveatName       = "caveat_name"
 	colCaveatContext    = "caveat_context"
+	colExpiration       = "expiration"
+	colIdempotencyKey   = "idempotency_key"
+	colRequestHash      = "idempotency_request_hash"
 
 	errUnableToInstantiate = "unable to instantiate datastore: %w"
 	liveDeletedTxnID       = uint64(math.MaxInt64)
@@ -271,6 +275,7 @@ func (mds *Datastore) SnapshotReader(revisionRaw datastore.Revision) datastore.R
 		createTxFunc,
 		querySplitter,
 		buildLivingObjectFilterForRevision(rev),
+		unexpiredAtTransaction(mds.driver.RelationTupleTransaction(), transactionFromRevision(rev)),
 	}
 }
 
@@ -282,12 +287,21 @@ func noCleanup() error { return nil }
 func (mds *Datastore) ReadWriteTx(
 	ctx context.Context,
 	fn datastore.TxUserFunc,
+	opts ...options.RWTOptionsOption,
 ) (datastore.Revision, error) {
+	rwtOpts := options.NewRWTOptionsWithOptions(opts...)
+
 	var err error
 	for i := uint8(0); i <= mds.maxRetries; i++ {
 		var newTxnID uint64
 		if err = migrations.BeginTxFunc(ctx, mds.db, &sql.TxOptions{Isolation: sql.LevelSerializable}, func(tx *sql.Tx) error {
-			newTxnID, err = mds.createNewTransaction(ctx, tx)
+			if rwtOpts.IdempotencyKey != "" {
+				if err := mds.checkIdempotencyKey(ctx, tx, rwtOpts.IdempotencyKey, rwtOpts.IdempotencyRequestHash); err != nil {
+					return err
+				}
+			}
+
+			newTxnID, err = mds.createNewTransaction(ctx, tx, rwtOpts.IdempotencyKey, rwtOpts.IdempotencyRequestHash)
 			if err != nil {
 				return fmt.Errorf("unable to create new txn ID: %w", err)
 			}
@@ -307,6 +321,7 @@ func (mds *Datastore) ReadWriteTx(
 					longLivedTx,
 					querySplitter,
 					currentlyLivingObjects,
+					currentlyUnexpiredClause,
 				},
 				tx,
 				newTxnID,
@@ -314,7 +329,9 @@ func (mds *Datastore) ReadWriteTx(
 
 			return fn(rwt)
 		}); err != nil {
-			if isErrorRetryable(err) {
+			// A transaction which committed concurrently with the same idempotency key will be
+			// found when retried.
+			if isErrorRetryable(err) || (rwtOpts.IdempotencyKey != "" && isDuplicateEntry(err)) {
 				continue
 			}
 
@@ -336,6 +353,11 @@ func isErrorRetryable(err error) bool {
 	return mysqlerr.Number == errMysqlDeadlock || mysqlerr.Number == errMysqlLockWaitTimeout
 }
 
+func isDuplicateEntry(err error) bool {
+	var mysqlerr *mysql.MySQLError
+	return errors.As(err, &mysqlerr) && mysqlerr.Number == errMysqlDuplicateEntry
+}
+
 type querier interface {
 	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
 }
@@ -376,6 +398,7 @@ func newMySQLExecutor(tx querier) common.ExecuteQueryFunc {
 
 			var caveatName string
 			var caveatContext caveatContextWrapper
+			var expiration *time.Time
 			err := rows.Scan(
 				&nextTuple.ResourceAndRelation.Namespace,
 				&nextTuple.ResourceAndRelation.ObjectId,
@@ -385,11 +408,14 @@ func newMySQLExecutor(tx querier) common.ExecuteQueryFunc {
 				&nextTuple.Subject.Relation,
 				&caveatName,
 				&caveatContext,
+				&expiration,
 			)
 			if err != nil {
 				return nil, fmt.Errorf(errUnableToQueryTuples, err)
 			}
 
+			nextTuple.OptionalExpirationTime = common.ExpirationTimestampFrom(expiration)
+
 			nextTuple.Caveat, err = common.ContextualizedCaveatFrom(caveatName, caveatContext)
 			if err != nil {
 				return nil, fmt.Errorf(errUnableToQueryTuples, err)
@@ -83,6 +83,11 @@ func (mds *Datastore) DeleteBeforeTx(
 	return
 }
 
+func (mds *Datastore) DeleteExpiredBefore(ctx context.Context, before time.Time) (int64, error) {
+	// Expired relationships are removed outright, whether or not they have since been deleted.
+	return mds.batchDelete(ctx, mds.driver.RelationTuple(), sq.Lt{colExpiration: before.UTC()})
+}
+
 // TODO (@vroldanbet) dupe from postgres datastore - need to refactor
 // - query was reworked to make it compatible with Vitess
 // - API differences with PSQL driver
@@ -0,0 +1,21 @@
+package migrations
+
+import "fmt"
+
+// expiration is stored in UTC and is indexed so that the garbage collector can find
+// expired relationships.
+func addExpirationToRelationTuplesTable(t *tables) string {
+	return fmt.Sprintf(`ALTER TABLE %s
+			ADD COLUMN expiration DATETIME(6) NULL DEFAULT NULL,
+			ADD INDEX ix_relation_tuple_expired (expiration);`,
+		t.RelationTuple(),
+	)
+}
+
+func init() {
+	mustRegisterMigration("add_relationship_expiration", "extend_object_id", noNonatomicMigration,
+		newStatementBatch(
+			addExpirationToRelationTuplesTable,
+		).execute,
+	)
+}
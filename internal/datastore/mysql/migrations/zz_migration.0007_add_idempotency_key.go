@@ -0,0 +1,24 @@
+package migrations
+
+import "fmt"
+
+// idempotency_key is unique so that the transaction which committed with a given key can be
+// found, and so that concurrent transactions cannot commit with the same key. The request hash
+// identifies the request made with the key, so that reuse of the key for a different request can
+// be rejected.
+func addIdempotencyKeyToTransactionTable(t *tables) string {
+	return fmt.Sprintf(`ALTER TABLE %s
+			ADD COLUMN idempotency_key VARCHAR(128) NULL DEFAULT NULL,
+			ADD COLUMN idempotency_request_hash VARCHAR(64) NOT NULL DEFAULT '',
+			ADD UNIQUE INDEX uq_relation_tuple_transaction_idempotency_key (idempotency_key);`,
+		t.RelationTupleTransaction(),
+	)
+}
+
+func init() {
+	mustRegisterMigration("add_idempotency_key", "add_relationship_expiration", noNonatomicMigration,
+		newStatementBatch(
+			addIdempotencyKeyToTransactionTable,
+		).execute,
+	)
+}
@@ -1,6 +1,8 @@
 package mysql
 
 import (
+	"fmt"
+
 	"github.com/authzed/spicedb/internal/datastore/mysql/migrations"
 
 	sq "github.com/Masterminds/squirrel"
@@ -29,6 +31,9 @@ type QueryBuilder struct {
 	ReadCaveatQuery   sq.SelectBuilder
 	ListCaveatsQuery  sq.SelectBuilder
 	DeleteCaveatQuery sq.UpdateBuilder
+
+	QueryChangedNamespacesQuery sq.SelectBuilder
+	QueryChangedCaveatsQuery    sq.SelectBuilder
 }
 
 // NewQueryBuilder returns a new QueryBuilder instance. The migration
@@ -61,6 +66,10 @@ func NewQueryBuilder(driver *migrations.MySQLDriver) *QueryBuilder {
 	builder.WriteCaveatQuery = writeCaveat(driver.Caveat())
 	builder.DeleteCaveatQuery = deleteCaveat(driver.Caveat())
 
+	// schema change builders
+	builder.QueryChangedNamespacesQuery = queryChangedNamespaces(driver.Namespace())
+	builder.QueryChangedCaveatsQuery = queryChangedCaveats(driver.Caveat())
+
 	return &builder
 }
 
@@ -84,6 +93,10 @@ func readCaveat(tableCaveat string) sq.SelectBuilder {
 	return sb.Select(colCaveatDefinition, colCreatedTxn).From(tableCaveat)
 }
 
+func queryChangedCaveats(tableCaveat string) sq.SelectBuilder {
+	return sb.Select(colName, colCaveatDefinition, colCreatedTxn, colDeletedTxn).From(tableCaveat)
+}
+
 func getLastRevision(tableTransaction string) sq.SelectBuilder {
 	return sb.Select("MAX(id)").From(tableTransaction).Limit(1)
 }
@@ -108,6 +121,10 @@ func deleteNamespace(tableNamespace string) sq.UpdateBuilder {
 	return sb.Update(tableNamespace).Where(sq.Eq{colDeletedTxn: liveDeletedTxnID})
 }
 
+func queryChangedNamespaces(tableNamespace string) sq.SelectBuilder {
+	return sb.Select(colConfig, colCreatedTxn, colDeletedTxn).From(tableNamespace)
+}
+
 func deleteNamespaceTuples(tableTuple string) sq.UpdateBuilder {
 	return sb.Update(tableTuple).Where(sq.Eq{colDeletedTxn: liveDeletedTxnID})
 }
@@ -118,6 +135,31 @@ func queryTupleIds(tableTuple string) sq.SelectBuilder {
 	).From(tableTuple)
 }
 
+// Within a read-write transaction, the expiration of relationships is evaluated against the
+// current time of the database.
+var (
+	expiredClause            = sq.Expr(colExpiration + " <= UTC_TIMESTAMP(6)")
+	currentlyUnexpiredClause = sq.Or{sq.Eq{colExpiration: nil}, sq.Expr(colExpiration + " > UTC_TIMESTAMP(6)")}
+)
+
+// unexpiredAtTransaction returns a clause filtering out the relationships which have expired as
+// of the timestamp of the latest transaction at or before that given, which is the time of its
+// revision. Reads at the revision evaluate the expiration of relationships against it, so that
+// they are repeatable.
+func unexpiredAtTransaction(tableTransaction string, txID uint64) sq.Sqlizer {
+	return sq.Or{
+		sq.Eq{colExpiration: nil},
+		sq.Expr(fmt.Sprintf(
+			"%s > (SELECT %s FROM %s WHERE %s <= ? ORDER BY %s DESC LIMIT 1)",
+			colExpiration,
+			colTimestamp,
+			tableTransaction,
+			colID,
+			colID,
+		), txID),
+	}
+}
+
 func queryTuples(tableTuple string) sq.SelectBuilder {
 	return sb.Select(
 		colNamespace,
@@ -128,6 +170,7 @@ func queryTuples(tableTuple string) sq.SelectBuilder {
 		colUsersetRelation,
 		colCaveatName,
 		colCaveatContext,
+		colExpiration,
 	).From(tableTuple)
 }
 
@@ -155,6 +198,7 @@ func writeTuple(tableTuple string) sq.InsertBuilder {
 		colUsersetRelation,
 		colCaveatName,
 		colCaveatContext,
+		colExpiration,
 		colCreatedTxn,
 	)
 }
@@ -169,6 +213,7 @@ func queryChanged(tableTuple string) sq.SelectBuilder {
 		colUsersetRelation,
 		colCaveatName,
 		colCaveatContext,
+		colExpiration,
 		colCreatedTxn,
 		colDeletedTxn,
 	).From(tableTuple)
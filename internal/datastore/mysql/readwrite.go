@@ -18,12 +18,15 @@ import (
 
 	"github.com/authzed/spicedb/internal/datastore/common"
 	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/options"
+	"github.com/authzed/spicedb/pkg/datastore/revision"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 )
 
 const (
 	errUnableToWriteRelationships  = "unable to write relationships: %w"
 	errUnableToDeleteRelationships = "unable to delete relationships: %w"
+	errUnableToCheckChanges        = "unable to check for changed relationships: %w"
 	errUnableToWriteConfig         = "unable to write namespace config: %w"
 	errUnableToDeleteConfig        = "unable to delete namespace config: %w"
 )
@@ -74,6 +77,11 @@ func (rwt *mysqlReadWriteTXN) WriteRelationships(ctx context.Context, mutations
 			clauses = append(clauses, exactRelationshipClause(tpl))
 		}
 
+		// An expired relationship does not prevent the creation of a new one in its place.
+		if mut.Operation == core.RelationTupleUpdate_CREATE {
+			clauses = append(clauses, sq.And{exactRelationshipClause(tpl), expiredClause})
+		}
+
 		var caveatName string
 		var caveatContext caveatContextWrapper
 		if tpl.Caveat != nil {
@@ -90,6 +98,7 @@ func (rwt *mysqlReadWriteTXN) WriteRelationships(ctx context.Context, mutations
 				tpl.Subject.Relation,
 				caveatName,
 				&caveatContext,
+				common.ExpirationTimeFrom(tpl),
 				rwt.newTxnID,
 			)
 			bulkWriteHasValues = true
@@ -156,40 +165,107 @@ func (rwt *mysqlReadWriteTXN) WriteRelationships(ctx context.Context, mutations
 	return nil
 }
 
-func (rwt *mysqlReadWriteTXN) DeleteRelationships(ctx context.Context, filter *v1.RelationshipFilter) error {
-	// TODO (@vroldanbet) dupe from postgres datastore - need to refactor
+// bulkLoadBatchSize is the number of relationships written by each multi-row insert during
+// a bulk load. It is kept well below MySQL's limit of 65535 placeholders per statement.
+const bulkLoadBatchSize = 1000
+
+func (rwt *mysqlReadWriteTXN) BulkLoad(ctx context.Context, iter datastore.BulkWriteRelationshipSource) (uint64, error) {
+	return common.BulkLoadInBatches(ctx, iter, bulkLoadBatchSize, rwt.WriteRelationships)
+}
+
+func (rwt *mysqlReadWriteTXN) DeleteRelationships(ctx context.Context, filter *v1.RelationshipFilter, opts ...options.DeleteOptionsOption) (uint64, bool, error) {
+	query := rwt.DeleteTupleQuery.Where(relationshipFilterClause(filter))
+
+	delOpts := options.NewDeleteOptionsWithOptions(opts...)
+	if delOpts.DeleteLimit != nil {
+		query = query.Limit(*delOpts.DeleteLimit)
+	}
+
+	query = query.Set(colDeletedTxn, rwt.newTxnID)
+
+	querySQL, args, err := query.ToSql()
+	if err != nil {
+		return 0, false, fmt.Errorf(errUnableToDeleteRelationships, err)
+	}
+
+	result, err := rwt.tx.ExecContext(ctx, querySQL, args...)
+	if err != nil {
+		return 0, false, fmt.Errorf(errUnableToDeleteRelationships, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, false, fmt.Errorf(errUnableToDeleteRelationships, err)
+	}
+
+	deleted := uint64(rowsAffected)
+	if delOpts.DeleteLimit == nil || deleted < *delOpts.DeleteLimit {
+		return deleted, false, nil
+	}
+
+	remain, err := common.RelationshipsRemain(ctx, rwt, filter)
+	if err != nil {
+		return 0, false, fmt.Errorf(errUnableToDeleteRelationships, err)
+	}
+
+	return deleted, remain, nil
+}
+
+func (rwt *mysqlReadWriteTXN) RelationshipsChangedSince(ctx context.Context, filter *v1.RelationshipFilter, rev datastore.Revision) (bool, error) {
+	afterTxn := transactionFromRevision(rev.(revision.Decimal))
+
+	// Deleted relationships are retained until garbage collected, so deletions within the
+	// GC window are found.
+	query := rwt.QueryTupleExistsQuery.
+		Where(relationshipFilterClause(filter)).
+		Where(sq.Or{
+			sq.Gt{colCreatedTxn: afterTxn},
+			sq.And{
+				sq.Gt{colDeletedTxn: afterTxn},
+				sq.NotEq{colDeletedTxn: liveDeletedTxnID},
+			},
+		}).
+		Limit(1)
+
+	querySQL, args, err := query.ToSql()
+	if err != nil {
+		return false, fmt.Errorf(errUnableToCheckChanges, err)
+	}
+
+	var id int64
+	if err := rwt.tx.QueryRowContext(ctx, querySQL, args...).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf(errUnableToCheckChanges, err)
+	}
+
+	return true, nil
+}
+
+// TODO (@vroldanbet) dupe from postgres datastore - need to refactor
+func relationshipFilterClause(filter *v1.RelationshipFilter) sq.And {
 	// Add clauses for the ResourceFilter
-	query := rwt.DeleteTupleQuery.Where(sq.Eq{colNamespace: filter.ResourceType})
+	clause := sq.And{sq.Eq{colNamespace: filter.ResourceType}}
 	if filter.OptionalResourceId != "" {
-		query = query.Where(sq.Eq{colObjectID: filter.OptionalResourceId})
+		clause = append(clause, sq.Eq{colObjectID: filter.OptionalResourceId})
 	}
 	if filter.OptionalRelation != "" {
-		query = query.Where(sq.Eq{colRelation: filter.OptionalRelation})
+		clause = append(clause, sq.Eq{colRelation: filter.OptionalRelation})
 	}
 
 	// Add clauses for the SubjectFilter
 	if subjectFilter := filter.OptionalSubjectFilter; subjectFilter != nil {
-		query = query.Where(sq.Eq{colUsersetNamespace: subjectFilter.SubjectType})
+		clause = append(clause, sq.Eq{colUsersetNamespace: subjectFilter.SubjectType})
 		if subjectFilter.OptionalSubjectId != "" {
-			query = query.Where(sq.Eq{colUsersetObjectID: subjectFilter.OptionalSubjectId})
+			clause = append(clause, sq.Eq{colUsersetObjectID: subjectFilter.OptionalSubjectId})
 		}
 		if relationFilter := subjectFilter.OptionalRelation; relationFilter != nil {
-			query = query.Where(sq.Eq{colUsersetRelation: stringz.DefaultEmpty(relationFilter.Relation, datastore.Ellipsis)})
+			clause = append(clause, sq.Eq{colUsersetRelation: stringz.DefaultEmpty(relationFilter.Relation, datastore.Ellipsis)})
 		}
 	}
 
-	query = query.Set(colDeletedTxn, rwt.newTxnID)
-
-	querySQL, args, err := query.ToSql()
-	if err != nil {
-		return fmt.Errorf(errUnableToDeleteRelationships, err)
-	}
-
-	if _, err := rwt.tx.ExecContext(ctx, querySQL, args...); err != nil {
-		return fmt.Errorf(errUnableToDeleteRelationships, err)
-	}
-
-	return nil
+	return clause
 }
 
 func (rwt *mysqlReadWriteTXN) WriteNamespaces(ctx context.Context, newNamespaces ...*core.NamespaceDefinition) error {
@@ -8,8 +8,10 @@ import (
 	"math/big"
 	"time"
 
+	sq "github.com/Masterminds/squirrel"
 	"github.com/shopspring/decimal"
 
+	"github.com/authzed/spicedb/internal/datastore/common"
 	"github.com/authzed/spicedb/pkg/datastore"
 	"github.com/authzed/spicedb/pkg/datastore/revision"
 )
@@ -18,6 +20,8 @@ const (
 	errRevision      = "unable to find revision: %w"
 	errCheckRevision = "unable to check revision: %w"
 
+	errCheckIdempotencyKey = "unable to check idempotency key: %w"
+
 	// querySelectRevision will round the database's timestamp down to the nearest
 	// quantization period, and then find the first transaction after that. If there
 	// are no transactions newer than the quantization period, it just picks the latest
@@ -158,16 +162,23 @@ func (mds *Datastore) checkValidTransaction(ctx context.Context, revisionTx uint
 	return freshEnough.Bool, unknown.Bool, nil
 }
 
-func (mds *Datastore) createNewTransaction(ctx context.Context, tx *sql.Tx) (newTxnID uint64, err error) {
+func (mds *Datastore) createNewTransaction(ctx context.Context, tx *sql.Tx, idempotencyKey string, requestHash string) (newTxnID uint64, err error) {
 	ctx, span := tracer.Start(ctx, "createNewTransaction")
 	defer span.End()
 
 	createQuery := mds.createTxn
-	if err != nil {
-		return 0, fmt.Errorf("createNewTransaction: %w", err)
+	var args []interface{}
+	if idempotencyKey != "" {
+		createQuery, args, err = sb.Insert(mds.driver.RelationTupleTransaction()).
+			Columns(colIdempotencyKey, colRequestHash).
+			Values(idempotencyKey, requestHash).
+			ToSql()
+		if err != nil {
+			return 0, fmt.Errorf("createNewTransaction: %w", err)
+		}
 	}
 
-	result, err := tx.ExecContext(ctx, createQuery)
+	result, err := tx.ExecContext(ctx, createQuery, args...)
 	if err != nil {
 		return 0, fmt.Errorf("createNewTransaction: %w", err)
 	}
@@ -180,6 +191,33 @@ func (mds *Datastore) createNewTransaction(ctx context.Context, tx *sql.Tx) (new
 	return uint64(lastInsertID), nil
 }
 
+// checkIdempotencyKey returns an ErrIdempotencyKeyAlreadyCommitted if a transaction has already
+// committed with the given key, or an ErrIdempotencyKeyReused if it did so for a different request.
+// Keys are removed along with their transactions by the GC.
+func (mds *Datastore) checkIdempotencyKey(ctx context.Context, tx *sql.Tx, idempotencyKey string, requestHash string) error {
+	ctx, span := tracer.Start(ctx, "checkIdempotencyKey")
+	defer span.End()
+
+	query, args, err := sb.Select(colID, colRequestHash).
+		From(mds.driver.RelationTupleTransaction()).
+		Where(sq.Eq{colIdempotencyKey: idempotencyKey}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf(errCheckIdempotencyKey, err)
+	}
+
+	var committedTxnID uint64
+	var committedRequestHash string
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(&committedTxnID, &committedRequestHash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf(errCheckIdempotencyKey, err)
+	}
+
+	return common.IdempotencyKeyCommittedErr(idempotencyKey, requestHash, committedRequestHash, revisionFromTransaction(committedTxnID))
+}
+
 func revisionFromTransaction(txID uint64) revision.Decimal {
 	return revision.NewFromDecimal(decimal.NewFromBigInt(new(big.Int).SetUint64(txID), 0))
 }
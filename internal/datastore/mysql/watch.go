@@ -3,6 +3,7 @@ package mysql
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/authzed/spicedb/internal/datastore/common"
@@ -17,12 +18,12 @@ const (
 	watchSleep = 100 * time.Millisecond
 )
 
-// Watch notifies the caller about all changes to tuples.
+// Watch notifies the caller about all changes to tuples, namespaces and caveats.
 //
 // All events following afterRevision will be sent to the caller.
 //
 // TODO (@vroldanbet) dupe from postgres datastore - need to refactor
-func (mds *Datastore) Watch(ctx context.Context, afterRevisionRaw datastore.Revision) (<-chan *datastore.RevisionChanges, <-chan error) {
+func (mds *Datastore) Watch(ctx context.Context, afterRevisionRaw datastore.Revision, options datastore.WatchOptions) (<-chan *datastore.RevisionChanges, <-chan error) {
 	afterRevision := afterRevisionRaw.(revision.Decimal)
 
 	updates := make(chan *datastore.RevisionChanges, mds.watchBufferLength)
@@ -36,8 +37,9 @@ func (mds *Datastore) Watch(ctx context.Context, afterRevisionRaw datastore.Revi
 
 		for {
 			var stagedUpdates []datastore.RevisionChanges
+			var newTxn uint64
 			var err error
-			stagedUpdates, currentTxn, err = mds.loadChanges(ctx, currentTxn)
+			stagedUpdates, newTxn, err = mds.loadChanges(ctx, currentTxn, options)
 			if err != nil {
 				if errors.Is(ctx.Err(), context.Canceled) {
 					errs <- datastore.NewWatchCanceledErr()
@@ -59,6 +61,13 @@ func (mds *Datastore) Watch(ctx context.Context, afterRevisionRaw datastore.Revi
 				}
 			}
 
+			// All changes through the latest transaction have been emitted, even if that
+			// transaction itself made no changes.
+			if newTxn != currentTxn {
+				currentTxn = newTxn
+				common.SendCheckpoint(updates, revisionFromTransaction(currentTxn))
+			}
+
 			// If there were no changes, sleep a bit
 			if len(stagedUpdates) == 0 {
 				sleep := time.NewTimer(watchSleep)
@@ -81,6 +90,7 @@ func (mds *Datastore) Watch(ctx context.Context, afterRevisionRaw datastore.Revi
 func (mds *Datastore) loadChanges(
 	ctx context.Context,
 	afterRevision uint64,
+	options datastore.WatchOptions,
 ) (changes []datastore.RevisionChanges, newRevision uint64, err error) {
 	newRevision, err = mds.loadRevision(ctx)
 	if err != nil {
@@ -91,7 +101,7 @@ func (mds *Datastore) loadChanges(
 		return
 	}
 
-	sql, args, err := mds.QueryChangedQuery.Where(sq.Or{
+	txnFilter := sq.Or{
 		sq.And{
 			sq.Gt{colCreatedTxn: afterRevision},
 			sq.LtOrEq{colCreatedTxn: newRevision},
@@ -100,22 +110,60 @@ func (mds *Datastore) loadChanges(
 			sq.Gt{colDeletedTxn: afterRevision},
 			sq.LtOrEq{colDeletedTxn: newRevision},
 		},
-	}).ToSql()
-	if err != nil {
+	}
+
+	inRange := func(txn uint64) bool {
+		return txn > afterRevision && txn <= newRevision
+	}
+
+	stagedChanges := common.NewChanges(revision.DecimalKeyFunc)
+	if err = mds.loadRelationshipChanges(ctx, txnFilter, options, inRange, stagedChanges); err != nil {
+		return
+	}
+
+	if err = mds.loadNamespaceChanges(ctx, txnFilter, inRange, stagedChanges); err != nil {
 		return
 	}
 
+	if err = mds.loadCaveatChanges(ctx, txnFilter, inRange, stagedChanges); err != nil {
+		return
+	}
+
+	changes = stagedChanges.AsRevisionChanges(revision.DecimalKeyLessThanFunc)
+
+	return
+}
+
+func (mds *Datastore) loadRelationshipChanges(
+	ctx context.Context,
+	txnFilter sq.Sqlizer,
+	options datastore.WatchOptions,
+	inRange func(txn uint64) bool,
+	stagedChanges common.Changes[revision.Decimal, int64],
+) error {
+	query := mds.QueryChangedQuery.Where(txnFilter)
+	if len(options.OptionalRelationshipsFilters) > 0 {
+		relationshipsFilter, err := schema.RelationshipsFiltersClause(options.OptionalRelationshipsFilters)
+		if err != nil {
+			return err
+		}
+		query = query.Where(relationshipsFilter)
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
 	rows, err := mds.db.QueryContext(ctx, sql, args...)
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
 			err = datastore.NewWatchCanceledErr()
 		}
-		return
+		return err
 	}
 	defer common.LogOnError(ctx, rows.Close)
 
-	stagedChanges := common.NewChanges(revision.DecimalKeyFunc)
-
 	for rows.Next() {
 		nextTuple := &core.RelationTuple{
 			ResourceAndRelation: &core.ObjectAndRelation{},
@@ -126,6 +174,7 @@ func (mds *Datastore) loadChanges(
 		var deletedTxn uint64
 		var caveatName string
 		var caveatContext caveatContextWrapper
+		var expiration *time.Time
 		err = rows.Scan(
 			&nextTuple.ResourceAndRelation.Namespace,
 			&nextTuple.ResourceAndRelation.ObjectId,
@@ -135,30 +184,114 @@ func (mds *Datastore) loadChanges(
 			&nextTuple.Subject.Relation,
 			&caveatName,
 			&caveatContext,
+			&expiration,
 			&createdTxn,
 			&deletedTxn,
 		)
 		if err != nil {
-			return
+			return err
 		}
 		nextTuple.Caveat, err = common.ContextualizedCaveatFrom(caveatName, caveatContext)
 		if err != nil {
-			return
+			return err
 		}
+		nextTuple.OptionalExpirationTime = common.ExpirationTimestampFrom(expiration)
 
-		if createdTxn > afterRevision && createdTxn <= newRevision {
+		if inRange(createdTxn) {
 			stagedChanges.AddChange(ctx, revisionFromTransaction(createdTxn), nextTuple, core.RelationTupleUpdate_TOUCH)
 		}
 
-		if deletedTxn > afterRevision && deletedTxn <= newRevision {
+		if inRange(deletedTxn) {
 			stagedChanges.AddChange(ctx, revisionFromTransaction(deletedTxn), nextTuple, core.RelationTupleUpdate_DELETE)
 		}
 	}
-	if err = rows.Err(); err != nil {
-		return
+	return rows.Err()
+}
+
+func (mds *Datastore) loadNamespaceChanges(
+	ctx context.Context,
+	txnFilter sq.Sqlizer,
+	inRange func(txn uint64) bool,
+	stagedChanges common.Changes[revision.Decimal, int64],
+) error {
+	sql, args, err := mds.QueryChangedNamespacesQuery.Where(txnFilter).ToSql()
+	if err != nil {
+		return err
 	}
 
-	changes = stagedChanges.AsRevisionChanges(revision.DecimalKeyLessThanFunc)
+	rows, err := mds.db.QueryContext(ctx, sql, args...)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			err = datastore.NewWatchCanceledErr()
+		}
+		return err
+	}
+	defer common.LogOnError(ctx, rows.Close)
 
-	return
+	for rows.Next() {
+		var config []byte
+		var createdTxn uint64
+		var deletedTxn uint64
+		if err := rows.Scan(&config, &createdTxn, &deletedTxn); err != nil {
+			return err
+		}
+
+		loaded := &core.NamespaceDefinition{}
+		if err := loaded.UnmarshalVT(config); err != nil {
+			return fmt.Errorf(errUnableToReadConfig, err)
+		}
+
+		if inRange(createdTxn) {
+			stagedChanges.AddChangedDefinition(ctx, revisionFromTransaction(createdTxn), loaded)
+		}
+
+		if inRange(deletedTxn) {
+			stagedChanges.AddDeletedNamespace(ctx, revisionFromTransaction(deletedTxn), loaded.Name)
+		}
+	}
+	return rows.Err()
+}
+
+func (mds *Datastore) loadCaveatChanges(
+	ctx context.Context,
+	txnFilter sq.Sqlizer,
+	inRange func(txn uint64) bool,
+	stagedChanges common.Changes[revision.Decimal, int64],
+) error {
+	sql, args, err := mds.QueryChangedCaveatsQuery.Where(txnFilter).ToSql()
+	if err != nil {
+		return err
+	}
+
+	rows, err := mds.db.QueryContext(ctx, sql, args...)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			err = datastore.NewWatchCanceledErr()
+		}
+		return err
+	}
+	defer common.LogOnError(ctx, rows.Close)
+
+	for rows.Next() {
+		var name string
+		var definitionBytes []byte
+		var createdTxn uint64
+		var deletedTxn uint64
+		if err := rows.Scan(&name, &definitionBytes, &createdTxn, &deletedTxn); err != nil {
+			return err
+		}
+
+		if inRange(createdTxn) {
+			loaded := &core.CaveatDefinition{}
+			if err := loaded.UnmarshalVT(definitionBytes); err != nil {
+				return fmt.Errorf(errReadCaveat, err)
+			}
+			stagedChanges.AddChangedDefinition(ctx, revisionFromTransaction(createdTxn), loaded)
+		}
+
+		if inRange(deletedTxn) {
+			stagedChanges.AddDeletedCaveat(ctx, revisionFromTransaction(deletedTxn), name)
+		}
+	}
+	return rows.Err()
 }
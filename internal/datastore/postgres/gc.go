@@ -110,6 +110,16 @@ func (pgd *pgDatastore) DeleteBeforeTx(ctx context.Context, txID datastore.Revis
 	return
 }
 
+func (pgd *pgDatastore) DeleteExpiredBefore(ctx context.Context, before time.Time) (int64, error) {
+	// Expired relationships are removed outright, whether or not they have since been deleted.
+	return pgd.batchDelete(
+		ctx,
+		tableTuple,
+		relationTuplePKCols,
+		sq.Lt{colExpiration: before},
+	)
+}
+
 func (pgd *pgDatastore) batchDelete(
 	ctx context.Context,
 	tableName string,
@@ -0,0 +1,21 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const addRelationshipExpirationColumn = `ALTER TABLE relation_tuple
+	ADD COLUMN expiration TIMESTAMPTZ;`
+
+func init() {
+	if err := DatabaseMigrations.Register("add-relationship-expiration", "add-gc-covering-index",
+		noNonatomicMigration,
+		func(ctx context.Context, tx pgx.Tx) error {
+			_, err := tx.Exec(ctx, addRelationshipExpirationColumn)
+			return err
+		}); err != nil {
+		panic("failed to register migration: " + err.Error())
+	}
+}
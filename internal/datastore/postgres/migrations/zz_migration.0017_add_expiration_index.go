@@ -0,0 +1,24 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Used by the garbage collector to find expired relationships.
+const createRelationTupleExpirationIndex = `CREATE INDEX CONCURRENTLY
+	IF NOT EXISTS ix_relation_tuple_expired
+	ON relation_tuple (expiration)
+	WHERE expiration IS NOT NULL;`
+
+func init() {
+	if err := DatabaseMigrations.Register("add-expiration-index", "add-relationship-expiration",
+		func(ctx context.Context, conn *pgx.Conn) error {
+			_, err := conn.Exec(ctx, createRelationTupleExpirationIndex)
+			return err
+		},
+		noTxMigration); err != nil {
+		panic("failed to register migration: " + err.Error())
+	}
+}
@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const addIdempotencyKeyColumn = `ALTER TABLE relation_tuple_transaction
+	ADD COLUMN IF NOT EXISTS idempotency_key VARCHAR;`
+
+// Identifies the request made with the idempotency key, so that reuse of the key for a
+// different request can be rejected.
+const addIdempotencyRequestHashColumn = `ALTER TABLE relation_tuple_transaction
+	ADD COLUMN IF NOT EXISTS idempotency_request_hash VARCHAR NOT NULL DEFAULT '';`
+
+// Used to find the transaction, if any, which committed with a given idempotency key.
+const createIdempotencyKeyIndex = `CREATE UNIQUE INDEX CONCURRENTLY
+	IF NOT EXISTS ix_relation_tuple_transaction_idempotency_key
+	ON relation_tuple_transaction (idempotency_key)
+	WHERE idempotency_key IS NOT NULL;`
+
+func init() {
+	if err := DatabaseMigrations.Register("add-idempotency-key", "add-expiration-index",
+		func(ctx context.Context, conn *pgx.Conn) error {
+			for _, stmt := range []string{addIdempotencyKeyColumn, addIdempotencyRequestHashColumn, createIdempotencyKeyIndex} {
+				if _, err := conn.Exec(ctx, stmt); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		noTxMigration); err != nil {
+		panic("failed to register migration: " + err.Error())
+	}
+}
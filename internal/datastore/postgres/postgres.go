@@ -25,6 +25,7 @@ import (
 	"github.com/authzed/spicedb/internal/datastore/proxy"
 	log "github.com/authzed/spicedb/internal/logging"
 	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/options"
 )
 
 func init() {
@@ -54,6 +55,9 @@ const (
 	colCaveatDefinition  = "definition"
 	colCaveatContextName = "caveat_name"
 	colCaveatContext     = "caveat_context"
+	colExpiration        = "expiration"
+	colIdempotencyKey    = "idempotency_key"
+	colRequestHash       = "idempotency_request_hash"
 
 	errUnableToInstantiate = "unable to instantiate datastore: %w"
 
@@ -96,6 +100,17 @@ var (
 		colSnapshot,
 	)
 
+	createTxnWithIdempotencyKey = fmt.Sprintf(
+		"INSERT INTO %s (%s, %s) VALUES ($1, $2) RETURNING %s, %s",
+		tableTransaction,
+		colIdempotencyKey,
+		colRequestHash,
+		colXID,
+		colSnapshot,
+	)
+
+	getIdempotencyKeyTxn = psql.Select(colXID, colSnapshot, colRequestHash).From(tableTransaction)
+
 	getNow = psql.Select("NOW()")
 
 	tracer = otel.Tracer("spicedb/internal/datastore/postgres")
@@ -309,6 +324,7 @@ func (pgd *pgDatastore) SnapshotReader(revRaw datastore.Revision) datastore.Read
 		createTxFunc,
 		querySplitter,
 		buildLivingObjectFilterForRevision(rev),
+		unexpiredAtRevision(rev),
 	}
 }
 
@@ -319,14 +335,23 @@ func noCleanup(context.Context) {}
 func (pgd *pgDatastore) ReadWriteTx(
 	ctx context.Context,
 	fn datastore.TxUserFunc,
+	opts ...options.RWTOptionsOption,
 ) (datastore.Revision, error) {
+	rwtOpts := options.NewRWTOptionsWithOptions(opts...)
+
 	var err error
 	for i := uint8(0); i <= pgd.maxRetries; i++ {
 		var newXID xid8
 		var newSnapshot pgSnapshot
 		err = pgx.BeginTxFunc(ctx, pgd.writePool, pgx.TxOptions{IsoLevel: pgx.Serializable}, func(tx pgx.Tx) error {
+			if rwtOpts.IdempotencyKey != "" {
+				if err := checkIdempotencyKey(ctx, tx, rwtOpts.IdempotencyKey, rwtOpts.IdempotencyRequestHash); err != nil {
+					return err
+				}
+			}
+
 			var err error
-			newXID, newSnapshot, err = createNewTransaction(ctx, tx)
+			newXID, newSnapshot, err = createNewTransaction(ctx, tx, rwtOpts.IdempotencyKey, rwtOpts.IdempotencyRequestHash)
 			if err != nil {
 				return err
 			}
@@ -345,6 +370,7 @@ func (pgd *pgDatastore) ReadWriteTx(
 					longLivedTx,
 					querySplitter,
 					currentlyLivingObjects,
+					currentlyUnexpiredClause,
 				},
 				tx,
 				newXID,
@@ -441,6 +467,15 @@ func buildLivingObjectFilterForRevision(revision postgresRevision) queryFilterer
 	}
 }
 
+// unexpiredAtRevision returns a clause filtering out the relationships which have expired as of
+// the time of the revision.
+func unexpiredAtRevision(revision postgresRevision) sq.Sqlizer {
+	return sq.Or{
+		sq.Eq{colExpiration: nil},
+		sq.Expr(colExpiration+" > "+snapshotTimestamp, revision.snapshot),
+	}
+}
+
 func currentlyLivingObjects(original sq.SelectBuilder) sq.SelectBuilder {
 	return original.Where(sq.Eq{colDeletedXid: liveDeletedTxnID})
 }
@@ -349,7 +349,7 @@ func TransactionTimestampsTest(t *testing.T, ds datastore.Datastore) {
 	tx, err := pgd.writePool.Begin(ctx)
 	require.NoError(err)
 
-	txXID, _, err := createNewTransaction(ctx, tx)
+	txXID, _, err := createNewTransaction(ctx, tx, "", "")
 	require.NoError(err)
 
 	err = tx.Commit(ctx)
@@ -832,6 +832,7 @@ func WatchNotEnabledTest(t *testing.T, _ testdatastore.RunningEngineForTest) {
 	_, errChan := ds.Watch(
 		context.Background(),
 		revision,
+		datastore.WatchOptions{},
 	)
 	err := <-errChan
 	require.NotNil(err)
@@ -18,6 +18,9 @@ type pgReader struct {
 	txSource      pgxcommon.TxFactory
 	querySplitter common.TupleQuerySplitter
 	filterer      queryFilterer
+
+	// notExpired filters out relationships which have expired as of the read.
+	notExpired sq.Sqlizer
 }
 
 type queryFilterer func(original sq.SelectBuilder) sq.SelectBuilder
@@ -32,8 +35,24 @@ var (
 		colUsersetRelation,
 		colCaveatContextName,
 		colCaveatContext,
+		colExpiration,
 	).From(tableTuple)
 
+	// Within a read-write transaction, the expiration of relationships is evaluated against the
+	// start time of the database transaction.
+	expiredClause            = sq.Expr(colExpiration + " <= NOW()")
+	currentlyUnexpiredClause = sq.Or{sq.Eq{colExpiration: nil}, sq.Expr(colExpiration + " > NOW()")}
+
+	// snapshotTimestamp selects the time of a snapshot revision: the timestamp of the latest
+	// transaction visible in the snapshot. Reads at the revision evaluate the expiration of
+	// relationships against it, so that they are repeatable. The placeholder is the snapshot.
+	snapshotTimestamp = fmt.Sprintf(
+		"(SELECT %[1]s AT TIME ZONE 'UTC' FROM %[2]s WHERE pg_visible_in_snapshot(%[3]s, ?) ORDER BY %[3]s DESC LIMIT 1)",
+		colTimestamp,
+		tableTransaction,
+		colXID,
+	)
+
 	schema = common.NewSchemaInformation(
 		colNamespace,
 		colObjectID,
@@ -42,6 +61,7 @@ var (
 		colUsersetObjectID,
 		colUsersetRelation,
 		colCaveatContextName,
+		colExpiration,
 		common.TupleComparison,
 	)
 
@@ -60,7 +80,7 @@ func (r *pgReader) QueryRelationships(
 	filter datastore.RelationshipsFilter,
 	opts ...options.QueryOptionsOption,
 ) (iter datastore.RelationshipIterator, err error) {
-	qBuilder, err := common.NewSchemaQueryFilterer(schema, r.filterer(queryTuples)).FilterWithRelationshipsFilter(filter)
+	qBuilder, err := common.NewSchemaQueryFilterer(schema, r.filterer(queryTuples).Where(r.notExpired)).FilterWithRelationshipsFilter(filter)
 	if err != nil {
 		return nil, err
 	}
@@ -73,7 +93,7 @@ func (r *pgReader) ReverseQueryRelationships(
 	subjectsFilter datastore.SubjectsFilter,
 	opts ...options.ReverseQueryOptionsOption,
 ) (iter datastore.RelationshipIterator, err error) {
-	qBuilder, err := common.NewSchemaQueryFilterer(schema, r.filterer(queryTuples)).
+	qBuilder, err := common.NewSchemaQueryFilterer(schema, r.filterer(queryTuples).Where(r.notExpired)).
 		FilterWithSubjectsSelectors(subjectsFilter.AsSelector())
 	if err != nil {
 		return nil, err
@@ -90,6 +110,8 @@ func (r *pgReader) ReverseQueryRelationships(
 	return r.querySplitter.SplitAndExecuteQuery(ctx,
 		qBuilder,
 		options.WithLimit(queryOpts.ReverseLimit),
+		options.WithSort(queryOpts.SortForReverse),
+		options.WithAfter(queryOpts.AfterForReverse),
 	)
 }
 
@@ -11,8 +11,10 @@ import (
 	"github.com/jzelinskie/stringz"
 	"google.golang.org/protobuf/proto"
 
+	"github.com/authzed/spicedb/internal/datastore/common"
 	pgxcommon "github.com/authzed/spicedb/internal/datastore/postgres/common"
 	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/options"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 )
 
@@ -21,6 +23,7 @@ const (
 	errUnableToDeleteConfig        = "unable to delete namespace config: %w"
 	errUnableToWriteRelationships  = "unable to write relationships: %w"
 	errUnableToDeleteRelationships = "unable to delete relationships: %w"
+	errUnableToCheckChanges        = "unable to check for changed relationships: %w"
 )
 
 var (
@@ -42,6 +45,7 @@ var (
 		colUsersetRelation,
 		colCaveatContextName,
 		colCaveatContext,
+		colExpiration,
 	)
 
 	deleteTuple = psql.Update(tableTuple).Where(sq.Eq{colDeletedXid: liveDeletedTxnID})
@@ -66,6 +70,11 @@ func (rwt *pgReadWriteTXN) WriteRelationships(ctx context.Context, mutations []*
 			deleteClauses = append(deleteClauses, exactRelationshipClause(tpl))
 		}
 
+		// An expired relationship does not prevent the creation of a new one in its place.
+		if mut.Operation == core.RelationTupleUpdate_CREATE {
+			deleteClauses = append(deleteClauses, sq.And{exactRelationshipClause(tpl), expiredClause})
+		}
+
 		if mut.Operation == core.RelationTupleUpdate_TOUCH || mut.Operation == core.RelationTupleUpdate_CREATE {
 			var caveatName string
 			var caveatContext map[string]any
@@ -82,6 +91,7 @@ func (rwt *pgReadWriteTXN) WriteRelationships(ctx context.Context, mutations []*
 				tpl.Subject.Relation,
 				caveatName,
 				caveatContext, // PGX driver serializes map[string]any to JSONB type columns
+				common.ExpirationTimeFrom(tpl),
 			}
 
 			bulkWrite = bulkWrite.Values(valuesToWrite...)
@@ -123,37 +133,116 @@ func (rwt *pgReadWriteTXN) WriteRelationships(ctx context.Context, mutations []*
 	return nil
 }
 
-func (rwt *pgReadWriteTXN) DeleteRelationships(ctx context.Context, filter *v1.RelationshipFilter) error {
+func (rwt *pgReadWriteTXN) DeleteRelationships(ctx context.Context, filter *v1.RelationshipFilter, opts ...options.DeleteOptionsOption) (uint64, bool, error) {
+	delOpts := options.NewDeleteOptionsWithOptions(opts...)
+	filterClause := relationshipFilterClause(filter)
+
+	query := deleteTuple.Where(filterClause)
+	if delOpts.DeleteLimit != nil {
+		// Postgres does not support a LIMIT on an UPDATE, so the relationships to be deleted
+		// are selected by a limited subquery.
+		limited := sq.Select(
+			colNamespace,
+			colObjectID,
+			colRelation,
+			colUsersetNamespace,
+			colUsersetObjectID,
+			colUsersetRelation,
+		).
+			From(tableTuple).
+			Where(sq.Eq{colDeletedXid: liveDeletedTxnID}).
+			Where(filterClause).
+			Limit(*delOpts.DeleteLimit)
+
+		query = deleteTuple.Where(sq.Expr(fmt.Sprintf(
+			"(%s, %s, %s, %s, %s, %s) IN (?)",
+			colNamespace,
+			colObjectID,
+			colRelation,
+			colUsersetNamespace,
+			colUsersetObjectID,
+			colUsersetRelation,
+		), limited))
+	}
+
+	sql, args, err := query.Set(colDeletedXid, rwt.newXID).ToSql()
+	if err != nil {
+		return 0, false, fmt.Errorf(errUnableToDeleteRelationships, err)
+	}
+
+	result, err := rwt.tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, false, fmt.Errorf(errUnableToDeleteRelationships, err)
+	}
+
+	deleted := uint64(result.RowsAffected())
+	if delOpts.DeleteLimit == nil || deleted < *delOpts.DeleteLimit {
+		return deleted, false, nil
+	}
+
+	remain, err := common.RelationshipsRemain(ctx, rwt, filter)
+	if err != nil {
+		return 0, false, fmt.Errorf(errUnableToDeleteRelationships, err)
+	}
+
+	return deleted, remain, nil
+}
+
+func (rwt *pgReadWriteTXN) RelationshipsChangedSince(ctx context.Context, filter *v1.RelationshipFilter, revision datastore.Revision) (bool, error) {
+	snapshot := revision.(postgresRevision).snapshot
+
+	// A relationship has changed if the transaction which created or deleted it is not
+	// visible in the snapshot of the revision. Deleted relationships are retained until
+	// garbage collected, so deletions within the GC window are found.
+	createdAfter := sq.Expr(fmt.Sprintf(snapshotAlive, colCreatedXid), snapshot, false)
+	deletedAfter := sq.And{
+		sq.NotEq{colDeletedXid: liveDeletedTxnID},
+		sq.Expr(fmt.Sprintf(snapshotAlive, colDeletedXid), snapshot, false),
+	}
+
+	sql, args, err := psql.Select("1").
+		From(tableTuple).
+		Where(relationshipFilterClause(filter)).
+		Where(sq.Or{createdAfter, deletedAfter}).
+		Limit(1).
+		ToSql()
+	if err != nil {
+		return false, fmt.Errorf(errUnableToCheckChanges, err)
+	}
+
+	var found int
+	if err := rwt.tx.QueryRow(ctx, sql, args...).Scan(&found); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf(errUnableToCheckChanges, err)
+	}
+
+	return true, nil
+}
+
+func relationshipFilterClause(filter *v1.RelationshipFilter) sq.And {
 	// Add clauses for the ResourceFilter
-	query := deleteTuple.Where(sq.Eq{colNamespace: filter.ResourceType})
+	clause := sq.And{sq.Eq{colNamespace: filter.ResourceType}}
 	if filter.OptionalResourceId != "" {
-		query = query.Where(sq.Eq{colObjectID: filter.OptionalResourceId})
+		clause = append(clause, sq.Eq{colObjectID: filter.OptionalResourceId})
 	}
 	if filter.OptionalRelation != "" {
-		query = query.Where(sq.Eq{colRelation: filter.OptionalRelation})
+		clause = append(clause, sq.Eq{colRelation: filter.OptionalRelation})
 	}
 
 	// Add clauses for the SubjectFilter
 	if subjectFilter := filter.OptionalSubjectFilter; subjectFilter != nil {
-		query = query.Where(sq.Eq{colUsersetNamespace: subjectFilter.SubjectType})
+		clause = append(clause, sq.Eq{colUsersetNamespace: subjectFilter.SubjectType})
 		if subjectFilter.OptionalSubjectId != "" {
-			query = query.Where(sq.Eq{colUsersetObjectID: subjectFilter.OptionalSubjectId})
+			clause = append(clause, sq.Eq{colUsersetObjectID: subjectFilter.OptionalSubjectId})
 		}
 		if relationFilter := subjectFilter.OptionalRelation; relationFilter != nil {
-			query = query.Where(sq.Eq{colUsersetRelation: stringz.DefaultEmpty(relationFilter.Relation, datastore.Ellipsis)})
+			clause = append(clause, sq.Eq{colUsersetRelation: stringz.DefaultEmpty(relationFilter.Relation, datastore.Ellipsis)})
 		}
 	}
 
-	sql, args, err := query.Set(colDeletedXid, rwt.newXID).ToSql()
-	if err != nil {
-		return fmt.Errorf(errUnableToDeleteRelationships, err)
-	}
-
-	if _, err := rwt.tx.Exec(ctx, sql, args...); err != nil {
-		return fmt.Errorf(errUnableToDeleteRelationships, err)
-	}
-
-	return nil
+	return clause
 }
 
 func (rwt *pgReadWriteTXN) WriteNamespaces(ctx context.Context, newConfigs ...*core.NamespaceDefinition) error {
@@ -249,6 +338,84 @@ func (rwt *pgReadWriteTXN) DeleteNamespaces(ctx context.Context, nsNames ...stri
 	return nil
 }
 
+var copyColumns = []string{
+	colNamespace,
+	colObjectID,
+	colRelation,
+	colUsersetNamespace,
+	colUsersetObjectID,
+	colUsersetRelation,
+	colCaveatContextName,
+	colCaveatContext,
+	colExpiration,
+}
+
+// tupleSourceAdapter adapts a BulkWriteRelationshipSource to the pgx.CopyFromSource interface.
+type tupleSourceAdapter struct {
+	ctx    context.Context
+	source datastore.BulkWriteRelationshipSource
+
+	current    *core.RelationTuple
+	err        error
+	numWritten uint64
+	values     []any
+}
+
+func (tsa *tupleSourceAdapter) Next() bool {
+	tsa.current, tsa.err = tsa.source.Next(tsa.ctx)
+	if tsa.current == nil {
+		return false
+	}
+
+	tsa.numWritten++
+	return true
+}
+
+func (tsa *tupleSourceAdapter) Values() ([]any, error) {
+	var caveatName string
+	var caveatContext map[string]any
+	if tsa.current.Caveat != nil {
+		caveatName = tsa.current.Caveat.CaveatName
+		caveatContext = tsa.current.Caveat.Context.AsMap()
+	}
+
+	tsa.values = append(tsa.values[:0],
+		tsa.current.ResourceAndRelation.Namespace,
+		tsa.current.ResourceAndRelation.ObjectId,
+		tsa.current.ResourceAndRelation.Relation,
+		tsa.current.Subject.Namespace,
+		tsa.current.Subject.ObjectId,
+		tsa.current.Subject.Relation,
+		caveatName,
+		caveatContext,
+		common.ExpirationTimeFrom(tsa.current),
+	)
+	return tsa.values, nil
+}
+
+func (tsa *tupleSourceAdapter) Err() error {
+	return tsa.err
+}
+
+func (rwt *pgReadWriteTXN) BulkLoad(ctx context.Context, iter datastore.BulkWriteRelationshipSource) (uint64, error) {
+	adapter := &tupleSourceAdapter{
+		ctx:    ctx,
+		source: iter,
+		values: make([]any, 0, len(copyColumns)),
+	}
+
+	// COPY fills in the created_xid column from its default of the current transaction ID.
+	if _, err := rwt.tx.CopyFrom(ctx, pgx.Identifier{tableTuple}, copyColumns, adapter); err != nil {
+		if cerr := pgxcommon.ConvertToWriteConstraintError(livingTupleConstraint, err); cerr != nil {
+			return 0, cerr
+		}
+
+		return 0, fmt.Errorf(errUnableToWriteRelationships, err)
+	}
+
+	return adapter.numWritten, nil
+}
+
 func exactRelationshipClause(r *core.RelationTuple) sq.Eq {
 	return sq.Eq{
 		colNamespace:        r.ResourceAndRelation.Namespace,
@@ -9,8 +9,10 @@ import (
 	"strings"
 	"time"
 
+	sq "github.com/Masterminds/squirrel"
 	"github.com/jackc/pgx/v5"
 
+	"github.com/authzed/spicedb/internal/datastore/common"
 	"github.com/authzed/spicedb/pkg/datastore"
 	implv1 "github.com/authzed/spicedb/pkg/proto/impl/v1"
 )
@@ -20,6 +22,8 @@ const (
 	errCheckRevision  = "unable to check revision: %w"
 	errRevisionFormat = "invalid revision format: %w"
 
+	errCheckIdempotencyKey = "unable to check idempotency key: %w"
+
 	// querySelectRevision will round the database's timestamp down to the nearest
 	// quantization period, and then find the first transaction (and its active xmin)
 	// after that. If there are no transactions newer than the quantization period,
@@ -216,14 +220,45 @@ func parseRevisionDecimal(revisionStr string) (datastore.Revision, error) {
 	}}, nil
 }
 
-func createNewTransaction(ctx context.Context, tx pgx.Tx) (newXID xid8, newSnapshot pgSnapshot, err error) {
+func createNewTransaction(ctx context.Context, tx pgx.Tx, idempotencyKey string, requestHash string) (newXID xid8, newSnapshot pgSnapshot, err error) {
 	ctx, span := tracer.Start(ctx, "createNewTransaction")
 	defer span.End()
 
+	if idempotencyKey != "" {
+		err = tx.QueryRow(ctx, createTxnWithIdempotencyKey, idempotencyKey, requestHash).Scan(&newXID, &newSnapshot)
+		return
+	}
+
 	err = tx.QueryRow(ctx, createTxn).Scan(&newXID, &newSnapshot)
 	return
 }
 
+// checkIdempotencyKey returns an ErrIdempotencyKeyAlreadyCommitted if a transaction has already
+// committed with the given key, or an ErrIdempotencyKeyReused if it did so for a different request.
+// Keys are removed along with their transactions by the GC.
+func checkIdempotencyKey(ctx context.Context, tx pgx.Tx, idempotencyKey string, requestHash string) error {
+	ctx, span := tracer.Start(ctx, "checkIdempotencyKey")
+	defer span.End()
+
+	sql, args, err := getIdempotencyKeyTxn.Where(sq.Eq{colIdempotencyKey: idempotencyKey}).ToSql()
+	if err != nil {
+		return fmt.Errorf(errCheckIdempotencyKey, err)
+	}
+
+	var committedXID xid8
+	var committedSnapshot pgSnapshot
+	var committedRequestHash string
+	if err := tx.QueryRow(ctx, sql, args...).Scan(&committedXID, &committedSnapshot, &committedRequestHash); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf(errCheckIdempotencyKey, err)
+	}
+
+	committed := postgresRevision{committedSnapshot.markComplete(committedXID.Uint64)}
+	return common.IdempotencyKeyCommittedErr(idempotencyKey, requestHash, committedRequestHash, committed)
+}
+
 type postgresRevision struct {
 	snapshot pgSnapshot
 }
@@ -43,14 +43,29 @@ var (
 		colUsersetRelation,
 		colCaveatContextName,
 		colCaveatContext,
+		colExpiration,
 		colCreatedXid,
 		colDeletedXid,
 	).From(tableTuple)
+
+	queryChangedNamespaces = psql.Select(
+		colConfig,
+		colCreatedXid,
+		colDeletedXid,
+	).From(tableNamespace)
+
+	queryChangedCaveats = psql.Select(
+		colCaveatName,
+		colCaveatDefinition,
+		colCreatedXid,
+		colDeletedXid,
+	).From(tableCaveat)
 )
 
 func (pgd *pgDatastore) Watch(
 	ctx context.Context,
 	afterRevisionRaw datastore.Revision,
+	options datastore.WatchOptions,
 ) (<-chan *datastore.RevisionChanges, <-chan error) {
 	updates := make(chan *datastore.RevisionChanges, pgd.watchBufferLength)
 	errs := make(chan error, 1)
@@ -80,7 +95,7 @@ func (pgd *pgDatastore) Watch(
 			}
 
 			if len(newTxns) > 0 {
-				changesToWrite, err := pgd.loadChanges(ctx, newTxns)
+				changesToWrite, err := pgd.loadChanges(ctx, newTxns, options)
 				if err != nil {
 					if errors.Is(ctx.Err(), context.Canceled) {
 						errs <- datastore.NewWatchCanceledErr()
@@ -103,6 +118,12 @@ func (pgd *pgDatastore) Watch(
 
 					currentTxn = changeToWrite.Revision.(revisionWithXid).postgresRevision
 				}
+
+				// All changes through the latest of the new transactions have been emitted,
+				// even if that transaction itself made no changes.
+				latestTxn := newTxns[len(newTxns)-1]
+				currentTxn = latestTxn.postgresRevision
+				common.SendCheckpoint(updates, latestTxn)
 			} else {
 				sleep := time.NewTimer(watchSleep)
 
@@ -152,7 +173,7 @@ func (pgd *pgDatastore) getNewRevisions(ctx context.Context, afterTX postgresRev
 	return ids, nil
 }
 
-func (pgd *pgDatastore) loadChanges(ctx context.Context, revisions []revisionWithXid) ([]datastore.RevisionChanges, error) {
+func (pgd *pgDatastore) loadChanges(ctx context.Context, revisions []revisionWithXid, options datastore.WatchOptions) ([]datastore.RevisionChanges, error) {
 	min := revisions[0].tx.Uint64
 	max := revisions[0].tx.Uint64
 	filter := make(map[uint64]int, len(revisions))
@@ -169,7 +190,7 @@ func (pgd *pgDatastore) loadChanges(ctx context.Context, revisions []revisionWit
 		txidToRevision[rev.tx.Uint64] = rev
 	}
 
-	sql, args, err := queryChanged.Where(sq.Or{
+	xidFilter := sq.Or{
 		sq.And{
 			sq.LtOrEq{colCreatedXid: max},
 			sq.GtOrEq{colCreatedXid: min},
@@ -178,17 +199,55 @@ func (pgd *pgDatastore) loadChanges(ctx context.Context, revisions []revisionWit
 			sq.LtOrEq{colDeletedXid: max},
 			sq.GtOrEq{colDeletedXid: min},
 		},
-	}).ToSql()
+	}
+
+	tracked := common.NewChanges(revisionKeyFunc)
+	if err := pgd.loadRelationshipChanges(ctx, xidFilter, options, filter, txidToRevision, tracked); err != nil {
+		return nil, err
+	}
+
+	if err := pgd.loadNamespaceChanges(ctx, xidFilter, filter, txidToRevision, tracked); err != nil {
+		return nil, err
+	}
+
+	if err := pgd.loadCaveatChanges(ctx, xidFilter, filter, txidToRevision, tracked); err != nil {
+		return nil, err
+	}
+
+	reconciledChanges := tracked.AsRevisionChanges(func(lhs, rhs uint64) bool {
+		return filter[lhs] < filter[rhs]
+	})
+	return reconciledChanges, nil
+}
+
+func (pgd *pgDatastore) loadRelationshipChanges(
+	ctx context.Context,
+	xidFilter sq.Sqlizer,
+	options datastore.WatchOptions,
+	filter map[uint64]int,
+	txidToRevision map[uint64]revisionWithXid,
+	tracked common.Changes[revisionWithXid, uint64],
+) error {
+	query := queryChanged.Where(xidFilter)
+	if len(options.OptionalRelationshipsFilters) > 0 {
+		relationshipsFilter, err := schema.RelationshipsFiltersClause(options.OptionalRelationshipsFilters)
+		if err != nil {
+			return err
+		}
+		query = query.Where(relationshipsFilter)
+	}
+
+	sql, args, err := query.ToSql()
 	if err != nil {
-		return nil, fmt.Errorf("unable to prepare changes SQL: %w", err)
+		return fmt.Errorf("unable to prepare changes SQL: %w", err)
 	}
 
 	changes, err := pgd.readPool.Query(ctx, sql, args...)
 	if err != nil {
-		return nil, fmt.Errorf("unable to load changes for XID: %w", err)
+		return fmt.Errorf("unable to load changes for XID: %w", err)
 	}
+	defer changes.Close()
 
-	tracked := common.NewChanges(revisionKeyFunc)
 	for changes.Next() {
 		nextTuple := &core.RelationTuple{
 			ResourceAndRelation: &core.ObjectAndRelation{},
@@ -198,6 +257,7 @@ func (pgd *pgDatastore) loadChanges(ctx context.Context, revisions []revisionWit
 		var createdXID, deletedXID xid8
 		var caveatName string
 		var caveatContext map[string]any
+		var expiration *time.Time
 		if err := changes.Scan(
 			&nextTuple.ResourceAndRelation.Namespace,
 			&nextTuple.ResourceAndRelation.ObjectId,
@@ -207,16 +267,17 @@ func (pgd *pgDatastore) loadChanges(ctx context.Context, revisions []revisionWit
 			&nextTuple.Subject.Relation,
 			&caveatName,
 			&caveatContext,
+			&expiration,
 			&createdXID,
 			&deletedXID,
 		); err != nil {
-			return nil, fmt.Errorf("unable to parse changed tuple: %w", err)
+			return fmt.Errorf("unable to parse changed tuple: %w", err)
 		}
 
 		if caveatName != "" {
 			contextStruct, err := structpb.NewStruct(caveatContext)
 			if err != nil {
-				return nil, fmt.Errorf("failed to read caveat context from update: %w", err)
+				return fmt.Errorf("failed to read caveat context from update: %w", err)
 			}
 			nextTuple.Caveat = &core.ContextualizedCaveat{
 				CaveatName: caveatName,
@@ -224,6 +285,8 @@ func (pgd *pgDatastore) loadChanges(ctx context.Context, revisions []revisionWit
 			}
 		}
 
+		nextTuple.OptionalExpirationTime = common.ExpirationTimestampFrom(expiration)
+
 		if _, found := filter[createdXID.Uint64]; found {
 			tracked.AddChange(ctx, txidToRevision[createdXID.Uint64], nextTuple, core.RelationTupleUpdate_TOUCH)
 		}
@@ -232,11 +295,93 @@ func (pgd *pgDatastore) loadChanges(ctx context.Context, revisions []revisionWit
 		}
 	}
 	if changes.Err() != nil {
-		return nil, fmt.Errorf("unable to load changes for XID: %w", err)
+		return fmt.Errorf("unable to load changes for XID: %w", changes.Err())
 	}
+	return nil
+}
 
-	reconciledChanges := tracked.AsRevisionChanges(func(lhs, rhs uint64) bool {
-		return filter[lhs] < filter[rhs]
-	})
-	return reconciledChanges, nil
+func (pgd *pgDatastore) loadNamespaceChanges(
+	ctx context.Context,
+	xidFilter sq.Sqlizer,
+	filter map[uint64]int,
+	txidToRevision map[uint64]revisionWithXid,
+	tracked common.Changes[revisionWithXid, uint64],
+) error {
+	sql, args, err := queryChangedNamespaces.Where(xidFilter).ToSql()
+	if err != nil {
+		return fmt.Errorf("unable to prepare namespace changes SQL: %w", err)
+	}
+
+	changes, err := pgd.readPool.Query(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("unable to load namespace changes for XID: %w", err)
+	}
+	defer changes.Close()
+
+	for changes.Next() {
+		var createdXID, deletedXID xid8
+		var config []byte
+		if err := changes.Scan(&config, &createdXID, &deletedXID); err != nil {
+			return fmt.Errorf("unable to parse changed namespace: %w", err)
+		}
+
+		loaded := &core.NamespaceDefinition{}
+		if err := loaded.UnmarshalVT(config); err != nil {
+			return fmt.Errorf(errUnableToReadConfig, err)
+		}
+
+		if _, found := filter[createdXID.Uint64]; found {
+			tracked.AddChangedDefinition(ctx, txidToRevision[createdXID.Uint64], loaded)
+		}
+		if _, found := filter[deletedXID.Uint64]; found {
+			tracked.AddDeletedNamespace(ctx, txidToRevision[deletedXID.Uint64], loaded.Name)
+		}
+	}
+	if changes.Err() != nil {
+		return fmt.Errorf("unable to load namespace changes for XID: %w", changes.Err())
+	}
+	return nil
+}
+
+func (pgd *pgDatastore) loadCaveatChanges(
+	ctx context.Context,
+	xidFilter sq.Sqlizer,
+	filter map[uint64]int,
+	txidToRevision map[uint64]revisionWithXid,
+	tracked common.Changes[revisionWithXid, uint64],
+) error {
+	sql, args, err := queryChangedCaveats.Where(xidFilter).ToSql()
+	if err != nil {
+		return fmt.Errorf("unable to prepare caveat changes SQL: %w", err)
+	}
+
+	changes, err := pgd.readPool.Query(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("unable to load caveat changes for XID: %w", err)
+	}
+	defer changes.Close()
+
+	for changes.Next() {
+		var createdXID, deletedXID xid8
+		var name string
+		var definitionBytes []byte
+		if err := changes.Scan(&name, &definitionBytes, &createdXID, &deletedXID); err != nil {
+			return fmt.Errorf("unable to parse changed caveat: %w", err)
+		}
+
+		if _, found := filter[createdXID.Uint64]; found {
+			loaded := &core.CaveatDefinition{}
+			if err := loaded.UnmarshalVT(definitionBytes); err != nil {
+				return fmt.Errorf(errReadCaveat, err)
+			}
+			tracked.AddChangedDefinition(ctx, txidToRevision[createdXID.Uint64], loaded)
+		}
+		if _, found := filter[deletedXID.Uint64]; found {
+			tracked.AddDeletedCaveat(ctx, txidToRevision[deletedXID.Uint64], name)
+		}
+	}
+	if changes.Err() != nil {
+		return fmt.Errorf("unable to load caveat changes for XID: %w", changes.Err())
+	}
+	return nil
 }
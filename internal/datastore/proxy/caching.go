@@ -17,6 +17,7 @@ import (
 
 	"github.com/authzed/spicedb/pkg/cache"
 	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/options"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 )
 
@@ -79,11 +80,12 @@ func (p *definitionCachingProxy) SnapshotReader(rev datastore.Revision) datastor
 func (p *definitionCachingProxy) ReadWriteTx(
 	ctx context.Context,
 	f datastore.TxUserFunc,
+	opts ...options.RWTOptionsOption,
 ) (datastore.Revision, error) {
 	return p.Datastore.ReadWriteTx(ctx, func(delegateRWT datastore.ReadWriteTransaction) error {
 		rwt := &definitionCachingRWT{delegateRWT, &sync.Map{}}
 		return f(rwt)
-	})
+	}, opts...)
 }
 
 const (
@@ -0,0 +1,184 @@
+package proxy
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/options"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// NewRelationshipsOverlayDatastore creates a proxy which overlays the given relationships on
+// those read from the delegate datastore, as if they had been written at every revision. An
+// overlaid relationship replaces any stored relationship which differs only by caveat.
+//
+// If the delegate is itself an overlay, its relationships are replaced rather than added to.
+func NewRelationshipsOverlayDatastore(delegate datastore.Datastore, relationships []*core.RelationTuple) datastore.Datastore {
+	if overlay, ok := delegate.(*overlayDatastore); ok {
+		delegate = overlay.Datastore
+	}
+
+	return &overlayDatastore{Datastore: delegate, relationships: relationships}
+}
+
+type overlayDatastore struct {
+	datastore.Datastore
+	relationships []*core.RelationTuple
+}
+
+func (od *overlayDatastore) SnapshotReader(rev datastore.Revision) datastore.Reader {
+	return &overlayReader{Reader: od.Datastore.SnapshotReader(rev), relationships: od.relationships}
+}
+
+func (od *overlayDatastore) Unwrap() datastore.Datastore {
+	return od.Datastore
+}
+
+type overlayReader struct {
+	datastore.Reader
+	relationships []*core.RelationTuple
+}
+
+func (r *overlayReader) QueryRelationships(ctx context.Context, filter datastore.RelationshipsFilter, opts ...options.QueryOptionsOption) (datastore.RelationshipIterator, error) {
+	queryOpts := options.NewQueryOptionsWithOptions(opts...)
+
+	overlaid := r.matching(func(tpl *core.RelationTuple) bool {
+		return filter.Test(tpl) && matchesUsersets(tpl, queryOpts.Usersets) && isAfter(tpl, queryOpts.After, queryOpts.Sort)
+	})
+	if len(overlaid) == 0 {
+		return r.Reader.QueryRelationships(ctx, filter, opts...)
+	}
+
+	iter, err := r.Reader.QueryRelationships(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeOverlaid(iter, overlaid, queryOpts.Limit, queryOpts.Sort)
+}
+
+func (r *overlayReader) ReverseQueryRelationships(ctx context.Context, subjectsFilter datastore.SubjectsFilter, opts ...options.ReverseQueryOptionsOption) (datastore.RelationshipIterator, error) {
+	queryOpts := options.NewReverseQueryOptionsWithOptions(opts...)
+	subjectsSelector := subjectsFilter.AsSelector()
+
+	overlaid := r.matching(func(tpl *core.RelationTuple) bool {
+		if !subjectsSelector.Test(tpl.Subject) {
+			return false
+		}
+
+		if queryOpts.ResRelation != nil && (queryOpts.ResRelation.Namespace != tpl.ResourceAndRelation.Namespace ||
+			queryOpts.ResRelation.Relation != tpl.ResourceAndRelation.Relation) {
+			return false
+		}
+
+		return isAfter(tpl, queryOpts.AfterForReverse, queryOpts.SortForReverse)
+	})
+	if len(overlaid) == 0 {
+		return r.Reader.ReverseQueryRelationships(ctx, subjectsFilter, opts...)
+	}
+
+	iter, err := r.Reader.ReverseQueryRelationships(ctx, subjectsFilter, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeOverlaid(iter, overlaid, queryOpts.ReverseLimit, queryOpts.SortForReverse)
+}
+
+func (r *overlayReader) matching(predicate func(tpl *core.RelationTuple) bool) []*core.RelationTuple {
+	var found []*core.RelationTuple
+	for _, tpl := range r.relationships {
+		if predicate(tpl) {
+			found = append(found, tpl)
+		}
+	}
+	return found
+}
+
+// mergeOverlaid reads all relationships from the given iterator, merges in the overlaid
+// relationships and returns an iterator over the merged set, in the requested order and
+// truncated to the limit.
+func mergeOverlaid(iter datastore.RelationshipIterator, overlaid []*core.RelationTuple, limit *uint64, order options.SortOrder) (datastore.RelationshipIterator, error) {
+	defer iter.Close()
+
+	merged := make([]*core.RelationTuple, 0, len(overlaid))
+	merged = append(merged, overlaid...)
+
+	encountered := make(map[string]struct{}, len(overlaid))
+	for _, tpl := range overlaid {
+		encountered[tuple.StringWithoutCaveat(tpl)] = struct{}{}
+	}
+
+	for tpl := iter.Next(); tpl != nil; tpl = iter.Next() {
+		if _, ok := encountered[tuple.StringWithoutCaveat(tpl)]; ok {
+			continue
+		}
+		merged = append(merged, tpl)
+	}
+	if iter.Err() != nil {
+		return nil, iter.Err()
+	}
+
+	if order == options.ByResource {
+		sort.SliceStable(merged, func(i, j int) bool {
+			return lessByResource(merged[i], merged[j])
+		})
+	}
+
+	if limit != nil && uint64(len(merged)) > *limit {
+		merged = merged[:*limit]
+	}
+
+	return common.NewSliceRelationshipIterator(merged, order), nil
+}
+
+func matchesUsersets(tpl *core.RelationTuple, usersets []*core.ObjectAndRelation) bool {
+	if len(usersets) == 0 {
+		return true
+	}
+
+	for _, userset := range usersets {
+		if tpl.Subject.Namespace == userset.Namespace &&
+			tpl.Subject.ObjectId == userset.ObjectId &&
+			tpl.Subject.Relation == userset.Relation {
+			return true
+		}
+	}
+	return false
+}
+
+// isAfter returns whether the relationship is after the cursor in the given order. Without
+// a cursor or an order, all relationships are considered to be after.
+func isAfter(tpl *core.RelationTuple, after options.Cursor, order options.SortOrder) bool {
+	if after == nil || order != options.ByResource {
+		return true
+	}
+	return lessByResource(after, tpl)
+}
+
+func lessByResource(lhs, rhs *core.RelationTuple) bool {
+	if c := compareONR(lhs.ResourceAndRelation, rhs.ResourceAndRelation); c != 0 {
+		return c < 0
+	}
+	return compareONR(lhs.Subject, rhs.Subject) < 0
+}
+
+func compareONR(lhs, rhs *core.ObjectAndRelation) int {
+	switch {
+	case lhs.Namespace != rhs.Namespace:
+		return strings.Compare(lhs.Namespace, rhs.Namespace)
+	case lhs.ObjectId != rhs.ObjectId:
+		return strings.Compare(lhs.ObjectId, rhs.ObjectId)
+	default:
+		return strings.Compare(lhs.Relation, rhs.Relation)
+	}
+}
+
+var (
+	_ datastore.Datastore = (*overlayDatastore)(nil)
+	_ datastore.Reader    = (*overlayReader)(nil)
+)
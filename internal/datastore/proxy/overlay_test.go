@@ -0,0 +1,166 @@
+package proxy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/options"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func TestOverlayQueryRelationships(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	ds, revision := testfixtures.StandardDatastoreWithData(rawDS, require)
+
+	overlaid := NewRelationshipsOverlayDatastore(ds, []*core.RelationTuple{
+		tuple.MustParse("document:masterplan#viewer@user:villain"),
+		tuple.MustParse("document:masterplan#owner@user:product_manager[test]"),
+		tuple.MustParse("document:newplan#viewer@user:villain"),
+		tuple.MustParse("folder:plans#viewer@user:villain"),
+	})
+	reader := overlaid.SnapshotReader(revision)
+
+	testCases := []struct {
+		name     string
+		filter   datastore.RelationshipsFilter
+		options  []options.QueryOptionsOption
+		expected []string
+	}{
+		{
+			"resource with overlaid relationships",
+			datastore.RelationshipsFilter{
+				ResourceType:        "document",
+				OptionalResourceIds: []string{"masterplan"},
+			},
+			[]options.QueryOptionsOption{options.WithSort(options.ByResource)},
+			[]string{
+				"document:masterplan#owner@user:product_manager[test]",
+				"document:masterplan#parent@folder:plans",
+				"document:masterplan#parent@folder:strategy",
+				"document:masterplan#viewer@user:eng_lead",
+				"document:masterplan#viewer@user:villain",
+			},
+		},
+		{
+			"limited",
+			datastore.RelationshipsFilter{
+				ResourceType:             "document",
+				OptionalResourceRelation: "viewer",
+			},
+			[]options.QueryOptionsOption{options.WithSort(options.ByResource), options.WithLimit(options.LimitOne)},
+			[]string{
+				"document:masterplan#viewer@user:eng_lead",
+			},
+		},
+		{
+			"after cursor",
+			datastore.RelationshipsFilter{
+				ResourceType:             "document",
+				OptionalResourceRelation: "viewer",
+			},
+			[]options.QueryOptionsOption{
+				options.WithSort(options.ByResource),
+				options.WithAfter(tuple.MustParse("document:masterplan#viewer@user:eng_lead")),
+			},
+			[]string{
+				"document:masterplan#viewer@user:villain",
+				"document:newplan#viewer@user:villain",
+			},
+		},
+		{
+			"usersets",
+			datastore.RelationshipsFilter{
+				ResourceType: "folder",
+			},
+			[]options.QueryOptionsOption{
+				options.WithUsersets(tuple.ParseSubjectONR("user:villain")),
+			},
+			[]string{
+				"folder:isolated#viewer@user:villain",
+				"folder:plans#viewer@user:villain",
+			},
+		},
+		{
+			"no overlaid relationships",
+			datastore.RelationshipsFilter{
+				ResourceType:        "document",
+				OptionalResourceIds: []string{"healthplan"},
+			},
+			nil,
+			[]string{
+				"document:healthplan#parent@folder:plans",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			iter, err := reader.QueryRelationships(ctx, tc.filter, tc.options...)
+			require.NoError(err)
+			require.ElementsMatch(tc.expected, collectTuples(t, iter))
+		})
+	}
+}
+
+func TestOverlayReverseQueryRelationships(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	ds, revision := testfixtures.StandardDatastoreWithData(rawDS, require)
+
+	overlaid := NewRelationshipsOverlayDatastore(ds, []*core.RelationTuple{
+		tuple.MustParse("document:masterplan#viewer@user:villain"),
+		tuple.MustParse("folder:plans#viewer@user:villain"),
+	})
+
+	// Wrapping an overlay replaces its relationships.
+	overlaid = NewRelationshipsOverlayDatastore(overlaid, []*core.RelationTuple{
+		tuple.MustParse("document:masterplan#viewer@user:villain"),
+	})
+	reader := overlaid.SnapshotReader(revision)
+
+	iter, err := reader.ReverseQueryRelationships(ctx, datastore.SubjectsFilter{
+		SubjectType:        "user",
+		OptionalSubjectIds: []string{"villain"},
+	})
+	require.NoError(err)
+	require.ElementsMatch([]string{
+		"document:masterplan#viewer@user:villain",
+		"folder:isolated#viewer@user:villain",
+	}, collectTuples(t, iter))
+
+	iter, err = reader.ReverseQueryRelationships(ctx, datastore.SubjectsFilter{
+		SubjectType:        "user",
+		OptionalSubjectIds: []string{"villain"},
+	}, options.WithResRelation(&options.ResourceRelation{Namespace: "folder", Relation: "viewer"}))
+	require.NoError(err)
+	require.ElementsMatch([]string{
+		"folder:isolated#viewer@user:villain",
+	}, collectTuples(t, iter))
+}
+
+func collectTuples(t *testing.T, iter datastore.RelationshipIterator) []string {
+	defer iter.Close()
+
+	found := make([]string, 0)
+	for tpl := iter.Next(); tpl != nil; tpl = iter.Next() {
+		found = append(found, tuple.MustString(tpl))
+	}
+	require.NoError(t, iter.Err())
+	return found
+}
@@ -23,6 +23,7 @@ func (dm *MockDatastore) SnapshotReader(rev datastore.Revision) datastore.Reader
 func (dm *MockDatastore) ReadWriteTx(
 	_ context.Context,
 	f datastore.TxUserFunc,
+	_ ...options.RWTOptionsOption,
 ) (datastore.Revision, error) {
 	args := dm.Called()
 	mockRWT := args.Get(0).(datastore.ReadWriteTransaction)
@@ -54,8 +55,8 @@ func (dm *MockDatastore) RevisionFromString(s string) (datastore.Revision, error
 	return args.Get(0).(datastore.Revision), args.Error(1)
 }
 
-func (dm *MockDatastore) Watch(_ context.Context, afterRevision datastore.Revision) (<-chan *datastore.RevisionChanges, <-chan error) {
-	args := dm.Called(afterRevision)
+func (dm *MockDatastore) Watch(_ context.Context, afterRevision datastore.Revision, options datastore.WatchOptions) (<-chan *datastore.RevisionChanges, <-chan error) {
+	args := dm.Called(afterRevision, options)
 	return args.Get(0).(<-chan *datastore.RevisionChanges), args.Get(1).(<-chan error)
 }
 
@@ -241,9 +242,19 @@ func (dm *MockReadWriteTransaction) WriteRelationships(_ context.Context, mutati
 	return args.Error(0)
 }
 
-func (dm *MockReadWriteTransaction) DeleteRelationships(_ context.Context, filter *v1.RelationshipFilter) error {
-	args := dm.Called(filter)
-	return args.Error(0)
+func (dm *MockReadWriteTransaction) DeleteRelationships(_ context.Context, filter *v1.RelationshipFilter, options ...options.DeleteOptionsOption) (uint64, bool, error) {
+	args := dm.Called(filter, options)
+	return uint64(args.Int(0)), args.Bool(1), args.Error(2)
+}
+
+func (dm *MockReadWriteTransaction) RelationshipsChangedSince(_ context.Context, filter *v1.RelationshipFilter, revision datastore.Revision) (bool, error) {
+	args := dm.Called(filter, revision)
+	return args.Bool(0), args.Error(1)
+}
+
+func (dm *MockReadWriteTransaction) BulkLoad(_ context.Context, iter datastore.BulkWriteRelationshipSource) (uint64, error) {
+	args := dm.Called(iter)
+	return uint64(args.Int(0)), args.Error(1)
 }
 
 func (dm *MockReadWriteTransaction) WriteNamespaces(_ context.Context, newConfigs ...*core.NamespaceDefinition) error {
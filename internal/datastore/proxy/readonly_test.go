@@ -117,12 +117,16 @@ func TestWatchPassthrough(t *testing.T) {
 	ds := NewReadonlyDatastore(delegate)
 	ctx := context.Background()
 
-	delegate.On("Watch", expectedRevision).Return(
+	options := datastore.WatchOptions{
+		OptionalRelationshipsFilters: []datastore.RelationshipsFilter{{ResourceType: "document"}},
+	}
+
+	delegate.On("Watch", expectedRevision, options).Return(
 		make(<-chan *datastore.RevisionChanges),
 		make(<-chan error),
 	).Times(1)
 
-	ds.Watch(ctx, expectedRevision)
+	ds.Watch(ctx, expectedRevision, options)
 	delegate.AssertExpectations(t)
 }
 
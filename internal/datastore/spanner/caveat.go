@@ -6,7 +6,9 @@ import (
 	"time"
 
 	"cloud.google.com/go/spanner"
+	"github.com/google/uuid"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/authzed/spicedb/internal/datastore/common"
 	"github.com/authzed/spicedb/pkg/datastore"
@@ -89,7 +91,8 @@ func (sr spannerReader) listCaveats(ctx context.Context, caveatNames []string) (
 
 func (rwt spannerReadWriteTXN) WriteCaveats(_ context.Context, caveats []*core.CaveatDefinition) error {
 	names := map[string]struct{}{}
-	mutations := make([]*spanner.Mutation, 0, len(caveats))
+	changeUUID := uuid.NewString()
+	mutations := make([]*spanner.Mutation, 0, 2*len(caveats))
 	for _, caveat := range caveats {
 		if _, ok := names[caveat.Name]; ok {
 			return fmt.Errorf(errUnableToWriteCaveat, fmt.Errorf("duplicate caveats in input: %s", caveat.Name))
@@ -100,24 +103,35 @@ func (rwt spannerReadWriteTXN) WriteCaveats(_ context.Context, caveats []*core.C
 			return fmt.Errorf(errUnableToWriteCaveat, err)
 		}
 
-		mutations = append(mutations, spanner.InsertOrUpdate(
-			tableCaveat,
-			[]string{colName, colCaveatDefinition, colCaveatTS},
-			[]interface{}{caveat.Name, serialized, spanner.CommitTimestamp},
-		))
+		mutations = append(mutations,
+			spanner.InsertOrUpdate(
+				tableCaveat,
+				[]string{colName, colCaveatDefinition, colCaveatTS},
+				[]interface{}{caveat.Name, serialized, spanner.CommitTimestamp},
+			),
+			schemaChangeMutation(changeUUID, colChangeOpTouch, schemaChangeKindCaveat, caveat.Name, serialized),
+		)
 	}
 
 	return rwt.spannerRWT.BufferWrite(mutations)
 }
 
 func (rwt spannerReadWriteTXN) DeleteCaveats(_ context.Context, names []string) error {
+	changeUUID := uuid.NewString()
 	keys := make([]spanner.Key, 0, len(names))
+	mutations := make([]*spanner.Mutation, 0, len(names)+1)
+	seen := make(map[string]struct{}, len(names))
 	for _, n := range names {
+		if _, ok := seen[n]; ok {
+			continue
+		}
+		seen[n] = struct{}{}
+
 		keys = append(keys, spanner.Key{n})
+		mutations = append(mutations, schemaChangeMutation(changeUUID, colChangeOpDelete, schemaChangeKindCaveat, n, nil))
 	}
-	err := rwt.spannerRWT.BufferWrite([]*spanner.Mutation{
-		spanner.Delete(tableCaveat, spanner.KeySetFromKeys(keys...)),
-	})
+	mutations = append(mutations, spanner.Delete(tableCaveat, spanner.KeySetFromKeys(keys...)))
+	err := rwt.spannerRWT.BufferWrite(mutations)
 	if err != nil {
 		return fmt.Errorf(errUnableToDeleteCaveat, err)
 	}
@@ -125,6 +139,14 @@ func (rwt spannerReadWriteTXN) DeleteCaveats(_ context.Context, names []string)
 	return err
 }
 
+// ExpirationTimestampFrom converts a stored expiration into the form found on tuples.
+func ExpirationTimestampFrom(expiration spanner.NullTime) *timestamppb.Timestamp {
+	if !expiration.Valid {
+		return nil
+	}
+	return timestamppb.New(expiration.Time)
+}
+
 func ContextualizedCaveatFrom(name spanner.NullString, context spanner.NullJSON) (*core.ContextualizedCaveat, error) {
 	if name.Valid {
 		var cctx map[string]any
@@ -24,7 +24,7 @@ func (sd spannerDatastore) runGC(ctx context.Context) error {
 
 	s := gocron.NewScheduler(time.UTC)
 
-	var numRemoved int64
+	var numRemoved, numExpiredRemoved int64
 	_, err := s.Every(sd.config.gcInterval).Do(func() {
 		ctx, span := tracer.Start(context.Background(), "CollectGarbage")
 		defer span.End()
@@ -44,9 +44,50 @@ func (sd spannerDatastore) runGC(ctx context.Context) error {
 			log.Ctx(ctx).Error().Err(err).Msg("garbage collection: error creating delete statement")
 		}
 
+		schemaStmt, schemaArgs, err := sql.Delete(tableSchemaChangelog).Where(sq.Lt{colSchemaChangeTS: oldestRevision}).ToSql()
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msg("garbage collection: error creating schema changelog delete statement")
+		}
+
+		keysStmt, keysArgs, err := sql.Delete(tableIdempotencyKey).Where(sq.Lt{colIdempotencyKeyTS: oldestRevision}).ToSql()
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msg("garbage collection: error creating idempotency key delete statement")
+		}
+
+		// Relationships which expired before the window can no longer be read at any
+		// valid revision.
+		expiredStmt, expiredArgs, err := sql.Delete(tableRelationship).Where(sq.Lt{colExpiration: oldestRevision}).ToSql()
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msg("garbage collection: error creating expired relationships delete statement")
+		}
+
 		_, err = sd.client.ReadWriteTransaction(ctx, func(ctx context.Context, rwt *spanner.ReadWriteTransaction) error {
 			numRemoved, err = rwt.Update(ctx, statementFromSQL(stmt, args))
-			return err
+			if err != nil {
+				return err
+			}
+
+			numSchemaRemoved, err := rwt.Update(ctx, statementFromSQL(schemaStmt, schemaArgs))
+			if err != nil {
+				return err
+			}
+			numRemoved += numSchemaRemoved
+
+			numKeysRemoved, err := rwt.Update(ctx, statementFromSQL(keysStmt, keysArgs))
+			if err != nil {
+				return err
+			}
+			numRemoved += numKeysRemoved
+
+			numExpiredRemoved, err = rwt.Update(ctx, statementFromSQL(expiredStmt, expiredArgs))
+			if err != nil {
+				return err
+			}
+
+			if sd.config.disableStats {
+				return nil
+			}
+			return updateCounter(ctx, rwt, -1*numExpiredRemoved)
 		})
 		if err != nil {
 			log.Ctx(ctx).Error().Err(err).Msg("garbage collection: error deleting entries")
@@ -54,6 +95,8 @@ func (sd spannerDatastore) runGC(ctx context.Context) error {
 
 		log.Ctx(ctx).Info().Int64("removed", numRemoved).Stringer("before", oldestRevision).
 			Msg("garbage collection: removed changelog entries")
+		log.Ctx(ctx).Info().Int64("removed", numExpiredRemoved).Stringer("before", oldestRevision).
+			Msg("garbage collection: removed expired relationships")
 	})
 	if err != nil {
 		return fmt.Errorf("unable to start garbage collection: %w", err)
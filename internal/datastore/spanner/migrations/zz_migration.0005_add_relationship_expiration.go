@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+)
+
+const (
+	addRelationshipExpiration = `ALTER TABLE relation_tuple
+		ADD COLUMN expiration TIMESTAMP`
+	addRelationshipExpirationIndex = `CREATE NULL_FILTERED INDEX ix_relation_tuple_expired
+		ON relation_tuple (expiration)`
+
+	addChangelogExpiration = `ALTER TABLE changelog
+		ADD COLUMN expiration TIMESTAMP`
+)
+
+func init() {
+	if err := SpannerMigrations.Register("add-relationship-expiration", "add-schema-changelog", func(ctx context.Context, w Wrapper) error {
+		updateOp, err := w.adminClient.UpdateDatabaseDdl(ctx, &databasepb.UpdateDatabaseDdlRequest{
+			Database: w.client.DatabaseName(),
+			Statements: []string{
+				addRelationshipExpiration,
+				addRelationshipExpirationIndex,
+				addChangelogExpiration,
+			},
+		})
+		if err != nil {
+			return err
+		}
+		return updateOp.Wait(ctx)
+	}, nil); err != nil {
+		panic("failed to register migration: " + err.Error())
+	}
+}
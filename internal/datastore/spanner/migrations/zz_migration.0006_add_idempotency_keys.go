@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+)
+
+const (
+	createIdempotencyKey = `CREATE TABLE idempotency_key (
+		key STRING(128) NOT NULL,
+		timestamp TIMESTAMP NOT NULL OPTIONS (allow_commit_timestamp=true),
+		request_hash STRING(64) NOT NULL DEFAULT ("")
+	) PRIMARY KEY (key)`
+	createIdempotencyKeyTimestampIndex = `CREATE INDEX ix_idempotency_key_timestamp
+		ON idempotency_key (timestamp)`
+)
+
+func init() {
+	if err := SpannerMigrations.Register("add-idempotency-keys", "add-relationship-expiration", func(ctx context.Context, w Wrapper) error {
+		updateOp, err := w.adminClient.UpdateDatabaseDdl(ctx, &databasepb.UpdateDatabaseDdlRequest{
+			Database: w.client.DatabaseName(),
+			Statements: []string{
+				createIdempotencyKey,
+				createIdempotencyKeyTimestampIndex,
+			},
+		})
+		if err != nil {
+			return err
+		}
+		return updateOp.Wait(ctx)
+	}, nil); err != nil {
+		panic("failed to register migration: " + err.Error())
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"cloud.google.com/go/spanner"
+	sq "github.com/Masterminds/squirrel"
 	"google.golang.org/grpc/codes"
 
 	"github.com/authzed/spicedb/internal/datastore/common"
@@ -30,6 +31,7 @@ type txFactory func() readTX
 type spannerReader struct {
 	querySplitter common.TupleQuerySplitter
 	txSource      txFactory
+	notExpired    sq.Sqlizer
 }
 
 func (sr spannerReader) QueryRelationships(
@@ -37,7 +39,7 @@ func (sr spannerReader) QueryRelationships(
 	filter datastore.RelationshipsFilter,
 	opts ...options.QueryOptionsOption,
 ) (iter datastore.RelationshipIterator, err error) {
-	qBuilder, err := common.NewSchemaQueryFilterer(schema, queryTuples).FilterWithRelationshipsFilter(filter)
+	qBuilder, err := common.NewSchemaQueryFilterer(schema, queryTuples.Where(sr.notExpired)).FilterWithRelationshipsFilter(filter)
 	if err != nil {
 		return nil, err
 	}
@@ -50,7 +52,7 @@ func (sr spannerReader) ReverseQueryRelationships(
 	subjectsFilter datastore.SubjectsFilter,
 	opts ...options.ReverseQueryOptionsOption,
 ) (iter datastore.RelationshipIterator, err error) {
-	qBuilder, err := common.NewSchemaQueryFilterer(schema, queryTuples).
+	qBuilder, err := common.NewSchemaQueryFilterer(schema, queryTuples.Where(sr.notExpired)).
 		FilterWithSubjectsSelectors(subjectsFilter.AsSelector())
 	if err != nil {
 		return nil, err
@@ -67,6 +69,8 @@ func (sr spannerReader) ReverseQueryRelationships(
 	return sr.querySplitter.SplitAndExecuteQuery(ctx,
 		qBuilder,
 		options.WithLimit(queryOpts.ReverseLimit),
+		options.WithSort(queryOpts.SortForReverse),
+		options.WithAfter(queryOpts.AfterForReverse),
 	)
 }
 
@@ -90,6 +94,7 @@ func queryExecutor(txSource txFactory) common.ExecuteQueryFunc {
 			}
 			var caveatName spanner.NullString
 			var caveatCtx spanner.NullJSON
+			var expiration spanner.NullTime
 			err := row.Columns(
 				&nextTuple.ResourceAndRelation.Namespace,
 				&nextTuple.ResourceAndRelation.ObjectId,
@@ -99,11 +104,13 @@ func queryExecutor(txSource txFactory) common.ExecuteQueryFunc {
 				&nextTuple.Subject.Relation,
 				&caveatName,
 				&caveatCtx,
+				&expiration,
 			)
 			if err != nil {
 				return err
 			}
 
+			nextTuple.OptionalExpirationTime = ExpirationTimestampFrom(expiration)
 			nextTuple.Caveat, err = ContextualizedCaveatFrom(caveatName, caveatCtx)
 			if err != nil {
 				return err
@@ -226,8 +233,21 @@ var queryTuples = sql.Select(
 	colUsersetRelation,
 	colCaveatName,
 	colCaveatContext,
+	colExpiration,
 ).From(tableRelationship)
 
+var (
+	// expiredClause and notExpiredClause evaluate expiration against the current time, and
+	// are used within read-write transactions.
+	expiredClause    = sq.Expr(colExpiration + " <= CURRENT_TIMESTAMP()")
+	notExpiredClause = sq.Or{sq.Eq{colExpiration: nil}, sq.Expr(colExpiration + " > CURRENT_TIMESTAMP()")}
+)
+
+// notExpiredAt returns a clause matching relationships which have not expired at the given time.
+func notExpiredAt(at time.Time) sq.Sqlizer {
+	return sq.Or{sq.Eq{colExpiration: nil}, sq.Gt{colExpiration: at}}
+}
+
 var schema = common.NewSchemaInformation(
 	colNamespace,
 	colObjectID,
@@ -236,6 +256,7 @@ var schema = common.NewSchemaInformation(
 	colUsersetObjectID,
 	colUsersetRelation,
 	colCaveatName,
+	colExpiration,
 	common.ExpandedLogicComparison,
 )
 
@@ -11,8 +11,11 @@ import (
 	"github.com/jzelinskie/stringz"
 	"google.golang.org/protobuf/proto"
 
+	"github.com/authzed/spicedb/internal/datastore/common"
 	log "github.com/authzed/spicedb/internal/logging"
 	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/options"
+	"github.com/authzed/spicedb/pkg/datastore/revision"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 )
 
@@ -27,6 +30,27 @@ func (rwt spannerReadWriteTXN) WriteRelationships(ctx context.Context, mutations
 
 	var rowCountChange int64
 
+	// An expired relationship does not prevent the creation of a new one in its place.
+	expiredToReplace := sq.Or{}
+	for _, mutation := range mutations {
+		if mutation.Operation == core.RelationTupleUpdate_CREATE {
+			expiredToReplace = append(expiredToReplace, sq.And{exactRelationshipClause(mutation.Tuple), expiredClause})
+		}
+	}
+
+	if len(expiredToReplace) > 0 {
+		stmt, args, err := sql.Delete(tableRelationship).Where(expiredToReplace).ToSql()
+		if err != nil {
+			return fmt.Errorf(errUnableToWriteRelationships, err)
+		}
+
+		numReplaced, err := rwt.spannerRWT.Update(ctx, statementFromSQL(stmt, args))
+		if err != nil {
+			return fmt.Errorf(errUnableToWriteRelationships, err)
+		}
+		rowCountChange -= numReplaced
+	}
+
 	for _, mutation := range mutations {
 		var txnMut *spanner.Mutation
 		var op int
@@ -66,12 +90,51 @@ func (rwt spannerReadWriteTXN) WriteRelationships(ctx context.Context, mutations
 	return nil
 }
 
-func (rwt spannerReadWriteTXN) DeleteRelationships(ctx context.Context, filter *v1.RelationshipFilter) error {
-	err := deleteWithFilter(ctx, rwt.spannerRWT, filter, rwt.disableStats)
+// bulkLoadBatchSize is the number of relationships buffered by each write during a bulk load.
+const bulkLoadBatchSize = 1000
+
+func (rwt spannerReadWriteTXN) BulkLoad(ctx context.Context, iter datastore.BulkWriteRelationshipSource) (uint64, error) {
+	return common.BulkLoadInBatches(ctx, iter, bulkLoadBatchSize, rwt.WriteRelationships)
+}
+
+func (rwt spannerReadWriteTXN) DeleteRelationships(ctx context.Context, filter *v1.RelationshipFilter, opts ...options.DeleteOptionsOption) (uint64, bool, error) {
+	delOpts := options.NewDeleteOptionsWithOptions(opts...)
+	deleted, limitReached, err := deleteWithFilter(ctx, rwt.spannerRWT, filter, rwt.disableStats, delOpts.DeleteLimit)
 	if err != nil {
-		return fmt.Errorf(errUnableToDeleteRelationships, err)
+		return 0, false, fmt.Errorf(errUnableToDeleteRelationships, err)
 	}
-	return nil
+	return deleted, limitReached, nil
+}
+
+func (rwt spannerReadWriteTXN) RelationshipsChangedSince(ctx context.Context, filter *v1.RelationshipFilter, rev datastore.Revision) (bool, error) {
+	// Relationships are deleted in place, so changes are found in the changelog, which is
+	// retained for the GC window.
+	filterClause, err := changelogSchema.RelationshipsFiltersClause([]datastore.RelationshipsFilter{
+		datastore.RelationshipsFilterFromPublicFilter(filter),
+	})
+	if err != nil {
+		return false, fmt.Errorf(errUnableToCheckChanges, err)
+	}
+
+	stmt, args, err := sql.Select(colChangeUUID).
+		From(tableChangelog).
+		Where(sq.Gt{colChangeTS: timestampFromRevision(rev.(revision.Decimal))}).
+		Where(filterClause).
+		Limit(1).
+		ToSql()
+	if err != nil {
+		return false, fmt.Errorf(errUnableToCheckChanges, err)
+	}
+
+	changed := false
+	if err := rwt.spannerRWT.Query(ctx, statementFromSQL(stmt, args)).Do(func(_ *spanner.Row) error {
+		changed = true
+		return nil
+	}); err != nil {
+		return false, fmt.Errorf(errUnableToCheckChanges, err)
+	}
+
+	return changed, nil
 }
 
 type selectAndDelete struct {
@@ -85,7 +148,7 @@ func (snd selectAndDelete) Where(pred interface{}, args ...interface{}) selectAn
 	return snd
 }
 
-func deleteWithFilter(ctx context.Context, rwt *spanner.ReadWriteTransaction, filter *v1.RelationshipFilter, disableStats bool) error {
+func deleteWithFilter(ctx context.Context, rwt *spanner.ReadWriteTransaction, filter *v1.RelationshipFilter, disableStats bool, limit *uint64) (uint64, bool, error) {
 	queries := selectAndDelete{queryTuples, sql.Delete(tableRelationship)}
 
 	// Add clauses for the ResourceFilter
@@ -108,9 +171,14 @@ func deleteWithFilter(ctx context.Context, rwt *spanner.ReadWriteTransaction, fi
 		}
 	}
 
+	if limit != nil {
+		// Select one more relationship than the limit, to determine whether any would remain.
+		queries.sel = queries.sel.Limit(*limit + 1)
+	}
+
 	ssql, sargs, err := queries.sel.ToSql()
 	if err != nil {
-		return err
+		return 0, false, err
 	}
 
 	toDelete := rwt.Query(ctx, statementFromSQL(ssql, sargs))
@@ -124,9 +192,17 @@ func deleteWithFilter(ctx context.Context, rwt *spanner.ReadWriteTransaction, fi
 	}
 	var caveatName spanner.NullString
 	var caveatCtx spanner.NullJSON
+	var expiration spanner.NullTime
 
 	var changelogMutations []*spanner.Mutation
+	var limitedKeys []spanner.Key
+	limitReached := false
 	if err := toDelete.Do(func(row *spanner.Row) error {
+		if limit != nil && uint64(len(changelogMutations)) == *limit {
+			limitReached = true
+			return nil
+		}
+
 		err := row.Columns(
 			&rel.ResourceAndRelation.Namespace,
 			&rel.ResourceAndRelation.ObjectId,
@@ -136,10 +212,12 @@ func deleteWithFilter(ctx context.Context, rwt *spanner.ReadWriteTransaction, fi
 			&rel.Subject.Relation,
 			&caveatName,
 			&caveatCtx,
+			&expiration,
 		)
 		if err != nil {
 			return err
 		}
+		rel.OptionalExpirationTime = ExpirationTimestampFrom(expiration)
 		rel.Caveat, err = ContextualizedCaveatFrom(caveatName, caveatCtx)
 		if err != nil {
 			return err
@@ -150,38 +228,54 @@ func deleteWithFilter(ctx context.Context, rwt *spanner.ReadWriteTransaction, fi
 			allChangelogCols,
 			changeVals(changeUUID, colChangeOpDelete, &rel),
 		))
+		if limit != nil {
+			limitedKeys = append(limitedKeys, keyFromRelationship(&rel))
+		}
 		return nil
 	}); err != nil {
-		return err
+		return 0, false, err
 	}
 
 	if err := rwt.BufferWrite(changelogMutations); err != nil {
-		return err
+		return 0, false, err
 	}
 
-	sql, args, err := queries.del.ToSql()
-	if err != nil {
-		return err
-	}
+	var numDeleted int64
+	if limit != nil {
+		// Spanner does not support a LIMIT on a DELETE, so the selected relationships are
+		// deleted by key.
+		if err := rwt.BufferWrite([]*spanner.Mutation{
+			spanner.Delete(tableRelationship, spanner.KeySetFromKeys(limitedKeys...)),
+		}); err != nil {
+			return 0, false, err
+		}
+		numDeleted = int64(len(limitedKeys))
+	} else {
+		sql, args, err := queries.del.ToSql()
+		if err != nil {
+			return 0, false, err
+		}
 
-	numDeleted, err := rwt.Update(ctx, statementFromSQL(sql, args))
-	if err != nil {
-		return err
+		numDeleted, err = rwt.Update(ctx, statementFromSQL(sql, args))
+		if err != nil {
+			return 0, false, err
+		}
 	}
 
 	if !disableStats {
 		if err := updateCounter(ctx, rwt, -1*numDeleted); err != nil {
-			return err
+			return 0, false, err
 		}
 	}
 
-	return nil
+	return uint64(numDeleted), limitReached, nil
 }
 
 func upsertVals(r *core.RelationTuple) []any {
 	key := keyFromRelationship(r)
 	key = append(key, spanner.CommitTimestamp)
 	key = append(key, caveatVals(r)...)
+	key = append(key, expirationVal(r))
 	return key
 }
 
@@ -209,9 +303,28 @@ func changeVals(changeUUID string, op int, r *core.RelationTuple) []any {
 		r.Subject.Relation,
 	}
 	vals = append(vals, caveatVals(r)...)
+	vals = append(vals, expirationVal(r))
 	return vals
 }
 
+func expirationVal(r *core.RelationTuple) spanner.NullTime {
+	if r.OptionalExpirationTime == nil {
+		return spanner.NullTime{}
+	}
+	return spanner.NullTime{Time: r.OptionalExpirationTime.AsTime(), Valid: true}
+}
+
+func exactRelationshipClause(r *core.RelationTuple) sq.Eq {
+	return sq.Eq{
+		colNamespace:        r.ResourceAndRelation.Namespace,
+		colObjectID:         r.ResourceAndRelation.ObjectId,
+		colRelation:         r.ResourceAndRelation.Relation,
+		colUsersetNamespace: r.Subject.Namespace,
+		colUsersetObjectID:  r.Subject.ObjectId,
+		colUsersetRelation:  r.Subject.Relation,
+	}
+}
+
 func caveatVals(r *core.RelationTuple) []any {
 	if r.Caveat == nil {
 		return []any{"", nil}
@@ -226,18 +339,22 @@ func caveatVals(r *core.RelationTuple) []any {
 }
 
 func (rwt spannerReadWriteTXN) WriteNamespaces(_ context.Context, newConfigs ...*core.NamespaceDefinition) error {
-	mutations := make([]*spanner.Mutation, 0, len(newConfigs))
+	changeUUID := uuid.NewString()
+	mutations := make([]*spanner.Mutation, 0, 2*len(newConfigs))
 	for _, newConfig := range newConfigs {
 		serialized, err := proto.Marshal(newConfig)
 		if err != nil {
 			return fmt.Errorf(errUnableToWriteConfig, err)
 		}
 
-		mutations = append(mutations, spanner.InsertOrUpdate(
-			tableNamespace,
-			[]string{colNamespaceName, colNamespaceConfig, colTimestamp},
-			[]interface{}{newConfig.Name, serialized, spanner.CommitTimestamp},
-		))
+		mutations = append(mutations,
+			spanner.InsertOrUpdate(
+				tableNamespace,
+				[]string{colNamespaceName, colNamespaceConfig, colTimestamp},
+				[]interface{}{newConfig.Name, serialized, spanner.CommitTimestamp},
+			),
+			schemaChangeMutation(changeUUID, colChangeOpTouch, schemaChangeKindNamespace, newConfig.Name, serialized),
+		)
 	}
 
 	return rwt.spannerRWT.BufferWrite(mutations)
@@ -245,14 +362,15 @@ func (rwt spannerReadWriteTXN) WriteNamespaces(_ context.Context, newConfigs ...
 
 func (rwt spannerReadWriteTXN) DeleteNamespaces(ctx context.Context, nsNames ...string) error {
 	for _, nsName := range nsNames {
-		if err := deleteWithFilter(ctx, rwt.spannerRWT, &v1.RelationshipFilter{
+		if _, _, err := deleteWithFilter(ctx, rwt.spannerRWT, &v1.RelationshipFilter{
 			ResourceType: nsName,
-		}, rwt.disableStats); err != nil {
+		}, rwt.disableStats, nil); err != nil {
 			return fmt.Errorf(errUnableToDeleteConfig, err)
 		}
 
 		err := rwt.spannerRWT.BufferWrite([]*spanner.Mutation{
 			spanner.Delete(tableNamespace, spanner.KeySetFromKeys(spanner.Key{nsName})),
+			schemaChangeMutation(uuid.NewString(), colChangeOpDelete, schemaChangeKindNamespace, nsName, nil),
 		})
 		if err != nil {
 			return fmt.Errorf(errUnableToDeleteConfig, err)
@@ -262,4 +380,17 @@ func (rwt spannerReadWriteTXN) DeleteNamespaces(ctx context.Context, nsNames ...
 	return nil
 }
 
+// schemaChangeMutation returns the mutation which records a change to a namespace or caveat
+// definition in the schema changelog, for consumption by Watch.
+func schemaChangeMutation(changeUUID string, op int, kind int, name string, definition []byte) *spanner.Mutation {
+	return spanner.Insert(tableSchemaChangelog, allSchemaChangelogCols, []interface{}{
+		spanner.CommitTimestamp,
+		changeUUID,
+		op,
+		kind,
+		name,
+		definition,
+	})
+}
+
 var _ datastore.ReadWriteTransaction = spannerReadWriteTXN{}
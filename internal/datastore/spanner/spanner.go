@@ -19,6 +19,7 @@ import (
 	"github.com/authzed/spicedb/internal/datastore/spanner/migrations"
 	log "github.com/authzed/spicedb/internal/logging"
 	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/options"
 	"github.com/authzed/spicedb/pkg/datastore/revision"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 )
@@ -36,6 +37,8 @@ const (
 
 	errUnableToWriteRelationships  = "unable to write relationships: %w"
 	errUnableToDeleteRelationships = "unable to delete relationships: %w"
+	errUnableToCheckChanges        = "unable to check for changed relationships: %w"
+	errUnableToCheckIdempotencyKey = "unable to check idempotency key: %w"
 
 	errUnableToWriteConfig    = "unable to write namespace config: %w"
 	errUnableToReadConfig     = "unable to read namespace config: %w"
@@ -132,14 +135,24 @@ func (sd spannerDatastore) SnapshotReader(revisionRaw datastore.Revision) datast
 		UsersetBatchSize: usersetBatchsize,
 	}
 
-	return spannerReader{querySplitter, txSource}
+	// Relationships are expired as of the time of the revision being read.
+	return spannerReader{querySplitter, txSource, notExpiredAt(timestampFromRevision(revision))}
 }
 
 func (sd spannerDatastore) ReadWriteTx(
 	ctx context.Context,
 	fn datastore.TxUserFunc,
+	opts ...options.RWTOptionsOption,
 ) (datastore.Revision, error) {
+	rwtOpts := options.NewRWTOptionsWithOptions(opts...)
+
 	ts, err := sd.client.ReadWriteTransaction(ctx, func(ctx context.Context, spannerRWT *spanner.ReadWriteTransaction) error {
+		if rwtOpts.IdempotencyKey != "" {
+			if err := checkIdempotencyKey(ctx, spannerRWT, rwtOpts.IdempotencyKey, rwtOpts.IdempotencyRequestHash); err != nil {
+				return err
+			}
+		}
+
 		txSource := func() readTX {
 			return spannerRWT
 		}
@@ -149,11 +162,22 @@ func (sd spannerDatastore) ReadWriteTx(
 			UsersetBatchSize: usersetBatchsize,
 		}
 		rwt := spannerReadWriteTXN{
-			spannerReader{querySplitter, txSource},
+			spannerReader{querySplitter, txSource, notExpiredClause},
 			spannerRWT,
 			sd.config.disableStats,
 		}
-		return fn(rwt)
+		if err := fn(rwt); err != nil {
+			return err
+		}
+
+		if rwtOpts.IdempotencyKey != "" {
+			return spannerRWT.BufferWrite([]*spanner.Mutation{spanner.Insert(
+				tableIdempotencyKey,
+				[]string{colIdempotencyKey, colIdempotencyKeyTS, colRequestHash},
+				[]interface{}{rwtOpts.IdempotencyKey, spanner.CommitTimestamp, rwtOpts.IdempotencyRequestHash},
+			)})
+		}
+		return nil
 	})
 	if err != nil {
 		if cerr := convertToWriteConstraintError(err); cerr != nil {
@@ -165,6 +189,27 @@ func (sd spannerDatastore) ReadWriteTx(
 	return revisionFromTimestamp(ts), nil
 }
 
+// checkIdempotencyKey returns an ErrIdempotencyKeyAlreadyCommitted if a transaction has already
+// committed with the given key, or an ErrIdempotencyKeyReused if it did so for a different request.
+// Keys are removed by the GC once outside of the GC window.
+func checkIdempotencyKey(ctx context.Context, rwt *spanner.ReadWriteTransaction, idempotencyKey string, requestHash string) error {
+	row, err := rwt.ReadRow(ctx, tableIdempotencyKey, spanner.Key{idempotencyKey}, []string{colIdempotencyKeyTS, colRequestHash})
+	if err != nil {
+		if spanner.ErrCode(err) == codes.NotFound {
+			return nil
+		}
+		return fmt.Errorf(errUnableToCheckIdempotencyKey, err)
+	}
+
+	var committed time.Time
+	var committedRequestHash string
+	if err := row.Columns(&committed, &committedRequestHash); err != nil {
+		return fmt.Errorf(errUnableToCheckIdempotencyKey, err)
+	}
+
+	return common.IdempotencyKeyCommittedErr(idempotencyKey, requestHash, committedRequestHash, revisionFromTimestamp(committed))
+}
+
 func (sd spannerDatastore) ReadyState(ctx context.Context) (datastore.ReadyState, error) {
 	headMigration, err := migrations.SpannerMigrations.HeadRevision()
 	if err != nil {
@@ -3,6 +3,7 @@ package spanner
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"cloud.google.com/go/spanner"
@@ -16,11 +17,33 @@ import (
 
 const (
 	watchSleep = 100 * time.Millisecond
+
+	// watchCheckpointInterval is the minimum interval between checkpoints emitted by
+	// the watch. As the read timestamp advances on every poll, checkpoints are rate
+	// limited to avoid flooding consumers.
+	watchCheckpointInterval = 1 * time.Second
 )
 
-var queryChanged = sql.Select(allChangelogCols...).From(tableChangelog)
+var (
+	queryChanged       = sql.Select(allChangelogCols...).From(tableChangelog)
+	querySchemaChanged = sql.Select(allSchemaChangelogCols...).From(tableSchemaChangelog)
+
+	// changelogSchema describes the relationship columns of the changelog, to allow
+	// filtering of the changes loaded for a watch.
+	changelogSchema = common.NewSchemaInformation(
+		colChangeNamespace,
+		colChangeObjectID,
+		colChangeRelation,
+		colChangeUsersetNamespace,
+		colChangeUsersetObjectID,
+		colChangeUsersetRelation,
+		colChangeCaveatName,
+		colChangeExpiration,
+		common.ExpandedLogicComparison,
+	)
+)
 
-func (sd spannerDatastore) Watch(ctx context.Context, afterRevisionRaw datastore.Revision) (<-chan *datastore.RevisionChanges, <-chan error) {
+func (sd spannerDatastore) Watch(ctx context.Context, afterRevisionRaw datastore.Revision, options datastore.WatchOptions) (<-chan *datastore.RevisionChanges, <-chan error) {
 	afterRevision := afterRevisionRaw.(revision.Decimal)
 
 	updates := make(chan *datastore.RevisionChanges, sd.config.watchBufferLength)
@@ -31,11 +54,12 @@ func (sd spannerDatastore) Watch(ctx context.Context, afterRevisionRaw datastore
 		defer close(errs)
 
 		currentTxn := timestampFromRevision(afterRevision)
+		var lastCheckpoint time.Time
 
 		for {
 			var stagedUpdates []datastore.RevisionChanges
 			var err error
-			stagedUpdates, currentTxn, err = sd.loadChanges(ctx, currentTxn)
+			stagedUpdates, currentTxn, err = sd.loadChanges(ctx, currentTxn, options)
 			if err != nil {
 				if errors.Is(ctx.Err(), context.Canceled) {
 					errs <- datastore.NewWatchCanceledErr()
@@ -57,6 +81,11 @@ func (sd spannerDatastore) Watch(ctx context.Context, afterRevisionRaw datastore
 				}
 			}
 
+			if time.Since(lastCheckpoint) >= watchCheckpointInterval {
+				lastCheckpoint = time.Now()
+				common.SendCheckpoint(updates, revisionFromTimestamp(currentTxn))
+			}
+
 			// If there were no changes, sleep a bit
 			if len(stagedUpdates) == 0 {
 				sleep := time.NewTimer(watchSleep)
@@ -78,13 +107,33 @@ func (sd spannerDatastore) Watch(ctx context.Context, afterRevisionRaw datastore
 func (sd spannerDatastore) loadChanges(
 	ctx context.Context,
 	afterTimestamp time.Time,
+	options datastore.WatchOptions,
 ) ([]datastore.RevisionChanges, time.Time, error) {
-	sql, args, err := queryChanged.Where(sq.Gt{colChangeTS: afterTimestamp}).ToSql()
+	query := queryChanged.Where(sq.Gt{colChangeTS: afterTimestamp})
+	if len(options.OptionalRelationshipsFilters) > 0 {
+		relationshipsFilter, err := changelogSchema.RelationshipsFiltersClause(options.OptionalRelationshipsFilters)
+		if err != nil {
+			return nil, afterTimestamp, err
+		}
+		query = query.Where(relationshipsFilter)
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, afterTimestamp, err
+	}
+
+	schemaSQL, schemaArgs, err := querySchemaChanged.Where(sq.Gt{colSchemaChangeTS: afterTimestamp}).ToSql()
 	if err != nil {
 		return nil, afterTimestamp, err
 	}
 
-	rows := sd.client.Single().Query(ctx, statementFromSQL(sql, args))
+	// Both changelogs must be read at the same snapshot, to ensure no changes are skipped
+	// when advancing the timestamp.
+	txn := sd.client.ReadOnlyTransaction()
+	defer txn.Close()
+
+	rows := txn.Query(ctx, statementFromSQL(sql, args))
 	stagedChanges := common.NewChanges(revision.DecimalKeyFunc)
 
 	newTimestamp := afterTimestamp
@@ -99,6 +148,7 @@ func (sd spannerDatastore) loadChanges(
 		var colChangeUUID string
 		var caveatName spanner.NullString
 		var caveatCtx spanner.NullJSON
+		var expiration spanner.NullTime
 		err := r.Columns(
 			&timestamp,
 			&colChangeUUID,
@@ -111,10 +161,12 @@ func (sd spannerDatastore) loadChanges(
 			&tpl.Subject.Relation,
 			&caveatName,
 			&caveatCtx,
+			&expiration,
 		)
 		if err != nil {
 			return err
 		}
+		tpl.OptionalExpirationTime = ExpirationTimestampFrom(expiration)
 		tpl.Caveat, err = ContextualizedCaveatFrom(caveatName, caveatCtx)
 		if err != nil {
 			return err
@@ -130,6 +182,63 @@ func (sd spannerDatastore) loadChanges(
 		return nil, afterTimestamp, err
 	}
 
+	schemaRows := txn.Query(ctx, statementFromSQL(schemaSQL, schemaArgs))
+	err = schemaRows.Do(func(r *spanner.Row) error {
+		var timestamp time.Time
+		var changeUUID string
+		var op int64
+		var kind int64
+		var name string
+		var definition []byte
+		if err := r.Columns(&timestamp, &changeUUID, &op, &kind, &name, &definition); err != nil {
+			return err
+		}
+
+		newTimestamp = maxTime(newTimestamp, timestamp)
+		changeRevision := revisionFromTimestamp(timestamp)
+
+		switch kind {
+		case schemaChangeKindNamespace:
+			if op == colChangeOpDelete {
+				stagedChanges.AddDeletedNamespace(ctx, changeRevision, name)
+				return nil
+			}
+
+			loaded := &core.NamespaceDefinition{}
+			if err := loaded.UnmarshalVT(definition); err != nil {
+				return fmt.Errorf(errUnableToReadConfig, err)
+			}
+			stagedChanges.AddChangedDefinition(ctx, changeRevision, loaded)
+
+		case schemaChangeKindCaveat:
+			if op == colChangeOpDelete {
+				stagedChanges.AddDeletedCaveat(ctx, changeRevision, name)
+				return nil
+			}
+
+			loaded := &core.CaveatDefinition{}
+			if err := loaded.UnmarshalVT(definition); err != nil {
+				return fmt.Errorf(errUnableToReadCaveat, err)
+			}
+			stagedChanges.AddChangedDefinition(ctx, changeRevision, loaded)
+
+		default:
+			return fmt.Errorf("unknown schema definition kind in changelog: %d", kind)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, afterTimestamp, err
+	}
+
+	// All changes committed at or before the read timestamp were visible to the reads above.
+	readTimestamp, err := txn.Timestamp()
+	if err != nil {
+		return nil, afterTimestamp, err
+	}
+	newTimestamp = maxTime(newTimestamp, readTimestamp)
+
 	changes := stagedChanges.AsRevisionChanges(revision.DecimalKeyLessThanFunc)
 
 	return changes, newTimestamp, nil
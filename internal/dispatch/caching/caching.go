@@ -17,6 +17,8 @@ import (
 	"github.com/authzed/spicedb/internal/dispatch/keys"
 	log "github.com/authzed/spicedb/internal/logging"
 	"github.com/authzed/spicedb/pkg/cache"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
 )
 
@@ -31,6 +33,7 @@ type Dispatcher struct {
 	d          dispatch.Dispatcher
 	c          cache.Cache
 	keyHandler keys.Handler
+	tracker    *ChangeTracker
 
 	checkTotalCounter                  prometheus.Counter
 	checkFromCacheCounter              prometheus.Counter
@@ -161,6 +164,60 @@ func (cd *Dispatcher) SetDelegate(delegate dispatch.Dispatcher) {
 	cd.d = delegate
 }
 
+// ReuseAcrossRevisions configures the dispatcher to reuse a result cached at one revision at
+// later revisions, for so long as the tracker has observed no change to the schema or to the
+// relationships on which the result depends. The cache keys of the dispatcher are replaced by
+// those of the keys.CrossRevisionKeyHandler.
+func (cd *Dispatcher) ReuseAcrossRevisions(tracker *ChangeTracker) {
+	cd.tracker = tracker
+	cd.keyHandler = &keys.CrossRevisionKeyHandler{}
+}
+
+// revisionedEntry is a cached result along with the revision at which it was computed, as cached
+// when reusing results across revisions.
+type revisionedEntry struct {
+	atRevision string
+	computedAt datastore.Revision
+	value      any
+}
+
+// cached returns the cached result for the request key, if one exists which is valid at the
+// revision of the request.
+func (cd *Dispatcher) cached(ctx context.Context, requestKey keys.DispatchCacheKey, resourceRelation *core.RelationReference, metadata *v1.ResolverMeta) (any, bool) {
+	cachedRaw, found := cd.c.Get(requestKey)
+	if !found || cd.tracker == nil {
+		return cachedRaw, found
+	}
+
+	entry := cachedRaw.(revisionedEntry)
+	if entry.atRevision == metadata.AtRevision {
+		return entry.value, true
+	}
+
+	requestedAt, err := cd.tracker.ds.RevisionFromString(metadata.AtRevision)
+	if err != nil {
+		return nil, false
+	}
+
+	if !cd.tracker.canReuse(ctx, resourceRelation, entry.computedAt, requestedAt) {
+		return nil, false
+	}
+	return entry.value, true
+}
+
+// cache caches the result computed for the request key.
+func (cd *Dispatcher) cache(requestKey keys.DispatchCacheKey, metadata *v1.ResolverMeta, value any, cost int64) {
+	if cd.tracker != nil {
+		computedAt, err := cd.tracker.ds.RevisionFromString(metadata.AtRevision)
+		if err != nil {
+			return
+		}
+		value = revisionedEntry{metadata.AtRevision, computedAt, value}
+	}
+
+	cd.c.Set(requestKey, value, cost)
+}
+
 // DispatchCheck implements dispatch.Check interface
 func (cd *Dispatcher) DispatchCheck(ctx context.Context, req *v1.DispatchCheckRequest) (*v1.DispatchCheckResponse, error) {
 	cd.checkTotalCounter.Inc()
@@ -171,7 +228,7 @@ func (cd *Dispatcher) DispatchCheck(ctx context.Context, req *v1.DispatchCheckRe
 	}
 
 	// Disable caching when debugging is enabled.
-	if cachedResultRaw, found := cd.c.Get(requestKey); found {
+	if cachedResultRaw, found := cd.cached(ctx, requestKey, req.ResourceRelation, req.Metadata); found {
 		var response v1.DispatchCheckResponse
 		if err := response.UnmarshalVT(cachedResultRaw.([]byte)); err != nil {
 			return &v1.DispatchCheckResponse{Metadata: &v1.ResponseMeta{}}, err
@@ -207,7 +264,7 @@ func (cd *Dispatcher) DispatchCheck(ctx context.Context, req *v1.DispatchCheckRe
 			return &v1.DispatchCheckResponse{Metadata: &v1.ResponseMeta{}}, err
 		}
 
-		cd.c.Set(requestKey, adjustedBytes, sliceSize(adjustedBytes))
+		cd.cache(requestKey, req.Metadata, adjustedBytes, sliceSize(adjustedBytes))
 	}
 
 	// Return both the computed and err in ALL cases: computed contains resolved
@@ -221,49 +278,83 @@ func (cd *Dispatcher) DispatchExpand(ctx context.Context, req *v1.DispatchExpand
 	return resp, err
 }
 
-// DispatchLookup implements dispatch.Lookup interface and does not do any caching yet.
-func (cd *Dispatcher) DispatchLookup(ctx context.Context, req *v1.DispatchLookupRequest) (*v1.DispatchLookupResponse, error) {
+// DispatchLookup implements dispatch.Lookup interface.
+func (cd *Dispatcher) DispatchLookup(req *v1.DispatchLookupRequest, stream dispatch.LookupStream) error {
 	cd.lookupTotalCounter.Inc()
 
-	requestKey, err := cd.keyHandler.LookupResourcesCacheKey(ctx, req)
+	requestKey, err := cd.keyHandler.LookupResourcesCacheKey(stream.Context(), req)
 	if err != nil {
-		return &v1.DispatchLookupResponse{Metadata: &v1.ResponseMeta{}}, err
+		return err
 	}
 
-	if cachedResultRaw, found := cd.c.Get(requestKey); found {
-		var response v1.DispatchLookupResponse
-		if err := response.UnmarshalVT(cachedResultRaw.([]byte)); err != nil {
-			return &v1.DispatchLookupResponse{Metadata: &v1.ResponseMeta{}}, err
+	if cachedResultRaw, found := cd.cached(stream.Context(), requestKey, req.ObjectRelation, req.Metadata); found {
+		cachedSlices := cachedResultRaw.([][]byte)
+		responses := make([]*v1.DispatchLookupResponse, 0, len(cachedSlices))
+		var depthRequired uint32
+		for _, slice := range cachedSlices {
+			var response v1.DispatchLookupResponse
+			if err := response.UnmarshalVT(slice); err != nil {
+				return fmt.Errorf("could not publish cached lookup result: %w", err)
+			}
+
+			if response.Metadata.DepthRequired > depthRequired {
+				depthRequired = response.Metadata.DepthRequired
+			}
+			responses = append(responses, &response)
 		}
 
-		if req.Metadata.DepthRemaining >= response.Metadata.DepthRequired {
-			log.Ctx(ctx).Trace().Object("cachedLookup", req).Int("resultCount", len(response.ResolvedResources)).Send()
+		if req.Metadata.DepthRemaining >= depthRequired {
+			log.Ctx(stream.Context()).Trace().Object("cachedLookup", req).Int("responseCount", len(responses)).Send()
 			cd.lookupFromCacheCounter.Inc()
-			return &response, nil
+			for _, response := range responses {
+				if err := stream.Publish(response); err != nil {
+					return fmt.Errorf("could not publish cached lookup result: %w", err)
+				}
+			}
+			return nil
 		}
 	}
-	computed, err := cd.d.DispatchLookup(ctx, req)
 
-	// We only want to cache the result if there was no error.
-	if err == nil {
-		log.Ctx(ctx).Trace().Object("cachingLookup", req).Int("resultCount", len(computed.ResolvedResources)).Send()
+	var (
+		mu             sync.Mutex
+		toCacheResults [][]byte
+	)
+	wrapped := &dispatch.WrappedDispatchStream[*v1.DispatchLookupResponse]{
+		Stream: stream,
+		Ctx:    stream.Context(),
+		Processor: func(result *v1.DispatchLookupResponse) (*v1.DispatchLookupResponse, bool, error) {
+			adjustedResult := result.CloneVT()
+			adjustedResult.Metadata.CachedDispatchCount = adjustedResult.Metadata.DispatchCount
+			adjustedResult.Metadata.DispatchCount = 0
+			adjustedResult.Metadata.DebugInfo = nil
 
-		adjustedComputed := computed.CloneVT()
-		adjustedComputed.Metadata.CachedDispatchCount = adjustedComputed.Metadata.DispatchCount
-		adjustedComputed.Metadata.DispatchCount = 0
-		adjustedComputed.Metadata.DebugInfo = nil
+			adjustedBytes, err := adjustedResult.MarshalVT()
+			if err != nil {
+				return nil, false, err
+			}
 
-		adjustedBytes, err := adjustedComputed.MarshalVT()
-		if err != nil {
-			return &v1.DispatchLookupResponse{Metadata: &v1.ResponseMeta{}}, err
-		}
+			mu.Lock()
+			toCacheResults = append(toCacheResults, adjustedBytes)
+			mu.Unlock()
 
-		cd.c.Set(requestKey, adjustedBytes, sliceSize(adjustedBytes))
+			return result, true, nil
+		},
 	}
 
-	// Return both the computed and err in ALL cases: computed contains resolved
-	// metadata even if there was an error.
-	return computed, err
+	// We only want to cache the result if there was no error.
+	if err := cd.d.DispatchLookup(req, wrapped); err != nil {
+		return err
+	}
+
+	log.Ctx(stream.Context()).Trace().Object("cachingLookup", req).Int("responseCount", len(toCacheResults)).Send()
+
+	var size int64
+	for _, slice := range toCacheResults {
+		size += sliceSize(slice)
+	}
+
+	cd.cache(requestKey, req.Metadata, toCacheResults, size)
+	return nil
 }
 
 // DispatchReachableResources implements dispatch.ReachableResources interface.
@@ -275,7 +366,7 @@ func (cd *Dispatcher) DispatchReachableResources(req *v1.DispatchReachableResour
 		return err
 	}
 
-	if cachedResultRaw, found := cd.c.Get(requestKey); found {
+	if cachedResultRaw, found := cd.cached(stream.Context(), requestKey, req.ResourceRelation, req.Metadata); found {
 		cd.reachableResourcesFromCacheCounter.Inc()
 		for _, slice := range cachedResultRaw.([][]byte) {
 			var response v1.DispatchReachableResourcesResponse
@@ -325,7 +416,7 @@ func (cd *Dispatcher) DispatchReachableResources(req *v1.DispatchReachableResour
 		size += sliceSize(slice)
 	}
 
-	cd.c.Set(requestKey, toCacheResults, size)
+	cd.cache(requestKey, req.Metadata, toCacheResults, size)
 	return nil
 }
 
@@ -343,7 +434,7 @@ func (cd *Dispatcher) DispatchLookupSubjects(req *v1.DispatchLookupSubjectsReque
 		return err
 	}
 
-	if cachedResultRaw, found := cd.c.Get(requestKey); found {
+	if cachedResultRaw, found := cd.cached(stream.Context(), requestKey, req.ResourceRelation, req.Metadata); found {
 		cd.lookupSubjectsFromCacheCounter.Inc()
 		for _, slice := range cachedResultRaw.([][]byte) {
 			var response v1.DispatchLookupSubjectsResponse
@@ -395,7 +486,7 @@ func (cd *Dispatcher) DispatchLookupSubjects(req *v1.DispatchLookupSubjectsReque
 		size += sliceSize(slice)
 	}
 
-	cd.c.Set(requestKey, toCacheResults, size)
+	cd.cache(requestKey, req.Metadata, toCacheResults, size)
 	return nil
 }
 
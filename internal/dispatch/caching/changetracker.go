@@ -0,0 +1,293 @@
+package caching
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// watchRestartDelay is the delay before restarting the watch of the datastore, once interrupted.
+const watchRestartDelay = 1 * time.Second
+
+// ChangeTracker consumes the changes reported by the watch of a datastore, recording the latest
+// revision at which the relationships of each relation, and the schema, were changed. It is used
+// by the caching dispatcher to determine whether a result cached at one revision remains valid at
+// a later revision.
+type ChangeTracker struct {
+	ds     datastore.Datastore
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	lock sync.RWMutex
+
+	// watchedAfter is the revision after which changes have been recorded, or nil if the
+	// datastore is not being watched.
+	watchedAfter datastore.Revision
+
+	// watchedThrough is the revision through which all changes have been recorded.
+	watchedThrough datastore.Revision
+
+	// schemaChangedAt is the latest revision at which any definition was changed, if any.
+	schemaChangedAt datastore.Revision
+
+	// relationChangedAt holds the latest revision at which the relationships of each relation,
+	// in the form `namespace#relation`, were changed.
+	relationChangedAt map[string]datastore.Revision
+
+	// expiringRelations holds the relations, in the form `namespace#relation`, which have held
+	// any relationship with an expiration since the watch began. Relationships expire without a
+	// change being reported, so results depending on such relations are never reused.
+	expiringRelations map[string]struct{}
+
+	// expiringLoaded is whether the relations holding relationships with an expiration before
+	// the watch began have been read into expiringRelations. Until then, nothing may be reused.
+	expiringLoaded bool
+
+	// dependencies holds the relations on which each relation depends, keyed by its namespace
+	// and canonical cache key, as of the latest schema change.
+	dependencies map[string]map[string]struct{}
+}
+
+// NewChangeTracker creates a new change tracker, watching the given datastore until closed.
+func NewChangeTracker(ds datastore.Datastore) *ChangeTracker {
+	ctx, cancel := context.WithCancel(context.Background())
+	ct := &ChangeTracker{
+		ds:     ds,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	go ct.run(ctx)
+	return ct
+}
+
+// Close stops watching the datastore.
+func (ct *ChangeTracker) Close() error {
+	ct.cancel()
+	<-ct.done
+	return nil
+}
+
+func (ct *ChangeTracker) run(ctx context.Context) {
+	defer close(ct.done)
+
+	for {
+		err := ct.watch(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Ctx(ctx).Warn().Err(err).Msg("watch of datastore for dispatch cache changes was interrupted; restarting")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(watchRestartDelay):
+		}
+	}
+}
+
+// watch records the changes reported by the datastore until the watch fails. As changes made
+// while the datastore is not being watched are unknown, nothing may be reused until the watch
+// has restarted.
+func (ct *ChangeTracker) watch(ctx context.Context) error {
+	afterRevision, err := ct.ds.OptimizedRevision(ctx)
+	if err != nil {
+		return err
+	}
+
+	ct.reset(afterRevision)
+	defer ct.reset(nil)
+
+	// The relations holding expiring relationships before the watch began are read in the
+	// background, rather than when determining whether a result can be reused.
+	loadCtx, cancelLoad := context.WithCancel(ctx)
+	loaded := make(chan struct{})
+	go func() {
+		defer close(loaded)
+		if err := ct.loadExpiringRelations(loadCtx, afterRevision); err != nil && loadCtx.Err() == nil {
+			log.Ctx(ctx).Warn().Err(err).Msg("could not read relations with expiring relationships; cached dispatch results will not be reused across revisions")
+		}
+	}()
+	defer func() {
+		cancelLoad()
+		<-loaded
+	}()
+
+	updates, errchan := ct.ds.Watch(ctx, afterRevision, datastore.WatchOptions{})
+	for {
+		select {
+		case update, ok := <-updates:
+			if ok {
+				ct.record(update)
+			}
+		case err := <-errchan:
+			return err
+		}
+	}
+}
+
+func (ct *ChangeTracker) reset(watchedAfter datastore.Revision) {
+	ct.lock.Lock()
+	defer ct.lock.Unlock()
+
+	ct.watchedAfter = watchedAfter
+	ct.watchedThrough = watchedAfter
+	ct.schemaChangedAt = nil
+	ct.relationChangedAt = make(map[string]datastore.Revision)
+	ct.expiringRelations = make(map[string]struct{})
+	ct.expiringLoaded = false
+	ct.dependencies = make(map[string]map[string]struct{})
+}
+
+func (ct *ChangeTracker) record(update *datastore.RevisionChanges) {
+	ct.lock.Lock()
+	defer ct.lock.Unlock()
+
+	for _, change := range update.Changes {
+		resource := change.Tuple.ResourceAndRelation
+		relation := tuple.JoinRelRef(resource.Namespace, resource.Relation)
+		ct.relationChangedAt[relation] = update.Revision
+		if change.Operation != core.RelationTupleUpdate_DELETE && change.Tuple.OptionalExpirationTime != nil {
+			ct.expiringRelations[relation] = struct{}{}
+		}
+	}
+
+	if len(update.ChangedDefinitions) > 0 || len(update.DeletedNamespaces) > 0 || len(update.DeletedCaveats) > 0 {
+		ct.schemaChangedAt = update.Revision
+		ct.dependencies = make(map[string]map[string]struct{})
+	}
+
+	ct.watchedThrough = update.Revision
+}
+
+// canReuse returns whether a result for the resource relation, computed at the given revision,
+// remains valid at the requested revision, as neither the schema nor the relationships of any
+// relation on which it depends have been changed in between, and none of those relationships
+// can have expired in between.
+func (ct *ChangeTracker) canReuse(ctx context.Context, resourceRelation *core.RelationReference, computedAt datastore.Revision, requestedAt datastore.Revision) bool {
+	ct.lock.RLock()
+	watched := ct.watchedAfter != nil &&
+		ct.expiringLoaded &&
+		atOrAfter(computedAt, ct.watchedAfter) &&
+		atOrAfter(ct.watchedThrough, requestedAt) &&
+		(ct.schemaChangedAt == nil || atOrAfter(computedAt, ct.schemaChangedAt))
+	ct.lock.RUnlock()
+
+	if !watched || !requestedAt.GreaterThan(computedAt) {
+		return false
+	}
+
+	dependencies, err := ct.dependenciesOf(ctx, resourceRelation, requestedAt)
+	if err != nil {
+		log.Ctx(ctx).Debug().Err(err).Msg("could not determine dependencies of cached dispatch result")
+		return false
+	}
+
+	ct.lock.RLock()
+	defer ct.lock.RUnlock()
+	for dependency := range dependencies {
+		if changedAt, ok := ct.relationChangedAt[dependency]; ok && !atOrAfter(computedAt, changedAt) {
+			return false
+		}
+		if _, ok := ct.expiringRelations[dependency]; ok {
+			return false
+		}
+	}
+	return true
+}
+
+// loadExpiringRelations reads the relations which hold any relationship with an expiration at the
+// revision after which the datastore is watched. Relations defined later hold only relationships
+// written since, which are recorded from the watch.
+func (ct *ChangeTracker) loadExpiringRelations(ctx context.Context, watchedAfter datastore.Revision) error {
+	reader := ct.ds.SnapshotReader(watchedAfter)
+	namespaces, err := reader.ListAllNamespaces(ctx)
+	if err != nil {
+		return err
+	}
+
+	var expiring []string
+	for _, revisioned := range namespaces {
+		definition := revisioned.Definition
+		for _, relation := range definition.Relation {
+			// Permissions hold no relationships.
+			if relation.UsersetRewrite != nil {
+				continue
+			}
+
+			found, err := common.HasExpiringRelationships(ctx, reader, definition.Name, relation.Name)
+			if err != nil {
+				return err
+			}
+			if found {
+				expiring = append(expiring, tuple.JoinRelRef(definition.Name, relation.Name))
+			}
+		}
+	}
+
+	ct.lock.Lock()
+	defer ct.lock.Unlock()
+
+	// If the watch was restarted while reading, the relations read may not reflect the
+	// relationships written before the new watch began.
+	if ct.watchedAfter == nil || !ct.watchedAfter.Equal(watchedAfter) {
+		return nil
+	}
+
+	for _, relation := range expiring {
+		ct.expiringRelations[relation] = struct{}{}
+	}
+	ct.expiringLoaded = true
+	return nil
+}
+
+// dependenciesOf returns the relations on which the resource relation depends, as of the given
+// revision. Relations sharing a canonical cache key compute the same result from the same
+// relations, so the dependencies are held by canonical key.
+func (ct *ChangeTracker) dependenciesOf(ctx context.Context, resourceRelation *core.RelationReference, revision datastore.Revision) (map[string]struct{}, error) {
+	reader := ct.ds.SnapshotReader(revision)
+	_, relation, err := namespace.ReadNamespaceAndRelation(ctx, resourceRelation.Namespace, resourceRelation.Relation, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	canonicalKey := relation.CanonicalCacheKey
+	if canonicalKey == "" {
+		canonicalKey = relation.Name
+	}
+	key := tuple.JoinRelRef(resourceRelation.Namespace, canonicalKey)
+
+	ct.lock.RLock()
+	dependencies, ok := ct.dependencies[key]
+	ct.lock.RUnlock()
+	if ok {
+		return dependencies, nil
+	}
+
+	dependencies, err = namespace.RelationDependencies(ctx, namespace.ResolverForDatastoreReader(reader), resourceRelation.Namespace, resourceRelation.Relation)
+	if err != nil {
+		return nil, err
+	}
+
+	// The dependencies are only held if read since the latest schema change, as they may
+	// otherwise differ from those of the current schema.
+	ct.lock.Lock()
+	defer ct.lock.Unlock()
+	if ct.watchedAfter != nil && atOrAfter(revision, ct.watchedAfter) && (ct.schemaChangedAt == nil || atOrAfter(revision, ct.schemaChangedAt)) {
+		ct.dependencies[key] = dependencies
+	}
+	return dependencies, nil
+}
+
+// atOrAfter returns whether the first revision is provably at or after the second.
+func atOrAfter(revision datastore.Revision, other datastore.Revision) bool {
+	return revision.GreaterThan(other) || revision.Equal(other)
+}
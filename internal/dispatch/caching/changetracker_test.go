@@ -0,0 +1,231 @@
+package caching
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/pkg/datastore"
+	ns "github.com/authzed/spicedb/pkg/namespace"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+const changeTrackerSchema = `
+	definition user {}
+
+	definition folder {
+		relation viewer: user
+	}
+
+	definition document {
+		relation parent: folder
+		relation viewer: user
+		relation editor: user
+		permission view = viewer + parent->viewer
+	}
+`
+
+func TestCheckReusedAcrossRevisions(t *testing.T) {
+	require := require.New(t)
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	ds, _ := testfixtures.DatastoreFromSchemaAndTestRelationships(rawDS, changeTrackerSchema, []*core.RelationTuple{
+		tuple.MustParse("document:doc1#viewer@user:tom"),
+	}, require)
+
+	tracker := NewChangeTracker(ds)
+	defer tracker.Close()
+
+	// Wait for the tracker to begin watching and to read the relations with expiring
+	// relationships, before writing the revisions to be checked.
+	require.Eventually(func() bool {
+		tracker.lock.RLock()
+		defer tracker.lock.RUnlock()
+		return tracker.watchedAfter != nil && tracker.expiringLoaded
+	}, 5*time.Second, 10*time.Millisecond)
+
+	ctx := datastoremw.ContextWithDatastore(context.Background(), ds)
+	computedAt := writeRelationship(ctx, require, ds, tracker, "document:doc1#editor@user:sarah")
+
+	delegate := delegateDispatchMock{&mock.Mock{}}
+	dispatcher, err := NewCachingDispatcher(DispatchTestCache(t), false, "", nil)
+	require.NoError(err)
+	dispatcher.SetDelegate(delegate)
+	dispatcher.ReuseAcrossRevisions(tracker)
+	defer dispatcher.Close()
+
+	checkAt := func(revision datastore.Revision, expectPassthrough bool) {
+		req := &v1.DispatchCheckRequest{
+			ResourceRelation: RR("document", "view"),
+			ResourceIds:      []string{"doc1"},
+			Subject:          tuple.ParseSubjectONR("user:tom"),
+			Metadata: &v1.ResolverMeta{
+				AtRevision:     revision.String(),
+				DepthRemaining: 50,
+			},
+		}
+
+		if expectPassthrough {
+			delegate.On("DispatchCheck", req).Return(&v1.DispatchCheckResponse{
+				ResultsByResourceId: map[string]*v1.ResourceCheckResult{
+					"doc1": {Membership: v1.ResourceCheckResult_MEMBER},
+				},
+				Metadata: &v1.ResponseMeta{DispatchCount: 1, DepthRequired: 1},
+			}, nil).Times(1)
+		}
+
+		resp, err := dispatcher.DispatchCheck(ctx, req)
+		require.NoError(err)
+		require.Equal(v1.ResourceCheckResult_MEMBER, resp.ResultsByResourceId["doc1"].Membership)
+		delegate.AssertExpectations(t)
+
+		// Let the cache converge before the next check.
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	checkAt(computedAt, true)
+	checkAt(computedAt, false)
+
+	// The editor relation is not read by the permission, so the result is reused.
+	unrelated := writeRelationship(ctx, require, ds, tracker, "document:doc2#editor@user:sarah")
+	checkAt(unrelated, false)
+
+	// The viewer relation of the folder is read through the arrow, so the result is recomputed.
+	related := writeRelationship(ctx, require, ds, tracker, "folder:folder1#viewer@user:sarah")
+	checkAt(related, true)
+	checkAt(related, false)
+
+	// Any change to the schema requires the result to be recomputed.
+	schemaChanged, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteNamespaces(ctx, ns.Namespace("team"))
+	})
+	require.NoError(err)
+	waitForRevision(require, tracker, schemaChanged)
+	checkAt(schemaChanged, true)
+}
+
+func TestCheckNotReusedWithExpiringRelationships(t *testing.T) {
+	testCases := []struct {
+		name string
+
+		// initial are the relationships written before the tracker begins watching.
+		initial []string
+
+		// expiring is the relationship written once watching, expiring after the delay.
+		expiring string
+	}{
+		{
+			"expiring relationship written while watching",
+			nil,
+			"document:doc1#viewer@user:tom",
+		},
+		{
+			"expiring relationship written before watching",
+			[]string{"folder:folder1#viewer@user:tom[expiration:2100-01-01T00:00:00Z]"},
+			"",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+			require.NoError(err)
+
+			initial := make([]*core.RelationTuple, 0, len(tc.initial))
+			for _, relationship := range tc.initial {
+				initial = append(initial, tuple.MustParse(relationship))
+			}
+			ds, _ := testfixtures.DatastoreFromSchemaAndTestRelationships(rawDS, changeTrackerSchema, initial, require)
+
+			tracker := NewChangeTracker(ds)
+			defer tracker.Close()
+
+			require.Eventually(func() bool {
+				tracker.lock.RLock()
+				defer tracker.lock.RUnlock()
+				return tracker.watchedAfter != nil && tracker.expiringLoaded
+			}, 5*time.Second, 10*time.Millisecond)
+
+			ctx := datastoremw.ContextWithDatastore(context.Background(), ds)
+
+			expiresAt := time.Now().Add(500 * time.Millisecond)
+			computedAt := writeRelationship(ctx, require, ds, tracker, "document:doc2#editor@user:sarah")
+			if tc.expiring != "" {
+				computedAt = writeRelationship(ctx, require, ds, tracker, tc.expiring+tuple.StringExpiration(timestamppb.New(expiresAt)))
+			}
+
+			delegate := delegateDispatchMock{&mock.Mock{}}
+			dispatcher, err := NewCachingDispatcher(DispatchTestCache(t), false, "", nil)
+			require.NoError(err)
+			dispatcher.SetDelegate(delegate)
+			dispatcher.ReuseAcrossRevisions(tracker)
+			defer dispatcher.Close()
+
+			checkAt := func(revision datastore.Revision) {
+				req := &v1.DispatchCheckRequest{
+					ResourceRelation: RR("document", "view"),
+					ResourceIds:      []string{"doc1"},
+					Subject:          tuple.ParseSubjectONR("user:tom"),
+					Metadata: &v1.ResolverMeta{
+						AtRevision:     revision.String(),
+						DepthRemaining: 50,
+					},
+				}
+
+				delegate.On("DispatchCheck", req).Return(&v1.DispatchCheckResponse{
+					ResultsByResourceId: map[string]*v1.ResourceCheckResult{
+						"doc1": {Membership: v1.ResourceCheckResult_MEMBER},
+					},
+					Metadata: &v1.ResponseMeta{DispatchCount: 1, DepthRequired: 1},
+				}, nil).Times(1)
+
+				_, err := dispatcher.DispatchCheck(ctx, req)
+				require.NoError(err)
+				delegate.AssertExpectations(t)
+
+				// Let the cache converge before the next check.
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			checkAt(computedAt)
+
+			// Once the relationship has expired, the result computed before must not be reused,
+			// even though no change to the relationships of the permission has been observed.
+			time.Sleep(time.Until(expiresAt))
+			unrelated := writeRelationship(ctx, require, ds, tracker, "document:doc3#editor@user:sarah")
+			checkAt(unrelated)
+		})
+	}
+}
+
+func writeRelationship(ctx context.Context, require *require.Assertions, ds datastore.Datastore, tracker *ChangeTracker, relationship string) datastore.Revision {
+	revision, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships(ctx, []*core.RelationTupleUpdate{tuple.Touch(tuple.MustParse(relationship))})
+	})
+	require.NoError(err)
+
+	waitForRevision(require, tracker, revision)
+	return revision
+}
+
+func waitForRevision(require *require.Assertions, tracker *ChangeTracker, revision datastore.Revision) {
+	require.Eventually(func() bool {
+		tracker.lock.RLock()
+		defer tracker.lock.RUnlock()
+		return tracker.watchedThrough != nil && atOrAfter(tracker.watchedThrough, revision)
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -7,6 +7,8 @@ import (
 	"github.com/authzed/spicedb/internal/dispatch/caching"
 	"github.com/authzed/spicedb/internal/dispatch/graph"
 	"github.com/authzed/spicedb/internal/dispatch/keys"
+	"github.com/authzed/spicedb/internal/dispatch/singleflight"
+	"github.com/authzed/spicedb/internal/materialize"
 	"github.com/authzed/spicedb/pkg/cache"
 )
 
@@ -19,6 +21,8 @@ type optionState struct {
 	cache                 cache.Cache
 	concurrencyLimits     graph.ConcurrencyLimits
 	remoteDispatchTimeout time.Duration
+	changeTracker         *caching.ChangeTracker
+	materializedIndex     *materialize.Index
 }
 
 // MetricsEnabled enables issuing prometheus metrics
@@ -49,6 +53,22 @@ func ConcurrencyLimits(limits graph.ConcurrencyLimits) Option {
 	}
 }
 
+// ChangeTracker sets the tracker of datastore changes with which cached results are reused
+// across revisions. If unset, cached results are only reused at the revision they were computed.
+func ChangeTracker(tracker *caching.ChangeTracker) Option {
+	return func(state *optionState) {
+		state.changeTracker = tracker
+	}
+}
+
+// MaterializedIndex sets the index from which checks and lookups of materialized permissions are
+// answered, whenever it has caught up to their revision. If unset, all are computed.
+func MaterializedIndex(index *materialize.Index) Option {
+	return func(state *optionState) {
+		state.materializedIndex = index
+	}
+}
+
 // RemoteDispatchTimeout sets the maximum timeout for a remote dispatch.
 // Defaults to 60s (as defined in the remote dispatcher).
 func RemoteDispatchTimeout(remoteDispatchTimeout time.Duration) Option {
@@ -66,7 +86,7 @@ func NewClusterDispatcher(dispatch dispatch.Dispatcher, options ...Option) (disp
 		fn(&opts)
 	}
 
-	clusterDispatch := graph.NewDispatcher(dispatch, opts.concurrencyLimits)
+	clusterDispatch := graph.NewDispatcherWithMaterializedIndex(dispatch, opts.concurrencyLimits, opts.materializedIndex)
 
 	if opts.prometheusSubsystem == "" {
 		opts.prometheusSubsystem = "dispatch"
@@ -76,6 +96,10 @@ func NewClusterDispatcher(dispatch dispatch.Dispatcher, options ...Option) (disp
 	if err != nil {
 		return nil, err
 	}
-	cachingClusterDispatch.SetDelegate(clusterDispatch)
+
+	if opts.changeTracker != nil {
+		cachingClusterDispatch.ReuseAcrossRevisions(opts.changeTracker)
+	}
+	cachingClusterDispatch.SetDelegate(singleflight.New(clusterDispatch, &keys.CanonicalKeyHandler{}))
 	return cachingClusterDispatch, nil
 }
@@ -15,7 +15,9 @@ import (
 	"github.com/authzed/spicedb/internal/dispatch/graph"
 	"github.com/authzed/spicedb/internal/dispatch/keys"
 	"github.com/authzed/spicedb/internal/dispatch/remote"
+	"github.com/authzed/spicedb/internal/dispatch/singleflight"
 	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/internal/materialize"
 	"github.com/authzed/spicedb/pkg/cache"
 	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
 )
@@ -33,6 +35,8 @@ type optionState struct {
 	cache                 cache.Cache
 	concurrencyLimits     graph.ConcurrencyLimits
 	remoteDispatchTimeout time.Duration
+	changeTracker         *caching.ChangeTracker
+	materializedIndex     *materialize.Index
 }
 
 // MetricsEnabled enables issuing prometheus metrics
@@ -94,6 +98,22 @@ func ConcurrencyLimits(limits graph.ConcurrencyLimits) Option {
 	}
 }
 
+// ChangeTracker sets the tracker of datastore changes with which cached results are reused
+// across revisions. If unset, cached results are only reused at the revision they were computed.
+func ChangeTracker(tracker *caching.ChangeTracker) Option {
+	return func(state *optionState) {
+		state.changeTracker = tracker
+	}
+}
+
+// MaterializedIndex sets the index from which checks and lookups of materialized permissions are
+// answered, whenever it has caught up to their revision. If unset, all are computed.
+func MaterializedIndex(index *materialize.Index) Option {
+	return func(state *optionState) {
+		state.materializedIndex = index
+	}
+}
+
 // RemoteDispatchTimeout sets the maximum timeout for a remote dispatch.
 // Defaults to 60s (as defined in the remote dispatcher).
 func RemoteDispatchTimeout(remoteDispatchTimeout time.Duration) Option {
@@ -120,7 +140,11 @@ func NewDispatcher(options ...Option) (dispatch.Dispatcher, error) {
 		return nil, err
 	}
 
-	redispatch := graph.NewDispatcher(cachingRedispatch, opts.concurrencyLimits)
+	if opts.changeTracker != nil {
+		cachingRedispatch.ReuseAcrossRevisions(opts.changeTracker)
+	}
+
+	redispatch := graph.NewDispatcherWithMaterializedIndex(cachingRedispatch, opts.concurrencyLimits, opts.materializedIndex)
 
 	// If an upstream is specified, create a cluster dispatcher.
 	if opts.upstreamAddr != "" {
@@ -148,7 +172,9 @@ func NewDispatcher(options ...Option) (dispatch.Dispatcher, error) {
 		})
 	}
 
-	cachingRedispatch.SetDelegate(redispatch)
+	// Identical requests which miss the cache at the same time are coalesced, so that each is
+	// computed or dispatched to the cluster only once.
+	cachingRedispatch.SetDelegate(singleflight.New(redispatch, &keys.CanonicalKeyHandler{}))
 
 	return cachingRedispatch, nil
 }
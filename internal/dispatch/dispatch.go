@@ -50,10 +50,16 @@ type Expand interface {
 	DispatchExpand(ctx context.Context, req *v1.DispatchExpandRequest) (*v1.DispatchExpandResponse, error)
 }
 
+// LookupStream is an alias for the stream to which found resources will be written.
+type LookupStream = Stream[*v1.DispatchLookupResponse]
+
 // Lookup interface describes just the methods required to dispatch lookup requests.
 type Lookup interface {
-	// DispatchLookup submits a single lookup request and returns its result.
-	DispatchLookup(ctx context.Context, req *v1.DispatchLookupRequest) (*v1.DispatchLookupResponse, error)
+	// DispatchLookup submits a single lookup request, writing its results to the specified stream.
+	DispatchLookup(
+		req *v1.DispatchLookupRequest,
+		stream LookupStream,
+	) error
 }
 
 // ReachableResourcesStream is an alias for the stream to which reachable resources will be written.
@@ -10,8 +10,10 @@ import (
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 
+	"github.com/authzed/spicedb/internal/datastore/proxy"
 	"github.com/authzed/spicedb/internal/dispatch"
 	"github.com/authzed/spicedb/internal/graph"
+	"github.com/authzed/spicedb/internal/materialize"
 	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
 	"github.com/authzed/spicedb/pkg/datastore"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
@@ -96,6 +98,13 @@ func NewLocalOnlyDispatcherWithLimits(concurrencyLimits ConcurrencyLimits) dispa
 // NewDispatcher creates a dispatcher that consults with the graph and redispatches subproblems to
 // the provided redispatcher.
 func NewDispatcher(redispatcher dispatch.Dispatcher, concurrencyLimits ConcurrencyLimits) dispatch.Dispatcher {
+	return NewDispatcherWithMaterializedIndex(redispatcher, concurrencyLimits, nil)
+}
+
+// NewDispatcherWithMaterializedIndex creates a dispatcher as per NewDispatcher, which additionally
+// answers checks and lookups of materialized permissions from the given index, at those revisions
+// to which it has caught up. If the index is nil, all checks and lookups are computed.
+func NewDispatcherWithMaterializedIndex(redispatcher dispatch.Dispatcher, concurrencyLimits ConcurrencyLimits, index *materialize.Index) dispatch.Dispatcher {
 	concurrencyLimits = limitsOrDefaults(concurrencyLimits, defaultConcurrencyLimit)
 
 	checker := graph.NewConcurrentChecker(redispatcher, concurrencyLimits.Check)
@@ -104,6 +113,11 @@ func NewDispatcher(redispatcher dispatch.Dispatcher, concurrencyLimits Concurren
 	reachableResourcesHandler := graph.NewConcurrentReachableResources(redispatcher, concurrencyLimits.ReachableResources)
 	lookupSubjectsHandler := graph.NewConcurrentLookupSubjects(redispatcher, concurrencyLimits.LookupSubjects)
 
+	if index != nil {
+		checker.UseMaterializedIndex(index)
+		lookupHandler.UseMaterializedIndex(index)
+	}
+
 	return &localDispatcher{
 		checker:                   checker,
 		expander:                  expander,
@@ -133,6 +147,17 @@ func (ld *localDispatcher) loadNamespace(ctx context.Context, nsName string, rev
 	return ns, err
 }
 
+// withContextualRelationships returns a context whose datastore overlays the contextual
+// relationships of the request, if any, on those stored in the datastore.
+func withContextualRelationships(ctx context.Context, metadata *v1.ResolverMeta) context.Context {
+	if len(metadata.ContextualRelationships) == 0 {
+		return ctx
+	}
+
+	ds := datastoremw.MustFromContext(ctx)
+	return datastoremw.ContextWithDatastore(ctx, proxy.NewRelationshipsOverlayDatastore(ds, metadata.ContextualRelationships))
+}
+
 func (ld *localDispatcher) parseRevision(ctx context.Context, s string) (datastore.Revision, error) {
 	ds := datastoremw.MustFromContext(ctx)
 	return ds.RevisionFromString(s)
@@ -156,6 +181,10 @@ func (ld *localDispatcher) lookupRelation(_ context.Context, ns *core.NamespaceD
 
 // DispatchCheck implements dispatch.Check interface
 func (ld *localDispatcher) DispatchCheck(ctx context.Context, req *v1.DispatchCheckRequest) (*v1.DispatchCheckResponse, error) {
+	return memoizedCheck(ctx, req, ld.dispatchCheck)
+}
+
+func (ld *localDispatcher) dispatchCheck(ctx context.Context, req *v1.DispatchCheckRequest) (*v1.DispatchCheckResponse, error) {
 	ctx, span := tracer.Start(ctx, "DispatchCheck", trace.WithAttributes(
 		attribute.String("resource-type", tuple.StringRR(req.ResourceRelation)),
 		attribute.StringSlice("resource-ids", req.ResourceIds),
@@ -185,6 +214,8 @@ func (ld *localDispatcher) DispatchCheck(ctx context.Context, req *v1.DispatchCh
 		}, err
 	}
 
+	ctx = withContextualRelationships(ctx, req.Metadata)
+
 	revision, err := ld.parseRevision(ctx, req.Metadata.AtRevision)
 	if err != nil {
 		return &v1.DispatchCheckResponse{Metadata: emptyMetadata}, err
@@ -245,6 +276,8 @@ func (ld *localDispatcher) DispatchExpand(ctx context.Context, req *v1.DispatchE
 		return &v1.DispatchExpandResponse{Metadata: emptyMetadata}, err
 	}
 
+	ctx = withContextualRelationships(ctx, req.Metadata)
+
 	revision, err := ld.parseRevision(ctx, req.Metadata.AtRevision)
 	if err != nil {
 		return &v1.DispatchExpandResponse{Metadata: emptyMetadata}, err
@@ -267,10 +300,13 @@ func (ld *localDispatcher) DispatchExpand(ctx context.Context, req *v1.DispatchE
 }
 
 // DispatchLookup implements dispatch.Lookup interface
-func (ld *localDispatcher) DispatchLookup(ctx context.Context, req *v1.DispatchLookupRequest) (*v1.DispatchLookupResponse, error) {
+func (ld *localDispatcher) DispatchLookup(
+	req *v1.DispatchLookupRequest,
+	stream dispatch.LookupStream,
+) error {
 	// TODO(jschorr): Since lookup is now calling reachable resources exclusively, we should
 	// probably move it out of the dispatcher and into computed
-	ctx, span := tracer.Start(ctx, "DispatchLookup", trace.WithAttributes(
+	ctx, span := tracer.Start(stream.Context(), "DispatchLookup", trace.WithAttributes(
 		attribute.String("start", tuple.StringRR(req.ObjectRelation)),
 		attribute.String("subject", tuple.StringONR(req.Subject)),
 		attribute.Int64("limit", int64(req.Limit)),
@@ -278,22 +314,27 @@ func (ld *localDispatcher) DispatchLookup(ctx context.Context, req *v1.DispatchL
 	defer span.End()
 
 	if err := dispatch.CheckDepth(ctx, req); err != nil {
-		return &v1.DispatchLookupResponse{Metadata: emptyMetadata}, err
+		return err
 	}
 
+	ctx = withContextualRelationships(ctx, req.Metadata)
+
 	revision, err := ld.parseRevision(ctx, req.Metadata.AtRevision)
 	if err != nil {
-		return &v1.DispatchLookupResponse{Metadata: emptyMetadata}, err
+		return err
 	}
 
 	if req.Limit <= 0 {
-		return &v1.DispatchLookupResponse{Metadata: emptyMetadata, ResolvedResources: []*v1.ResolvedResource{}}, nil
+		return nil
 	}
 
-	return ld.lookupHandler.LookupViaReachability(ctx, graph.ValidatedLookupRequest{
-		DispatchLookupRequest: req,
-		Revision:              revision,
-	})
+	return ld.lookupHandler.LookupViaReachability(
+		graph.ValidatedLookupRequest{
+			DispatchLookupRequest: req,
+			Revision:              revision,
+		},
+		dispatch.StreamWithContext(ctx, stream),
+	)
 }
 
 // DispatchReachableResources implements dispatch.ReachableResources interface
@@ -312,6 +353,8 @@ func (ld *localDispatcher) DispatchReachableResources(
 		return err
 	}
 
+	ctx = withContextualRelationships(ctx, req.Metadata)
+
 	revision, err := ld.parseRevision(ctx, req.Metadata.AtRevision)
 	if err != nil {
 		return err
@@ -342,6 +385,8 @@ func (ld *localDispatcher) DispatchLookupSubjects(
 		return err
 	}
 
+	ctx = withContextualRelationships(ctx, req.Metadata)
+
 	revision, err := ld.parseRevision(ctx, req.Metadata.AtRevision)
 	if err != nil {
 		return err
@@ -11,6 +11,7 @@ import (
 	"go.uber.org/goleak"
 
 	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/dispatch"
 	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
 	"github.com/authzed/spicedb/internal/testfixtures"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
@@ -111,10 +112,11 @@ func TestSimpleLookup(t *testing.T) {
 			defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
 
 			require := require.New(t)
-			ctx, dispatch, revision := newLocalDispatcher(t)
-			defer dispatch.Close()
+			ctx, dispatcher, revision := newLocalDispatcher(t)
+			defer dispatcher.Close()
 
-			lookupResult, err := dispatch.DispatchLookup(ctx, &v1.DispatchLookupRequest{
+			stream := dispatch.NewCollectingDispatchStream[*v1.DispatchLookupResponse](ctx)
+			err := dispatcher.DispatchLookup(&v1.DispatchLookupRequest{
 				ObjectRelation: tc.start,
 				Subject:        tc.target,
 				Metadata: &v1.ResolverMeta{
@@ -122,21 +124,23 @@ func TestSimpleLookup(t *testing.T) {
 					DepthRemaining: 50,
 				},
 				Limit: 10,
-			})
+			}, stream)
 
 			require.NoError(err)
-			require.ElementsMatch(tc.expectedResources, lookupResult.ResolvedResources, "Found: %v, Expected: %v", lookupResult.ResolvedResources, tc.expectedResources)
-			require.GreaterOrEqual(lookupResult.Metadata.DepthRequired, uint32(1))
-			require.LessOrEqual(int(lookupResult.Metadata.DispatchCount), tc.expectedDispatchCount, "Found dispatch count greater than expected")
-			require.Equal(0, int(lookupResult.Metadata.CachedDispatchCount))
-			require.Equal(tc.expectedDepthRequired, int(lookupResult.Metadata.DepthRequired), "Depth required mismatch")
+			resolvedResources, metadata := collectLookupResults(stream.Results())
+			require.ElementsMatch(tc.expectedResources, resolvedResources, "Found: %v, Expected: %v", resolvedResources, tc.expectedResources)
+			require.GreaterOrEqual(metadata.DepthRequired, uint32(1))
+			require.LessOrEqual(int(metadata.DispatchCount), tc.expectedDispatchCount, "Found dispatch count greater than expected")
+			require.Equal(0, int(metadata.CachedDispatchCount))
+			require.Equal(tc.expectedDepthRequired, int(metadata.DepthRequired), "Depth required mismatch")
 
 			// We have to sleep a while to let the cache converge:
 			// https://github.com/outcaste-io/ristretto/blob/01b9f37dd0fd453225e042d6f3a27cd14f252cd0/cache_test.go#L17
 			time.Sleep(10 * time.Millisecond)
 
 			// Run again with the cache available.
-			lookupResult, err = dispatch.DispatchLookup(context.Background(), &v1.DispatchLookupRequest{
+			stream = dispatch.NewCollectingDispatchStream[*v1.DispatchLookupResponse](ctx)
+			err = dispatcher.DispatchLookup(&v1.DispatchLookupRequest{
 				ObjectRelation: tc.start,
 				Subject:        tc.target,
 				Metadata: &v1.ResolverMeta{
@@ -144,15 +148,16 @@ func TestSimpleLookup(t *testing.T) {
 					DepthRemaining: 50,
 				},
 				Limit: 10,
-			})
-			dispatch.Close()
+			}, stream)
+			dispatcher.Close()
 
 			require.NoError(err)
-			require.ElementsMatch(tc.expectedResources, lookupResult.ResolvedResources, "Found: %v, Expected: %v", lookupResult.ResolvedResources, tc.expectedResources)
-			require.GreaterOrEqual(lookupResult.Metadata.DepthRequired, uint32(1))
-			require.Equal(0, int(lookupResult.Metadata.DispatchCount))
-			require.LessOrEqual(int(lookupResult.Metadata.CachedDispatchCount), tc.expectedDispatchCount)
-			require.Equal(tc.expectedDepthRequired, int(lookupResult.Metadata.DepthRequired))
+			resolvedResources, metadata = collectLookupResults(stream.Results())
+			require.ElementsMatch(tc.expectedResources, resolvedResources, "Found: %v, Expected: %v", resolvedResources, tc.expectedResources)
+			require.GreaterOrEqual(metadata.DepthRequired, uint32(1))
+			require.Equal(0, int(metadata.DispatchCount))
+			require.LessOrEqual(int(metadata.CachedDispatchCount), tc.expectedDispatchCount)
+			require.Equal(tc.expectedDepthRequired, int(metadata.DepthRequired))
 		})
 	}
 }
@@ -165,11 +170,12 @@ func TestMaxDepthLookup(t *testing.T) {
 
 	ds, revision := testfixtures.StandardDatastoreWithData(rawDS, require)
 
-	dispatch := NewLocalOnlyDispatcher(10)
+	dispatcher := NewLocalOnlyDispatcher(10)
 	ctx := datastoremw.ContextWithHandle(context.Background())
 	require.NoError(datastoremw.SetInContext(ctx, ds))
 
-	_, err = dispatch.DispatchLookup(ctx, &v1.DispatchLookupRequest{
+	stream := dispatch.NewCollectingDispatchStream[*v1.DispatchLookupResponse](ctx)
+	err = dispatcher.DispatchLookup(&v1.DispatchLookupRequest{
 		ObjectRelation: RR("document", "view"),
 		Subject:        ONR("user", "legal", "..."),
 		Metadata: &v1.ResolverMeta{
@@ -177,11 +183,47 @@ func TestMaxDepthLookup(t *testing.T) {
 			DepthRemaining: 0,
 		},
 		Limit: 10,
-	})
+	}, stream)
 
 	require.Error(err)
 }
 
+func TestLookupLimit(t *testing.T) {
+	defer goleak.VerifyNone(t, goleakIgnores...)
+
+	require := require.New(t)
+	ctx, dispatcher, revision := newLocalDispatcher(t)
+	defer dispatcher.Close()
+
+	for _, limit := range []uint32{1, 2} {
+		stream := dispatch.NewCollectingDispatchStream[*v1.DispatchLookupResponse](ctx)
+		err := dispatcher.DispatchLookup(&v1.DispatchLookupRequest{
+			ObjectRelation: RR("document", "view"),
+			Subject:        ONR("user", "owner", "..."),
+			Metadata: &v1.ResolverMeta{
+				AtRevision:     revision.String(),
+				DepthRemaining: 50,
+			},
+			Limit: limit,
+		}, stream)
+		require.NoError(err)
+
+		resolvedResources, metadata := collectLookupResults(stream.Results())
+		require.Len(resolvedResources, int(limit))
+		require.GreaterOrEqual(metadata.DepthRequired, uint32(1))
+	}
+}
+
+func collectLookupResults(responses []*v1.DispatchLookupResponse) ([]*v1.ResolvedResource, *v1.ResponseMeta) {
+	resolvedResources := make([]*v1.ResolvedResource, 0)
+	metadata := &v1.ResponseMeta{}
+	for _, response := range responses {
+		resolvedResources = append(resolvedResources, response.ResolvedResources...)
+		dispatch.AddResponseMetadata(metadata, response.Metadata)
+	}
+	return resolvedResources, metadata
+}
+
 type OrderedResolved []*v1.ResolvedResource
 
 func (a OrderedResolved) Len() int { return len(a) }
@@ -191,3 +233,124 @@ func (a OrderedResolved) Less(i, j int) bool {
 }
 
 func (a OrderedResolved) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+
+func TestLookupWithCursor(t *testing.T) {
+	defer goleak.VerifyNone(t, goleakIgnores...)
+
+	require := require.New(t)
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	testRels := make([]*core.RelationTuple, 0)
+	for i := 0; i < 40; i++ {
+		if i%3 == 0 {
+			testRels = append(testRels, tuple.MustParse(fmt.Sprintf("document:doc%d#viewer@user:tom", i)))
+		}
+		if i%7 == 0 {
+			testRels = append(testRels, tuple.MustParse(fmt.Sprintf("document:doc%d#banned@user:tom", i)))
+		}
+		testRels = append(testRels, tuple.MustParse(fmt.Sprintf("document:doc%d#folder@folder:folder%d", i, i%5)))
+		testRels = append(testRels, tuple.MustParse(fmt.Sprintf("document:doc%d#viewer@group:group%d#member", i, i%4)))
+	}
+	testRels = append(testRels,
+		tuple.MustParse("folder:folder1#viewer@user:tom"),
+		tuple.MustParse("folder:folder3#parent@folder:folder1"),
+		tuple.MustParse("group:group2#member@user:tom"),
+	)
+
+	ds, revision := testfixtures.DatastoreFromSchemaAndTestRelationships(
+		rawDS,
+		`
+			definition user {}
+
+			definition group {
+				relation member: user | group#member
+			}
+
+			definition folder {
+				relation parent: folder
+				relation viewer: user | group#member
+				permission view = viewer + parent->view
+			}
+
+			definition document {
+				relation folder: folder
+				relation viewer: user | group#member
+				relation banned: user
+				permission view = (viewer + folder->view) - banned
+			}
+		`,
+		testRels,
+		require,
+	)
+
+	dispatcher := NewLocalOnlyDispatcher(10)
+	defer dispatcher.Close()
+
+	ctx := datastoremw.ContextWithHandle(context.Background())
+	require.NoError(datastoremw.SetInContext(ctx, ds))
+
+	lookup := func(limit uint32, cursor *v1.Cursor) []*v1.ResolvedResource {
+		stream := dispatch.NewCollectingDispatchStream[*v1.DispatchLookupResponse](ctx)
+		err := dispatcher.DispatchLookup(&v1.DispatchLookupRequest{
+			ObjectRelation: RR("document", "view"),
+			Subject:        ONR("user", "tom", "..."),
+			Metadata: &v1.ResolverMeta{
+				AtRevision:     revision.String(),
+				DepthRemaining: 50,
+			},
+			Limit:          limit,
+			OptionalCursor: cursor,
+		}, stream)
+		require.NoError(err)
+
+		resolvedResources, _ := collectLookupResults(stream.Results())
+		return resolvedResources
+	}
+
+	expected := make([]string, 0)
+	for _, resource := range lookup(^uint32(0), nil) {
+		expected = append(expected, resource.ResourceId)
+	}
+	require.NotEmpty(expected)
+
+	// Ensure that a single cursored lookup returns the same set of resources, each with a cursor.
+	all := lookup(^uint32(0), &v1.Cursor{})
+	allIDs := make([]string, 0, len(all))
+	for _, resource := range all {
+		require.NotNil(resource.AfterResponseCursor)
+		allIDs = append(allIDs, resource.ResourceId)
+	}
+	require.ElementsMatch(expected, allIDs)
+
+	for _, pageSize := range []uint32{1, 2, 5, 13, 100} {
+		pageSize := pageSize
+		t.Run(fmt.Sprintf("page-size-%d", pageSize), func(t *testing.T) {
+			found := make(map[string]struct{})
+			ordered := make([]string, 0)
+			cursor := &v1.Cursor{}
+			for {
+				page := lookup(pageSize, cursor)
+				require.LessOrEqual(len(page), int(pageSize))
+				if len(page) == 0 {
+					break
+				}
+
+				for _, resource := range page {
+					if _, ok := found[resource.ResourceId]; !ok {
+						found[resource.ResourceId] = struct{}{}
+						ordered = append(ordered, resource.ResourceId)
+					}
+				}
+
+				cursor = page[len(page)-1].AfterResponseCursor
+				require.NotNil(cursor)
+			}
+
+			// Paging must find every resource, in the same order as the single cursored lookup.
+			require.ElementsMatch(expected, ordered)
+			require.Equal(allIDs, ordered)
+		})
+	}
+}
@@ -4,12 +4,14 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"sync/atomic"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 	"go.uber.org/goleak"
 
 	"github.com/authzed/spicedb/internal/caveats"
+	"github.com/authzed/spicedb/internal/datasets"
 	"github.com/authzed/spicedb/internal/datastore/common"
 	"github.com/authzed/spicedb/internal/datastore/memdb"
 	"github.com/authzed/spicedb/internal/dispatch"
@@ -18,6 +20,7 @@ import (
 	"github.com/authzed/spicedb/internal/testfixtures"
 	itestutil "github.com/authzed/spicedb/internal/testutil"
 	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/options"
 	corev1 "github.com/authzed/spicedb/pkg/proto/core/v1"
 	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
 	"github.com/authzed/spicedb/pkg/tuple"
@@ -193,6 +196,231 @@ func TestSimpleLookupSubjects(t *testing.T) {
 	}
 }
 
+func TestLookupSubjectsWithLimitAndCursor(t *testing.T) {
+	defer goleak.VerifyNone(t, goleakIgnores...)
+
+	testCases := []struct {
+		resourceType     string
+		resourceID       string
+		permission       string
+		expectedSubjects []string
+	}{
+		{
+			"document",
+			"masterplan",
+			"view",
+			[]string{"auditor", "chief_financial_officer", "eng_lead", "legal", "owner", "product_manager", "vp_product"},
+		},
+		{
+			"document",
+			"specialplan",
+			"viewer_and_editor",
+			[]string{"missingrolegal", "multiroleguy"},
+		},
+		{
+			"document",
+			"specialplan",
+			"view_and_edit",
+			[]string{"multiroleguy"},
+		},
+		{
+			"folder",
+			"strategy",
+			"view",
+			[]string{"auditor", "legal", "owner", "vp_product"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		for _, limit := range []uint32{1, 2, 3, 100} {
+			limit := limit
+			t.Run(fmt.Sprintf("%s:%s:%s:%d", tc.resourceType, tc.resourceID, tc.permission, limit), func(t *testing.T) {
+				require := require.New(t)
+
+				ctx, dis, revision := newLocalDispatcher(t)
+				defer dis.Close()
+
+				foundSubjectIds := []string{}
+				var cursor *v1.Cursor
+				for {
+					stream := dispatch.NewCollectingDispatchStream[*v1.DispatchLookupSubjectsResponse](ctx)
+					err := dis.DispatchLookupSubjects(&v1.DispatchLookupSubjectsRequest{
+						ResourceRelation: RR(tc.resourceType, tc.permission),
+						ResourceIds:      []string{tc.resourceID},
+						SubjectRelation:  RR("user", "..."),
+						Metadata: &v1.ResolverMeta{
+							AtRevision:     revision.String(),
+							DepthRemaining: 50,
+						},
+						OptionalLimit:  limit,
+						OptionalCursor: cursor,
+					}, stream)
+					require.NoError(err)
+
+					// A limited lookup returns a single response.
+					require.LessOrEqual(len(stream.Results()), 1)
+					if len(stream.Results()) == 0 {
+						break
+					}
+
+					pageSubjectIds := []string{}
+					for _, found := range stream.Results()[0].FoundSubjectsByResourceId[tc.resourceID].FoundSubjects {
+						pageSubjectIds = append(pageSubjectIds, found.SubjectId)
+					}
+					require.LessOrEqual(len(pageSubjectIds), int(limit))
+
+					sort.Strings(pageSubjectIds)
+					foundSubjectIds = append(foundSubjectIds, pageSubjectIds...)
+					cursor = &v1.Cursor{Sections: []string{pageSubjectIds[len(pageSubjectIds)-1]}}
+				}
+
+				require.Equal(tc.expectedSubjects, foundSubjectIds)
+			})
+		}
+	}
+}
+
+func TestLookupSubjectsWithLimitReadsOnlyPage(t *testing.T) {
+	defer goleak.VerifyNone(t, goleakIgnores...)
+
+	require := require.New(t)
+
+	schema := `
+		definition user {}
+
+		definition group {
+			relation member: user
+		}
+
+		definition document {
+			relation viewer: user | user:* | group#member
+			permission view = viewer
+		}
+	`
+
+	relationships := []*corev1.RelationTuple{
+		tuple.MustParse("document:doc#viewer@user:*"),
+		tuple.MustParse("document:doc#viewer@group:admins#member"),
+		tuple.MustParse("group:admins#member@user:u0042a"),
+	}
+
+	expectedSubjectIds := []string{"u0042a"}
+	for i := 0; i < 100; i++ {
+		subjectID := fmt.Sprintf("u%04d", i)
+		relationships = append(relationships, tuple.MustParse("document:doc#viewer@user:"+subjectID))
+		expectedSubjectIds = append(expectedSubjectIds, subjectID)
+	}
+	sort.Strings(expectedSubjectIds)
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	ds, revision := testfixtures.DatastoreFromSchemaAndTestRelationships(rawDS, schema, relationships, require)
+	counting := &readCountingDatastore{Datastore: ds}
+
+	ctx := log.Logger.WithContext(datastoremw.ContextWithHandle(context.Background()))
+	require.NoError(datastoremw.SetInContext(ctx, counting))
+
+	dis := NewLocalOnlyDispatcher(10)
+	defer dis.Close()
+
+	const limit = 10
+
+	foundSubjectIds := []string{}
+	var cursor *v1.Cursor
+	for {
+		counting.read.Store(0)
+
+		stream := dispatch.NewCollectingDispatchStream[*v1.DispatchLookupSubjectsResponse](ctx)
+		err := dis.DispatchLookupSubjects(&v1.DispatchLookupSubjectsRequest{
+			ResourceRelation: RR("document", "view"),
+			ResourceIds:      []string{"doc"},
+			SubjectRelation:  RR("user", "..."),
+			Metadata: &v1.ResolverMeta{
+				AtRevision:     revision.String(),
+				DepthRemaining: 50,
+			},
+			OptionalLimit:  limit,
+			OptionalCursor: cursor,
+		}, stream)
+		require.NoError(err)
+
+		// Only the relationships for the page, the wildcard and those to dispatch should be read,
+		// rather than all of the relationships for the resource.
+		require.LessOrEqual(counting.read.Load(), int64(limit+5))
+
+		require.LessOrEqual(len(stream.Results()), 1)
+		if len(stream.Results()) == 0 {
+			break
+		}
+
+		pageSubjectIds := []string{}
+		foundWildcard := false
+		for _, found := range stream.Results()[0].FoundSubjectsByResourceId["doc"].FoundSubjects {
+			if found.SubjectId == tuple.PublicWildcard {
+				foundWildcard = true
+				continue
+			}
+			pageSubjectIds = append(pageSubjectIds, found.SubjectId)
+		}
+		require.True(foundWildcard, "expected the wildcard on every page")
+		require.LessOrEqual(len(pageSubjectIds), limit)
+		if len(pageSubjectIds) == 0 {
+			break
+		}
+
+		sort.Strings(pageSubjectIds)
+		foundSubjectIds = append(foundSubjectIds, pageSubjectIds...)
+		cursor = &v1.Cursor{Sections: []string{pageSubjectIds[len(pageSubjectIds)-1]}}
+	}
+
+	require.Equal(expectedSubjectIds, foundSubjectIds)
+}
+
+type readCountingDatastore struct {
+	datastore.Datastore
+	read atomic.Int64
+}
+
+func (cd *readCountingDatastore) SnapshotReader(rev datastore.Revision) datastore.Reader {
+	return readCountingReader{cd.Datastore.SnapshotReader(rev), &cd.read}
+}
+
+type readCountingReader struct {
+	datastore.Reader
+	read *atomic.Int64
+}
+
+func (cr readCountingReader) QueryRelationships(ctx context.Context, filter datastore.RelationshipsFilter, opts ...options.QueryOptionsOption) (datastore.RelationshipIterator, error) {
+	it, err := cr.Reader.QueryRelationships(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return readCountingIterator{it, cr.read}, nil
+}
+
+func (cr readCountingReader) ReverseQueryRelationships(ctx context.Context, subjectsFilter datastore.SubjectsFilter, opts ...options.ReverseQueryOptionsOption) (datastore.RelationshipIterator, error) {
+	it, err := cr.Reader.ReverseQueryRelationships(ctx, subjectsFilter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return readCountingIterator{it, cr.read}, nil
+}
+
+type readCountingIterator struct {
+	datastore.RelationshipIterator
+	read *atomic.Int64
+}
+
+func (ci readCountingIterator) Next() *corev1.RelationTuple {
+	tpl := ci.RelationshipIterator.Next()
+	if tpl != nil {
+		ci.read.Add(1)
+	}
+	return tpl
+}
+
 func TestLookupSubjectsMaxDepth(t *testing.T) {
 	require := require.New(t)
 
@@ -277,6 +505,55 @@ func TestLookupSubjectsDispatchCount(t *testing.T) {
 	}
 }
 
+func TestLookupSubjectsAllowingCompactSubjects(t *testing.T) {
+	for _, permission := range []string{"view", "edit", "view_and_edit", "viewer_and_editor"} {
+		permission := permission
+		t.Run(permission, func(t *testing.T) {
+			require := require.New(t)
+
+			ctx, dis, revision := newLocalDispatcher(t)
+			defer dis.Close()
+
+			lookup := func(allowCompactSubjects bool) datasets.SubjectSet {
+				stream := dispatch.NewCollectingDispatchStream[*v1.DispatchLookupSubjectsResponse](ctx)
+				err := dis.DispatchLookupSubjects(&v1.DispatchLookupSubjectsRequest{
+					ResourceRelation: RR("document", permission),
+					ResourceIds:      []string{"masterplan", "specialplan"},
+					SubjectRelation:  RR("user", "..."),
+					Metadata: &v1.ResolverMeta{
+						AtRevision:     revision.String(),
+						DepthRemaining: 50,
+					},
+					AllowCompactSubjects: allowCompactSubjects,
+				}, stream)
+				require.NoError(err)
+
+				found := datasets.NewSubjectSet()
+				for _, result := range stream.Results() {
+					for _, foundSubjects := range result.FoundSubjectsByResourceId {
+						if !allowCompactSubjects {
+							require.Empty(foundSubjects.ConcreteSubjectIds)
+						}
+
+						// Only subjects with a caveat expression or wildcards are returned as found subjects
+						// when compact subjects are allowed.
+						for _, foundSubject := range foundSubjects.FoundSubjects {
+							if allowCompactSubjects {
+								require.True(foundSubject.CaveatExpression != nil || foundSubject.SubjectId == tuple.PublicWildcard)
+							}
+						}
+
+						require.NoError(found.UnionWithFoundSubjects(foundSubjects))
+					}
+				}
+				return found
+			}
+
+			itestutil.RequireEquivalentSets(t, lookup(false).AsSlice(), lookup(true).AsSlice())
+		})
+	}
+}
+
 func TestCaveatedLookupSubjects(t *testing.T) {
 	testCases := []struct {
 		name          string
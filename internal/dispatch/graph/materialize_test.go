@@ -0,0 +1,230 @@
+package graph
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/dispatch"
+	"github.com/authzed/spicedb/internal/dispatch/caching"
+	"github.com/authzed/spicedb/internal/dispatch/keys"
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/internal/materialize"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+const materializedSchema = `
+	definition user {}
+
+	definition group {
+		relation member: user | group#member
+	}
+
+	definition folder {
+		relation parent: folder
+		relation viewer: user | group#member
+		materialized permission view = viewer + parent->view
+	}
+
+	definition document {
+		relation parent: folder
+		relation viewer: user | group#member
+		relation banned: user
+		relation public: user:*
+		materialized permission view = viewer + parent->view
+		materialized permission view_unbanned = view - banned
+		materialized permission public_view = view + public
+	}
+`
+
+var (
+	materializedUsers     = []string{"alice", "bob", "carol", "dave", "eve"}
+	materializedResources = map[string][]string{
+		"document": {"plan", "budget", "roadmap", "notes"},
+		"folder":   {"root", "plans"},
+	}
+)
+
+func TestMaterializedPermissions(t *testing.T) {
+	defer goleak.VerifyNone(t, goleakIgnores...)
+
+	require := require.New(t)
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	_, revision := testfixtures.DatastoreFromSchemaAndTestRelationships(rawDS, materializedSchema, []*core.RelationTuple{
+		tuple.MustParse("group:eng#member@user:alice"),
+		tuple.MustParse("group:eng#member@group:leads#member"),
+		tuple.MustParse("group:leads#member@user:bob"),
+		tuple.MustParse("folder:root#viewer@group:eng#member"),
+		tuple.MustParse("folder:plans#parent@folder:root"),
+		tuple.MustParse("folder:plans#viewer@user:carol"),
+		tuple.MustParse("document:plan#parent@folder:plans"),
+		tuple.MustParse("document:budget#viewer@user:dave"),
+		tuple.MustParse("document:roadmap#parent@folder:root"),
+		tuple.MustParse("document:roadmap#banned@user:bob"),
+		tuple.MustParse("document:notes#public@user:*"),
+	}, require)
+
+	// The index is written directly to the datastore, rather than through the validating
+	// datastore, as its namespace is not that of a valid definition.
+	ctx := log.Logger.WithContext(datastoremw.ContextWithHandle(context.Background()))
+	require.NoError(datastoremw.SetInContext(ctx, rawDS))
+
+	index := materialize.NewIndex()
+	dispatcher, err := caching.NewCachingDispatcher(nil, false, "", &keys.CanonicalKeyHandler{})
+	require.NoError(err)
+	dispatcher.SetDelegate(NewDispatcherWithMaterializedIndex(dispatcher, SharedConcurrencyLimits(10), index))
+	defer dispatcher.Close()
+
+	live := NewLocalOnlyDispatcher(10)
+	defer live.Close()
+
+	worker := materialize.StartWorker(rawDS, dispatcher, index, 50)
+	defer worker.Close()
+
+	permissions := []*core.RelationReference{
+		RR("document", "view"),
+		RR("document", "view_unbanned"),
+		RR("folder", "view"),
+	}
+
+	verify := func(written datastore.Revision) {
+		// The index is written at a later revision than that of the changes it reflects, so
+		// answers are verified at the head revision once the index has caught up to it.
+		revision := requireCaughtUp(ctx, t, index, rawDS, written, permissions)
+		for _, permission := range permissions {
+			for _, user := range materializedUsers {
+				subject := ONR("user", user, tuple.Ellipsis)
+				expected := checkMembers(ctx, t, live, permission, subject, revision)
+
+				members, ok, err := index.CheckMembers(ctx, rawDS.SnapshotReader(revision), revision, permission, materializedResources[permission.Namespace], subject)
+				require.NoError(err)
+				require.True(ok)
+				require.ElementsMatch(expected, members, "index mismatch for %s and %s", tuple.StringRR(permission), user)
+
+				require.ElementsMatch(expected, checkMembers(ctx, t, dispatcher, permission, subject, revision), "check mismatch for %s and %s", tuple.StringRR(permission), user)
+				require.ElementsMatch(lookupResources(ctx, t, live, permission, subject, revision), lookupResources(ctx, t, dispatcher, permission, subject, revision), "lookup mismatch for %s and %s", tuple.StringRR(permission), user)
+			}
+		}
+
+		// Permissions with wildcard members are never answered from the index.
+		_, ok, err := index.CheckMembers(ctx, rawDS.SnapshotReader(revision), revision, RR("document", "public_view"), materializedResources["document"], ONR("user", "alice", tuple.Ellipsis))
+		require.NoError(err)
+		require.False(ok)
+	}
+
+	verify(revision)
+
+	updates := []struct {
+		name      string
+		mutations []*core.RelationTupleUpdate
+	}{
+		{"add nested group member", []*core.RelationTupleUpdate{
+			tuple.Touch(tuple.MustParse("group:leads#member@user:eve")),
+		}},
+		{"remove nested group", []*core.RelationTupleUpdate{
+			tuple.Delete(tuple.MustParse("group:eng#member@group:leads#member")),
+		}},
+		{"move folder", []*core.RelationTupleUpdate{
+			tuple.Delete(tuple.MustParse("document:plan#parent@folder:plans")),
+			tuple.Touch(tuple.MustParse("document:plan#parent@folder:root")),
+		}},
+		{"ban user", []*core.RelationTupleUpdate{
+			tuple.Touch(tuple.MustParse("document:roadmap#banned@user:alice")),
+		}},
+		{"restore nested group", []*core.RelationTupleUpdate{
+			tuple.Touch(tuple.MustParse("group:eng#member@group:leads#member")),
+			tuple.Delete(tuple.MustParse("group:eng#member@user:alice")),
+		}},
+		{"add document", []*core.RelationTupleUpdate{
+			tuple.Touch(tuple.MustParse("document:notes#viewer@group:leads#member")),
+		}},
+	}
+
+	for _, update := range updates {
+		revision, err := rawDS.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+			return rwt.WriteRelationships(ctx, update.mutations)
+		})
+		require.NoError(err, update.name)
+		verify(revision)
+	}
+}
+
+func requireCaughtUp(ctx context.Context, t *testing.T, index *materialize.Index, ds datastore.Datastore, written datastore.Revision, permissions []*core.RelationReference) datastore.Revision {
+	var caughtUp datastore.Revision
+	require.Eventually(t, func() bool {
+		head, err := ds.HeadRevision(ctx)
+		require.NoError(t, err)
+		if head.LessThan(written) {
+			return false
+		}
+
+		for _, permission := range permissions {
+			_, ok, err := index.CheckMembers(ctx, ds.SnapshotReader(head), head, permission, nil, ONR("user", "alice", tuple.Ellipsis))
+			require.NoError(t, err)
+			if !ok {
+				return false
+			}
+		}
+
+		caughtUp = head
+		return true
+	}, 5*time.Second, 10*time.Millisecond, "index did not catch up to revision %s", written)
+	return caughtUp
+}
+
+func checkMembers(ctx context.Context, t *testing.T, dispatcher dispatch.Dispatcher, permission *core.RelationReference, subject *core.ObjectAndRelation, revision datastore.Revision) []string {
+	resp, err := dispatcher.DispatchCheck(ctx, &v1.DispatchCheckRequest{
+		ResourceRelation: permission,
+		ResourceIds:      materializedResources[permission.Namespace],
+		Subject:          subject,
+		ResultsSetting:   v1.DispatchCheckRequest_REQUIRE_ALL_RESULTS,
+		Metadata: &v1.ResolverMeta{
+			AtRevision:     revision.String(),
+			DepthRemaining: 50,
+		},
+	})
+	require.NoError(t, err)
+
+	members := make([]string, 0, len(resp.ResultsByResourceId))
+	for resourceID, result := range resp.ResultsByResourceId {
+		require.Equal(t, v1.ResourceCheckResult_MEMBER, result.Membership)
+		members = append(members, resourceID)
+	}
+	sort.Strings(members)
+	return members
+}
+
+func lookupResources(ctx context.Context, t *testing.T, dispatcher dispatch.Dispatcher, permission *core.RelationReference, subject *core.ObjectAndRelation, revision datastore.Revision) []string {
+	stream := dispatch.NewCollectingDispatchStream[*v1.DispatchLookupResponse](ctx)
+	err := dispatcher.DispatchLookup(&v1.DispatchLookupRequest{
+		ObjectRelation: permission,
+		Subject:        subject,
+		Metadata: &v1.ResolverMeta{
+			AtRevision:     revision.String(),
+			DepthRemaining: 50,
+		},
+		Limit: 100,
+	}, stream)
+	require.NoError(t, err)
+
+	resolvedResources, _ := collectLookupResults(stream.Results())
+	resourceIDs := make([]string, 0, len(resolvedResources))
+	for _, resolved := range resolvedResources {
+		resourceIDs = append(resourceIDs, resolved.ResourceId)
+	}
+	sort.Strings(resourceIDs)
+	return resourceIDs
+}
@@ -0,0 +1,73 @@
+package graph
+
+import (
+	"context"
+	"sync"
+
+	"github.com/authzed/spicedb/internal/dispatch/keys"
+	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+)
+
+type checkMemoKey struct{}
+
+var memoKeyHandler = &keys.DirectKeyHandler{}
+
+// checkMemo holds the results of the checks completed under a single request.
+type checkMemo struct {
+	lock    sync.RWMutex
+	results map[keys.DispatchCacheKey]*v1.DispatchCheckResponse
+}
+
+// ContextWithCheckMemo returns a context under which the results of the checks completed by
+// local dispatchers are memoized for the lifetime of the context, allowing requests which issue
+// many related checks to compute each shared subproblem only once, regardless of whether a
+// dispatch cache is configured.
+//
+// NOTE: only completed results are shared; checks running concurrently are not waited upon,
+// as doing so could deadlock on cycles in the relationship graph.
+func ContextWithCheckMemo(ctx context.Context) context.Context {
+	return context.WithValue(ctx, checkMemoKey{}, &checkMemo{
+		results: make(map[keys.DispatchCacheKey]*v1.DispatchCheckResponse),
+	})
+}
+
+// memoizedCheck returns the memoized result of the check, if the context carries a memo holding
+// one, and otherwise computes the check, memoizing its result.
+func memoizedCheck(
+	ctx context.Context,
+	req *v1.DispatchCheckRequest,
+	compute func(ctx context.Context, req *v1.DispatchCheckRequest) (*v1.DispatchCheckResponse, error),
+) (*v1.DispatchCheckResponse, error) {
+	memo, ok := ctx.Value(checkMemoKey{}).(*checkMemo)
+	if !ok || req.Debug != v1.DispatchCheckRequest_NO_DEBUG {
+		return compute(ctx, req)
+	}
+
+	key, err := memoKeyHandler.CheckCacheKey(ctx, req)
+	if err != nil {
+		return &v1.DispatchCheckResponse{Metadata: emptyMetadata}, err
+	}
+
+	memo.lock.RLock()
+	found, ok := memo.results[key]
+	memo.lock.RUnlock()
+
+	if ok && req.Metadata.DepthRemaining >= found.Metadata.DepthRequired {
+		return found.CloneVT(), nil
+	}
+
+	computed, err := compute(ctx, req)
+	if err != nil {
+		return computed, err
+	}
+
+	adjusted := computed.CloneVT()
+	adjusted.Metadata.CachedDispatchCount = adjusted.Metadata.DispatchCount
+	adjusted.Metadata.DispatchCount = 0
+
+	memo.lock.Lock()
+	memo.results[key] = adjusted
+	memo.lock.Unlock()
+
+	return computed, nil
+}
@@ -0,0 +1,67 @@
+package graph
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	log "github.com/authzed/spicedb/internal/logging"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+)
+
+func TestCheckMemo(t *testing.T) {
+	require := require.New(t)
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	ds, revision := testfixtures.StandardDatastoreWithData(rawDS, require)
+
+	// NOTE: no caching dispatcher is used, to ensure that any reuse is due to the memo.
+	dispatcher := NewLocalOnlyDispatcher(10)
+	t.Cleanup(func() { _ = dispatcher.Close() })
+
+	ctx := log.Logger.WithContext(datastoremw.ContextWithHandle(context.Background()))
+	require.NoError(datastoremw.SetInContext(ctx, ds))
+
+	check := func(ctx context.Context, relation string) *v1.DispatchCheckResponse {
+		resp, err := dispatcher.DispatchCheck(ctx, &v1.DispatchCheckRequest{
+			ResourceRelation: &core.RelationReference{Namespace: "document", Relation: relation},
+			ResourceIds:      []string{"masterplan"},
+			Subject:          ONR("user", "product_manager", "..."),
+			ResultsSetting:   v1.DispatchCheckRequest_ALLOW_SINGLE_RESULT,
+			Metadata: &v1.ResolverMeta{
+				AtRevision:     revision.String(),
+				DepthRemaining: 50,
+			},
+		})
+		require.NoError(err)
+		require.Equal(v1.ResourceCheckResult_MEMBER, resp.ResultsByResourceId["masterplan"].Membership)
+		return resp
+	}
+
+	memoCtx := ContextWithCheckMemo(ctx)
+
+	first := check(memoCtx, "edit")
+	require.Greater(first.Metadata.DispatchCount, uint32(0))
+	require.Equal(uint32(0), first.Metadata.CachedDispatchCount)
+
+	// The same check under the memo is not recomputed.
+	second := check(memoCtx, "edit")
+	require.Equal(uint32(0), second.Metadata.DispatchCount)
+	require.Equal(first.Metadata.DispatchCount, second.Metadata.CachedDispatchCount)
+
+	// A check depending on the memoized check reuses its result.
+	dependent := check(memoCtx, "view")
+	require.Greater(dependent.Metadata.CachedDispatchCount, uint32(0))
+
+	// Without the memo, the check is recomputed.
+	unmemoized := check(ctx, "edit")
+	require.Equal(first.Metadata.DispatchCount, unmemoized.Metadata.DispatchCount)
+	require.Equal(uint32(0), unmemoized.Metadata.CachedDispatchCount)
+}
@@ -31,25 +31,25 @@ var cachePrefixes = []cachePrefix{
 
 // checkRequestToKey converts a check request into a cache key based on the relation
 func checkRequestToKey(req *v1.DispatchCheckRequest, option dispatchCacheKeyHashComputeOption) DispatchCacheKey {
-	return dispatchCacheKeyHash(checkViaRelationPrefix, req.Metadata.AtRevision, option,
+	return dispatchCacheKeyHash(checkViaRelationPrefix, req.Metadata.AtRevision, option, withContextualRelationships(req.Metadata,
 		hashableRelationReference{req.ResourceRelation},
 		hashableIds(req.ResourceIds),
 		hashableOnr{req.Subject},
 		hashableResultSetting(req.ResultsSetting),
-	)
+	)...)
 }
 
 // checkRequestToKeyWithCanonical converts a check request into a cache key based
 // on the canonical key.
 func checkRequestToKeyWithCanonical(req *v1.DispatchCheckRequest, canonicalKey string) (DispatchCacheKey, error) {
 	// NOTE: canonical cache keys are only unique *within* a version of a namespace.
-	cacheKey := dispatchCacheKeyHash(checkViaCanonicalPrefix, req.Metadata.AtRevision, computeBothHashes,
+	cacheKey := dispatchCacheKeyHash(checkViaCanonicalPrefix, req.Metadata.AtRevision, computeBothHashes, withContextualRelationships(req.Metadata,
 		hashableString(req.ResourceRelation.Namespace),
 		hashableString(canonicalKey),
 		hashableIds(req.ResourceIds),
 		hashableOnr{req.Subject},
 		hashableResultSetting(req.ResultsSetting),
-	)
+	)...)
 
 	if canonicalKey == "" {
 		return cacheKey, spiceerrors.MustBugf("given empty canonical key for request: %s => %s", req.ResourceRelation, tuple.StringONR(req.Subject))
@@ -60,34 +60,58 @@ func checkRequestToKeyWithCanonical(req *v1.DispatchCheckRequest, canonicalKey s
 
 // lookupRequestToKey converts a lookup request into a cache key
 func lookupRequestToKey(req *v1.DispatchLookupRequest, option dispatchCacheKeyHashComputeOption) DispatchCacheKey {
-	return dispatchCacheKeyHash(lookupPrefix, req.Metadata.AtRevision, option,
+	return dispatchCacheKeyHash(lookupPrefix, req.Metadata.AtRevision, option, withContextualRelationships(req.Metadata,
 		hashableRelationReference{req.ObjectRelation},
 		hashableOnr{req.Subject},
 		hashableContext{req.Context}, // NOTE: context is included here because lookup does a single dispatch
-	)
+		hashableLimit(req.Limit),
+		hashableCursor{req.OptionalCursor},
+	)...)
 }
 
 // expandRequestToKey converts an expand request into a cache key
 func expandRequestToKey(req *v1.DispatchExpandRequest, option dispatchCacheKeyHashComputeOption) DispatchCacheKey {
-	return dispatchCacheKeyHash(expandPrefix, req.Metadata.AtRevision, option,
+	return dispatchCacheKeyHash(expandPrefix, req.Metadata.AtRevision, option, withContextualRelationships(req.Metadata,
 		hashableOnr{req.ResourceAndRelation},
-	)
+	)...)
 }
 
 // reachableResourcesRequestToKey converts a reachable resources request into a cache key
 func reachableResourcesRequestToKey(req *v1.DispatchReachableResourcesRequest, option dispatchCacheKeyHashComputeOption) DispatchCacheKey {
-	return dispatchCacheKeyHash(reachableResourcesPrefix, req.Metadata.AtRevision, option,
+	return dispatchCacheKeyHash(reachableResourcesPrefix, req.Metadata.AtRevision, option, withContextualRelationships(req.Metadata,
 		hashableRelationReference{req.ResourceRelation},
 		hashableRelationReference{req.SubjectRelation},
 		hashableIds(req.SubjectIds),
-	)
+		hashableCursor{req.OptionalCursor},
+	)...)
 }
 
 // lookupSubjectsRequestToKey converts a lookup subjects request into a cache key
 func lookupSubjectsRequestToKey(req *v1.DispatchLookupSubjectsRequest, option dispatchCacheKeyHashComputeOption) DispatchCacheKey {
-	return dispatchCacheKeyHash(lookupSubjectsPrefix, req.Metadata.AtRevision, option,
+	args := []hashableValue{
 		hashableRelationReference{req.ResourceRelation},
 		hashableRelationReference{req.SubjectRelation},
 		hashableIds(req.ResourceIds),
-	)
+		hashableLimit(req.OptionalLimit),
+		hashableCursor{req.OptionalCursor},
+	}
+
+	// NOTE: compact subjects are only added to the key when allowed, as the responses differ in
+	// form, while the keys of requests which do not allow them are left unchanged.
+	if req.AllowCompactSubjects {
+		args = append(args, hashableString("compact"))
+	}
+	args = withContextualRelationships(req.Metadata, args...)
+
+	return dispatchCacheKeyHash(lookupSubjectsPrefix, req.Metadata.AtRevision, option, args...)
+}
+
+// withContextualRelationships returns the hashable values for a key with the contextual
+// relationships of the request appended. The relationships are only appended if given, as every
+// value adds to the hash, so that the keys of requests without them are left unchanged.
+func withContextualRelationships(metadata *v1.ResolverMeta, args ...hashableValue) []hashableValue {
+	if len(metadata.GetContextualRelationships()) == 0 {
+		return args
+	}
+	return append(args, hashableContextualRelationships(metadata.ContextualRelationships))
 }
@@ -118,7 +118,7 @@ func TestStableCacheKeys(t *testing.T) {
 					},
 				}, computeBothHashes)
 			},
-			"87b8e4dcf893f4abd701",
+			"dafc9feacce9f4dbb001",
 		},
 		{
 			"lookup resources with nil context",
@@ -133,7 +133,7 @@ func TestStableCacheKeys(t *testing.T) {
 					Context: nil,
 				}, computeBothHashes)
 			},
-			"87b8e4dcf893f4abd701",
+			"dafc9feacce9f4dbb001",
 		},
 		{
 			"lookup resources with empty context",
@@ -151,7 +151,7 @@ func TestStableCacheKeys(t *testing.T) {
 					}(),
 				}, computeBothHashes)
 			},
-			"87b8e4dcf893f4abd701",
+			"dafc9feacce9f4dbb001",
 		},
 		{
 			"lookup resources with context",
@@ -172,7 +172,7 @@ func TestStableCacheKeys(t *testing.T) {
 					}(),
 				}, computeBothHashes)
 			},
-			"8a9bd5bba3ba9cde9301",
+			"b7b9abd5edfee4ff03",
 		},
 		{
 			"lookup resources with different context",
@@ -193,7 +193,7 @@ func TestStableCacheKeys(t *testing.T) {
 					}(),
 				}, computeBothHashes)
 			},
-			"f6db868dc194c19ade01",
+			"83e597a2cca8bde95c",
 		},
 		{
 			"lookup resources with escaped string",
@@ -213,7 +213,7 @@ func TestStableCacheKeys(t *testing.T) {
 					}(),
 				}, computeBothHashes)
 			},
-			"f98bb6f7fce8eb9ecc01",
+			"c1bfeb8ac6aadcac5f",
 		},
 		{
 			"lookup resources with nested context",
@@ -237,7 +237,38 @@ func TestStableCacheKeys(t *testing.T) {
 					}(),
 				}, computeBothHashes)
 			},
-			"e0d8d0e099d68b96fa01",
+			"e3909c82bdbabfd06d",
+		},
+		{
+			"lookup resources with cursor",
+			func() DispatchCacheKey {
+				return lookupRequestToKey(&v1.DispatchLookupRequest{
+					ObjectRelation: RR("document", "view"),
+					Subject:        ONR("user", "mariah", "..."),
+					Limit:          10,
+					Metadata: &v1.ResolverMeta{
+						AtRevision: "1234",
+					},
+					OptionalCursor: &v1.Cursor{
+						Sections: []string{"1", "foo"},
+					},
+				}, computeBothHashes)
+			},
+			"dde6a8e7f3f6dbe562",
+		},
+		{
+			"lookup resources with different limit",
+			func() DispatchCacheKey {
+				return lookupRequestToKey(&v1.DispatchLookupRequest{
+					ObjectRelation: RR("document", "view"),
+					Subject:        ONR("user", "mariah", "..."),
+					Limit:          20,
+					Metadata: &v1.ResolverMeta{
+						AtRevision: "1234",
+					},
+				}, computeBothHashes)
+			},
+			"f5becca1ddc395869f01",
 		},
 		{
 			"reachable resources",
@@ -251,7 +282,39 @@ func TestStableCacheKeys(t *testing.T) {
 					},
 				}, computeBothHashes)
 			},
-			"e8848b9dd68f93a6c801",
+			"caf99d9fe4d68ab63f",
+		},
+		{
+			"reachable resources with empty cursor",
+			func() DispatchCacheKey {
+				return reachableResourcesRequestToKey(&v1.DispatchReachableResourcesRequest{
+					ResourceRelation: RR("document", "view"),
+					SubjectRelation:  RR("user", "..."),
+					SubjectIds:       []string{"mariah", "tom"},
+					Metadata: &v1.ResolverMeta{
+						AtRevision: "1234",
+					},
+					OptionalCursor: &v1.Cursor{},
+				}, computeBothHashes)
+			},
+			"afbfcdabb799f1e2c801",
+		},
+		{
+			"reachable resources with cursor",
+			func() DispatchCacheKey {
+				return reachableResourcesRequestToKey(&v1.DispatchReachableResourcesRequest{
+					ResourceRelation: RR("document", "view"),
+					SubjectRelation:  RR("user", "..."),
+					SubjectIds:       []string{"mariah", "tom"},
+					Metadata: &v1.ResolverMeta{
+						AtRevision: "1234",
+					},
+					OptionalCursor: &v1.Cursor{
+						Sections: []string{"1", "0", "", "foo"},
+					},
+				}, computeBothHashes)
+			},
+			"bb8f8ee780fdeca09201",
 		},
 		{
 			"lookup subjects",
@@ -265,7 +328,55 @@ func TestStableCacheKeys(t *testing.T) {
 					},
 				}, computeBothHashes)
 			},
-			"d699c5b5d3a6dfade601",
+			"b5cab6a0dfedf3d7a901",
+		},
+		{
+			"lookup subjects with limit",
+			func() DispatchCacheKey {
+				return lookupSubjectsRequestToKey(&v1.DispatchLookupSubjectsRequest{
+					ResourceRelation: RR("document", "view"),
+					SubjectRelation:  RR("user", "..."),
+					ResourceIds:      []string{"mariah", "tom"},
+					Metadata: &v1.ResolverMeta{
+						AtRevision: "1234",
+					},
+					OptionalLimit: 10,
+				}, computeBothHashes)
+			},
+			"8ad0c59ae598e780ee01",
+		},
+		{
+			"lookup subjects with cursor",
+			func() DispatchCacheKey {
+				return lookupSubjectsRequestToKey(&v1.DispatchLookupSubjectsRequest{
+					ResourceRelation: RR("document", "view"),
+					SubjectRelation:  RR("user", "..."),
+					ResourceIds:      []string{"mariah", "tom"},
+					Metadata: &v1.ResolverMeta{
+						AtRevision: "1234",
+					},
+					OptionalLimit: 10,
+					OptionalCursor: &v1.Cursor{
+						Sections: []string{"sarah"},
+					},
+				}, computeBothHashes)
+			},
+			"9ee8a48d9ab2e0b88301",
+		},
+		{
+			"lookup subjects allowing compact subjects",
+			func() DispatchCacheKey {
+				return lookupSubjectsRequestToKey(&v1.DispatchLookupSubjectsRequest{
+					ResourceRelation: RR("document", "view"),
+					SubjectRelation:  RR("user", "..."),
+					ResourceIds:      []string{"mariah", "tom"},
+					Metadata: &v1.ResolverMeta{
+						AtRevision: "1234",
+					},
+					AllowCompactSubjects: true,
+				}, computeBothHashes)
+			},
+			"adc9e9afd5d3b5b38f01",
 		},
 	}
 
@@ -531,5 +642,38 @@ func TestComputeContextHash(t *testing.T) {
 		}(),
 	}, computeBothHashes)
 
-	require.Equal(t, "82b4a3a3c5e3ecf1df01", hex.EncodeToString(result.StableSumAsBytes()))
+	require.Equal(t, "fffecbcab0f1fc9022", hex.EncodeToString(result.StableSumAsBytes()))
+}
+
+func TestContextualRelationshipsHash(t *testing.T) {
+	keyFor := func(contextual ...string) DispatchCacheKey {
+		tuples := make([]*core.RelationTuple, 0, len(contextual))
+		for _, tplString := range contextual {
+			tuples = append(tuples, tuple.MustParse(tplString))
+		}
+
+		return checkRequestToKey(&v1.DispatchCheckRequest{
+			ResourceRelation: RR("document", "view"),
+			ResourceIds:      []string{"foo"},
+			Subject:          ONR("user", "tom", "..."),
+			Metadata: &v1.ResolverMeta{
+				AtRevision:              "1234",
+				ContextualRelationships: tuples,
+			},
+		}, computeBothHashes)
+	}
+
+	withoutContextual := keyFor()
+	withContextual := keyFor("document:foo#viewer@user:tom", "group:admins#member@user:tom")
+	require.NotEqual(t, withoutContextual, withContextual)
+
+	// The order of the contextual relationships does not matter.
+	require.Equal(t, withContextual, keyFor("group:admins#member@user:tom", "document:foo#viewer@user:tom"))
+
+	// Caveats on the contextual relationships do matter.
+	require.NotEqual(t, withContextual, keyFor("document:foo#viewer@user:tom[somecaveat]", "group:admins#member@user:tom"))
+	require.NotEqual(t,
+		keyFor(`document:foo#viewer@user:tom[somecaveat:{"a":1}]`),
+		keyFor(`document:foo#viewer@user:tom[somecaveat:{"a":2}]`),
+	)
 }
@@ -70,6 +70,53 @@ func (hs hashableString) AppendToHash(hasher hasherInterface) {
 	hasher.WriteString(string(hs))
 }
 
+type hashableLimit uint32
+
+func (hl hashableLimit) AppendToHash(hasher hasherInterface) {
+	hasher.WriteString(strconv.FormatUint(uint64(hl), 10))
+}
+
+type hashableCursor struct{ *v1.Cursor }
+
+func (hc hashableCursor) AppendToHash(hasher hasherInterface) {
+	// NOTE: a nil cursor adds nothing to the hash, while an empty cursor must still be
+	// distinguished from it, as it indicates that the results are to be cursored.
+	if hc.Cursor == nil {
+		return
+	}
+
+	hasher.WriteString("cursor:")
+	for _, section := range hc.Sections {
+		hasher.WriteString(strconv.Quote(section))
+		hasher.WriteString(",")
+	}
+}
+
+type hashableContextualRelationships []*core.RelationTuple
+
+func (hcr hashableContextualRelationships) AppendToHash(hasher hasherInterface) {
+	// Sort the relationships to canonicalize them, cloning to ensure that the slice held by
+	// the request is not modified.
+	c := make([]*core.RelationTuple, len(hcr))
+	copy(c, hcr)
+	sort.Slice(c, func(i, j int) bool {
+		return tuple.StringWithoutCaveat(c[i]) < tuple.StringWithoutCaveat(c[j])
+	})
+
+	hasher.WriteString("contextual:")
+	for _, tpl := range c {
+		hasher.WriteString(tuple.StringWithoutCaveat(tpl))
+		if tpl.Caveat != nil {
+			hasher.WriteString("[")
+			hasher.WriteString(tpl.Caveat.CaveatName)
+			hasher.WriteString(":")
+			hashableContext{tpl.Caveat.Context}.AppendToHash(hasher)
+			hasher.WriteString("]")
+		}
+		hasher.WriteString(",")
+	}
+}
+
 type hashableContext struct{ *structpb.Struct }
 
 func (hc hashableContext) AppendToHash(hasher hasherInterface) {
@@ -95,35 +95,90 @@ type CanonicalKeyHandler struct {
 }
 
 func (c *CanonicalKeyHandler) CheckCacheKey(ctx context.Context, req *v1.DispatchCheckRequest) (DispatchCacheKey, error) {
+	canonicalKey, err := canonicalCacheKey(ctx, req)
+	if err != nil {
+		return emptyDispatchCacheKey, err
+	}
+
+	// TODO(jschorr): Remove this conditional once we have a verified migration ordering system that ensures a backfill migration has
+	// run after the namespace annotation code has been fully deployed by users.
+	if canonicalKey != "" {
+		return checkRequestToKeyWithCanonical(req, canonicalKey)
+	}
+
+	return checkRequestToKey(req, computeBothHashes), nil
+}
+
+// CrossRevisionKeyHandler is a key handler which computes the same cache keys as the
+// CanonicalKeyHandler, but without the revision of the request, so that a result cached at one
+// revision can be found for the same request at another. Results found must therefore be
+// verified as unchanged at the revision requested before use.
+type CrossRevisionKeyHandler struct {
+	CanonicalKeyHandler
+}
+
+func (c *CrossRevisionKeyHandler) CheckCacheKey(ctx context.Context, req *v1.DispatchCheckRequest) (DispatchCacheKey, error) {
+	canonicalKey, err := canonicalCacheKey(ctx, req)
+	if err != nil {
+		return emptyDispatchCacheKey, err
+	}
+
+	withoutRevision := req.CloneVT()
+	withoutRevision.Metadata.AtRevision = ""
+
+	if canonicalKey != "" {
+		return checkRequestToKeyWithCanonical(withoutRevision, canonicalKey)
+	}
+
+	return checkRequestToKey(withoutRevision, computeBothHashes), nil
+}
+
+func (c *CrossRevisionKeyHandler) LookupResourcesCacheKey(_ context.Context, req *v1.DispatchLookupRequest) (DispatchCacheKey, error) {
+	withoutRevision := req.CloneVT()
+	withoutRevision.Metadata.AtRevision = ""
+	return lookupRequestToKey(withoutRevision, computeBothHashes), nil
+}
+
+func (c *CrossRevisionKeyHandler) ReachableResourcesCacheKey(_ context.Context, req *v1.DispatchReachableResourcesRequest) (DispatchCacheKey, error) {
+	withoutRevision := req.CloneVT()
+	withoutRevision.Metadata.AtRevision = ""
+	return reachableResourcesRequestToKey(withoutRevision, computeBothHashes), nil
+}
+
+func (c *CrossRevisionKeyHandler) LookupSubjectsCacheKey(_ context.Context, req *v1.DispatchLookupSubjectsRequest) (DispatchCacheKey, error) {
+	withoutRevision := req.CloneVT()
+	withoutRevision.Metadata.AtRevision = ""
+	return lookupSubjectsRequestToKey(withoutRevision, computeBothHashes), nil
+}
+
+// canonicalCacheKey returns the canonical cache key of the relation being checked, or empty if
+// the canonical key cannot be used for the request.
+func canonicalCacheKey(ctx context.Context, req *v1.DispatchCheckRequest) (string, error) {
 	// NOTE: We do not use the canonicalized cache key when checking within the same namespace, as
 	// we may get different results if the subject being checked matches the resource exactly, e.g.
 	// a check for `somenamespace:someobject#somerel@somenamespace:someobject#somerel`.
-	if req.ResourceRelation.Namespace != req.Subject.Namespace {
-		// Load the relation to get its computed cache key, if any.
-		ds := datastoremw.MustFromContext(ctx)
-
-		revision, err := ds.RevisionFromString(req.Metadata.AtRevision)
-		if err != nil {
-			return emptyDispatchCacheKey, err
-		}
-		r := ds.SnapshotReader(revision)
-
-		_, relation, err := namespace.ReadNamespaceAndRelation(
-			ctx,
-			req.ResourceRelation.Namespace,
-			req.ResourceRelation.Relation,
-			r,
-		)
-		if err != nil {
-			return emptyDispatchCacheKey, err
-		}
-
-		// TODO(jschorr): Remove this conditional once we have a verified migration ordering system that ensures a backfill migration has
-		// run after the namespace annotation code has been fully deployed by users.
-		if relation.CanonicalCacheKey != "" {
-			return checkRequestToKeyWithCanonical(req, relation.CanonicalCacheKey)
-		}
+	if req.ResourceRelation.Namespace == req.Subject.Namespace {
+		return "", nil
 	}
 
-	return checkRequestToKey(req, computeBothHashes), nil
+	// Load the relation to get its computed cache key, if any.
+	ds := datastoremw.MustFromContext(ctx)
+
+	revision, err := ds.RevisionFromString(req.Metadata.AtRevision)
+	if err != nil {
+		return "", err
+	}
+	r := ds.SnapshotReader(revision)
+
+	_, relation, err := namespace.ReadNamespaceAndRelation(
+		ctx,
+		req.ResourceRelation.Namespace,
+		req.ResourceRelation.Relation,
+		r,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return relation.CanonicalCacheKey, nil
 }
@@ -0,0 +1,262 @@
+// Package singleflight implements a dispatcher which coalesces identical requests that are in
+// flight at the same time, so that concurrent identical subproblems are computed only once.
+package singleflight
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/authzed/spicedb/internal/dispatch"
+	"github.com/authzed/spicedb/internal/dispatch/keys"
+	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+)
+
+var sharedResultCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "spicedb",
+	Subsystem: "dispatch",
+	Name:      "singleflight_shared_total",
+	Help:      "number of dispatches answered by an identical dispatch already in flight",
+}, []string{"method"})
+
+func init() {
+	prometheus.MustRegister(sharedResultCounter)
+}
+
+// Dispatcher is a dispatcher which coalesces identical check and lookup subjects requests,
+// as determined by the keys of the key handler, that are in flight at the same time. All other
+// requests are passed to the delegate.
+type Dispatcher struct {
+	delegate   dispatch.Dispatcher
+	keyHandler keys.Handler
+
+	checkGroup singleflight.Group
+
+	lookupSubjectsMu    sync.Mutex
+	lookupSubjectsCalls map[string]*lookupSubjectsCall
+}
+
+// New creates a new singleflight dispatcher, delegating to the given dispatcher.
+func New(delegate dispatch.Dispatcher, keyHandler keys.Handler) *Dispatcher {
+	return &Dispatcher{
+		delegate:            delegate,
+		keyHandler:          keyHandler,
+		lookupSubjectsCalls: make(map[string]*lookupSubjectsCall),
+	}
+}
+
+// DispatchCheck implements dispatch.Check interface.
+func (d *Dispatcher) DispatchCheck(ctx context.Context, req *v1.DispatchCheckRequest) (*v1.DispatchCheckResponse, error) {
+	// Debug traces are specific to the request, so are never shared.
+	if req.Debug != v1.DispatchCheckRequest_NO_DEBUG {
+		return d.delegate.DispatchCheck(ctx, req)
+	}
+
+	requestKey, err := d.keyHandler.CheckCacheKey(ctx, req)
+	if err != nil {
+		return &v1.DispatchCheckResponse{Metadata: &v1.ResponseMeta{}}, err
+	}
+
+	computedHere := false
+	resultChan := d.checkGroup.DoChan(keyString(requestKey, req.Metadata), func() (any, error) {
+		computedHere = true
+		return d.delegate.DispatchCheck(ctx, req)
+	})
+
+	// A caller whose context is done stops waiting, without affecting the other callers. The
+	// computation, if started by this caller, is canceled along with its context.
+	var result singleflight.Result
+	select {
+	case result = <-resultChan:
+	case <-ctx.Done():
+		return &v1.DispatchCheckResponse{Metadata: &v1.ResponseMeta{}}, ctx.Err()
+	}
+
+	resp, err := result.Val.(*v1.DispatchCheckResponse), result.Err
+	if !result.Shared {
+		return resp, err
+	}
+
+	// The response is shared with the other callers, so each receives its own copy.
+	if computedHere {
+		return resp.CloneVT(), err
+	}
+
+	// Errors may be specific to the request that computed them, such as its cancellation, so
+	// are recomputed.
+	if err != nil {
+		return d.delegate.DispatchCheck(ctx, req)
+	}
+
+	sharedResultCounter.WithLabelValues("check").Inc()
+	adjusted := resp.CloneVT()
+	adjusted.Metadata.CachedDispatchCount = adjusted.Metadata.DispatchCount
+	adjusted.Metadata.DispatchCount = 0
+	return adjusted, nil
+}
+
+// DispatchLookupSubjects implements dispatch.LookupSubjects interface.
+func (d *Dispatcher) DispatchLookupSubjects(req *v1.DispatchLookupSubjectsRequest, stream dispatch.LookupSubjectsStream) error {
+	ctx := stream.Context()
+	requestKey, err := d.keyHandler.LookupSubjectsCacheKey(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	key := keyString(requestKey, req.Metadata)
+
+	d.lookupSubjectsMu.Lock()
+	if call, ok := d.lookupSubjectsCalls[key]; ok && call.join() {
+		d.lookupSubjectsMu.Unlock()
+		return d.waitForLookupSubjects(ctx, call, req, stream)
+	}
+
+	// The results are published to the stream of this request as they are computed, and only
+	// collected once another request has joined the call.
+	call := &lookupSubjectsCall{stream: stream, done: make(chan struct{})}
+	d.lookupSubjectsCalls[key] = call
+	d.lookupSubjectsMu.Unlock()
+
+	// See DispatchCheck. The computation runs on another goroutine, so once this caller stops
+	// waiting, its stream must no longer be published to.
+	go func() {
+		call.err = d.delegate.DispatchLookupSubjects(req, call)
+
+		d.lookupSubjectsMu.Lock()
+		if d.lookupSubjectsCalls[key] == call {
+			delete(d.lookupSubjectsCalls, key)
+		}
+		d.lookupSubjectsMu.Unlock()
+		close(call.done)
+	}()
+
+	select {
+	case <-call.done:
+		return call.err
+	case <-ctx.Done():
+		call.abandon()
+		return ctx.Err()
+	}
+}
+
+// waitForLookupSubjects waits for the call joined by the request to complete, and publishes its
+// results to the stream of the request.
+func (d *Dispatcher) waitForLookupSubjects(ctx context.Context, call *lookupSubjectsCall, req *v1.DispatchLookupSubjectsRequest, stream dispatch.LookupSubjectsStream) error {
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	// See DispatchCheck for why errors are recomputed.
+	if call.err != nil {
+		return d.delegate.DispatchLookupSubjects(req, stream)
+	}
+
+	sharedResultCounter.WithLabelValues("lookup_subjects").Inc()
+	for _, response := range call.collected {
+		adjusted := response.CloneVT()
+		adjusted.Metadata.CachedDispatchCount = adjusted.Metadata.DispatchCount
+		adjusted.Metadata.DispatchCount = 0
+
+		if err := stream.Publish(adjusted); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DispatchExpand implements dispatch.Expand interface.
+func (d *Dispatcher) DispatchExpand(ctx context.Context, req *v1.DispatchExpandRequest) (*v1.DispatchExpandResponse, error) {
+	return d.delegate.DispatchExpand(ctx, req)
+}
+
+// DispatchLookup implements dispatch.Lookup interface.
+func (d *Dispatcher) DispatchLookup(req *v1.DispatchLookupRequest, stream dispatch.LookupStream) error {
+	return d.delegate.DispatchLookup(req, stream)
+}
+
+// DispatchReachableResources implements dispatch.ReachableResources interface.
+func (d *Dispatcher) DispatchReachableResources(req *v1.DispatchReachableResourcesRequest, stream dispatch.ReachableResourcesStream) error {
+	return d.delegate.DispatchReachableResources(req, stream)
+}
+
+func (d *Dispatcher) Close() error {
+	return d.delegate.Close()
+}
+
+func (d *Dispatcher) ReadyState() dispatch.ReadyState {
+	return d.delegate.ReadyState()
+}
+
+// lookupSubjectsCall is an in-flight lookup subjects computation. Its results are published to
+// the stream of the request computing them, until that request is abandoned. Other requests may
+// join the call only until its first result is published, as earlier results are not retained;
+// once joined, the results are collected for them.
+type lookupSubjectsCall struct {
+	stream dispatch.LookupSubjectsStream
+	done   chan struct{}
+
+	mu        sync.Mutex
+	published bool
+	joined    bool
+	abandoned bool
+	collected []*v1.DispatchLookupSubjectsResponse
+	err       error
+}
+
+// join joins another request to the call, returning false if results have already been
+// published.
+func (lc *lookupSubjectsCall) join() bool {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	if lc.published {
+		return false
+	}
+	lc.joined = true
+	return true
+}
+
+func (lc *lookupSubjectsCall) Publish(result *v1.DispatchLookupSubjectsResponse) error {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	lc.published = true
+	if lc.joined {
+		lc.collected = append(lc.collected, result.CloneVT())
+	}
+	if lc.abandoned {
+		return nil
+	}
+	return lc.stream.Publish(result)
+}
+
+func (lc *lookupSubjectsCall) Context() context.Context {
+	return lc.stream.Context()
+}
+
+// abandon stops publishing to the stream of the request computing the results.
+func (lc *lookupSubjectsCall) abandon() {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	lc.abandoned = true
+}
+
+// keyString returns the key under which identical requests are coalesced. The remaining depth
+// is included, as the dispatch keys do not include it: a request which recursively dispatches
+// itself, such as over cyclic relationships, must not wait on its own computation, and a result
+// computed with more depth remaining may not be valid for a request with less.
+func keyString(key keys.DispatchCacheKey, metadata *v1.ResolverMeta) string {
+	processSpecificSum, stableSum := key.AsUInt64s()
+	b := make([]byte, 0, 20)
+	b = binary.LittleEndian.AppendUint64(b, processSpecificSum)
+	b = binary.LittleEndian.AppendUint64(b, stableSum)
+	return string(binary.LittleEndian.AppendUint32(b, metadata.GetDepthRemaining()))
+}
+
+// Always verify that we implement the interface
+var _ dispatch.Dispatcher = &Dispatcher{}
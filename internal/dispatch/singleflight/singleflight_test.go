@@ -0,0 +1,383 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/dispatch"
+	"github.com/authzed/spicedb/internal/dispatch/keys"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+const concurrentRequests = 10
+
+// blockingDispatcher blocks each dispatch until released, counting the dispatches made and
+// failing the first if requested.
+type blockingDispatcher struct {
+	dispatch.Dispatcher
+
+	started   chan struct{}
+	release   chan struct{}
+	failFirst bool
+
+	startOnce  sync.Once
+	dispatched atomic.Int32
+}
+
+func newBlockingDispatcher(failFirst bool) *blockingDispatcher {
+	return &blockingDispatcher{
+		started:   make(chan struct{}),
+		release:   make(chan struct{}),
+		failFirst: failFirst,
+	}
+}
+
+func (bd *blockingDispatcher) wait() error {
+	count := bd.dispatched.Add(1)
+	bd.startOnce.Do(func() { close(bd.started) })
+	<-bd.release
+
+	if bd.failFirst && count == 1 {
+		return errors.New("first dispatch failed")
+	}
+	return nil
+}
+
+func (bd *blockingDispatcher) DispatchCheck(_ context.Context, req *v1.DispatchCheckRequest) (*v1.DispatchCheckResponse, error) {
+	err := bd.wait()
+	if err != nil {
+		return &v1.DispatchCheckResponse{Metadata: &v1.ResponseMeta{DispatchCount: 1}}, err
+	}
+
+	return &v1.DispatchCheckResponse{
+		Metadata: &v1.ResponseMeta{DispatchCount: 3, DepthRequired: 2},
+		ResultsByResourceId: map[string]*v1.ResourceCheckResult{
+			req.ResourceIds[0]: {Membership: v1.ResourceCheckResult_MEMBER},
+		},
+	}, nil
+}
+
+func (bd *blockingDispatcher) DispatchLookupSubjects(_ *v1.DispatchLookupSubjectsRequest, stream dispatch.LookupSubjectsStream) error {
+	if err := bd.wait(); err != nil {
+		return err
+	}
+
+	for _, subjectID := range []string{"tom", "sarah"} {
+		err := stream.Publish(&v1.DispatchLookupSubjectsResponse{
+			FoundSubjectsByResourceId: map[string]*v1.FoundSubjects{
+				"doc1": {FoundSubjects: []*v1.FoundSubject{{SubjectId: subjectID}}},
+			},
+			Metadata: &v1.ResponseMeta{DispatchCount: 2, DepthRequired: 1},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkRequest(depthRemaining uint32) *v1.DispatchCheckRequest {
+	return &v1.DispatchCheckRequest{
+		ResourceRelation: &core.RelationReference{Namespace: "document", Relation: "view"},
+		ResourceIds:      []string{"doc1"},
+		Subject:          tuple.ParseSubjectONR("user:tom"),
+		ResultsSetting:   v1.DispatchCheckRequest_ALLOW_SINGLE_RESULT,
+		Metadata:         &v1.ResolverMeta{AtRevision: "1234", DepthRemaining: depthRemaining},
+	}
+}
+
+// runConcurrently runs the function the given number of times concurrently, releasing the
+// dispatcher once the first dispatch has started and the others have had time to join it.
+func runConcurrently(bd *blockingDispatcher, count int, fn func()) {
+	var wg sync.WaitGroup
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn()
+		}()
+	}
+
+	<-bd.started
+	time.Sleep(100 * time.Millisecond)
+	close(bd.release)
+	wg.Wait()
+}
+
+func TestConcurrentChecksCoalesced(t *testing.T) {
+	bd := newBlockingDispatcher(false)
+	sf := New(bd, &keys.DirectKeyHandler{})
+
+	var (
+		mu        sync.Mutex
+		responses []*v1.DispatchCheckResponse
+	)
+	runConcurrently(bd, concurrentRequests, func() {
+		resp, err := sf.DispatchCheck(context.Background(), checkRequest(50))
+		require.NoError(t, err)
+
+		mu.Lock()
+		defer mu.Unlock()
+		responses = append(responses, resp)
+	})
+
+	require.Equal(t, int32(1), bd.dispatched.Load())
+	require.Len(t, responses, concurrentRequests)
+
+	var dispatchCount, cachedDispatchCount uint32
+	for _, resp := range responses {
+		require.Equal(t, v1.ResourceCheckResult_MEMBER, resp.ResultsByResourceId["doc1"].Membership)
+		dispatchCount += resp.Metadata.DispatchCount
+		cachedDispatchCount += resp.Metadata.CachedDispatchCount
+	}
+
+	// Only the request which computed the result reports its dispatches; the others report
+	// them as cached.
+	require.Equal(t, uint32(3), dispatchCount)
+	require.Equal(t, uint32(3*(concurrentRequests-1)), cachedDispatchCount)
+
+	// Once complete, identical requests are dispatched again.
+	_, err := sf.DispatchCheck(context.Background(), checkRequest(50))
+	require.NoError(t, err)
+	require.Equal(t, int32(2), bd.dispatched.Load())
+}
+
+func TestChecksWithDifferentDepthNotCoalesced(t *testing.T) {
+	bd := newBlockingDispatcher(false)
+	sf := New(bd, &keys.DirectKeyHandler{})
+
+	var depth atomic.Uint32
+	depth.Store(10)
+	runConcurrently(bd, 2, func() {
+		_, err := sf.DispatchCheck(context.Background(), checkRequest(depth.Add(1)))
+		require.NoError(t, err)
+	})
+
+	require.Equal(t, int32(2), bd.dispatched.Load())
+}
+
+func TestCheckErrorRecomputed(t *testing.T) {
+	bd := newBlockingDispatcher(true)
+	sf := New(bd, &keys.DirectKeyHandler{})
+
+	var failed atomic.Int32
+	runConcurrently(bd, concurrentRequests, func() {
+		resp, err := sf.DispatchCheck(context.Background(), checkRequest(50))
+		if err != nil {
+			failed.Add(1)
+			return
+		}
+		require.Equal(t, v1.ResourceCheckResult_MEMBER, resp.ResultsByResourceId["doc1"].Membership)
+	})
+
+	// Only the request which computed the error receives it; the others dispatch themselves.
+	require.Equal(t, int32(1), failed.Load())
+	require.Equal(t, int32(concurrentRequests), bd.dispatched.Load())
+}
+
+func TestCanceledCheckStopsWaiting(t *testing.T) {
+	bd := newBlockingDispatcher(false)
+	sf := New(bd, &keys.DirectKeyHandler{})
+
+	computed := make(chan error)
+	go func() {
+		_, err := sf.DispatchCheck(context.Background(), checkRequest(50))
+		computed <- err
+	}()
+	<-bd.started
+
+	// A waiter whose context is canceled returns without waiting for the computation.
+	ctx, cancel := context.WithCancel(context.Background())
+	waited := make(chan error)
+	go func() {
+		_, err := sf.DispatchCheck(ctx, checkRequest(50))
+		waited <- err
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-waited:
+		require.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		require.Fail(t, "canceled check did not return")
+	}
+
+	// The computation is unaffected.
+	close(bd.release)
+	require.NoError(t, <-computed)
+	require.Equal(t, int32(1), bd.dispatched.Load())
+}
+
+func TestConcurrentLookupSubjectsCoalesced(t *testing.T) {
+	bd := newBlockingDispatcher(false)
+	sf := New(bd, &keys.DirectKeyHandler{})
+
+	req := &v1.DispatchLookupSubjectsRequest{
+		ResourceRelation: &core.RelationReference{Namespace: "document", Relation: "view"},
+		ResourceIds:      []string{"doc1"},
+		SubjectRelation:  &core.RelationReference{Namespace: "user", Relation: tuple.Ellipsis},
+		Metadata:         &v1.ResolverMeta{AtRevision: "1234", DepthRemaining: 50},
+	}
+
+	var (
+		mu      sync.Mutex
+		streams []*dispatch.CollectingDispatchStream[*v1.DispatchLookupSubjectsResponse]
+	)
+	runConcurrently(bd, concurrentRequests, func() {
+		stream := dispatch.NewCollectingDispatchStream[*v1.DispatchLookupSubjectsResponse](context.Background())
+		require.NoError(t, sf.DispatchLookupSubjects(req, stream))
+
+		mu.Lock()
+		defer mu.Unlock()
+		streams = append(streams, stream)
+	})
+
+	require.Equal(t, int32(1), bd.dispatched.Load())
+	require.Len(t, streams, concurrentRequests)
+
+	var dispatchCount, cachedDispatchCount uint32
+	for _, stream := range streams {
+		results := stream.Results()
+		require.Len(t, results, 2)
+		require.Equal(t, "tom", results[0].FoundSubjectsByResourceId["doc1"].FoundSubjects[0].SubjectId)
+		require.Equal(t, "sarah", results[1].FoundSubjectsByResourceId["doc1"].FoundSubjects[0].SubjectId)
+
+		for _, result := range results {
+			dispatchCount += result.Metadata.DispatchCount
+			cachedDispatchCount += result.Metadata.CachedDispatchCount
+		}
+	}
+
+	require.Equal(t, uint32(4), dispatchCount)
+	require.Equal(t, uint32(4*(concurrentRequests-1)), cachedDispatchCount)
+}
+
+func TestCanceledLookupSubjectsStopsWaiting(t *testing.T) {
+	bd := newBlockingDispatcher(false)
+	sf := New(bd, &keys.DirectKeyHandler{})
+
+	req := &v1.DispatchLookupSubjectsRequest{
+		ResourceRelation: &core.RelationReference{Namespace: "document", Relation: "view"},
+		ResourceIds:      []string{"doc1"},
+		SubjectRelation:  &core.RelationReference{Namespace: "user", Relation: tuple.Ellipsis},
+		Metadata:         &v1.ResolverMeta{AtRevision: "1234", DepthRemaining: 50},
+	}
+
+	// The computing request is abandoned once started, and its stream is not published to.
+	ctx, cancel := context.WithCancel(context.Background())
+	abandonedStream := dispatch.NewCollectingDispatchStream[*v1.DispatchLookupSubjectsResponse](ctx)
+	abandoned := make(chan error)
+	go func() {
+		abandoned <- sf.DispatchLookupSubjects(req, abandonedStream)
+	}()
+	<-bd.started
+
+	waitingStream := dispatch.NewCollectingDispatchStream[*v1.DispatchLookupSubjectsResponse](context.Background())
+	waited := make(chan error)
+	go func() {
+		waited <- sf.DispatchLookupSubjects(req, waitingStream)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-abandoned:
+		require.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		require.Fail(t, "canceled lookup subjects did not return")
+	}
+
+	close(bd.release)
+	require.NoError(t, <-waited)
+	require.Len(t, waitingStream.Results(), 2)
+	require.Empty(t, abandonedStream.Results())
+}
+
+// streamingDispatcher publishes a first result, then blocks until released before publishing a
+// second, recording the streams it was given.
+type streamingDispatcher struct {
+	dispatch.Dispatcher
+
+	published chan struct{}
+	release   chan struct{}
+
+	mu      sync.Mutex
+	streams []dispatch.LookupSubjectsStream
+}
+
+func (sd *streamingDispatcher) DispatchLookupSubjects(_ *v1.DispatchLookupSubjectsRequest, stream dispatch.LookupSubjectsStream) error {
+	sd.mu.Lock()
+	sd.streams = append(sd.streams, stream)
+	first := len(sd.streams) == 1
+	sd.mu.Unlock()
+
+	for _, subjectID := range []string{"tom", "sarah"} {
+		err := stream.Publish(&v1.DispatchLookupSubjectsResponse{
+			FoundSubjectsByResourceId: map[string]*v1.FoundSubjects{
+				"doc1": {FoundSubjects: []*v1.FoundSubject{{SubjectId: subjectID}}},
+			},
+			Metadata: &v1.ResponseMeta{DispatchCount: 1},
+		})
+		if err != nil {
+			return err
+		}
+
+		if first && subjectID == "tom" {
+			close(sd.published)
+			<-sd.release
+		}
+	}
+	return nil
+}
+
+func TestLookupSubjectsNotCollectedWithoutJoiners(t *testing.T) {
+	sd := &streamingDispatcher{published: make(chan struct{}), release: make(chan struct{})}
+	sf := New(sd, &keys.DirectKeyHandler{})
+
+	req := &v1.DispatchLookupSubjectsRequest{
+		ResourceRelation: &core.RelationReference{Namespace: "document", Relation: "view"},
+		ResourceIds:      []string{"doc1"},
+		SubjectRelation:  &core.RelationReference{Namespace: "user", Relation: tuple.Ellipsis},
+		Metadata:         &v1.ResolverMeta{AtRevision: "1234", DepthRemaining: 50},
+	}
+
+	firstStream := dispatch.NewCollectingDispatchStream[*v1.DispatchLookupSubjectsResponse](context.Background())
+	first := make(chan error)
+	go func() {
+		first <- sf.DispatchLookupSubjects(req, firstStream)
+	}()
+	<-sd.published
+
+	// A request arriving once results have been published cannot be given the earlier results,
+	// so it is computed itself rather than joining the call.
+	secondStream := dispatch.NewCollectingDispatchStream[*v1.DispatchLookupSubjectsResponse](context.Background())
+	require.NoError(t, sf.DispatchLookupSubjects(req, secondStream))
+
+	close(sd.release)
+	require.NoError(t, <-first)
+
+	require.Len(t, sd.streams, 2)
+	require.Len(t, firstStream.Results(), 2)
+	require.Len(t, secondStream.Results(), 2)
+
+	// Without any joined requests, no results are collected.
+	for _, stream := range sd.streams {
+		require.Empty(t, stream.(*lookupSubjectsCall).collected)
+	}
+}
+
+func TestKeyStringWithoutMetadata(t *testing.T) {
+	key := keys.DispatchCacheKey{}
+	require.NotPanics(t, func() {
+		require.Equal(t, keyString(key, &v1.ResolverMeta{}), keyString(key, nil))
+	})
+}
@@ -4,12 +4,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/exp/maps"
 
 	"github.com/authzed/spicedb/internal/dispatch"
 	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/internal/materialize"
 	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
 	"github.com/authzed/spicedb/internal/namespace"
 	"github.com/authzed/spicedb/pkg/datastore"
@@ -41,7 +44,7 @@ func init() {
 
 // NewConcurrentChecker creates an instance of ConcurrentChecker.
 func NewConcurrentChecker(d dispatch.Check, concurrencyLimit uint16) *ConcurrentChecker {
-	return &ConcurrentChecker{d, concurrencyLimit}
+	return &ConcurrentChecker{d, concurrencyLimit, newCheckPlanner(), nil}
 }
 
 // ConcurrentChecker exposes a method to perform Check requests, and delegates subproblems to the
@@ -49,6 +52,14 @@ func NewConcurrentChecker(d dispatch.Check, concurrencyLimit uint16) *Concurrent
 type ConcurrentChecker struct {
 	d                dispatch.Check
 	concurrencyLimit uint16
+	planner          *checkPlanner
+	materialized     *materialize.Index
+}
+
+// UseMaterializedIndex sets the index from which checks of materialized permissions are answered,
+// at those revisions to which it has caught up. Must be called before any checks are performed.
+func (cc *ConcurrentChecker) UseMaterializedIndex(index *materialize.Index) {
+	cc.materialized = index
 }
 
 // ValidatedCheckRequest represents a request after it has been validated and parsed for internal
@@ -159,6 +170,10 @@ func (cc *ConcurrentChecker) checkInternal(ctx context.Context, req ValidatedChe
 		return noMembers()
 	}
 
+	if result, ok := cc.checkMaterialized(ctx, req, relation, filteredResourcesIds); ok {
+		return combineResultWithFoundResources(result, membershipSet)
+	}
+
 	// NOTE: We can always allow a single result if we're only trying to find the results for a
 	// single resource ID. This "reset" allows for short circuiting of downstream dispatched calls.
 	resultsSetting := req.ResultsSetting
@@ -184,6 +199,31 @@ func (cc *ConcurrentChecker) checkInternal(ctx context.Context, req ValidatedChe
 	return combineResultWithFoundResources(cc.checkUsersetRewrite(ctx, crc, relation.UsersetRewrite), membershipSet)
 }
 
+// checkMaterialized answers the check from the materialized index, if the relation is a
+// materialized permission and the index has caught up to the revision of the check. Checks with
+// contextual relationships or debugging are always computed.
+func (cc *ConcurrentChecker) checkMaterialized(ctx context.Context, req ValidatedCheckRequest, relation *core.Relation, resourceIDs []string) (CheckResult, bool) {
+	if cc.materialized == nil || !nspkg.IsMaterialized(relation) || req.Debug != v1.DispatchCheckRequest_NO_DEBUG || len(req.Metadata.ContextualRelationships) > 0 {
+		return CheckResult{}, false
+	}
+
+	reader := datastoremw.MustFromContext(ctx).SnapshotReader(req.Revision)
+	members, ok, err := cc.materialized.CheckMembers(ctx, reader, req.Revision, req.ResourceRelation, resourceIDs, req.Subject)
+	if err != nil {
+		return checkResultError(err, emptyMetadata), true
+	}
+
+	if !ok {
+		return CheckResult{}, false
+	}
+
+	membershipSet := NewMembershipSet()
+	for _, resourceID := range members {
+		membershipSet.AddDirectMember(resourceID, nil)
+	}
+	return checkResultsForMembership(membershipSet, emptyMetadata), true
+}
+
 func onrEqual(lhs, rhs *core.ObjectAndRelation) bool {
 	// Properties are sorted by highest to lowest cardinality to optimize for short-circuiting.
 	return lhs.ObjectId == rhs.ObjectId && lhs.Relation == rhs.Relation && lhs.Namespace == rhs.Namespace
@@ -423,18 +463,109 @@ func mapFoundResources(result CheckResult, resourceType *core.RelationReference,
 }
 
 func (cc *ConcurrentChecker) checkUsersetRewrite(ctx context.Context, crc currentRequestContext, rewrite *core.UsersetRewrite) CheckResult {
+	rr := crc.parentReq.ResourceRelation
 	switch rw := rewrite.RewriteOperation.(type) {
 	case *core.UsersetRewrite_Union:
-		return union(ctx, crc, rw.Union.Child, cc.runSetOperation, cc.concurrencyLimit)
+		return cc.checkUnion(ctx, crc, cc.planner.plan(ctx, rr, rw.Union.Child))
 	case *core.UsersetRewrite_Intersection:
-		return all(ctx, crc, rw.Intersection.Child, cc.runSetOperation, cc.concurrencyLimit)
+		return cc.checkIntersection(ctx, crc, cc.planner.plan(ctx, rr, rw.Intersection.Child))
 	case *core.UsersetRewrite_Exclusion:
-		return difference(ctx, crc, rw.Exclusion.Child, cc.runSetOperation, cc.concurrencyLimit)
+		if len(rw.Exclusion.Child) == 0 {
+			return noMembers()
+		}
+
+		// The base branch must remain first, but those subtracted from it are ordered by cost.
+		branches := cc.planner.plan(ctx, rr, rw.Exclusion.Child[0:1])
+		branches = append(branches, cc.planner.plan(ctx, rr, rw.Exclusion.Child[1:])...)
+		return difference(ctx, crc, branches, cc.runPlannedBranch, cc.concurrencyLimit)
 	default:
 		return checkResultError(fmt.Errorf("unknown userset rewrite operator"), emptyMetadata)
 	}
 }
 
+// checkUnion checks the branches of a union, ordered by cost. If a single result suffices,
+// the branches estimated to be no more costly than reading a single relation are checked before
+// any others are dispatched, as they are likely to find a result most cheaply.
+func (cc *ConcurrentChecker) checkUnion(ctx context.Context, crc currentRequestContext, branches []plannedBranch) CheckResult {
+	cheapCount := 0
+	for cheapCount < len(branches) && branches[cheapCount].cost <= directBranchCost {
+		cheapCount++
+	}
+
+	if crc.resultsSetting != v1.DispatchCheckRequest_ALLOW_SINGLE_RESULT ||
+		crc.parentReq.Debug != v1.DispatchCheckRequest_NO_DEBUG ||
+		cheapCount == 0 || cheapCount == len(branches) {
+		return union(ctx, crc, branches, cc.runPlannedBranch, cc.concurrencyLimit)
+	}
+
+	cheapResult := union(ctx, crc, branches[:cheapCount], cc.runPlannedBranch, cc.concurrencyLimit)
+	if cheapResult.Err != nil {
+		return cheapResult
+	}
+
+	membershipSet := NewMembershipSet()
+	membershipSet.UnionWith(cheapResult.Resp.ResultsByResourceId)
+	if membershipSet.HasDeterminedMember() {
+		return cheapResult
+	}
+
+	remainingResult := union(ctx, crc, branches[cheapCount:], cc.runPlannedBranch, cc.concurrencyLimit)
+	responseMetadata := combineResponseMetadata(cheapResult.Resp.Metadata, remainingResult.Resp.Metadata)
+	if remainingResult.Err != nil {
+		return checkResultError(remainingResult.Err, responseMetadata)
+	}
+
+	membershipSet.UnionWith(remainingResult.Resp.ResultsByResourceId)
+	return checkResultsForMembership(membershipSet, responseMetadata)
+}
+
+// checkIntersection checks the branches of an intersection. If the most selective branch is
+// expected to find few of the resources, it is checked first, and the others are then only
+// dispatched for those resources it found, if any.
+func (cc *ConcurrentChecker) checkIntersection(ctx context.Context, crc currentRequestContext, branches []plannedBranch) CheckResult {
+	mostSelectiveFirst(branches)
+	if crc.parentReq.Debug != v1.DispatchCheckRequest_NO_DEBUG ||
+		len(branches) < 2 || branches[0].selectivity >= selectiveBranchThreshold {
+		return all(ctx, crc, branches, cc.runPlannedBranch, cc.concurrencyLimit)
+	}
+
+	firstCrc := crc
+	firstCrc.resultsSetting = v1.DispatchCheckRequest_REQUIRE_ALL_RESULTS
+
+	firstResult := cc.runPlannedBranch(ctx, firstCrc, branches[0])
+	if firstResult.Err != nil || len(firstResult.Resp.ResultsByResourceId) == 0 {
+		return firstResult
+	}
+
+	foundResourceIDs := maps.Keys(firstResult.Resp.ResultsByResourceId)
+	sort.Strings(foundResourceIDs)
+
+	remainingCrc := crc
+	remainingCrc.filteredResourceIDs = foundResourceIDs
+
+	remainingResult := all(ctx, remainingCrc, branches[1:], cc.runPlannedBranch, cc.concurrencyLimit)
+	responseMetadata := combineResponseMetadata(firstResult.Resp.Metadata, remainingResult.Resp.Metadata)
+	if remainingResult.Err != nil {
+		return checkResultError(remainingResult.Err, responseMetadata)
+	}
+
+	membershipSet := NewMembershipSet()
+	membershipSet.UnionWith(firstResult.Resp.ResultsByResourceId)
+	membershipSet.IntersectWith(remainingResult.Resp.ResultsByResourceId)
+	if membershipSet.IsEmpty() {
+		return noMembersWithMetadata(responseMetadata)
+	}
+
+	return checkResultsForMembership(membershipSet, responseMetadata)
+}
+
+// runPlannedBranch checks a single planned branch, recording the result with the planner.
+func (cc *ConcurrentChecker) runPlannedBranch(ctx context.Context, crc currentRequestContext, branch plannedBranch) CheckResult {
+	result := cc.runSetOperation(ctx, crc, branch.child)
+	cc.planner.observe(branch, len(crc.filteredResourceIDs), result)
+	return result
+}
+
 func (cc *ConcurrentChecker) dispatch(ctx context.Context, _ currentRequestContext, req ValidatedCheckRequest) CheckResult {
 	log.Ctx(ctx).Trace().Object("dispatch", req).Send()
 	result, err := cc.d.DispatchCheck(ctx, req.DispatchCheckRequest)
@@ -0,0 +1,285 @@
+package graph
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/authzed/spicedb/internal/logging"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+const (
+	// observationWeight is the weight given to each new observation of a branch in the moving
+	// averages of its cost and selectivity.
+	observationWeight = 0.2
+
+	// defaultSelectivity is the estimated fraction of the checked resources found by a branch
+	// which has not yet been observed.
+	defaultSelectivity = 0.5
+
+	// selectiveBranchThreshold is the selectivity below which the first branch of an intersection
+	// is evaluated before any other, so that the others need not be dispatched if it finds nothing.
+	selectiveBranchThreshold = 0.25
+
+	// directBranchCost is the estimated cost, in dispatches, of a branch reading a single relation.
+	directBranchCost = 1.0
+
+	// statisticsRefreshInterval is the interval at which the datastore statistics are reloaded.
+	statisticsRefreshInterval = 5 * time.Minute
+
+	// statisticsRetryInterval is the interval after which loading the datastore statistics is
+	// retried, once failed.
+	statisticsRetryInterval = 10 * time.Second
+
+	// statisticsLoadTimeout bounds the time taken to load the datastore statistics.
+	statisticsLoadTimeout = 10 * time.Second
+
+	// maxObservedBranches is the number of branches for which observations are held before they
+	// are discarded, bounding the memory held as schemas change.
+	maxObservedBranches = 10_000
+)
+
+// checkPlanner orders the branches of the set operations evaluated by check, using the cost
+// and selectivity observed for earlier checks of each branch, and otherwise an estimate based on
+// the kind of branch. Arrows are estimated from those observed over the same tupleset, or else
+// from the number of relationships in the datastore.
+type checkPlanner struct {
+	lock     sync.RWMutex
+	observed map[string]branchObservation
+
+	// observedArrowCosts holds the moving average of the cost observed for the arrows over each
+	// tupleset relation, in the form `namespace#relation`.
+	observedArrowCosts map[string]float64
+
+	estimatedRelationshipCount atomic.Uint64
+	statisticsLoadedAt         atomic.Int64
+	statisticsLoading          atomic.Bool
+}
+
+// branchObservation holds the moving averages observed for a branch.
+type branchObservation struct {
+	// cost is the number of dispatches made to compute the branch.
+	cost float64
+
+	// selectivity is the fraction of the checked resources found by the branch.
+	selectivity float64
+}
+
+// plannedBranch is a single child of a set operation, along with its estimates.
+type plannedBranch struct {
+	child       *core.SetOperation_Child
+	key         string
+	cost        float64
+	selectivity float64
+
+	// tuplesetKey is the key of the tupleset relation of the branch, if an arrow.
+	tuplesetKey string
+}
+
+func newCheckPlanner() *checkPlanner {
+	return &checkPlanner{
+		observed:           make(map[string]branchObservation),
+		observedArrowCosts: make(map[string]float64),
+	}
+}
+
+// plan returns the children of a set operation under the resource relation, ordered from the
+// least to the most costly.
+func (cp *checkPlanner) plan(ctx context.Context, rr *core.RelationReference, children []*core.SetOperation_Child) []plannedBranch {
+	cp.refreshStatistics(ctx)
+
+	branches := make([]plannedBranch, 0, len(children))
+	for _, child := range children {
+		key := branchKey(rr, child)
+		cost, selectivity := cp.estimate(key, rr, child)
+		branches = append(branches, plannedBranch{child, key, cost, selectivity, tuplesetKey(rr, child)})
+	}
+
+	sort.SliceStable(branches, func(i, j int) bool {
+		return branches[i].cost < branches[j].cost
+	})
+	return branches
+}
+
+// mostSelectiveFirst reorders the branches from the most to the least selective, preferring
+// the least costly between those equally selective.
+func mostSelectiveFirst(branches []plannedBranch) {
+	sort.SliceStable(branches, func(i, j int) bool {
+		if branches[i].selectivity != branches[j].selectivity {
+			return branches[i].selectivity < branches[j].selectivity
+		}
+		return branches[i].cost < branches[j].cost
+	})
+}
+
+// observe records the result of checking the given number of resources against the branch.
+// Failed checks are not recorded, as they may have been canceled before completing.
+func (cp *checkPlanner) observe(branch plannedBranch, checkedCount int, result CheckResult) {
+	if result.Err != nil || checkedCount == 0 {
+		return
+	}
+
+	cost := float64(result.Resp.Metadata.DispatchCount)
+	selectivity := math.Min(float64(len(result.Resp.ResultsByResourceId))/float64(checkedCount), 1)
+
+	cp.lock.Lock()
+	defer cp.lock.Unlock()
+
+	if branch.tuplesetKey != "" {
+		if existing, ok := cp.observedArrowCosts[branch.tuplesetKey]; ok {
+			cp.observedArrowCosts[branch.tuplesetKey] = existing + observationWeight*(cost-existing)
+		} else {
+			if len(cp.observedArrowCosts) >= maxObservedBranches {
+				cp.observedArrowCosts = make(map[string]float64)
+			}
+			cp.observedArrowCosts[branch.tuplesetKey] = cost
+		}
+	}
+
+	existing, ok := cp.observed[branch.key]
+	if !ok {
+		if len(cp.observed) >= maxObservedBranches {
+			cp.observed = make(map[string]branchObservation)
+		}
+
+		cp.observed[branch.key] = branchObservation{cost, selectivity}
+		return
+	}
+
+	cp.observed[branch.key] = branchObservation{
+		cost:        existing.cost + observationWeight*(cost-existing.cost),
+		selectivity: existing.selectivity + observationWeight*(selectivity-existing.selectivity),
+	}
+}
+
+// estimate returns the estimated cost and selectivity of the branch, preferring those observed.
+func (cp *checkPlanner) estimate(key string, rr *core.RelationReference, child *core.SetOperation_Child) (float64, float64) {
+	cp.lock.RLock()
+	observed, ok := cp.observed[key]
+	arrowCost, arrowObserved := cp.observedArrowCosts[tuplesetKey(rr, child)]
+	cp.lock.RUnlock()
+	if ok {
+		return observed.cost, observed.selectivity
+	}
+
+	switch child := child.ChildType.(type) {
+	case *core.SetOperation_Child_ComputedUserset:
+		return directBranchCost, defaultSelectivity
+
+	case *core.SetOperation_Child_TupleToUserset:
+		// An arrow reads the tupleset before dispatching over each of the subjects found, so
+		// arrows over the same tupleset are expected to fan out alike.
+		if arrowObserved {
+			return arrowCost, defaultSelectivity
+		}
+
+		// Otherwise, the number of subjects is assumed to grow with the size of the datastore.
+		fanout := math.Log10(1 + float64(cp.estimatedRelationshipCount.Load()))
+		return 2*directBranchCost + fanout, defaultSelectivity
+
+	case *core.SetOperation_Child_UsersetRewrite:
+		var cost float64
+		for _, nested := range rewriteChildren(child.UsersetRewrite) {
+			nestedCost, _ := cp.estimate(branchKey(rr, nested), rr, nested)
+			cost += nestedCost
+		}
+		return cost, defaultSelectivity
+
+	default:
+		return 0, defaultSelectivity
+	}
+}
+
+// refreshStatistics starts reloading the estimated number of relationships from the datastore
+// in the background, if not loaded within the refresh interval and not already loading. Failures
+// retain the previous estimate, and are retried after the retry interval.
+func (cp *checkPlanner) refreshStatistics(ctx context.Context) {
+	if time.Now().UnixNano()-cp.statisticsLoadedAt.Load() < int64(statisticsRefreshInterval) {
+		return
+	}
+
+	ds := datastoremw.FromContext(ctx)
+	if ds == nil || !cp.statisticsLoading.CompareAndSwap(false, true) {
+		return
+	}
+
+	// The statistics are loaded independently of the request, which may complete first.
+	logger := log.Ctx(ctx)
+	go func() {
+		defer cp.statisticsLoading.Store(false)
+
+		loadCtx, cancel := context.WithTimeout(context.Background(), statisticsLoadTimeout)
+		defer cancel()
+
+		stats, err := ds.Statistics(loadCtx)
+		if err != nil {
+			logger.Debug().Err(err).Msg("could not load datastore statistics for check planning")
+			cp.statisticsLoadedAt.Store(time.Now().Add(statisticsRetryInterval - statisticsRefreshInterval).UnixNano())
+			return
+		}
+
+		cp.estimatedRelationshipCount.Store(stats.EstimatedRelationshipCount)
+		cp.statisticsLoadedAt.Store(time.Now().UnixNano())
+	}()
+}
+
+// tuplesetKey returns the key under which the costs observed for arrows over the tupleset of the
+// branch are held, or empty if the branch is not an arrow.
+func tuplesetKey(rr *core.RelationReference, child *core.SetOperation_Child) string {
+	ttu := child.GetTupleToUserset()
+	if ttu == nil {
+		return ""
+	}
+	return rr.Namespace + "#" + ttu.Tupleset.Relation
+}
+
+// branchKey returns the key under which the observations of the branch are held.
+func branchKey(rr *core.RelationReference, child *core.SetOperation_Child) string {
+	switch child := child.ChildType.(type) {
+	case *core.SetOperation_Child_ComputedUserset:
+		return rr.Namespace + "#" + child.ComputedUserset.Relation
+
+	case *core.SetOperation_Child_TupleToUserset:
+		return rr.Namespace + "#" + child.TupleToUserset.Tupleset.Relation + "->" + child.TupleToUserset.ComputedUserset.Relation
+
+	case *core.SetOperation_Child_UsersetRewrite:
+		var op string
+		switch child.UsersetRewrite.RewriteOperation.(type) {
+		case *core.UsersetRewrite_Union:
+			op = "+"
+		case *core.UsersetRewrite_Intersection:
+			op = "&"
+		case *core.UsersetRewrite_Exclusion:
+			op = "-"
+		}
+
+		nested := rewriteChildren(child.UsersetRewrite)
+		keys := make([]string, 0, len(nested))
+		for _, nestedChild := range nested {
+			keys = append(keys, branchKey(rr, nestedChild))
+		}
+		return "(" + strings.Join(keys, " "+op+" ") + ")"
+
+	default:
+		return rr.Namespace + "#nil"
+	}
+}
+
+func rewriteChildren(rewrite *core.UsersetRewrite) []*core.SetOperation_Child {
+	switch rw := rewrite.RewriteOperation.(type) {
+	case *core.UsersetRewrite_Union:
+		return rw.Union.Child
+	case *core.UsersetRewrite_Intersection:
+		return rw.Intersection.Child
+	case *core.UsersetRewrite_Exclusion:
+		return rw.Exclusion.Child
+	default:
+		return nil
+	}
+}
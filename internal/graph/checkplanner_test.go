@@ -0,0 +1,246 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/pkg/datastore"
+	nspkg "github.com/authzed/spicedb/pkg/namespace"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// relationDispatcher returns the configured members for checks of each relation, counting the
+// checks dispatched for each.
+type relationDispatcher struct {
+	lock              sync.Mutex
+	membersByRelation map[string][]string
+	dispatched        map[string]int
+}
+
+func (rd *relationDispatcher) DispatchCheck(_ context.Context, req *v1.DispatchCheckRequest) (*v1.DispatchCheckResponse, error) {
+	rd.lock.Lock()
+	defer rd.lock.Unlock()
+
+	rd.dispatched[req.ResourceRelation.Relation]++
+
+	results := make(map[string]*v1.ResourceCheckResult)
+	for _, resourceID := range req.ResourceIds {
+		for _, member := range rd.membersByRelation[req.ResourceRelation.Relation] {
+			if member == resourceID {
+				results[resourceID] = &v1.ResourceCheckResult{Membership: v1.ResourceCheckResult_MEMBER}
+			}
+		}
+	}
+
+	return &v1.DispatchCheckResponse{
+		Metadata:            &v1.ResponseMeta{DispatchCount: 1},
+		ResultsByResourceId: results,
+	}, nil
+}
+
+func (rd *relationDispatcher) dispatchedCount(relation string) int {
+	rd.lock.Lock()
+	defer rd.lock.Unlock()
+	return rd.dispatched[relation]
+}
+
+func checkRequest(resourceIDs ...string) ValidatedCheckRequest {
+	return ValidatedCheckRequest{
+		&v1.DispatchCheckRequest{
+			ResourceRelation: &core.RelationReference{Namespace: "document", Relation: "view"},
+			ResourceIds:      resourceIDs,
+			Subject:          tuple.ParseSubjectONR("user:tom"),
+			ResultsSetting:   v1.DispatchCheckRequest_REQUIRE_ALL_RESULTS,
+			Metadata:         &v1.ResolverMeta{DepthRemaining: 50},
+		},
+		nil,
+	}
+}
+
+func TestCheckPlannerOrdering(t *testing.T) {
+	planner := newCheckPlanner()
+	rr := &core.RelationReference{Namespace: "document", Relation: "view"}
+
+	arrow := nspkg.TupleToUserset("parent", "view")
+	nested := nspkg.Rewrite(nspkg.Intersection(nspkg.ComputedUserset("editor"), nspkg.ComputedUserset("reader"), nspkg.ComputedUserset("owner")))
+	direct := nspkg.ComputedUserset("viewer")
+
+	branches := planner.plan(context.Background(), rr, []*core.SetOperation_Child{arrow, nested, direct})
+	require.Equal(t, []string{"document#viewer", "document#parent->view", "(document#editor & document#reader & document#owner)"}, branchKeys(branches))
+
+	// Once the direct relation has been observed to be costly, it is ordered after the others.
+	for i := 0; i < 10; i++ {
+		planner.observe(branches[0], 1, CheckResult{Resp: &v1.DispatchCheckResponse{
+			Metadata: &v1.ResponseMeta{DispatchCount: 10},
+		}})
+	}
+
+	branches = planner.plan(context.Background(), rr, []*core.SetOperation_Child{arrow, nested, direct})
+	require.Equal(t, []string{"document#parent->view", "(document#editor & document#reader & document#owner)", "document#viewer"}, branchKeys(branches))
+
+	// The observed selectivity orders the branches of intersections.
+	mostSelectiveFirst(branches)
+	require.Equal(t, "document#viewer", branches[0].key)
+	require.Less(t, branches[0].selectivity, selectiveBranchThreshold)
+}
+
+func TestCheckPlannerArrowEstimatedFromTupleset(t *testing.T) {
+	planner := newCheckPlanner()
+	rr := &core.RelationReference{Namespace: "document", Relation: "view"}
+
+	parentView := nspkg.TupleToUserset("parent", "view")
+	parentEdit := nspkg.TupleToUserset("parent", "edit")
+	ownerView := nspkg.TupleToUserset("owner", "view")
+
+	// Without observations, arrows are estimated alike.
+	branches := planner.plan(context.Background(), rr, []*core.SetOperation_Child{parentEdit, ownerView})
+	require.Equal(t, branches[0].cost, branches[1].cost)
+
+	// Arrows over a tupleset observed to be costly are estimated as such, before being observed.
+	branches = planner.plan(context.Background(), rr, []*core.SetOperation_Child{parentView})
+	planner.observe(branches[0], 1, CheckResult{Resp: &v1.DispatchCheckResponse{
+		Metadata: &v1.ResponseMeta{DispatchCount: 50},
+	}})
+
+	branches = planner.plan(context.Background(), rr, []*core.SetOperation_Child{parentEdit, ownerView})
+	require.Equal(t, []string{"document#owner->view", "document#parent->edit"}, branchKeys(branches))
+	require.Equal(t, float64(50), branches[1].cost)
+}
+
+// statisticsDatastore returns the configured statistics, or fails if none are configured.
+type statisticsDatastore struct {
+	datastore.Datastore
+
+	lock  sync.Mutex
+	stats *datastore.Stats
+}
+
+func (sd *statisticsDatastore) Statistics(_ context.Context) (datastore.Stats, error) {
+	sd.lock.Lock()
+	defer sd.lock.Unlock()
+
+	if sd.stats == nil {
+		return datastore.Stats{}, errors.New("statistics unavailable")
+	}
+	return *sd.stats, nil
+}
+
+func TestCheckPlannerStatisticsLoadedInBackground(t *testing.T) {
+	planner := newCheckPlanner()
+	rr := &core.RelationReference{Namespace: "document", Relation: "view"}
+
+	ds := &statisticsDatastore{}
+	ctx := datastoremw.ContextWithDatastore(context.Background(), ds)
+
+	// A failed load retains the estimate, and is retried once the retry interval has passed.
+	planner.plan(ctx, rr, []*core.SetOperation_Child{nspkg.ComputedUserset("viewer")})
+	require.Eventually(t, func() bool { return !planner.statisticsLoading.Load() && planner.statisticsLoadedAt.Load() != 0 }, time.Second, time.Millisecond)
+	require.Zero(t, planner.estimatedRelationshipCount.Load())
+	require.Greater(t, time.Since(time.Unix(0, planner.statisticsLoadedAt.Load())), statisticsRefreshInterval-statisticsRetryInterval)
+
+	ds.lock.Lock()
+	ds.stats = &datastore.Stats{EstimatedRelationshipCount: 1000}
+	ds.lock.Unlock()
+
+	planner.statisticsLoadedAt.Add(-int64(statisticsRetryInterval))
+	planner.plan(ctx, rr, []*core.SetOperation_Child{nspkg.ComputedUserset("viewer")})
+	require.Eventually(t, func() bool { return planner.estimatedRelationshipCount.Load() == 1000 }, time.Second, time.Millisecond)
+	require.Eventually(t, func() bool { return !planner.statisticsLoading.Load() }, time.Second, time.Millisecond)
+	require.Less(t, time.Since(time.Unix(0, planner.statisticsLoadedAt.Load())), statisticsRefreshInterval)
+}
+
+func TestCheckIntersectionMostSelectiveFirst(t *testing.T) {
+	dispatcher := &relationDispatcher{
+		membersByRelation: map[string][]string{
+			"viewer": {"first", "second", "third"},
+		},
+		dispatched: map[string]int{},
+	}
+	checker := NewConcurrentChecker(dispatcher, 10)
+
+	relation := nspkg.MustRelation("view", nspkg.Intersection(
+		nspkg.ComputedUserset("viewer"),
+		nspkg.ComputedUserset("approved"),
+	))
+
+	// Without observations, both branches are dispatched.
+	resp, err := checker.Check(context.Background(), checkRequest("first", "second"), relation)
+	require.NoError(t, err)
+	require.Empty(t, resp.ResultsByResourceId)
+	require.Equal(t, 1, dispatcher.dispatchedCount("approved"))
+	require.Equal(t, 1, dispatcher.dispatchedCount("viewer"))
+
+	// Once the approved branch is known to be selective, it is checked first, and the viewer
+	// branch need not be dispatched.
+	for i := 0; i < 10; i++ {
+		_, err := checker.Check(context.Background(), checkRequest("first", "second"), relation)
+		require.NoError(t, err)
+	}
+
+	viewerCount := dispatcher.dispatchedCount("viewer")
+	_, err = checker.Check(context.Background(), checkRequest("first", "second"), relation)
+	require.NoError(t, err)
+	require.Equal(t, viewerCount, dispatcher.dispatchedCount("viewer"))
+
+	// When the selective branch finds resources, only those are checked by the others.
+	dispatcher.membersByRelation["approved"] = []string{"second"}
+	resp, err = checker.Check(context.Background(), checkRequest("first", "second"), relation)
+	require.NoError(t, err)
+	require.Equal(t, []string{"second"}, resultIDs(resp))
+}
+
+func TestCheckUnionCheapestFirst(t *testing.T) {
+	dispatcher := &relationDispatcher{
+		membersByRelation: map[string][]string{
+			"viewer": {"first"},
+			"editor": {"first"},
+			"reader": {"first"},
+		},
+		dispatched: map[string]int{},
+	}
+	checker := NewConcurrentChecker(dispatcher, 10)
+
+	relation := nspkg.MustRelation("view", nspkg.Union(
+		nspkg.Rewrite(nspkg.Intersection(nspkg.ComputedUserset("editor"), nspkg.ComputedUserset("reader"))),
+		nspkg.ComputedUserset("viewer"),
+	))
+
+	// The direct relation finds the single result required, so the nested intersection is never dispatched.
+	resp, err := checker.Check(context.Background(), checkRequest("first"), relation)
+	require.NoError(t, err)
+	require.Equal(t, []string{"first"}, resultIDs(resp))
+	require.Equal(t, 1, dispatcher.dispatchedCount("viewer"))
+	require.Equal(t, 0, dispatcher.dispatchedCount("editor"))
+	require.Equal(t, 0, dispatcher.dispatchedCount("reader"))
+
+	// When all results are required, all branches are dispatched.
+	resp, err = checker.Check(context.Background(), checkRequest("first", "second"), relation)
+	require.NoError(t, err)
+	require.Equal(t, []string{"first"}, resultIDs(resp))
+	require.Equal(t, 1, dispatcher.dispatchedCount("editor"))
+	require.Equal(t, 1, dispatcher.dispatchedCount("reader"))
+}
+
+func branchKeys(branches []plannedBranch) []string {
+	keys := make([]string, 0, len(branches))
+	for _, branch := range branches {
+		keys = append(keys, branch.key)
+	}
+	return keys
+}
+
+func resultIDs(resp *v1.DispatchCheckResponse) []string {
+	ids := make([]string, 0, len(resp.ResultsByResourceId))
+	for resourceID := range resp.ResultsByResourceId {
+		ids = append(ids, resourceID)
+	}
+	return ids
+}
@@ -44,6 +44,10 @@ type CheckParameters struct {
 	AtRevision    datastore.Revision
 	MaximumDepth  uint32
 	DebugOption   DebugOption
+
+	// ContextualRelationships, if specified, are overlaid on the relationships in the
+	// datastore for the duration of the check.
+	ContextualRelationships []*core.RelationTuple
 }
 
 // ComputeCheck computes a check result for the given resource and subject, computing any
@@ -72,11 +76,81 @@ func ComputeBulkCheck(
 	return computeCheck(ctx, d, params, resourceIDs)
 }
 
+// CheckItem is a single resource to be checked by ComputeCheckItems, along with the caveat
+// context under which any caveats found for the resource will be evaluated.
+type CheckItem struct {
+	ResourceID    string
+	CaveatContext map[string]any
+}
+
+// CheckItemResult is the result of checking a single CheckItem. If Err is non-nil, the
+// result could not be computed for the item, and Result will be nil.
+type CheckItemResult struct {
+	Result *v1.ResourceCheckResult
+	Err    error
+}
+
+// ComputeCheckItems computes check results for the given items with a single dispatch, computing
+// any caveat expressions found against the caveat context of each item. The CaveatContext of the
+// given parameters is ignored. The returned results are in the same order as the items.
+func ComputeCheckItems(
+	ctx context.Context,
+	d dispatch.Check,
+	params CheckParameters,
+	items []CheckItem,
+) ([]CheckItemResult, *v1.ResponseMeta, error) {
+	resourceIDs := make([]string, 0, len(items))
+	encountered := make(map[string]struct{}, len(items))
+	for _, item := range items {
+		if _, ok := encountered[item.ResourceID]; ok {
+			continue
+		}
+		encountered[item.ResourceID] = struct{}{}
+		resourceIDs = append(resourceIDs, item.ResourceID)
+	}
+
+	checkResult, err := dispatchCheck(ctx, d, params, resourceIDs)
+	if err != nil {
+		return nil, checkResult.Metadata, err
+	}
+
+	results := make([]CheckItemResult, 0, len(items))
+	for _, item := range items {
+		itemParams := params
+		itemParams.CaveatContext = item.CaveatContext
+
+		computed, err := computeCaveatedCheckResult(ctx, itemParams, item.ResourceID, checkResult)
+		results = append(results, CheckItemResult{Result: computed, Err: err})
+	}
+	return results, checkResult.Metadata, nil
+}
+
 func computeCheck(ctx context.Context,
 	d dispatch.Check,
 	params CheckParameters,
 	resourceIDs []string,
 ) (map[string]*v1.ResourceCheckResult, *v1.ResponseMeta, error) {
+	checkResult, err := dispatchCheck(ctx, d, params, resourceIDs)
+	if err != nil {
+		return nil, checkResult.Metadata, err
+	}
+
+	results := make(map[string]*v1.ResourceCheckResult, len(resourceIDs))
+	for _, resourceID := range resourceIDs {
+		computed, err := computeCaveatedCheckResult(ctx, params, resourceID, checkResult)
+		if err != nil {
+			return nil, checkResult.Metadata, err
+		}
+		results[resourceID] = computed
+	}
+	return results, checkResult.Metadata, nil
+}
+
+func dispatchCheck(ctx context.Context,
+	d dispatch.Check,
+	params CheckParameters,
+	resourceIDs []string,
+) (*v1.DispatchCheckResponse, error) {
 	debugging := v1.DispatchCheckRequest_NO_DEBUG
 	if params.DebugOption == BasicDebuggingEnabled {
 		debugging = v1.DispatchCheckRequest_ENABLE_BASIC_DEBUGGING
@@ -89,30 +163,18 @@ func computeCheck(ctx context.Context,
 		setting = v1.DispatchCheckRequest_ALLOW_SINGLE_RESULT
 	}
 
-	checkResult, err := d.DispatchCheck(ctx, &v1.DispatchCheckRequest{
+	return d.DispatchCheck(ctx, &v1.DispatchCheckRequest{
 		ResourceRelation: params.ResourceType,
 		ResourceIds:      resourceIDs,
 		ResultsSetting:   setting,
 		Subject:          params.Subject,
 		Metadata: &v1.ResolverMeta{
-			AtRevision:     params.AtRevision.String(),
-			DepthRemaining: params.MaximumDepth,
+			AtRevision:              params.AtRevision.String(),
+			DepthRemaining:          params.MaximumDepth,
+			ContextualRelationships: params.ContextualRelationships,
 		},
 		Debug: debugging,
 	})
-	if err != nil {
-		return nil, checkResult.Metadata, err
-	}
-
-	results := make(map[string]*v1.ResourceCheckResult, len(resourceIDs))
-	for _, resourceID := range resourceIDs {
-		computed, err := computeCaveatedCheckResult(ctx, params, resourceID, checkResult)
-		if err != nil {
-			return nil, checkResult.Metadata, err
-		}
-		results[resourceID] = computed
-	}
-	return results, checkResult.Metadata, nil
 }
 
 func computeCaveatedCheckResult(ctx context.Context, params CheckParameters, resourceID string, checkResult *v1.DispatchCheckResponse) (*v1.ResourceCheckResult, error) {
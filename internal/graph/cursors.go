@@ -0,0 +1,144 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+)
+
+// taskScheduler is the interface shared by TaskRunner and orderedTaskRunner.
+type taskScheduler interface {
+	Schedule(f TaskFunc)
+	Wait() error
+}
+
+// orderedTaskRunner is a taskScheduler which runs each task immediately on the calling
+// goroutine, in the order scheduled. It is used when results must be produced in a stable
+// order, such as when a cursor was requested.
+type orderedTaskRunner struct {
+	ctx context.Context
+	err error
+}
+
+func newOrderedTaskRunner(ctx context.Context) *orderedTaskRunner {
+	return &orderedTaskRunner{ctx: ctx}
+}
+
+// Schedule runs the task immediately, unless a previous task has failed or the context
+// has been canceled.
+func (otr *orderedTaskRunner) Schedule(f TaskFunc) {
+	if otr.err != nil {
+		return
+	}
+
+	if err := otr.ctx.Err(); err != nil {
+		otr.err = err
+		return
+	}
+
+	// NOTE: tasks can schedule further tasks, which run before this call returns, so only
+	// record the error if a nested task has not already done so.
+	if err := f(otr.ctx); err != nil && otr.err == nil {
+		otr.err = err
+	}
+}
+
+// Wait returns the error of the first failed task, if any.
+func (otr *orderedTaskRunner) Wait() error {
+	return otr.err
+}
+
+// positionCursor tracks a position within a cursored operation. A nil *positionCursor
+// indicates that no cursor was requested, and therefore that results need not be returned
+// in a stable order.
+type positionCursor struct {
+	// prefix holds the sections identifying the position, to be prepended onto the cursor
+	// of every result found at or beneath the position.
+	prefix []string
+
+	// after holds the sections of the incoming cursor relative to this position, if the
+	// operation is resuming at this position.
+	after []string
+}
+
+func newPositionCursor(cursor *v1.Cursor) *positionCursor {
+	if cursor == nil {
+		return nil
+	}
+
+	return &positionCursor{after: cursor.Sections}
+}
+
+// withSections returns a new position beneath this one, identified by the given sections,
+// and resuming after the given sections of the incoming cursor, if any.
+func (pc *positionCursor) withSections(after []string, sections ...string) *positionCursor {
+	prefix := make([]string, 0, len(pc.prefix)+len(sections))
+	prefix = append(prefix, pc.prefix...)
+	prefix = append(prefix, sections...)
+	return &positionCursor{prefix: prefix, after: after}
+}
+
+// descend returns the cursor for the numbered child position beneath this position, and whether
+// the child position comes before the incoming cursor and should therefore be skipped entirely.
+func (pc *positionCursor) descend(position int) (*positionCursor, bool, error) {
+	if pc == nil {
+		return nil, false, nil
+	}
+
+	section := strconv.Itoa(position)
+	if len(pc.after) == 0 {
+		return pc.withSections(nil, section), false, nil
+	}
+
+	cursorPosition, err := strconv.Atoi(pc.after[0])
+	if err != nil {
+		return nil, false, NewErrInvalidArgument(fmt.Errorf("invalid cursor section `%s`", pc.after[0]))
+	}
+
+	switch {
+	case position < cursorPosition:
+		return nil, true, nil
+
+	case position == cursorPosition:
+		return pc.withSections(pc.after[1:], section), false, nil
+
+	default:
+		return pc.withSections(nil, section), false, nil
+	}
+}
+
+// cursorFor returns the cursor for a result found at this position with the given remaining
+// sections.
+func (pc *positionCursor) cursorFor(sections ...string) *v1.Cursor {
+	full := make([]string, 0, len(pc.prefix)+len(sections))
+	full = append(full, pc.prefix...)
+	full = append(full, sections...)
+	return &v1.Cursor{Sections: full}
+}
+
+// cursoredResources sorts the resources directly found at the position by ID, removes those
+// at or before the incoming cursor and sets the cursor of each remaining resource. If no cursor
+// was requested, the resources are returned unchanged.
+func cursoredResources(resources []*v1.ReachableResource, pc *positionCursor) []*v1.ReachableResource {
+	if pc == nil {
+		return resources
+	}
+
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].ResourceId < resources[j].ResourceId
+	})
+
+	filtered := make([]*v1.ReachableResource, 0, len(resources))
+	for _, resource := range resources {
+		if len(pc.after) > 0 && resource.ResourceId <= pc.after[0] {
+			continue
+		}
+
+		resource.AfterResponseCursor = pc.cursorFor(resource.ResourceId)
+		filtered = append(filtered, resource)
+	}
+	return filtered
+}
@@ -44,12 +44,6 @@ type ExpandResult struct {
 	Err  error
 }
 
-// LookupResult is the data that is returned by a single lookup or sub-lookup.
-type LookupResult struct {
-	Resp *v1.DispatchLookupResponse
-	Err  error
-}
-
 // ReduceableExpandFunc is a function that can be bound to a execution context.
 type ReduceableExpandFunc func(ctx context.Context, resultChan chan<- ExpandResult)
 
@@ -67,8 +61,9 @@ type ExpandReducer func(
 
 func decrementDepth(md *v1.ResolverMeta) *v1.ResolverMeta {
 	return &v1.ResolverMeta{
-		AtRevision:     md.AtRevision,
-		DepthRemaining: md.DepthRemaining - 1,
+		AtRevision:              md.AtRevision,
+		DepthRemaining:          md.DepthRemaining - 1,
+		ContextualRelationships: md.ContextualRelationships,
 	}
 }
 
@@ -9,14 +9,20 @@ import (
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 
 	"github.com/authzed/spicedb/internal/dispatch"
+	"github.com/authzed/spicedb/internal/graph/computed"
+	"github.com/authzed/spicedb/internal/materialize"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/namespace"
+	nspkg "github.com/authzed/spicedb/pkg/namespace"
 	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
 	"github.com/authzed/spicedb/pkg/spiceerrors"
 	"github.com/authzed/spicedb/pkg/tuple"
+	"github.com/authzed/spicedb/pkg/util"
 )
 
 // NewConcurrentLookup creates and instance of ConcurrentLookup.
 func NewConcurrentLookup(c dispatch.Check, r dispatch.ReachableResources, concurrencyLimit uint16) *ConcurrentLookup {
-	return &ConcurrentLookup{c, r, concurrencyLimit}
+	return &ConcurrentLookup{c, r, concurrencyLimit, nil}
 }
 
 // ConcurrentLookup exposes a method to perform Lookup requests, and delegates subproblems to the
@@ -25,6 +31,13 @@ type ConcurrentLookup struct {
 	c                dispatch.Check
 	r                dispatch.ReachableResources
 	concurrencyLimit uint16
+	materialized     *materialize.Index
+}
+
+// UseMaterializedIndex sets the index from which lookups of materialized permissions are answered,
+// at those revisions to which it has caught up. Must be called before any lookups are performed.
+func (cl *ConcurrentLookup) UseMaterializedIndex(index *materialize.Index) {
+	cl.materialized = index
 }
 
 // ValidatedLookupRequest represents a request after it has been validated and parsed for internal
@@ -34,7 +47,10 @@ type ValidatedLookupRequest struct {
 	Revision datastore.Revision
 }
 
-type collectingStream struct {
+// checkingStream is the stream to which reachable resources are published for lookups without
+// cursors. Resources requiring a check are queued in the parallel checker, while the others are
+// published directly.
+type checkingStream struct {
 	checker *parallelChecker
 	req     ValidatedLookupRequest
 	context context.Context
@@ -46,49 +62,61 @@ type collectingStream struct {
 	mu sync.Mutex
 }
 
-func (ls *collectingStream) Context() context.Context {
-	return ls.context
+func (cs *checkingStream) Context() context.Context {
+	return cs.context
 }
 
-func (ls *collectingStream) Publish(result *v1.DispatchReachableResourcesResponse) error {
+func (cs *checkingStream) Publish(result *v1.DispatchReachableResourcesResponse) error {
 	if result == nil {
 		return spiceerrors.MustBugf("got nil result for Lookup publish")
 	}
 
 	func() {
-		ls.mu.Lock()
-		defer ls.mu.Unlock()
+		cs.mu.Lock()
+		defer cs.mu.Unlock()
 
-		ls.dispatchCount += result.Metadata.DispatchCount
-		ls.cachedDispatchCount += result.Metadata.CachedDispatchCount
-		ls.depthRequired = max(result.Metadata.DepthRequired, ls.depthRequired)
+		cs.dispatchCount += result.Metadata.DispatchCount
+		cs.cachedDispatchCount += result.Metadata.CachedDispatchCount
+		cs.depthRequired = max(result.Metadata.DepthRequired, cs.depthRequired)
 	}()
 
 	for _, found := range result.Resources {
 		if found.ResultStatus == v1.ReachableResource_HAS_PERMISSION {
-			ls.checker.AddResolvedResource(&v1.ResolvedResource{
+			if err := cs.checker.AddResolvedResource(&v1.ResolvedResource{
 				ResourceId:     found.ResourceId,
 				Permissionship: v1.ResolvedResource_HAS_PERMISSION,
-			})
+			}); err != nil {
+				return err
+			}
 			continue
 		}
 
-		ls.checker.QueueToCheck(found.ResourceId)
+		cs.checker.QueueToCheck(found.ResourceId)
 	}
 	return nil
 }
 
-func (cl *ConcurrentLookup) LookupViaReachability(ctx context.Context, req ValidatedLookupRequest) (*v1.DispatchLookupResponse, error) {
+// LookupViaReachability performs the lookup, publishing each found resource to the stream as
+// soon as it has been confirmed. The final response published to the stream contains the
+// metadata for the lookup as a whole.
+func (cl *ConcurrentLookup) LookupViaReachability(req ValidatedLookupRequest, stream dispatch.LookupStream) error {
 	if req.Subject.ObjectId == tuple.PublicWildcard {
-		resp := lookupResultError(NewErrInvalidArgument(errors.New("cannot perform lookup on wildcard")), emptyMetadata)
-		return resp.Resp, resp.Err
+		return NewErrInvalidArgument(errors.New("cannot perform lookup on wildcard"))
 	}
 
-	cancelCtx, cancel := context.WithCancel(ctx)
+	if req.OptionalCursor != nil {
+		return cl.lookupViaReachabilityWithCursor(req, stream)
+	}
+
+	if handled, err := cl.lookupMaterialized(req, stream); handled {
+		return err
+	}
+
+	cancelCtx, cancel := context.WithCancel(stream.Context())
 	defer cancel()
 
-	checker := newParallelChecker(cancelCtx, cancel, cl.c, req, cl.concurrencyLimit)
-	stream := &collectingStream{checker, req, cancelCtx, 0, 0, 0, sync.Mutex{}}
+	checker := newParallelChecker(cancelCtx, cancel, cl.c, req, stream, cl.concurrencyLimit)
+	reachableStream := &checkingStream{checker, req, cancelCtx, 0, 0, 0, sync.Mutex{}}
 
 	// Start the checker.
 	checker.Start()
@@ -105,52 +133,239 @@ func (cl *ConcurrentLookup) LookupViaReachability(ctx context.Context, req Valid
 		},
 		SubjectIds: []string{req.Subject.ObjectId},
 		Metadata:   req.Metadata,
-	}, stream)
-	if err != nil {
-		resp := lookupResultError(err, emptyMetadata)
-		return resp.Resp, resp.Err
+	}, reachableStream)
+
+	// If the limit was reached, the remaining work was canceled, so any error is expected.
+	if err != nil && !checker.LimitReached() {
+		return err
 	}
 
 	// Wait for the checker to finish.
-	allowed, err := checker.Wait()
-	if err != nil {
-		resp := lookupResultError(err, emptyMetadata)
-		return resp.Resp, resp.Err
+	if err := checker.Wait(); err != nil {
+		return err
 	}
 
-	res := lookupResult(allowed, req, &v1.ResponseMeta{
-		DispatchCount:       stream.dispatchCount + checker.DispatchCount() + 1, // +1 for the lookup
-		CachedDispatchCount: stream.cachedDispatchCount + checker.CachedDispatchCount(),
-		DepthRequired:       max(stream.depthRequired, checker.DepthRequired()) + 1, // +1 for the lookup
+	return stream.Publish(&v1.DispatchLookupResponse{
+		Metadata: &v1.ResponseMeta{
+			DispatchCount:       reachableStream.dispatchCount + checker.DispatchCount() + 1, // +1 for the lookup
+			CachedDispatchCount: reachableStream.cachedDispatchCount + checker.CachedDispatchCount(),
+			DepthRequired:       max(reachableStream.depthRequired, checker.DepthRequired()) + 1, // +1 for the lookup
+		},
 	})
-	return res.Resp, res.Err
 }
 
-func lookupResult(foundResources []*v1.ResolvedResource, req ValidatedLookupRequest, subProblemMetadata *v1.ResponseMeta) LookupResult {
-	limitedResources := limitedSlice(foundResources, req.Limit)
+// lookupMaterialized answers the lookup from the materialized index, if the relation is a
+// materialized permission and the index has caught up to the revision of the lookup, returning
+// whether it was answered. Lookups with contextual relationships are always computed.
+func (cl *ConcurrentLookup) lookupMaterialized(req ValidatedLookupRequest, stream dispatch.LookupStream) (bool, error) {
+	if cl.materialized == nil || len(req.Metadata.ContextualRelationships) > 0 {
+		return false, nil
+	}
 
-	return LookupResult{
-		&v1.DispatchLookupResponse{
-			Metadata:          ensureMetadata(subProblemMetadata),
-			ResolvedResources: limitedResources,
-		},
-		nil,
+	ctx := stream.Context()
+	reader := datastoremw.MustFromContext(ctx).SnapshotReader(req.Revision)
+	_, relation, err := namespace.ReadNamespaceAndRelation(ctx, req.ObjectRelation.Namespace, req.ObjectRelation.Relation, reader)
+	if err != nil || !nspkg.IsMaterialized(relation) {
+		return false, nil
+	}
+
+	resourceIDs, ok, err := cl.materialized.LookupResources(ctx, reader, req.Revision, req.ObjectRelation, req.Subject, uint64(req.Limit))
+	if err != nil {
+		return true, err
+	}
+
+	if !ok {
+		return false, nil
+	}
+
+	if len(resourceIDs) > 0 {
+		resolved := make([]*v1.ResolvedResource, 0, len(resourceIDs))
+		for _, resourceID := range resourceIDs {
+			resolved = append(resolved, &v1.ResolvedResource{
+				ResourceId:     resourceID,
+				Permissionship: v1.ResolvedResource_HAS_PERMISSION,
+			})
+		}
+
+		if err := stream.Publish(&v1.DispatchLookupResponse{
+			Metadata:          emptyMetadata,
+			ResolvedResources: resolved,
+		}); err != nil {
+			return true, err
+		}
 	}
+
+	return true, stream.Publish(&v1.DispatchLookupResponse{
+		Metadata: &v1.ResponseMeta{
+			DispatchCount: 1,
+			DepthRequired: 1,
+		},
+	})
 }
 
-func limitedSlice[T any](slice []T, limit uint32) []T {
-	if len(slice) > int(limit) {
-		return slice[0:limit]
+// cursoredLookupStream is the stream used for lookups with cursors. Each published set of
+// reachable resources is checked as necessary and then published, in order, until the limit
+// is reached.
+type cursoredLookupStream struct {
+	ctx    context.Context
+	cancel func()
+	cl     *ConcurrentLookup
+	req    ValidatedLookupRequest
+	stream dispatch.LookupStream
+
+	seen *util.Set[string]
+
+	dispatchCount       uint32
+	cachedDispatchCount uint32
+	depthRequired       uint32
+}
+
+func (cls *cursoredLookupStream) Context() context.Context {
+	return cls.ctx
+}
+
+func (cls *cursoredLookupStream) limitReached() bool {
+	return cls.seen.Len() >= int(cls.req.Limit)
+}
+
+func (cls *cursoredLookupStream) updateStats(metadata *v1.ResponseMeta) {
+	cls.dispatchCount += metadata.DispatchCount
+	cls.cachedDispatchCount += metadata.CachedDispatchCount
+	cls.depthRequired = max(metadata.DepthRequired, cls.depthRequired)
+}
+
+func (cls *cursoredLookupStream) Publish(result *v1.DispatchReachableResourcesResponse) error {
+	if result == nil {
+		return spiceerrors.MustBugf("got nil result for Lookup publish")
+	}
+
+	if cls.limitReached() {
+		return nil
+	}
+
+	cls.updateStats(result.Metadata)
+
+	// Check any resources that require it, in chunks.
+	toCheck := make([]string, 0, len(result.Resources))
+	toCheckSet := util.NewSet[string]()
+	for _, found := range result.Resources {
+		if found.ResultStatus == v1.ReachableResource_REQUIRES_CHECK && !cls.seen.Has(found.ResourceId) && toCheckSet.Add(found.ResourceId) {
+			toCheck = append(toCheck, found.ResourceId)
+		}
+	}
+
+	checkResults := make(map[string]*v1.ResourceCheckResult, len(toCheck))
+	for len(toCheck) > 0 {
+		chunk := toCheck[0:min(len(toCheck), int(maxDispatchChunkSize))]
+		toCheck = toCheck[len(chunk):]
+
+		results, resultsMeta, err := computed.ComputeBulkCheck(cls.ctx, cls.cl.c,
+			computed.CheckParameters{
+				ResourceType:            cls.req.ObjectRelation,
+				Subject:                 cls.req.Subject,
+				CaveatContext:           cls.req.Context.AsMap(),
+				AtRevision:              cls.req.Revision,
+				MaximumDepth:            cls.req.Metadata.DepthRemaining,
+				DebugOption:             computed.NoDebugging,
+				ContextualRelationships: cls.req.Metadata.ContextualRelationships,
+			},
+			chunk,
+		)
+		if err != nil {
+			return err
+		}
+
+		cls.updateStats(resultsMeta)
+		for resourceID, result := range results {
+			checkResults[resourceID] = result
+		}
+	}
+
+	// Publish the found resources in the order given.
+	for _, found := range result.Resources {
+		if cls.seen.Has(found.ResourceId) {
+			continue
+		}
+
+		resolved := &v1.ResolvedResource{
+			ResourceId:          found.ResourceId,
+			Permissionship:      v1.ResolvedResource_HAS_PERMISSION,
+			AfterResponseCursor: found.AfterResponseCursor,
+		}
+
+		if found.ResultStatus == v1.ReachableResource_REQUIRES_CHECK {
+			checkResult, ok := checkResults[found.ResourceId]
+			if !ok {
+				continue
+			}
+
+			switch checkResult.Membership {
+			case v1.ResourceCheckResult_MEMBER:
+			case v1.ResourceCheckResult_CAVEATED_MEMBER:
+				resolved.Permissionship = v1.ResolvedResource_CONDITIONALLY_HAS_PERMISSION
+				resolved.MissingRequiredContext = checkResult.MissingExprFields
+			default:
+				continue
+			}
+		}
+
+		cls.seen.Add(found.ResourceId)
+		if err := cls.stream.Publish(&v1.DispatchLookupResponse{
+			Metadata:          emptyMetadata,
+			ResolvedResources: []*v1.ResolvedResource{resolved},
+		}); err != nil {
+			return err
+		}
+
+		if cls.limitReached() {
+			// Cancel any further work.
+			cls.cancel()
+			return nil
+		}
 	}
 
-	return slice
+	return nil
 }
 
-func lookupResultError(err error, subProblemMetadata *v1.ResponseMeta) LookupResult {
-	return LookupResult{
-		&v1.DispatchLookupResponse{
-			Metadata: ensureMetadata(subProblemMetadata),
+// lookupViaReachabilityWithCursor performs the lookup in a stable order, returning each found
+// resource with the cursor after which the lookup can be resumed.
+//
+// NOTE: a resource reachable via multiple paths is only returned once per call, but can be returned
+// again by a call resumed from a cursor.
+func (cl *ConcurrentLookup) lookupViaReachabilityWithCursor(req ValidatedLookupRequest, stream dispatch.LookupStream) error {
+	cancelCtx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	reachableStream := &cursoredLookupStream{
+		ctx:    cancelCtx,
+		cancel: cancel,
+		cl:     cl,
+		req:    req,
+		stream: stream,
+		seen:   util.NewSet[string](),
+	}
+
+	err := cl.r.DispatchReachableResources(&v1.DispatchReachableResourcesRequest{
+		ResourceRelation: req.ObjectRelation,
+		SubjectRelation: &core.RelationReference{
+			Namespace: req.Subject.Namespace,
+			Relation:  req.Subject.Relation,
 		},
-		err,
+		SubjectIds:     []string{req.Subject.ObjectId},
+		Metadata:       req.Metadata,
+		OptionalCursor: req.OptionalCursor,
+	}, reachableStream)
+
+	// If the limit was reached, the remaining work was canceled, so any error is expected.
+	if err != nil && !reachableStream.limitReached() {
+		return err
 	}
+
+	return stream.Publish(&v1.DispatchLookupResponse{
+		Metadata: &v1.ResponseMeta{
+			DispatchCount:       reachableStream.dispatchCount + 1, // +1 for the lookup
+			CachedDispatchCount: reachableStream.cachedDispatchCount,
+			DepthRequired:       reachableStream.depthRequired + 1, // +1 for the lookup
+		},
+	})
 }
@@ -13,6 +13,7 @@ import (
 	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
 	"github.com/authzed/spicedb/internal/namespace"
 	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/options"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
 	"github.com/authzed/spicedb/pkg/tuple"
@@ -39,6 +40,111 @@ type ConcurrentLookupSubjects struct {
 func (cl *ConcurrentLookupSubjects) LookupSubjects(
 	req ValidatedLookupSubjectsRequest,
 	stream dispatch.LookupSubjectsStream,
+) error {
+	// Subjects are always found compactly, and expanded for requests which do not allow it.
+	if !req.AllowCompactSubjects {
+		stream = &dispatch.WrappedDispatchStream[*v1.DispatchLookupSubjectsResponse]{
+			Stream:    stream,
+			Ctx:       stream.Context(),
+			Processor: expandCompactSubjects,
+		}
+	}
+
+	if req.OptionalLimit > 0 || req.OptionalCursor != nil {
+		return cl.lookupSubjectsWithinPage(req, stream)
+	}
+
+	return cl.lookupSubjects(req, stream)
+}
+
+// lookupSubjectsWithinPage performs the lookup, returning only those concrete subjects found within
+// the page defined by the limit and cursor of the request, in a single response.
+//
+// NOTE: all responses for the request are combined before the page is applied, to ensure that the
+// caveats of each concrete subject returned reflect every path by which it was found.
+func (cl *ConcurrentLookupSubjects) lookupSubjectsWithinPage(
+	req ValidatedLookupSubjectsRequest,
+	stream dispatch.LookupSubjectsStream,
+) error {
+	afterSubjectID, err := afterSubjectIDFromCursor(req.OptionalCursor)
+	if err != nil {
+		return err
+	}
+
+	collector := dispatch.NewCollectingDispatchStream[*v1.DispatchLookupSubjectsResponse](stream.Context())
+	if err := cl.lookupSubjects(req, collector); err != nil {
+		return err
+	}
+
+	foundSubjects := datasets.NewSubjectSetByResourceID()
+	metadata := emptyMetadata
+	for _, result := range collector.Results() {
+		metadata = combineResponseMetadata(metadata, result.Metadata)
+		if err := foundSubjects.UnionWith(result.FoundSubjectsByResourceId); err != nil {
+			return fmt.Errorf("failed to UnionWith under lookupSubjectsWithinPage: %w", err)
+		}
+	}
+
+	limited := foundSubjects.LimitedAfter(afterSubjectID, req.OptionalLimit)
+	if limited.IsEmpty() {
+		return nil
+	}
+
+	return stream.Publish(&v1.DispatchLookupSubjectsResponse{
+		FoundSubjectsByResourceId: limited.AsCompactMap(),
+		Metadata:                  metadata,
+	})
+}
+
+// expandCompactSubjects returns the response with the concrete subjects found by ID expanded into
+// found subjects, for a request which does not allow compact subjects.
+func expandCompactSubjects(result *v1.DispatchLookupSubjectsResponse) (*v1.DispatchLookupSubjectsResponse, bool, error) {
+	expanded := make(map[string]*v1.FoundSubjects, len(result.FoundSubjectsByResourceId))
+	for resourceID, foundSubjects := range result.FoundSubjectsByResourceId {
+		subjects := make([]*v1.FoundSubject, 0, len(foundSubjects.FoundSubjects)+len(foundSubjects.ConcreteSubjectIds))
+		subjects = append(subjects, foundSubjects.FoundSubjects...)
+		for _, subjectID := range foundSubjects.ConcreteSubjectIds {
+			subjects = append(subjects, &v1.FoundSubject{SubjectId: subjectID})
+		}
+		expanded[resourceID] = &v1.FoundSubjects{FoundSubjects: subjects}
+	}
+
+	return &v1.DispatchLookupSubjectsResponse{
+		FoundSubjectsByResourceId: expanded,
+		Metadata:                  result.Metadata,
+	}, true, nil
+}
+
+// afterSubjectIDFromCursor returns the subject ID after which concrete subjects should be returned,
+// as found in the cursor, if any.
+func afterSubjectIDFromCursor(cursor *v1.Cursor) (string, error) {
+	if cursor == nil || len(cursor.Sections) == 0 {
+		return "", nil
+	}
+
+	if len(cursor.Sections) != 1 {
+		return "", NewErrInvalidArgument(fmt.Errorf("invalid lookup subjects cursor with %d sections", len(cursor.Sections)))
+	}
+
+	return cursor.Sections[0], nil
+}
+
+// withoutLimit returns the request with its limit removed, if any. The limit cannot be applied to the
+// branches of an intersection or exclusion, as the subjects found beyond the limit of one branch can
+// change those found within the limit of another.
+func withoutLimit(req ValidatedLookupSubjectsRequest) ValidatedLookupSubjectsRequest {
+	if req.OptionalLimit == 0 {
+		return req
+	}
+
+	cloned := req.DispatchLookupSubjectsRequest.CloneVT()
+	cloned.OptionalLimit = 0
+	return ValidatedLookupSubjectsRequest{cloned, req.Revision}
+}
+
+func (cl *ConcurrentLookupSubjects) lookupSubjects(
+	req ValidatedLookupSubjectsRequest,
+	stream dispatch.LookupSubjectsStream,
 ) error {
 	ctx := stream.Context()
 
@@ -80,12 +186,7 @@ func subjectsForConcreteIds(subjectIds []string) map[string]*v1.FoundSubjects {
 	foundSubjects := make(map[string]*v1.FoundSubjects, len(subjectIds))
 	for _, subjectID := range subjectIds {
 		foundSubjects[subjectID] = &v1.FoundSubjects{
-			FoundSubjects: []*v1.FoundSubject{
-				{
-					SubjectId:        subjectID,
-					CaveatExpression: nil, // Explicitly nil since this is a concrete found subject.
-				},
-			},
+			ConcreteSubjectIds: []string{subjectID},
 		}
 	}
 	return foundSubjects
@@ -98,12 +199,27 @@ func (cl *ConcurrentLookupSubjects) lookupDirectSubjects(
 	_ *core.Relation,
 	reader datastore.Reader,
 ) error {
-	// TODO(jschorr): use type information to skip subject relations that cannot reach the subject type.
-	it, err := reader.QueryRelationships(ctx, datastore.RelationshipsFilter{
+	afterSubjectID, err := afterSubjectIDFromCursor(req.OptionalCursor)
+	if err != nil {
+		return err
+	}
+
+	// When paging, the subjects of the requested type are found by queries limited to the page,
+	// so only those subjects to be dispatched are found here.
+	paged := req.OptionalLimit > 0 || afterSubjectID != ""
+	filter := datastore.RelationshipsFilter{
 		ResourceType:             req.ResourceRelation.Namespace,
 		OptionalResourceRelation: req.ResourceRelation.Relation,
 		OptionalResourceIds:      req.ResourceIds,
-	})
+	}
+	if paged {
+		filter.OptionalSubjectsSelectors = []datastore.SubjectsSelector{{
+			RelationFilter: datastore.SubjectRelationFilter{}.WithOnlyNonEllipsisRelations(),
+		}}
+	}
+
+	// TODO(jschorr): use type information to skip subject relations that cannot reach the subject type.
+	it, err := reader.QueryRelationships(ctx, filter)
 	if err != nil {
 		return err
 	}
@@ -117,7 +233,8 @@ func (cl *ConcurrentLookupSubjects) lookupDirectSubjects(
 			return it.Err()
 		}
 
-		if tpl.Subject.Namespace == req.SubjectRelation.Namespace &&
+		if !paged &&
+			tpl.Subject.Namespace == req.SubjectRelation.Namespace &&
 			tpl.Subject.Relation == req.SubjectRelation.Relation {
 			if err := foundSubjectsByResourceID.AddFromRelationship(tpl); err != nil {
 				return fmt.Errorf("failed to call AddFromRelationship in lookupDirectSubjects: %w", err)
@@ -135,9 +252,15 @@ func (cl *ConcurrentLookupSubjects) lookupDirectSubjects(
 	}
 	it.Close()
 
+	if paged {
+		if err := lookupDirectSubjectsWithinPage(ctx, req, afterSubjectID, reader, foundSubjectsByResourceID); err != nil {
+			return err
+		}
+	}
+
 	if !foundSubjectsByResourceID.IsEmpty() {
 		if err := stream.Publish(&v1.DispatchLookupSubjectsResponse{
-			FoundSubjectsByResourceId: foundSubjectsByResourceID.AsMap(),
+			FoundSubjectsByResourceId: foundSubjectsByResourceID.AsCompactMap(),
 			Metadata:                  emptyMetadata,
 		}); err != nil {
 			return err
@@ -147,6 +270,93 @@ func (cl *ConcurrentLookupSubjects) lookupDirectSubjects(
 	return cl.dispatchTo(ctx, req, toDispatchByType, relationshipsBySubjectONR, stream)
 }
 
+// lookupDirectSubjectsWithinPage adds the subjects of the requested type found directly on each of
+// the resources, within the page defined by the limit and cursor of the request. The relationships
+// of each resource are read in order of subject ID, which, with the resource and the subject type
+// and relation fixed, is the order of the relationships, so only those within the page are read.
+func lookupDirectSubjectsWithinPage(
+	ctx context.Context,
+	req ValidatedLookupSubjectsRequest,
+	afterSubjectID string,
+	reader datastore.Reader,
+	foundSubjectsByResourceID datasets.SubjectSetByResourceID,
+) error {
+	subjectsSelector := datastore.SubjectsSelector{
+		OptionalSubjectType: req.SubjectRelation.Namespace,
+		RelationFilter:      datastore.SubjectRelationFilter{}.WithRelation(req.SubjectRelation.Relation),
+	}
+
+	// The wildcard is returned on every page, and sorts before all concrete subject IDs, so it
+	// is read separately and the page starts after it when no cursor was given.
+	if afterSubjectID == "" {
+		afterSubjectID = tuple.PublicWildcard
+	}
+
+	var limit *uint64
+	if req.OptionalLimit > 0 {
+		pageSize := uint64(req.OptionalLimit)
+		limit = &pageSize
+	}
+
+	addRelationships := func(filter datastore.RelationshipsFilter, opts ...options.QueryOptionsOption) error {
+		it, err := reader.QueryRelationships(ctx, filter, opts...)
+		if err != nil {
+			return err
+		}
+		defer it.Close()
+
+		for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+			if it.Err() != nil {
+				return it.Err()
+			}
+
+			if err := foundSubjectsByResourceID.AddFromRelationship(tpl); err != nil {
+				return fmt.Errorf("failed to call AddFromRelationship in lookupDirectSubjectsWithinPage: %w", err)
+			}
+		}
+		return it.Err()
+	}
+
+	wildcardSelector := subjectsSelector
+	wildcardSelector.OptionalSubjectIds = []string{tuple.PublicWildcard}
+	if err := addRelationships(datastore.RelationshipsFilter{
+		ResourceType:              req.ResourceRelation.Namespace,
+		OptionalResourceRelation:  req.ResourceRelation.Relation,
+		OptionalResourceIds:       req.ResourceIds,
+		OptionalSubjectsSelectors: []datastore.SubjectsSelector{wildcardSelector},
+	}); err != nil {
+		return err
+	}
+
+	for _, resourceID := range req.ResourceIds {
+		if err := addRelationships(
+			datastore.RelationshipsFilter{
+				ResourceType:              req.ResourceRelation.Namespace,
+				OptionalResourceRelation:  req.ResourceRelation.Relation,
+				OptionalResourceIds:       []string{resourceID},
+				OptionalSubjectsSelectors: []datastore.SubjectsSelector{subjectsSelector},
+			},
+			options.WithSort(options.ByResource),
+			options.WithAfter(&core.RelationTuple{
+				ResourceAndRelation: &core.ObjectAndRelation{
+					Namespace: req.ResourceRelation.Namespace,
+					ObjectId:  resourceID,
+					Relation:  req.ResourceRelation.Relation,
+				},
+				Subject: &core.ObjectAndRelation{
+					Namespace: req.SubjectRelation.Namespace,
+					ObjectId:  afterSubjectID,
+					Relation:  req.SubjectRelation.Relation,
+				},
+			}),
+			options.WithLimit(limit),
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (cl *ConcurrentLookupSubjects) lookupViaComputed(
 	ctx context.Context,
 	parentRequest ValidatedLookupSubjectsRequest,
@@ -181,9 +391,13 @@ func (cl *ConcurrentLookupSubjects) lookupViaComputed(
 		ResourceIds:     parentRequest.ResourceIds,
 		SubjectRelation: parentRequest.SubjectRelation,
 		Metadata: &v1.ResolverMeta{
-			AtRevision:     parentRequest.Revision.String(),
-			DepthRemaining: parentRequest.Metadata.DepthRemaining - 1,
+			AtRevision:              parentRequest.Revision.String(),
+			DepthRemaining:          parentRequest.Metadata.DepthRemaining - 1,
+			ContextualRelationships: parentRequest.Metadata.ContextualRelationships,
 		},
+		OptionalLimit:        parentRequest.OptionalLimit,
+		OptionalCursor:       parentRequest.OptionalCursor,
+		AllowCompactSubjects: true,
 	}, stream)
 }
 
@@ -261,10 +475,10 @@ func (cl *ConcurrentLookupSubjects) lookupViaRewrite(
 		return cl.lookupSetOperation(ctx, req, rw.Union, newLookupSubjectsUnion(stream))
 	case *core.UsersetRewrite_Intersection:
 		log.Ctx(ctx).Trace().Msg("intersection")
-		return cl.lookupSetOperation(ctx, req, rw.Intersection, newLookupSubjectsIntersection(stream))
+		return cl.lookupSetOperation(ctx, withoutLimit(req), rw.Intersection, newLookupSubjectsIntersection(stream))
 	case *core.UsersetRewrite_Exclusion:
 		log.Ctx(ctx).Trace().Msg("exclusion")
-		return cl.lookupSetOperation(ctx, req, rw.Exclusion, newLookupSubjectsExclusion(stream))
+		return cl.lookupSetOperation(ctx, withoutLimit(req), rw.Exclusion, newLookupSubjectsExclusion(stream))
 	default:
 		return fmt.Errorf("unknown kind of rewrite in lookup subjects")
 	}
@@ -391,14 +605,14 @@ func (cl *ConcurrentLookupSubjects) dispatchTo(
 
 						// Otherwise, apply the caveat to all found subjects for that resource and map to the resource ID.
 						foundSubjectSet := datasets.NewSubjectSet()
-						err := foundSubjectSet.UnionWith(foundSubjects.FoundSubjects)
+						err := foundSubjectSet.UnionWithFoundSubjects(foundSubjects)
 						if err != nil {
 							return nil, false, fmt.Errorf("could not combine subject sets: %w", err)
 						}
 
 						combined, err := combineFoundSubjects(
 							existing,
-							foundSubjectSet.WithParentCaveatExpression(wrapCaveat(relationship.Caveat)).AsFoundSubjects(),
+							foundSubjectSet.WithParentCaveatExpression(wrapCaveat(relationship.Caveat)).AsCompactFoundSubjects(),
 						)
 						if err != nil {
 							return nil, false, fmt.Errorf("could not combine caveated subjects: %w", err)
@@ -423,9 +637,13 @@ func (cl *ConcurrentLookupSubjects) dispatchTo(
 					ResourceIds:      resourceIdChunk,
 					SubjectRelation:  parentRequest.SubjectRelation,
 					Metadata: &v1.ResolverMeta{
-						AtRevision:     parentRequest.Revision.String(),
-						DepthRemaining: parentRequest.Metadata.DepthRemaining - 1,
+						AtRevision:              parentRequest.Revision.String(),
+						DepthRemaining:          parentRequest.Metadata.DepthRemaining - 1,
+						ContextualRelationships: parentRequest.Metadata.ContextualRelationships,
 					},
+					OptionalLimit:        parentRequest.OptionalLimit,
+					OptionalCursor:       parentRequest.OptionalCursor,
+					AllowCompactSubjects: true,
 				}, stream)
 			})
 		})
@@ -444,7 +662,8 @@ func combineFoundSubjects(existing *v1.FoundSubjects, toAdd *v1.FoundSubjects) (
 	}
 
 	return &v1.FoundSubjects{
-		FoundSubjects: append(existing.FoundSubjects, toAdd.FoundSubjects...),
+		FoundSubjects:      append(existing.FoundSubjects, toAdd.FoundSubjects...),
+		ConcreteSubjectIds: append(existing.ConcreteSubjectIds, toAdd.ConcreteSubjectIds...),
 	}, nil
 }
 
@@ -495,7 +714,7 @@ func (lsu *lookupSubjectsUnion) CompletedChildOperations() error {
 	}
 
 	return lsu.parentStream.Publish(&v1.DispatchLookupSubjectsResponse{
-		FoundSubjectsByResourceId: foundSubjects.AsMap(),
+		FoundSubjectsByResourceId: foundSubjects.AsCompactMap(),
 		Metadata:                  metadata,
 	})
 }
@@ -522,6 +741,7 @@ func (lsi *lookupSubjectsIntersection) ForIndex(ctx context.Context, setOperatio
 func (lsi *lookupSubjectsIntersection) CompletedChildOperations() error {
 	var foundSubjects datasets.SubjectSetByResourceID
 	metadata := emptyMetadata
+	interner := datasets.NewInterner()
 
 	for index := 0; index < len(lsi.collectors); index++ {
 		collector, ok := lsi.collectors[index]
@@ -529,7 +749,7 @@ func (lsi *lookupSubjectsIntersection) CompletedChildOperations() error {
 			return fmt.Errorf("missing collector for index %d", index)
 		}
 
-		results := datasets.NewSubjectSetByResourceID()
+		results := datasets.NewSubjectSetByResourceIDWithInterner(interner)
 		for _, result := range collector.Results() {
 			metadata = combineResponseMetadata(metadata, result.Metadata)
 			if err := results.UnionWith(result.FoundSubjectsByResourceId); err != nil {
@@ -552,7 +772,7 @@ func (lsi *lookupSubjectsIntersection) CompletedChildOperations() error {
 	}
 
 	return lsi.parentStream.Publish(&v1.DispatchLookupSubjectsResponse{
-		FoundSubjectsByResourceId: foundSubjects.AsMap(),
+		FoundSubjectsByResourceId: foundSubjects.AsCompactMap(),
 		Metadata:                  metadata,
 	})
 }
@@ -579,10 +799,11 @@ func (lse *lookupSubjectsExclusion) ForIndex(ctx context.Context, setOperationIn
 func (lse *lookupSubjectsExclusion) CompletedChildOperations() error {
 	var foundSubjects datasets.SubjectSetByResourceID
 	metadata := emptyMetadata
+	interner := datasets.NewInterner()
 
 	for index := 0; index < len(lse.collectors); index++ {
 		collector := lse.collectors[index]
-		results := datasets.NewSubjectSetByResourceID()
+		results := datasets.NewSubjectSetByResourceIDWithInterner(interner)
 		for _, result := range collector.Results() {
 			metadata = combineResponseMetadata(metadata, result.Metadata)
 			if err := results.UnionWith(result.FoundSubjectsByResourceId); err != nil {
@@ -601,7 +822,7 @@ func (lse *lookupSubjectsExclusion) CompletedChildOperations() error {
 	}
 
 	return lse.parentStream.Publish(&v1.DispatchLookupSubjectsResponse{
-		FoundSubjectsByResourceId: foundSubjects.AsMap(),
+		FoundSubjectsByResourceId: foundSubjects.AsCompactMap(),
 		Metadata:                  metadata,
 	})
 }
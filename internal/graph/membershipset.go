@@ -1,7 +1,10 @@
 package graph
 
 import (
+	"github.com/RoaringBitmap/roaring"
+
 	"github.com/authzed/spicedb/internal/caveats"
+	"github.com/authzed/spicedb/internal/datasets"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
 )
@@ -22,8 +25,9 @@ type CheckResultsMap map[string]*v1.ResourceCheckResult
 // check request.
 func NewMembershipSet() *MembershipSet {
 	return &MembershipSet{
-		hasDeterminedMember: false,
-		membersByID:         map[string]*core.CaveatExpression{},
+		interner:     datasets.NewInterner(),
+		determined:   roaring.New(),
+		caveatedByID: map[string]*core.CaveatExpression{},
 	}
 }
 
@@ -37,9 +41,13 @@ func membershipSetFromMap(mp map[string]*core.CaveatExpression) *MembershipSet {
 
 // MembershipSet is a helper set that trackes the membership results for a dispatched Check
 // request, including tracking of the caveats associated with found resource IDs.
+//
+// Resource IDs found without a caveat are held as a bitmap over their interned IDs, with only
+// those found with a caveat held along with their caveat expression.
 type MembershipSet struct {
-	membersByID         map[string]*core.CaveatExpression
-	hasDeterminedMember bool
+	interner     *datasets.Interner
+	determined   *roaring.Bitmap
+	caveatedByID map[string]*core.CaveatExpression
 }
 
 // AddDirectMember adds a resource ID that was *directly* found for the dispatched check, with
@@ -63,28 +71,27 @@ func (ms *MembershipSet) AddMemberViaRelationship(
 }
 
 func (ms *MembershipSet) addMember(resourceID string, caveatExpr *core.CaveatExpression) {
-	existing, ok := ms.membersByID[resourceID]
-	if !ok {
-		ms.hasDeterminedMember = ms.hasDeterminedMember || caveatExpr == nil
-		ms.membersByID[resourceID] = caveatExpr
-		return
-	}
-
 	// If a determined membership result has already been found (i.e. there is no caveat),
 	// then nothing more to do.
-	if existing == nil {
+	if ms.isDetermined(resourceID) {
 		return
 	}
 
 	// If the new caveat expression is nil, then we are adding a determined result.
 	if caveatExpr == nil {
-		ms.hasDeterminedMember = true
-		ms.membersByID[resourceID] = nil
+		delete(ms.caveatedByID, resourceID)
+		ms.determined.Add(ms.interner.Intern(resourceID))
 		return
 	}
 
 	// Otherwise, the caveats get unioned together.
-	ms.membersByID[resourceID] = caveatOr(existing, caveatExpr)
+	ms.caveatedByID[resourceID] = caveatOr(ms.caveatedByID[resourceID], caveatExpr)
+}
+
+// isDetermined returns whether the resource ID was found without a caveat.
+func (ms *MembershipSet) isDetermined(resourceID string) bool {
+	id, ok := ms.interner.Lookup(resourceID)
+	return ok && ms.determined.Contains(id)
 }
 
 // UnionWith combines the results found in the given map with the members of this set.
@@ -98,47 +105,55 @@ func (ms *MembershipSet) UnionWith(resultsMap CheckResultsMap) {
 // IntersectWith intersects the results found in the given map with the members of this set.
 // The changes are made in-place.
 func (ms *MembershipSet) IntersectWith(resultsMap CheckResultsMap) {
-	for resourceID := range ms.membersByID {
-		if _, ok := resultsMap[resourceID]; !ok {
-			delete(ms.membersByID, resourceID)
-		}
-	}
-
-	ms.hasDeterminedMember = false
+	determined := roaring.New()
+	caveatedByID := make(map[string]*core.CaveatExpression, len(ms.caveatedByID))
 	for resourceID, details := range resultsMap {
-		existing, ok := ms.membersByID[resourceID]
-		if !ok {
-			continue
-		}
-		if existing == nil && details.Expression == nil {
-			ms.hasDeterminedMember = true
+		if id, ok := ms.interner.Lookup(resourceID); ok && ms.determined.Contains(id) {
+			if details.Expression == nil {
+				determined.Add(id)
+			} else {
+				caveatedByID[resourceID] = details.Expression
+			}
 			continue
 		}
 
-		ms.membersByID[resourceID] = caveatAnd(existing, details.Expression)
+		if existing, ok := ms.caveatedByID[resourceID]; ok {
+			caveatedByID[resourceID] = caveatAnd(existing, details.Expression)
+		}
 	}
+
+	ms.determined = determined
+	ms.caveatedByID = caveatedByID
 }
 
 // Subtract subtracts the results found in the given map with the members of this set.
 // The changes are made in-place.
 func (ms *MembershipSet) Subtract(resultsMap CheckResultsMap) {
-	ms.hasDeterminedMember = false
-	for resourceID, expression := range ms.membersByID {
-		if details, ok := resultsMap[resourceID]; ok {
-			// If the incoming member has no caveat, then this removal is absolute.
-			if details.Expression == nil {
-				delete(ms.membersByID, resourceID)
-				continue
+	for resourceID, details := range resultsMap {
+		if id, ok := ms.interner.Lookup(resourceID); ok && ms.determined.Contains(id) {
+			// If the incoming member has no caveat, then this removal is absolute. Otherwise,
+			// the member is only found if the incoming member's caveat is false.
+			ms.determined.Remove(id)
+			if details.Expression != nil {
+				ms.caveatedByID[resourceID] = caveatSub(nil, details.Expression)
 			}
+			continue
+		}
 
-			// Otherwise, the caveat expression gets combined with an intersection of the inversion
-			// of the expression.
-			ms.membersByID[resourceID] = caveatSub(expression, details.Expression)
-		} else {
-			if expression == nil {
-				ms.hasDeterminedMember = true
-			}
+		existing, ok := ms.caveatedByID[resourceID]
+		if !ok {
+			continue
+		}
+
+		// If the incoming member has no caveat, then this removal is absolute.
+		if details.Expression == nil {
+			delete(ms.caveatedByID, resourceID)
+			continue
 		}
+
+		// Otherwise, the caveat expression gets combined with an intersection of the inversion
+		// of the expression.
+		ms.caveatedByID[resourceID] = caveatSub(existing, details.Expression)
 	}
 }
 
@@ -149,8 +164,7 @@ func (ms *MembershipSet) HasConcreteResourceID(resourceID string) bool {
 		return false
 	}
 
-	found, ok := ms.membersByID[resourceID]
-	return ok && found == nil
+	return ms.isDetermined(resourceID)
 }
 
 // Size returns the number of elements in the membership set.
@@ -159,7 +173,7 @@ func (ms *MembershipSet) Size() int {
 		return 0
 	}
 
-	return len(ms.membersByID)
+	return int(ms.determined.GetCardinality()) + len(ms.caveatedByID)
 }
 
 // IsEmpty returns true if the set is empty.
@@ -168,7 +182,7 @@ func (ms *MembershipSet) IsEmpty() bool {
 		return true
 	}
 
-	return len(ms.membersByID) == 0
+	return ms.determined.IsEmpty() && len(ms.caveatedByID) == 0
 }
 
 // HasDeterminedMember returns whether there exists at least one non-caveated member of the set.
@@ -177,24 +191,38 @@ func (ms *MembershipSet) HasDeterminedMember() bool {
 		return false
 	}
 
-	return ms.hasDeterminedMember
+	return !ms.determined.IsEmpty()
 }
 
 // AsCheckResultsMap converts the membership set back into a CheckResultsMap for placement into
 // a DispatchCheckResult.
 func (ms *MembershipSet) AsCheckResultsMap() CheckResultsMap {
-	resultsMap := make(CheckResultsMap, len(ms.membersByID))
-	for resourceID, caveat := range ms.membersByID {
-		membership := v1.ResourceCheckResult_MEMBER
-		if caveat != nil {
-			membership = v1.ResourceCheckResult_CAVEATED_MEMBER
+	resultsMap := make(CheckResultsMap, ms.Size())
+	for _, resourceID := range ms.interner.Values(ms.determined) {
+		resultsMap[resourceID] = &v1.ResourceCheckResult{
+			Membership: v1.ResourceCheckResult_MEMBER,
 		}
+	}
 
+	for resourceID, caveat := range ms.caveatedByID {
 		resultsMap[resourceID] = &v1.ResourceCheckResult{
-			Membership: membership,
+			Membership: v1.ResourceCheckResult_CAVEATED_MEMBER,
 			Expression: caveat,
 		}
 	}
 
 	return resultsMap
 }
+
+// membersByID returns the members of the set, along with the caveat expression of each, if any.
+func (ms *MembershipSet) membersByID() map[string]*core.CaveatExpression {
+	membersByID := make(map[string]*core.CaveatExpression, ms.Size())
+	for _, resourceID := range ms.interner.Values(ms.determined) {
+		membersByID[resourceID] = nil
+	}
+
+	for resourceID, caveat := range ms.caveatedByID {
+		membersByID[resourceID] = caveat
+	}
+	return membersByID
+}
@@ -129,7 +129,7 @@ func TestMembershipSetAddDirectMember(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			ms := membershipSetFromMap(tc.existingMembers)
 			ms.AddDirectMember(tc.directMemberID, unwrapCaveat(tc.directMemberCaveat))
-			require.Equal(t, tc.expectedMembers, ms.membersByID)
+			require.Equal(t, tc.expectedMembers, ms.membersByID())
 			require.Equal(t, tc.hasDeterminedMember, ms.HasDeterminedMember())
 			require.False(t, ms.IsEmpty())
 		})
@@ -232,7 +232,7 @@ func TestMembershipSetAddMemberViaRelationship(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			ms := membershipSetFromMap(tc.existingMembers)
 			ms.AddMemberViaRelationship(tc.resourceID, tc.resourceCaveatExpression, tc.parentRelationship)
-			require.Equal(t, tc.expectedMembers, ms.membersByID)
+			require.Equal(t, tc.expectedMembers, ms.membersByID())
 			require.Equal(t, tc.hasDeterminedMember, ms.HasDeterminedMember())
 		})
 	}
@@ -375,7 +375,7 @@ func TestMembershipSetUnionWith(t *testing.T) {
 			ms1 := membershipSetFromMap(tc.set1)
 			ms2 := membershipSetFromMap(tc.set2)
 			ms1.UnionWith(ms2.AsCheckResultsMap())
-			require.Equal(t, tc.expected, ms1.membersByID)
+			require.Equal(t, tc.expected, ms1.membersByID())
 			require.Equal(t, tc.hasDeterminedMember, ms1.HasDeterminedMember())
 			require.Equal(t, tc.isEmpty, ms1.IsEmpty())
 		})
@@ -552,7 +552,7 @@ func TestMembershipSetIntersectWith(t *testing.T) {
 			ms1 := membershipSetFromMap(tc.set1)
 			ms2 := membershipSetFromMap(tc.set2)
 			ms1.IntersectWith(ms2.AsCheckResultsMap())
-			require.Equal(t, tc.expected, ms1.membersByID)
+			require.Equal(t, tc.expected, ms1.membersByID())
 			require.Equal(t, tc.hasDeterminedMember, ms1.HasDeterminedMember())
 			require.Equal(t, tc.isEmpty, ms1.IsEmpty())
 		})
@@ -713,7 +713,7 @@ func TestMembershipSetSubtract(t *testing.T) {
 			ms1 := membershipSetFromMap(tc.set1)
 			ms2 := membershipSetFromMap(tc.set2)
 			ms1.Subtract(ms2.AsCheckResultsMap())
-			require.Equal(t, tc.expected, ms1.membersByID)
+			require.Equal(t, tc.expected, ms1.membersByID())
 			require.Equal(t, tc.hasDeterminedMember, ms1.HasDeterminedMember())
 			require.Equal(t, tc.isEmpty, ms1.IsEmpty())
 		})
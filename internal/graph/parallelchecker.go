@@ -2,6 +2,7 @@ package graph
 
 import (
 	"context"
+	"sort"
 	"sync"
 
 	"golang.org/x/exp/maps"
@@ -14,11 +15,12 @@ import (
 )
 
 // parallelChecker is a helper for initiating checks over a large set of resources of a specific
-// type, for a specific subject, and putting the results concurrently into a set.
+// type, for a specific subject, and publishing the results concurrently to a stream.
 type parallelChecker struct {
 	c      dispatch.Check
 	t      *TaskRunner
 	cancel func()
+	stream dispatch.LookupStream
 
 	toCheck         chan string
 	enqueuedToCheck *util.Set[string]
@@ -26,7 +28,13 @@ type parallelChecker struct {
 	lookupRequest ValidatedLookupRequest
 	maxConcurrent uint16
 
-	foundResourceIDs map[string]*v1.ResolvedResource
+	// publishedResourceIDs holds the IDs of the resources published to the stream.
+	publishedResourceIDs *util.Set[string]
+
+	// conditionalResources holds the resources found to conditionally have permission. They
+	// are only published once all checks have completed, as the same resource may yet be found
+	// to unconditionally have permission.
+	conditionalResources map[string]*v1.ResolvedResource
 
 	dispatchCount       uint32
 	cachedDispatchCount uint32
@@ -36,11 +44,12 @@ type parallelChecker struct {
 }
 
 // newParallelChecker creates a new parallel checker, for a given subject.
-func newParallelChecker(ctx context.Context, cancel func(), c dispatch.Check, req ValidatedLookupRequest, maxConcurrent uint16) *parallelChecker {
+func newParallelChecker(ctx context.Context, cancel func(), c dispatch.Check, req ValidatedLookupRequest, stream dispatch.LookupStream, maxConcurrent uint16) *parallelChecker {
 	t := NewTaskRunner(ctx, maxConcurrent+1) // +1 for the work scheduling goroutine
 	toCheck := make(chan string, maxConcurrent)
 	return &parallelChecker{
 		cancel: cancel,
+		stream: stream,
 
 		c: c,
 		t: t,
@@ -51,20 +60,21 @@ func newParallelChecker(ctx context.Context, cancel func(), c dispatch.Check, re
 		lookupRequest: req,
 		maxConcurrent: maxConcurrent,
 
-		foundResourceIDs:    map[string]*v1.ResolvedResource{},
-		dispatchCount:       0,
-		cachedDispatchCount: 0,
-		depthRequired:       0,
+		publishedResourceIDs: util.NewSet[string](),
+		conditionalResources: map[string]*v1.ResolvedResource{},
+		dispatchCount:        0,
+		cachedDispatchCount:  0,
+		depthRequired:        0,
 
 		mu: sync.Mutex{},
 	}
 }
 
-// AddResolvedResource adds a resource that has been already checked to the set.
-func (pc *parallelChecker) AddResolvedResource(resolvedResource *v1.ResolvedResource) {
+// AddResolvedResource adds a resource that has been already checked to the results.
+func (pc *parallelChecker) AddResolvedResource(resolvedResource *v1.ResolvedResource) error {
 	pc.mu.Lock()
 	defer pc.mu.Unlock()
-	pc.addResultsUnsafe(resolvedResource)
+	return pc.addResultsUnsafe(resolvedResource)
 }
 
 // DispatchCount returns the number of dispatches used for checks.
@@ -82,21 +92,52 @@ func (pc *parallelChecker) DepthRequired() uint32 {
 	return pc.depthRequired
 }
 
-func (pc *parallelChecker) addResultsUnsafe(resolvedResource *v1.ResolvedResource) {
-	// If the result being added is conditional and we've already found a valid permission, skip.
-	if resolvedResource.Permissionship == v1.ResolvedResource_CONDITIONALLY_HAS_PERMISSION {
-		existing, ok := pc.foundResourceIDs[resolvedResource.ResourceId]
-		if ok && existing.Permissionship == v1.ResolvedResource_HAS_PERMISSION {
-			return
+// LimitReached returns whether the limit of the lookup request has been reached.
+func (pc *parallelChecker) LimitReached() bool {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	return pc.limitReachedUnsafe()
+}
+
+func (pc *parallelChecker) limitReachedUnsafe() bool {
+	return pc.publishedResourceIDs.Len()+len(pc.conditionalResources) >= int(pc.lookupRequest.Limit)
+}
+
+func (pc *parallelChecker) addResultsUnsafe(resolvedResource *v1.ResolvedResource) error {
+	if pc.publishedResourceIDs.Has(resolvedResource.ResourceId) {
+		return nil
+	}
+
+	// NOTE: a resource already found to conditionally have permission does not count against
+	// the limit a second time.
+	if _, ok := pc.conditionalResources[resolvedResource.ResourceId]; !ok && pc.limitReachedUnsafe() {
+		return nil
+	}
+
+	switch resolvedResource.Permissionship {
+	case v1.ResolvedResource_CONDITIONALLY_HAS_PERMISSION:
+		pc.conditionalResources[resolvedResource.ResourceId] = resolvedResource
+
+	default:
+		delete(pc.conditionalResources, resolvedResource.ResourceId)
+		pc.publishedResourceIDs.Add(resolvedResource.ResourceId)
+		if err := pc.publishUnsafe(resolvedResource); err != nil {
+			return err
 		}
 	}
 
-	pc.foundResourceIDs[resolvedResource.ResourceId] = resolvedResource
-	if len(pc.foundResourceIDs) >= int(pc.lookupRequest.Limit) {
+	if pc.limitReachedUnsafe() {
 		// Cancel any further work
 		pc.cancel()
-		return
 	}
+	return nil
+}
+
+func (pc *parallelChecker) publishUnsafe(resolvedResource *v1.ResolvedResource) error {
+	return pc.stream.Publish(&v1.DispatchLookupResponse{
+		Metadata:          emptyMetadata,
+		ResolvedResources: []*v1.ResolvedResource{resolvedResource},
+	})
 }
 
 func (pc *parallelChecker) updateStatsUnsafe(metadata *v1.ResponseMeta) {
@@ -110,8 +151,7 @@ func (pc *parallelChecker) QueueToCheck(resourceID string) bool {
 	queue := func() bool {
 		pc.mu.Lock()
 		defer pc.mu.Unlock()
-		if len(pc.foundResourceIDs) >= int(pc.lookupRequest.Limit) {
-			close(pc.toCheck)
+		if pc.limitReachedUnsafe() {
 			return false
 		}
 
@@ -121,15 +161,22 @@ func (pc *parallelChecker) QueueToCheck(resourceID string) bool {
 		return false
 	}
 
-	pc.toCheck <- resourceID
-	return true
+	// If the checks have been canceled, the queue is no longer being read.
+	select {
+	case pc.toCheck <- resourceID:
+		return true
+
+	case <-pc.t.ctx.Done():
+		return false
+	}
 }
 
 // Start starts the parallel checks over those items added via QueueToCheck.
 func (pc *parallelChecker) Start() {
 	meta := &v1.ResolverMeta{
-		AtRevision:     pc.lookupRequest.Revision.String(),
-		DepthRemaining: pc.lookupRequest.Metadata.DepthRemaining,
+		AtRevision:              pc.lookupRequest.Revision.String(),
+		DepthRemaining:          pc.lookupRequest.Metadata.DepthRemaining,
+		ContextualRelationships: pc.lookupRequest.Metadata.ContextualRelationships,
 	}
 
 	pc.t.Schedule(func(ctx context.Context) error {
@@ -171,12 +218,13 @@ func (pc *parallelChecker) Start() {
 
 				results, resultsMeta, err := computed.ComputeBulkCheck(ctx, pc.c,
 					computed.CheckParameters{
-						ResourceType:  pc.lookupRequest.ObjectRelation,
-						Subject:       pc.lookupRequest.Subject,
-						CaveatContext: pc.lookupRequest.Context.AsMap(),
-						AtRevision:    pc.lookupRequest.Revision,
-						MaximumDepth:  meta.DepthRemaining,
-						DebugOption:   computed.NoDebugging,
+						ResourceType:            pc.lookupRequest.ObjectRelation,
+						Subject:                 pc.lookupRequest.Subject,
+						CaveatContext:           pc.lookupRequest.Context.AsMap(),
+						AtRevision:              pc.lookupRequest.Revision,
+						MaximumDepth:            meta.DepthRemaining,
+						DebugOption:             computed.NoDebugging,
+						ContextualRelationships: meta.ContextualRelationships,
 					},
 					collected,
 				)
@@ -185,23 +233,27 @@ func (pc *parallelChecker) Start() {
 				}
 
 				pc.mu.Lock()
+				defer pc.mu.Unlock()
 				pc.updateStatsUnsafe(resultsMeta)
 
 				for resourceID, result := range results {
+					var err error
 					if result.Membership == v1.ResourceCheckResult_MEMBER {
-						pc.addResultsUnsafe(&v1.ResolvedResource{
+						err = pc.addResultsUnsafe(&v1.ResolvedResource{
 							ResourceId:     resourceID,
 							Permissionship: v1.ResolvedResource_HAS_PERMISSION,
 						})
 					} else if result.Membership == v1.ResourceCheckResult_CAVEATED_MEMBER {
-						pc.addResultsUnsafe(&v1.ResolvedResource{
+						err = pc.addResultsUnsafe(&v1.ResolvedResource{
 							ResourceId:             resourceID,
 							Permissionship:         v1.ResolvedResource_CONDITIONALLY_HAS_PERMISSION,
 							MissingRequiredContext: result.MissingExprFields,
 						})
 					}
+					if err != nil {
+						return err
+					}
 				}
-				pc.mu.Unlock()
 				return nil
 			})
 		}
@@ -211,13 +263,24 @@ func (pc *parallelChecker) Start() {
 }
 
 // Wait waits for the parallel checker to finish performing all of its
-// checks and returns the set of resources that checked, along with whether an
-// error occurred. Once called, no new items can be added via QueueToCheck.
-func (pc *parallelChecker) Wait() ([]*v1.ResolvedResource, error) {
+// checks and then publishes the resources found to conditionally have permission,
+// returning whether an error occurred. Once called, no new items can be added via
+// QueueToCheck.
+func (pc *parallelChecker) Wait() error {
 	close(pc.toCheck)
-	if err := pc.t.Wait(); err != nil {
-		return nil, err
+	if err := pc.t.Wait(); err != nil && !pc.LimitReached() {
+		return err
 	}
 
-	return maps.Values(pc.foundResourceIDs), nil
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	resourceIDs := maps.Keys(pc.conditionalResources)
+	sort.Strings(resourceIDs)
+	for _, resourceID := range resourceIDs {
+		if err := pc.publishUnsafe(pc.conditionalResources[resourceID]); err != nil {
+			return err
+		}
+	}
+	return nil
 }
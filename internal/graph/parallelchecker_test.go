@@ -6,52 +6,85 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/authzed/spicedb/internal/dispatch"
 	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
 )
 
 func TestParallelCheckerDirectOverload(t *testing.T) {
+	stream := dispatch.NewCollectingDispatchStream[*v1.DispatchLookupResponse](context.Background())
 	pc := newParallelChecker(context.Background(), func() {}, nil, ValidatedLookupRequest{
 		DispatchLookupRequest: &v1.DispatchLookupRequest{
 			Limit: 50,
 		},
-	}, 10)
+	}, stream, 10)
 
-	// Add a conditional item and ensure it is added.
-	pc.addResultsUnsafe(&v1.ResolvedResource{
+	// Add a conditional item and ensure it is held back.
+	require.NoError(t, pc.addResultsUnsafe(&v1.ResolvedResource{
 		ResourceId:     "foo",
 		Permissionship: v1.ResolvedResource_CONDITIONALLY_HAS_PERMISSION,
-	})
+	}))
 
-	require.Equal(t, v1.ResolvedResource_CONDITIONALLY_HAS_PERMISSION, pc.foundResourceIDs["foo"].Permissionship)
+	require.Equal(t, v1.ResolvedResource_CONDITIONALLY_HAS_PERMISSION, pc.conditionalResources["foo"].Permissionship)
+	require.Empty(t, stream.Results())
 
-	// Add a concrete item and ensure it overloads.
-	pc.addResultsUnsafe(&v1.ResolvedResource{
+	// Add a concrete item and ensure it overloads and is published.
+	require.NoError(t, pc.addResultsUnsafe(&v1.ResolvedResource{
 		ResourceId:     "foo",
 		Permissionship: v1.ResolvedResource_HAS_PERMISSION,
-	})
+	}))
 
-	require.Equal(t, v1.ResolvedResource_HAS_PERMISSION, pc.foundResourceIDs["foo"].Permissionship)
+	require.Empty(t, pc.conditionalResources)
+	require.Len(t, stream.Results(), 1)
+	require.Equal(t, v1.ResolvedResource_HAS_PERMISSION, stream.Results()[0].ResolvedResources[0].Permissionship)
 
 	// Add a conditional item and ensure it is ignored.
-	pc.addResultsUnsafe(&v1.ResolvedResource{
+	require.NoError(t, pc.addResultsUnsafe(&v1.ResolvedResource{
 		ResourceId:     "foo",
 		Permissionship: v1.ResolvedResource_CONDITIONALLY_HAS_PERMISSION,
-	})
+	}))
 
-	require.Equal(t, v1.ResolvedResource_HAS_PERMISSION, pc.foundResourceIDs["foo"].Permissionship)
+	require.Empty(t, pc.conditionalResources)
+	require.Len(t, stream.Results(), 1)
+}
+
+func TestParallelCheckerPublishesConditionalOnWait(t *testing.T) {
+	stream := dispatch.NewCollectingDispatchStream[*v1.DispatchLookupResponse](context.Background())
+	pc := newParallelChecker(context.Background(), func() {}, nil, ValidatedLookupRequest{
+		DispatchLookupRequest: &v1.DispatchLookupRequest{
+			Limit: 50,
+		},
+	}, stream, 10)
+
+	require.NoError(t, pc.AddResolvedResource(&v1.ResolvedResource{
+		ResourceId:     "foo",
+		Permissionship: v1.ResolvedResource_CONDITIONALLY_HAS_PERMISSION,
+	}))
+	require.NoError(t, pc.AddResolvedResource(&v1.ResolvedResource{
+		ResourceId:     "bar",
+		Permissionship: v1.ResolvedResource_HAS_PERMISSION,
+	}))
+
+	require.Len(t, stream.Results(), 1)
+	require.Equal(t, "bar", stream.Results()[0].ResolvedResources[0].ResourceId)
+
+	require.NoError(t, pc.Wait())
+	require.Len(t, stream.Results(), 2)
+	require.Equal(t, "foo", stream.Results()[1].ResolvedResources[0].ResourceId)
+	require.Equal(t, v1.ResolvedResource_CONDITIONALLY_HAS_PERMISSION, stream.Results()[1].ResolvedResources[0].Permissionship)
 }
 
 func TestQueueToCheckLimit(t *testing.T) {
+	stream := dispatch.NewCollectingDispatchStream[*v1.DispatchLookupResponse](context.Background())
 	pc := newParallelChecker(context.Background(), func() {}, nil, ValidatedLookupRequest{
 		DispatchLookupRequest: &v1.DispatchLookupRequest{
 			Limit: 1,
 		},
-	}, 10)
+	}, stream, 10)
 
-	pc.addResultsUnsafe(&v1.ResolvedResource{
+	require.NoError(t, pc.addResultsUnsafe(&v1.ResolvedResource{
 		ResourceId:     "foo",
 		Permissionship: v1.ResolvedResource_HAS_PERMISSION,
-	})
+	}))
 
 	// Queue a second and ensure it is ignored.
 	require.False(t, pc.QueueToCheck("bar"))
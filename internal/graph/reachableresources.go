@@ -3,6 +3,8 @@ package graph
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strconv"
 
 	"github.com/authzed/spicedb/internal/dispatch"
 	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
@@ -45,26 +47,40 @@ func (crr *ConcurrentReachableResources) ReachableResources(
 		return fmt.Errorf("no subjects ids given to reachable resources dispatch")
 	}
 
+	// If a cursor was requested, all work is performed in order, so that the results (and their
+	// cursors) are stable.
+	cursor := newPositionCursor(req.OptionalCursor)
+
 	// If the resource type matches the subject type, yield directly as a one-to-one result
 	// for each subjectID.
 	if req.SubjectRelation.Namespace == req.ResourceRelation.Namespace &&
 		req.SubjectRelation.Relation == req.ResourceRelation.Relation {
-		resources := make([]*v1.ReachableResource, 0, len(req.SubjectIds))
-		for _, subjectID := range req.SubjectIds {
-			resources = append(resources, &v1.ReachableResource{
-				ResourceId:    subjectID,
-				ResultStatus:  v1.ReachableResource_HAS_PERMISSION,
-				ForSubjectIds: []string{subjectID},
-			})
-		}
-
-		err := stream.Publish(&v1.DispatchReachableResourcesResponse{
-			Resources: resources,
-			Metadata:  emptyMetadata,
-		})
+		selfCursor, skip, err := cursor.descend(0)
 		if err != nil {
 			return err
 		}
+
+		if !skip {
+			resources := make([]*v1.ReachableResource, 0, len(req.SubjectIds))
+			for _, subjectID := range req.SubjectIds {
+				resources = append(resources, &v1.ReachableResource{
+					ResourceId:    subjectID,
+					ResultStatus:  v1.ReachableResource_HAS_PERMISSION,
+					ForSubjectIds: []string{subjectID},
+				})
+			}
+
+			resources = cursoredResources(resources, selfCursor)
+			if len(resources) > 0 {
+				err := stream.Publish(&v1.DispatchReachableResourcesResponse{
+					Resources: resources,
+					Metadata:  emptyMetadata,
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
 	}
 
 	// Load the type system and reachability graph to find the entrypoints for the reachability.
@@ -84,13 +100,26 @@ func (crr *ConcurrentReachableResources) ReachableResources(
 		return err
 	}
 
-	t := NewTaskRunner(ctx, crr.concurrencyLimit)
+	var t taskScheduler = NewTaskRunner(ctx, crr.concurrencyLimit)
+	if cursor != nil {
+		t = newOrderedTaskRunner(ctx)
+	}
 
 	// For each entrypoint, load the necessary data and re-dispatch if a subproblem was found.
-	for _, entrypoint := range entrypoints {
+	// Position zero of the cursor is reserved for the direct yield above.
+	for index, entrypoint := range entrypoints {
+		entrypointCursor, skip, err := cursor.descend(index + 1)
+		if err != nil {
+			return err
+		}
+
+		if skip {
+			continue
+		}
+
 		switch entrypoint.EntrypointKind() {
 		case core.ReachabilityEntrypoint_RELATION_ENTRYPOINT:
-			err := crr.lookupRelationEntrypoint(ctx, t, entrypoint, rg, reader, req, stream, dispatched)
+			err := crr.lookupRelationEntrypoint(ctx, t, entrypoint, rg, reader, req, stream, dispatched, entrypointCursor)
 			if err != nil {
 				return err
 			}
@@ -115,13 +144,14 @@ func (crr *ConcurrentReachableResources) ReachableResources(
 				stream,
 				req,
 				dispatched,
+				entrypointCursor,
 			)
 			if err != nil {
 				return err
 			}
 
 		case core.ReachabilityEntrypoint_TUPLESET_TO_USERSET_ENTRYPOINT:
-			err := crr.lookupTTUEntrypoint(ctx, t, entrypoint, rg, reader, req, stream, dispatched)
+			err := crr.lookupTTUEntrypoint(ctx, t, entrypoint, rg, reader, req, stream, dispatched, entrypointCursor)
 			if err != nil {
 				return err
 			}
@@ -136,13 +166,14 @@ func (crr *ConcurrentReachableResources) ReachableResources(
 
 func (crr *ConcurrentReachableResources) lookupRelationEntrypoint(
 	ctx context.Context,
-	t *TaskRunner,
+	t taskScheduler,
 	entrypoint namespace.ReachabilityEntrypoint,
 	rg *namespace.ReachabilityGraph,
 	reader datastore.Reader,
 	req ValidatedReachableResourcesRequest,
 	stream dispatch.ReachableResourcesStream,
 	dispatched *syncONRSet,
+	cursor *positionCursor,
 ) error {
 	relationReference, err := entrypoint.DirectRelation()
 	if err != nil {
@@ -189,11 +220,10 @@ func (crr *ConcurrentReachableResources) lookupRelationEntrypoint(
 		},
 	}
 
-	crr.scheduleChunkedRedispatch(t, reader, subjectsFilter, relationReference,
-		func(ctx context.Context, drsm dispatchableResourcesSubjectMap) error {
-			return crr.redispatchOrReport(ctx, t, relationReference, drsm, rg, entrypoint, stream, req, dispatched)
+	return crr.scheduleChunkedRedispatch(t, reader, subjectsFilter, relationReference, cursor,
+		func(ctx context.Context, drsm dispatchableResourcesSubjectMap, chunkCursor *positionCursor) error {
+			return crr.redispatchOrReport(ctx, t, relationReference, drsm, rg, entrypoint, stream, req, dispatched, chunkCursor)
 		})
-	return nil
 }
 
 func min(a, b int) int {
@@ -204,12 +234,17 @@ func min(a, b int) int {
 }
 
 func (crr *ConcurrentReachableResources) scheduleChunkedRedispatch(
-	t *TaskRunner,
+	t taskScheduler,
 	reader datastore.Reader,
 	subjectsFilter datastore.SubjectsFilter,
 	resourceType *core.RelationReference,
-	handler func(ctx context.Context, resources dispatchableResourcesSubjectMap) error,
-) {
+	cursor *positionCursor,
+	handler func(ctx context.Context, resources dispatchableResourcesSubjectMap, chunkCursor *positionCursor) error,
+) error {
+	if cursor != nil {
+		return crr.scheduleCursoredChunkedRedispatch(t, reader, subjectsFilter, resourceType, cursor, handler)
+	}
+
 	t.Schedule(func(ctx context.Context) error {
 		toBeHandled := make([]resourcesSubjectMap, 0)
 		it, err := reader.ReverseQueryRelationships(
@@ -255,23 +290,135 @@ func (crr *ConcurrentReachableResources) scheduleChunkedRedispatch(
 		}
 
 		for _, rsmToHandle := range toBeHandled {
-			err := handler(ctx, rsmToHandle.asReadOnly())
+			err := handler(ctx, rsmToHandle.asReadOnly(), nil)
 			if err != nil {
 				return err
 			}
 		}
 		return nil
 	})
+	return nil
+}
+
+// cursoredChunk is a chunk of resources found by scheduleCursoredChunkedRedispatch, along with
+// the sections identifying its position.
+type cursoredChunk struct {
+	rsm        resourcesSubjectMap
+	chunkIndex int
+	startAfter string
+}
+
+// scheduleCursoredChunkedRedispatch is the variant of scheduleChunkedRedispatch used when a cursor
+// was requested. Relationships are read sorted by resource, and chunks are only split between
+// resources, so that each chunk can be identified by its index and the relationship after which
+// it starts. The sections of the cursor at this position are therefore:
+// [chunkIndex, startAfterRelationship, ...sections for the resources within the chunk].
+func (crr *ConcurrentReachableResources) scheduleCursoredChunkedRedispatch(
+	t taskScheduler,
+	reader datastore.Reader,
+	subjectsFilter datastore.SubjectsFilter,
+	resourceType *core.RelationReference,
+	cursor *positionCursor,
+	handler func(ctx context.Context, resources dispatchableResourcesSubjectMap, chunkCursor *positionCursor) error,
+) error {
+	chunkIndex := 0
+	startAfter := ""
+	var remainingAfter []string
+	if len(cursor.after) > 0 {
+		if len(cursor.after) < 2 {
+			return NewErrInvalidArgument(fmt.Errorf("invalid cursor for reachable resources chunk"))
+		}
+
+		index, err := strconv.Atoi(cursor.after[0])
+		if err != nil || index < 0 {
+			return NewErrInvalidArgument(fmt.Errorf("invalid cursor chunk index `%s`", cursor.after[0]))
+		}
+
+		chunkIndex = index
+		startAfter = cursor.after[1]
+		remainingAfter = cursor.after[2:]
+	}
+
+	queryOpts := []options.ReverseQueryOptionsOption{
+		options.WithResRelation(&options.ResourceRelation{
+			Namespace: resourceType.Namespace,
+			Relation:  resourceType.Relation,
+		}),
+		options.WithSortForReverse(options.ByResource),
+	}
+
+	if startAfter != "" {
+		afterTpl := tuple.Parse(startAfter)
+		if afterTpl == nil {
+			return NewErrInvalidArgument(fmt.Errorf("invalid cursor relationship `%s`", startAfter))
+		}
+
+		queryOpts = append(queryOpts, options.WithAfterForReverse(afterTpl))
+	}
+
+	t.Schedule(func(ctx context.Context) error {
+		toBeHandled := make([]cursoredChunk, 0)
+		it, err := reader.ReverseQueryRelationships(ctx, subjectsFilter, queryOpts...)
+		if err != nil {
+			return err
+		}
+		defer it.Close()
+
+		current := cursoredChunk{newResourcesSubjectMap(resourceType), chunkIndex, startAfter}
+		var lastTpl *core.RelationTuple
+		for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+			if it.Err() != nil {
+				return it.Err()
+			}
+
+			// Only start a new chunk between resources, as the relationships for the
+			// same resource are returned contiguously.
+			chunkSize := progressiveDispatchChunkSizes[min(current.chunkIndex, len(progressiveDispatchChunkSizes)-1)]
+			if current.rsm.len() == int(chunkSize) && tpl.ResourceAndRelation.ObjectId != lastTpl.ResourceAndRelation.ObjectId {
+				toBeHandled = append(toBeHandled, current)
+				current = cursoredChunk{newResourcesSubjectMap(resourceType), current.chunkIndex + 1, tuple.StringWithoutCaveat(lastTpl)}
+			}
+
+			if err := current.rsm.addRelationship(tpl); err != nil {
+				return err
+			}
+			lastTpl = tpl
+		}
+		if it.Err() != nil {
+			return it.Err()
+		}
+		it.Close()
+
+		if current.rsm.len() > 0 {
+			toBeHandled = append(toBeHandled, current)
+		}
+
+		for index, chunk := range toBeHandled {
+			// Only the first chunk can be the one in which the cursor resumes.
+			var chunkAfter []string
+			if index == 0 {
+				chunkAfter = remainingAfter
+			}
+
+			chunkCursor := cursor.withSections(chunkAfter, strconv.Itoa(chunk.chunkIndex), chunk.startAfter)
+			if err := handler(ctx, chunk.rsm.asReadOnly(), chunkCursor); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return nil
 }
 
 func (crr *ConcurrentReachableResources) lookupTTUEntrypoint(ctx context.Context,
-	t *TaskRunner,
+	t taskScheduler,
 	entrypoint namespace.ReachabilityEntrypoint,
 	rg *namespace.ReachabilityGraph,
 	reader datastore.Reader,
 	req ValidatedReachableResourcesRequest,
 	stream dispatch.ReachableResourcesStream,
 	dispatched *syncONRSet,
+	cursor *positionCursor,
 ) error {
 	containingRelation := entrypoint.ContainingRelationOrPermission()
 
@@ -322,11 +469,10 @@ func (crr *ConcurrentReachableResources) lookupTTUEntrypoint(ctx context.Context
 		Relation:  tuplesetRelation,
 	}
 
-	crr.scheduleChunkedRedispatch(t, reader, subjectsFilter, tuplesetRelationReference,
-		func(ctx context.Context, drsm dispatchableResourcesSubjectMap) error {
-			return crr.redispatchOrReport(ctx, t, containingRelation, drsm, rg, entrypoint, stream, req, dispatched)
+	return crr.scheduleChunkedRedispatch(t, reader, subjectsFilter, tuplesetRelationReference, cursor,
+		func(ctx context.Context, drsm dispatchableResourcesSubjectMap, chunkCursor *positionCursor) error {
+			return crr.redispatchOrReport(ctx, t, containingRelation, drsm, rg, entrypoint, stream, req, dispatched, chunkCursor)
 		})
-	return nil
 }
 
 // redispatchOrReport checks if further redispatching is necessary for the found resource
@@ -334,7 +480,7 @@ func (crr *ConcurrentReachableResources) lookupTTUEntrypoint(ctx context.Context
 // the resource is reported to the parent stream.
 func (crr *ConcurrentReachableResources) redispatchOrReport(
 	ctx context.Context,
-	t *TaskRunner,
+	t taskScheduler,
 	foundResourceType *core.RelationReference,
 	foundResources dispatchableResourcesSubjectMap,
 	rg *namespace.ReachabilityGraph,
@@ -342,6 +488,7 @@ func (crr *ConcurrentReachableResources) redispatchOrReport(
 	parentStream dispatch.ReachableResourcesStream,
 	parentRequest ValidatedReachableResourcesRequest,
 	dispatched *syncONRSet,
+	cursor *positionCursor,
 ) error {
 	if foundResources.isEmpty() {
 		// Nothing more to do.
@@ -359,8 +506,13 @@ func (crr *ConcurrentReachableResources) redispatchOrReport(
 		// If the found resource matches the target resource type and relation, yield the resource.
 		if foundResourceType.Namespace == parentRequest.ResourceRelation.Namespace &&
 			foundResourceType.Relation == parentRequest.ResourceRelation.Relation {
+			resources := cursoredResources(foundResources.asReachableResources(entrypoint.IsDirectResult()), cursor)
+			if len(resources) == 0 {
+				return nil
+			}
+
 			return parentStream.Publish(&v1.DispatchReachableResourcesResponse{
-				Resources: foundResources.asReachableResources(entrypoint.IsDirectResult()),
+				Resources: resources,
 				Metadata:  emptyMetadata,
 			})
 		}
@@ -390,6 +542,18 @@ func (crr *ConcurrentReachableResources) redispatchOrReport(
 					return nil, false, err
 				}
 
+				// If a cursor was requested, prefix the cursor of each found resource with the
+				// current position.
+				if cursor != nil {
+					for index, resource := range result.Resources {
+						if resource.AfterResponseCursor == nil {
+							return nil, false, spiceerrors.MustBugf("missing cursor for reachable resource")
+						}
+
+						mapped[index].AfterResponseCursor = cursor.cursorFor(resource.AfterResponseCursor.Sections...)
+					}
+				}
+
 				return &v1.DispatchReachableResourcesResponse{
 					Resources: mapped,
 					Metadata:  addCallToResponseMetadata(result.Metadata),
@@ -400,8 +564,18 @@ func (crr *ConcurrentReachableResources) redispatchOrReport(
 		// The new subject type for dispatching was the found type of the *resource*.
 		newSubjectType := foundResourceType
 
-		// To avoid duplicate work, remove any subjects already dispatched.
-		filteredSubjectIDs := foundResources.filterSubjectIDsToDispatch(dispatched, newSubjectType)
+		// To avoid duplicate work, remove any subjects already dispatched. If a cursor was requested,
+		// the subjects must instead be stable, so that the dispatch can be resumed.
+		var filteredSubjectIDs []string
+		var dispatchCursor *v1.Cursor
+		if cursor != nil {
+			filteredSubjectIDs = foundResources.resourceIDs()
+			sort.Strings(filteredSubjectIDs)
+			dispatchCursor = &v1.Cursor{Sections: cursor.after}
+		} else {
+			filteredSubjectIDs = foundResources.filterSubjectIDsToDispatch(dispatched, newSubjectType)
+		}
+
 		if len(filteredSubjectIDs) == 0 {
 			return nil
 		}
@@ -413,9 +587,11 @@ func (crr *ConcurrentReachableResources) redispatchOrReport(
 			SubjectRelation:  newSubjectType,
 			SubjectIds:       filteredSubjectIDs,
 			Metadata: &v1.ResolverMeta{
-				AtRevision:     parentRequest.Revision.String(),
-				DepthRemaining: parentRequest.Metadata.DepthRemaining - 1,
+				AtRevision:              parentRequest.Revision.String(),
+				DepthRemaining:          parentRequest.Metadata.DepthRemaining - 1,
+				ContextualRelationships: parentRequest.Metadata.ContextualRelationships,
 			},
+			OptionalCursor: dispatchCursor,
 		}, stream)
 	})
 	return nil
@@ -0,0 +1,255 @@
+package materialize
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/options"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// indexNamespacePrefix prefixes the resource type of each materialized permission to form the
+// namespace under which the index of the permission is held. As it is not a valid prefix for a
+// definition, the index can neither collide with, nor be read or written as, the relationships
+// of any definition.
+const indexNamespacePrefix = "_materialized/"
+
+// maxRetainedUpdates is the number of writes to the index of each permission which are retained,
+// and so the number of earlier states of the index from which the permission can be answered.
+const maxRetainedUpdates = 100
+
+// IndexNamespace returns the namespace under which the index of the materialized permissions of
+// the given resource type is held.
+func IndexNamespace(resourceType string) string {
+	return indexNamespacePrefix + resourceType
+}
+
+// IsIndexNamespace returns whether the given namespace is that of an index of materialized
+// permissions, rather than of a definition.
+func IsIndexNamespace(namespaceName string) bool {
+	return strings.HasPrefix(namespaceName, indexNamespacePrefix)
+}
+
+// indexUpdate records that the index of a permission, as written at one revision, holds the
+// members of the permission as of an earlier source revision.
+type indexUpdate struct {
+	source  datastore.Revision
+	written datastore.Revision
+}
+
+type permissionIndex struct {
+	subjectTypes map[string]struct{}
+
+	// updates are the retained writes to the index, in order. Each write reflects all changes to
+	// the members of the permission made at or before its source revision.
+	updates []indexUpdate
+}
+
+// Index tracks the revisions at which the index of each materialized permission, as maintained
+// by a Worker, has provably caught up with the changes to the relationships of the permission,
+// and answers checks and lookups from the index at those revisions.
+//
+// The index holds a relationship from each resource to each subject which is a member of the
+// permission, for each subject type with which the permission is materialized.
+type Index struct {
+	lock sync.RWMutex
+
+	// watchedThrough is the revision through which all changes have been applied to the index,
+	// or nil if the index is not being maintained.
+	watchedThrough datastore.Revision
+
+	// permissions holds the state of the index of each permission, in the form
+	// `namespace#relation`, which is being maintained.
+	permissions map[string]*permissionIndex
+}
+
+// NewIndex creates a new index, which answers nothing until maintained by a Worker.
+func NewIndex() *Index {
+	return &Index{
+		permissions: make(map[string]*permissionIndex),
+	}
+}
+
+// CheckMembers returns those of the resource IDs of which the subject is a member of the
+// materialized permission at the given revision, as read from the index with the reader. If the
+// index has not provably caught up to the revision, false is returned and the check must be
+// computed instead.
+func (idx *Index) CheckMembers(
+	ctx context.Context,
+	reader datastore.Reader,
+	revision datastore.Revision,
+	resourceRelation *core.RelationReference,
+	resourceIDs []string,
+	subject *core.ObjectAndRelation,
+) ([]string, bool, error) {
+	if subject.Relation != tuple.Ellipsis || !idx.covers(resourceRelation, subject.Namespace, revision) {
+		return nil, false, nil
+	}
+
+	it, err := reader.QueryRelationships(ctx, datastore.RelationshipsFilter{
+		ResourceType:             IndexNamespace(resourceRelation.Namespace),
+		OptionalResourceIds:      resourceIDs,
+		OptionalResourceRelation: resourceRelation.Relation,
+		OptionalSubjectsSelectors: []datastore.SubjectsSelector{{
+			OptionalSubjectType: subject.Namespace,
+			OptionalSubjectIds:  []string{subject.ObjectId},
+			RelationFilter:      datastore.SubjectRelationFilter{}.WithEllipsisRelation(),
+		}},
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	defer it.Close()
+
+	var members []string
+	for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+		members = append(members, tpl.ResourceAndRelation.ObjectId)
+	}
+	if it.Err() != nil {
+		return nil, false, it.Err()
+	}
+
+	return members, true, nil
+}
+
+// LookupResources returns the IDs of the resources of which the subject is a member of the
+// materialized permission at the given revision, up to the limit, as read from the index with the
+// reader. If the index has not provably caught up to the revision, false is returned and the
+// lookup must be computed instead.
+func (idx *Index) LookupResources(
+	ctx context.Context,
+	reader datastore.Reader,
+	revision datastore.Revision,
+	resourceRelation *core.RelationReference,
+	subject *core.ObjectAndRelation,
+	limit uint64,
+) ([]string, bool, error) {
+	if subject.Relation != tuple.Ellipsis || !idx.covers(resourceRelation, subject.Namespace, revision) {
+		return nil, false, nil
+	}
+
+	it, err := reader.ReverseQueryRelationships(ctx, datastore.SubjectsFilter{
+		SubjectType:        subject.Namespace,
+		OptionalSubjectIds: []string{subject.ObjectId},
+		RelationFilter:     datastore.SubjectRelationFilter{}.WithEllipsisRelation(),
+	}, options.WithResRelation(&options.ResourceRelation{
+		Namespace: IndexNamespace(resourceRelation.Namespace),
+		Relation:  resourceRelation.Relation,
+	}), options.WithReverseLimit(&limit))
+	if err != nil {
+		return nil, false, err
+	}
+	defer it.Close()
+
+	var resourceIDs []string
+	for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+		resourceIDs = append(resourceIDs, tpl.ResourceAndRelation.ObjectId)
+	}
+	if it.Err() != nil {
+		return nil, false, it.Err()
+	}
+
+	return resourceIDs, true, nil
+}
+
+// covers returns whether the index of the permission for the subject type provably holds the
+// members of the permission at the given revision: all changes at or before the revision have
+// been applied, and the latest write to the index at or before the revision reflects the last
+// change to the members of the permission at or before the revision.
+func (idx *Index) covers(resourceRelation *core.RelationReference, subjectType string, revision datastore.Revision) bool {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+
+	if idx.watchedThrough == nil || !atOrAfter(idx.watchedThrough, revision) {
+		return false
+	}
+
+	permission, ok := idx.permissions[tuple.JoinRelRef(resourceRelation.Namespace, resourceRelation.Relation)]
+	if !ok {
+		return false
+	}
+
+	if _, ok := permission.subjectTypes[subjectType]; !ok {
+		return false
+	}
+
+	for index := len(permission.updates) - 1; index >= 0; index-- {
+		if !atOrAfter(revision, permission.updates[index].written) {
+			continue
+		}
+
+		// If the members of the permission were changed after the source revision of the write
+		// but at or before the requested revision, the index had yet to catch up to the change.
+		next := index + 1
+		return next == len(permission.updates) || !atOrAfter(revision, permission.updates[next].source)
+	}
+
+	return false
+}
+
+// reset removes all state of the index, so that nothing is answered until it is again maintained
+// by a Worker.
+func (idx *Index) reset() {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	idx.watchedThrough = nil
+	idx.permissions = make(map[string]*permissionIndex)
+}
+
+// setWatchedThrough records that all changes through the revision have been applied to the index.
+func (idx *Index) setWatchedThrough(revision datastore.Revision) {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	idx.watchedThrough = revision
+}
+
+// rebuilt records that the index of the permission was rebuilt in full at the written revision,
+// holding its members for the subject types as of the source revision.
+func (idx *Index) rebuilt(permission string, subjectTypes []string, source datastore.Revision, written datastore.Revision) {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	state := &permissionIndex{
+		subjectTypes: make(map[string]struct{}, len(subjectTypes)),
+		updates:      []indexUpdate{{source, written}},
+	}
+	for _, subjectType := range subjectTypes {
+		state.subjectTypes[subjectType] = struct{}{}
+	}
+	idx.permissions[permission] = state
+}
+
+// updated records that the index of the permission was updated at the written revision to hold
+// the members changed at the source revision.
+func (idx *Index) updated(permission string, source datastore.Revision, written datastore.Revision) {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	state, ok := idx.permissions[permission]
+	if !ok {
+		return
+	}
+
+	state.updates = append(state.updates, indexUpdate{source, written})
+	if len(state.updates) > maxRetainedUpdates {
+		state.updates = state.updates[len(state.updates)-maxRetainedUpdates:]
+	}
+}
+
+// remove records that the index of the permission is no longer maintained.
+func (idx *Index) remove(permission string) {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	delete(idx.permissions, permission)
+}
+
+// atOrAfter returns whether the first revision is provably at or after the second.
+func atOrAfter(revision datastore.Revision, other datastore.Revision) bool {
+	return revision.GreaterThan(other) || revision.Equal(other)
+}
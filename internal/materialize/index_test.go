@@ -0,0 +1,89 @@
+package materialize
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/revision"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+func rev(value int64) datastore.Revision {
+	return revision.NewFromDecimal(decimal.NewFromInt(value))
+}
+
+func TestIndexNamespace(t *testing.T) {
+	require.Equal(t, "_materialized/document", IndexNamespace("document"))
+	require.True(t, IsIndexNamespace(IndexNamespace("document")))
+	require.False(t, IsIndexNamespace("document"))
+	require.False(t, IsIndexNamespace("materialized/document"))
+}
+
+func TestIndexCovers(t *testing.T) {
+	view := &core.RelationReference{Namespace: "document", Relation: "view"}
+
+	idx := NewIndex()
+	require.False(t, idx.covers(view, "user", rev(1)))
+
+	// Rebuilt at revision 2 from the state at revision 1, with changes applied through revision 5.
+	idx.rebuilt("document#view", []string{"user"}, rev(1), rev(2))
+	require.False(t, idx.covers(view, "user", rev(2)), "not watched")
+
+	idx.setWatchedThrough(rev(5))
+
+	// Members changed at revision 3, written at revision 4.
+	idx.updated("document#view", rev(3), rev(4))
+
+	testCases := []struct {
+		name         string
+		relation     *core.RelationReference
+		subjectType  string
+		revision     datastore.Revision
+		expectCovers bool
+	}{
+		{"before rebuild", view, "user", rev(1), false},
+		{"at rebuild", view, "user", rev(2), true},
+		{"after change before update", view, "user", rev(3), false},
+		{"at update", view, "user", rev(4), true},
+		{"watched through", view, "user", rev(5), true},
+		{"after watched", view, "user", rev(6), false},
+		{"unmaterialized subject type", view, "group", rev(4), false},
+		{"unmaintained permission", &core.RelationReference{Namespace: "document", Relation: "edit"}, "user", rev(4), false},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expectCovers, idx.covers(tc.relation, tc.subjectType, tc.revision))
+		})
+	}
+
+	idx.remove("document#view")
+	require.False(t, idx.covers(view, "user", rev(4)))
+
+	idx.rebuilt("document#view", []string{"user"}, rev(5), rev(6))
+	idx.setWatchedThrough(rev(6))
+	require.True(t, idx.covers(view, "user", rev(6)))
+
+	idx.reset()
+	require.False(t, idx.covers(view, "user", rev(6)))
+}
+
+func TestIndexRetainedUpdates(t *testing.T) {
+	view := &core.RelationReference{Namespace: "document", Relation: "view"}
+
+	idx := NewIndex()
+	idx.rebuilt("document#view", []string{"user"}, rev(0), rev(1))
+	for i := int64(1); i <= maxRetainedUpdates; i++ {
+		idx.updated("document#view", rev(2*i), rev(2*i+1))
+	}
+	idx.setWatchedThrough(rev(2*maxRetainedUpdates + 1))
+
+	require.False(t, idx.covers(view, "user", rev(1)), "earliest update should no longer be retained")
+	require.True(t, idx.covers(view, "user", rev(3)))
+	require.False(t, idx.covers(view, "user", rev(4)))
+	require.True(t, idx.covers(view, "user", rev(2*maxRetainedUpdates+1)))
+}
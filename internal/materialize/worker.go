@@ -0,0 +1,663 @@
+package materialize
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	v1api "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"golang.org/x/exp/maps"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/dispatch"
+	log "github.com/authzed/spicedb/internal/logging"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/pkg/datastore"
+	nspkg "github.com/authzed/spicedb/pkg/namespace"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+	"github.com/authzed/spicedb/pkg/util"
+)
+
+// watchRestartDelay is the delay before restarting the maintenance of the index, once interrupted.
+const watchRestartDelay = 1 * time.Second
+
+// resourceChunkSize is the number of resources whose members are computed in each dispatch.
+const resourceChunkSize = 100
+
+// errNotMaterializable is returned when the members of a permission cannot be held in the index,
+// as some are caveated or are wildcards.
+var errNotMaterializable = errors.New("permission has caveated or wildcard members")
+
+// materializedPermission holds what is known of a permission marked as materialized in the schema.
+type materializedPermission struct {
+	resourceRelation *core.RelationReference
+
+	// dependencies are the relations, in the form `namespace#relation`, on which the
+	// permission depends.
+	dependencies map[string]struct{}
+
+	// subjectTypes are the types of the subjects with which the permission is materialized.
+	subjectTypes []string
+
+	// incremental is whether the resources affected by a change can be found by reachability.
+	// Otherwise, the members of all resources are recomputed on any change.
+	incremental bool
+}
+
+// Worker maintains the index of each permission marked as materialized in the schema, by applying
+// the changes reported by the watch of the datastore. Members are computed with the dispatcher.
+//
+// The index must be maintained by a single worker per datastore.
+type Worker struct {
+	ds           datastore.Datastore
+	dispatcher   dispatch.Dispatcher
+	index        *Index
+	maximumDepth uint32
+
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	// permissions holds the permissions being maintained, in the form `namespace#relation`.
+	permissions map[string]*materializedPermission
+
+	// affectingRelations holds, for each relation on which a maintained permission depends, the
+	// relations of the same definition whose subjects are changed when it is changed.
+	affectingRelations map[string][]*core.RelationReference
+}
+
+// StartWorker starts a worker maintaining the index, until closed.
+func StartWorker(ds datastore.Datastore, dispatcher dispatch.Dispatcher, index *Index, maximumDepth uint32) *Worker {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &Worker{
+		ds:           ds,
+		dispatcher:   dispatcher,
+		index:        index,
+		maximumDepth: maximumDepth,
+		cancel:       cancel,
+		done:         make(chan struct{}),
+	}
+
+	go w.run(ctx)
+	return w
+}
+
+// Close stops maintaining the index.
+func (w *Worker) Close() error {
+	w.cancel()
+	<-w.done
+	return nil
+}
+
+func (w *Worker) run(ctx context.Context) {
+	defer close(w.done)
+
+	for {
+		err := w.maintain(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Ctx(ctx).Warn().Err(err).Msg("maintenance of materialized permissions was interrupted; restarting")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(watchRestartDelay):
+		}
+	}
+}
+
+// maintain rebuilds the index and then applies the changes reported by the datastore until the
+// watch fails. As changes made while the datastore is not being watched are unknown, nothing may
+// be answered from the index until it has been rebuilt.
+func (w *Worker) maintain(ctx context.Context) error {
+	w.index.reset()
+	defer w.index.reset()
+
+	ctx = datastoremw.ContextWithDatastore(ctx, w.ds)
+
+	afterRevision, err := w.ds.HeadRevision(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := w.rebuild(ctx, afterRevision, nil); err != nil {
+		return err
+	}
+	w.index.setWatchedThrough(afterRevision)
+
+	previous := afterRevision
+	updates, errchan := w.ds.Watch(ctx, afterRevision, datastore.WatchOptions{})
+	for {
+		select {
+		case update, ok := <-updates:
+			if ok {
+				if err := w.apply(ctx, previous, update); err != nil {
+					return err
+				}
+				w.index.setWatchedThrough(update.Revision)
+				previous = update.Revision
+			}
+		case err := <-errchan:
+			return err
+		}
+	}
+}
+
+// apply applies the changes at a revision to the index of each permission depending on them.
+func (w *Worker) apply(ctx context.Context, previous datastore.Revision, update *datastore.RevisionChanges) error {
+	if len(update.ChangedDefinitions) > 0 || len(update.DeletedNamespaces) > 0 || len(update.DeletedCaveats) > 0 {
+		return w.rebuild(ctx, update.Revision, update.DeletedNamespaces)
+	}
+
+	for key, permission := range w.permissions {
+		var changes []*core.RelationTupleUpdate
+		expiring := false
+		for _, change := range update.Changes {
+			resource := change.Tuple.ResourceAndRelation
+			if _, ok := permission.dependencies[tuple.JoinRelRef(resource.Namespace, resource.Relation)]; ok {
+				changes = append(changes, change)
+				expiring = expiring || change.Tuple.OptionalExpirationTime != nil
+			}
+		}
+
+		if len(changes) == 0 {
+			continue
+		}
+
+		// Relationships which expire do so without a change being reported, so the index of a
+		// permission depending on them cannot be maintained.
+		if expiring {
+			log.Ctx(ctx).Warn().Str("permission", key).Msg("materialized permission depends on expiring relationships; it will be computed instead")
+			if err := w.drop(ctx, key); err != nil {
+				return err
+			}
+			continue
+		}
+
+		written, changed, err := w.update(ctx, permission, previous, update.Revision, changes)
+		if errors.Is(err, errNotMaterializable) {
+			log.Ctx(ctx).Warn().Str("permission", key).Msg("materialized permission has caveated or wildcard members; it will be computed instead")
+			if err := w.drop(ctx, key); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if changed {
+			w.index.updated(key, update.Revision, written)
+		}
+	}
+
+	return nil
+}
+
+// rebuild determines the permissions marked as materialized in the schema at the revision, and
+// rebuilds the index of each in full. The index of every other relation, and of each deleted
+// definition, is removed.
+func (w *Worker) rebuild(ctx context.Context, revision datastore.Revision, deletedNamespaces []string) error {
+	for key := range w.permissions {
+		w.index.remove(key)
+	}
+	w.permissions = make(map[string]*materializedPermission)
+	w.affectingRelations = make(map[string][]*core.RelationReference)
+
+	reader := w.ds.SnapshotReader(revision)
+	namespaces, err := reader.ListAllNamespaces(ctx)
+	if err != nil {
+		return err
+	}
+
+	definitions := make([]*core.NamespaceDefinition, 0, len(namespaces))
+	for _, ns := range namespaces {
+		definitions = append(definitions, ns.Definition)
+	}
+
+	resolver := namespace.ResolverForPredefinedDefinitions(namespace.PredefinedElements{Namespaces: definitions})
+	unmaintained := make([]*core.RelationReference, 0)
+	for _, definition := range definitions {
+		for _, relation := range definition.Relation {
+			resourceRelation := &core.RelationReference{Namespace: definition.Name, Relation: relation.Name}
+			if !nspkg.IsMaterialized(relation) {
+				unmaintained = append(unmaintained, resourceRelation)
+				continue
+			}
+
+			permission, err := newMaterializedPermission(ctx, resolver, resourceRelation)
+			if err != nil {
+				return err
+			}
+
+			if permission == nil {
+				log.Ctx(ctx).Warn().Str("permission", tuple.StringRR(resourceRelation)).Msg("materialized permission depends on caveated or wildcard relations; it will be computed instead")
+				unmaintained = append(unmaintained, resourceRelation)
+				continue
+			}
+
+			w.permissions[tuple.StringRR(resourceRelation)] = permission
+		}
+	}
+
+	if _, err := w.ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		for _, resourceRelation := range unmaintained {
+			if _, _, err := rwt.DeleteRelationships(ctx, &v1api.RelationshipFilter{
+				ResourceType:     IndexNamespace(resourceRelation.Namespace),
+				OptionalRelation: resourceRelation.Relation,
+			}); err != nil {
+				return err
+			}
+		}
+
+		for _, deleted := range deletedNamespaces {
+			if _, _, err := rwt.DeleteRelationships(ctx, &v1api.RelationshipFilter{
+				ResourceType: IndexNamespace(deleted),
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if err := w.computeAffectingRelations(ctx, resolver, definitions); err != nil {
+		return err
+	}
+
+	for key, permission := range w.permissions {
+		written, err := w.build(ctx, permission, revision)
+		if errors.Is(err, errNotMaterializable) {
+			log.Ctx(ctx).Warn().Str("permission", key).Err(err).Msg("materialized permission cannot be held in the index; it will be computed instead")
+			if err := w.drop(ctx, key); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		w.index.rebuilt(key, permission.subjectTypes, revision, written)
+	}
+
+	return nil
+}
+
+// newMaterializedPermission returns the materialized permission, or nil if it depends on a
+// relation allowing caveated or wildcard subjects, whose members cannot be held in the index.
+func newMaterializedPermission(ctx context.Context, resolver namespace.Resolver, resourceRelation *core.RelationReference) (*materializedPermission, error) {
+	dependencies, err := namespace.RelationDependencies(ctx, resolver, resourceRelation.Namespace, resourceRelation.Relation)
+	if err != nil {
+		return nil, err
+	}
+
+	subjectTypes := util.NewSet[string]()
+	incremental := true
+	for dependency := range dependencies {
+		namespaceName, relationName := tuple.MustSplitRelRef(dependency)
+		definition, err := resolver.LookupNamespace(ctx, namespaceName)
+		if err != nil {
+			return nil, err
+		}
+
+		relation, ok := findRelation(definition, relationName)
+		if !ok {
+			return nil, namespace.NewRelationNotFoundErr(namespaceName, relationName)
+		}
+
+		for _, allowed := range relation.GetTypeInformation().GetAllowedDirectRelations() {
+			if allowed.GetRequiredCaveat() != nil || allowed.GetPublicWildcard() != nil {
+				return nil, nil
+			}
+
+			if allowed.GetRelation() == tuple.Ellipsis {
+				subjectTypes.Add(allowed.Namespace)
+			}
+		}
+
+		incremental = incremental && isUnionOnly(relation.GetUsersetRewrite())
+	}
+
+	sortedSubjectTypes := subjectTypes.AsSlice()
+	sort.Strings(sortedSubjectTypes)
+
+	return &materializedPermission{
+		resourceRelation: resourceRelation,
+		dependencies:     dependencies,
+		subjectTypes:     sortedSubjectTypes,
+		incremental:      incremental,
+	}, nil
+}
+
+// isUnionOnly returns whether the rewrite, if any, only unions its children. Resources reachable
+// through intersections and exclusions may be affected by changes which do not reach them, so
+// changes to permissions using them cannot be applied incrementally.
+func isUnionOnly(rewrite *core.UsersetRewrite) bool {
+	if rewrite == nil {
+		return true
+	}
+
+	union, ok := rewrite.RewriteOperation.(*core.UsersetRewrite_Union)
+	if !ok {
+		return false
+	}
+
+	for _, child := range union.Union.Child {
+		if nested, ok := child.ChildType.(*core.SetOperation_Child_UsersetRewrite); ok && !isUnionOnly(nested.UsersetRewrite) {
+			return false
+		}
+	}
+	return true
+}
+
+// computeAffectingRelations records, for each relation on which a maintained permission depends,
+// the relations of the same definition which depend upon it. A change to a relationship of the
+// relation changes the subjects of those relations for the resource of the relationship, and
+// through them, of the resources from which they are reachable.
+func (w *Worker) computeAffectingRelations(ctx context.Context, resolver namespace.Resolver, definitions []*core.NamespaceDefinition) error {
+	dependencies := util.NewSet[string]()
+	for _, permission := range w.permissions {
+		if permission.incremental {
+			dependencies.Extend(maps.Keys(permission.dependencies))
+		}
+	}
+
+	for _, definition := range definitions {
+		for _, relation := range definition.Relation {
+			relationDependencies, err := namespace.RelationDependencies(ctx, resolver, definition.Name, relation.Name)
+			if err != nil {
+				return err
+			}
+
+			for dependency := range relationDependencies {
+				namespaceName, _ := tuple.MustSplitRelRef(dependency)
+				if namespaceName == definition.Name && dependencies.Has(dependency) {
+					w.affectingRelations[dependency] = append(w.affectingRelations[dependency], &core.RelationReference{
+						Namespace: definition.Name,
+						Relation:  relation.Name,
+					})
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// drop stops maintaining the index of the permission, and removes it.
+func (w *Worker) drop(ctx context.Context, key string) error {
+	permission, ok := w.permissions[key]
+	if !ok {
+		return nil
+	}
+
+	delete(w.permissions, key)
+	w.index.remove(key)
+
+	_, err := w.ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		_, _, err := rwt.DeleteRelationships(ctx, &v1api.RelationshipFilter{
+			ResourceType:     IndexNamespace(permission.resourceRelation.Namespace),
+			OptionalRelation: permission.resourceRelation.Relation,
+		})
+		return err
+	})
+	return err
+}
+
+// build computes the members of every resource of the permission at the revision, and replaces
+// the index of the permission with them, returning the revision at which the index was written.
+func (w *Worker) build(ctx context.Context, permission *materializedPermission, revision datastore.Revision) (datastore.Revision, error) {
+	reader := w.ds.SnapshotReader(revision)
+
+	for dependency := range permission.dependencies {
+		namespaceName, relationName := tuple.MustSplitRelRef(dependency)
+		expiring, err := common.HasExpiringRelationships(ctx, reader, namespaceName, relationName)
+		if err != nil {
+			return nil, err
+		}
+		if expiring {
+			return nil, fmt.Errorf("%w: depends on expiring relationships", errNotMaterializable)
+		}
+	}
+
+	// The permission can only have members for resources with at least one relationship.
+	it, err := reader.QueryRelationships(ctx, datastore.RelationshipsFilter{
+		ResourceType: permission.resourceRelation.Namespace,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resourceIDs := util.NewSet[string]()
+	for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+		resourceIDs.Add(tpl.ResourceAndRelation.ObjectId)
+	}
+	if it.Err() != nil {
+		it.Close()
+		return nil, it.Err()
+	}
+	it.Close()
+
+	sortedResourceIDs := resourceIDs.AsSlice()
+	sort.Strings(sortedResourceIDs)
+
+	members, err := w.computeMembers(ctx, permission, revision, sortedResourceIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return w.ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		if _, _, err := rwt.DeleteRelationships(ctx, &v1api.RelationshipFilter{
+			ResourceType:     IndexNamespace(permission.resourceRelation.Namespace),
+			OptionalRelation: permission.resourceRelation.Relation,
+		}); err != nil {
+			return err
+		}
+
+		mutations := make([]*core.RelationTupleUpdate, 0, len(members))
+		for _, member := range members {
+			mutations = append(mutations, tuple.Create(member))
+		}
+		return rwt.WriteRelationships(ctx, mutations)
+	})
+}
+
+// update applies the changes at the revision to the index of the permission, returning the
+// revision at which the index was written and whether any members were changed.
+func (w *Worker) update(
+	ctx context.Context,
+	permission *materializedPermission,
+	previous datastore.Revision,
+	revision datastore.Revision,
+	changes []*core.RelationTupleUpdate,
+) (datastore.Revision, bool, error) {
+	if !permission.incremental {
+		written, err := w.build(ctx, permission, revision)
+		return written, err == nil, err
+	}
+
+	affected, err := w.affectedResources(ctx, permission, previous, revision, changes)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(affected) == 0 {
+		return nil, false, nil
+	}
+
+	members, err := w.computeMembers(ctx, permission, revision, affected)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// The index is only written by this worker, so its latest state is that last written.
+	head, err := w.ds.HeadRevision(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	it, err := w.ds.SnapshotReader(head).QueryRelationships(ctx, datastore.RelationshipsFilter{
+		ResourceType:             IndexNamespace(permission.resourceRelation.Namespace),
+		OptionalResourceIds:      affected,
+		OptionalResourceRelation: permission.resourceRelation.Relation,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	var mutations []*core.RelationTupleUpdate
+	for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+		key := tuple.StringWithoutCaveat(tpl)
+		if _, ok := members[key]; ok {
+			delete(members, key)
+			continue
+		}
+		mutations = append(mutations, tuple.Delete(tpl))
+	}
+	if it.Err() != nil {
+		it.Close()
+		return nil, false, it.Err()
+	}
+	it.Close()
+
+	for _, member := range members {
+		mutations = append(mutations, tuple.Create(member))
+	}
+
+	if len(mutations) == 0 {
+		return nil, false, nil
+	}
+
+	written, err := w.ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships(ctx, mutations)
+	})
+	return written, err == nil, err
+}
+
+// affectedResources returns the IDs of the resources of the permission whose members may have
+// been changed by the changes: those from which the subjects changed by the changes are reachable,
+// either before or after the changes were made.
+func (w *Worker) affectedResources(
+	ctx context.Context,
+	permission *materializedPermission,
+	previous datastore.Revision,
+	revision datastore.Revision,
+	changes []*core.RelationTupleUpdate,
+) ([]string, error) {
+	affected := util.NewSet[string]()
+	changedSubjectIDs := make(map[string]*util.Set[string])
+	changedSubjectRelations := make(map[string]*core.RelationReference)
+	for _, change := range changes {
+		resource := change.Tuple.ResourceAndRelation
+		if resource.Namespace == permission.resourceRelation.Namespace {
+			affected.Add(resource.ObjectId)
+		}
+
+		for _, affecting := range w.affectingRelations[tuple.JoinRelRef(resource.Namespace, resource.Relation)] {
+			key := tuple.StringRR(affecting)
+			if _, ok := changedSubjectIDs[key]; !ok {
+				changedSubjectIDs[key] = util.NewSet[string]()
+				changedSubjectRelations[key] = affecting
+			}
+			changedSubjectIDs[key].Add(resource.ObjectId)
+		}
+	}
+
+	for key, subjectIDs := range changedSubjectIDs {
+		for _, atRevision := range []datastore.Revision{previous, revision} {
+			stream := dispatch.NewCollectingDispatchStream[*v1.DispatchReachableResourcesResponse](ctx)
+			if err := w.dispatcher.DispatchReachableResources(&v1.DispatchReachableResourcesRequest{
+				ResourceRelation: permission.resourceRelation,
+				SubjectRelation:  changedSubjectRelations[key],
+				SubjectIds:       subjectIDs.AsSlice(),
+				Metadata: &v1.ResolverMeta{
+					AtRevision:     atRevision.String(),
+					DepthRemaining: w.maximumDepth,
+				},
+			}, stream); err != nil {
+				return nil, err
+			}
+
+			for _, result := range stream.Results() {
+				for _, resource := range result.Resources {
+					affected.Add(resource.ResourceId)
+				}
+			}
+		}
+	}
+
+	resourceIDs := affected.AsSlice()
+	sort.Strings(resourceIDs)
+	return resourceIDs, nil
+}
+
+// computeMembers computes the members of the permission for the resources at the revision,
+// returning the relationships of the index holding them, keyed by their string form.
+func (w *Worker) computeMembers(ctx context.Context, permission *materializedPermission, revision datastore.Revision, resourceIDs []string) (map[string]*core.RelationTuple, error) {
+	members := make(map[string]*core.RelationTuple)
+	for len(resourceIDs) > 0 {
+		chunk := resourceIDs
+		if len(chunk) > resourceChunkSize {
+			chunk = chunk[:resourceChunkSize]
+		}
+		resourceIDs = resourceIDs[len(chunk):]
+
+		for _, subjectType := range permission.subjectTypes {
+			stream := dispatch.NewCollectingDispatchStream[*v1.DispatchLookupSubjectsResponse](ctx)
+			if err := w.dispatcher.DispatchLookupSubjects(&v1.DispatchLookupSubjectsRequest{
+				ResourceRelation: permission.resourceRelation,
+				ResourceIds:      chunk,
+				SubjectRelation:  &core.RelationReference{Namespace: subjectType, Relation: tuple.Ellipsis},
+				Metadata: &v1.ResolverMeta{
+					AtRevision:     revision.String(),
+					DepthRemaining: w.maximumDepth,
+				},
+			}, stream); err != nil {
+				return nil, err
+			}
+
+			for _, result := range stream.Results() {
+				for resourceID, found := range result.FoundSubjectsByResourceId {
+					for _, subject := range found.FoundSubjects {
+						if subject.CaveatExpression != nil || subject.SubjectId == tuple.PublicWildcard {
+							return nil, errNotMaterializable
+						}
+
+						member := &core.RelationTuple{
+							ResourceAndRelation: &core.ObjectAndRelation{
+								Namespace: IndexNamespace(permission.resourceRelation.Namespace),
+								ObjectId:  resourceID,
+								Relation:  permission.resourceRelation.Relation,
+							},
+							Subject: &core.ObjectAndRelation{
+								Namespace: subjectType,
+								ObjectId:  subject.SubjectId,
+								Relation:  tuple.Ellipsis,
+							},
+						}
+						members[tuple.StringWithoutCaveat(member)] = member
+					}
+				}
+			}
+		}
+	}
+
+	return members, nil
+}
+
+func findRelation(definition *core.NamespaceDefinition, relationName string) (*core.Relation, bool) {
+	for _, relation := range definition.Relation {
+		if relation.Name == relationName {
+			return relation, true
+		}
+	}
+	return nil, false
+}
@@ -8,7 +8,7 @@ import (
 )
 
 // MustStreamServerInterceptor returns a new stream server interceptor that cancels the context
-// after a timeout if no new data has been received.
+// after a timeout if no new data has been sent or received.
 func MustStreamServerInterceptor(timeout time.Duration) grpc.StreamServerInterceptor {
 	if timeout <= 0 {
 		panic("timeout must be >= 0 for streaming timeout interceptor")
@@ -45,3 +45,11 @@ func (s *sendWrapper) SendMsg(m any) error {
 	}
 	return err
 }
+
+func (s *sendWrapper) RecvMsg(m any) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.timer.Reset(s.timeout)
+	}
+	return err
+}
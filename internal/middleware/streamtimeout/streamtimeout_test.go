@@ -2,7 +2,9 @@ package streamtimeout
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"testing"
 	"time"
 
@@ -47,8 +49,22 @@ func (t testServer) PingList(_ *testpb.PingListRequest, server testpb.TestServic
 	}
 }
 
-func (t testServer) PingStream(_ testpb.TestService_PingStreamServer) error {
-	return fmt.Errorf("unused")
+func (t testServer) PingStream(server testpb.TestService_PingStreamServer) error {
+	// Count the received pings until the client closes its side of the stream.
+	var counter int32
+	for {
+		_, err := server.Recv()
+		if errors.Is(err, io.EOF) {
+			return server.Send(&testpb.PingStreamResponse{Counter: counter})
+		}
+		if err != nil {
+			return err
+		}
+		if err := server.Context().Err(); err != nil {
+			return err
+		}
+		counter++
+	}
 }
 
 type testSuite struct {
@@ -88,3 +104,20 @@ func (s *testSuite) TestStreamTimeout() {
 		require.LessOrEqual(s.T(), maxCounter, int32(6), "stream was not properly canceled: %d", maxCounter)
 	}
 }
+
+func (s *testSuite) TestStreamTimeoutResetOnReceive() {
+	stream, err := s.Client.PingStream(s.SimpleCtx())
+	require.NoError(s.T(), err)
+
+	// Send pings over a period longer than the timeout, but with each ping arriving within
+	// the timeout of the last.
+	for i := 0; i < 10; i++ {
+		require.NoError(s.T(), stream.Send(&testpb.PingStreamRequest{Value: "something"}))
+		time.Sleep(20 * time.Millisecond)
+	}
+	require.NoError(s.T(), stream.CloseSend())
+
+	resp, err := stream.Recv()
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), int32(10), resp.Counter)
+}
@@ -0,0 +1,97 @@
+package namespace
+
+import (
+	"context"
+
+	"github.com/authzed/spicedb/pkg/graph"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// RelationDependencies returns the relations, in the form `namespace#relation`, whose relationships
+// may be read when computing the given relation or permission. Subject relations and arrows are
+// followed into the definitions they reference, so a change to a relationship of any relation not
+// returned cannot change the computed result.
+func RelationDependencies(ctx context.Context, resolver Resolver, namespaceName string, relationName string) (map[string]struct{}, error) {
+	dependencies := make(map[string]struct{})
+	toVisit := []*core.RelationReference{{Namespace: namespaceName, Relation: relationName}}
+
+	for len(toVisit) > 0 {
+		current := toVisit[0]
+		toVisit = toVisit[1:]
+
+		key := tuple.StringRR(current)
+		if _, ok := dependencies[key]; ok {
+			continue
+		}
+		dependencies[key] = struct{}{}
+
+		nsDef, err := resolver.LookupNamespace(ctx, current.Namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		relation, ok := findRelation(nsDef, current.Relation)
+		if !ok {
+			return nil, NewRelationNotFoundErr(current.Namespace, current.Relation)
+		}
+
+		rewrite := relation.GetUsersetRewrite()
+		if rewrite == nil {
+			// Subjects of a relation with a subject relation are computed through that relation.
+			for _, allowed := range relation.GetTypeInformation().GetAllowedDirectRelations() {
+				if allowed.GetPublicWildcard() == nil && allowed.GetRelation() != tuple.Ellipsis {
+					toVisit = append(toVisit, &core.RelationReference{Namespace: allowed.Namespace, Relation: allowed.GetRelation()})
+				}
+			}
+			continue
+		}
+
+		var arrows []*core.TupleToUserset
+		_, err = graph.WalkRewrite(rewrite, func(childOneof *core.SetOperation_Child) interface{} {
+			switch child := childOneof.ChildType.(type) {
+			case *core.SetOperation_Child_ComputedUserset:
+				toVisit = append(toVisit, &core.RelationReference{Namespace: current.Namespace, Relation: child.ComputedUserset.Relation})
+			case *core.SetOperation_Child_TupleToUserset:
+				arrows = append(arrows, child.TupleToUserset)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// Arrows read the relationships of the tupleset, and then compute the relation on each of
+		// the subject types allowed on it which define the relation.
+		for _, arrow := range arrows {
+			toVisit = append(toVisit, &core.RelationReference{Namespace: current.Namespace, Relation: arrow.Tupleset.Relation})
+
+			tuplesetRelation, ok := findRelation(nsDef, arrow.Tupleset.Relation)
+			if !ok {
+				return nil, NewRelationNotFoundErr(current.Namespace, arrow.Tupleset.Relation)
+			}
+
+			for _, allowed := range tuplesetRelation.GetTypeInformation().GetAllowedDirectRelations() {
+				subjectDef, err := resolver.LookupNamespace(ctx, allowed.Namespace)
+				if err != nil {
+					return nil, err
+				}
+
+				if _, ok := findRelation(subjectDef, arrow.ComputedUserset.Relation); ok {
+					toVisit = append(toVisit, &core.RelationReference{Namespace: allowed.Namespace, Relation: arrow.ComputedUserset.Relation})
+				}
+			}
+		}
+	}
+
+	return dependencies, nil
+}
+
+func findRelation(nsDef *core.NamespaceDefinition, relationName string) (*core.Relation, bool) {
+	for _, relation := range nsDef.Relation {
+		if relation.Name == relationName {
+			return relation, true
+		}
+	}
+	return nil, false
+}
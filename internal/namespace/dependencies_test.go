@@ -0,0 +1,78 @@
+package namespace
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/maps"
+
+	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
+	"github.com/authzed/spicedb/pkg/schemadsl/input"
+)
+
+func TestRelationDependencies(t *testing.T) {
+	schema := `
+		definition user {}
+
+		definition group {
+			relation member: user | group#member
+			relation banned: user
+		}
+
+		definition folder {
+			relation parent: folder
+			relation viewer: user | group#member
+			permission view = viewer + parent->view
+		}
+
+		definition document {
+			relation parent: folder
+			relation owner: user
+			relation viewer: user | user:*
+			relation editor: user
+			permission edit = owner + editor
+			permission view = (viewer + edit + parent->view) - parent->banned
+		}
+	`
+
+	testCases := []struct {
+		name         string
+		resourceType string
+		relation     string
+		expected     []string
+	}{
+		{"relation", "document", "owner", []string{"document#owner"}},
+		{"permission", "document", "edit", []string{"document#edit", "document#editor", "document#owner"}},
+		{"subject relation", "group", "member", []string{"group#member"}},
+		{
+			"arrows",
+			"document",
+			"view",
+			[]string{
+				"document#view", "document#viewer", "document#edit", "document#editor", "document#owner", "document#parent",
+				"folder#view", "folder#viewer", "folder#parent", "group#member",
+			},
+		},
+	}
+
+	empty := ""
+	compiled, err := compiler.Compile(compiler.InputSchema{
+		Source:       input.Source("schema"),
+		SchemaString: schema,
+	}, &empty)
+	require.NoError(t, err)
+
+	resolver := ResolverForPredefinedDefinitions(PredefinedElements{
+		Namespaces: compiled.ObjectDefinitions,
+	})
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			dependencies, err := RelationDependencies(context.Background(), resolver, tc.resourceType, tc.relation)
+			require.NoError(t, err)
+			require.ElementsMatch(t, tc.expected, maps.Keys(dependencies))
+		})
+	}
+}
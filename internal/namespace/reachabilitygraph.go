@@ -3,9 +3,12 @@ package namespace
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strconv"
 	"sync"
 
+	"golang.org/x/exp/maps"
+
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 	"github.com/authzed/spicedb/pkg/tuple"
 )
@@ -211,7 +214,13 @@ func (rg *ReachabilityGraph) collectEntrypoints(
 	}
 
 	// Recursively collect over any reachability graphs for subjects with non-ellipsis relations.
-	for _, entrypointSet := range rrg.EntrypointsBySubjectRelation {
+	// NOTE: the keys are sorted to ensure the entrypoints are always collected in the same order,
+	// which is required for cursors over reachable resources.
+	keys := maps.Keys(rrg.EntrypointsBySubjectRelation)
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		entrypointSet := rrg.EntrypointsBySubjectRelation[key]
 		if entrypointSet.SubjectRelation != nil && entrypointSet.SubjectRelation.Relation != tuple.Ellipsis {
 			err := rg.collectEntrypoints(ctx, subjectType, entrypointSet.SubjectRelation, collected, encounteredRelations, reachabilityOption, entrypointLookupOption)
 			if err != nil {
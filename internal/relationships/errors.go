@@ -19,20 +19,20 @@ import (
 // allowed on relation.
 type ErrInvalidSubjectType struct {
 	error
-	update       *core.RelationTupleUpdate
+	relationship *core.RelationTuple
 	relationType *core.AllowedRelation
 }
 
 // NewInvalidSubjectTypeError constructs a new error for attempting to write an invalid subject type.
-func NewInvalidSubjectTypeError(update *core.RelationTupleUpdate, relationType *core.AllowedRelation) ErrInvalidSubjectType {
+func NewInvalidSubjectTypeError(relationship *core.RelationTuple, relationType *core.AllowedRelation) ErrInvalidSubjectType {
 	return ErrInvalidSubjectType{
 		error: fmt.Errorf(
 			"subjects of type `%s` are not allowed on relation `%s#%s`",
 			namespace.SourceForAllowedRelation(relationType),
-			update.Tuple.ResourceAndRelation.Namespace,
-			update.Tuple.ResourceAndRelation.Relation,
+			relationship.ResourceAndRelation.Namespace,
+			relationship.ResourceAndRelation.Relation,
 		),
-		update:       update,
+		relationship: relationship,
 		relationType: relationType,
 	}
 }
@@ -45,8 +45,8 @@ func (err ErrInvalidSubjectType) GRPCStatus() *status.Status {
 		spiceerrors.ForReason(
 			v1.ErrorReason_ERROR_REASON_INVALID_SUBJECT_TYPE,
 			map[string]string{
-				"definition_name": err.update.Tuple.ResourceAndRelation.Namespace,
-				"relation_name":   err.update.Tuple.ResourceAndRelation.Relation,
+				"definition_name": err.relationship.ResourceAndRelation.Namespace,
+				"relation_name":   err.relationship.ResourceAndRelation.Relation,
 				"subject_type":    namespace.SourceForAllowedRelation(err.relationType),
 			},
 		),
@@ -56,18 +56,18 @@ func (err ErrInvalidSubjectType) GRPCStatus() *status.Status {
 // ErrCannotWriteToPermission indicates that a write was attempted on a permission.
 type ErrCannotWriteToPermission struct {
 	error
-	update *core.RelationTupleUpdate
+	relationship *core.RelationTuple
 }
 
 // NewCannotWriteToPermissionError constructs a new error for attempting to write to a permission.
-func NewCannotWriteToPermissionError(update *core.RelationTupleUpdate) ErrCannotWriteToPermission {
+func NewCannotWriteToPermissionError(relationship *core.RelationTuple) ErrCannotWriteToPermission {
 	return ErrCannotWriteToPermission{
 		error: fmt.Errorf(
 			"cannot write a relationship to permission `%s` under definition `%s`",
-			update.Tuple.ResourceAndRelation.Relation,
-			update.Tuple.ResourceAndRelation.Namespace,
+			relationship.ResourceAndRelation.Relation,
+			relationship.ResourceAndRelation.Namespace,
 		),
-		update: update,
+		relationship: relationship,
 	}
 }
 
@@ -79,8 +79,8 @@ func (err ErrCannotWriteToPermission) GRPCStatus() *status.Status {
 		spiceerrors.ForReason(
 			v1.ErrorReason_ERROR_REASON_CANNOT_UPDATE_PERMISSION,
 			map[string]string{
-				"definition_name": err.update.Tuple.ResourceAndRelation.Namespace,
-				"permission_name": err.update.Tuple.ResourceAndRelation.Relation,
+				"definition_name": err.relationship.ResourceAndRelation.Namespace,
+				"permission_name": err.relationship.ResourceAndRelation.Relation,
 			},
 		),
 	)
@@ -89,18 +89,18 @@ func (err ErrCannotWriteToPermission) GRPCStatus() *status.Status {
 // ErrCaveatNotFound indicates that a caveat referenced in a relationship update was not found.
 type ErrCaveatNotFound struct {
 	error
-	update *core.RelationTupleUpdate
+	relationship *core.RelationTuple
 }
 
 // NewCaveatNotFoundError constructs a new caveat not found error.
-func NewCaveatNotFoundError(update *core.RelationTupleUpdate) ErrCaveatNotFound {
+func NewCaveatNotFoundError(relationship *core.RelationTuple) ErrCaveatNotFound {
 	return ErrCaveatNotFound{
 		error: fmt.Errorf(
 			"the caveat `%s` was not found for relationship `%s`",
-			update.Tuple.Caveat.CaveatName,
-			tuple.MustString(update.Tuple),
+			relationship.Caveat.CaveatName,
+			tuple.MustString(relationship),
 		),
-		update: update,
+		relationship: relationship,
 	}
 }
 
@@ -112,7 +112,7 @@ func (err ErrCaveatNotFound) GRPCStatus() *status.Status {
 		spiceerrors.ForReason(
 			v1.ErrorReason_ERROR_REASON_UNKNOWN_CAVEAT,
 			map[string]string{
-				"caveat_name": err.update.Tuple.Caveat.CaveatName,
+				"caveat_name": err.relationship.Caveat.CaveatName,
 			},
 		),
 	)
@@ -24,138 +24,171 @@ func ValidateRelationshipUpdates(
 	for _, update := range updates {
 		referencedNamespaceNames.Add(update.Tuple.ResourceAndRelation.Namespace)
 		referencedNamespaceNames.Add(update.Tuple.Subject.Namespace)
-		if hasNonEmptyCaveatContext(update) {
+		if hasNonEmptyCaveatContext(update.Tuple) {
 			referencedCaveatNamesWithContext.Add(update.Tuple.Caveat.CaveatName)
 		}
 	}
 
-	var referencedNamespaceMap map[string]*namespace.TypeSystem
-	var referencedCaveatMap map[string]*core.CaveatDefinition
+	referencedNamespaceMap, err := LoadNamespaceTypeSystems(ctx, reader, referencedNamespaceNames.AsSlice())
+	if err != nil {
+		return err
+	}
 
-	// Load namespaces.
-	if !referencedNamespaceNames.IsEmpty() {
-		foundNamespaces, err := reader.LookupNamespacesWithNames(ctx, referencedNamespaceNames.AsSlice())
-		if err != nil {
+	referencedCaveatMap, err := LoadCaveats(ctx, reader, referencedCaveatNamesWithContext.AsSlice())
+	if err != nil {
+		return err
+	}
+
+	// Validate each update's types.
+	for _, update := range updates {
+		if err := ValidateOneRelationship(referencedNamespaceMap, referencedCaveatMap, update.Tuple); err != nil {
 			return err
 		}
+	}
 
-		referencedNamespaceMap = make(map[string]*namespace.TypeSystem, len(foundNamespaces))
-		for _, nsDef := range foundNamespaces {
-			nts, err := namespace.NewNamespaceTypeSystem(nsDef.Definition, namespace.ResolverForDatastoreReader(reader))
-			if err != nil {
-				return err
-			}
+	return nil
+}
 
-			referencedNamespaceMap[nsDef.Definition.Name] = nts
-		}
+// LoadNamespaceTypeSystems loads and returns the type systems for the namespaces with the given
+// names, keyed by namespace name. Namespaces which do not exist are not included in the map.
+func LoadNamespaceTypeSystems(ctx context.Context, reader datastore.Reader, namespaceNames []string) (map[string]*namespace.TypeSystem, error) {
+	if len(namespaceNames) == 0 {
+		return map[string]*namespace.TypeSystem{}, nil
 	}
 
-	// Load caveats, if any.
-	if !referencedCaveatNamesWithContext.IsEmpty() {
-		foundCaveats, err := reader.LookupCaveatsWithNames(ctx, referencedCaveatNamesWithContext.AsSlice())
+	foundNamespaces, err := reader.LookupNamespacesWithNames(ctx, namespaceNames)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaceMap := make(map[string]*namespace.TypeSystem, len(foundNamespaces))
+	for _, nsDef := range foundNamespaces {
+		nts, err := namespace.NewNamespaceTypeSystem(nsDef.Definition, namespace.ResolverForDatastoreReader(reader))
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		referencedCaveatMap = make(map[string]*core.CaveatDefinition, len(foundCaveats))
-		for _, caveatDef := range foundCaveats {
-			referencedCaveatMap[caveatDef.Definition.Name] = caveatDef.Definition
-		}
+		namespaceMap[nsDef.Definition.Name] = nts
 	}
+	return namespaceMap, nil
+}
 
-	// Validate each update's types.
-	for _, update := range updates {
-		// Validate the IDs of the resource and subject.
-		if err := tuple.ValidateResourceID(update.Tuple.ResourceAndRelation.ObjectId); err != nil {
-			return err
-		}
+// LoadCaveats loads and returns the definitions of the caveats with the given names, keyed by
+// caveat name. Caveats which do not exist are not included in the map.
+func LoadCaveats(ctx context.Context, reader datastore.Reader, caveatNames []string) (map[string]*core.CaveatDefinition, error) {
+	if len(caveatNames) == 0 {
+		return map[string]*core.CaveatDefinition{}, nil
+	}
 
-		if err := tuple.ValidateSubjectID(update.Tuple.Subject.ObjectId); err != nil {
-			return err
-		}
+	foundCaveats, err := reader.LookupCaveatsWithNames(ctx, caveatNames)
+	if err != nil {
+		return nil, err
+	}
 
-		// Validate the namespace and relation for the resource.
-		resourceTS, ok := referencedNamespaceMap[update.Tuple.ResourceAndRelation.Namespace]
-		if !ok {
-			return namespace.NewNamespaceNotFoundErr(update.Tuple.ResourceAndRelation.Namespace)
-		}
+	caveatMap := make(map[string]*core.CaveatDefinition, len(foundCaveats))
+	for _, caveatDef := range foundCaveats {
+		caveatMap[caveatDef.Definition.Name] = caveatDef.Definition
+	}
+	return caveatMap, nil
+}
 
-		if !resourceTS.HasRelation(update.Tuple.ResourceAndRelation.Relation) {
-			return namespace.NewRelationNotFoundErr(update.Tuple.ResourceAndRelation.Namespace, update.Tuple.ResourceAndRelation.Relation)
-		}
+// ValidateOneRelationship validates a single relationship against the given namespace type
+// systems and caveat definitions, which must contain those referenced by the relationship.
+func ValidateOneRelationship(
+	namespaceMap map[string]*namespace.TypeSystem,
+	caveatMap map[string]*core.CaveatDefinition,
+	relationship *core.RelationTuple,
+) error {
+	// Validate the IDs of the resource and subject.
+	if err := tuple.ValidateResourceID(relationship.ResourceAndRelation.ObjectId); err != nil {
+		return err
+	}
 
-		// Validate the namespace and relation for the subject.
-		subjectTS, ok := referencedNamespaceMap[update.Tuple.Subject.Namespace]
-		if !ok {
-			return namespace.NewNamespaceNotFoundErr(update.Tuple.Subject.Namespace)
-		}
+	if err := tuple.ValidateSubjectID(relationship.Subject.ObjectId); err != nil {
+		return err
+	}
 
-		if update.Tuple.Subject.Relation != tuple.Ellipsis {
-			if !subjectTS.HasRelation(update.Tuple.Subject.Relation) {
-				return namespace.NewRelationNotFoundErr(update.Tuple.Subject.Namespace, update.Tuple.Subject.Relation)
-			}
-		}
+	// Validate the namespace and relation for the resource.
+	resourceTS, ok := namespaceMap[relationship.ResourceAndRelation.Namespace]
+	if !ok {
+		return namespace.NewNamespaceNotFoundErr(relationship.ResourceAndRelation.Namespace)
+	}
 
-		// Validate that the relationship is not writing to a permission.
-		if resourceTS.IsPermission(update.Tuple.ResourceAndRelation.Relation) {
-			return NewCannotWriteToPermissionError(update)
-		}
+	if !resourceTS.HasRelation(relationship.ResourceAndRelation.Relation) {
+		return namespace.NewRelationNotFoundErr(relationship.ResourceAndRelation.Namespace, relationship.ResourceAndRelation.Relation)
+	}
 
-		// Validate the subject against the allowed relation(s).
-		var relationToCheck *core.AllowedRelation
-		var caveat *core.AllowedCaveat
+	// Validate the namespace and relation for the subject.
+	subjectTS, ok := namespaceMap[relationship.Subject.Namespace]
+	if !ok {
+		return namespace.NewNamespaceNotFoundErr(relationship.Subject.Namespace)
+	}
 
-		if update.Tuple.Caveat != nil {
-			caveat = ns.AllowedCaveat(update.Tuple.Caveat.CaveatName)
+	if relationship.Subject.Relation != tuple.Ellipsis {
+		if !subjectTS.HasRelation(relationship.Subject.Relation) {
+			return namespace.NewRelationNotFoundErr(relationship.Subject.Namespace, relationship.Subject.Relation)
 		}
+	}
+
+	// Validate that the relationship is not writing to a permission.
+	if resourceTS.IsPermission(relationship.ResourceAndRelation.Relation) {
+		return NewCannotWriteToPermissionError(relationship)
+	}
+
+	// Validate the subject against the allowed relation(s).
+	var relationToCheck *core.AllowedRelation
+	var caveat *core.AllowedCaveat
 
-		if update.Tuple.Subject.ObjectId == tuple.PublicWildcard {
-			relationToCheck = ns.AllowedPublicNamespaceWithCaveat(update.Tuple.Subject.Namespace, caveat)
-		} else {
-			relationToCheck = ns.AllowedRelationWithCaveat(
-				update.Tuple.Subject.Namespace,
-				update.Tuple.Subject.Relation,
-				caveat)
+	if relationship.Caveat != nil {
+		caveat = ns.AllowedCaveat(relationship.Caveat.CaveatName)
+	}
+
+	if relationship.Subject.ObjectId == tuple.PublicWildcard {
+		relationToCheck = ns.AllowedPublicNamespaceWithCaveat(relationship.Subject.Namespace, caveat)
+	} else {
+		relationToCheck = ns.AllowedRelationWithCaveat(
+			relationship.Subject.Namespace,
+			relationship.Subject.Relation,
+			caveat)
+	}
+
+	isAllowed, err := resourceTS.HasAllowedRelation(
+		relationship.ResourceAndRelation.Relation,
+		relationToCheck,
+	)
+	if err != nil {
+		return err
+	}
+
+	if isAllowed != namespace.AllowedRelationValid {
+		return NewInvalidSubjectTypeError(relationship, relationToCheck)
+	}
+
+	// Validate caveat and its context, if applicable.
+	if hasNonEmptyCaveatContext(relationship) {
+		caveat, ok := caveatMap[relationship.Caveat.CaveatName]
+		if !ok {
+			// Should ideally never happen since the caveat is type checked above, but just in case.
+			return NewCaveatNotFoundError(relationship)
 		}
 
-		isAllowed, err := resourceTS.HasAllowedRelation(
-			update.Tuple.ResourceAndRelation.Relation,
-			relationToCheck,
+		// Verify that the provided context information matches the types of the parameters defined.
+		_, err := caveats.ConvertContextToParameters(
+			relationship.Caveat.Context.AsMap(),
+			caveat.ParameterTypes,
+			caveats.ErrorForUnknownParameters,
 		)
 		if err != nil {
 			return err
 		}
-
-		if isAllowed != namespace.AllowedRelationValid {
-			return NewInvalidSubjectTypeError(update, relationToCheck)
-		}
-
-		// Validate caveat and its context, if applicable.
-		if hasNonEmptyCaveatContext(update) {
-			caveat, ok := referencedCaveatMap[update.Tuple.Caveat.CaveatName]
-			if !ok {
-				// Should ideally never happen since the caveat is type checked above, but just in case.
-				return NewCaveatNotFoundError(update)
-			}
-
-			// Verify that the provided context information matches the types of the parameters defined.
-			_, err := caveats.ConvertContextToParameters(
-				update.Tuple.Caveat.Context.AsMap(),
-				caveat.ParameterTypes,
-				caveats.ErrorForUnknownParameters,
-			)
-			if err != nil {
-				return err
-			}
-		}
 	}
 
 	return nil
 }
 
-func hasNonEmptyCaveatContext(update *core.RelationTupleUpdate) bool {
-	return update.Tuple.Caveat != nil &&
-		update.Tuple.Caveat.CaveatName != "" &&
-		update.Tuple.Caveat.Context != nil &&
-		len(update.Tuple.Caveat.Context.GetFields()) > 0
+func hasNonEmptyCaveatContext(relationship *core.RelationTuple) bool {
+	return relationship.Caveat != nil &&
+		relationship.Caveat.CaveatName != "" &&
+		relationship.Caveat.Context != nil &&
+		len(relationship.Caveat.Context.GetFields()) > 0
 }
@@ -53,9 +53,13 @@ func (ds *dispatchServer) DispatchExpand(ctx context.Context, req *dispatchv1.Di
 	return resp, rewriteGraphError(ctx, err)
 }
 
-func (ds *dispatchServer) DispatchLookup(ctx context.Context, req *dispatchv1.DispatchLookupRequest) (*dispatchv1.DispatchLookupResponse, error) {
-	resp, err := ds.localDispatch.DispatchLookup(ctx, req)
-	return resp, rewriteGraphError(ctx, err)
+func (ds *dispatchServer) DispatchLookup(
+	req *dispatchv1.DispatchLookupRequest,
+	resp dispatchv1.DispatchService_DispatchLookupServer,
+) error {
+	err := ds.localDispatch.DispatchLookup(req,
+		dispatch.WrapGRPCStream[*dispatchv1.DispatchLookupResponse](resp))
+	return rewriteGraphError(resp.Context(), err)
 }
 
 func (ds *dispatchServer) DispatchReachableResources(
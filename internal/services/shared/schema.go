@@ -14,6 +14,10 @@ import (
 	"github.com/authzed/spicedb/pkg/util"
 )
 
+// maxBlockingRelationshipsCount is the maximum number of blocking relationships counted for a
+// single schema delta, so that diffing schemas never scans entire namespaces.
+const maxBlockingRelationshipsCount = 1000
+
 // ValidatedSchemaChanges is a set of validated schema changes that can be applied to the datastore.
 type ValidatedSchemaChanges struct {
 	compiled          *compiler.CompiledSchema
@@ -224,6 +228,227 @@ func ApplySchemaChangesOverExisting(
 	}, nil
 }
 
+// SchemaDelta is a single change that applying validated schema changes would make to the existing
+// schema.
+type SchemaDelta struct {
+	// DefinitionName is the name of the object or caveat definition changed.
+	DefinitionName string
+
+	// Type is the type of the change, as the namespace or caveat delta type.
+	Type string
+
+	// RelationName is the name of the relation or permission changed, if any.
+	RelationName string
+
+	// AllowedType is the allowed type added to or removed from the relation, if any.
+	AllowedType *core.AllowedRelation
+
+	// ParameterName is the name of the caveat parameter changed, if any.
+	ParameterName string
+
+	// BlockingRelationshipsCount is the number of existing relationships which prevent the change
+	// from being applied, up to maxBlockingRelationshipsCount.
+	BlockingRelationshipsCount uint64
+
+	// BlockingRelationshipsCountCapped is whether more blocking relationships exist than are
+	// counted in BlockingRelationshipsCount.
+	BlockingRelationshipsCountCapped bool
+
+	// Blocked is whether applying the change would fail, either because of existing relationships
+	// or because the change is never allowed.
+	Blocked bool
+}
+
+// DiffSchemaChanges computes, without applying them, the changes that the validated schema changes
+// would make to the schema read by the reader, along with the number of existing relationships
+// which would prevent each change from being applied.
+func DiffSchemaChanges(ctx context.Context, reader datastore.Reader, validated *ValidatedSchemaChanges) ([]SchemaDelta, error) {
+	existingCaveats, err := reader.ListAllCaveats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	existingObjectDefs, err := reader.ListAllNamespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	existingCaveatDefMap := make(map[string]*core.CaveatDefinition, len(existingCaveats))
+	for _, existingCaveat := range existingCaveats {
+		existingCaveatDefMap[existingCaveat.Definition.Name] = existingCaveat.Definition
+	}
+
+	existingObjectDefMap := make(map[string]*core.NamespaceDefinition, len(existingObjectDefs))
+	for _, existingDef := range existingObjectDefs {
+		existingObjectDefMap[existingDef.Definition.Name] = existingDef.Definition
+	}
+
+	// Pair each definition with its existing definition. Removed definitions are paired with nil,
+	// unless the changes are additive only, in which case they are not removed.
+	type caveatPair struct{ existing, updated *core.CaveatDefinition }
+	caveatPairs := make([]caveatPair, 0, len(validated.compiled.CaveatDefinitions))
+	for _, caveatDef := range validated.compiled.CaveatDefinitions {
+		caveatPairs = append(caveatPairs, caveatPair{existingCaveatDefMap[caveatDef.Name], caveatDef})
+	}
+
+	type objectDefPair struct{ existing, updated *core.NamespaceDefinition }
+	objectDefPairs := make([]objectDefPair, 0, len(validated.compiled.ObjectDefinitions))
+	for _, nsdef := range validated.compiled.ObjectDefinitions {
+		objectDefPairs = append(objectDefPairs, objectDefPair{existingObjectDefMap[nsdef.Name], nsdef})
+	}
+
+	if !validated.additiveOnly {
+		for _, existingCaveat := range existingCaveats {
+			if !validated.newCaveatDefNames.Has(existingCaveat.Definition.Name) {
+				caveatPairs = append(caveatPairs, caveatPair{existingCaveat.Definition, nil})
+			}
+		}
+
+		for _, existingDef := range existingObjectDefs {
+			if !validated.newObjectDefNames.Has(existingDef.Definition.Name) {
+				objectDefPairs = append(objectDefPairs, objectDefPair{existingDef.Definition, nil})
+			}
+		}
+	}
+
+	var deltas []SchemaDelta
+	for _, pair := range caveatPairs {
+		diff, err := caveats.DiffCaveats(pair.existing, pair.updated)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, delta := range diff.Deltas() {
+			deltas = append(deltas, SchemaDelta{
+				DefinitionName: definitionName(pair.existing, pair.updated),
+				Type:           string(delta.Type),
+				ParameterName:  delta.ParameterName,
+				Blocked:        delta.Type == caveats.RemovedParameter || delta.Type == caveats.ParameterTypeChanged,
+			})
+		}
+	}
+
+	for _, pair := range objectDefPairs {
+		diff, err := namespace.DiffNamespaces(pair.existing, pair.updated)
+		if err != nil {
+			return nil, err
+		}
+
+		nsName := definitionName(pair.existing, pair.updated)
+		for _, delta := range diff.Deltas() {
+			count, capped, err := countBlockingRelationships(ctx, reader, nsName, delta, maxBlockingRelationshipsCount)
+			if err != nil {
+				return nil, err
+			}
+
+			deltas = append(deltas, SchemaDelta{
+				DefinitionName:                   nsName,
+				Type:                             string(delta.Type),
+				RelationName:                     delta.RelationName,
+				AllowedType:                      delta.AllowedType,
+				BlockingRelationshipsCount:       count,
+				BlockingRelationshipsCountCapped: capped,
+				Blocked:                          count > 0,
+			})
+		}
+	}
+
+	return deltas, nil
+}
+
+func definitionName[T interface{ GetName() string }](existing, updated T) string {
+	if name := updated.GetName(); name != "" {
+		return name
+	}
+	return existing.GetName()
+}
+
+// countBlockingRelationships returns the number of existing relationships which prevent the
+// namespace delta from being applied, counting at most limit of them. If more exist, the limit is
+// returned and capped is set.
+func countBlockingRelationships(ctx context.Context, reader datastore.Reader, namespaceName string, delta namespace.Delta, limit uint64) (count uint64, capped bool, err error) {
+	switch delta.Type {
+	case namespace.NamespaceRemoved:
+		return countRelationships(
+			ctx,
+			reader,
+			datastore.RelationshipsFilter{ResourceType: namespaceName},
+			&datastore.SubjectsFilter{SubjectType: namespaceName},
+			limit,
+		)
+
+	case namespace.RemovedRelation:
+		return countRelationships(
+			ctx,
+			reader,
+			datastore.RelationshipsFilter{
+				ResourceType:             namespaceName,
+				OptionalResourceRelation: delta.RelationName,
+			},
+			&datastore.SubjectsFilter{
+				SubjectType: namespaceName,
+				RelationFilter: datastore.SubjectRelationFilter{
+					NonEllipsisRelation: delta.RelationName,
+				},
+			},
+			limit,
+		)
+
+	case namespace.RelationAllowedTypeRemoved:
+		return countRelationships(ctx, reader, allowedTypeRelationshipsFilter(namespaceName, delta), nil, limit)
+
+	default:
+		return 0, false, nil
+	}
+}
+
+// countRelationships counts the relationships matching the filter and, if given, those matching
+// the subjects filter which do not also match the filter. At most limit relationships are counted;
+// if more exist, the limit is returned and capped is set.
+func countRelationships(
+	ctx context.Context,
+	reader datastore.Reader,
+	filter datastore.RelationshipsFilter,
+	subjectsFilter *datastore.SubjectsFilter,
+	limit uint64,
+) (uint64, bool, error) {
+	var count uint64
+	countIter := func(it datastore.RelationshipIterator, err error, skip func(*core.RelationTuple) bool) error {
+		if err != nil {
+			return err
+		}
+		defer it.Close()
+
+		for tpl := it.Next(); tpl != nil && count <= limit; tpl = it.Next() {
+			if !skip(tpl) {
+				count++
+			}
+		}
+		return it.Err()
+	}
+
+	queryLimit := limit + 1
+	it, err := reader.QueryRelationships(ctx, filter, options.WithLimit(&queryLimit))
+	if err := countIter(it, err, func(*core.RelationTuple) bool { return false }); err != nil {
+		return 0, false, err
+	}
+
+	if subjectsFilter != nil && count <= limit {
+		// Every skipped relationship was already counted above, so reading that many more than
+		// the remaining limit is enough to tell whether the limit is exceeded.
+		reverseLimit := count + limit + 1
+		it, err := reader.ReverseQueryRelationships(ctx, *subjectsFilter, options.WithReverseLimit(&reverseLimit))
+		if err := countIter(it, err, filter.Test); err != nil {
+			return 0, false, err
+		}
+	}
+
+	if count > limit {
+		return limit, true, nil
+	}
+	return count, false, nil
+}
+
 // sanityCheckCaveatChanges ensures that a caveat definition being written does not break
 // the types of the parameters that may already exist on relationships.
 func sanityCheckCaveatChanges(
@@ -338,36 +563,9 @@ func sanityCheckNamespaceChanges(
 			}
 
 		case namespace.RelationAllowedTypeRemoved:
-			var optionalSubjectIds []string
-			var relationFilter datastore.SubjectRelationFilter
-			optionalCaveatName := ""
-
-			if delta.AllowedType.GetPublicWildcard() != nil {
-				optionalSubjectIds = []string{tuple.PublicWildcard}
-			} else {
-				relationFilter = datastore.SubjectRelationFilter{
-					NonEllipsisRelation: delta.AllowedType.GetRelation(),
-				}
-			}
-
-			if delta.AllowedType.GetRequiredCaveat() != nil {
-				optionalCaveatName = delta.AllowedType.GetRequiredCaveat().CaveatName
-			}
-
 			qyr, qyrErr := rwt.QueryRelationships(
 				ctx,
-				datastore.RelationshipsFilter{
-					ResourceType:             nsdef.Name,
-					OptionalResourceRelation: delta.RelationName,
-					OptionalSubjectsSelectors: []datastore.SubjectsSelector{
-						{
-							OptionalSubjectType: delta.AllowedType.Namespace,
-							OptionalSubjectIds:  optionalSubjectIds,
-							RelationFilter:      relationFilter,
-						},
-					},
-					OptionalCaveatName: optionalCaveatName,
-				},
+				allowedTypeRelationshipsFilter(nsdef.Name, delta),
 				options.WithLimit(options.LimitOne),
 			)
 			err = errorIfTupleIteratorReturnsTuples(
@@ -385,6 +583,39 @@ func sanityCheckNamespaceChanges(
 	return diff, nil
 }
 
+// allowedTypeRelationshipsFilter returns a filter matching the relationships written with the allowed
+// type of the delta, on the relation of the delta.
+func allowedTypeRelationshipsFilter(namespaceName string, delta namespace.Delta) datastore.RelationshipsFilter {
+	var optionalSubjectIds []string
+	var relationFilter datastore.SubjectRelationFilter
+	optionalCaveatName := ""
+
+	if delta.AllowedType.GetPublicWildcard() != nil {
+		optionalSubjectIds = []string{tuple.PublicWildcard}
+	} else {
+		relationFilter = datastore.SubjectRelationFilter{
+			NonEllipsisRelation: delta.AllowedType.GetRelation(),
+		}
+	}
+
+	if delta.AllowedType.GetRequiredCaveat() != nil {
+		optionalCaveatName = delta.AllowedType.GetRequiredCaveat().CaveatName
+	}
+
+	return datastore.RelationshipsFilter{
+		ResourceType:             namespaceName,
+		OptionalResourceRelation: delta.RelationName,
+		OptionalSubjectsSelectors: []datastore.SubjectsSelector{
+			{
+				OptionalSubjectType: delta.AllowedType.Namespace,
+				OptionalSubjectIds:  optionalSubjectIds,
+				RelationFilter:      relationFilter,
+			},
+		},
+		OptionalCaveatName: optionalCaveatName,
+	}
+}
+
 // errorIfTupleIteratorReturnsTuples takes a tuple iterator and any error that was generated
 // when the original iterator was created, and returns an error if iterator contains any tuples.
 func errorIfTupleIteratorReturnsTuples(_ context.Context, qy datastore.RelationshipIterator, qyErr error, message string, args ...interface{}) error {
@@ -7,10 +7,13 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/namespace"
 	"github.com/authzed/spicedb/internal/testfixtures"
 	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
 	"github.com/authzed/spicedb/pkg/schemadsl/input"
+	"github.com/authzed/spicedb/pkg/tuple"
 )
 
 func TestApplySchemaChanges(t *testing.T) {
@@ -63,3 +66,130 @@ func TestApplySchemaChanges(t *testing.T) {
 	})
 	require.NoError(err)
 }
+
+func TestDiffSchemaChanges(t *testing.T) {
+	require := require.New(t)
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	ds, revision := testfixtures.DatastoreFromSchemaAndTestRelationships(rawDS, `
+		definition user {}
+
+		definition folder {}
+
+		definition document {
+			relation viewer: user | user:*
+			relation editor: user
+			permission view = viewer
+		}
+
+		caveat hasFortyTwo(value int) {
+          value == 42
+        }
+	`, []*core.RelationTuple{
+		tuple.MustParse("document:firstdoc#viewer@user:tom"),
+		tuple.MustParse("document:firstdoc#viewer@user:*"),
+		tuple.MustParse("document:firstdoc#editor@user:fred"),
+		tuple.MustParse("document:seconddoc#editor@user:tom"),
+	}, require)
+
+	emptyDefaultPrefix := ""
+	compiled, err := compiler.Compile(compiler.InputSchema{
+		Source: input.Source("schema"),
+		SchemaString: `
+			definition user {}
+
+			definition document {
+				relation viewer: user
+				relation owner: user
+				permission view = viewer + owner
+			}
+
+			caveat hasFortyTwo(value string) {
+			  value == "42"
+			}
+		`,
+	}, &emptyDefaultPrefix)
+	require.NoError(err)
+
+	validated, err := ValidateSchemaChanges(context.Background(), compiled, false)
+	require.NoError(err)
+
+	deltas, err := DiffSchemaChanges(context.Background(), ds.SnapshotReader(revision), validated)
+	require.NoError(err)
+
+	for i := range deltas {
+		deltas[i].AllowedType = nil
+	}
+
+	require.ElementsMatch([]SchemaDelta{
+		{DefinitionName: "hasFortyTwo", Type: "parameter-type-changed", ParameterName: "value", Blocked: true},
+		{DefinitionName: "hasFortyTwo", Type: "expression-may-have-changed"},
+		{DefinitionName: "document", Type: "added-relation", RelationName: "owner"},
+		{DefinitionName: "document", Type: "removed-relation", RelationName: "editor", BlockingRelationshipsCount: 2, Blocked: true},
+		{DefinitionName: "document", Type: "relation-allowed-type-removed", RelationName: "viewer", BlockingRelationshipsCount: 1, Blocked: true},
+		{DefinitionName: "document", Type: "changed-permission-implementation", RelationName: "view"},
+		{DefinitionName: "folder", Type: "namespace-removed"},
+	}, deltas)
+
+	// Nothing is removed if the changes are additive only.
+	validated, err = ValidateSchemaChanges(context.Background(), compiled, true)
+	require.NoError(err)
+
+	deltas, err = DiffSchemaChanges(context.Background(), ds.SnapshotReader(revision), validated)
+	require.NoError(err)
+	for _, delta := range deltas {
+		require.NotEqual("folder", delta.DefinitionName)
+	}
+}
+
+func TestCountBlockingRelationshipsCapped(t *testing.T) {
+	require := require.New(t)
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	ds, revision := testfixtures.DatastoreFromSchemaAndTestRelationships(rawDS, `
+		definition user {}
+
+		definition folder {
+			relation parent: folder
+			relation viewer: user
+		}
+
+		definition document {
+			relation folder: folder
+		}
+	`, []*core.RelationTuple{
+		tuple.MustParse("document:readme#folder@folder:first"),
+		tuple.MustParse("folder:first#parent@folder:root"),
+		tuple.MustParse("folder:second#parent@folder:root"),
+		tuple.MustParse("folder:first#viewer@user:tom"),
+		tuple.MustParse("folder:second#viewer@user:tom"),
+		tuple.MustParse("folder:third#viewer@user:tom"),
+	}, require)
+	reader := ds.SnapshotReader(revision)
+
+	tcs := []struct {
+		name           string
+		delta          namespace.Delta
+		limit          uint64
+		expectedCount  uint64
+		expectedCapped bool
+	}{
+		{"namespace removed under limit", namespace.Delta{Type: namespace.NamespaceRemoved}, 6, 6, false},
+		{"namespace removed capped by subjects", namespace.Delta{Type: namespace.NamespaceRemoved}, 5, 5, true},
+		{"namespace removed capped", namespace.Delta{Type: namespace.NamespaceRemoved}, 2, 2, true},
+		{"relation removed under limit", namespace.Delta{Type: namespace.RemovedRelation, RelationName: "viewer"}, 3, 3, false},
+		{"relation removed capped", namespace.Delta{Type: namespace.RemovedRelation, RelationName: "viewer"}, 1, 1, true},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			count, capped, err := countBlockingRelationships(context.Background(), reader, "folder", tc.delta, tc.limit)
+			require.NoError(err)
+			require.Equal(tc.expectedCount, count)
+			require.Equal(tc.expectedCapped, capped)
+		})
+	}
+}
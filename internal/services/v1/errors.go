@@ -18,6 +18,7 @@ import (
 	"github.com/authzed/spicedb/internal/services/shared"
 	"github.com/authzed/spicedb/internal/sharederrors"
 	"github.com/authzed/spicedb/pkg/datastore"
+	experimentalv1 "github.com/authzed/spicedb/pkg/proto/experimental/v1"
 	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
 	"github.com/authzed/spicedb/pkg/spiceerrors"
 	"github.com/authzed/spicedb/pkg/tuple"
@@ -98,6 +99,119 @@ func NewExceedsMaximumPreconditionsErr(preconditionCount uint16, maxCountAllowed
 	}
 }
 
+// ErrExceedsMaximumLimit occurs when a limit that is too large is given to a call.
+type ErrExceedsMaximumLimit struct {
+	error
+	providedLimit   uint64
+	maxLimitAllowed uint64
+}
+
+// MarshalZerologObject implements zerolog object marshalling.
+func (err ErrExceedsMaximumLimit) MarshalZerologObject(e *zerolog.Event) {
+	e.Err(err.error).Uint64("providedLimit", err.providedLimit).Uint64("maxLimitAllowed", err.maxLimitAllowed)
+}
+
+// GRPCStatus implements retrieving the gRPC status for the error.
+func (err ErrExceedsMaximumLimit) GRPCStatus() *status.Status {
+	return spiceerrors.WithCodeAndDetails(
+		err,
+		codes.InvalidArgument,
+		spiceerrors.ForReason(
+			v1.ErrorReason_ERROR_REASON_UNSPECIFIED,
+			map[string]string{
+				"limit_provided":        strconv.FormatUint(err.providedLimit, 10),
+				"maximum_limit_allowed": strconv.FormatUint(err.maxLimitAllowed, 10),
+			},
+		),
+	)
+}
+
+// NewExceedsMaximumLimitErr creates a new error representing that the limit specified was too large.
+func NewExceedsMaximumLimitErr(providedLimit uint64, maxLimitAllowed uint64) ErrExceedsMaximumLimit {
+	return ErrExceedsMaximumLimit{
+		error:           fmt.Errorf("provided limit %d is greater than maximum allowed of %d", providedLimit, maxLimitAllowed),
+		providedLimit:   providedLimit,
+		maxLimitAllowed: maxLimitAllowed,
+	}
+}
+
+// ErrExceedsMaximumChecks occurs when too many checks are given to a call.
+type ErrExceedsMaximumChecks struct {
+	error
+	checkCount      uint64
+	maxCountAllowed uint64
+}
+
+// MarshalZerologObject implements zerolog object marshalling.
+func (err ErrExceedsMaximumChecks) MarshalZerologObject(e *zerolog.Event) {
+	e.Err(err.error).Uint64("checkCount", err.checkCount).Uint64("maxCountAllowed", err.maxCountAllowed)
+}
+
+// GRPCStatus implements retrieving the gRPC status for the error.
+func (err ErrExceedsMaximumChecks) GRPCStatus() *status.Status {
+	return spiceerrors.WithCodeAndDetails(
+		err,
+		codes.InvalidArgument,
+		spiceerrors.ForReason(
+			v1.ErrorReason_ERROR_REASON_UNSPECIFIED,
+			map[string]string{
+				"check_count":            strconv.FormatUint(err.checkCount, 10),
+				"maximum_checks_allowed": strconv.FormatUint(err.maxCountAllowed, 10),
+			},
+		),
+	)
+}
+
+// NewExceedsMaximumChecksErr creates a new error representing that too many checks were given to a call.
+func NewExceedsMaximumChecksErr(checkCount uint64, maxCountAllowed uint64) ErrExceedsMaximumChecks {
+	return ErrExceedsMaximumChecks{
+		error:           fmt.Errorf("check count of %d is greater than maximum allowed of %d", checkCount, maxCountAllowed),
+		checkCount:      checkCount,
+		maxCountAllowed: maxCountAllowed,
+	}
+}
+
+// ErrCouldNotTransactionallyDelete occurs when a deletion with a limit, which does not allow
+// partial deletions, matches more relationships than the limit.
+type ErrCouldNotTransactionallyDelete struct {
+	error
+	limit  uint32
+	filter *v1.RelationshipFilter
+}
+
+// MarshalZerologObject implements zerolog object marshalling.
+func (err ErrCouldNotTransactionallyDelete) MarshalZerologObject(e *zerolog.Event) {
+	e.Err(err.error).Uint32("limit", err.limit).Interface("filter", err.filter)
+}
+
+// GRPCStatus implements retrieving the gRPC status for the error.
+func (err ErrCouldNotTransactionallyDelete) GRPCStatus() *status.Status {
+	return spiceerrors.WithCodeAndDetails(
+		err,
+		codes.FailedPrecondition,
+		spiceerrors.ForReason(
+			v1.ErrorReason_ERROR_REASON_UNSPECIFIED,
+			map[string]string{
+				"limit":         strconv.Itoa(int(err.limit)),
+				"resource_type": err.filter.ResourceType,
+			},
+		),
+	)
+}
+
+// NewCouldNotTransactionallyDeleteErr constructs a new error representing that a deletion
+// matched more relationships than its limit allows.
+func NewCouldNotTransactionallyDeleteErr(filter *v1.RelationshipFilter, limit uint32) ErrCouldNotTransactionallyDelete {
+	return ErrCouldNotTransactionallyDelete{
+		error: fmt.Errorf(
+			"found more than %d relationships to be deleted and partial deletion was not requested",
+			limit,
+		),
+		limit:  limit,
+		filter: filter,
+	}
+}
+
 // ErrPreconditionFailed occurs when the precondition to a write tuple call does not match.
 type ErrPreconditionFailed struct {
 	error
@@ -119,30 +233,44 @@ func NewPreconditionFailedErr(precondition *v1.Precondition) error {
 
 // GRPCStatus implements retrieving the gRPC status for the error.
 func (err ErrPreconditionFailed) GRPCStatus() *status.Status {
-	metadata := map[string]string{
-		"precondition_resource_type": err.precondition.Filter.ResourceType,
-		"precondition_operation":     v1.Precondition_Operation_name[int32(err.precondition.Operation)],
-	}
+	metadata := preconditionFilterMetadata(err.precondition.Filter)
+	metadata["precondition_operation"] = v1.Precondition_Operation_name[int32(err.precondition.Operation)]
 
-	if err.precondition.Filter.OptionalResourceId != "" {
-		metadata["precondition_resource_id"] = err.precondition.Filter.OptionalResourceId
-	}
-
-	if err.precondition.Filter.OptionalRelation != "" {
-		metadata["precondition_relation"] = err.precondition.Filter.OptionalRelation
-	}
+	return spiceerrors.WithCodeAndDetails(
+		err,
+		codes.FailedPrecondition,
+		spiceerrors.ForReason(
+			v1.ErrorReason_ERROR_REASON_WRITE_OR_DELETE_PRECONDITION_FAILURE,
+			metadata,
+		),
+	)
+}
 
-	if err.precondition.Filter.OptionalSubjectFilter != nil {
-		metadata["precondition_subject_type"] = err.precondition.Filter.OptionalSubjectFilter.SubjectType
+// ErrUnchangedSincePreconditionFailed occurs when relationships matching the filter of an
+// unchanged since precondition have changed since its revision.
+type ErrUnchangedSincePreconditionFailed struct {
+	error
+	precondition *experimentalv1.UnchangedSincePrecondition
+}
 
-		if err.precondition.Filter.OptionalSubjectFilter.OptionalSubjectId != "" {
-			metadata["precondition_subject_id"] = err.precondition.Filter.OptionalSubjectFilter.OptionalSubjectId
-		}
+// MarshalZerologObject implements zerolog object marshalling.
+func (err ErrUnchangedSincePreconditionFailed) MarshalZerologObject(e *zerolog.Event) {
+	e.Err(err.error).Interface("precondition", err.precondition)
+}
 
-		if err.precondition.Filter.OptionalSubjectFilter.OptionalRelation != nil {
-			metadata["precondition_subject_relation"] = err.precondition.Filter.OptionalSubjectFilter.OptionalRelation.Relation
-		}
+// NewUnchangedSincePreconditionFailedErr constructs a new unchanged since precondition failed
+// error.
+func NewUnchangedSincePreconditionFailedErr(precondition *experimentalv1.UnchangedSincePrecondition) error {
+	return ErrUnchangedSincePreconditionFailed{
+		error:        fmt.Errorf("relationships matching precondition `%s` have changed", precondition),
+		precondition: precondition,
 	}
+}
+
+// GRPCStatus implements retrieving the gRPC status for the error.
+func (err ErrUnchangedSincePreconditionFailed) GRPCStatus() *status.Status {
+	metadata := preconditionFilterMetadata(err.precondition.Filter)
+	metadata["precondition_unchanged_since"] = err.precondition.UnchangedSince.Token
 
 	return spiceerrors.WithCodeAndDetails(
 		err,
@@ -154,6 +282,34 @@ func (err ErrPreconditionFailed) GRPCStatus() *status.Status {
 	)
 }
 
+func preconditionFilterMetadata(filter *v1.RelationshipFilter) map[string]string {
+	metadata := map[string]string{
+		"precondition_resource_type": filter.ResourceType,
+	}
+
+	if filter.OptionalResourceId != "" {
+		metadata["precondition_resource_id"] = filter.OptionalResourceId
+	}
+
+	if filter.OptionalRelation != "" {
+		metadata["precondition_relation"] = filter.OptionalRelation
+	}
+
+	if filter.OptionalSubjectFilter != nil {
+		metadata["precondition_subject_type"] = filter.OptionalSubjectFilter.SubjectType
+
+		if filter.OptionalSubjectFilter.OptionalSubjectId != "" {
+			metadata["precondition_subject_id"] = filter.OptionalSubjectFilter.OptionalSubjectId
+		}
+
+		if filter.OptionalSubjectFilter.OptionalRelation != nil {
+			metadata["precondition_subject_relation"] = filter.OptionalSubjectFilter.OptionalRelation.Relation
+		}
+	}
+
+	return metadata
+}
+
 // ErrDuplicateRelationshipError indicates that an update was attempted on the same relationship.
 type ErrDuplicateRelationshipError struct {
 	error
@@ -221,6 +377,8 @@ func rewriteError(ctx context.Context, err error) error {
 		return shared.ErrServiceReadOnly
 	case errors.As(err, &datastore.ErrCaveatNameNotFound{}):
 		return spiceerrors.WithCodeAndReason(err, codes.FailedPrecondition, v1.ErrorReason_ERROR_REASON_UNKNOWN_CAVEAT)
+	case errors.As(err, &datastore.ErrIdempotencyKeyReused{}):
+		return status.Errorf(codes.FailedPrecondition, "%s", err)
 	case errors.As(err, &datastore.ErrWatchDisabled{}):
 		return status.Errorf(codes.FailedPrecondition, "%s", err)
 
@@ -0,0 +1,1346 @@
+package v1
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	grpcvalidate "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/validator"
+	"github.com/jzelinskie/stringz"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/authzed/spicedb/internal/datasets"
+	"github.com/authzed/spicedb/internal/dispatch"
+	dispatchgraph "github.com/authzed/spicedb/internal/dispatch/graph"
+	"github.com/authzed/spicedb/internal/graph/computed"
+	"github.com/authzed/spicedb/internal/middleware"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/middleware/handwrittenvalidation"
+	"github.com/authzed/spicedb/internal/middleware/streamtimeout"
+	"github.com/authzed/spicedb/internal/middleware/usagemetrics"
+	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/internal/relationships"
+	"github.com/authzed/spicedb/internal/services/shared"
+	"github.com/authzed/spicedb/pkg/cursor"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/options"
+	"github.com/authzed/spicedb/pkg/datastore/pagination"
+	"github.com/authzed/spicedb/pkg/graph"
+	"github.com/authzed/spicedb/pkg/middleware/consistency"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	dispatchv1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	experimentalv1 "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+	"github.com/authzed/spicedb/pkg/spiceerrors"
+	"github.com/authzed/spicedb/pkg/tuple"
+	"github.com/authzed/spicedb/pkg/util"
+	"github.com/authzed/spicedb/pkg/zedtoken"
+)
+
+// maxBulkCheckConcurrency is the maximum number of dispatches issued concurrently for a single
+// BulkCheckPermission call.
+const maxBulkCheckConcurrency = 10
+
+// NewExperimentalServer creates an ExperimentalServiceServer instance.
+func NewExperimentalServer(dispatch dispatch.Dispatcher, config PermissionsServerConfig) experimentalv1.ExperimentalServiceServer {
+	configWithDefaults := PermissionsServerConfig{
+		MaxPreconditionsCount:       defaultIfZero(config.MaxPreconditionsCount, 1000),
+		MaxUpdatesPerWrite:          defaultIfZero(config.MaxUpdatesPerWrite, 1000),
+		MaximumAPIDepth:             defaultIfZero(config.MaximumAPIDepth, 50),
+		StreamingAPITimeout:         defaultIfZero(config.StreamingAPITimeout, 30*time.Second),
+		MaxCaveatContextSize:        config.MaxCaveatContextSize,
+		MaxDatastoreReadPageSize:    defaultIfZero(config.MaxDatastoreReadPageSize, 1_000),
+		MaxDeleteRelationshipsLimit: defaultIfZero(config.MaxDeleteRelationshipsLimit, 1_000),
+		MaxBulkCheckItems:           defaultIfZero(config.MaxBulkCheckItems, 1_000),
+	}
+
+	return &experimentalServer{
+		dispatch:    dispatch,
+		config:      configWithDefaults,
+		permissions: &permissionServer{dispatch: dispatch, config: configWithDefaults},
+		WithServiceSpecificInterceptors: shared.WithServiceSpecificInterceptors{
+			Unary: middleware.ChainUnaryServer(
+				grpcvalidate.UnaryServerInterceptor(true),
+				handwrittenvalidation.UnaryServerInterceptor,
+				usagemetrics.UnaryServerInterceptor(),
+			),
+			Stream: middleware.ChainStreamServer(
+				grpcvalidate.StreamServerInterceptor(true),
+				handwrittenvalidation.StreamServerInterceptor,
+				usagemetrics.StreamServerInterceptor(),
+				streamtimeout.MustStreamServerInterceptor(configWithDefaults.StreamingAPITimeout),
+			),
+		},
+	}
+}
+
+type experimentalServer struct {
+	experimentalv1.UnimplementedExperimentalServiceServer
+	shared.WithServiceSpecificInterceptors
+
+	dispatch dispatch.Dispatcher
+	config   PermissionsServerConfig
+
+	// permissions serves the calls implemented by the permissions service.
+	permissions *permissionServer
+}
+
+func (es *experimentalServer) CheckPermission(ctx context.Context, req *experimentalv1.CheckPermissionRequest) (*v1.CheckPermissionResponse, error) {
+	atRevision, checkedAt, err := consistency.RevisionFromContext(ctx)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	ds := datastoremw.MustFromContext(ctx).SnapshotReader(atRevision)
+
+	caveatContext, err := GetCaveatContext(ctx, req.Context, es.config.MaxCaveatContextSize)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	// Perform our preflight checks in parallel
+	errG, checksCtx := errgroup.WithContext(ctx)
+	errG.Go(func() error {
+		return namespace.CheckNamespaceAndRelation(
+			checksCtx,
+			req.Resource.ObjectType,
+			req.Permission,
+			false,
+			ds,
+		)
+	})
+	errG.Go(func() error {
+		return namespace.CheckNamespaceAndRelation(
+			checksCtx,
+			req.Subject.Object.ObjectType,
+			normalizeSubjectRelation(req.Subject),
+			true,
+			ds,
+		)
+	})
+	if err := errG.Wait(); err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	contextualRelationships, err := es.contextualRelationships(ctx, req.ContextualRelationships, ds)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	cr, metadata, err := computed.ComputeCheck(ctx, es.dispatch,
+		computed.CheckParameters{
+			ResourceType: &core.RelationReference{
+				Namespace: req.Resource.ObjectType,
+				Relation:  req.Permission,
+			},
+			Subject: &core.ObjectAndRelation{
+				Namespace: req.Subject.Object.ObjectType,
+				ObjectId:  req.Subject.Object.ObjectId,
+				Relation:  normalizeSubjectRelation(req.Subject),
+			},
+			CaveatContext:           caveatContext,
+			AtRevision:              atRevision,
+			MaximumDepth:            es.config.MaximumAPIDepth,
+			DebugOption:             computed.NoDebugging,
+			ContextualRelationships: contextualRelationships,
+		},
+		req.Resource.ObjectId,
+	)
+	usagemetrics.SetInContext(ctx, metadata)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	item := bulkCheckResponseItem(cr)
+	return &v1.CheckPermissionResponse{
+		CheckedAt:         checkedAt,
+		Permissionship:    item.Permissionship,
+		PartialCaveatInfo: item.PartialCaveatInfo,
+	}, nil
+}
+
+// contextualRelationships validates the given contextual relationships against the schema,
+// returning them as tuples to be overlaid on the datastore for the duration of a request.
+func (es *experimentalServer) contextualRelationships(ctx context.Context, rels []*v1.Relationship, ds datastore.Reader) ([]*core.RelationTuple, error) {
+	if len(rels) == 0 {
+		return nil, nil
+	}
+
+	if len(rels) > int(es.config.MaxUpdatesPerWrite) {
+		return nil, status.Errorf(
+			codes.InvalidArgument,
+			"contextual relationship count of %d is greater than maximum allowed of %d",
+			len(rels),
+			es.config.MaxUpdatesPerWrite,
+		)
+	}
+
+	tuples := make([]*core.RelationTuple, 0, len(rels))
+	updates := make([]*core.RelationTupleUpdate, 0, len(rels))
+	encountered := util.NewSet[string]()
+	for _, rel := range rels {
+		if err := rel.HandwrittenValidate(); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "%s", err)
+		}
+
+		tpl := tuple.FromRelationship(rel)
+		if !encountered.Add(tuple.StringWithoutCaveat(tpl)) {
+			return nil, status.Errorf(codes.InvalidArgument, "found duplicate contextual relationship %s", tuple.StringWithoutCaveat(tpl))
+		}
+
+		tuples = append(tuples, tpl)
+		updates = append(updates, tuple.Touch(tpl))
+	}
+
+	if err := relationships.ValidateRelationshipUpdates(ctx, ds, updates); err != nil {
+		return nil, err
+	}
+	return tuples, nil
+}
+
+func (es *experimentalServer) BulkCheckPermission(ctx context.Context, req *experimentalv1.BulkCheckPermissionRequest) (*experimentalv1.BulkCheckPermissionResponse, error) {
+	return es.permissions.BulkCheckPermission(ctx, req)
+}
+
+func (es *experimentalServer) LookupResources(req *experimentalv1.LookupResourcesRequest, resp experimentalv1.ExperimentalService_LookupResourcesServer) error {
+	ctx := resp.Context()
+
+	// NOTE: if a cursor was given, the consistency middleware has selected the revision at
+	// which the cursor was issued.
+	atRevision, revisionReadAt, err := consistency.RevisionFromContext(ctx)
+	if err != nil {
+		return rewriteError(ctx, err)
+	}
+
+	ds := datastoremw.MustFromContext(ctx).SnapshotReader(atRevision)
+
+	// Perform our preflight checks in parallel
+	errG, checksCtx := errgroup.WithContext(ctx)
+	errG.Go(func() error {
+		return namespace.CheckNamespaceAndRelation(
+			checksCtx,
+			req.Subject.Object.ObjectType,
+			normalizeSubjectRelation(req.Subject),
+			true,
+			ds,
+		)
+	})
+	errG.Go(func() error {
+		return namespace.CheckNamespaceAndRelation(
+			ctx,
+			req.ResourceObjectType,
+			req.Permission,
+			false,
+			ds,
+		)
+	})
+	if err := errG.Wait(); err != nil {
+		return rewriteError(ctx, err)
+	}
+
+	contextualRelationships, err := es.contextualRelationships(ctx, req.ContextualRelationships, ds)
+	if err != nil {
+		return rewriteError(ctx, err)
+	}
+
+	requestHash, err := computeLookupResourcesRequestHash(req)
+	if err != nil {
+		return rewriteError(ctx, err)
+	}
+
+	// An empty dispatch cursor indicates that the lookup should start from the beginning, in
+	// a stable order.
+	dispatchCursor := &dispatchv1.Cursor{}
+	if req.OptionalCursor != nil {
+		decoded, err := cursor.DecodeToDispatchCursor(req.OptionalCursor, requestHash)
+		if err != nil {
+			return rewriteError(ctx, err)
+		}
+		dispatchCursor = decoded
+	}
+
+	limit := req.OptionalLimit
+	if limit == 0 {
+		limit = ^uint32(0)
+	}
+
+	respMetadata := &dispatchv1.ResponseMeta{
+		DispatchCount:       0,
+		CachedDispatchCount: 0,
+		DepthRequired:       0,
+		DebugInfo:           nil,
+	}
+	usagemetrics.SetInContext(ctx, respMetadata)
+
+	stream := dispatch.NewHandlingDispatchStream(ctx, func(result *dispatchv1.DispatchLookupResponse) error {
+		for _, found := range result.ResolvedResources {
+			var partial *v1.PartialCaveatInfo
+			permissionship := v1.LookupPermissionship_LOOKUP_PERMISSIONSHIP_HAS_PERMISSION
+			if found.Permissionship == dispatchv1.ResolvedResource_CONDITIONALLY_HAS_PERMISSION {
+				permissionship = v1.LookupPermissionship_LOOKUP_PERMISSIONSHIP_CONDITIONAL_PERMISSION
+				partial = &v1.PartialCaveatInfo{
+					MissingRequiredContext: found.MissingRequiredContext,
+				}
+			}
+
+			afterResultCursor, err := cursor.EncodeFromDispatchCursor(found.AfterResponseCursor, requestHash, atRevision)
+			if err != nil {
+				return err
+			}
+
+			err = resp.Send(&experimentalv1.LookupResourcesResponse{
+				LookedUpAt:        revisionReadAt,
+				ResourceObjectId:  found.ResourceId,
+				Permissionship:    permissionship,
+				PartialCaveatInfo: partial,
+				AfterResultCursor: afterResultCursor,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		dispatch.AddResponseMetadata(respMetadata, result.Metadata)
+		return nil
+	})
+
+	err = es.dispatch.DispatchLookup(
+		&dispatchv1.DispatchLookupRequest{
+			Metadata: &dispatchv1.ResolverMeta{
+				AtRevision:              atRevision.String(),
+				DepthRemaining:          es.config.MaximumAPIDepth,
+				ContextualRelationships: contextualRelationships,
+			},
+			ObjectRelation: &core.RelationReference{
+				Namespace: req.ResourceObjectType,
+				Relation:  req.Permission,
+			},
+			Subject: &core.ObjectAndRelation{
+				Namespace: req.Subject.Object.ObjectType,
+				ObjectId:  req.Subject.Object.ObjectId,
+				Relation:  normalizeSubjectRelation(req.Subject),
+			},
+			Context:        req.Context,
+			Limit:          limit,
+			OptionalCursor: dispatchCursor,
+		},
+		stream)
+	if err != nil {
+		return rewriteError(ctx, err)
+	}
+
+	return nil
+}
+
+func (es *experimentalServer) LookupSubjects(req *experimentalv1.LookupSubjectsRequest, resp experimentalv1.ExperimentalService_LookupSubjectsServer) error {
+	ctx := resp.Context()
+
+	// NOTE: if a cursor was given, the consistency middleware has selected the revision at
+	// which the cursor was issued.
+	atRevision, revisionReadAt, err := consistency.RevisionFromContext(ctx)
+	if err != nil {
+		return rewriteError(ctx, err)
+	}
+
+	ds := datastoremw.MustFromContext(ctx).SnapshotReader(atRevision)
+
+	caveatContext, err := GetCaveatContext(ctx, req.Context, es.config.MaxCaveatContextSize)
+	if err != nil {
+		return rewriteError(ctx, err)
+	}
+
+	// Perform our preflight checks in parallel
+	errG, checksCtx := errgroup.WithContext(ctx)
+	errG.Go(func() error {
+		return namespace.CheckNamespaceAndRelation(
+			checksCtx,
+			req.Resource.ObjectType,
+			req.Permission,
+			false,
+			ds,
+		)
+	})
+	errG.Go(func() error {
+		return namespace.CheckNamespaceAndRelation(
+			ctx,
+			req.SubjectObjectType,
+			stringz.DefaultEmpty(req.OptionalSubjectRelation, tuple.Ellipsis),
+			true,
+			ds,
+		)
+	})
+	if err := errG.Wait(); err != nil {
+		return rewriteError(ctx, err)
+	}
+
+	contextualRelationships, err := es.contextualRelationships(ctx, req.ContextualRelationships, ds)
+	if err != nil {
+		return rewriteError(ctx, err)
+	}
+
+	requestHash, err := computeLookupSubjectsRequestHash(req)
+	if err != nil {
+		return rewriteError(ctx, err)
+	}
+
+	afterSubjectID := ""
+	if req.OptionalCursor != nil {
+		decoded, err := cursor.DecodeToDispatchCursor(req.OptionalCursor, requestHash)
+		if err != nil {
+			return rewriteError(ctx, err)
+		}
+
+		if len(decoded.Sections) != 1 {
+			return rewriteError(ctx, cursor.NewInvalidCursorErr(fmt.Errorf("expected a single cursor section, found %d", len(decoded.Sections))))
+		}
+		afterSubjectID = decoded.Sections[0]
+	}
+
+	respMetadata := &dispatchv1.ResponseMeta{
+		DispatchCount:       0,
+		CachedDispatchCount: 0,
+		DepthRequired:       0,
+		DebugInfo:           nil,
+	}
+	usagemetrics.SetInContext(ctx, respMetadata)
+
+	lookupPage := func(afterSubjectID string, limit uint32) ([]*dispatchv1.FoundSubject, error) {
+		return es.lookupSubjectsPage(ctx, req, atRevision, contextualRelationships, afterSubjectID, limit, respMetadata)
+	}
+
+	// NOTE: the dispatched lookup returns the concrete subjects in order, up to the limit, but some can
+	// then be removed due to their caveats, so further pages are dispatched after the last subject found
+	// until the limit has been reached or no further subjects are found.
+	var sentCount uint32
+	for {
+		pageLimit := uint32(0)
+		if req.OptionalConcreteLimit > 0 {
+			pageLimit = req.OptionalConcreteLimit - sentCount
+		}
+
+		foundSubjects, err := lookupPage(afterSubjectID, pageLimit)
+		if err != nil {
+			return rewriteError(ctx, err)
+		}
+
+		var concreteCount uint32
+		for _, foundSubject := range foundSubjects {
+			if foundSubject.SubjectId != tuple.PublicWildcard {
+				concreteCount++
+			}
+		}
+		truncated := pageLimit > 0 && concreteCount >= pageLimit
+
+		for _, foundSubject := range foundSubjects {
+			if foundSubject.SubjectId == tuple.PublicWildcard {
+				// The wildcard is only returned as part of the first page.
+				if afterSubjectID != "" {
+					continue
+				}
+
+				// The exclusions of the wildcard that sort after the last concrete subject found may
+				// not reflect those concrete subjects removed by the limit, so if any exist, the
+				// wildcard is recomputed without the limit.
+				if truncated && hasExclusionsAfter(foundSubject, foundSubjects[len(foundSubjects)-1].SubjectId) {
+					unlimited, err := lookupPage("", 0)
+					if err != nil {
+						return rewriteError(ctx, err)
+					}
+
+					if len(unlimited) == 0 || unlimited[0].SubjectId != tuple.PublicWildcard {
+						return rewriteError(ctx, spiceerrors.MustBugf("wildcard missing from unlimited lookup subjects"))
+					}
+					foundSubject = unlimited[0]
+				}
+			}
+
+			sent, err := es.sendFoundSubject(ctx, resp, foundSubject, caveatContext, ds, revisionReadAt, requestHash, atRevision)
+			if err != nil {
+				return rewriteError(ctx, err)
+			}
+
+			if sent && foundSubject.SubjectId != tuple.PublicWildcard {
+				sentCount++
+			}
+		}
+
+		if !truncated || sentCount >= req.OptionalConcreteLimit {
+			return nil
+		}
+
+		afterSubjectID = foundSubjects[len(foundSubjects)-1].SubjectId
+	}
+}
+
+// lookupSubjectsPage dispatches a lookup of the subjects found after the given subject ID, up to the
+// given limit, returning them sorted by subject ID, with any wildcard first.
+func (es *experimentalServer) lookupSubjectsPage(
+	ctx context.Context,
+	req *experimentalv1.LookupSubjectsRequest,
+	atRevision datastore.Revision,
+	contextualRelationships []*core.RelationTuple,
+	afterSubjectID string,
+	limit uint32,
+	respMetadata *dispatchv1.ResponseMeta,
+) ([]*dispatchv1.FoundSubject, error) {
+	foundSubjects := datasets.NewSubjectSet()
+	stream := dispatch.NewHandlingDispatchStream(ctx, func(result *dispatchv1.DispatchLookupSubjectsResponse) error {
+		dispatch.AddResponseMetadata(respMetadata, result.Metadata)
+
+		found, ok := result.FoundSubjectsByResourceId[req.Resource.ObjectId]
+		if !ok {
+			return fmt.Errorf("missing resource ID in returned LS")
+		}
+
+		return foundSubjects.UnionWithFoundSubjects(found)
+	})
+
+	var dispatchCursor *dispatchv1.Cursor
+	if afterSubjectID != "" {
+		dispatchCursor = &dispatchv1.Cursor{Sections: []string{afterSubjectID}}
+	}
+
+	err := es.dispatch.DispatchLookupSubjects(
+		&dispatchv1.DispatchLookupSubjectsRequest{
+			Metadata: &dispatchv1.ResolverMeta{
+				AtRevision:              atRevision.String(),
+				DepthRemaining:          es.config.MaximumAPIDepth,
+				ContextualRelationships: contextualRelationships,
+			},
+			ResourceRelation: &core.RelationReference{
+				Namespace: req.Resource.ObjectType,
+				Relation:  req.Permission,
+			},
+			ResourceIds: []string{req.Resource.ObjectId},
+			SubjectRelation: &core.RelationReference{
+				Namespace: req.SubjectObjectType,
+				Relation:  stringz.DefaultEmpty(req.OptionalSubjectRelation, tuple.Ellipsis),
+			},
+			OptionalLimit:        limit,
+			OptionalCursor:       dispatchCursor,
+			AllowCompactSubjects: true,
+		},
+		stream)
+	if err != nil {
+		return nil, err
+	}
+
+	// NOTE: the wildcard sorts before all concrete subject IDs.
+	sorted := foundSubjects.LimitedAfter(afterSubjectID, limit).AsSlice()
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].SubjectId < sorted[j].SubjectId
+	})
+	return sorted, nil
+}
+
+// sendFoundSubject resolves the caveats of the found subject and, if it has permission, sends it
+// to the client, returning whether it was sent.
+func (es *experimentalServer) sendFoundSubject(
+	ctx context.Context,
+	resp experimentalv1.ExperimentalService_LookupSubjectsServer,
+	foundSubject *dispatchv1.FoundSubject,
+	caveatContext map[string]any,
+	ds datastore.Reader,
+	revisionReadAt *v1.ZedToken,
+	requestHash string,
+	atRevision datastore.Revision,
+) (bool, error) {
+	subject, err := foundSubjectToResolvedSubject(ctx, foundSubject, caveatContext, ds)
+	if err != nil {
+		return false, err
+	}
+	if subject == nil {
+		return false, nil
+	}
+
+	excludedSubjects := make([]*v1.ResolvedSubject, 0, len(foundSubject.ExcludedSubjects))
+	for _, excludedSubject := range foundSubject.ExcludedSubjects {
+		resolvedExcludedSubject, err := foundSubjectToResolvedSubject(ctx, excludedSubject, caveatContext, ds)
+		if err != nil {
+			return false, err
+		}
+
+		if resolvedExcludedSubject == nil {
+			continue
+		}
+
+		excludedSubjects = append(excludedSubjects, resolvedExcludedSubject)
+	}
+
+	afterResultCursor, err := cursor.EncodeFromDispatchCursor(&dispatchv1.Cursor{
+		Sections: []string{foundSubject.SubjectId},
+	}, requestHash, atRevision)
+	if err != nil {
+		return false, err
+	}
+
+	err = resp.Send(&experimentalv1.LookupSubjectsResponse{
+		LookedUpAt:        revisionReadAt,
+		Subject:           subject,
+		ExcludedSubjects:  excludedSubjects,
+		AfterResultCursor: afterResultCursor,
+	})
+	return err == nil, err
+}
+
+// hasExclusionsAfter returns whether the wildcard has any excluded subjects that sort after the
+// given subject ID.
+func hasExclusionsAfter(wildcard *dispatchv1.FoundSubject, subjectID string) bool {
+	for _, excludedSubject := range wildcard.ExcludedSubjects {
+		if excludedSubject.SubjectId > subjectID {
+			return true
+		}
+	}
+	return false
+}
+
+func (es *experimentalServer) LookupPermissions(ctx context.Context, req *experimentalv1.LookupPermissionsRequest) (*experimentalv1.LookupPermissionsResponse, error) {
+	atRevision, checkedAt, err := consistency.RevisionFromContext(ctx)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	ds := datastoremw.MustFromContext(ctx).SnapshotReader(atRevision)
+
+	caveatContext, err := GetCaveatContext(ctx, req.Context, es.config.MaxCaveatContextSize)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	nsDef, ts, err := namespace.ReadNamespaceAndTypes(ctx, req.Resource.ObjectType, ds)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	err = namespace.CheckNamespaceAndRelation(
+		ctx,
+		req.Subject.Object.ObjectType,
+		normalizeSubjectRelation(req.Subject),
+		true,
+		ds,
+	)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	var metadataLock sync.Mutex
+	respMetadata := &dispatchv1.ResponseMeta{}
+	usagemetrics.SetInContext(ctx, respMetadata)
+
+	// Relations and permissions are evaluated in waves, each depending only on those of earlier
+	// waves, with the results of all checks memoized for the request, ensuring that each
+	// relation or permission referenced by another is computed only once.
+	memoCtx := dispatchgraph.ContextWithCheckMemo(ctx)
+	subject := &core.ObjectAndRelation{
+		Namespace: req.Subject.Object.ObjectType,
+		ObjectId:  req.Subject.Object.ObjectId,
+		Relation:  normalizeSubjectRelation(req.Subject),
+	}
+
+	results := make(map[string]*experimentalv1.LookupPermissionsResult, len(nsDef.Relation))
+	for _, wave := range lookupPermissionsWaves(nsDef) {
+		tr, trCtx := errgroup.WithContext(memoCtx)
+		tr.SetLimit(maxBulkCheckConcurrency)
+
+		waveResults := make([]*experimentalv1.LookupPermissionsResult, len(wave))
+		for index, relation := range wave {
+			index := index
+			relation := relation
+			tr.Go(func() error {
+				cr, metadata, err := computed.ComputeCheck(trCtx, es.dispatch,
+					computed.CheckParameters{
+						ResourceType: &core.RelationReference{
+							Namespace: nsDef.Name,
+							Relation:  relation.Name,
+						},
+						Subject:       subject,
+						CaveatContext: caveatContext,
+						AtRevision:    atRevision,
+						MaximumDepth:  es.config.MaximumAPIDepth,
+						DebugOption:   computed.NoDebugging,
+					},
+					req.Resource.ObjectId,
+				)
+				if metadata != nil {
+					metadataLock.Lock()
+					dispatch.AddResponseMetadata(respMetadata, metadata)
+					metadataLock.Unlock()
+				}
+				if err != nil {
+					return err
+				}
+
+				item := bulkCheckResponseItem(cr)
+				waveResults[index] = &experimentalv1.LookupPermissionsResult{
+					Permission:        relation.Name,
+					IsPermission:      ts.IsPermission(relation.Name),
+					Permissionship:    item.Permissionship,
+					PartialCaveatInfo: item.PartialCaveatInfo,
+				}
+				return nil
+			})
+		}
+
+		if err := tr.Wait(); err != nil {
+			return nil, rewriteError(ctx, err)
+		}
+
+		for _, result := range waveResults {
+			results[result.Permission] = result
+		}
+	}
+
+	ordered := make([]*experimentalv1.LookupPermissionsResult, 0, len(nsDef.Relation))
+	for _, relation := range nsDef.Relation {
+		ordered = append(ordered, results[relation.Name])
+	}
+
+	return &experimentalv1.LookupPermissionsResponse{
+		CheckedAt: checkedAt,
+		Results:   ordered,
+	}, nil
+}
+
+// lookupPermissionsWaves groups the relations of the namespace into waves, such that the
+// relations of each wave only reference, via computed usersets, relations of earlier waves.
+func lookupPermissionsWaves(nsDef *core.NamespaceDefinition) [][]*core.Relation {
+	referenced := make(map[string][]string, len(nsDef.Relation))
+	for _, relation := range nsDef.Relation {
+		_, _ = graph.WalkRewrite(relation.UsersetRewrite, func(childOneof *core.SetOperation_Child) interface{} {
+			if computedUserset, ok := childOneof.ChildType.(*core.SetOperation_Child_ComputedUserset); ok {
+				referenced[relation.Name] = append(referenced[relation.Name], computedUserset.ComputedUserset.Relation)
+			}
+			return nil
+		})
+	}
+
+	waves := make([][]*core.Relation, 0)
+	placed := util.NewSet[string]()
+	remaining := nsDef.Relation
+	for len(remaining) > 0 {
+		var wave, deferred []*core.Relation
+		for _, relation := range remaining {
+			ready := true
+			for _, name := range referenced[relation.Name] {
+				if name != relation.Name && !placed.Has(name) {
+					ready = false
+					break
+				}
+			}
+
+			if ready {
+				wave = append(wave, relation)
+			} else {
+				deferred = append(deferred, relation)
+			}
+		}
+
+		// NOTE: a valid schema cannot contain cycles of computed usersets, but if one is found,
+		// its relations are placed into a single final wave.
+		if len(wave) == 0 {
+			wave, deferred = deferred, nil
+		}
+
+		for _, relation := range wave {
+			placed.Add(relation.Name)
+		}
+		waves = append(waves, wave)
+		remaining = deferred
+	}
+	return waves
+}
+
+// computeLookupResourcesRequestHash computes a hash of the parameters of the request, which is
+// placed into the cursors returned, to ensure that a cursor is only used with the same call.
+func computeLookupResourcesRequestHash(req *experimentalv1.LookupResourcesRequest) (string, error) {
+	cloned := req.CloneVT()
+	cloned.Consistency = nil
+	cloned.OptionalCursor = nil
+	return computeParametersHash(cloned)
+}
+
+// computeLookupSubjectsRequestHash computes a hash of the parameters of the request, which is
+// placed into the cursors returned, to ensure that a cursor is only used with the same call.
+func computeLookupSubjectsRequestHash(req *experimentalv1.LookupSubjectsRequest) (string, error) {
+	cloned := req.CloneVT()
+	cloned.Consistency = nil
+	cloned.OptionalCursor = nil
+	return computeParametersHash(cloned)
+}
+
+// computeReadRelationshipsRequestHash computes a hash of the parameters of the request, which
+// is placed into the cursors returned, to ensure that a cursor is only used with the same filter.
+// The limit does not affect the order of the results, and is excluded to allow the size of the
+// pages to vary between calls.
+func computeReadRelationshipsRequestHash(req *experimentalv1.ReadRelationshipsRequest) (string, error) {
+	cloned := req.CloneVT()
+	cloned.Consistency = nil
+	cloned.OptionalCursor = nil
+	cloned.OptionalLimit = 0
+	return computeParametersHash(cloned)
+}
+
+func computeParametersHash(parameters proto.Message) (string, error) {
+	marshalled, err := proto.MarshalOptions{Deterministic: true}.Marshal(parameters)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(marshalled)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+func (es *experimentalServer) ReadRelationships(req *experimentalv1.ReadRelationshipsRequest, resp experimentalv1.ExperimentalService_ReadRelationshipsServer) error {
+	ctx := resp.Context()
+
+	// NOTE: if a cursor was given, the consistency middleware has selected the revision at
+	// which the cursor was issued.
+	atRevision, revisionReadAt, err := consistency.RevisionFromContext(ctx)
+	if err != nil {
+		return rewriteError(ctx, err)
+	}
+
+	ds := datastoremw.MustFromContext(ctx).SnapshotReader(atRevision)
+
+	if err := checkFilterNamespaces(ctx, req.RelationshipFilter, ds); err != nil {
+		return rewriteError(ctx, err)
+	}
+
+	requestHash, err := computeReadRelationshipsRequestHash(req)
+	if err != nil {
+		return rewriteError(ctx, err)
+	}
+
+	var startCursor options.Cursor
+	if req.OptionalCursor != nil {
+		decoded, err := cursor.DecodeToDispatchCursor(req.OptionalCursor, requestHash)
+		if err != nil {
+			return rewriteError(ctx, err)
+		}
+
+		if len(decoded.Sections) != 1 {
+			return rewriteError(ctx, cursor.NewInvalidCursorErr(fmt.Errorf("expected one cursor section, found %d", len(decoded.Sections))))
+		}
+
+		startCursor = tuple.Parse(decoded.Sections[0])
+		if startCursor == nil {
+			return rewriteError(ctx, cursor.NewInvalidCursorErr(fmt.Errorf("invalid cursor relationship `%s`", decoded.Sections[0])))
+		}
+	}
+
+	pageSize := es.config.MaxDatastoreReadPageSize
+	if req.OptionalLimit > 0 && uint64(req.OptionalLimit) < pageSize {
+		pageSize = uint64(req.OptionalLimit)
+	}
+
+	usagemetrics.SetInContext(ctx, &dispatchv1.ResponseMeta{
+		DispatchCount: 1,
+	})
+
+	tupleIterator, err := pagination.NewPaginatedIterator(
+		ctx,
+		ds,
+		datastore.RelationshipsFilterFromPublicFilter(req.RelationshipFilter),
+		pageSize,
+		options.ByResource,
+		startCursor,
+	)
+	if err != nil {
+		return rewriteError(ctx, err)
+	}
+	defer tupleIterator.Close()
+
+	response := &experimentalv1.ReadRelationshipsResponse{
+		ReadAt: revisionReadAt,
+	}
+	targetRel := tuple.NewRelationship()
+	targetCaveat := &v1.ContextualizedCaveat{}
+
+	var returned uint32
+	for tpl := tupleIterator.Next(); tpl != nil; tpl = tupleIterator.Next() {
+		afterResultCursor, err := cursor.EncodeFromDispatchCursor(&dispatchv1.Cursor{
+			Sections: []string{tuple.StringWithoutCaveat(tpl)},
+		}, requestHash, atRevision)
+		if err != nil {
+			return rewriteError(ctx, err)
+		}
+
+		tuple.MustToRelationshipMutating(tpl, targetRel, targetCaveat)
+		response.Relationship = targetRel
+		response.AfterResultCursor = afterResultCursor
+		if err := resp.Send(response); err != nil {
+			return rewriteError(ctx, fmt.Errorf("error when streaming tuple: %w", err))
+		}
+
+		// Stop before reading another page of relationships once the limit is reached.
+		returned++
+		if returned == req.OptionalLimit {
+			break
+		}
+	}
+
+	if tupleIterator.Err() != nil {
+		return rewriteError(ctx, fmt.Errorf("error when reading tuples: %w", tupleIterator.Err()))
+	}
+
+	return nil
+}
+
+func (es *experimentalServer) WriteRelationships(ctx context.Context, req *experimentalv1.WriteRelationshipsRequest) (*v1.WriteRelationshipsResponse, error) {
+	writeReq := &v1.WriteRelationshipsRequest{
+		Updates:               req.Updates,
+		OptionalPreconditions: req.OptionalPreconditions,
+	}
+
+	// The updates and preconditions are validated as they would be for the stable API.
+	if err := writeReq.HandwrittenValidate(); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%s", err)
+	}
+
+	requestHash, err := computeWriteRelationshipsRequestHash(req)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	return writeRelationships(
+		ctx,
+		es.config,
+		writeReq,
+		req.OptionalUnchangedSincePreconditions,
+		idempotencyKeyOptions(req.OptionalIdempotencyKey, requestHash)...,
+	)
+}
+
+func (es *experimentalServer) DeleteRelationships(ctx context.Context, req *experimentalv1.DeleteRelationshipsRequest) (*experimentalv1.DeleteRelationshipsResponse, error) {
+	preconditionsCount := len(req.OptionalPreconditions) + len(req.OptionalUnchangedSincePreconditions)
+	if preconditionsCount > int(es.config.MaxPreconditionsCount) {
+		return nil, rewriteError(
+			ctx,
+			NewExceedsMaximumPreconditionsErr(uint16(preconditionsCount), es.config.MaxPreconditionsCount),
+		)
+	}
+
+	if req.OptionalLimit > es.config.MaxDeleteRelationshipsLimit {
+		return nil, rewriteError(
+			ctx,
+			NewExceedsMaximumLimitErr(uint64(req.OptionalLimit), uint64(es.config.MaxDeleteRelationshipsLimit)),
+		)
+	}
+
+	var deleteOpts []options.DeleteOptionsOption
+	if req.OptionalLimit > 0 {
+		limit := uint64(req.OptionalLimit)
+		deleteOpts = append(deleteOpts, options.WithDeleteLimit(&limit))
+	}
+
+	requestHash, err := computeDeleteRelationshipsRequestHash(req)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	ds := datastoremw.MustFromContext(ctx)
+
+	var deleted uint64
+	var progress experimentalv1.DeleteRelationshipsResponse_DeletionProgress
+	revision, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		if err := checkFilterNamespaces(ctx, req.RelationshipFilter, rwt); err != nil {
+			return err
+		}
+		for _, precond := range req.OptionalUnchangedSincePreconditions {
+			if err := checkFilterNamespaces(ctx, precond.Filter, rwt); err != nil {
+				return err
+			}
+		}
+
+		usagemetrics.SetInContext(ctx, &dispatchv1.ResponseMeta{
+			// One request per precondition and one request for the actual delete.
+			DispatchCount: uint32(preconditionsCount) + 1,
+		})
+
+		if err := checkPreconditions(ctx, rwt, req.OptionalPreconditions); err != nil {
+			return err
+		}
+
+		if err := checkUnchangedSincePreconditions(ctx, ds, rwt, req.OptionalUnchangedSincePreconditions); err != nil {
+			return err
+		}
+
+		numDeleted, limitReached, err := rwt.DeleteRelationships(ctx, req.RelationshipFilter, deleteOpts...)
+		if err != nil {
+			return err
+		}
+
+		progress = experimentalv1.DeleteRelationshipsResponse_DELETION_PROGRESS_COMPLETE
+		if limitReached {
+			// Returning an error rolls back the deletions made up to the limit.
+			if !req.OptionalAllowPartialDeletions {
+				return NewCouldNotTransactionallyDeleteErr(req.RelationshipFilter, req.OptionalLimit)
+			}
+
+			progress = experimentalv1.DeleteRelationshipsResponse_DELETION_PROGRESS_PARTIAL
+		}
+
+		deleted = numDeleted
+		return nil
+	}, idempotencyKeyOptions(req.OptionalIdempotencyKey, requestHash)...)
+
+	// A retried deletion is not applied again, but reports the revision of the original deletion.
+	// The number of relationships it deleted is not stored, and so is not reported. A deletion
+	// which does not allow partial deletions only commits once complete, whereas one which does
+	// may have stopped at the limit, so it is reported as partial for the caller to repeat.
+	var committedErr datastore.ErrIdempotencyKeyAlreadyCommitted
+	if errors.As(err, &committedErr) {
+		progress := experimentalv1.DeleteRelationshipsResponse_DELETION_PROGRESS_COMPLETE
+		if req.OptionalAllowPartialDeletions {
+			progress = experimentalv1.DeleteRelationshipsResponse_DELETION_PROGRESS_PARTIAL
+		}
+
+		return &experimentalv1.DeleteRelationshipsResponse{
+			DeletedAt:        zedtoken.MustNewFromRevision(committedErr.CommittedRevision()),
+			DeletionProgress: progress,
+		}, nil
+	}
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	return &experimentalv1.DeleteRelationshipsResponse{
+		DeletedAt:                 zedtoken.MustNewFromRevision(revision),
+		DeletionProgress:          progress,
+		RelationshipsDeletedCount: deleted,
+	}, nil
+}
+
+func idempotencyKeyOptions(idempotencyKey string, requestHash string) []options.RWTOptionsOption {
+	if idempotencyKey == "" {
+		return nil
+	}
+	return []options.RWTOptionsOption{
+		options.WithIdempotencyKey(idempotencyKey),
+		options.WithIdempotencyRequestHash(requestHash),
+	}
+}
+
+// computeWriteRelationshipsRequestHash computes a hash of the request, which is stored alongside
+// its idempotency key, to ensure that a key is only reused to retry the same request.
+func computeWriteRelationshipsRequestHash(req *experimentalv1.WriteRelationshipsRequest) (string, error) {
+	cloned := req.CloneVT()
+	cloned.OptionalIdempotencyKey = ""
+	return computeParametersHash(cloned)
+}
+
+// computeDeleteRelationshipsRequestHash computes a hash of the request, which is stored alongside
+// its idempotency key, to ensure that a key is only reused to retry the same request.
+func computeDeleteRelationshipsRequestHash(req *experimentalv1.DeleteRelationshipsRequest) (string, error) {
+	cloned := req.CloneVT()
+	cloned.OptionalIdempotencyKey = ""
+	return computeParametersHash(cloned)
+}
+
+// errBulkImportRetried is returned if the datastore attempts to retry the transaction of a bulk
+// import after relationships have been read from the stream, as they cannot be read again.
+var errBulkImportRetried = status.Error(codes.Aborted, "bulk import transaction cannot be retried; please retry the import")
+
+func (es *experimentalServer) BulkImportRelationships(stream experimentalv1.ExperimentalService_BulkImportRelationshipsServer) error {
+	ctx := stream.Context()
+	ds := datastoremw.MustFromContext(ctx)
+
+	adapter := &bulkLoadAdapter{
+		stream:       stream,
+		namespaceMap: make(map[string]*namespace.TypeSystem),
+		caveatMap:    make(map[string]*core.CaveatDefinition),
+	}
+
+	var numLoaded uint64
+	revision, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		if adapter.received {
+			return errBulkImportRetried
+		}
+
+		for {
+			loaded, err := rwt.BulkLoad(ctx, adapter)
+			numLoaded += loaded
+			if adapter.err != nil {
+				// Return the error from the stream or validation directly, as the datastore may
+				// have wrapped it.
+				return adapter.err
+			}
+			if err != nil {
+				return err
+			}
+			if adapter.done {
+				return nil
+			}
+
+			// The load ended because the current batch references definitions which have not
+			// yet been loaded, so load them before resuming.
+			if err := adapter.loadAwaitedDefinitions(ctx, rwt); err != nil {
+				return err
+			}
+		}
+	})
+	if err != nil {
+		return rewriteError(ctx, err)
+	}
+
+	usagemetrics.SetInContext(ctx, &dispatchv1.ResponseMeta{
+		// One request for the entire import.
+		DispatchCount: 1,
+	})
+
+	return stream.SendAndClose(&experimentalv1.BulkImportRelationshipsResponse{
+		NumLoaded: numLoaded,
+		WrittenAt: zedtoken.MustNewFromRevision(revision),
+	})
+}
+
+// bulkLoadAdapter adapts a bulk import stream into a source for the datastore's BulkLoad,
+// validating each relationship against the type system as it is read. Type systems and
+// caveats are loaded once, when first referenced by a received batch.
+type bulkLoadAdapter struct {
+	stream       experimentalv1.ExperimentalService_BulkImportRelationshipsServer
+	namespaceMap map[string]*namespace.TypeSystem
+	caveatMap    map[string]*core.CaveatDefinition
+
+	currentBatch []*v1.Relationship
+	numSent      int
+
+	awaitingNamespaces []string
+	awaitingCaveats    []string
+
+	received bool
+	done     bool
+	err      error
+}
+
+func (a *bulkLoadAdapter) Next(_ context.Context) (*core.RelationTuple, error) {
+	for a.numSent == len(a.currentBatch) {
+		batch, err := a.stream.Recv()
+		if errors.Is(err, io.EOF) {
+			a.done = true
+			return nil, nil
+		}
+		if err != nil {
+			a.err = err
+			return nil, err
+		}
+
+		a.received = true
+		a.currentBatch = batch.Relationships
+		a.numSent = 0
+		a.awaitingNamespaces, a.awaitingCaveats = a.unloadedDefinitions(batch.Relationships)
+	}
+
+	// Definitions cannot be read while the datastore is in the middle of a load, so end
+	// the load to allow the caller to load those referenced by the current batch.
+	if len(a.awaitingNamespaces) > 0 || len(a.awaitingCaveats) > 0 {
+		return nil, nil
+	}
+
+	rel := a.currentBatch[a.numSent]
+	if err := rel.HandwrittenValidate(); err != nil {
+		a.err = status.Errorf(codes.InvalidArgument, "%s", err)
+		return nil, a.err
+	}
+
+	tpl := tuple.FromRelationship(rel)
+	if err := relationships.ValidateOneRelationship(a.namespaceMap, a.caveatMap, tpl); err != nil {
+		a.err = err
+		return nil, err
+	}
+
+	a.numSent++
+	return tpl, nil
+}
+
+// unloadedDefinitions returns the names of the namespaces and caveats referenced by the given
+// relationships which have not yet been loaded.
+func (a *bulkLoadAdapter) unloadedDefinitions(rels []*v1.Relationship) ([]string, []string) {
+	namespaceNames := util.NewSet[string]()
+	caveatNames := util.NewSet[string]()
+	for _, rel := range rels {
+		if _, ok := a.namespaceMap[rel.Resource.ObjectType]; !ok {
+			namespaceNames.Add(rel.Resource.ObjectType)
+		}
+		if _, ok := a.namespaceMap[rel.Subject.Object.ObjectType]; !ok {
+			namespaceNames.Add(rel.Subject.Object.ObjectType)
+		}
+		if rel.OptionalCaveat != nil {
+			if _, ok := a.caveatMap[rel.OptionalCaveat.CaveatName]; !ok {
+				caveatNames.Add(rel.OptionalCaveat.CaveatName)
+			}
+		}
+	}
+	return namespaceNames.AsSlice(), caveatNames.AsSlice()
+}
+
+// loadAwaitedDefinitions loads the namespaces and caveats referenced by the current batch.
+// Definitions which do not exist are left unloaded, causing validation of the relationships
+// referencing them to fail.
+func (a *bulkLoadAdapter) loadAwaitedDefinitions(ctx context.Context, reader datastore.Reader) error {
+	namespaceMap, err := relationships.LoadNamespaceTypeSystems(ctx, reader, a.awaitingNamespaces)
+	if err != nil {
+		return err
+	}
+	for name, ts := range namespaceMap {
+		a.namespaceMap[name] = ts
+	}
+
+	caveatMap, err := relationships.LoadCaveats(ctx, reader, a.awaitingCaveats)
+	if err != nil {
+		return err
+	}
+	for name, caveat := range caveatMap {
+		a.caveatMap[name] = caveat
+	}
+
+	a.awaitingNamespaces = nil
+	a.awaitingCaveats = nil
+	return nil
+}
+
+// bulkExportCallHash is placed into the cursors returned by BulkExportRelationships, to ensure
+// that they are only used to resume exports. The export has no parameters affecting its results,
+// so any export can be resumed from the cursor of another.
+const bulkExportCallHash = "bulkexport"
+
+func (es *experimentalServer) BulkExportRelationships(req *experimentalv1.BulkExportRelationshipsRequest, resp experimentalv1.ExperimentalService_BulkExportRelationshipsServer) error {
+	ctx := resp.Context()
+
+	// NOTE: if a cursor was given, the consistency middleware has selected the revision at
+	// which the cursor was issued.
+	atRevision, revisionReadAt, err := consistency.RevisionFromContext(ctx)
+	if err != nil {
+		return rewriteError(ctx, err)
+	}
+
+	reader := datastoremw.MustFromContext(ctx).SnapshotReader(atRevision)
+
+	var afterNamespace string
+	var afterRelationship options.Cursor
+	if req.OptionalCursor != nil {
+		decoded, err := cursor.DecodeToDispatchCursor(req.OptionalCursor, bulkExportCallHash)
+		if err != nil {
+			return rewriteError(ctx, err)
+		}
+
+		if len(decoded.Sections) != 2 {
+			return rewriteError(ctx, cursor.NewInvalidCursorErr(fmt.Errorf("expected two cursor sections, found %d", len(decoded.Sections))))
+		}
+
+		afterNamespace = decoded.Sections[0]
+		afterRelationship = tuple.Parse(decoded.Sections[1])
+		if afterRelationship == nil {
+			return rewriteError(ctx, cursor.NewInvalidCursorErr(fmt.Errorf("invalid cursor relationship `%s`", decoded.Sections[1])))
+		}
+	}
+
+	batchSize := uint64(es.config.MaxDatastoreReadPageSize)
+	if req.OptionalBatchSize > 0 && uint64(req.OptionalBatchSize) < batchSize {
+		batchSize = uint64(req.OptionalBatchSize)
+	}
+
+	namespaces, err := reader.ListAllNamespaces(ctx)
+	if err != nil {
+		return rewriteError(ctx, err)
+	}
+
+	// Namespaces are exported in name order, so that the export can be resumed from the
+	// namespace in the cursor.
+	sort.Slice(namespaces, func(i, j int) bool {
+		return namespaces[i].Definition.Name < namespaces[j].Definition.Name
+	})
+
+	usagemetrics.SetInContext(ctx, &dispatchv1.ResponseMeta{
+		// One request per namespace exported.
+		DispatchCount: uint32(len(namespaces)),
+	})
+
+	for _, ns := range namespaces {
+		namespaceName := ns.Definition.Name
+		if namespaceName < afterNamespace {
+			continue
+		}
+
+		var after options.Cursor
+		if namespaceName == afterNamespace {
+			after = afterRelationship
+		}
+
+		for {
+			relationships, lastTuple, err := exportRelationshipsBatch(ctx, reader, namespaceName, batchSize, after)
+			if err != nil {
+				return rewriteError(ctx, err)
+			}
+
+			if len(relationships) == 0 {
+				break
+			}
+
+			encodedCursor, err := cursor.EncodeFromDispatchCursor(&dispatchv1.Cursor{
+				Sections: []string{namespaceName, tuple.StringWithoutCaveat(lastTuple)},
+			}, bulkExportCallHash, atRevision)
+			if err != nil {
+				return rewriteError(ctx, err)
+			}
+
+			if err := resp.Send(&experimentalv1.BulkExportRelationshipsResponse{
+				ExportedAt:        revisionReadAt,
+				Relationships:     relationships,
+				AfterResultCursor: encodedCursor,
+			}); err != nil {
+				return rewriteError(ctx, err)
+			}
+
+			if uint64(len(relationships)) < batchSize {
+				break
+			}
+			after = lastTuple
+		}
+	}
+
+	return nil
+}
+
+// exportRelationshipsBatch reads the next batch of relationships of the given namespace, in resource
+// order, after the given cursor, returning them along with the last relationship read.
+func exportRelationshipsBatch(
+	ctx context.Context,
+	reader datastore.Reader,
+	namespaceName string,
+	batchSize uint64,
+	after options.Cursor,
+) ([]*v1.Relationship, *core.RelationTuple, error) {
+	iter, err := reader.QueryRelationships(
+		ctx,
+		datastore.RelationshipsFilter{ResourceType: namespaceName},
+		options.WithSort(options.ByResource),
+		options.WithLimit(&batchSize),
+		options.WithAfter(after),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer iter.Close()
+
+	relationships := make([]*v1.Relationship, 0, batchSize)
+	var lastTuple *core.RelationTuple
+	for tpl := iter.Next(); tpl != nil; tpl = iter.Next() {
+		relationships = append(relationships, tuple.MustToRelationship(tpl))
+		lastTuple = tpl
+	}
+	if iter.Err() != nil {
+		return nil, nil, fmt.Errorf("error when reading tuples: %w", iter.Err())
+	}
+
+	return relationships, lastTuple, nil
+}
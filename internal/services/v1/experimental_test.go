@@ -0,0 +1,1404 @@
+package v1_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/authzed/grpcutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	tf "github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/internal/testserver"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	experimentalv1 "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+	"github.com/authzed/spicedb/pkg/spiceerrors"
+	"github.com/authzed/spicedb/pkg/tuple"
+	"github.com/authzed/spicedb/pkg/zedtoken"
+)
+
+func TestBulkCheckPermission(t *testing.T) {
+	req := require.New(t)
+	conn, cleanup, _, revision := testserver.NewTestServer(req, testTimedeltas[0], memdb.DisableGC, true, tf.StandardDatastoreWithCaveatedData)
+	client := experimentalv1.NewExperimentalServiceClient(conn)
+	t.Cleanup(cleanup)
+
+	mustContext := func(values map[string]any) *structpb.Struct {
+		s, err := structpb.NewStruct(values)
+		req.NoError(err)
+		return s
+	}
+
+	type expected struct {
+		permissionship v1.CheckPermissionResponse_Permissionship
+		missingContext []string
+		errorCode      codes.Code
+	}
+
+	testCases := []struct {
+		item     *experimentalv1.BulkCheckPermissionRequestItem
+		expected expected
+	}{
+		{
+			&experimentalv1.BulkCheckPermissionRequestItem{
+				Resource:   obj("document", "companyplan"),
+				Permission: "view",
+				Subject:    sub("user", "owner", ""),
+				Context:    mustContext(map[string]any{"secret": "1234"}),
+			},
+			expected{permissionship: v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION},
+		},
+		{
+			&experimentalv1.BulkCheckPermissionRequestItem{
+				Resource:   obj("document", "companyplan"),
+				Permission: "view",
+				Subject:    sub("user", "owner", ""),
+				Context:    mustContext(map[string]any{"secret": "incorrect_value"}),
+			},
+			expected{permissionship: v1.CheckPermissionResponse_PERMISSIONSHIP_NO_PERMISSION},
+		},
+		{
+			&experimentalv1.BulkCheckPermissionRequestItem{
+				Resource:   obj("document", "companyplan"),
+				Permission: "view",
+				Subject:    sub("user", "owner", ""),
+			},
+			expected{
+				permissionship: v1.CheckPermissionResponse_PERMISSIONSHIP_CONDITIONAL_PERMISSION,
+				missingContext: []string{"secret"},
+			},
+		},
+		{
+			&experimentalv1.BulkCheckPermissionRequestItem{
+				Resource:   obj("document", "masterplan"),
+				Permission: "view",
+				Subject:    sub("user", "unknown", ""),
+				Context:    mustContext(map[string]any{"secret": "1234"}),
+			},
+			expected{permissionship: v1.CheckPermissionResponse_PERMISSIONSHIP_NO_PERMISSION},
+		},
+		{
+			&experimentalv1.BulkCheckPermissionRequestItem{
+				Resource:   obj("fake", "companyplan"),
+				Permission: "view",
+				Subject:    sub("user", "owner", ""),
+			},
+			expected{errorCode: codes.FailedPrecondition},
+		},
+		{
+			&experimentalv1.BulkCheckPermissionRequestItem{
+				Resource:   obj("document", "companyplan"),
+				Permission: "fake",
+				Subject:    sub("user", "owner", ""),
+			},
+			expected{errorCode: codes.FailedPrecondition},
+		},
+		{
+			&experimentalv1.BulkCheckPermissionRequestItem{
+				Resource:   obj("document", "companyplan"),
+				Permission: "view",
+				Subject:    sub("user", "owner", ""),
+				Context:    mustContext(generateMap(64)),
+			},
+			expected{errorCode: codes.InvalidArgument},
+		},
+	}
+
+	request := &experimentalv1.BulkCheckPermissionRequest{
+		Consistency: &v1.Consistency{
+			Requirement: &v1.Consistency_AtLeastAsFresh{
+				AtLeastAsFresh: zedtoken.MustNewFromRevision(revision),
+			},
+		},
+	}
+	for _, tc := range testCases {
+		request.Items = append(request.Items, tc.item)
+	}
+
+	resp, err := client.BulkCheckPermission(context.Background(), request)
+	req.NoError(err)
+	req.NotNil(resp.CheckedAt)
+	req.Len(resp.Pairs, len(testCases))
+
+	for index, tc := range testCases {
+		pair := resp.Pairs[index]
+		req.Equal(tc.item.Resource.ObjectId, pair.Request.Resource.ObjectId)
+		req.Equal(tc.item.Permission, pair.Request.Permission)
+
+		if tc.expected.errorCode != codes.OK {
+			req.NotNil(pair.GetError(), "expected error for item %d", index)
+			req.Equal(int32(tc.expected.errorCode), pair.GetError().Code)
+			continue
+		}
+
+		req.Nil(pair.GetError(), "unexpected error for item %d", index)
+		req.Equal(tc.expected.permissionship, pair.GetItem().Permissionship, "wrong permissionship for item %d", index)
+		if tc.expected.missingContext != nil {
+			req.Equal(tc.expected.missingContext, pair.GetItem().PartialCaveatInfo.MissingRequiredContext)
+		} else {
+			req.Nil(pair.GetItem().PartialCaveatInfo)
+		}
+	}
+}
+
+func TestBulkCheckPermissionManyResources(t *testing.T) {
+	req := require.New(t)
+
+	resourceCount := int(datastore.FilterMaximumIDCount)*2 + 5
+	conn, cleanup, _, revision := testserver.NewTestServer(
+		req,
+		testTimedeltas[0],
+		memdb.DisableGC,
+		true,
+		func(ds datastore.Datastore, assertions *require.Assertions) (datastore.Datastore, datastore.Revision) {
+			relationships := make([]*core.RelationTuple, 0, resourceCount)
+			for i := 0; i < resourceCount; i += 2 {
+				relationships = append(relationships, tuple.MustParse(fmt.Sprintf("document:doc%d#viewer@user:tom", i)))
+			}
+
+			return tf.DatastoreFromSchemaAndTestRelationships(
+				ds,
+				`definition user {}
+
+				 definition document {
+					relation viewer: user
+					permission view = viewer
+				 }
+				`,
+				relationships,
+				assertions,
+			)
+		})
+	client := experimentalv1.NewExperimentalServiceClient(conn)
+	t.Cleanup(cleanup)
+
+	request := &experimentalv1.BulkCheckPermissionRequest{
+		Consistency: &v1.Consistency{
+			Requirement: &v1.Consistency_AtLeastAsFresh{
+				AtLeastAsFresh: zedtoken.MustNewFromRevision(revision),
+			},
+		},
+	}
+
+	// Include each resource twice, to ensure duplicates within a group are handled.
+	for repeat := 0; repeat < 2; repeat++ {
+		for i := 0; i < resourceCount; i++ {
+			request.Items = append(request.Items, &experimentalv1.BulkCheckPermissionRequestItem{
+				Resource:   obj("document", fmt.Sprintf("doc%d", i)),
+				Permission: "view",
+				Subject:    sub("user", "tom", ""),
+			})
+		}
+	}
+
+	resp, err := client.BulkCheckPermission(context.Background(), request)
+	req.NoError(err)
+	req.Len(resp.Pairs, len(request.Items))
+
+	for index, pair := range resp.Pairs {
+		req.Equal(request.Items[index].Resource.ObjectId, pair.Request.Resource.ObjectId)
+		req.Nil(pair.GetError())
+
+		expected := v1.CheckPermissionResponse_PERMISSIONSHIP_NO_PERMISSION
+		if (index%resourceCount)%2 == 0 {
+			expected = v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION
+		}
+		req.Equal(expected, pair.GetItem().Permissionship, "wrong permissionship for %s", pair.Request.Resource.ObjectId)
+	}
+}
+
+func TestBulkCheckPermissionTooManyItems(t *testing.T) {
+	req := require.New(t)
+	conn, cleanup, _, revision := testserver.NewTestServer(req, testTimedeltas[0], memdb.DisableGC, true, tf.StandardDatastoreWithData)
+	client := experimentalv1.NewExperimentalServiceClient(conn)
+	t.Cleanup(cleanup)
+
+	request := &experimentalv1.BulkCheckPermissionRequest{
+		Consistency: &v1.Consistency{
+			Requirement: &v1.Consistency_AtLeastAsFresh{
+				AtLeastAsFresh: zedtoken.MustNewFromRevision(revision),
+			},
+		},
+	}
+	for i := 0; i <= 1000; i++ {
+		request.Items = append(request.Items, &experimentalv1.BulkCheckPermissionRequestItem{
+			Resource:   obj("document", fmt.Sprintf("doc%d", i)),
+			Permission: "view",
+			Subject:    sub("user", "tom", ""),
+		})
+	}
+
+	_, err := client.BulkCheckPermission(context.Background(), request)
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+	req.ErrorContains(err, "check count of 1001 is greater than maximum allowed of 1000")
+
+	request.Items = request.Items[:1000]
+	resp, err := client.BulkCheckPermission(context.Background(), request)
+	req.NoError(err)
+	req.Len(resp.Pairs, 1000)
+}
+
+func TestLookupResourcesWithCursors(t *testing.T) {
+	req := require.New(t)
+
+	conn, cleanup, _, revision := testserver.NewTestServer(
+		req,
+		testTimedeltas[0],
+		memdb.DisableGC,
+		true,
+		func(ds datastore.Datastore, assertions *require.Assertions) (datastore.Datastore, datastore.Revision) {
+			relationships := make([]*core.RelationTuple, 0)
+			for i := 0; i < 50; i++ {
+				if i%2 == 0 {
+					relationships = append(relationships, tuple.MustParse(fmt.Sprintf("document:doc%d#viewer@user:tom", i)))
+				}
+				relationships = append(relationships, tuple.MustParse(fmt.Sprintf("document:doc%d#folder@folder:folder%d", i, i%3)))
+			}
+			relationships = append(relationships, tuple.MustParse("folder:folder1#viewer@user:tom"))
+
+			return tf.DatastoreFromSchemaAndTestRelationships(
+				ds,
+				`definition user {}
+
+				 definition folder {
+					relation viewer: user
+				 }
+
+				 definition document {
+					relation folder: folder
+					relation viewer: user
+					permission view = viewer + folder->viewer
+				 }
+				`,
+				relationships,
+				assertions,
+			)
+		})
+	client := experimentalv1.NewExperimentalServiceClient(conn)
+	t.Cleanup(cleanup)
+
+	lookup := func(limit uint32, optionalCursor *experimentalv1.Cursor) ([]*experimentalv1.LookupResourcesResponse, error) {
+		stream, err := client.LookupResources(context.Background(), &experimentalv1.LookupResourcesRequest{
+			ResourceObjectType: "document",
+			Permission:         "view",
+			Subject:            sub("user", "tom", ""),
+			Consistency: &v1.Consistency{
+				Requirement: &v1.Consistency_AtLeastAsFresh{
+					AtLeastAsFresh: zedtoken.MustNewFromRevision(revision),
+				},
+			},
+			OptionalLimit:  limit,
+			OptionalCursor: optionalCursor,
+		})
+		req.NoError(err)
+
+		results := make([]*experimentalv1.LookupResourcesResponse, 0)
+		for {
+			resp, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				return results, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, resp)
+		}
+	}
+
+	expected := make([]string, 0)
+	for i := 0; i < 50; i++ {
+		if i%2 == 0 || i%3 == 1 {
+			expected = append(expected, fmt.Sprintf("doc%d", i))
+		}
+	}
+
+	all, err := lookup(0, nil)
+	req.NoError(err)
+
+	allIDs := make([]string, 0, len(all))
+	for _, result := range all {
+		req.NotNil(result.AfterResultCursor)
+		allIDs = append(allIDs, result.ResourceObjectId)
+	}
+	req.ElementsMatch(expected, allIDs)
+
+	for _, pageSize := range []uint32{1, 3, 10, 100} {
+		pageSize := pageSize
+		t.Run(fmt.Sprintf("page-size-%d", pageSize), func(t *testing.T) {
+			// NOTE: a resource reachable via multiple paths can be returned on more than one page.
+			seen := make(map[string]struct{}, len(expected))
+			found := make([]string, 0, len(expected))
+			var currentCursor *experimentalv1.Cursor
+			for {
+				page, err := lookup(pageSize, currentCursor)
+				require.NoError(t, err)
+				require.LessOrEqual(t, len(page), int(pageSize))
+				if len(page) == 0 {
+					break
+				}
+
+				for _, result := range page {
+					if _, ok := seen[result.ResourceObjectId]; !ok {
+						seen[result.ResourceObjectId] = struct{}{}
+						found = append(found, result.ResourceObjectId)
+					}
+				}
+				currentCursor = page[len(page)-1].AfterResultCursor
+			}
+
+			require.Equal(t, allIDs, found)
+		})
+	}
+
+	// Ensure a cursor cannot be used with a different call.
+	stream, err := client.LookupResources(context.Background(), &experimentalv1.LookupResourcesRequest{
+		ResourceObjectType: "document",
+		Permission:         "viewer",
+		Subject:            sub("user", "tom", ""),
+		OptionalCursor:     all[0].AfterResultCursor,
+	})
+	req.NoError(err)
+
+	_, err = stream.Recv()
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+
+	// Ensure an invalid cursor is rejected.
+	_, err = lookup(0, &experimentalv1.Cursor{Token: "invalid"})
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+}
+
+func TestLookupSubjectsWithCursors(t *testing.T) {
+	req := require.New(t)
+
+	conn, cleanup, _, revision := testserver.NewTestServer(
+		req,
+		testTimedeltas[0],
+		memdb.DisableGC,
+		true,
+		func(ds datastore.Datastore, assertions *require.Assertions) (datastore.Datastore, datastore.Revision) {
+			relationships := []*core.RelationTuple{
+				tuple.MustParse("document:doc1#viewer@group:group1#member"),
+				tuple.MustParse("document:doc1#editor@user:u03"),
+				tuple.MustParse("document:doc1#editor@user:u25"),
+				tuple.MustParse("document:doc2#viewer@user:*"),
+				tuple.MustParse("document:doc2#banned@user:u02"),
+				tuple.MustParse("document:doc2#banned@user:u30"),
+				tuple.MustParse("document:doc2#banned@user:u40"),
+				tuple.MustParse("document:doc2#editor@user:u30"),
+			}
+			for i := 0; i < 20; i++ {
+				if i%5 == 0 && i > 0 {
+					relationships = append(relationships, tuple.MustWithCaveat(
+						tuple.MustParse(fmt.Sprintf("group:group1#member@user:u%02d", i)),
+						"somecaveat",
+					))
+					continue
+				}
+				relationships = append(relationships, tuple.MustParse(fmt.Sprintf("group:group1#member@user:u%02d", i)))
+			}
+			for i := 1; i <= 5; i++ {
+				relationships = append(relationships, tuple.MustParse(fmt.Sprintf("document:doc2#editor@user:u%02d", i)))
+			}
+
+			return tf.DatastoreFromSchemaAndTestRelationships(
+				ds,
+				`definition user {}
+
+				 caveat somecaveat(somecondition int) {
+					somecondition == 42
+				 }
+
+				 definition group {
+					relation member: user | user with somecaveat
+				 }
+
+				 definition document {
+					relation viewer: user | user:* | group#member
+					relation editor: user
+					relation banned: user
+					permission nonbanned = viewer - banned
+					permission view = viewer + editor
+					permission view_all = nonbanned + editor
+				 }
+				`,
+				relationships,
+				assertions,
+			)
+		})
+	client := experimentalv1.NewExperimentalServiceClient(conn)
+	t.Cleanup(cleanup)
+
+	lookup := func(resourceID string, permission string, limit uint32, optionalCursor *experimentalv1.Cursor) ([]*experimentalv1.LookupSubjectsResponse, error) {
+		caveatContext, err := structpb.NewStruct(map[string]any{"somecondition": 41})
+		req.NoError(err)
+
+		stream, err := client.LookupSubjects(context.Background(), &experimentalv1.LookupSubjectsRequest{
+			Resource:          obj("document", resourceID),
+			Permission:        permission,
+			SubjectObjectType: "user",
+			Context:           caveatContext,
+			Consistency: &v1.Consistency{
+				Requirement: &v1.Consistency_AtLeastAsFresh{
+					AtLeastAsFresh: zedtoken.MustNewFromRevision(revision),
+				},
+			},
+			OptionalConcreteLimit: limit,
+			OptionalCursor:        optionalCursor,
+		})
+		req.NoError(err)
+
+		results := make([]*experimentalv1.LookupSubjectsResponse, 0)
+		for {
+			resp, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				return results, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, resp)
+		}
+	}
+
+	tcs := []struct {
+		resourceID        string
+		permission        string
+		expectedSubjects  []string
+		expectedExclusion []string
+	}{
+		{
+			"doc1",
+			"view",
+			[]string{"u00", "u01", "u02", "u03", "u04", "u06", "u07", "u08", "u09", "u11", "u12", "u13", "u14", "u16", "u17", "u18", "u19", "u25"},
+			nil,
+		},
+		{
+			"doc2",
+			"view_all",
+			[]string{"*", "u01", "u02", "u03", "u04", "u05", "u30"},
+			[]string{"u40"},
+		},
+		{
+			"doc2",
+			"nonbanned",
+			[]string{"*"},
+			[]string{"u02", "u30", "u40"},
+		},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		for _, pageSize := range []uint32{0, 1, 3, 7, 100} {
+			pageSize := pageSize
+			t.Run(fmt.Sprintf("%s-%s-page-size-%d", tc.resourceID, tc.permission, pageSize), func(t *testing.T) {
+				found := make([]string, 0, len(tc.expectedSubjects))
+				var foundExclusions []string
+				var currentCursor *experimentalv1.Cursor
+				for {
+					page, err := lookup(tc.resourceID, tc.permission, pageSize, currentCursor)
+					require.NoError(t, err)
+					if len(page) == 0 {
+						break
+					}
+
+					concreteCount := 0
+					for _, result := range page {
+						require.NotNil(t, result.AfterResultCursor)
+						found = append(found, result.Subject.SubjectObjectId)
+						if result.Subject.SubjectObjectId == tuple.PublicWildcard {
+							for _, excluded := range result.ExcludedSubjects {
+								foundExclusions = append(foundExclusions, excluded.SubjectObjectId)
+							}
+							continue
+						}
+						concreteCount++
+					}
+
+					if pageSize > 0 {
+						require.LessOrEqual(t, concreteCount, int(pageSize))
+					}
+					currentCursor = page[len(page)-1].AfterResultCursor
+				}
+
+				require.Equal(t, tc.expectedSubjects, found)
+				require.ElementsMatch(t, tc.expectedExclusion, foundExclusions)
+			})
+		}
+	}
+
+	// Ensure a cursor cannot be used with a different call.
+	all, err := lookup("doc1", "view", 0, nil)
+	req.NoError(err)
+
+	_, err = lookup("doc1", "viewer", 0, all[0].AfterResultCursor)
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+
+	// Ensure an invalid cursor is rejected.
+	_, err = lookup("doc1", "view", 0, &experimentalv1.Cursor{Token: "invalid"})
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+}
+
+func TestContextualRelationships(t *testing.T) {
+	req := require.New(t)
+	conn, cleanup, _, revision := testserver.NewTestServer(req, testTimedeltas[0], memdb.DisableGC, true, tf.StandardDatastoreWithData)
+	client := experimentalv1.NewExperimentalServiceClient(conn)
+	t.Cleanup(cleanup)
+
+	consistency := &v1.Consistency{
+		Requirement: &v1.Consistency_AtLeastAsFresh{
+			AtLeastAsFresh: zedtoken.MustNewFromRevision(revision),
+		},
+	}
+
+	check := func(contextual ...*v1.Relationship) (v1.CheckPermissionResponse_Permissionship, error) {
+		resp, err := client.CheckPermission(context.Background(), &experimentalv1.CheckPermissionRequest{
+			Consistency:             consistency,
+			Resource:                obj("document", "masterplan"),
+			Permission:              "view",
+			Subject:                 sub("user", "villain", ""),
+			ContextualRelationships: contextual,
+		})
+		if err != nil {
+			return v1.CheckPermissionResponse_PERMISSIONSHIP_UNSPECIFIED, err
+		}
+		return resp.Permissionship, nil
+	}
+
+	lookupResources := func(contextual ...*v1.Relationship) []string {
+		stream, err := client.LookupResources(context.Background(), &experimentalv1.LookupResourcesRequest{
+			Consistency:             consistency,
+			ResourceObjectType:      "document",
+			Permission:              "view",
+			Subject:                 sub("user", "villain", ""),
+			ContextualRelationships: contextual,
+		})
+		req.NoError(err)
+
+		found := make([]string, 0)
+		for {
+			resp, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				return found
+			}
+			req.NoError(err)
+			found = append(found, resp.ResourceObjectId)
+		}
+	}
+
+	lookupSubjects := func(contextual ...*v1.Relationship) []string {
+		stream, err := client.LookupSubjects(context.Background(), &experimentalv1.LookupSubjectsRequest{
+			Consistency:             consistency,
+			Resource:                obj("document", "healthplan"),
+			Permission:              "view",
+			SubjectObjectType:       "user",
+			ContextualRelationships: contextual,
+		})
+		req.NoError(err)
+
+		found := make([]string, 0)
+		for {
+			resp, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				return found
+			}
+			req.NoError(err)
+			found = append(found, resp.Subject.SubjectObjectId)
+		}
+	}
+
+	inPlansFolder := tuple.ParseRel("folder:plans#viewer@user:villain")
+
+	// Contextual relationships are considered only for the request in which they are given,
+	// which also ensures that cached results are not shared between the requests.
+	for i := 0; i < 2; i++ {
+		permissionship, err := check()
+		req.NoError(err)
+		req.Equal(v1.CheckPermissionResponse_PERMISSIONSHIP_NO_PERMISSION, permissionship)
+
+		permissionship, err = check(inPlansFolder)
+		req.NoError(err)
+		req.Equal(v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION, permissionship)
+
+		req.Empty(lookupResources())
+		req.ElementsMatch([]string{"masterplan", "healthplan"}, lookupResources(inPlansFolder))
+
+		req.ElementsMatch([]string{"chief_financial_officer"}, lookupSubjects())
+		req.ElementsMatch([]string{"chief_financial_officer", "villain"}, lookupSubjects(inPlansFolder))
+	}
+
+	// Contextual relationships must be valid for the schema.
+	_, err := check(tuple.ParseRel("folder:plans#viewer@document:masterplan"))
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+
+	_, err = check(tuple.ParseRel("folder:plans#unknown@user:villain"))
+	grpcutil.RequireStatus(t, codes.FailedPrecondition, err)
+
+	_, err = check(inPlansFolder, inPlansFolder)
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+}
+
+func TestLookupPermissions(t *testing.T) {
+	req := require.New(t)
+	conn, cleanup, _, revision := testserver.NewTestServer(req, testTimedeltas[0], memdb.DisableGC, true, tf.StandardDatastoreWithData)
+	client := experimentalv1.NewExperimentalServiceClient(conn)
+	t.Cleanup(cleanup)
+
+	lookup := func(resource *v1.ObjectReference, subject *v1.SubjectReference) (map[string]v1.CheckPermissionResponse_Permissionship, []string, error) {
+		resp, err := client.LookupPermissions(context.Background(), &experimentalv1.LookupPermissionsRequest{
+			Consistency: &v1.Consistency{
+				Requirement: &v1.Consistency_AtLeastAsFresh{
+					AtLeastAsFresh: zedtoken.MustNewFromRevision(revision),
+				},
+			},
+			Resource: resource,
+			Subject:  subject,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		req.NotNil(resp.CheckedAt)
+
+		found := make(map[string]v1.CheckPermissionResponse_Permissionship, len(resp.Results))
+		order := make([]string, 0, len(resp.Results))
+		for _, result := range resp.Results {
+			found[result.Permission] = result.Permissionship
+			order = append(order, result.Permission)
+
+			isPermission := result.Permission == "edit" || result.Permission == "view" || result.Permission == "view_and_edit"
+			req.Equal(isPermission, result.IsPermission, result.Permission)
+		}
+		return found, order, nil
+	}
+
+	has := v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION
+	no := v1.CheckPermissionResponse_PERMISSIONSHIP_NO_PERMISSION
+
+	testCases := []struct {
+		name     string
+		resource *v1.ObjectReference
+		subject  *v1.SubjectReference
+		expected map[string]v1.CheckPermissionResponse_Permissionship
+	}{
+		{
+			"owner",
+			obj("document", "masterplan"),
+			sub("user", "product_manager", ""),
+			map[string]v1.CheckPermissionResponse_Permissionship{
+				"owner": has, "editor": no, "viewer": no, "viewer_and_editor": no, "caveated_viewer": no,
+				"parent": no, "edit": has, "view": has, "view_and_edit": no,
+			},
+		},
+		{
+			"viewer",
+			obj("document", "masterplan"),
+			sub("user", "eng_lead", ""),
+			map[string]v1.CheckPermissionResponse_Permissionship{
+				"owner": no, "editor": no, "viewer": has, "viewer_and_editor": no, "caveated_viewer": no,
+				"parent": no, "edit": no, "view": has, "view_and_edit": no,
+			},
+		},
+		{
+			"via parent",
+			obj("document", "masterplan"),
+			sub("user", "vp_product", ""),
+			map[string]v1.CheckPermissionResponse_Permissionship{
+				"owner": no, "editor": no, "viewer": no, "viewer_and_editor": no, "caveated_viewer": no,
+				"parent": no, "edit": no, "view": has, "view_and_edit": no,
+			},
+		},
+		{
+			"intersection",
+			obj("document", "specialplan"),
+			sub("user", "multiroleguy", ""),
+			map[string]v1.CheckPermissionResponse_Permissionship{
+				"owner": no, "editor": has, "viewer": no, "viewer_and_editor": has, "caveated_viewer": no,
+				"parent": no, "edit": has, "view": has, "view_and_edit": has,
+			},
+		},
+		{
+			"subject relation",
+			obj("document", "masterplan"),
+			sub("folder", "plans", ""),
+			map[string]v1.CheckPermissionResponse_Permissionship{
+				"owner": no, "editor": no, "viewer": no, "viewer_and_editor": no, "caveated_viewer": no,
+				"parent": has, "edit": no, "view": no, "view_and_edit": no,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			found, order, err := lookup(tc.resource, tc.subject)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, found)
+
+			// Results are returned in the order of the definition.
+			require.Equal(t, []string{
+				"owner", "editor", "viewer", "viewer_and_editor", "caveated_viewer", "parent", "edit", "view", "view_and_edit",
+			}, order)
+		})
+	}
+
+	_, _, err := lookup(obj("unknown", "masterplan"), sub("user", "product_manager", ""))
+	grpcutil.RequireStatus(t, codes.FailedPrecondition, err)
+
+	_, _, err = lookup(obj("document", "masterplan"), sub("unknown", "product_manager", ""))
+	grpcutil.RequireStatus(t, codes.FailedPrecondition, err)
+}
+
+func TestReadRelationshipsWithCursors(t *testing.T) {
+	req := require.New(t)
+	conn, cleanup, _, _ := testserver.NewTestServer(req, testTimedeltas[0], memdb.DisableGC, true, tf.StandardDatastoreWithData)
+	client := experimentalv1.NewExperimentalServiceClient(conn)
+	permissionsClient := v1.NewPermissionsServiceClient(conn)
+	t.Cleanup(cleanup)
+
+	filter := &v1.RelationshipFilter{ResourceType: "folder"}
+
+	read := func(filter *v1.RelationshipFilter, limit uint32, optionalCursor *experimentalv1.Cursor) ([]*experimentalv1.ReadRelationshipsResponse, error) {
+		stream, err := client.ReadRelationships(context.Background(), &experimentalv1.ReadRelationshipsRequest{
+			Consistency: &v1.Consistency{
+				Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true},
+			},
+			RelationshipFilter: filter,
+			OptionalLimit:      limit,
+			OptionalCursor:     optionalCursor,
+		})
+		req.NoError(err)
+
+		results := make([]*experimentalv1.ReadRelationshipsResponse, 0)
+		for {
+			resp, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				return results, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, resp)
+		}
+	}
+
+	all, err := read(filter, 0, nil)
+	req.NoError(err)
+
+	allRels := make([]string, 0, len(all))
+	for _, result := range all {
+		req.NotNil(result.AfterResultCursor)
+		allRels = append(allRels, tuple.MustStringRelationship(result.Relationship))
+	}
+	req.Len(allRels, 8)
+
+	for _, pageSize := range []uint32{1, 3, 8, 100} {
+		pageSize := pageSize
+		t.Run(fmt.Sprintf("page-size-%d", pageSize), func(t *testing.T) {
+			found := make([]string, 0, len(allRels))
+			var currentCursor *experimentalv1.Cursor
+			for {
+				page, err := read(filter, pageSize, currentCursor)
+				require.NoError(t, err)
+				require.LessOrEqual(t, len(page), int(pageSize))
+				if len(page) == 0 {
+					break
+				}
+
+				for _, result := range page {
+					found = append(found, tuple.MustStringRelationship(result.Relationship))
+				}
+				currentCursor = page[len(page)-1].AfterResultCursor
+			}
+
+			require.Equal(t, allRels, found)
+		})
+	}
+
+	// Ensure pages resumed from a cursor are read at the revision of the first page.
+	firstPage, err := read(filter, 4, nil)
+	req.NoError(err)
+
+	_, err = permissionsClient.WriteRelationships(context.Background(), &v1.WriteRelationshipsRequest{
+		Updates: []*v1.RelationshipUpdate{{
+			Operation:    v1.RelationshipUpdate_OPERATION_CREATE,
+			Relationship: tuple.ParseRel("folder:zzz#viewer@user:villain"),
+		}},
+	})
+	req.NoError(err)
+
+	secondPage, err := read(filter, 100, firstPage[len(firstPage)-1].AfterResultCursor)
+	req.NoError(err)
+	req.Len(secondPage, 4)
+	req.Equal(firstPage[0].ReadAt.Token, secondPage[0].ReadAt.Token)
+
+	fresh, err := read(filter, 0, nil)
+	req.NoError(err)
+	req.Len(fresh, 9)
+
+	// Ensure a cursor cannot be used with a different filter.
+	_, err = read(&v1.RelationshipFilter{ResourceType: "document"}, 0, all[0].AfterResultCursor)
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+
+	// Ensure an invalid cursor is rejected.
+	_, err = read(filter, 0, &experimentalv1.Cursor{Token: "invalid"})
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+}
+
+func TestDeleteRelationshipsWithLimit(t *testing.T) {
+	req := require.New(t)
+	conn, cleanup, _, _ := testserver.NewTestServer(req, testTimedeltas[0], memdb.DisableGC, true, tf.StandardDatastoreWithData)
+	client := experimentalv1.NewExperimentalServiceClient(conn)
+	permissionsClient := v1.NewPermissionsServiceClient(conn)
+	t.Cleanup(cleanup)
+
+	filter := &v1.RelationshipFilter{
+		ResourceType:     "folder",
+		OptionalRelation: "viewer",
+	}
+
+	countRemaining := func() int {
+		stream, err := permissionsClient.ReadRelationships(context.Background(), &v1.ReadRelationshipsRequest{
+			Consistency: &v1.Consistency{
+				Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true},
+			},
+			RelationshipFilter: filter,
+		})
+		req.NoError(err)
+
+		count := 0
+		for {
+			_, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				return count
+			}
+			req.NoError(err)
+			count++
+		}
+	}
+
+	req.Equal(5, countRemaining())
+
+	// A limit above the maximum is rejected.
+	_, err := client.DeleteRelationships(context.Background(), &experimentalv1.DeleteRelationshipsRequest{
+		RelationshipFilter: filter,
+		OptionalLimit:      1001,
+	})
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+
+	// Without partial deletions, matching more relationships than the limit deletes nothing.
+	_, err = client.DeleteRelationships(context.Background(), &experimentalv1.DeleteRelationshipsRequest{
+		RelationshipFilter: filter,
+		OptionalLimit:      2,
+	})
+	grpcutil.RequireStatus(t, codes.FailedPrecondition, err)
+	req.Equal(5, countRemaining())
+
+	// With partial deletions, relationships are deleted up to the limit.
+	resp, err := client.DeleteRelationships(context.Background(), &experimentalv1.DeleteRelationshipsRequest{
+		RelationshipFilter:            filter,
+		OptionalLimit:                 2,
+		OptionalAllowPartialDeletions: true,
+	})
+	req.NoError(err)
+	req.NotNil(resp.DeletedAt)
+	req.Equal(experimentalv1.DeleteRelationshipsResponse_DELETION_PROGRESS_PARTIAL, resp.DeletionProgress)
+	req.Equal(uint64(2), resp.RelationshipsDeletedCount)
+	req.Equal(3, countRemaining())
+
+	// Deleting exactly the remaining relationships completes the deletion.
+	resp, err = client.DeleteRelationships(context.Background(), &experimentalv1.DeleteRelationshipsRequest{
+		RelationshipFilter:            filter,
+		OptionalLimit:                 3,
+		OptionalAllowPartialDeletions: true,
+	})
+	req.NoError(err)
+	req.Equal(experimentalv1.DeleteRelationshipsResponse_DELETION_PROGRESS_COMPLETE, resp.DeletionProgress)
+	req.Equal(uint64(3), resp.RelationshipsDeletedCount)
+	req.Equal(0, countRemaining())
+
+	resp, err = client.DeleteRelationships(context.Background(), &experimentalv1.DeleteRelationshipsRequest{
+		RelationshipFilter: filter,
+	})
+	req.NoError(err)
+	req.Equal(experimentalv1.DeleteRelationshipsResponse_DELETION_PROGRESS_COMPLETE, resp.DeletionProgress)
+	req.Equal(uint64(0), resp.RelationshipsDeletedCount)
+}
+
+func TestUnchangedSincePreconditions(t *testing.T) {
+	req := require.New(t)
+	conn, cleanup, _, _ := testserver.NewTestServer(req, testTimedeltas[0], memdb.DisableGC, true, tf.StandardDatastoreWithData)
+	client := experimentalv1.NewExperimentalServiceClient(conn)
+	t.Cleanup(cleanup)
+
+	viewers := &v1.RelationshipFilter{
+		ResourceType:       "document",
+		OptionalResourceId: "masterplan",
+		OptionalRelation:   "viewer",
+	}
+
+	write := func(relationship string, preconditions ...*experimentalv1.UnchangedSincePrecondition) (*v1.ZedToken, error) {
+		resp, err := client.WriteRelationships(context.Background(), &experimentalv1.WriteRelationshipsRequest{
+			Updates: []*v1.RelationshipUpdate{{
+				Operation:    v1.RelationshipUpdate_OPERATION_TOUCH,
+				Relationship: tuple.MustToRelationship(tuple.MustParse(relationship)),
+			}},
+			OptionalUnchangedSincePreconditions: preconditions,
+		})
+		return resp.GetWrittenAt(), err
+	}
+
+	first, err := write("document:masterplan#viewer@user:alice")
+	req.NoError(err)
+
+	// Nothing matching has changed since the first write.
+	second, err := write("document:masterplan#viewer@user:bob", &experimentalv1.UnchangedSincePrecondition{
+		Filter:         viewers,
+		UnchangedSince: first,
+	})
+	req.NoError(err)
+
+	// The second write changed the viewers since the first.
+	_, err = write("document:masterplan#viewer@user:carol", &experimentalv1.UnchangedSincePrecondition{
+		Filter:         viewers,
+		UnchangedSince: first,
+	})
+	grpcutil.RequireStatus(t, codes.FailedPrecondition, err)
+	spiceerrors.RequireReason(t, v1.ErrorReason_ERROR_REASON_WRITE_OR_DELETE_PRECONDITION_FAILURE, err,
+		"precondition_resource_type",
+		"precondition_resource_id",
+		"precondition_relation",
+		"precondition_unchanged_since",
+	)
+
+	// Changes to relationships not matching the filter are ignored.
+	_, err = write("document:masterplan#viewer@user:carol", &experimentalv1.UnchangedSincePrecondition{
+		Filter:         &v1.RelationshipFilter{ResourceType: "document", OptionalResourceId: "healthplan"},
+		UnchangedSince: first,
+	})
+	req.NoError(err)
+
+	// Deletions are conditioned in the same way, and delete nothing if the precondition fails.
+	_, err = client.DeleteRelationships(context.Background(), &experimentalv1.DeleteRelationshipsRequest{
+		RelationshipFilter: viewers,
+		OptionalUnchangedSincePreconditions: []*experimentalv1.UnchangedSincePrecondition{{
+			Filter:         viewers,
+			UnchangedSince: second,
+		}},
+	})
+	grpcutil.RequireStatus(t, codes.FailedPrecondition, err)
+
+	third, err := write("document:masterplan#viewer@user:dave")
+	req.NoError(err)
+
+	resp, err := client.DeleteRelationships(context.Background(), &experimentalv1.DeleteRelationshipsRequest{
+		RelationshipFilter: viewers,
+		OptionalUnchangedSincePreconditions: []*experimentalv1.UnchangedSincePrecondition{{
+			Filter:         viewers,
+			UnchangedSince: third,
+		}},
+	})
+	req.NoError(err)
+	req.Equal(uint64(5), resp.RelationshipsDeletedCount)
+
+	// An invalid ZedToken is rejected.
+	_, err = write("document:masterplan#viewer@user:alice", &experimentalv1.UnchangedSincePrecondition{
+		Filter:         viewers,
+		UnchangedSince: &v1.ZedToken{Token: "invalid"},
+	})
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+}
+
+func TestIdempotencyKeys(t *testing.T) {
+	req := require.New(t)
+	conn, cleanup, _, _ := testserver.NewTestServer(req, testTimedeltas[0], memdb.DisableGC, true, tf.StandardDatastoreWithData)
+	client := experimentalv1.NewExperimentalServiceClient(conn)
+	t.Cleanup(cleanup)
+
+	viewers := &v1.RelationshipFilter{
+		ResourceType:       "document",
+		OptionalResourceId: "masterplan",
+		OptionalRelation:   "viewer",
+	}
+
+	countViewers := func() int {
+		stream, err := v1.NewPermissionsServiceClient(conn).ReadRelationships(context.Background(), &v1.ReadRelationshipsRequest{
+			Consistency:        &v1.Consistency{Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true}},
+			RelationshipFilter: viewers,
+		})
+		req.NoError(err)
+
+		count := 0
+		for {
+			_, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				return count
+			}
+			req.NoError(err)
+			count++
+		}
+	}
+
+	write := func(relationship string, idempotencyKey string) *v1.ZedToken {
+		resp, err := client.WriteRelationships(context.Background(), &experimentalv1.WriteRelationshipsRequest{
+			Updates: []*v1.RelationshipUpdate{{
+				Operation:    v1.RelationshipUpdate_OPERATION_CREATE,
+				Relationship: tuple.MustToRelationship(tuple.MustParse(relationship)),
+			}},
+			OptionalIdempotencyKey: idempotencyKey,
+		})
+		req.NoError(err)
+		return resp.WrittenAt
+	}
+
+	before := countViewers()
+	written := write("document:masterplan#viewer@user:alice", "write-key")
+
+	// A retry with the same key is not applied, and returns the original ZedToken.
+	retried := write("document:masterplan#viewer@user:alice", "write-key")
+	req.Equal(written.Token, retried.Token)
+	req.Equal(before+1, countViewers())
+
+	// A different write with the same key is not applied, and fails.
+	_, err := client.WriteRelationships(context.Background(), &experimentalv1.WriteRelationshipsRequest{
+		Updates: []*v1.RelationshipUpdate{{
+			Operation:    v1.RelationshipUpdate_OPERATION_CREATE,
+			Relationship: tuple.MustToRelationship(tuple.MustParse("document:masterplan#viewer@user:bob")),
+		}},
+		OptionalIdempotencyKey: "write-key",
+	})
+	grpcutil.RequireStatus(t, codes.FailedPrecondition, err)
+	req.Equal(before+1, countViewers())
+
+	deleteViewers := func(idempotencyKey string, limit uint32) (*experimentalv1.DeleteRelationshipsResponse, error) {
+		return client.DeleteRelationships(context.Background(), &experimentalv1.DeleteRelationshipsRequest{
+			RelationshipFilter:            viewers,
+			OptionalLimit:                 limit,
+			OptionalAllowPartialDeletions: limit > 0,
+			OptionalIdempotencyKey:        idempotencyKey,
+		})
+	}
+
+	deleted, err := deleteViewers("delete-key", 0)
+	req.NoError(err)
+	req.Equal(uint64(before+1), deleted.RelationshipsDeletedCount)
+	req.Equal(0, countViewers())
+
+	write("document:masterplan#viewer@user:alice", "")
+	write("document:masterplan#viewer@user:bob", "")
+
+	// A retried deletion deletes nothing, and returns the original ZedToken. The deleted count
+	// is not stored, but a deletion without a limit is known to have completed.
+	retriedDelete, err := deleteViewers("delete-key", 0)
+	req.NoError(err)
+	req.Equal(deleted.DeletedAt.Token, retriedDelete.DeletedAt.Token)
+	req.Equal(experimentalv1.DeleteRelationshipsResponse_DELETION_PROGRESS_COMPLETE, retriedDelete.DeletionProgress)
+	req.Equal(uint64(0), retriedDelete.RelationshipsDeletedCount)
+	req.Equal(2, countViewers())
+
+	// A different deletion with the same key deletes nothing, and fails.
+	_, err = deleteViewers("delete-key", 1)
+	grpcutil.RequireStatus(t, codes.FailedPrecondition, err)
+	req.Equal(2, countViewers())
+
+	// A retried deletion which allows partial deletions may have stopped at the limit, and so
+	// is reported as partial.
+	partial, err := deleteViewers("partial-key", 1)
+	req.NoError(err)
+	req.Equal(experimentalv1.DeleteRelationshipsResponse_DELETION_PROGRESS_PARTIAL, partial.DeletionProgress)
+	req.Equal(1, countViewers())
+
+	retriedPartial, err := deleteViewers("partial-key", 1)
+	req.NoError(err)
+	req.Equal(partial.DeletedAt.Token, retriedPartial.DeletedAt.Token)
+	req.Equal(experimentalv1.DeleteRelationshipsResponse_DELETION_PROGRESS_PARTIAL, retriedPartial.DeletionProgress)
+	req.Equal(1, countViewers())
+}
+
+func TestBulkImportRelationships(t *testing.T) {
+	testCases := []struct {
+		name       string
+		batchSize  int
+		numBatches int
+		withCaveat bool
+	}{
+		{"one small batch", 1, 1, false},
+		{"one large batch", 10_000, 1, false},
+		{"many small batches", 5, 1_000, false},
+		{"caveated", 100, 10, true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			conn, cleanup, ds, _ := testserver.NewTestServer(require, 0, memdb.DisableGC, true, tf.StandardDatastoreWithSchema)
+			client := experimentalv1.NewExperimentalServiceClient(conn)
+			t.Cleanup(cleanup)
+
+			ctx := context.Background()
+			writer, err := client.BulkImportRelationships(ctx)
+			require.NoError(err)
+
+			expectedTotal := tc.batchSize * tc.numBatches
+			for batchNum := 0; batchNum < tc.numBatches; batchNum++ {
+				batch := make([]*v1.Relationship, 0, tc.batchSize)
+				for i := 0; i < tc.batchSize; i++ {
+					relationship := rel("document", fmt.Sprintf("doc%d", batchNum), "viewer", "user", fmt.Sprintf("user%d", i), "")
+					if tc.withCaveat {
+						caveatContext, err := structpb.NewStruct(map[string]any{"secret": "1234"})
+						require.NoError(err)
+
+						relationship.Relation = "caveated_viewer"
+						relationship.OptionalCaveat = &v1.ContextualizedCaveat{
+							CaveatName: "test",
+							Context:    caveatContext,
+						}
+					}
+					batch = append(batch, relationship)
+				}
+
+				require.NoError(writer.Send(&experimentalv1.BulkImportRelationshipsRequest{
+					Relationships: batch,
+				}))
+			}
+
+			resp, err := writer.CloseAndRecv()
+			require.NoError(err)
+			require.Equal(uint64(expectedTotal), resp.NumLoaded)
+			require.NotNil(resp.WrittenAt)
+
+			writtenAt, err := zedtoken.DecodeRevision(resp.WrittenAt, ds)
+			require.NoError(err)
+
+			relation := "viewer"
+			if tc.withCaveat {
+				relation = "caveated_viewer"
+			}
+
+			iter, err := ds.SnapshotReader(writtenAt).QueryRelationships(ctx, datastore.RelationshipsFilter{
+				ResourceType:             "document",
+				OptionalResourceRelation: relation,
+			})
+			require.NoError(err)
+			defer iter.Close()
+
+			var count int
+			for tpl := iter.Next(); tpl != nil; tpl = iter.Next() {
+				require.Equal(tc.withCaveat, tpl.Caveat != nil)
+				count++
+			}
+			require.NoError(iter.Err())
+			require.Equal(expectedTotal, count)
+		})
+	}
+}
+
+func TestBulkImportRelationshipsErrors(t *testing.T) {
+	testCases := []struct {
+		name          string
+		relationships []*v1.Relationship
+		expectedCode  codes.Code
+	}{
+		{
+			"unknown resource type",
+			[]*v1.Relationship{rel("unknown", "foo", "viewer", "user", "tom", "")},
+			codes.FailedPrecondition,
+		},
+		{
+			"unknown relation",
+			[]*v1.Relationship{rel("document", "foo", "unknown", "user", "tom", "")},
+			codes.FailedPrecondition,
+		},
+		{
+			"write to permission",
+			[]*v1.Relationship{rel("document", "foo", "view", "user", "tom", "")},
+			codes.InvalidArgument,
+		},
+		{
+			"invalid subject type",
+			[]*v1.Relationship{rel("document", "foo", "viewer", "folder", "plans", "")},
+			codes.InvalidArgument,
+		},
+		{
+			"wildcard subject with relation",
+			[]*v1.Relationship{rel("document", "foo", "viewer", "user", "*", "viewer")},
+			codes.InvalidArgument,
+		},
+		{
+			"invalid relationship after valid ones",
+			[]*v1.Relationship{
+				rel("document", "foo", "viewer", "user", "tom", ""),
+				rel("document", "foo", "viewer", "user", "sarah", ""),
+				rel("document", "foo", "parent", "user", "tom", ""),
+			},
+			codes.InvalidArgument,
+		},
+		{
+			"already existing",
+			[]*v1.Relationship{rel("document", "masterplan", "viewer", "user", "eng_lead", "")},
+			codes.Unknown,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			conn, cleanup, ds, revision := testserver.NewTestServer(require, 0, memdb.DisableGC, true, tf.StandardDatastoreWithData)
+			client := experimentalv1.NewExperimentalServiceClient(conn)
+			t.Cleanup(cleanup)
+
+			ctx := context.Background()
+			writer, err := client.BulkImportRelationships(ctx)
+			require.NoError(err)
+
+			err = writer.Send(&experimentalv1.BulkImportRelationshipsRequest{
+				Relationships: tc.relationships,
+			})
+			if err == nil {
+				_, err = writer.CloseAndRecv()
+			}
+			grpcutil.RequireStatus(t, tc.expectedCode, err)
+
+			// Ensure that nothing was written.
+			headRevision, err := ds.HeadRevision(ctx)
+			require.NoError(err)
+			require.True(headRevision.Equal(revision))
+		})
+	}
+}
+
+func TestBulkExportRelationships(t *testing.T) {
+	conn, cleanup, _, _ := testserver.NewTestServer(require.New(t), 0, memdb.DisableGC, true, tf.StandardDatastoreWithData)
+	client := experimentalv1.NewExperimentalServiceClient(conn)
+	t.Cleanup(cleanup)
+
+	expected := make([]string, 0, len(tf.StandardTuples))
+	for _, tplString := range tf.StandardTuples {
+		expected = append(expected, tuple.MustString(tuple.MustParse(tplString)))
+	}
+
+	for _, batchSize := range []uint32{0, 1, 5, 1000} {
+		batchSize := batchSize
+		t.Run(fmt.Sprintf("batch-size-%d", batchSize), func(t *testing.T) {
+			require := require.New(t)
+
+			stream, err := client.BulkExportRelationships(context.Background(), &experimentalv1.BulkExportRelationshipsRequest{
+				OptionalBatchSize: batchSize,
+			})
+			require.NoError(err)
+
+			found := make([]string, 0, len(expected))
+			var exportedAt *v1.ZedToken
+			for {
+				resp, err := stream.Recv()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				require.NoError(err)
+				require.NotNil(resp.AfterResultCursor)
+				require.NotEmpty(resp.Relationships)
+				if batchSize > 0 {
+					require.LessOrEqual(len(resp.Relationships), int(batchSize))
+				}
+
+				// All batches must be read at the same revision.
+				if exportedAt == nil {
+					exportedAt = resp.ExportedAt
+				}
+				require.Equal(exportedAt.Token, resp.ExportedAt.Token)
+
+				for _, rel := range resp.Relationships {
+					found = append(found, tuple.MustString(tuple.MustFromRelationship(rel)))
+				}
+			}
+
+			require.ElementsMatch(expected, found)
+		})
+	}
+}
+
+func TestBulkExportRelationshipsResume(t *testing.T) {
+	require := require.New(t)
+
+	conn, cleanup, ds, _ := testserver.NewTestServer(require, 0, memdb.DisableGC, true, tf.StandardDatastoreWithData)
+	client := experimentalv1.NewExperimentalServiceClient(conn)
+	t.Cleanup(cleanup)
+
+	expected := make([]string, 0, len(tf.StandardTuples))
+	for _, tplString := range tf.StandardTuples {
+		expected = append(expected, tuple.MustString(tuple.MustParse(tplString)))
+	}
+
+	// Export a single batch at a time, resuming from the cursor of the previous batch, and
+	// writing a new relationship in between. The new relationships must not be exported, as
+	// a resumed export reads at the revision of its cursor, even if full consistency is requested.
+	found := make([]string, 0, len(expected))
+	var cursor *experimentalv1.Cursor
+	for i := 0; ; i++ {
+		stream, err := client.BulkExportRelationships(context.Background(), &experimentalv1.BulkExportRelationshipsRequest{
+			Consistency: &v1.Consistency{
+				Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true},
+			},
+			OptionalBatchSize: 3,
+			OptionalCursor:    cursor,
+		})
+		require.NoError(err)
+
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(err)
+
+		for _, rel := range resp.Relationships {
+			found = append(found, tuple.MustString(tuple.MustFromRelationship(rel)))
+		}
+		cursor = resp.AfterResultCursor
+
+		_, err = ds.ReadWriteTx(context.Background(), func(rwt datastore.ReadWriteTransaction) error {
+			return rwt.WriteRelationships(context.Background(), []*core.RelationTupleUpdate{
+				tuple.Create(tuple.MustParse(fmt.Sprintf("document:newdoc%d#viewer@user:tom", i))),
+			})
+		})
+		require.NoError(err)
+	}
+
+	require.ElementsMatch(expected, found)
+}
+
+func TestBulkExportRelationshipsInvalidCursor(t *testing.T) {
+	require := require.New(t)
+
+	conn, cleanup, _, _ := testserver.NewTestServer(require, 0, memdb.DisableGC, true, tf.StandardDatastoreWithData)
+	client := experimentalv1.NewExperimentalServiceClient(conn)
+	t.Cleanup(cleanup)
+
+	lrStream, err := client.LookupResources(context.Background(), &experimentalv1.LookupResourcesRequest{
+		ResourceObjectType: "document",
+		Permission:         "view",
+		Subject:            sub("user", "owner", ""),
+		OptionalLimit:      1,
+	})
+	require.NoError(err)
+
+	lrResp, err := lrStream.Recv()
+	require.NoError(err)
+
+	stream, err := client.BulkExportRelationships(context.Background(), &experimentalv1.BulkExportRelationshipsRequest{
+		OptionalCursor: lrResp.AfterResultCursor,
+	})
+	require.NoError(err)
+
+	_, err = stream.Recv()
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+}
@@ -3,6 +3,7 @@ package v1
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	"github.com/authzed/spicedb/pkg/datastore"
 
@@ -28,6 +29,7 @@ import (
 	"github.com/authzed/spicedb/pkg/middleware/consistency"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 	dispatch "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	experimentalv1 "github.com/authzed/spicedb/pkg/proto/experimental/v1"
 	"github.com/authzed/spicedb/pkg/tuple"
 )
 
@@ -139,6 +141,241 @@ func (ps *permissionServer) CheckPermission(ctx context.Context, req *v1.CheckPe
 	}, nil
 }
 
+// bulkCheckGroupKey identifies the items of a bulk check that can be resolved by
+// a single dispatch.
+type bulkCheckGroupKey struct {
+	resourceType    string
+	permission      string
+	subjectType     string
+	subjectID       string
+	subjectRelation string
+}
+
+// bulkCheckGroup is a set of items of a bulk check that share a resource type, permission and subject.
+type bulkCheckGroup struct {
+	resourceType *core.RelationReference
+	subject      *core.ObjectAndRelation
+
+	// itemIndexes are the indexes into the request's items of the items in the group.
+	itemIndexes []int
+	checkItems  []computed.CheckItem
+}
+
+// BulkCheckPermission checks each of the request's items, resolving the items that share a
+// resource type, permission and subject with a single dispatch. The PermissionsService is defined
+// by the published v1 API, which has no bulk check call, so this is served via the
+// ExperimentalService until the call is added there.
+func (ps *permissionServer) BulkCheckPermission(ctx context.Context, req *experimentalv1.BulkCheckPermissionRequest) (*experimentalv1.BulkCheckPermissionResponse, error) {
+	if len(req.Items) > int(ps.config.MaxBulkCheckItems) {
+		return nil, rewriteError(ctx, NewExceedsMaximumChecksErr(uint64(len(req.Items)), uint64(ps.config.MaxBulkCheckItems)))
+	}
+
+	atRevision, checkedAt, err := consistency.RevisionFromContext(ctx)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	ds := datastoremw.MustFromContext(ctx).SnapshotReader(atRevision)
+
+	pairs := make([]*experimentalv1.BulkCheckPermissionPair, len(req.Items))
+	setError := func(index int, err error) {
+		pairs[index] = &experimentalv1.BulkCheckPermissionPair{
+			Request: req.Items[index],
+			Response: &experimentalv1.BulkCheckPermissionPair_Error{
+				Error: status.Convert(rewriteError(ctx, err)).Proto(),
+			},
+		}
+	}
+
+	// Group the items by resource type, permission and subject, as each such group can be
+	// resolved by a single dispatch.
+	groups := make(map[bulkCheckGroupKey]*bulkCheckGroup)
+	orderedGroups := make([]*bulkCheckGroup, 0)
+	for index, item := range req.Items {
+		caveatContext, err := GetCaveatContext(ctx, item.Context, ps.config.MaxCaveatContextSize)
+		if err != nil {
+			setError(index, err)
+			continue
+		}
+
+		key := bulkCheckGroupKey{
+			resourceType:    item.Resource.ObjectType,
+			permission:      item.Permission,
+			subjectType:     item.Subject.Object.ObjectType,
+			subjectID:       item.Subject.Object.ObjectId,
+			subjectRelation: normalizeSubjectRelation(item.Subject),
+		}
+
+		group, ok := groups[key]
+		if !ok {
+			group = &bulkCheckGroup{
+				resourceType: &core.RelationReference{
+					Namespace: key.resourceType,
+					Relation:  key.permission,
+				},
+				subject: &core.ObjectAndRelation{
+					Namespace: key.subjectType,
+					ObjectId:  key.subjectID,
+					Relation:  key.subjectRelation,
+				},
+			}
+			groups[key] = group
+			orderedGroups = append(orderedGroups, group)
+		}
+
+		group.itemIndexes = append(group.itemIndexes, index)
+		group.checkItems = append(group.checkItems, computed.CheckItem{
+			ResourceID:    item.Resource.ObjectId,
+			CaveatContext: caveatContext,
+		})
+	}
+
+	var metadataLock sync.Mutex
+	respMetadata := &dispatch.ResponseMeta{}
+	addMetadata := func(metadata *dispatch.ResponseMeta) {
+		if metadata == nil {
+			return
+		}
+
+		metadataLock.Lock()
+		defer metadataLock.Unlock()
+		respMetadata.DispatchCount += metadata.DispatchCount
+		respMetadata.CachedDispatchCount += metadata.CachedDispatchCount
+		if metadata.DepthRequired > respMetadata.DepthRequired {
+			respMetadata.DepthRequired = metadata.DepthRequired
+		}
+	}
+
+	// Errors are reported per item, so the goroutines below never fail the errgroup; it is
+	// only used to bound the concurrency of the dispatches.
+	tr := errgroup.Group{}
+	tr.SetLimit(maxBulkCheckConcurrency)
+
+	for _, group := range orderedGroups {
+		group := group
+
+		if err := ps.checkBulkCheckGroup(ctx, group, ds); err != nil {
+			for _, index := range group.itemIndexes {
+				setError(index, err)
+			}
+			continue
+		}
+
+		for _, chunk := range chunkBulkCheckGroup(group) {
+			chunk := chunk
+			tr.Go(func() error {
+				results, metadata, err := computed.ComputeCheckItems(ctx, ps.dispatch,
+					computed.CheckParameters{
+						ResourceType:  group.resourceType,
+						Subject:       group.subject,
+						CaveatContext: nil,
+						AtRevision:    atRevision,
+						MaximumDepth:  ps.config.MaximumAPIDepth,
+						DebugOption:   computed.NoDebugging,
+					},
+					chunk.checkItems,
+				)
+				addMetadata(metadata)
+
+				for i, index := range chunk.itemIndexes {
+					if err != nil {
+						setError(index, err)
+						continue
+					}
+
+					if results[i].Err != nil {
+						setError(index, results[i].Err)
+						continue
+					}
+
+					pairs[index] = &experimentalv1.BulkCheckPermissionPair{
+						Request: req.Items[index],
+						Response: &experimentalv1.BulkCheckPermissionPair_Item{
+							Item: bulkCheckResponseItem(results[i].Result),
+						},
+					}
+				}
+				return nil
+			})
+		}
+	}
+
+	_ = tr.Wait()
+	usagemetrics.SetInContext(ctx, respMetadata)
+
+	return &experimentalv1.BulkCheckPermissionResponse{
+		CheckedAt: checkedAt,
+		Pairs:     pairs,
+	}, nil
+}
+
+// checkBulkCheckGroup performs the preflight checks for all items in the group.
+func (ps *permissionServer) checkBulkCheckGroup(ctx context.Context, group *bulkCheckGroup, ds datastore.Reader) error {
+	if err := namespace.CheckNamespaceAndRelation(
+		ctx,
+		group.resourceType.Namespace,
+		group.resourceType.Relation,
+		false,
+		ds,
+	); err != nil {
+		return err
+	}
+
+	return namespace.CheckNamespaceAndRelation(
+		ctx,
+		group.subject.Namespace,
+		group.subject.Relation,
+		true,
+		ds,
+	)
+}
+
+// chunkBulkCheckGroup splits the group into chunks containing at most
+// datastore.FilterMaximumIDCount distinct resource IDs each.
+func chunkBulkCheckGroup(group *bulkCheckGroup) []*bulkCheckGroup {
+	chunks := make([]*bulkCheckGroup, 0, 1)
+	current := &bulkCheckGroup{resourceType: group.resourceType, subject: group.subject}
+	currentIDs := make(map[string]struct{})
+
+	for i, item := range group.checkItems {
+		if _, ok := currentIDs[item.ResourceID]; !ok {
+			if uint16(len(currentIDs)) >= datastore.FilterMaximumIDCount {
+				chunks = append(chunks, current)
+				current = &bulkCheckGroup{resourceType: group.resourceType, subject: group.subject}
+				currentIDs = make(map[string]struct{})
+			}
+			currentIDs[item.ResourceID] = struct{}{}
+		}
+
+		current.itemIndexes = append(current.itemIndexes, group.itemIndexes[i])
+		current.checkItems = append(current.checkItems, item)
+	}
+
+	return append(chunks, current)
+}
+
+func bulkCheckResponseItem(cr *dispatch.ResourceCheckResult) *experimentalv1.BulkCheckPermissionResponseItem {
+	switch cr.Membership {
+	case dispatch.ResourceCheckResult_MEMBER:
+		return &experimentalv1.BulkCheckPermissionResponseItem{
+			Permissionship: v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION,
+		}
+
+	case dispatch.ResourceCheckResult_CAVEATED_MEMBER:
+		return &experimentalv1.BulkCheckPermissionResponseItem{
+			Permissionship: v1.CheckPermissionResponse_PERMISSIONSHIP_CONDITIONAL_PERMISSION,
+			PartialCaveatInfo: &v1.PartialCaveatInfo{
+				MissingRequiredContext: cr.MissingExprFields,
+			},
+		}
+
+	default:
+		return &experimentalv1.BulkCheckPermissionResponseItem{
+			Permissionship: v1.CheckPermissionResponse_PERMISSIONSHIP_NO_PERMISSION,
+		}
+	}
+}
+
 func (ps *permissionServer) ExpandPermissionTree(ctx context.Context, req *v1.ExpandPermissionTreeRequest) (*v1.ExpandPermissionTreeResponse, error) {
 	atRevision, expandedAt, err := consistency.RevisionFromContext(ctx)
 	if err != nil {
@@ -355,49 +592,63 @@ func (ps *permissionServer) LookupResources(req *v1.LookupResourcesRequest, resp
 		return rewriteError(ctx, err)
 	}
 
-	// TODO(jschorr): Change the internal dispatched lookup to also be streamed.
-	lookupResp, err := ps.dispatch.DispatchLookup(ctx, &dispatch.DispatchLookupRequest{
-		Metadata: &dispatch.ResolverMeta{
-			AtRevision:     atRevision.String(),
-			DepthRemaining: ps.config.MaximumAPIDepth,
-		},
-		ObjectRelation: &core.RelationReference{
-			Namespace: req.ResourceObjectType,
-			Relation:  req.Permission,
-		},
-		Subject: &core.ObjectAndRelation{
-			Namespace: req.Subject.Object.ObjectType,
-			ObjectId:  req.Subject.Object.ObjectId,
-			Relation:  normalizeSubjectRelation(req.Subject),
-		},
-		Context: req.Context,
-		Limit:   ^uint32(0), // Set no limit for now
-	})
-	usagemetrics.SetInContext(ctx, lookupResp.Metadata)
-	if err != nil {
-		return rewriteError(ctx, err)
+	respMetadata := &dispatch.ResponseMeta{
+		DispatchCount:       0,
+		CachedDispatchCount: 0,
+		DepthRequired:       0,
+		DebugInfo:           nil,
 	}
+	usagemetrics.SetInContext(ctx, respMetadata)
 
-	for _, found := range lookupResp.ResolvedResources {
-		var partial *v1.PartialCaveatInfo
-		permissionship := v1.LookupPermissionship_LOOKUP_PERMISSIONSHIP_HAS_PERMISSION
-		if found.Permissionship == dispatch.ResolvedResource_CONDITIONALLY_HAS_PERMISSION {
-			permissionship = v1.LookupPermissionship_LOOKUP_PERMISSIONSHIP_CONDITIONAL_PERMISSION
-			partial = &v1.PartialCaveatInfo{
-				MissingRequiredContext: found.MissingRequiredContext,
+	stream := dispatchpkg.NewHandlingDispatchStream(ctx, func(result *dispatch.DispatchLookupResponse) error {
+		for _, found := range result.ResolvedResources {
+			var partial *v1.PartialCaveatInfo
+			permissionship := v1.LookupPermissionship_LOOKUP_PERMISSIONSHIP_HAS_PERMISSION
+			if found.Permissionship == dispatch.ResolvedResource_CONDITIONALLY_HAS_PERMISSION {
+				permissionship = v1.LookupPermissionship_LOOKUP_PERMISSIONSHIP_CONDITIONAL_PERMISSION
+				partial = &v1.PartialCaveatInfo{
+					MissingRequiredContext: found.MissingRequiredContext,
+				}
 			}
-		}
 
-		err := resp.Send(&v1.LookupResourcesResponse{
-			LookedUpAt:        revisionReadAt,
-			ResourceObjectId:  found.ResourceId,
-			Permissionship:    permissionship,
-			PartialCaveatInfo: partial,
-		})
-		if err != nil {
-			return err
+			err := resp.Send(&v1.LookupResourcesResponse{
+				LookedUpAt:        revisionReadAt,
+				ResourceObjectId:  found.ResourceId,
+				Permissionship:    permissionship,
+				PartialCaveatInfo: partial,
+			})
+			if err != nil {
+				return err
+			}
 		}
+
+		dispatchpkg.AddResponseMetadata(respMetadata, result.Metadata)
+		return nil
+	})
+
+	err = ps.dispatch.DispatchLookup(
+		&dispatch.DispatchLookupRequest{
+			Metadata: &dispatch.ResolverMeta{
+				AtRevision:     atRevision.String(),
+				DepthRemaining: ps.config.MaximumAPIDepth,
+			},
+			ObjectRelation: &core.RelationReference{
+				Namespace: req.ResourceObjectType,
+				Relation:  req.Permission,
+			},
+			Subject: &core.ObjectAndRelation{
+				Namespace: req.Subject.Object.ObjectType,
+				ObjectId:  req.Subject.Object.ObjectId,
+				Relation:  normalizeSubjectRelation(req.Subject),
+			},
+			Context: req.Context,
+			Limit:   ^uint32(0), // Set no limit for now
+		},
+		stream)
+	if err != nil {
+		return rewriteError(ctx, err)
 	}
+
 	return nil
 }
 
@@ -5,9 +5,13 @@ import (
 	"fmt"
 
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/authzed/spicedb/pkg/datastore"
 	"github.com/authzed/spicedb/pkg/datastore/options"
+	experimentalv1 "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+	"github.com/authzed/spicedb/pkg/zedtoken"
 )
 
 var limitOne uint64 = 1
@@ -48,3 +52,36 @@ func checkPreconditions(
 
 	return nil
 }
+
+// checkUnchangedSincePreconditions checks, in the context of a datastore read-write
+// transaction, that no relationships matching each precondition have changed since its
+// revision, and returns an error if any have.
+func checkUnchangedSincePreconditions(
+	ctx context.Context,
+	ds datastore.Datastore,
+	rwt datastore.ReadWriteTransaction,
+	preconditions []*experimentalv1.UnchangedSincePrecondition,
+) error {
+	for _, precond := range preconditions {
+		revision, err := zedtoken.DecodeRevision(precond.UnchangedSince, ds)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid zedtoken in precondition: %s", err)
+		}
+
+		// The revision must still be within the GC window, for changes since it to be known.
+		if err := ds.CheckRevision(ctx, revision); err != nil {
+			return err
+		}
+
+		changed, err := rwt.RelationshipsChangedSince(ctx, precond.Filter, revision)
+		if err != nil {
+			return fmt.Errorf("error checking for changed relationships: %w", err)
+		}
+
+		if changed {
+			return NewUnchangedSincePreconditionFailedErr(precond)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,212 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/exp/maps"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/middleware/usagemetrics"
+	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/pkg/caveats"
+	caveattypes "github.com/authzed/spicedb/pkg/caveats/types"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/middleware/consistency"
+	nspkg "github.com/authzed/spicedb/pkg/namespace"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	dispatchv1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	experimentalv1 "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func (ess *experimentalSchemaServer) ReflectSchema(ctx context.Context, _ *experimentalv1.ReflectSchemaRequest) (*experimentalv1.ReflectSchemaResponse, error) {
+	atRevision, revisionReadAt, err := consistency.RevisionFromContext(ctx)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	ds := datastoremw.MustFromContext(ctx)
+	reader := ds.SnapshotReader(atRevision)
+
+	nsDefs, err := reader.ListAllNamespaces(ctx)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	if len(nsDefs) == 0 {
+		return nil, status.Errorf(codes.NotFound, "No schema has been defined; please call WriteSchema to start")
+	}
+
+	caveatDefs, err := reader.ListAllCaveats(ctx)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	definitions := datastore.DefinitionsOf(nsDefs)
+	sort.Slice(definitions, func(i, j int) bool { return definitions[i].Name < definitions[j].Name })
+
+	caveatDefinitions := datastore.DefinitionsOf(caveatDefs)
+	sort.Slice(caveatDefinitions, func(i, j int) bool { return caveatDefinitions[i].Name < caveatDefinitions[j].Name })
+
+	// All definitions are resolved from those already read, rather than from the datastore.
+	resolver := namespace.ResolverForPredefinedDefinitions(namespace.PredefinedElements{
+		Namespaces: definitions,
+		Caveats:    caveatDefinitions,
+	})
+
+	resp := &experimentalv1.ReflectSchemaResponse{
+		Definitions: make([]*experimentalv1.ReflectionDefinition, 0, len(definitions)),
+		Caveats:     make([]*experimentalv1.ReflectionCaveat, 0, len(caveatDefinitions)),
+		ReadAt:      revisionReadAt,
+	}
+
+	for _, nsDef := range definitions {
+		reflected, err := reflectDefinition(ctx, nsDef, definitions, resolver)
+		if err != nil {
+			return nil, rewriteError(ctx, err)
+		}
+		resp.Definitions = append(resp.Definitions, reflected)
+	}
+
+	for _, caveatDef := range caveatDefinitions {
+		reflected, err := reflectCaveat(caveatDef)
+		if err != nil {
+			return nil, rewriteError(ctx, err)
+		}
+		resp.Caveats = append(resp.Caveats, reflected)
+	}
+
+	usagemetrics.SetInContext(ctx, &dispatchv1.ResponseMeta{
+		DispatchCount: uint32(len(nsDefs) + len(caveatDefs)),
+	})
+
+	return resp, nil
+}
+
+// reflectDefinition returns the structured form of the object definition, including the subject
+// types, of those in the schema, which can reach each of its permissions.
+func reflectDefinition(
+	ctx context.Context,
+	nsDef *core.NamespaceDefinition,
+	definitions []*core.NamespaceDefinition,
+	resolver namespace.Resolver,
+) (*experimentalv1.ReflectionDefinition, error) {
+	ts, err := namespace.NewNamespaceTypeSystem(nsDef, resolver)
+	if err != nil {
+		return nil, err
+	}
+
+	vts, err := ts.Validate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reachability := namespace.ReachabilityGraphFor(vts)
+
+	reflected := &experimentalv1.ReflectionDefinition{
+		Name:    nsDef.Name,
+		Comment: reflectComment(nsDef.Metadata),
+	}
+
+	for _, relation := range nsDef.Relation {
+		if !vts.IsPermission(relation.Name) {
+			reflected.Relations = append(reflected.Relations, reflectRelation(relation))
+			continue
+		}
+
+		reachableSubjectTypes := make([]string, 0)
+		for _, subjectDef := range definitions {
+			entrypoints, err := reachability.AllEntrypointsForSubjectToResource(
+				ctx,
+				&core.RelationReference{Namespace: subjectDef.Name, Relation: tuple.Ellipsis},
+				&core.RelationReference{Namespace: nsDef.Name, Relation: relation.Name},
+			)
+			if err != nil {
+				return nil, err
+			}
+
+			if len(entrypoints) > 0 {
+				reachableSubjectTypes = append(reachableSubjectTypes, subjectDef.Name)
+			}
+		}
+
+		reflected.Permissions = append(reflected.Permissions, &experimentalv1.ReflectionPermission{
+			Name:                  relation.Name,
+			Comment:               reflectComment(relation.Metadata),
+			ReachableSubjectTypes: reachableSubjectTypes,
+		})
+	}
+
+	return reflected, nil
+}
+
+func reflectRelation(relation *core.Relation) *experimentalv1.ReflectionRelation {
+	reflected := &experimentalv1.ReflectionRelation{
+		Name:    relation.Name,
+		Comment: reflectComment(relation.Metadata),
+	}
+
+	for _, allowed := range relation.GetTypeInformation().GetAllowedDirectRelations() {
+		allowedType := &experimentalv1.ReflectionAllowedType{
+			SubjectType:      allowed.Namespace,
+			IsPublicWildcard: allowed.GetPublicWildcard() != nil,
+		}
+
+		if subjectRelation := allowed.GetRelation(); subjectRelation != tuple.Ellipsis {
+			allowedType.OptionalSubjectRelation = subjectRelation
+		}
+
+		if allowed.GetRequiredCaveat() != nil {
+			allowedType.OptionalCaveatName = allowed.GetRequiredCaveat().CaveatName
+		}
+
+		reflected.AllowedTypes = append(reflected.AllowedTypes, allowedType)
+	}
+
+	return reflected
+}
+
+func reflectCaveat(caveatDef *core.CaveatDefinition) (*experimentalv1.ReflectionCaveat, error) {
+	deserialized, err := caveats.DeserializeCaveat(caveatDef.SerializedExpression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid caveat expression bytes: %w", err)
+	}
+
+	expression, err := deserialized.ExprString()
+	if err != nil {
+		return nil, fmt.Errorf("invalid caveat expression: %w", err)
+	}
+
+	reflected := &experimentalv1.ReflectionCaveat{
+		Name:       caveatDef.Name,
+		Comment:    reflectComment(caveatDef.Metadata),
+		Parameters: make([]*experimentalv1.ReflectionCaveatParameter, 0, len(caveatDef.ParameterTypes)),
+		Expression: strings.TrimSpace(expression),
+	}
+
+	parameterNames := maps.Keys(caveatDef.ParameterTypes)
+	sort.Strings(parameterNames)
+
+	for _, parameterName := range parameterNames {
+		decoded, err := caveattypes.DecodeParameterType(caveatDef.ParameterTypes[parameterName])
+		if err != nil {
+			return nil, fmt.Errorf("invalid parameter type on caveat: %w", err)
+		}
+
+		reflected.Parameters = append(reflected.Parameters, &experimentalv1.ReflectionCaveatParameter{
+			Name: parameterName,
+			Type: decoded.String(),
+		})
+	}
+
+	return reflected, nil
+}
+
+func reflectComment(metadata *core.Metadata) string {
+	return strings.Join(nspkg.GetComments(metadata), "\n")
+}
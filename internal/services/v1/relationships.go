@@ -2,6 +2,7 @@ package v1
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -25,6 +26,7 @@ import (
 	"github.com/authzed/spicedb/pkg/datastore/pagination"
 	"github.com/authzed/spicedb/pkg/middleware/consistency"
 	dispatchv1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	experimentalv1 "github.com/authzed/spicedb/pkg/proto/experimental/v1"
 	"github.com/authzed/spicedb/pkg/tuple"
 	"github.com/authzed/spicedb/pkg/util"
 	"github.com/authzed/spicedb/pkg/zedtoken"
@@ -62,6 +64,14 @@ type PermissionsServerConfig struct {
 	// MaxDatastoreReadPageSize defines the maximum number of relationships loaded from the
 	// datastore in one query.
 	MaxDatastoreReadPageSize uint64
+
+	// MaxDeleteRelationshipsLimit defines the maximum limit which may be given to a
+	// DeleteRelationships call.
+	MaxDeleteRelationshipsLimit uint32
+
+	// MaxBulkCheckItems defines the maximum number of items which may be given to a
+	// BulkCheckPermission call.
+	MaxBulkCheckItems uint32
 }
 
 // NewPermissionsServer creates a PermissionsServiceServer instance.
@@ -76,6 +86,7 @@ func NewPermissionsServer(
 		StreamingAPITimeout:      defaultIfZero(config.StreamingAPITimeout, 30*time.Second),
 		MaxCaveatContextSize:     config.MaxCaveatContextSize,
 		MaxDatastoreReadPageSize: defaultIfZero(config.MaxDatastoreReadPageSize, 1_000),
+		MaxBulkCheckItems:        defaultIfZero(config.MaxBulkCheckItems, 1_000),
 	}
 
 	return &permissionServer{
@@ -105,14 +116,14 @@ type permissionServer struct {
 	config   PermissionsServerConfig
 }
 
-func (ps *permissionServer) checkFilterComponent(ctx context.Context, objectType, optionalRelation string, ds datastore.Reader) error {
+func checkFilterComponent(ctx context.Context, objectType, optionalRelation string, ds datastore.Reader) error {
 	relationToTest := stringz.DefaultEmpty(optionalRelation, datastore.Ellipsis)
 	allowEllipsis := optionalRelation == ""
 	return namespace.CheckNamespaceAndRelation(ctx, objectType, relationToTest, allowEllipsis, ds)
 }
 
-func (ps *permissionServer) checkFilterNamespaces(ctx context.Context, filter *v1.RelationshipFilter, ds datastore.Reader) error {
-	if err := ps.checkFilterComponent(ctx, filter.ResourceType, filter.OptionalRelation, ds); err != nil {
+func checkFilterNamespaces(ctx context.Context, filter *v1.RelationshipFilter, ds datastore.Reader) error {
+	if err := checkFilterComponent(ctx, filter.ResourceType, filter.OptionalRelation, ds); err != nil {
 		return err
 	}
 
@@ -121,7 +132,7 @@ func (ps *permissionServer) checkFilterNamespaces(ctx context.Context, filter *v
 		if subjectFilter.OptionalRelation != nil {
 			subjectRelation = subjectFilter.OptionalRelation.Relation
 		}
-		if err := ps.checkFilterComponent(ctx, subjectFilter.SubjectType, subjectRelation, ds); err != nil {
+		if err := checkFilterComponent(ctx, subjectFilter.SubjectType, subjectRelation, ds); err != nil {
 			return err
 		}
 	}
@@ -138,7 +149,7 @@ func (ps *permissionServer) ReadRelationships(req *v1.ReadRelationshipsRequest,
 
 	ds := datastoremw.MustFromContext(ctx).SnapshotReader(atRevision)
 
-	if err := ps.checkFilterNamespaces(ctx, req.RelationshipFilter, ds); err != nil {
+	if err := checkFilterNamespaces(ctx, req.RelationshipFilter, ds); err != nil {
 		return rewriteError(ctx, err)
 	}
 
@@ -152,6 +163,7 @@ func (ps *permissionServer) ReadRelationships(req *v1.ReadRelationshipsRequest,
 		datastore.RelationshipsFilterFromPublicFilter(req.RelationshipFilter),
 		ps.config.MaxDatastoreReadPageSize,
 		options.ByResource,
+		nil,
 	)
 	if err != nil {
 		return rewriteError(ctx, err)
@@ -185,20 +197,33 @@ func (ps *permissionServer) ReadRelationships(req *v1.ReadRelationshipsRequest,
 }
 
 func (ps *permissionServer) WriteRelationships(ctx context.Context, req *v1.WriteRelationshipsRequest) (*v1.WriteRelationshipsResponse, error) {
+	return writeRelationships(ctx, ps.config, req, nil)
+}
+
+// writeRelationships applies the updates of the request, if both its preconditions and the
+// given unchanged since preconditions are met.
+func writeRelationships(
+	ctx context.Context,
+	config PermissionsServerConfig,
+	req *v1.WriteRelationshipsRequest,
+	unchangedSince []*experimentalv1.UnchangedSincePrecondition,
+	rwtOpts ...options.RWTOptionsOption,
+) (*v1.WriteRelationshipsResponse, error) {
 	ds := datastoremw.MustFromContext(ctx)
 
 	// Ensure that the updates and preconditions are not over the configured limits.
-	if len(req.Updates) > int(ps.config.MaxUpdatesPerWrite) {
+	if len(req.Updates) > int(config.MaxUpdatesPerWrite) {
 		return nil, rewriteError(
 			ctx,
-			NewExceedsMaximumUpdatesErr(uint16(len(req.Updates)), ps.config.MaxUpdatesPerWrite),
+			NewExceedsMaximumUpdatesErr(uint16(len(req.Updates)), config.MaxUpdatesPerWrite),
 		)
 	}
 
-	if len(req.OptionalPreconditions) > int(ps.config.MaxPreconditionsCount) {
+	preconditionsCount := len(req.OptionalPreconditions) + len(unchangedSince)
+	if preconditionsCount > int(config.MaxPreconditionsCount) {
 		return nil, rewriteError(
 			ctx,
-			NewExceedsMaximumPreconditionsErr(uint16(len(req.OptionalPreconditions)), ps.config.MaxPreconditionsCount),
+			NewExceedsMaximumPreconditionsErr(uint16(preconditionsCount), config.MaxPreconditionsCount),
 		)
 	}
 
@@ -219,7 +244,12 @@ func (ps *permissionServer) WriteRelationships(ctx context.Context, req *v1.Writ
 	revision, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
 		// Validate the preconditions.
 		for _, precond := range req.OptionalPreconditions {
-			if err := ps.checkFilterNamespaces(ctx, precond.Filter, rwt); err != nil {
+			if err := checkFilterNamespaces(ctx, precond.Filter, rwt); err != nil {
+				return err
+			}
+		}
+		for _, precond := range unchangedSince {
+			if err := checkFilterNamespaces(ctx, precond.Filter, rwt); err != nil {
 				return err
 			}
 		}
@@ -232,15 +262,27 @@ func (ps *permissionServer) WriteRelationships(ctx context.Context, req *v1.Writ
 
 		usagemetrics.SetInContext(ctx, &dispatchv1.ResponseMeta{
 			// One request per precondition and one request for the actual writes.
-			DispatchCount: uint32(len(req.OptionalPreconditions)) + 1,
+			DispatchCount: uint32(preconditionsCount) + 1,
 		})
 
 		if err := checkPreconditions(ctx, rwt, req.OptionalPreconditions); err != nil {
 			return err
 		}
 
+		if err := checkUnchangedSincePreconditions(ctx, ds, rwt, unchangedSince); err != nil {
+			return err
+		}
+
 		return rwt.WriteRelationships(ctx, tupleUpdates)
-	})
+	}, rwtOpts...)
+
+	// A retried write is not applied again, but reports the revision of the original write.
+	var committedErr datastore.ErrIdempotencyKeyAlreadyCommitted
+	if errors.As(err, &committedErr) {
+		return &v1.WriteRelationshipsResponse{
+			WrittenAt: zedtoken.MustNewFromRevision(committedErr.CommittedRevision()),
+		}, nil
+	}
 	if err != nil {
 		return nil, rewriteError(ctx, err)
 	}
@@ -271,7 +313,7 @@ func (ps *permissionServer) DeleteRelationships(ctx context.Context, req *v1.Del
 	ds := datastoremw.MustFromContext(ctx)
 
 	revision, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
-		if err := ps.checkFilterNamespaces(ctx, req.RelationshipFilter, rwt); err != nil {
+		if err := checkFilterNamespaces(ctx, req.RelationshipFilter, rwt); err != nil {
 			return err
 		}
 
@@ -284,7 +326,8 @@ func (ps *permissionServer) DeleteRelationships(ctx context.Context, req *v1.Del
 			return err
 		}
 
-		return rwt.DeleteRelationships(ctx, req.RelationshipFilter)
+		_, _, err := rwt.DeleteRelationships(ctx, req.RelationshipFilter)
+		return err
 	})
 	if err != nil {
 		return nil, rewriteError(ctx, err)
@@ -12,12 +12,16 @@ import (
 	"github.com/authzed/spicedb/internal/middleware"
 	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
 	"github.com/authzed/spicedb/internal/middleware/usagemetrics"
+	"github.com/authzed/spicedb/internal/namespace"
 	"github.com/authzed/spicedb/internal/services/shared"
 	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/middleware/consistency"
 	dispatchv1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	experimentalv1 "github.com/authzed/spicedb/pkg/proto/experimental/v1"
 	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
 	"github.com/authzed/spicedb/pkg/schemadsl/generator"
 	"github.com/authzed/spicedb/pkg/schemadsl/input"
+	"github.com/authzed/spicedb/pkg/zedtoken"
 )
 
 // NewSchemaServer creates a SchemaServiceServer instance.
@@ -52,20 +56,137 @@ func (ss *schemaServer) ReadSchema(ctx context.Context, _ *v1.ReadSchemaRequest)
 		return nil, rewriteError(ctx, err)
 	}
 
-	reader := ds.SnapshotReader(headRevision)
+	schemaText, err := readSchema(ctx, ds.SnapshotReader(headRevision))
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
 
-	nsDefs, err := reader.ListAllNamespaces(ctx)
+	return &v1.ReadSchemaResponse{
+		SchemaText: schemaText,
+	}, nil
+}
+
+func (ss *schemaServer) WriteSchema(ctx context.Context, in *v1.WriteSchemaRequest) (*v1.WriteSchemaResponse, error) {
+	if _, err := writeSchema(ctx, in.GetSchema(), ss.additiveOnly); err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	return &v1.WriteSchemaResponse{}, nil
+}
+
+// NewExperimentalSchemaServer creates an ExperimentalSchemaServiceServer instance.
+func NewExperimentalSchemaServer(additiveOnly bool) experimentalv1.ExperimentalSchemaServiceServer {
+	return &experimentalSchemaServer{
+		WithServiceSpecificInterceptors: shared.WithServiceSpecificInterceptors{
+			Unary: middleware.ChainUnaryServer(
+				grpcvalidate.UnaryServerInterceptor(true),
+				usagemetrics.UnaryServerInterceptor(),
+			),
+			Stream: middleware.ChainStreamServer(
+				grpcvalidate.StreamServerInterceptor(true),
+				usagemetrics.StreamServerInterceptor(),
+			),
+		},
+		additiveOnly: additiveOnly,
+	}
+}
+
+type experimentalSchemaServer struct {
+	experimentalv1.UnimplementedExperimentalSchemaServiceServer
+	shared.WithServiceSpecificInterceptors
+
+	additiveOnly bool
+}
+
+func (ess *experimentalSchemaServer) ReadSchema(ctx context.Context, _ *experimentalv1.ReadSchemaRequest) (*experimentalv1.ReadSchemaResponse, error) {
+	atRevision, revisionReadAt, err := consistency.RevisionFromContext(ctx)
 	if err != nil {
 		return nil, rewriteError(ctx, err)
 	}
 
-	caveatDefs, err := reader.ListAllCaveats(ctx)
+	ds := datastoremw.MustFromContext(ctx)
+	schemaText, err := readSchema(ctx, ds.SnapshotReader(atRevision))
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	return &experimentalv1.ReadSchemaResponse{
+		SchemaText: schemaText,
+		ReadAt:     revisionReadAt,
+	}, nil
+}
+
+func (ess *experimentalSchemaServer) WriteSchema(ctx context.Context, in *experimentalv1.WriteSchemaRequest) (*experimentalv1.WriteSchemaResponse, error) {
+	revision, err := writeSchema(ctx, in.GetSchema(), ess.additiveOnly)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	return &experimentalv1.WriteSchemaResponse{
+		WrittenAt: zedtoken.MustNewFromRevision(revision),
+	}, nil
+}
+
+func (ess *experimentalSchemaServer) DiffSchema(ctx context.Context, in *experimentalv1.DiffSchemaRequest) (*experimentalv1.DiffSchemaResponse, error) {
+	atRevision, revisionReadAt, err := consistency.RevisionFromContext(ctx)
 	if err != nil {
 		return nil, rewriteError(ctx, err)
 	}
 
+	validated, err := compileAndValidateSchema(ctx, in.GetSchema(), ess.additiveOnly)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	ds := datastoremw.MustFromContext(ctx)
+	deltas, err := shared.DiffSchemaChanges(ctx, ds.SnapshotReader(atRevision), validated)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	resp := &experimentalv1.DiffSchemaResponse{
+		Deltas: make([]*experimentalv1.SchemaDelta, 0, len(deltas)),
+		ReadAt: revisionReadAt,
+	}
+	for _, delta := range deltas {
+		var allowedType string
+		if delta.AllowedType != nil {
+			allowedType = namespace.SourceForAllowedRelation(delta.AllowedType)
+		}
+
+		resp.Deltas = append(resp.Deltas, &experimentalv1.SchemaDelta{
+			DefinitionName:                   delta.DefinitionName,
+			DeltaType:                        delta.Type,
+			RelationName:                     delta.RelationName,
+			AllowedType:                      allowedType,
+			ParameterName:                    delta.ParameterName,
+			BlockingRelationshipsCount:       delta.BlockingRelationshipsCount,
+			BlockingRelationshipsCountCapped: delta.BlockingRelationshipsCountCapped,
+			Blocked:                          delta.Blocked,
+		})
+	}
+
+	usagemetrics.SetInContext(ctx, &dispatchv1.ResponseMeta{
+		DispatchCount: uint32(len(deltas)) + 1,
+	})
+
+	return resp, nil
+}
+
+// readSchema generates the textual form of the schema defined as of the reader's revision.
+func readSchema(ctx context.Context, reader datastore.Reader) (string, error) {
+	nsDefs, err := reader.ListAllNamespaces(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	caveatDefs, err := reader.ListAllCaveats(ctx)
+	if err != nil {
+		return "", err
+	}
+
 	if len(nsDefs) == 0 {
-		return nil, status.Errorf(codes.NotFound, "No schema has been defined; please call WriteSchema to start")
+		return "", status.Errorf(codes.NotFound, "No schema has been defined; please call WriteSchema to start")
 	}
 
 	schemaDefinitions := make([]compiler.SchemaDefinition, 0, len(nsDefs)+len(caveatDefs))
@@ -79,42 +200,31 @@ func (ss *schemaServer) ReadSchema(ctx context.Context, _ *v1.ReadSchemaRequest)
 
 	schemaText, _, err := generator.GenerateSchema(schemaDefinitions)
 	if err != nil {
-		return nil, rewriteError(ctx, err)
+		return "", err
 	}
 
 	usagemetrics.SetInContext(ctx, &dispatchv1.ResponseMeta{
 		DispatchCount: uint32(len(nsDefs) + len(caveatDefs)),
 	})
 
-	return &v1.ReadSchemaResponse{
-		SchemaText: schemaText,
-	}, nil
+	return schemaText, nil
 }
 
-func (ss *schemaServer) WriteSchema(ctx context.Context, in *v1.WriteSchemaRequest) (*v1.WriteSchemaResponse, error) {
-	log.Ctx(ctx).Trace().Str("schema", in.GetSchema()).Msg("requested Schema to be written")
+// writeSchema compiles, validates and applies the given schema, returning the revision at which it
+// was written.
+func writeSchema(ctx context.Context, schema string, additiveOnly bool) (datastore.Revision, error) {
+	log.Ctx(ctx).Trace().Str("schema", schema).Msg("requested Schema to be written")
 
 	ds := datastoremw.MustFromContext(ctx)
 
-	// Compile the schema into the namespace definitions.
-	emptyDefaultPrefix := ""
-	compiled, err := compiler.Compile(compiler.InputSchema{
-		Source:       input.Source("schema"),
-		SchemaString: in.GetSchema(),
-	}, &emptyDefaultPrefix)
-	if err != nil {
-		return nil, rewriteError(ctx, err)
-	}
-	log.Ctx(ctx).Trace().Int("objectDefinitions", len(compiled.ObjectDefinitions)).Int("caveatDefinitions", len(compiled.CaveatDefinitions)).Msg("compiled namespace definitions")
-
 	// Do as much validation as we can before talking to the datastore.
-	validated, err := shared.ValidateSchemaChanges(ctx, compiled, ss.additiveOnly)
+	validated, err := compileAndValidateSchema(ctx, schema, additiveOnly)
 	if err != nil {
-		return nil, rewriteError(ctx, err)
+		return datastore.NoRevision, err
 	}
 
 	// Update the schema.
-	_, err = ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+	return ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
 		applied, err := shared.ApplySchemaChanges(ctx, rwt, validated)
 		if err != nil {
 			return err
@@ -124,9 +234,19 @@ func (ss *schemaServer) WriteSchema(ctx context.Context, in *v1.WriteSchemaReque
 		})
 		return nil
 	})
+}
+
+// compileAndValidateSchema compiles the given schema into its definitions and validates them.
+func compileAndValidateSchema(ctx context.Context, schema string, additiveOnly bool) (*shared.ValidatedSchemaChanges, error) {
+	emptyDefaultPrefix := ""
+	compiled, err := compiler.Compile(compiler.InputSchema{
+		Source:       input.Source("schema"),
+		SchemaString: schema,
+	}, &emptyDefaultPrefix)
 	if err != nil {
-		return nil, rewriteError(ctx, err)
+		return nil, err
 	}
+	log.Ctx(ctx).Trace().Int("objectDefinitions", len(compiled.ObjectDefinitions)).Int("caveatDefinitions", len(compiled.CaveatDefinitions)).Msg("compiled namespace definitions")
 
-	return &v1.WriteSchemaResponse{}, nil
+	return shared.ValidateSchemaChanges(ctx, compiled, additiveOnly)
 }
@@ -15,7 +15,9 @@ import (
 	tf "github.com/authzed/spicedb/internal/testfixtures"
 	"github.com/authzed/spicedb/internal/testserver"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	experimentalv1 "github.com/authzed/spicedb/pkg/proto/experimental/v1"
 	"github.com/authzed/spicedb/pkg/spiceerrors"
+	"github.com/authzed/spicedb/pkg/testutil"
 	"github.com/authzed/spicedb/pkg/tuple"
 )
 
@@ -549,3 +551,218 @@ func TestSchemaUnchangedNamespaces(t *testing.T) {
 
 	require.True(t, docRevision.GreaterThan(userRevision))
 }
+
+func TestExperimentalSchemaWriteAndReadAtRevisions(t *testing.T) {
+	conn, cleanup, _, _ := testserver.NewTestServer(require.New(t), 0, memdb.DisableGC, true, tf.EmptyDatastore)
+	t.Cleanup(cleanup)
+	client := experimentalv1.NewExperimentalSchemaServiceClient(conn)
+	permissionsClient := v1.NewPermissionsServiceClient(conn)
+
+	firstSchema := "definition example/document {\n\trelation viewer: example/user\n}\n\ndefinition example/user {}"
+	firstWrite, err := client.WriteSchema(context.Background(), &experimentalv1.WriteSchemaRequest{
+		Schema: firstSchema,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, firstWrite.WrittenAt)
+
+	secondSchema := "definition example/document {\n\trelation viewer: example/user\n\tpermission view = viewer\n}\n\ndefinition example/user {}"
+	secondWrite, err := client.WriteSchema(context.Background(), &experimentalv1.WriteSchemaRequest{
+		Schema: secondSchema,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, secondWrite.WrittenAt)
+
+	// Reading at the revision of each write returns the schema as written.
+	for _, tc := range []struct {
+		writtenAt      *v1.ZedToken
+		expectedSchema string
+	}{
+		{firstWrite.WrittenAt, firstSchema},
+		{secondWrite.WrittenAt, secondSchema},
+	} {
+		readback, err := client.ReadSchema(context.Background(), &experimentalv1.ReadSchemaRequest{
+			Consistency: &v1.Consistency{
+				Requirement: &v1.Consistency_AtExactSnapshot{AtExactSnapshot: tc.writtenAt},
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, tc.expectedSchema, readback.SchemaText)
+		require.Equal(t, tc.writtenAt.Token, readback.ReadAt.Token)
+	}
+
+	// A check at least as fresh as the write can use the newly added permission.
+	_, err = permissionsClient.CheckPermission(context.Background(), &v1.CheckPermissionRequest{
+		Consistency: &v1.Consistency{
+			Requirement: &v1.Consistency_AtLeastAsFresh{AtLeastAsFresh: secondWrite.WrittenAt},
+		},
+		Resource:   &v1.ObjectReference{ObjectType: "example/document", ObjectId: "somedoc"},
+		Permission: "view",
+		Subject:    &v1.SubjectReference{Object: &v1.ObjectReference{ObjectType: "example/user", ObjectId: "tom"}},
+	})
+	require.NoError(t, err)
+
+	// Reading with full consistency returns the latest schema.
+	readback, err := client.ReadSchema(context.Background(), &experimentalv1.ReadSchemaRequest{
+		Consistency: &v1.Consistency{
+			Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, secondSchema, readback.SchemaText)
+	require.NotNil(t, readback.ReadAt)
+}
+
+func TestExperimentalSchemaReadNoSchema(t *testing.T) {
+	conn, cleanup, _, _ := testserver.NewTestServer(require.New(t), 0, memdb.DisableGC, true, tf.EmptyDatastore)
+	t.Cleanup(cleanup)
+	client := experimentalv1.NewExperimentalSchemaServiceClient(conn)
+
+	_, err := client.ReadSchema(context.Background(), &experimentalv1.ReadSchemaRequest{})
+	grpcutil.RequireStatus(t, codes.NotFound, err)
+
+	_, err = client.WriteSchema(context.Background(), &experimentalv1.WriteSchemaRequest{
+		Schema: `invalid example/user {}`,
+	})
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+}
+
+func TestExperimentalSchemaDiff(t *testing.T) {
+	conn, cleanup, _, _ := testserver.NewTestServer(require.New(t), 0, memdb.DisableGC, true, tf.EmptyDatastore)
+	t.Cleanup(cleanup)
+	client := experimentalv1.NewExperimentalSchemaServiceClient(conn)
+
+	_, err := client.WriteSchema(context.Background(), &experimentalv1.WriteSchemaRequest{
+		Schema: `definition example/user {}
+
+		definition example/document {
+			relation viewer: example/user | example/user:*
+			relation editor: example/user
+			permission view = viewer
+		}`,
+	})
+	require.NoError(t, err)
+
+	_, err = v1.NewPermissionsServiceClient(conn).WriteRelationships(context.Background(), &v1.WriteRelationshipsRequest{
+		Updates: []*v1.RelationshipUpdate{
+			tuple.UpdateToRelationshipUpdate(tuple.Create(tuple.MustParse("example/document:somedoc#viewer@example/user:*"))),
+			tuple.UpdateToRelationshipUpdate(tuple.Create(tuple.MustParse("example/document:somedoc#editor@example/user:tom"))),
+		},
+	})
+	require.NoError(t, err)
+
+	proposed := `definition example/user {}
+
+		definition example/document {
+			relation viewer: example/user
+			permission view = viewer + viewer
+		}`
+
+	resp, err := client.DiffSchema(context.Background(), &experimentalv1.DiffSchemaRequest{
+		Consistency: &v1.Consistency{Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true}},
+		Schema:      proposed,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, resp.ReadAt)
+	require.ElementsMatch(t, []*experimentalv1.SchemaDelta{
+		{DefinitionName: "example/document", DeltaType: "removed-relation", RelationName: "editor", BlockingRelationshipsCount: 1, Blocked: true},
+		{DefinitionName: "example/document", DeltaType: "relation-allowed-type-removed", RelationName: "viewer", AllowedType: "example/user:*", BlockingRelationshipsCount: 1, Blocked: true},
+		{DefinitionName: "example/document", DeltaType: "changed-permission-implementation", RelationName: "view"},
+	}, resp.Deltas)
+
+	// Nothing was written by the diff.
+	_, err = client.WriteSchema(context.Background(), &experimentalv1.WriteSchemaRequest{Schema: proposed})
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+
+	// An invalid schema is rejected.
+	_, err = client.DiffSchema(context.Background(), &experimentalv1.DiffSchemaRequest{Schema: `invalid example/user {}`})
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+}
+
+func TestExperimentalSchemaReflect(t *testing.T) {
+	conn, cleanup, _, _ := testserver.NewTestServer(require.New(t), 0, memdb.DisableGC, true, tf.EmptyDatastore)
+	t.Cleanup(cleanup)
+	client := experimentalv1.NewExperimentalSchemaServiceClient(conn)
+
+	// Reflecting without a schema fails.
+	_, err := client.ReflectSchema(context.Background(), &experimentalv1.ReflectSchemaRequest{})
+	grpcutil.RequireStatus(t, codes.NotFound, err)
+
+	_, err = client.WriteSchema(context.Background(), &experimentalv1.WriteSchemaRequest{
+		Schema: `caveat example/only_on_tuesday(day_of_week string, allowed list<string>) {
+			day_of_week in allowed
+		}
+
+		definition example/user {}
+
+		definition example/team {
+			relation member: example/user
+		}
+
+		/** document is a protected document */
+		definition example/document {
+			// viewer can view the document
+			relation viewer: example/user | example/user:* | example/team#member with example/only_on_tuesday
+			relation parent: example/team
+
+			// view checks the viewer
+			permission view = viewer
+			permission view_parent = parent->member
+		}`,
+	})
+	require.NoError(t, err)
+
+	resp, err := client.ReflectSchema(context.Background(), &experimentalv1.ReflectSchemaRequest{
+		Consistency: &v1.Consistency{Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true}},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, resp.ReadAt)
+
+	testutil.RequireProtoEqual(t, &experimentalv1.ReflectSchemaResponse{
+		Definitions: []*experimentalv1.ReflectionDefinition{
+			{
+				Name:    "example/document",
+				Comment: "/** document is a protected document */",
+				Relations: []*experimentalv1.ReflectionRelation{
+					{
+						Name:    "viewer",
+						Comment: "// viewer can view the document",
+						AllowedTypes: []*experimentalv1.ReflectionAllowedType{
+							{SubjectType: "example/user"},
+							{SubjectType: "example/user", IsPublicWildcard: true},
+							{SubjectType: "example/team", OptionalSubjectRelation: "member", OptionalCaveatName: "example/only_on_tuesday"},
+						},
+					},
+					{
+						Name: "parent",
+						AllowedTypes: []*experimentalv1.ReflectionAllowedType{
+							{SubjectType: "example/team"},
+						},
+					},
+				},
+				Permissions: []*experimentalv1.ReflectionPermission{
+					{Name: "view", Comment: "// view checks the viewer", ReachableSubjectTypes: []string{"example/user"}},
+					{Name: "view_parent", ReachableSubjectTypes: []string{"example/user"}},
+				},
+			},
+			{
+				Name: "example/team",
+				Relations: []*experimentalv1.ReflectionRelation{
+					{Name: "member", AllowedTypes: []*experimentalv1.ReflectionAllowedType{{SubjectType: "example/user"}}},
+				},
+			},
+			{
+				Name: "example/user",
+			},
+		},
+		Caveats: []*experimentalv1.ReflectionCaveat{
+			{
+				Name: "example/only_on_tuesday",
+				Parameters: []*experimentalv1.ReflectionCaveatParameter{
+					{Name: "allowed", Type: "list<string>"},
+					{Name: "day_of_week", Type: "string"},
+				},
+				Expression: "day_of_week in allowed",
+			},
+		},
+	}, &experimentalv1.ReflectSchemaResponse{Definitions: resp.Definitions, Caveats: resp.Caveats}, "mismatch in reflected schema")
+}
@@ -1,50 +1,160 @@
 package v1
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
 	grpcvalidate "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/validator"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"github.com/authzed/spicedb/internal/dispatch"
+	"github.com/authzed/spicedb/internal/materialize"
 	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
 	"github.com/authzed/spicedb/internal/middleware/usagemetrics"
 	"github.com/authzed/spicedb/internal/services/shared"
 	"github.com/authzed/spicedb/pkg/datastore"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 	dispatchv1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	experimentalv1 "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+	"github.com/authzed/spicedb/pkg/schemadsl/generator"
 	"github.com/authzed/spicedb/pkg/tuple"
 	"github.com/authzed/spicedb/pkg/zedtoken"
 )
 
+// defaultWatchHeartbeat is the interval at which checkpoints are sent on watches, if
+// no changes were sent in the meantime and no other interval was configured.
+const defaultWatchHeartbeat = 1 * time.Second
+
 type watchServer struct {
 	v1.UnimplementedWatchServiceServer
 	shared.WithStreamServiceSpecificInterceptor
+
+	heartbeat time.Duration
 }
 
-// NewWatchServer creates an instance of the watch server.
-func NewWatchServer() v1.WatchServiceServer {
+// NewWatchServer creates an instance of the watch server. If no changes were sent on a
+// watch within the heartbeat interval, a response without updates is sent to checkpoint
+// the latest revision. A heartbeat of zero selects the default interval.
+func NewWatchServer(heartbeat time.Duration) v1.WatchServiceServer {
 	s := &watchServer{
 		WithStreamServiceSpecificInterceptor: shared.WithStreamServiceSpecificInterceptor{
 			Stream: grpcvalidate.StreamServerInterceptor(true),
 		},
+		heartbeat: heartbeatOrDefault(heartbeat),
 	}
 	return s
 }
 
 func (ws *watchServer) Watch(req *v1.WatchRequest, stream v1.WatchService_WatchServer) error {
-	ctx := stream.Context()
-	ds := datastoremw.MustFromContext(ctx)
+	options := watchOptionsForObjectTypes(req.GetOptionalObjectTypes())
+
+	return watchChanges(stream.Context(), req.OptionalStartCursor, options, ws.heartbeat, func(_ datastore.Revision, update *datastore.RevisionChanges) (bool, error) {
+		if len(update.Changes) == 0 {
+			return false, nil
+		}
 
-	objectTypesMap := make(map[string]struct{})
-	for _, objectType := range req.GetOptionalObjectTypes() {
-		objectTypesMap[objectType] = struct{}{}
+		if err := stream.Send(&v1.WatchResponse{
+			Updates:        tuple.UpdatesToRelationshipUpdates(update.Changes),
+			ChangesThrough: zedtoken.MustNewFromRevision(update.Revision),
+		}); err != nil {
+			return false, status.Errorf(codes.Canceled, "watch canceled by user: %s", err)
+		}
+		return true, nil
+	}, func(revision datastore.Revision) error {
+		if err := stream.Send(&v1.WatchResponse{
+			ChangesThrough: zedtoken.MustNewFromRevision(revision),
+		}); err != nil {
+			return status.Errorf(codes.Canceled, "watch canceled by user: %s", err)
+		}
+		return nil
+	})
+}
+
+type experimentalWatchServer struct {
+	experimentalv1.UnimplementedExperimentalWatchServiceServer
+	shared.WithStreamServiceSpecificInterceptor
+
+	dispatch        dispatch.Dispatcher
+	heartbeat       time.Duration
+	maximumAPIDepth uint32
+}
+
+// NewExperimentalWatchServer creates an instance of the experimental watch server, which
+// additionally emits changes to schema definitions and computes changes to permissions
+// using the dispatcher.
+func NewExperimentalWatchServer(dispatch dispatch.Dispatcher, heartbeat time.Duration, maximumAPIDepth uint32) experimentalv1.ExperimentalWatchServiceServer {
+	return &experimentalWatchServer{
+		WithStreamServiceSpecificInterceptor: shared.WithStreamServiceSpecificInterceptor{
+			Stream: grpcvalidate.StreamServerInterceptor(true),
+		},
+		dispatch:        dispatch,
+		heartbeat:       heartbeatOrDefault(heartbeat),
+		maximumAPIDepth: maximumAPIDepth,
+	}
+}
+
+func (ews *experimentalWatchServer) Watch(req *experimentalv1.WatchRequest, stream experimentalv1.ExperimentalWatchService_WatchServer) error {
+	if len(req.OptionalObjectTypes) > 0 && len(req.OptionalRelationshipFilters) > 0 {
+		return status.Errorf(codes.InvalidArgument, "cannot specify both object types and relationship filters")
+	}
+
+	options := watchOptionsForObjectTypes(req.OptionalObjectTypes)
+	for _, filter := range req.OptionalRelationshipFilters {
+		options.OptionalRelationshipsFilters = append(options.OptionalRelationshipsFilters, datastore.RelationshipsFilterFromPublicFilter(filter))
 	}
 
+	return watchChanges(stream.Context(), req.OptionalStartCursor, options, ews.heartbeat, func(_ datastore.Revision, update *datastore.RevisionChanges) (bool, error) {
+		schemaUpdates, err := schemaUpdatesFor(update)
+		if err != nil {
+			return false, status.Errorf(codes.Internal, "watch error: %s", err)
+		}
+
+		if len(update.Changes) == 0 && len(schemaUpdates) == 0 {
+			return false, nil
+		}
+
+		if err := stream.Send(&experimentalv1.WatchResponse{
+			Updates:        tuple.UpdatesToRelationshipUpdates(update.Changes),
+			ChangesThrough: zedtoken.MustNewFromRevision(update.Revision),
+			SchemaUpdates:  schemaUpdates,
+		}); err != nil {
+			return false, status.Errorf(codes.Canceled, "watch canceled by user: %s", err)
+		}
+		return true, nil
+	}, func(revision datastore.Revision) error {
+		if err := stream.Send(&experimentalv1.WatchResponse{
+			ChangesThrough: zedtoken.MustNewFromRevision(revision),
+			IsCheckpoint:   true,
+		}); err != nil {
+			return status.Errorf(codes.Canceled, "watch canceled by user: %s", err)
+		}
+		return nil
+	})
+}
+
+// watchChanges watches the datastore for changes after the start cursor (or the current
+// revision, if none is given) matching the options, invoking handle for each change until the watch fails or
+// a handler returns an error. handle is given the revision through which all earlier changes have been
+// handled, and returns whether it sent a response; if none was sent
+// for a full heartbeat interval, checkpoint is invoked with the latest revision through
+// which all changes have been handled.
+func watchChanges(
+	ctx context.Context,
+	startCursor *v1.ZedToken,
+	options datastore.WatchOptions,
+	heartbeat time.Duration,
+	handle func(previousRevision datastore.Revision, update *datastore.RevisionChanges) (bool, error),
+	checkpoint func(revision datastore.Revision) error,
+) error {
+	ds := datastoremw.MustFromContext(ctx)
+
 	var afterRevision datastore.Revision
-	if req.OptionalStartCursor != nil && req.OptionalStartCursor.Token != "" {
-		decodedRevision, err := zedtoken.DecodeRevision(req.OptionalStartCursor, ds)
+	if startCursor != nil && startCursor.Token != "" {
+		decodedRevision, err := zedtoken.DecodeRevision(startCursor, ds)
 		if err != nil {
 			return status.Errorf(codes.InvalidArgument, "failed to decode start revision: %s", err)
 		}
@@ -62,21 +172,32 @@ func (ws *watchServer) Watch(req *v1.WatchRequest, stream v1.WatchService_WatchS
 		DispatchCount: 1,
 	})
 
-	updates, errchan := ds.Watch(ctx, afterRevision)
+	heartbeatTicker := time.NewTicker(heartbeat)
+	defer heartbeatTicker.Stop()
+
+	latestRevision := afterRevision
+	sentSinceHeartbeat := false
+
+	updates, errchan := ds.Watch(ctx, afterRevision, options)
 	for {
 		select {
 		case update, ok := <-updates:
 			if ok {
-				filtered := filterUpdates(objectTypesMap, update.Changes)
-				if len(filtered) > 0 {
-					if err := stream.Send(&v1.WatchResponse{
-						Updates:        filtered,
-						ChangesThrough: zedtoken.MustNewFromRevision(update.Revision),
-					}); err != nil {
-						return status.Errorf(codes.Canceled, "watch canceled by user: %s", err)
-					}
+				sent, err := handle(latestRevision, withoutIndexChanges(update))
+				if err != nil {
+					return err
 				}
+
+				latestRevision = update.Revision
+				sentSinceHeartbeat = sentSinceHeartbeat || sent
 			}
+		case <-heartbeatTicker.C:
+			if !sentSinceHeartbeat {
+				if err := checkpoint(latestRevision); err != nil {
+					return err
+				}
+			}
+			sentSinceHeartbeat = false
 		case err := <-errchan:
 			switch {
 			case errors.As(err, &datastore.ErrWatchCanceled{}):
@@ -90,21 +211,94 @@ func (ws *watchServer) Watch(req *v1.WatchRequest, stream v1.WatchService_WatchS
 	}
 }
 
-func filterUpdates(objectTypes map[string]struct{}, candidates []*core.RelationTupleUpdate) []*v1.RelationshipUpdate {
-	updates := tuple.UpdatesToRelationshipUpdates(candidates)
+// withoutIndexChanges returns the update without any changes to the index of materialized
+// permissions, which are maintained internally rather than written by callers.
+func withoutIndexChanges(update *datastore.RevisionChanges) *datastore.RevisionChanges {
+	changes := make([]*core.RelationTupleUpdate, 0, len(update.Changes))
+	for _, change := range update.Changes {
+		if !materialize.IsIndexNamespace(change.Tuple.ResourceAndRelation.Namespace) {
+			changes = append(changes, change)
+		}
+	}
 
-	if len(objectTypes) == 0 {
-		return updates
+	if len(changes) == len(update.Changes) {
+		return update
 	}
 
-	var filtered []*v1.RelationshipUpdate
-	for _, update := range updates {
-		objectType := update.GetRelationship().GetResource().GetObjectType()
+	filtered := *update
+	filtered.Changes = changes
+	return &filtered
+}
 
-		if _, ok := objectTypes[objectType]; ok {
-			filtered = append(filtered, update)
+func heartbeatOrDefault(heartbeat time.Duration) time.Duration {
+	if heartbeat <= 0 {
+		return defaultWatchHeartbeat
+	}
+	return heartbeat
+}
+
+func schemaUpdatesFor(update *datastore.RevisionChanges) ([]*experimentalv1.SchemaDefinitionUpdate, error) {
+	var schemaUpdates []*experimentalv1.SchemaDefinitionUpdate
+	for _, def := range update.ChangedDefinitions {
+		switch t := def.(type) {
+		case *core.NamespaceDefinition:
+			source, _, err := generator.GenerateSource(t)
+			if err != nil {
+				return nil, err
+			}
+
+			schemaUpdates = append(schemaUpdates, &experimentalv1.SchemaDefinitionUpdate{
+				Operation:      experimentalv1.SchemaDefinitionUpdate_OPERATION_WRITE,
+				Kind:           experimentalv1.SchemaDefinitionUpdate_DEFINITION_KIND_OBJECT_DEFINITION,
+				DefinitionName: t.Name,
+				SchemaText:     source,
+			})
+
+		case *core.CaveatDefinition:
+			source, _, err := generator.GenerateCaveatSource(t)
+			if err != nil {
+				return nil, err
+			}
+
+			schemaUpdates = append(schemaUpdates, &experimentalv1.SchemaDefinitionUpdate{
+				Operation:      experimentalv1.SchemaDefinitionUpdate_OPERATION_WRITE,
+				Kind:           experimentalv1.SchemaDefinitionUpdate_DEFINITION_KIND_CAVEAT,
+				DefinitionName: t.Name,
+				SchemaText:     source,
+			})
+
+		default:
+			return nil, fmt.Errorf("unknown schema definition type %T", def)
 		}
 	}
 
-	return filtered
+	for _, name := range update.DeletedNamespaces {
+		schemaUpdates = append(schemaUpdates, &experimentalv1.SchemaDefinitionUpdate{
+			Operation:      experimentalv1.SchemaDefinitionUpdate_OPERATION_DELETE,
+			Kind:           experimentalv1.SchemaDefinitionUpdate_DEFINITION_KIND_OBJECT_DEFINITION,
+			DefinitionName: name,
+		})
+	}
+
+	for _, name := range update.DeletedCaveats {
+		schemaUpdates = append(schemaUpdates, &experimentalv1.SchemaDefinitionUpdate{
+			Operation:      experimentalv1.SchemaDefinitionUpdate_OPERATION_DELETE,
+			Kind:           experimentalv1.SchemaDefinitionUpdate_DEFINITION_KIND_CAVEAT,
+			DefinitionName: name,
+		})
+	}
+
+	return schemaUpdates, nil
+}
+
+// watchOptionsForObjectTypes returns the options for a watch limited to relationships whose
+// resource is one of the given object types, if any.
+func watchOptionsForObjectTypes(objectTypes []string) datastore.WatchOptions {
+	var options datastore.WatchOptions
+	for _, objectType := range objectTypes {
+		options.OptionalRelationshipsFilters = append(options.OptionalRelationshipsFilters, datastore.RelationshipsFilter{
+			ResourceType: objectType,
+		})
+	}
+	return options
 }
@@ -19,6 +19,8 @@ import (
 	"github.com/authzed/spicedb/internal/datastore/memdb"
 	"github.com/authzed/spicedb/internal/testfixtures"
 	"github.com/authzed/spicedb/internal/testserver"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	experimentalv1 "github.com/authzed/spicedb/pkg/proto/experimental/v1"
 	"github.com/authzed/spicedb/pkg/tuple"
 	"github.com/authzed/spicedb/pkg/zedtoken"
 )
@@ -175,6 +177,235 @@ func TestWatch(t *testing.T) {
 	}
 }
 
+func TestWatchCheckpoints(t *testing.T) {
+	require := require.New(t)
+
+	conn, cleanup, ds, revision := testserver.NewTestServer(require, 0, memdb.DisableGC, true, testfixtures.StandardDatastoreWithData)
+	t.Cleanup(cleanup)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := v1.NewWatchServiceClient(conn).Watch(ctx, &v1.WatchRequest{
+		OptionalObjectTypes: []string{"document"},
+		OptionalStartCursor: zedtoken.MustNewFromRevision(revision),
+	})
+	require.NoError(err)
+
+	experimentalStream, err := experimentalv1.NewExperimentalWatchServiceClient(conn).Watch(ctx, &experimentalv1.WatchRequest{
+		OptionalObjectTypes: []string{"document"},
+		OptionalStartCursor: zedtoken.MustNewFromRevision(revision),
+	})
+	require.NoError(err)
+
+	// Write a change which does not match the object type filter.
+	resp, err := v1.NewPermissionsServiceClient(conn).WriteRelationships(ctx, &v1.WriteRelationshipsRequest{
+		Updates: []*v1.RelationshipUpdate{
+			update(v1.RelationshipUpdate_OPERATION_CREATE, "folder", "newfolder", "viewer", "user", "user1"),
+		},
+	})
+	require.NoError(err)
+
+	writtenAt, err := zedtoken.DecodeRevision(resp.WrittenAt, ds)
+	require.NoError(err)
+
+	// Checkpoints are sent without updates and eventually advance past the write.
+	for {
+		watchResp, err := stream.Recv()
+		require.NoError(err)
+		require.Empty(watchResp.Updates)
+
+		checkpointed, err := zedtoken.DecodeRevision(watchResp.ChangesThrough, ds)
+		require.NoError(err)
+		if !checkpointed.LessThan(writtenAt) {
+			break
+		}
+	}
+
+	for {
+		watchResp, err := experimentalStream.Recv()
+		require.NoError(err)
+		require.True(watchResp.IsCheckpoint)
+		require.Empty(watchResp.Updates)
+
+		checkpointed, err := zedtoken.DecodeRevision(watchResp.ChangesThrough, ds)
+		require.NoError(err)
+		if !checkpointed.LessThan(writtenAt) {
+			break
+		}
+	}
+}
+
+func TestExperimentalWatchSchemaChanges(t *testing.T) {
+	require := require.New(t)
+
+	conn, cleanup, _, revision := testserver.NewTestServer(require, 0, memdb.DisableGC, true, testfixtures.EmptyDatastore)
+	t.Cleanup(cleanup)
+	schemaClient := v1.NewSchemaServiceClient(conn)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := experimentalv1.NewExperimentalWatchServiceClient(conn).Watch(ctx, &experimentalv1.WatchRequest{
+		OptionalStartCursor: zedtoken.MustNewFromRevision(revision),
+	})
+	require.NoError(err)
+
+	type updateKey struct {
+		op   experimentalv1.SchemaDefinitionUpdate_Operation
+		kind experimentalv1.SchemaDefinitionUpdate_DefinitionKind
+		name string
+	}
+
+	// receiveUpdates reads the schema updates of a single write.
+	receiveUpdates := func() map[updateKey]string {
+		resp, err := stream.Recv()
+		require.NoError(err)
+		require.Empty(resp.Updates)
+		require.NotNil(resp.ChangesThrough)
+
+		received := make(map[updateKey]string, len(resp.SchemaUpdates))
+		for _, update := range resp.SchemaUpdates {
+			received[updateKey{update.Operation, update.Kind, update.DefinitionName}] = update.SchemaText
+		}
+		return received
+	}
+
+	_, err = schemaClient.WriteSchema(ctx, &v1.WriteSchemaRequest{
+		Schema: "definition example/user {}\n\ndefinition example/document {\n\trelation viewer: example/user\n}",
+	})
+	require.NoError(err)
+
+	received := receiveUpdates()
+	require.Contains(received, updateKey{experimentalv1.SchemaDefinitionUpdate_OPERATION_WRITE, experimentalv1.SchemaDefinitionUpdate_DEFINITION_KIND_OBJECT_DEFINITION, "example/user"})
+	require.Equal(
+		"definition example/document {\n\trelation viewer: example/user\n}",
+		received[updateKey{experimentalv1.SchemaDefinitionUpdate_OPERATION_WRITE, experimentalv1.SchemaDefinitionUpdate_DEFINITION_KIND_OBJECT_DEFINITION, "example/document"}],
+	)
+
+	_, err = schemaClient.WriteSchema(ctx, &v1.WriteSchemaRequest{
+		Schema: "definition example/user {}\n\ncaveat example/is_tuesday(day string) {\n\tday == \"tuesday\"\n}",
+	})
+	require.NoError(err)
+
+	received = receiveUpdates()
+	require.Contains(received, updateKey{experimentalv1.SchemaDefinitionUpdate_OPERATION_DELETE, experimentalv1.SchemaDefinitionUpdate_DEFINITION_KIND_OBJECT_DEFINITION, "example/document"})
+	require.Contains(received, updateKey{experimentalv1.SchemaDefinitionUpdate_OPERATION_WRITE, experimentalv1.SchemaDefinitionUpdate_DEFINITION_KIND_CAVEAT, "example/is_tuesday"})
+	require.NotContains(received, updateKey{experimentalv1.SchemaDefinitionUpdate_OPERATION_DELETE, experimentalv1.SchemaDefinitionUpdate_DEFINITION_KIND_OBJECT_DEFINITION, "example/user"})
+}
+
+func TestExperimentalWatchRelationshipFilters(t *testing.T) {
+	mutations := []*v1.RelationshipUpdate{
+		update(v1.RelationshipUpdate_OPERATION_TOUCH, "document", "document1", "viewer", "user", "user1"),
+		update(v1.RelationshipUpdate_OPERATION_TOUCH, "document", "document2", "viewer", "user", "user1"),
+		update(v1.RelationshipUpdate_OPERATION_TOUCH, "folder", "folder2", "viewer", "user", "user1"),
+		update(v1.RelationshipUpdate_OPERATION_TOUCH, "folder", "folder2", "viewer", "user", "user2"),
+	}
+
+	testCases := []struct {
+		name              string
+		objectTypesFilter []string
+		filters           []*v1.RelationshipFilter
+		expectedCode      codes.Code
+		expectedUpdates   []*v1.RelationshipUpdate
+	}{
+		{
+			name: "resource filter",
+			filters: []*v1.RelationshipFilter{
+				{ResourceType: "document", OptionalResourceId: "document1", OptionalRelation: "viewer"},
+			},
+			expectedCode: codes.OK,
+			expectedUpdates: []*v1.RelationshipUpdate{
+				update(v1.RelationshipUpdate_OPERATION_TOUCH, "document", "document1", "viewer", "user", "user1"),
+			},
+		},
+		{
+			name: "subject filter",
+			filters: []*v1.RelationshipFilter{
+				{
+					ResourceType:     "folder",
+					OptionalRelation: "viewer",
+					OptionalSubjectFilter: &v1.SubjectFilter{
+						SubjectType:       "user",
+						OptionalSubjectId: "user2",
+					},
+				},
+			},
+			expectedCode: codes.OK,
+			expectedUpdates: []*v1.RelationshipUpdate{
+				update(v1.RelationshipUpdate_OPERATION_TOUCH, "folder", "folder2", "viewer", "user", "user2"),
+			},
+		},
+		{
+			name: "multiple filters",
+			filters: []*v1.RelationshipFilter{
+				{ResourceType: "document", OptionalResourceId: "document2"},
+				{ResourceType: "folder", OptionalSubjectFilter: &v1.SubjectFilter{SubjectType: "user", OptionalSubjectId: "user1"}},
+			},
+			expectedCode: codes.OK,
+			expectedUpdates: []*v1.RelationshipUpdate{
+				update(v1.RelationshipUpdate_OPERATION_TOUCH, "document", "document2", "viewer", "user", "user1"),
+				update(v1.RelationshipUpdate_OPERATION_TOUCH, "folder", "folder2", "viewer", "user", "user1"),
+			},
+		},
+		{
+			name:              "object types and filters",
+			objectTypesFilter: []string{"document"},
+			filters:           []*v1.RelationshipFilter{{ResourceType: "document"}},
+			expectedCode:      codes.InvalidArgument,
+		},
+		{
+			name:         "invalid filter",
+			filters:      []*v1.RelationshipFilter{{ResourceType: ""}},
+			expectedCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			conn, cleanup, _, revision := testserver.NewTestServer(require, 0, memdb.DisableGC, true, testfixtures.StandardDatastoreWithData)
+			t.Cleanup(cleanup)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			stream, err := experimentalv1.NewExperimentalWatchServiceClient(conn).Watch(ctx, &experimentalv1.WatchRequest{
+				OptionalObjectTypes:         tc.objectTypesFilter,
+				OptionalStartCursor:         zedtoken.MustNewFromRevision(revision),
+				OptionalRelationshipFilters: tc.filters,
+			})
+			require.NoError(err)
+
+			if tc.expectedCode != codes.OK {
+				_, err := stream.Recv()
+				grpcutil.RequireStatus(t, tc.expectedCode, err)
+				return
+			}
+
+			_, err = v1.NewPermissionsServiceClient(conn).WriteRelationships(ctx, &v1.WriteRelationshipsRequest{
+				Updates: mutations,
+			})
+			require.NoError(err)
+
+			// All mutations are written at a single revision, so the first response which is
+			// not a checkpoint carries all matching updates.
+			for {
+				resp, err := stream.Recv()
+				require.NoError(err)
+				if resp.IsCheckpoint {
+					continue
+				}
+
+				require.Equal(sortUpdates(tc.expectedUpdates), sortUpdates(resp.Updates))
+				return
+			}
+		})
+	}
+}
+
 func sortUpdates(in []*v1.RelationshipUpdate) []*v1.RelationshipUpdate {
 	out := make([]*v1.RelationshipUpdate, 0, len(in))
 	out = append(out, in...)
@@ -193,3 +424,156 @@ func sortUpdates(in []*v1.RelationshipUpdate) []*v1.RelationshipUpdate {
 
 	return out
 }
+
+func TestExperimentalWatchPermissions(t *testing.T) {
+	require := require.New(t)
+
+	conn, cleanup, _, _ := testserver.NewTestServer(require, 0, memdb.DisableGC, true, testfixtures.EmptyDatastore)
+	t.Cleanup(cleanup)
+
+	_, err := v1.NewSchemaServiceClient(conn).WriteSchema(context.Background(), &v1.WriteSchemaRequest{
+		Schema: `definition user {}
+
+		definition group {
+			relation member: user
+		}
+
+		definition folder {
+			relation viewer: user | group#member
+		}
+
+		definition document {
+			relation parent: folder
+			relation viewer: user | user:*
+			relation banned: user
+			permission view = (viewer + parent->viewer) - banned
+		}`,
+	})
+	require.NoError(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client := experimentalv1.NewExperimentalWatchServiceClient(conn)
+	permissionsClient := v1.NewPermissionsServiceClient(conn)
+
+	// Watching an unknown permission fails.
+	invalid, err := client.WatchPermissions(ctx, &experimentalv1.WatchPermissionsRequest{
+		Permissions: []*experimentalv1.WatchedPermission{
+			{ResourceObjectType: "document", Permission: "unknown", SubjectObjectType: "user"},
+		},
+	})
+	require.NoError(err)
+	_, err = invalid.Recv()
+	grpcutil.RequireStatus(t, codes.FailedPrecondition, err)
+
+	head, err := permissionsClient.WriteRelationships(ctx, &v1.WriteRelationshipsRequest{
+		Updates: []*v1.RelationshipUpdate{
+			tuple.UpdateToRelationshipUpdate(tuple.Touch(tuple.MustParse("document:other#viewer@user:alice"))),
+		},
+	})
+	require.NoError(err)
+
+	stream, err := client.WatchPermissions(ctx, &experimentalv1.WatchPermissionsRequest{
+		Permissions: []*experimentalv1.WatchedPermission{
+			{ResourceObjectType: "document", Permission: "view", SubjectObjectType: "user"},
+		},
+		OptionalStartCursor: head.WrittenAt,
+	})
+	require.NoError(err)
+
+	steps := []struct {
+		name            string
+		updates         []*core.RelationTupleUpdate
+		expectedChanges []string
+	}{
+		{
+			"direct relationship",
+			[]*core.RelationTupleUpdate{
+				tuple.Touch(tuple.MustParse("document:doc1#viewer@user:alice")),
+			},
+			[]string{"document:doc1#view@user:alice NO_PERMISSION->HAS_PERMISSION"},
+		},
+		{
+			"arrow and subject relation",
+			[]*core.RelationTupleUpdate{
+				tuple.Touch(tuple.MustParse("document:doc1#parent@folder:f1")),
+				tuple.Touch(tuple.MustParse("folder:f1#viewer@group:eng#member")),
+				tuple.Touch(tuple.MustParse("group:eng#member@user:bob")),
+				tuple.Touch(tuple.MustParse("group:eng#member@user:carol")),
+			},
+			[]string{
+				"document:doc1#view@user:bob NO_PERMISSION->HAS_PERMISSION",
+				"document:doc1#view@user:carol NO_PERMISSION->HAS_PERMISSION",
+			},
+		},
+		{
+			"exclusion",
+			[]*core.RelationTupleUpdate{
+				tuple.Touch(tuple.MustParse("document:doc1#banned@user:alice")),
+			},
+			[]string{"document:doc1#view@user:alice HAS_PERMISSION->NO_PERMISSION"},
+		},
+		{
+			"removed from group",
+			[]*core.RelationTupleUpdate{
+				tuple.Delete(tuple.MustParse("group:eng#member@user:bob")),
+			},
+			[]string{"document:doc1#view@user:bob HAS_PERMISSION->NO_PERMISSION"},
+		},
+		{
+			"unrelated change",
+			[]*core.RelationTupleUpdate{
+				tuple.Touch(tuple.MustParse("folder:f2#viewer@user:dave")),
+			},
+			nil,
+		},
+		{
+			"wildcard",
+			[]*core.RelationTupleUpdate{
+				tuple.Touch(tuple.MustParse("document:doc1#viewer@user:*")),
+				tuple.Touch(tuple.MustParse("document:doc2#viewer@user:erin")),
+			},
+			[]string{
+				"document:doc1#view@user:* NO_PERMISSION->HAS_PERMISSION",
+				"document:doc2#view@user:erin NO_PERMISSION->HAS_PERMISSION",
+			},
+		},
+	}
+
+	for _, step := range steps {
+		updates := make([]*v1.RelationshipUpdate, 0, len(step.updates))
+		for _, update := range step.updates {
+			updates = append(updates, tuple.UpdateToRelationshipUpdate(update))
+		}
+
+		written, err := permissionsClient.WriteRelationships(ctx, &v1.WriteRelationshipsRequest{Updates: updates})
+		require.NoError(err, step.name)
+
+		if len(step.expectedChanges) == 0 {
+			continue
+		}
+
+		var resp *experimentalv1.WatchPermissionsResponse
+		for resp == nil || resp.IsCheckpoint {
+			resp, err = stream.Recv()
+			require.NoError(err, step.name)
+		}
+
+		received := make([]string, 0, len(resp.Changes))
+		for _, change := range resp.Changes {
+			received = append(received, fmt.Sprintf("%s:%s#%s@%s:%s %s->%s",
+				change.Resource.ObjectType,
+				change.Resource.ObjectId,
+				change.Permission,
+				change.Subject.Object.ObjectType,
+				change.Subject.Object.ObjectId,
+				strings.TrimPrefix(change.PreviousPermissionship.String(), "PERMISSIONSHIP_"),
+				strings.TrimPrefix(change.Permissionship.String(), "PERMISSIONSHIP_"),
+			))
+		}
+
+		require.Equal(step.expectedChanges, received, step.name)
+		require.Equal(written.WrittenAt.Token, resp.ChangesThrough.Token, step.name)
+	}
+}
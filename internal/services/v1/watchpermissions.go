@@ -0,0 +1,506 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/jzelinskie/stringz"
+	"golang.org/x/exp/maps"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/authzed/spicedb/internal/datasets"
+	"github.com/authzed/spicedb/internal/dispatch"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/options"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	dispatchv1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	experimentalv1 "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+	"github.com/authzed/spicedb/pkg/zedtoken"
+)
+
+func (ews *experimentalWatchServer) WatchPermissions(req *experimentalv1.WatchPermissionsRequest, stream experimentalv1.ExperimentalWatchService_WatchPermissionsServer) error {
+	ctx := stream.Context()
+	ds := datastoremw.MustFromContext(ctx)
+
+	headRevision, err := ds.HeadRevision(ctx)
+	if err != nil {
+		return rewriteError(ctx, err)
+	}
+
+	reader := ds.SnapshotReader(headRevision)
+	for _, watched := range req.Permissions {
+		if err := namespace.CheckNamespaceAndRelation(ctx, watched.ResourceObjectType, watched.Permission, false, reader); err != nil {
+			return rewriteError(ctx, err)
+		}
+
+		subjectRelation := stringz.DefaultEmpty(watched.OptionalSubjectRelation, tuple.Ellipsis)
+		if err := namespace.CheckNamespaceAndRelation(ctx, watched.SubjectObjectType, subjectRelation, true, reader); err != nil {
+			return rewriteError(ctx, err)
+		}
+	}
+
+	return watchChanges(ctx, req.OptionalStartCursor, datastore.WatchOptions{}, ews.heartbeat, func(previousRevision datastore.Revision, update *datastore.RevisionChanges) (bool, error) {
+		if len(update.Changes) == 0 {
+			return false, nil
+		}
+
+		var changes []*experimentalv1.PermissionChange
+		for _, watched := range req.Permissions {
+			watchedChanges, err := ews.permissionChanges(ctx, ds, watched, previousRevision, update)
+			if err != nil {
+				return false, rewriteError(ctx, err)
+			}
+			changes = append(changes, watchedChanges...)
+		}
+
+		if len(changes) == 0 {
+			return false, nil
+		}
+
+		if err := stream.Send(&experimentalv1.WatchPermissionsResponse{
+			Changes:        changes,
+			ChangesThrough: zedtoken.MustNewFromRevision(update.Revision),
+		}); err != nil {
+			return false, status.Errorf(codes.Canceled, "watch canceled by user: %s", err)
+		}
+		return true, nil
+	}, func(revision datastore.Revision) error {
+		if err := stream.Send(&experimentalv1.WatchPermissionsResponse{
+			ChangesThrough: zedtoken.MustNewFromRevision(revision),
+			IsCheckpoint:   true,
+		}); err != nil {
+			return status.Errorf(codes.Canceled, "watch canceled by user: %s", err)
+		}
+		return nil
+	})
+}
+
+// permissionChanges returns the changes to the watched permission made by the relationship changes
+// of the update, sorted by resource ID and then subject ID.
+//
+// The resources whose permission may have changed are found by walking the reachability graph
+// back from each changed relationship, both before and after the update, and the subjects of those
+// resources found before and after the update are then compared.
+func (ews *experimentalWatchServer) permissionChanges(
+	ctx context.Context,
+	ds datastore.Datastore,
+	watched *experimentalv1.WatchedPermission,
+	previousRevision datastore.Revision,
+	update *datastore.RevisionChanges,
+) ([]*experimentalv1.PermissionChange, error) {
+	resourceIDs := map[string]struct{}{}
+	for _, revision := range []datastore.Revision{previousRevision, update.Revision} {
+		affected, err := affectedResourceIDs(ctx, ds.SnapshotReader(revision), watched, update.Changes)
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(resourceIDs, affected)
+	}
+
+	if len(resourceIDs) == 0 {
+		return nil, nil
+	}
+
+	sortedResourceIDs := maps.Keys(resourceIDs)
+	sort.Strings(sortedResourceIDs)
+
+	before, err := ews.subjectPermissionshipsByResourceID(ctx, ds.SnapshotReader(previousRevision), previousRevision, watched, sortedResourceIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := ews.subjectPermissionshipsByResourceID(ctx, ds.SnapshotReader(update.Revision), update.Revision, watched, sortedResourceIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []*experimentalv1.PermissionChange
+	for _, resourceID := range sortedResourceIDs {
+		subjectIDs := map[string]struct{}{}
+		maps.Copy(subjectIDs, before[resourceID].subjectIDs())
+		maps.Copy(subjectIDs, after[resourceID].subjectIDs())
+
+		sortedSubjectIDs := maps.Keys(subjectIDs)
+		sort.Strings(sortedSubjectIDs)
+
+		for _, subjectID := range sortedSubjectIDs {
+			previous := before[resourceID].permissionshipOf(subjectID)
+			current := after[resourceID].permissionshipOf(subjectID)
+			if previous == current {
+				continue
+			}
+
+			changes = append(changes, &experimentalv1.PermissionChange{
+				Resource: &v1.ObjectReference{
+					ObjectType: watched.ResourceObjectType,
+					ObjectId:   resourceID,
+				},
+				Permission: watched.Permission,
+				Subject: &v1.SubjectReference{
+					Object: &v1.ObjectReference{
+						ObjectType: watched.SubjectObjectType,
+						ObjectId:   subjectID,
+					},
+					OptionalRelation: watched.OptionalSubjectRelation,
+				},
+				PreviousPermissionship: previous,
+				Permissionship:         current,
+			})
+		}
+	}
+
+	return changes, nil
+}
+
+// affectedResourceIDs returns the IDs of the resources whose watched permission, as of the
+// revision of the reader, is computed from any of the changed relationships.
+func affectedResourceIDs(
+	ctx context.Context,
+	reader datastore.Reader,
+	watched *experimentalv1.WatchedPermission,
+	changes []*core.RelationTupleUpdate,
+) (map[string]struct{}, error) {
+	resourceIDs := map[string]struct{}{}
+
+	exists, err := watchedPermissionExists(ctx, reader, watched)
+	if err != nil || !exists {
+		return resourceIDs, err
+	}
+
+	_, ts, err := namespace.ReadNamespaceAndTypes(ctx, watched.ResourceObjectType, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	vts, err := ts.Validate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rg := namespace.ReachabilityGraphFor(vts)
+	target := &core.RelationReference{
+		Namespace: watched.ResourceObjectType,
+		Relation:  watched.Permission,
+	}
+
+	// Start at each object and relation whose subjects were changed, including those permissions
+	// on the changed resource containing arrows over the changed relation.
+	var pending []*core.ObjectAndRelation
+	for _, change := range changes {
+		changed := change.Tuple
+		pending = append(pending, changed.ResourceAndRelation)
+
+		subjectDef, _, err := reader.ReadNamespaceByName(ctx, changed.Subject.Namespace)
+		if err != nil {
+			if errors.As(err, &datastore.ErrNamespaceNotFound{}) {
+				continue
+			}
+			return nil, err
+		}
+
+		for _, subjectRelation := range subjectDef.Relation {
+			entrypoints, err := rg.AllEntrypointsForSubjectToResource(ctx, &core.RelationReference{
+				Namespace: changed.Subject.Namespace,
+				Relation:  subjectRelation.Name,
+			}, target)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, entrypoint := range entrypoints {
+				if entrypoint.EntrypointKind() != core.ReachabilityEntrypoint_TUPLESET_TO_USERSET_ENTRYPOINT {
+					continue
+				}
+
+				tuplesetRelation, err := entrypoint.TuplesetRelation()
+				if err != nil {
+					return nil, err
+				}
+
+				containing := entrypoint.ContainingRelationOrPermission()
+				if tuplesetRelation == changed.ResourceAndRelation.Relation && containing.Namespace == changed.ResourceAndRelation.Namespace {
+					pending = append(pending, &core.ObjectAndRelation{
+						Namespace: containing.Namespace,
+						ObjectId:  changed.ResourceAndRelation.ObjectId,
+						Relation:  containing.Relation,
+					})
+				}
+			}
+		}
+	}
+
+	// Walk back from each object and relation to those computed from it, until the watched
+	// permission is reached. The full reachability graph is used, so that objects reached only
+	// through intersections and exclusions are included.
+	encountered := map[string]struct{}{}
+	for len(pending) > 0 {
+		current := pending[0]
+		pending = pending[1:]
+
+		key := tuple.StringONR(current)
+		if _, ok := encountered[key]; ok {
+			continue
+		}
+		encountered[key] = struct{}{}
+
+		if current.Namespace == target.Namespace && current.Relation == target.Relation {
+			resourceIDs[current.ObjectId] = struct{}{}
+		}
+
+		entrypoints, err := rg.AllEntrypointsForSubjectToResource(ctx, &core.RelationReference{
+			Namespace: current.Namespace,
+			Relation:  current.Relation,
+		}, target)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entrypoint := range entrypoints {
+			containing := entrypoint.ContainingRelationOrPermission()
+
+			var resourceRelation *core.RelationReference
+			subjectsFilter := datastore.SubjectsFilter{
+				SubjectType:        current.Namespace,
+				OptionalSubjectIds: []string{current.ObjectId},
+			}
+
+			switch entrypoint.EntrypointKind() {
+			case core.ReachabilityEntrypoint_COMPUTED_USERSET_ENTRYPOINT:
+				if containing.Namespace == current.Namespace {
+					pending = append(pending, &core.ObjectAndRelation{
+						Namespace: current.Namespace,
+						ObjectId:  current.ObjectId,
+						Relation:  containing.Relation,
+					})
+				}
+				continue
+
+			case core.ReachabilityEntrypoint_RELATION_ENTRYPOINT:
+				directRelation, err := entrypoint.DirectRelation()
+				if err != nil {
+					return nil, err
+				}
+
+				resourceRelation = directRelation
+				subjectsFilter.RelationFilter = subjectsFilter.RelationFilter.WithRelation(current.Relation)
+
+			case core.ReachabilityEntrypoint_TUPLESET_TO_USERSET_ENTRYPOINT:
+				tuplesetRelation, err := entrypoint.TuplesetRelation()
+				if err != nil {
+					return nil, err
+				}
+
+				resourceRelation = &core.RelationReference{Namespace: containing.Namespace, Relation: tuplesetRelation}
+				subjectsFilter.RelationFilter = subjectsFilter.RelationFilter.WithEllipsisRelation()
+
+			default:
+				return nil, fmt.Errorf("unknown kind of entrypoint %v", entrypoint.EntrypointKind())
+			}
+
+			it, err := reader.ReverseQueryRelationships(ctx, subjectsFilter, options.WithResRelation(&options.ResourceRelation{
+				Namespace: resourceRelation.Namespace,
+				Relation:  resourceRelation.Relation,
+			}))
+			if err != nil {
+				return nil, err
+			}
+
+			for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+				pending = append(pending, &core.ObjectAndRelation{
+					Namespace: containing.Namespace,
+					ObjectId:  tpl.ResourceAndRelation.ObjectId,
+					Relation:  containing.Relation,
+				})
+			}
+
+			err = it.Err()
+			it.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return resourceIDs, nil
+}
+
+// subjectPermissionshipsByResourceID returns the permissionships of the subjects found for the
+// watched permission on each of the resources, as of the revision.
+func (ews *experimentalWatchServer) subjectPermissionshipsByResourceID(
+	ctx context.Context,
+	reader datastore.Reader,
+	revision datastore.Revision,
+	watched *experimentalv1.WatchedPermission,
+	resourceIDs []string,
+) (map[string]subjectPermissionships, error) {
+	foundSubjectsByResourceID := map[string]datasets.SubjectSet{}
+	for _, resourceID := range resourceIDs {
+		foundSubjectsByResourceID[resourceID] = datasets.NewSubjectSet()
+	}
+
+	exists, err := watchedPermissionExists(ctx, reader, watched)
+	if err != nil {
+		return nil, err
+	}
+
+	if exists {
+		stream := dispatch.NewHandlingDispatchStream(ctx, func(result *dispatchv1.DispatchLookupSubjectsResponse) error {
+			for resourceID, found := range result.FoundSubjectsByResourceId {
+				foundSubjects, ok := foundSubjectsByResourceID[resourceID]
+				if !ok {
+					return fmt.Errorf("unexpected resource ID in returned LS")
+				}
+
+				if err := foundSubjects.UnionWithFoundSubjects(found); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+		err := ews.dispatch.DispatchLookupSubjects(
+			&dispatchv1.DispatchLookupSubjectsRequest{
+				Metadata: &dispatchv1.ResolverMeta{
+					AtRevision:     revision.String(),
+					DepthRemaining: ews.maximumAPIDepth,
+				},
+				ResourceRelation: &core.RelationReference{
+					Namespace: watched.ResourceObjectType,
+					Relation:  watched.Permission,
+				},
+				ResourceIds: resourceIDs,
+				SubjectRelation: &core.RelationReference{
+					Namespace: watched.SubjectObjectType,
+					Relation:  stringz.DefaultEmpty(watched.OptionalSubjectRelation, tuple.Ellipsis),
+				},
+				AllowCompactSubjects: true,
+			},
+			stream)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	permissionships := make(map[string]subjectPermissionships, len(resourceIDs))
+	for resourceID, foundSubjects := range foundSubjectsByResourceID {
+		permissionships[resourceID] = subjectPermissionshipsFor(foundSubjects.AsSlice())
+	}
+	return permissionships, nil
+}
+
+// watchedPermissionExists returns whether the resource and subject types and the permission
+// of the watched permission are defined as of the revision of the reader.
+func watchedPermissionExists(ctx context.Context, reader datastore.Reader, watched *experimentalv1.WatchedPermission) (bool, error) {
+	subjectRelation := stringz.DefaultEmpty(watched.OptionalSubjectRelation, tuple.Ellipsis)
+	for _, err := range []error{
+		namespace.CheckNamespaceAndRelation(ctx, watched.ResourceObjectType, watched.Permission, false, reader),
+		namespace.CheckNamespaceAndRelation(ctx, watched.SubjectObjectType, subjectRelation, true, reader),
+	} {
+		var relationNotFoundErr namespace.ErrRelationNotFound
+		switch {
+		case err == nil:
+			continue
+		case errors.As(err, &datastore.ErrNamespaceNotFound{}), errors.As(err, &relationNotFoundErr):
+			return false, nil
+		default:
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// subjectPermissionships are the permissionships of the subjects found for a permission on
+// a single resource.
+type subjectPermissionships struct {
+	concrete map[string]v1.CheckPermissionResponse_Permissionship
+
+	// wildcard is the permissionship of the public wildcard, and excluded holds the
+	// permissionship through the wildcard of each subject excluded from it.
+	wildcard v1.CheckPermissionResponse_Permissionship
+	excluded map[string]v1.CheckPermissionResponse_Permissionship
+}
+
+func subjectPermissionshipsFor(foundSubjects []*dispatchv1.FoundSubject) subjectPermissionships {
+	sp := subjectPermissionships{
+		concrete: map[string]v1.CheckPermissionResponse_Permissionship{},
+		wildcard: v1.CheckPermissionResponse_PERMISSIONSHIP_NO_PERMISSION,
+		excluded: map[string]v1.CheckPermissionResponse_Permissionship{},
+	}
+
+	for _, foundSubject := range foundSubjects {
+		if foundSubject.SubjectId != tuple.PublicWildcard {
+			sp.concrete[foundSubject.SubjectId] = foundPermissionship(foundSubject)
+			continue
+		}
+
+		sp.wildcard = foundPermissionship(foundSubject)
+		for _, excludedSubject := range foundSubject.ExcludedSubjects {
+			// A conditional exclusion leaves the subject with conditional permission.
+			sp.excluded[excludedSubject.SubjectId] = v1.CheckPermissionResponse_PERMISSIONSHIP_NO_PERMISSION
+			if excludedSubject.CaveatExpression != nil {
+				sp.excluded[excludedSubject.SubjectId] = v1.CheckPermissionResponse_PERMISSIONSHIP_CONDITIONAL_PERMISSION
+			}
+		}
+	}
+
+	return sp
+}
+
+func foundPermissionship(foundSubject *dispatchv1.FoundSubject) v1.CheckPermissionResponse_Permissionship {
+	if foundSubject.CaveatExpression != nil {
+		return v1.CheckPermissionResponse_PERMISSIONSHIP_CONDITIONAL_PERMISSION
+	}
+	return v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION
+}
+
+// subjectIDs returns the IDs of all subjects with a permissionship of their own, including
+// the wildcard if found.
+func (sp subjectPermissionships) subjectIDs() map[string]struct{} {
+	subjectIDs := map[string]struct{}{}
+	for subjectID := range sp.concrete {
+		subjectIDs[subjectID] = struct{}{}
+	}
+
+	if sp.wildcard != v1.CheckPermissionResponse_PERMISSIONSHIP_NO_PERMISSION {
+		subjectIDs[tuple.PublicWildcard] = struct{}{}
+		for subjectID := range sp.excluded {
+			subjectIDs[subjectID] = struct{}{}
+		}
+	}
+
+	return subjectIDs
+}
+
+// permissionshipOf returns the permissionship of the subject, either found directly or
+// through the wildcard.
+func (sp subjectPermissionships) permissionshipOf(subjectID string) v1.CheckPermissionResponse_Permissionship {
+	if subjectID == tuple.PublicWildcard {
+		return sp.wildcard
+	}
+
+	viaWildcard := sp.wildcard
+	if excluded, ok := sp.excluded[subjectID]; ok && viaWildcard != v1.CheckPermissionResponse_PERMISSIONSHIP_NO_PERMISSION {
+		viaWildcard = excluded
+	}
+
+	direct, ok := sp.concrete[subjectID]
+	if !ok {
+		return viaWildcard
+	}
+
+	if direct == v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION {
+		return direct
+	}
+
+	if viaWildcard == v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION {
+		return viaWildcard
+	}
+	return direct
+}
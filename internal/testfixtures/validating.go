@@ -31,6 +31,7 @@ func (vd validatingDatastore) SnapshotReader(revision datastore.Revision) datast
 func (vd validatingDatastore) ReadWriteTx(
 	ctx context.Context,
 	f datastore.TxUserFunc,
+	opts ...options.RWTOptionsOption,
 ) (datastore.Revision, error) {
 	if f == nil {
 		return datastore.NoRevision, fmt.Errorf("nil delegate function")
@@ -39,7 +40,7 @@ func (vd validatingDatastore) ReadWriteTx(
 	return vd.Datastore.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
 		txDelegate := validatingReadWriteTransaction{validatingSnapshotReader{rwt}, rwt}
 		return f(txDelegate)
-	})
+	}, opts...)
 }
 
 func (vd validatingDatastore) Unwrap() datastore.Datastore {
@@ -211,12 +212,46 @@ func (vrwt validatingReadWriteTransaction) WriteRelationships(ctx context.Contex
 	return vrwt.delegate.WriteRelationships(ctx, mutations)
 }
 
-func (vrwt validatingReadWriteTransaction) DeleteRelationships(ctx context.Context, filter *v1.RelationshipFilter) error {
+func (vrwt validatingReadWriteTransaction) BulkLoad(ctx context.Context, iter datastore.BulkWriteRelationshipSource) (uint64, error) {
+	return vrwt.delegate.BulkLoad(ctx, validatingBulkSource{iter})
+}
+
+// validatingBulkSource runs validation on each relationship returned by the source.
+type validatingBulkSource struct {
+	delegate datastore.BulkWriteRelationshipSource
+}
+
+func (vbs validatingBulkSource) Next(ctx context.Context) (*core.RelationTuple, error) {
+	tpl, err := vbs.delegate.Next(ctx)
+	if err != nil || tpl == nil {
+		return tpl, err
+	}
+
+	if err := tpl.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := validateUpdatesToWrite(tuple.Create(tpl)); err != nil {
+		return nil, err
+	}
+
+	return tpl, nil
+}
+
+func (vrwt validatingReadWriteTransaction) DeleteRelationships(ctx context.Context, filter *v1.RelationshipFilter, options ...options.DeleteOptionsOption) (uint64, bool, error) {
 	if err := filter.Validate(); err != nil {
-		return err
+		return 0, false, err
+	}
+
+	return vrwt.delegate.DeleteRelationships(ctx, filter, options...)
+}
+
+func (vrwt validatingReadWriteTransaction) RelationshipsChangedSince(ctx context.Context, filter *v1.RelationshipFilter, revision datastore.Revision) (bool, error) {
+	if err := filter.Validate(); err != nil {
+		return false, err
 	}
 
-	return vrwt.delegate.DeleteRelationships(ctx, filter)
+	return vrwt.delegate.RelationshipsChangedSince(ctx, filter, revision)
 }
 
 func (vrwt validatingReadWriteTransaction) WriteCaveats(ctx context.Context, caveats []*core.CaveatDefinition) error {
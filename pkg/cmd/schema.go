@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/internal/services/shared"
+	"github.com/authzed/spicedb/pkg/cmd/datastore"
+	"github.com/authzed/spicedb/pkg/cmd/server"
+	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
+	"github.com/authzed/spicedb/pkg/schemadsl/input"
+)
+
+var errSchemaChangesBlocked = errors.New("schema changes would be rejected")
+
+func NewSchemaCommand(_ string) (*cobra.Command, error) {
+	schemaCmd := &cobra.Command{
+		Use:   "schema",
+		Short: "schema operations",
+		Long:  "Operations on the schema stored in the configured datastore",
+	}
+
+	cfg := datastore.Config{}
+
+	diffCmd := NewDiffSchemaCommand(schemaCmd.Use, &cfg)
+	if err := datastore.RegisterDatastoreFlagsWithPrefix(diffCmd.Flags(), "", &cfg); err != nil {
+		return nil, err
+	}
+	schemaCmd.AddCommand(diffCmd)
+
+	return schemaCmd, nil
+}
+
+func NewDiffSchemaCommand(programName string, cfg *datastore.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:     "diff <schema file>",
+		Short:   "reports the changes a schema would make",
+		Long:    "Compiles and validates the schema in the given file and reports the changes that writing it would make to the schema in the datastore, along with the number of existing relationships which would prevent each change, without writing anything",
+		PreRunE: server.DefaultPreRunE(programName),
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+
+			schema, err := os.ReadFile(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to read schema file: %w", err)
+			}
+
+			emptyDefaultPrefix := ""
+			compiled, err := compiler.Compile(compiler.InputSchema{
+				Source:       input.Source(args[0]),
+				SchemaString: string(schema),
+			}, &emptyDefaultPrefix)
+			if err != nil {
+				return err
+			}
+
+			validated, err := shared.ValidateSchemaChanges(ctx, compiled, false)
+			if err != nil {
+				return err
+			}
+
+			// Disable background GC and hedging, and never write.
+			cfg.GCInterval = -1 * time.Hour
+			cfg.RequestHedgingEnabled = false
+			cfg.ReadOnly = true
+
+			ds, err := datastore.NewDatastore(ctx, cfg.ToOption())
+			if err != nil {
+				return fmt.Errorf("failed to create datastore: %w", err)
+			}
+			defer ds.Close()
+
+			headRevision, err := ds.HeadRevision(ctx)
+			if err != nil {
+				return err
+			}
+
+			deltas, err := shared.DiffSchemaChanges(ctx, ds.SnapshotReader(headRevision), validated)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			if len(deltas) == 0 {
+				fmt.Fprintln(out, "no changes")
+				return nil
+			}
+
+			blocked := false
+			for _, delta := range deltas {
+				line := fmt.Sprintf("%s: %s", delta.DefinitionName, delta.Type)
+				switch {
+				case delta.RelationName != "":
+					line += fmt.Sprintf(" `%s`", delta.RelationName)
+				case delta.ParameterName != "":
+					line += fmt.Sprintf(" `%s`", delta.ParameterName)
+				}
+
+				if delta.AllowedType != nil {
+					line += fmt.Sprintf(" (allowed type `%s`)", namespace.SourceForAllowedRelation(delta.AllowedType))
+				}
+
+				if delta.Blocked {
+					blocked = true
+					switch {
+					case delta.BlockingRelationshipsCountCapped:
+						line += fmt.Sprintf(": blocked by %d+ existing relationships", delta.BlockingRelationshipsCount)
+					case delta.BlockingRelationshipsCount > 0:
+						line += fmt.Sprintf(": blocked by %d existing relationships", delta.BlockingRelationshipsCount)
+					default:
+						line += ": not allowed"
+					}
+				}
+
+				fmt.Fprintln(out, line)
+			}
+
+			if blocked {
+				return errSchemaChangesBlocked
+			}
+			return nil
+		},
+	}
+}
@@ -25,11 +25,13 @@ import (
 	"github.com/authzed/spicedb/internal/dashboard"
 	"github.com/authzed/spicedb/internal/datastore/proxy"
 	"github.com/authzed/spicedb/internal/dispatch"
+	"github.com/authzed/spicedb/internal/dispatch/caching"
 	clusterdispatch "github.com/authzed/spicedb/internal/dispatch/cluster"
 	combineddispatch "github.com/authzed/spicedb/internal/dispatch/combined"
 	"github.com/authzed/spicedb/internal/dispatch/graph"
 	"github.com/authzed/spicedb/internal/gateway"
 	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/internal/materialize"
 	"github.com/authzed/spicedb/internal/services"
 	dispatchSvc "github.com/authzed/spicedb/internal/services/dispatch"
 	"github.com/authzed/spicedb/internal/services/health"
@@ -97,15 +99,20 @@ type Config struct {
 	DispatchHashringReplicationFactor uint16
 	DispatchHashringSpread            uint8
 
-	DispatchCacheConfig        CacheConfig
-	ClusterDispatchCacheConfig CacheConfig
+	DispatchCacheConfig               CacheConfig
+	ClusterDispatchCacheConfig        CacheConfig
+	DispatchCacheReuseAcrossRevisions bool
+	MaterializePermissions            bool
 
 	// API Behavior
-	DisableV1SchemaAPI       bool
-	V1SchemaAdditiveOnly     bool
-	MaximumUpdatesPerWrite   uint16
-	MaximumPreconditionCount uint16
-	MaxDatastoreReadPageSize uint64
+	DisableV1SchemaAPI          bool
+	V1SchemaAdditiveOnly        bool
+	MaximumUpdatesPerWrite      uint16
+	MaximumPreconditionCount    uint16
+	MaxDatastoreReadPageSize    uint64
+	MaxDeleteRelationshipsLimit uint32
+	MaxBulkCheckItems           uint32
+	WatchHeartbeat              time.Duration
 
 	// Additional Services
 	DashboardAPI util.HTTPServerConfig
@@ -218,9 +225,26 @@ func (c *Config) Complete(ctx context.Context) (RunnableServer, error) {
 
 	enableGRPCHistogram()
 
+	// Results reused across revisions are not expired with the quantization window, as they
+	// remain valid for so long as the relationships on which they depend are unchanged.
+	var changeTracker *caching.ChangeTracker
+	dispatchCacheQuantization := c.DatastoreConfig.RevisionQuantization
+	if c.DispatchCacheReuseAcrossRevisions {
+		changeTracker = caching.NewChangeTracker(ds)
+		closeables.AddWithError(changeTracker.Close)
+		dispatchCacheQuantization = 0
+	}
+
+	// Checks and lookups of materialized permissions are answered from the index once the worker,
+	// started below with the dispatcher, has caught up.
+	var materializedIndex *materialize.Index
+	if c.MaterializePermissions {
+		materializedIndex = materialize.NewIndex()
+	}
+
 	dispatcher := c.Dispatcher
 	if dispatcher == nil {
-		cc, err := c.DispatchCacheConfig.WithQuantization(c.DatastoreConfig.RevisionQuantization).Complete()
+		cc, err := c.DispatchCacheConfig.WithQuantization(dispatchCacheQuantization).Complete()
 		if err != nil {
 			return nil, fmt.Errorf("failed to create dispatcher: %w", err)
 		}
@@ -257,6 +281,8 @@ func (c *Config) Complete(ctx context.Context) (RunnableServer, error) {
 			combineddispatch.PrometheusSubsystem(c.DispatchClientMetricsPrefix),
 			combineddispatch.Cache(cc),
 			combineddispatch.ConcurrencyLimits(concurrencyLimits),
+			combineddispatch.ChangeTracker(changeTracker),
+			combineddispatch.MaterializedIndex(materializedIndex),
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create dispatcher: %w", err)
@@ -265,6 +291,11 @@ func (c *Config) Complete(ctx context.Context) (RunnableServer, error) {
 	}
 	closeables.AddWithError(dispatcher.Close)
 
+	if materializedIndex != nil {
+		worker := materialize.StartWorker(ds, dispatcher, materializedIndex, c.DispatchMaxDepth)
+		closeables.AddWithError(worker.Close)
+	}
+
 	if len(c.DispatchUnaryMiddleware) == 0 && len(c.DispatchStreamingMiddleware) == 0 {
 		if c.GRPCAuthFunc == nil {
 			c.DispatchUnaryMiddleware, c.DispatchStreamingMiddleware = DefaultDispatchMiddleware(log.Logger, auth.MustRequirePresharedKey(c.PresharedKey), ds)
@@ -275,7 +306,7 @@ func (c *Config) Complete(ctx context.Context) (RunnableServer, error) {
 
 	var cachingClusterDispatch dispatch.Dispatcher
 	if c.DispatchServer.Enabled {
-		cdcc, err := c.ClusterDispatchCacheConfig.WithQuantization(c.DatastoreConfig.RevisionQuantization).Complete()
+		cdcc, err := c.ClusterDispatchCacheConfig.WithQuantization(dispatchCacheQuantization).Complete()
 		if err != nil {
 			return nil, fmt.Errorf("failed to configure cluster dispatch: %w", err)
 		}
@@ -288,6 +319,8 @@ func (c *Config) Complete(ctx context.Context) (RunnableServer, error) {
 			clusterdispatch.PrometheusSubsystem(c.DispatchClusterMetricsPrefix),
 			clusterdispatch.Cache(cdcc),
 			clusterdispatch.RemoteDispatchTimeout(c.DispatchUpstreamTimeout),
+			clusterdispatch.ChangeTracker(changeTracker),
+			clusterdispatch.MaterializedIndex(materializedIndex),
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to configure cluster dispatch: %w", err)
@@ -336,11 +369,13 @@ func (c *Config) Complete(ctx context.Context) (RunnableServer, error) {
 	}
 
 	permSysConfig := v1svc.PermissionsServerConfig{
-		MaxPreconditionsCount:    c.MaximumPreconditionCount,
-		MaxUpdatesPerWrite:       c.MaximumUpdatesPerWrite,
-		MaximumAPIDepth:          c.DispatchMaxDepth,
-		MaxCaveatContextSize:     c.MaxCaveatContextSize,
-		MaxDatastoreReadPageSize: c.MaxDatastoreReadPageSize,
+		MaxPreconditionsCount:       c.MaximumPreconditionCount,
+		MaxUpdatesPerWrite:          c.MaximumUpdatesPerWrite,
+		MaximumAPIDepth:             c.DispatchMaxDepth,
+		MaxCaveatContextSize:        c.MaxCaveatContextSize,
+		MaxDatastoreReadPageSize:    c.MaxDatastoreReadPageSize,
+		MaxDeleteRelationshipsLimit: c.MaxDeleteRelationshipsLimit,
+		MaxBulkCheckItems:           c.MaxBulkCheckItems,
 	}
 
 	healthManager := health.NewHealthManager(dispatcher, ds)
@@ -352,6 +387,7 @@ func (c *Config) Complete(ctx context.Context) (RunnableServer, error) {
 				dispatcher,
 				v1SchemaServiceOption,
 				watchServiceOption,
+				c.WatchHeartbeat,
 				permSysConfig,
 			)
 		},
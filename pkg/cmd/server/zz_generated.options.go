@@ -57,11 +57,16 @@ func (c *Config) ToOption() ConfigOption {
 		to.DispatchHashringSpread = c.DispatchHashringSpread
 		to.DispatchCacheConfig = c.DispatchCacheConfig
 		to.ClusterDispatchCacheConfig = c.ClusterDispatchCacheConfig
+		to.DispatchCacheReuseAcrossRevisions = c.DispatchCacheReuseAcrossRevisions
+		to.MaterializePermissions = c.MaterializePermissions
 		to.DisableV1SchemaAPI = c.DisableV1SchemaAPI
 		to.V1SchemaAdditiveOnly = c.V1SchemaAdditiveOnly
 		to.MaximumUpdatesPerWrite = c.MaximumUpdatesPerWrite
 		to.MaximumPreconditionCount = c.MaximumPreconditionCount
 		to.MaxDatastoreReadPageSize = c.MaxDatastoreReadPageSize
+		to.MaxDeleteRelationshipsLimit = c.MaxDeleteRelationshipsLimit
+		to.MaxBulkCheckItems = c.MaxBulkCheckItems
+		to.WatchHeartbeat = c.WatchHeartbeat
 		to.DashboardAPI = c.DashboardAPI
 		to.MetricsAPI = c.MetricsAPI
 		to.MiddlewareModification = c.MiddlewareModification
@@ -313,6 +318,20 @@ func WithClusterDispatchCacheConfig(clusterDispatchCacheConfig CacheConfig) Conf
 	}
 }
 
+// WithDispatchCacheReuseAcrossRevisions returns an option that can set DispatchCacheReuseAcrossRevisions on a Config
+func WithDispatchCacheReuseAcrossRevisions(dispatchCacheReuseAcrossRevisions bool) ConfigOption {
+	return func(c *Config) {
+		c.DispatchCacheReuseAcrossRevisions = dispatchCacheReuseAcrossRevisions
+	}
+}
+
+// WithMaterializePermissions returns an option that can set MaterializePermissions on a Config
+func WithMaterializePermissions(materializePermissions bool) ConfigOption {
+	return func(c *Config) {
+		c.MaterializePermissions = materializePermissions
+	}
+}
+
 // WithDisableV1SchemaAPI returns an option that can set DisableV1SchemaAPI on a Config
 func WithDisableV1SchemaAPI(disableV1SchemaAPI bool) ConfigOption {
 	return func(c *Config) {
@@ -348,6 +367,27 @@ func WithMaxDatastoreReadPageSize(maxDatastoreReadPageSize uint64) ConfigOption
 	}
 }
 
+// WithMaxDeleteRelationshipsLimit returns an option that can set MaxDeleteRelationshipsLimit on a Config
+func WithMaxDeleteRelationshipsLimit(maxDeleteRelationshipsLimit uint32) ConfigOption {
+	return func(c *Config) {
+		c.MaxDeleteRelationshipsLimit = maxDeleteRelationshipsLimit
+	}
+}
+
+// WithMaxBulkCheckItems returns an option that can set MaxBulkCheckItems on a Config
+func WithMaxBulkCheckItems(maxBulkCheckItems uint32) ConfigOption {
+	return func(c *Config) {
+		c.MaxBulkCheckItems = maxBulkCheckItems
+	}
+}
+
+// WithWatchHeartbeat returns an option that can set WatchHeartbeat on a Config
+func WithWatchHeartbeat(watchHeartbeat time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.WatchHeartbeat = watchHeartbeat
+	}
+}
+
 // WithDashboardAPI returns an option that can set DashboardAPI on a Config
 func WithDashboardAPI(dashboardAPI util.HTTPServerConfig) ConfigOption {
 	return func(c *Config) {
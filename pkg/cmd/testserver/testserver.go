@@ -27,14 +27,15 @@ const maxDepth = 50
 
 //go:generate go run github.com/ecordell/optgen -output zz_generated.options.go . Config
 type Config struct {
-	GRPCServer               util.GRPCServerConfig
-	ReadOnlyGRPCServer       util.GRPCServerConfig
-	HTTPGateway              util.HTTPServerConfig
-	ReadOnlyHTTPGateway      util.HTTPServerConfig
-	LoadConfigs              []string
-	MaximumUpdatesPerWrite   uint16
-	MaximumPreconditionCount uint16
-	MaxCaveatContextSize     int
+	GRPCServer                  util.GRPCServerConfig
+	ReadOnlyGRPCServer          util.GRPCServerConfig
+	HTTPGateway                 util.HTTPServerConfig
+	ReadOnlyHTTPGateway         util.HTTPServerConfig
+	LoadConfigs                 []string
+	MaximumUpdatesPerWrite      uint16
+	MaximumPreconditionCount    uint16
+	MaxCaveatContextSize        int
+	MaxDeleteRelationshipsLimit uint32
 }
 
 type RunnableTestServer interface {
@@ -63,11 +64,13 @@ func (c *Config) Complete() (RunnableTestServer, error) {
 			dispatcher,
 			services.V1SchemaServiceEnabled,
 			services.WatchServiceEnabled,
+			0,
 			v1svc.PermissionsServerConfig{
-				MaxPreconditionsCount: c.MaximumPreconditionCount,
-				MaxUpdatesPerWrite:    c.MaximumUpdatesPerWrite,
-				MaximumAPIDepth:       maxDepth,
-				MaxCaveatContextSize:  c.MaxCaveatContextSize,
+				MaxPreconditionsCount:       c.MaximumPreconditionCount,
+				MaxUpdatesPerWrite:          c.MaximumUpdatesPerWrite,
+				MaximumAPIDepth:             maxDepth,
+				MaxCaveatContextSize:        c.MaxCaveatContextSize,
+				MaxDeleteRelationshipsLimit: c.MaxDeleteRelationshipsLimit,
 			},
 		)
 	}
@@ -25,6 +25,7 @@ func (c *Config) ToOption() ConfigOption {
 		to.MaximumUpdatesPerWrite = c.MaximumUpdatesPerWrite
 		to.MaximumPreconditionCount = c.MaximumPreconditionCount
 		to.MaxCaveatContextSize = c.MaxCaveatContextSize
+		to.MaxDeleteRelationshipsLimit = c.MaxDeleteRelationshipsLimit
 	}
 }
 
@@ -98,3 +99,10 @@ func WithMaxCaveatContextSize(maxCaveatContextSize int) ConfigOption {
 		c.MaxCaveatContextSize = maxCaveatContextSize
 	}
 }
+
+// WithMaxDeleteRelationshipsLimit returns an option that can set MaxDeleteRelationshipsLimit on a Config
+func WithMaxDeleteRelationshipsLimit(maxDeleteRelationshipsLimit uint32) ConfigOption {
+	return func(c *Config) {
+		c.MaxDeleteRelationshipsLimit = maxDeleteRelationshipsLimit
+	}
+}
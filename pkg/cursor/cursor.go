@@ -0,0 +1,125 @@
+// Package cursor converts dispatch cursors to opaque API cursors and vice versa.
+package cursor
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/authzed/spicedb/pkg/datastore"
+	dispatch "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+	impl "github.com/authzed/spicedb/pkg/proto/impl/v1"
+)
+
+// Public facing errors
+const (
+	errEncodeError = "error encoding cursor: %w"
+	errDecodeError = "error decoding cursor: %w"
+)
+
+// ErrNilCursor is returned as the base error when nil is provided as the
+// cursor argument to Decode
+var ErrNilCursor = errors.New("cursor pointer was nil")
+
+// ErrHashMismatch is returned as the base error when a mismatching hash was given to the decoder.
+var ErrHashMismatch = errors.New("the cursor provided does not have the same arguments as the original API call; please ensure you are making the same API call, with the exact same parameters (besides the cursor)")
+
+// Encode converts a decoded cursor to its opaque version.
+func Encode(decoded *impl.DecodedCursor) (*experimental.Cursor, error) {
+	marshalled, err := decoded.MarshalVT()
+	if err != nil {
+		return nil, NewInvalidCursorErr(fmt.Errorf(errEncodeError, err))
+	}
+
+	return &experimental.Cursor{
+		Token: base64.StdEncoding.EncodeToString(marshalled),
+	}, nil
+}
+
+// Decode converts an encoded cursor to its decoded version.
+func Decode(encoded *experimental.Cursor) (*impl.DecodedCursor, error) {
+	if encoded == nil {
+		return nil, NewInvalidCursorErr(fmt.Errorf(errDecodeError, ErrNilCursor))
+	}
+
+	decodedBytes, err := base64.StdEncoding.DecodeString(encoded.Token)
+	if err != nil {
+		return nil, NewInvalidCursorErr(fmt.Errorf(errDecodeError, err))
+	}
+
+	decoded := &impl.DecodedCursor{}
+	if err := decoded.UnmarshalVT(decodedBytes); err != nil {
+		return nil, NewInvalidCursorErr(fmt.Errorf(errDecodeError, err))
+	}
+
+	return decoded, nil
+}
+
+// EncodeFromDispatchCursor encodes an internal dispatching cursor into an opaque cursor for the
+// API, tied to the given revision and the hash of the call and its parameters.
+func EncodeFromDispatchCursor(dispatchCursor *dispatch.Cursor, callAndParameterHash string, revision datastore.Revision) (*experimental.Cursor, error) {
+	if dispatchCursor == nil {
+		return nil, fmt.Errorf(errEncodeError, errors.New("got nil dispatch cursor"))
+	}
+
+	return Encode(&impl.DecodedCursor{
+		VersionOneof: &impl.DecodedCursor_V1{
+			V1: &impl.V1Cursor{
+				Revision:              revision.String(),
+				Sections:              dispatchCursor.Sections,
+				CallAndParametersHash: callAndParameterHash,
+			},
+		},
+	})
+}
+
+// DecodeToDispatchCursor decodes an encoded API cursor into an internal dispatching cursor,
+// ensuring that the cursor was issued for the call with the given hash.
+func DecodeToDispatchCursor(encoded *experimental.Cursor, callAndParameterHash string) (*dispatch.Cursor, error) {
+	decoded, err := decodeV1(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	if decoded.CallAndParametersHash != callAndParameterHash {
+		return nil, NewInvalidCursorErr(ErrHashMismatch)
+	}
+
+	return &dispatch.Cursor{
+		Sections: decoded.Sections,
+	}, nil
+}
+
+// DecodeToDispatchRevision decodes an encoded API cursor into the revision at which it was issued.
+func DecodeToDispatchRevision(encoded *experimental.Cursor, ds revisionDecoder) (datastore.Revision, error) {
+	decoded, err := decodeV1(encoded)
+	if err != nil {
+		return datastore.NoRevision, err
+	}
+
+	parsed, err := ds.RevisionFromString(decoded.Revision)
+	if err != nil {
+		return datastore.NoRevision, NewInvalidCursorErr(fmt.Errorf(errDecodeError, err))
+	}
+
+	return parsed, nil
+}
+
+func decodeV1(encoded *experimental.Cursor) (*impl.V1Cursor, error) {
+	decoded, err := Decode(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	v1decoded := decoded.GetV1()
+	if v1decoded == nil {
+		return nil, NewInvalidCursorErr(fmt.Errorf(errDecodeError, fmt.Errorf("unknown cursor version: %T", decoded.VersionOneof)))
+	}
+
+	return v1decoded, nil
+}
+
+type revisionDecoder interface {
+	RevisionFromString(string) (datastore.Revision, error)
+}
@@ -0,0 +1,63 @@
+package cursor
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/revision"
+	dispatch "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+)
+
+var encodeTests = []struct {
+	revision datastore.Revision
+	sections []string
+}{
+	{revision.NewFromDecimal(decimal.Zero), nil},
+	{revision.NewFromDecimal(decimal.NewFromInt(1)), []string{"1"}},
+	{revision.NewFromDecimal(decimal.NewFromInt(1621538189028928000)), []string{"1", "0", "", "foo"}},
+	{revision.NewFromDecimal(decimal.New(12345, -2)), []string{"2", "1", "document:foo#viewer@user:tom", "bar"}},
+}
+
+func TestEncodeDecode(t *testing.T) {
+	for _, tc := range encodeTests {
+		tc := tc
+		t.Run(fmt.Sprintf("%s-%v", tc.revision, tc.sections), func(t *testing.T) {
+			require := require.New(t)
+
+			encoded, err := EncodeFromDispatchCursor(&dispatch.Cursor{Sections: tc.sections}, "somehash", tc.revision)
+			require.NoError(err)
+			require.NotEmpty(encoded.Token)
+
+			decoded, err := DecodeToDispatchCursor(encoded, "somehash")
+			require.NoError(err)
+			require.Equal(tc.sections, decoded.Sections)
+
+			decodedRevision, err := DecodeToDispatchRevision(encoded, revision.DecimalDecoder{})
+			require.NoError(err)
+			require.True(tc.revision.Equal(decodedRevision))
+
+			_, err = DecodeToDispatchCursor(encoded, "anotherhash")
+			require.Error(err)
+			require.True(errors.Is(err, ErrHashMismatch))
+		})
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	require := require.New(t)
+
+	_, err := Decode(nil)
+	require.ErrorIs(err, ErrNilCursor)
+
+	_, err = Decode(&experimental.Cursor{Token: "invalid"})
+	require.Error(err)
+
+	var invalidCursorErr InvalidCursorError
+	require.ErrorAs(err, &invalidCursorErr)
+}
@@ -0,0 +1,26 @@
+package cursor
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// InvalidCursorError occurs when a cursor could not be decoded.
+type InvalidCursorError struct {
+	error
+}
+
+// Unwrap returns the underlying error.
+func (err InvalidCursorError) Unwrap() error {
+	return err.error
+}
+
+// GRPCStatus implements retrieving the gRPC status for the error.
+func (err InvalidCursorError) GRPCStatus() *status.Status {
+	return status.New(codes.InvalidArgument, err.Error())
+}
+
+// NewInvalidCursorErr creates and returns a new invalid cursor error.
+func NewInvalidCursorErr(err error) error {
+	return InvalidCursorError{err}
+}
@@ -7,6 +7,8 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/jzelinskie/stringz"
+
 	"github.com/authzed/spicedb/pkg/tuple"
 
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
@@ -47,6 +49,62 @@ const FilterMaximumIDCount uint16 = 100
 type RevisionChanges struct {
 	Revision Revision
 	Changes  []*core.RelationTupleUpdate
+
+	// ChangedDefinitions are any namespace or caveat definitions that were
+	// written at this revision.
+	ChangedDefinitions []SchemaDefinition
+
+	// DeletedNamespaces are the names of any namespaces that were deleted at
+	// this revision.
+	DeletedNamespaces []string
+
+	// DeletedCaveats are the names of any caveats that were deleted at this
+	// revision.
+	DeletedCaveats []string
+
+	// IsCheckpoint, if true, indicates that the datastore has reported all changes
+	// up to and including the Revision, and that no further changes will be
+	// reported at or before it. Checkpoints carry no changes of their own.
+	IsCheckpoint bool
+}
+
+// WatchOptions are the options for a call to Watch.
+type WatchOptions struct {
+	// OptionalRelationshipsFilters, if non-empty, limits the relationship changes reported
+	// to those matching *any* of the filters. Changes to schema definitions and checkpoints
+	// are always reported.
+	OptionalRelationshipsFilters []RelationshipsFilter
+}
+
+// MatchesRelationship returns true iff changes to the given relationship are to be
+// reported under these options.
+func (wo WatchOptions) MatchesRelationship(relationship *core.RelationTuple) bool {
+	if len(wo.OptionalRelationshipsFilters) == 0 {
+		return true
+	}
+
+	for _, filter := range wo.OptionalRelationshipsFilters {
+		if filter.Test(relationship) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterChanges returns the given relationship changes, limited to those matching
+// these options.
+func (wo WatchOptions) FilterChanges(changes []*core.RelationTupleUpdate) []*core.RelationTupleUpdate {
+	if len(wo.OptionalRelationshipsFilters) == 0 {
+		return changes
+	}
+
+	filtered := make([]*core.RelationTupleUpdate, 0, len(changes))
+	for _, change := range changes {
+		if wo.MatchesRelationship(change.Tuple) {
+			filtered = append(filtered, change)
+		}
+	}
+	return filtered
 }
 
 // RelationshipsFilter is a filter for relationships.
@@ -67,6 +125,9 @@ type RelationshipsFilter struct {
 	// OptionalCaveatName is the filter to use for caveated relationships, filtering by a specific caveat name.
 	// If nil, all caveated and non-caveated relationships are allowed
 	OptionalCaveatName string
+
+	// OnlyWithExpiration, if true, limits the relationships found to those with an expiration.
+	OnlyWithExpiration bool
 }
 
 // RelationshipsFilterFromPublicFilter constructs a datastore RelationshipsFilter from an API-defined RelationshipFilter.
@@ -109,6 +170,34 @@ func RelationshipsFilterFromPublicFilter(filter *v1.RelationshipFilter) Relation
 	}
 }
 
+// Test returns true iff the given relationship is matched by this filter.
+func (rf RelationshipsFilter) Test(relationship *core.RelationTuple) bool {
+	resource := relationship.ResourceAndRelation
+	switch {
+	case rf.ResourceType != "" && rf.ResourceType != resource.Namespace:
+		return false
+	case len(rf.OptionalResourceIds) > 0 && !stringz.SliceContains(rf.OptionalResourceIds, resource.ObjectId):
+		return false
+	case rf.OptionalResourceRelation != "" && rf.OptionalResourceRelation != resource.Relation:
+		return false
+	case rf.OptionalCaveatName != "" && relationship.Caveat.GetCaveatName() != rf.OptionalCaveatName:
+		return false
+	case rf.OnlyWithExpiration && relationship.OptionalExpirationTime == nil:
+		return false
+	}
+
+	if len(rf.OptionalSubjectsSelectors) == 0 {
+		return true
+	}
+
+	for _, selector := range rf.OptionalSubjectsSelectors {
+		if selector.Test(relationship.Subject) {
+			return true
+		}
+	}
+	return false
+}
+
 // SubjectsSelector is a selector for subjects.
 type SubjectsSelector struct {
 	// OptionalSubjectType is the namespace/type for the subjects to be found, if any.
@@ -122,6 +211,27 @@ type SubjectsSelector struct {
 	RelationFilter SubjectRelationFilter
 }
 
+// Test returns true iff the given subject is matched by this selector.
+func (ss SubjectsSelector) Test(subject *core.ObjectAndRelation) bool {
+	switch {
+	case ss.OptionalSubjectType != "" && ss.OptionalSubjectType != subject.Namespace:
+		return false
+	case len(ss.OptionalSubjectIds) > 0 && !stringz.SliceContains(ss.OptionalSubjectIds, subject.ObjectId):
+		return false
+	}
+
+	if ss.RelationFilter.OnlyNonEllipsisRelations {
+		return subject.Relation != Ellipsis
+	}
+
+	if ss.RelationFilter.IsEmpty() {
+		return true
+	}
+
+	return (ss.RelationFilter.IncludeEllipsisRelation && subject.Relation == Ellipsis) ||
+		(ss.RelationFilter.NonEllipsisRelation != "" && subject.Relation == ss.RelationFilter.NonEllipsisRelation)
+}
+
 // SubjectRelationFilter is the filter to use for relation(s) of subjects being queried.
 type SubjectRelationFilter struct {
 	// NonEllipsisRelation is the relation of the subject type to find. If empty,
@@ -218,6 +328,7 @@ type Reader interface {
 	CaveatReader
 
 	// QueryRelationships reads relationships, starting from the resource side.
+	// Relationships which have expired as of the read are never returned.
 	QueryRelationships(
 		ctx context.Context,
 		filter RelationshipsFilter,
@@ -225,6 +336,7 @@ type Reader interface {
 	) (RelationshipIterator, error)
 
 	// ReverseQueryRelationships reads relationships, starting from the subject.
+	// Relationships which have expired as of the read are never returned.
 	ReverseQueryRelationships(
 		ctx context.Context,
 		subjectsFilter SubjectsFilter,
@@ -249,14 +361,38 @@ type ReadWriteTransaction interface {
 	// WriteRelationships takes a list of tuple mutations and applies them to the datastore.
 	WriteRelationships(ctx context.Context, mutations []*core.RelationTupleUpdate) error
 
-	// DeleteRelationships deletes all Relationships that match the provided filter.
-	DeleteRelationships(ctx context.Context, filter *v1.RelationshipFilter) error
+	// DeleteRelationships deletes Relationships that match the provided filter, up to the
+	// optional delete limit, returning the number deleted. If a limit was given, limitReached
+	// indicates whether Relationships matching the filter remain after those deleted.
+	DeleteRelationships(ctx context.Context, filter *v1.RelationshipFilter, options ...options.DeleteOptionsOption) (deleted uint64, limitReached bool, err error)
+
+	// RelationshipsChangedSince returns whether any Relationship matching the provided filter
+	// has been written, touched or deleted by a transaction committed after the given
+	// revision. It must be called before the current transaction modifies any matching
+	// Relationships.
+	RelationshipsChangedSince(ctx context.Context, filter *v1.RelationshipFilter, revision Revision) (bool, error)
 
 	// WriteNamespaces takes proto namespace definitions and persists them.
 	WriteNamespaces(ctx context.Context, newConfigs ...*core.NamespaceDefinition) error
 
 	// DeleteNamespaces deletes namespaces including associated relationships.
 	DeleteNamespaces(ctx context.Context, nsNames ...string) error
+
+	// BulkLoad takes a relationship source iterator and creates all of the relationships
+	// it returns in the backing datastore, in an optimized fashion. Relationships are
+	// written as CREATEs without validation of any kind, and the number of relationships
+	// written is returned.
+	BulkLoad(ctx context.Context, iter BulkWriteRelationshipSource) (uint64, error)
+}
+
+// BulkWriteRelationshipSource is a source of relationships to be written by BulkLoad.
+type BulkWriteRelationshipSource interface {
+	// Next returns the next relationship to be written, or nil if the source has been
+	// exhausted.
+	//
+	// NOTE: sources may reuse the same tuple for every call, so the contents of the
+	// returned tuple must be consumed or copied before Next is called again.
+	Next(ctx context.Context) (*core.RelationTuple, error)
 }
 
 // TxUserFunc is a type for the function that users supply when they invoke a read-write transaction.
@@ -279,7 +415,12 @@ type Datastore interface {
 
 	// ReadWriteTx tarts a read/write transaction, which will be committed if no error is
 	// returned and rolled back if an error is returned.
-	ReadWriteTx(context.Context, TxUserFunc) (Revision, error)
+	//
+	// If given an idempotency key with which a transaction has already committed, the
+	// function is not run, and an ErrIdempotencyKeyAlreadyCommitted carrying the revision of
+	// that transaction is returned, or an ErrIdempotencyKeyReused if that transaction was
+	// given a different request hash.
+	ReadWriteTx(context.Context, TxUserFunc, ...options.RWTOptionsOption) (Revision, error)
 
 	// OptimizedRevision gets a revision that will likely already be replicated
 	// and will likely be shared amongst many queries.
@@ -297,10 +438,19 @@ type Datastore interface {
 	// used by the specific datastore implementation.
 	RevisionFromString(serialized string) (Revision, error)
 
-	// Watch notifies the caller about all changes to tuples.
+	// Watch notifies the caller about all changes to tuples and schema definitions.
 	//
 	// All events following afterRevision will be sent to the caller.
-	Watch(ctx context.Context, afterRevision Revision) (<-chan *RevisionChanges, <-chan error)
+	//
+	// In addition to the changes themselves, datastores emit checkpoints (see
+	// RevisionChanges.IsCheckpoint) as the revision through which all changes
+	// have been reported advances. Checkpoints may be dropped if the consumer
+	// falls behind.
+	//
+	// If options contains relationships filters, only changes to relationships
+	// matching the filters are reported; datastores should apply the filters as
+	// close to the underlying storage as possible.
+	Watch(ctx context.Context, afterRevision Revision, options WatchOptions) (<-chan *RevisionChanges, <-chan error)
 
 	// ReadyState returns a state indicating whether the datastore is ready to accept data.
 	// Datastores that require database schema creation will return not-ready until the migrations
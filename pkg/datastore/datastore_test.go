@@ -5,6 +5,8 @@ import (
 
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
 	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/pkg/tuple"
 )
 
 func TestRelationshipsFilterFromPublicFilter(t *testing.T) {
@@ -104,3 +106,89 @@ func TestRelationshipsFilterFromPublicFilter(t *testing.T) {
 		})
 	}
 }
+
+func TestRelationshipsFilterTest(t *testing.T) {
+	tests := []struct {
+		name         string
+		filter       RelationshipsFilter
+		relationship string
+		expected     bool
+	}{
+		{
+			"matching resource type",
+			RelationshipsFilter{ResourceType: "document"},
+			"document:first#viewer@user:tom",
+			true,
+		},
+		{
+			"mismatched resource type",
+			RelationshipsFilter{ResourceType: "folder"},
+			"document:first#viewer@user:tom",
+			false,
+		},
+		{
+			"matching resource ID and relation",
+			RelationshipsFilter{ResourceType: "document", OptionalResourceIds: []string{"second", "first"}, OptionalResourceRelation: "viewer"},
+			"document:first#viewer@user:tom",
+			true,
+		},
+		{
+			"mismatched relation",
+			RelationshipsFilter{ResourceType: "document", OptionalResourceRelation: "editor"},
+			"document:first#viewer@user:tom",
+			false,
+		},
+		{
+			"matching subject",
+			RelationshipsFilter{ResourceType: "group", OptionalSubjectsSelectors: []SubjectsSelector{
+				{OptionalSubjectType: "user", OptionalSubjectIds: []string{"tom"}, RelationFilter: SubjectRelationFilter{}.WithEllipsisRelation()},
+			}},
+			"group:eng#member@user:tom",
+			true,
+		},
+		{
+			"matching any subject selector",
+			RelationshipsFilter{ResourceType: "group", OptionalSubjectsSelectors: []SubjectsSelector{
+				{OptionalSubjectType: "user", OptionalSubjectIds: []string{"fred"}},
+				{OptionalSubjectType: "group", RelationFilter: SubjectRelationFilter{}.WithNonEllipsisRelation("member")},
+			}},
+			"group:eng#member@group:sre#member",
+			true,
+		},
+		{
+			"mismatched subject relation",
+			RelationshipsFilter{ResourceType: "group", OptionalSubjectsSelectors: []SubjectsSelector{
+				{OptionalSubjectType: "group", RelationFilter: SubjectRelationFilter{}.WithEllipsisRelation()},
+			}},
+			"group:eng#member@group:sre#member",
+			false,
+		},
+		{
+			"only non-ellipsis subject relations",
+			RelationshipsFilter{ResourceType: "group", OptionalSubjectsSelectors: []SubjectsSelector{
+				{RelationFilter: SubjectRelationFilter{}.WithOnlyNonEllipsisRelations()},
+			}},
+			"group:eng#member@user:tom",
+			false,
+		},
+		{
+			"matching caveat",
+			RelationshipsFilter{ResourceType: "document", OptionalCaveatName: "somecaveat"},
+			"document:first#viewer@user:tom[somecaveat]",
+			true,
+		},
+		{
+			"missing caveat",
+			RelationshipsFilter{ResourceType: "document", OptionalCaveatName: "somecaveat"},
+			"document:first#viewer@user:tom",
+			false,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, test.filter.Test(tuple.MustParse(test.relationship)))
+		})
+	}
+}
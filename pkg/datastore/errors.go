@@ -153,6 +153,64 @@ func NewInvalidRevisionErr(revision Revision, reason InvalidRevisionReason) erro
 	}
 }
 
+// ErrIdempotencyKeyAlreadyCommitted occurs when a read-write transaction is given an
+// idempotency key with which a transaction has already committed.
+type ErrIdempotencyKeyAlreadyCommitted struct {
+	error
+	key      string
+	revision Revision
+}
+
+// IdempotencyKey is the idempotency key given to the transaction.
+func (err ErrIdempotencyKeyAlreadyCommitted) IdempotencyKey() string {
+	return err.key
+}
+
+// CommittedRevision is the revision at which the transaction with the key committed.
+func (err ErrIdempotencyKeyAlreadyCommitted) CommittedRevision() Revision {
+	return err.revision
+}
+
+// MarshalZerologObject implements zerolog object marshalling.
+func (err ErrIdempotencyKeyAlreadyCommitted) MarshalZerologObject(e *zerolog.Event) {
+	e.Err(err.error).Str("key", err.key).Stringer("revision", err.revision)
+}
+
+// NewIdempotencyKeyAlreadyCommittedErr constructs a new idempotency key already committed
+// error.
+func NewIdempotencyKeyAlreadyCommittedErr(key string, revision Revision) error {
+	return ErrIdempotencyKeyAlreadyCommitted{
+		error:    fmt.Errorf("a transaction with idempotency key `%s` has already committed", key),
+		key:      key,
+		revision: revision,
+	}
+}
+
+// ErrIdempotencyKeyReused occurs when a read-write transaction is given an idempotency key
+// with which a transaction for a different request has already committed.
+type ErrIdempotencyKeyReused struct {
+	error
+	key string
+}
+
+// IdempotencyKey is the idempotency key given to the transaction.
+func (err ErrIdempotencyKeyReused) IdempotencyKey() string {
+	return err.key
+}
+
+// MarshalZerologObject implements zerolog object marshalling.
+func (err ErrIdempotencyKeyReused) MarshalZerologObject(e *zerolog.Event) {
+	e.Err(err.error).Str("key", err.key)
+}
+
+// NewIdempotencyKeyReusedErr constructs a new idempotency key reused error.
+func NewIdempotencyKeyReusedErr(key string) error {
+	return ErrIdempotencyKeyReused{
+		error: fmt.Errorf("idempotency key `%s` has already been used for a different request", key),
+		key:   key,
+	}
+}
+
 // ErrCaveatNameNotFound is the error returned when a caveat is not found by its name
 type ErrCaveatNameNotFound struct {
 	error
@@ -4,7 +4,7 @@ import (
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 )
 
-//go:generate go run github.com/ecordell/optgen -output zz_generated.query_options.go . QueryOptions ReverseQueryOptions
+//go:generate go run github.com/ecordell/optgen -output zz_generated.query_options.go . QueryOptions ReverseQueryOptions DeleteOptions RWTOptions
 
 // SortOrder is an enum which represents the order in which the caller would like
 // the data returned.
@@ -36,6 +36,28 @@ type QueryOptions struct {
 type ReverseQueryOptions struct {
 	ReverseLimit *uint64
 	ResRelation  *ResourceRelation
+
+	SortForReverse  SortOrder
+	AfterForReverse Cursor
+}
+
+// DeleteOptions are the options that can affect the results of a delete relationships
+// operation.
+type DeleteOptions struct {
+	DeleteLimit *uint64
+}
+
+// RWTOptions are the options that can affect the behavior of a read-write transaction.
+type RWTOptions struct {
+	// IdempotencyKey, if non-empty, is stored alongside the transaction when it commits. A
+	// transaction given a key with which a transaction has already committed, within the
+	// GC window, is not run.
+	IdempotencyKey string
+
+	// IdempotencyRequestHash, if non-empty, identifies the request made with the
+	// IdempotencyKey and is stored alongside it. A transaction given a key with which a
+	// transaction for a different request has already committed fails.
+	IdempotencyRequestHash string
 }
 
 // ResourceRelation combines a resource object type and relation.
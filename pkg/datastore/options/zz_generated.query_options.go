@@ -83,6 +83,8 @@ func (r *ReverseQueryOptions) ToOption() ReverseQueryOptionsOption {
 	return func(to *ReverseQueryOptions) {
 		to.ReverseLimit = r.ReverseLimit
 		to.ResRelation = r.ResRelation
+		to.SortForReverse = r.SortForReverse
+		to.AfterForReverse = r.AfterForReverse
 	}
 }
 
@@ -107,3 +109,91 @@ func WithResRelation(resRelation *ResourceRelation) ReverseQueryOptionsOption {
 		r.ResRelation = resRelation
 	}
 }
+
+// WithSortForReverse returns an option that can set SortForReverse on a ReverseQueryOptions
+func WithSortForReverse(sortForReverse SortOrder) ReverseQueryOptionsOption {
+	return func(r *ReverseQueryOptions) {
+		r.SortForReverse = sortForReverse
+	}
+}
+
+// WithAfterForReverse returns an option that can set AfterForReverse on a ReverseQueryOptions
+func WithAfterForReverse(afterForReverse Cursor) ReverseQueryOptionsOption {
+	return func(r *ReverseQueryOptions) {
+		r.AfterForReverse = afterForReverse
+	}
+}
+
+type DeleteOptionsOption func(d *DeleteOptions)
+
+// NewDeleteOptionsWithOptions creates a new DeleteOptions with the passed in options set
+func NewDeleteOptionsWithOptions(opts ...DeleteOptionsOption) *DeleteOptions {
+	d := &DeleteOptions{}
+	for _, o := range opts {
+		o(d)
+	}
+	return d
+}
+
+// ToOption returns a new DeleteOptionsOption that sets the values from the passed in DeleteOptions
+func (d *DeleteOptions) ToOption() DeleteOptionsOption {
+	return func(to *DeleteOptions) {
+		to.DeleteLimit = d.DeleteLimit
+	}
+}
+
+// DeleteOptionsWithOptions configures an existing DeleteOptions with the passed in options set
+func DeleteOptionsWithOptions(d *DeleteOptions, opts ...DeleteOptionsOption) *DeleteOptions {
+	for _, o := range opts {
+		o(d)
+	}
+	return d
+}
+
+// WithDeleteLimit returns an option that can set DeleteLimit on a DeleteOptions
+func WithDeleteLimit(deleteLimit *uint64) DeleteOptionsOption {
+	return func(d *DeleteOptions) {
+		d.DeleteLimit = deleteLimit
+	}
+}
+
+type RWTOptionsOption func(r *RWTOptions)
+
+// NewRWTOptionsWithOptions creates a new RWTOptions with the passed in options set
+func NewRWTOptionsWithOptions(opts ...RWTOptionsOption) *RWTOptions {
+	r := &RWTOptions{}
+	for _, o := range opts {
+		o(r)
+	}
+	return r
+}
+
+// ToOption returns a new RWTOptionsOption that sets the values from the passed in RWTOptions
+func (r *RWTOptions) ToOption() RWTOptionsOption {
+	return func(to *RWTOptions) {
+		to.IdempotencyKey = r.IdempotencyKey
+		to.IdempotencyRequestHash = r.IdempotencyRequestHash
+	}
+}
+
+// RWTOptionsWithOptions configures an existing RWTOptions with the passed in options set
+func RWTOptionsWithOptions(r *RWTOptions, opts ...RWTOptionsOption) *RWTOptions {
+	for _, o := range opts {
+		o(r)
+	}
+	return r
+}
+
+// WithIdempotencyKey returns an option that can set IdempotencyKey on a RWTOptions
+func WithIdempotencyKey(idempotencyKey string) RWTOptionsOption {
+	return func(r *RWTOptions) {
+		r.IdempotencyKey = idempotencyKey
+	}
+}
+
+// WithIdempotencyRequestHash returns an option that can set IdempotencyRequestHash on a RWTOptions
+func WithIdempotencyRequestHash(idempotencyRequestHash string) RWTOptionsOption {
+	return func(r *RWTOptions) {
+		r.IdempotencyRequestHash = idempotencyRequestHash
+	}
+}
@@ -11,13 +11,15 @@ import (
 )
 
 // NewPaginatedIterator creates an implementation of the datastore.Iterator
-// interface that internally paginates over datastore results.
+// interface that internally paginates over datastore results, starting after
+// the optional start cursor.
 func NewPaginatedIterator(
 	ctx context.Context,
 	reader datastore.Reader,
 	filter datastore.RelationshipsFilter,
 	pageSize uint64,
 	order options.SortOrder,
+	startCursor options.Cursor,
 ) (datastore.RelationshipIterator, error) {
 	pi := &paginatedIterator{
 		ctx:      ctx,
@@ -28,7 +30,7 @@ func NewPaginatedIterator(
 		delegate: common.NewSliceRelationshipIterator(nil, options.ByResource),
 	}
 
-	pi.startNewBatch(nil)
+	pi.startNewBatch(startCursor)
 
 	return pi, pi.err
 }
@@ -31,7 +31,7 @@ func TestDownstreamErrors(t *testing.T) {
 			On("QueryRelationships", options.Cursor(nil), defaultSortOrder, defaultPageSize).
 			Return(nilIter, defaultError)
 
-		_, err := NewPaginatedIterator(ctx, ds, datastore.RelationshipsFilter{}, defaultPageSize, defaultSortOrder)
+		_, err := NewPaginatedIterator(ctx, ds, datastore.RelationshipsFilter{}, defaultPageSize, defaultSortOrder, nil)
 		require.ErrorIs(err, defaultError)
 		require.True(ds.AssertExpectations(t))
 	})
@@ -49,7 +49,7 @@ func TestDownstreamErrors(t *testing.T) {
 			On("QueryRelationships", options.Cursor(nil), defaultSortOrder, defaultPageSize).
 			Return(iterMock, nil)
 
-		iter, err := NewPaginatedIterator(ctx, ds, datastore.RelationshipsFilter{}, defaultPageSize, defaultSortOrder)
+		iter, err := NewPaginatedIterator(ctx, ds, datastore.RelationshipsFilter{}, defaultPageSize, defaultSortOrder, nil)
 		require.NoError(err)
 		require.NotNil(iter)
 
@@ -77,7 +77,7 @@ func TestDownstreamErrors(t *testing.T) {
 			On("QueryRelationships", options.Cursor(nil), defaultSortOrder, defaultPageSize).
 			Return(iterMock, nil)
 
-		iter, err := NewPaginatedIterator(ctx, ds, datastore.RelationshipsFilter{}, defaultPageSize, defaultSortOrder)
+		iter, err := NewPaginatedIterator(ctx, ds, datastore.RelationshipsFilter{}, defaultPageSize, defaultSortOrder, nil)
 		require.NoError(err)
 		require.NotNil(iter)
 
@@ -102,18 +102,22 @@ func TestPaginatedIterator(t *testing.T) {
 		order              options.SortOrder
 		pageSize           uint64
 		totalRelationships uint64
+		startAfter         uint64
 	}{
-		{options.ByResource, 1, 0},
-		{options.ByResource, 1, 1},
-		{options.ByResource, 1, 10},
-		{options.ByResource, 10, 10},
-		{options.ByResource, 100, 10},
-		{options.ByResource, 10, 1000},
-		{options.ByResource, 9, 20},
+		{options.ByResource, 1, 0, 0},
+		{options.ByResource, 1, 1, 0},
+		{options.ByResource, 1, 10, 0},
+		{options.ByResource, 10, 10, 0},
+		{options.ByResource, 100, 10, 0},
+		{options.ByResource, 10, 1000, 0},
+		{options.ByResource, 9, 20, 0},
+		{options.ByResource, 9, 20, 5},
+		{options.ByResource, 10, 20, 10},
+		{options.ByResource, 10, 10, 10},
 	}
 
 	for _, tc := range testCases {
-		t.Run(fmt.Sprintf("%d/%d-%d", tc.pageSize, tc.totalRelationships, tc.order), func(t *testing.T) {
+		t.Run(fmt.Sprintf("%d/%d-%d-%d", tc.pageSize, tc.totalRelationships, tc.startAfter, tc.order), func(t *testing.T) {
 			require := require.New(t)
 
 			tpls := make([]*core.RelationTuple, 0, tc.totalRelationships)
@@ -132,12 +136,17 @@ func TestPaginatedIterator(t *testing.T) {
 				})
 			}
 
-			ds := generateMock(tpls, tc.pageSize, options.ByResource)
+			var startCursor options.Cursor
+			if tc.startAfter > 0 {
+				startCursor = tpls[tc.startAfter-1]
+			}
+
+			ds := generateMock(tpls[tc.startAfter:], tc.pageSize, options.ByResource, startCursor)
 
 			ctx := context.Background()
 			iter, err := NewPaginatedIterator(ctx, ds, datastore.RelationshipsFilter{
 				ResourceType: "unused",
-			}, tc.pageSize, options.ByResource)
+			}, tc.pageSize, options.ByResource, startCursor)
 			require.NoError(err)
 			defer iter.Close()
 
@@ -155,7 +164,7 @@ func TestPaginatedIterator(t *testing.T) {
 				require.NotNil(cursor)
 			}
 
-			require.Equal(tc.totalRelationships, count)
+			require.Equal(tc.totalRelationships-tc.startAfter, count)
 
 			require.NoError(iter.Err())
 
@@ -169,11 +178,11 @@ func TestPaginatedIterator(t *testing.T) {
 	}
 }
 
-func generateMock(tpls []*core.RelationTuple, pageSize uint64, order options.SortOrder) *mockedReader {
+func generateMock(tpls []*core.RelationTuple, pageSize uint64, order options.SortOrder, startCursor options.Cursor) *mockedReader {
 	mock := &mockedReader{}
 	tplsLen := uint64(len(tpls))
 
-	var last options.Cursor
+	last := startCursor
 	for i := uint64(0); i <= tplsLen; i += pageSize {
 		pastLastIndex := i + pageSize
 		if pastLastIndex > tplsLen {
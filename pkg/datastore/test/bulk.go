@@ -0,0 +1,98 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+// BulkUploadTest tests whether or not the requirements for bulk loading of
+// relationships hold for a particular datastore.
+func BulkUploadTest(t *testing.T, tester DatastoreTester) {
+	testCases := []int{0, 1, 10, 1001, 2500}
+
+	for _, numTuples := range testCases {
+		numTuples := numTuples
+		t.Run(strconv.Itoa(numTuples), func(t *testing.T) {
+			require := require.New(t)
+			ctx := context.Background()
+
+			rawDS, err := tester.New(0, veryLargeGCInterval, veryLargeGCWindow, 1)
+			require.NoError(err)
+
+			ds, _ := testfixtures.StandardDatastoreWithSchema(rawDS, require)
+			tRequire := testfixtures.TupleChecker{Require: require, DS: ds}
+
+			source := newReusingTupleSource(numTuples)
+			lastRevision, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+				loaded, err := rwt.BulkLoad(ctx, source)
+				require.NoError(err)
+				require.Equal(uint64(numTuples), loaded)
+				return err
+			})
+			require.NoError(err)
+
+			iter, err := ds.SnapshotReader(lastRevision).QueryRelationships(ctx, datastore.RelationshipsFilter{
+				ResourceType: testResourceNamespace,
+			})
+			require.NoError(err)
+			defer iter.Close()
+
+			tRequire.VerifyIteratorCount(iter, numTuples)
+		})
+	}
+}
+
+// BulkUploadAlreadyExistsTest tests that bulk loading a relationship which already exists
+// fails for a particular datastore.
+func BulkUploadAlreadyExistsTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	rawDS, err := tester.New(0, veryLargeGCInterval, veryLargeGCWindow, 1)
+	require.NoError(err)
+
+	ds, _ := testfixtures.StandardDatastoreWithSchema(rawDS, require)
+
+	_, err = common.WriteTuples(ctx, ds, core.RelationTupleUpdate_CREATE, makeTestTuple("resource0", "user0"))
+	require.NoError(err)
+
+	_, err = ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		_, err := rwt.BulkLoad(ctx, newReusingTupleSource(10))
+		return err
+	})
+	require.ErrorAs(err, &common.CreateRelationshipExistsError{})
+}
+
+// reusingTupleSource is a bulk load source which returns the same tuple, with updated
+// contents, on every call, to ensure that datastores do not hold onto returned tuples.
+type reusingTupleSource struct {
+	remaining int
+	current   *core.RelationTuple
+}
+
+func newReusingTupleSource(numTuples int) *reusingTupleSource {
+	return &reusingTupleSource{
+		remaining: numTuples,
+		current:   makeTestTuple("", ""),
+	}
+}
+
+func (rts *reusingTupleSource) Next(_ context.Context) (*core.RelationTuple, error) {
+	if rts.remaining == 0 {
+		return nil, nil
+	}
+
+	rts.remaining--
+	rts.current.ResourceAndRelation.ObjectId = fmt.Sprintf("resource%d", rts.remaining)
+	rts.current.Subject.ObjectId = fmt.Sprintf("user%d", rts.remaining)
+	return rts.current, nil
+}
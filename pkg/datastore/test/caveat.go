@@ -342,8 +342,9 @@ func expectTupleChange(t *testing.T, ds datastore.Datastore, revBeforeWrite data
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	chanRevisionChanges, chanErr := ds.Watch(ctx, revBeforeWrite)
+	chanRevisionChanges, chanErr := ds.Watch(ctx, revBeforeWrite, datastore.WatchOptions{})
 	require.Zero(t, len(chanErr))
+	chanRevisionChanges = withoutCheckpoints(chanRevisionChanges)
 
 	changeWait := time.NewTimer(waitForChangesTimeout)
 	select {
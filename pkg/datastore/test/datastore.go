@@ -46,20 +46,28 @@ func AllExceptWatch(t *testing.T, tester DatastoreTester) {
 	t.Run("TestSimple", func(t *testing.T) { SimpleTest(t, tester) })
 	t.Run("TestObjectIDs", func(t *testing.T) { ObjectIDsTest(t, tester) })
 	t.Run("TestDeleteRelationships", func(t *testing.T) { DeleteRelationshipsTest(t, tester) })
+	t.Run("TestDeleteRelationshipsWithLimit", func(t *testing.T) { DeleteRelationshipsWithLimitTest(t, tester) })
+	t.Run("TestRelationshipsChangedSince", func(t *testing.T) { RelationshipsChangedSinceTest(t, tester) })
+	t.Run("TestIdempotencyKey", func(t *testing.T) { IdempotencyKeyTest(t, tester) })
 	t.Run("TestInvalidReads", func(t *testing.T) { InvalidReadsTest(t, tester) })
 	t.Run("TestDeleteNonExistant", func(t *testing.T) { DeleteNotExistantTest(t, tester) })
 	t.Run("TestDeleteAlreadyDeleted", func(t *testing.T) { DeleteAlreadyDeletedTest(t, tester) })
 	t.Run("TestWriteDeleteWrite", func(t *testing.T) { WriteDeleteWriteTest(t, tester) })
 	t.Run("TestCreateAlreadyExisting", func(t *testing.T) { CreateAlreadyExistingTest(t, tester) })
 	t.Run("TestTouchAlreadyExisting", func(t *testing.T) { TouchAlreadyExistingTest(t, tester) })
+	t.Run("TestRelationshipExpiration", func(t *testing.T) { RelationshipExpirationTest(t, tester) })
+	t.Run("TestRelationshipExpirationAtRevision", func(t *testing.T) { RelationshipExpirationAtRevisionTest(t, tester) })
 	t.Run("TestUsersets", func(t *testing.T) { UsersetsTest(t, tester) })
 	t.Run("TestMultipleReadsInRWT", func(t *testing.T) { MultipleReadsInRWTTest(t, tester) })
 	t.Run("TestConcurrentWriteSerialization", func(t *testing.T) { ConcurrentWriteSerializationTest(t, tester) })
+	t.Run("TestBulkUpload", func(t *testing.T) { BulkUploadTest(t, tester) })
+	t.Run("TestBulkUploadAlreadyExists", func(t *testing.T) { BulkUploadAlreadyExistsTest(t, tester) })
 
 	t.Run("TestOrdering", func(t *testing.T) { OrderingTest(t, tester) })
 	t.Run("TestLimit", func(t *testing.T) { LimitTest(t, tester) })
 	t.Run("TestOrderedLimit", func(t *testing.T) { OrderedLimitTest(t, tester) })
 	t.Run("TestResume", func(t *testing.T) { ResumeTest(t, tester) })
+	t.Run("TestReverseQueryCursor", func(t *testing.T) { ReverseQueryCursorTest(t, tester) })
 	t.Run("TestCursorErrors", func(t *testing.T) { CursorErrorsTest(t, tester) })
 
 	t.Run("TestRevisionQuantization", func(t *testing.T) { RevisionQuantizationTest(t, tester) })
@@ -81,6 +89,9 @@ func All(t *testing.T, tester DatastoreTester) {
 
 	t.Run("TestWatch", func(t *testing.T) { WatchTest(t, tester) })
 	t.Run("TestWatchCancel", func(t *testing.T) { WatchCancelTest(t, tester) })
+	t.Run("TestWatchSchema", func(t *testing.T) { WatchSchemaTest(t, tester) })
+	t.Run("TestWatchCheckpoint", func(t *testing.T) { WatchCheckpointTest(t, tester) })
+	t.Run("TestWatchWithFilters", func(t *testing.T) { WatchWithFiltersTest(t, tester) })
 	t.Run("TestCaveatedRelationshipWatch", func(t *testing.T) { CaveatedRelationshipWatchTest(t, tester) })
 }
 
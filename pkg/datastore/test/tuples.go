@@ -307,7 +307,7 @@ func SimpleTest(t *testing.T, tester DatastoreTester) {
 
 			// Delete with DeleteRelationship
 			deletedAt, err = ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
-				err := rwt.DeleteRelationships(ctx, &v1.RelationshipFilter{
+				_, _, err := rwt.DeleteRelationships(ctx, &v1.RelationshipFilter{
 					ResourceType: testResourceNamespace,
 				})
 				require.NoError(err)
@@ -470,8 +470,10 @@ func DeleteRelationshipsTest(t *testing.T, tester DatastoreTester) {
 			require.NoError(err)
 
 			deletedAt, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
-				err := rwt.DeleteRelationships(ctx, tt.filter)
+				deleted, limitReached, err := rwt.DeleteRelationships(ctx, tt.filter)
 				require.NoError(err)
+				require.Equal(uint64(len(tt.expectedNonExistingTuples)), deleted)
+				require.False(limitReached)
 				return err
 			})
 			require.NoError(err)
@@ -487,6 +489,208 @@ func DeleteRelationshipsTest(t *testing.T, tester DatastoreTester) {
 	}
 }
 
+// DeleteRelationshipsWithLimitTest tests whether or not the requirements for deleting
+// relationships with a limit hold for a particular datastore.
+func DeleteRelationshipsWithLimitTest(t *testing.T, tester DatastoreTester) {
+	var testTuples []*core.RelationTuple
+	for i := 0; i < 10; i++ {
+		newTuple := makeTestTuple(fmt.Sprintf("resource%d", i), fmt.Sprintf("user%d", i%2))
+		testTuples = append(testTuples, newTuple)
+	}
+
+	filter := &v1.RelationshipFilter{
+		ResourceType:          testResourceNamespace,
+		OptionalSubjectFilter: &v1.SubjectFilter{SubjectType: testUserNamespace, OptionalSubjectId: "user0"},
+	}
+
+	table := []struct {
+		name                 string
+		limit                uint64
+		expectedDeleted      uint64
+		expectedLimitReached bool
+	}{
+		{"under limit", 10, 5, false},
+		{"at limit", 5, 5, false},
+		{"over limit", 3, 3, true},
+		{"limit of one", 1, 1, true},
+	}
+
+	for _, tt := range table {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+			ctx := context.Background()
+
+			ds, err := tester.New(0, veryLargeGCInterval, veryLargeGCWindow, 1)
+			require.NoError(err)
+			defer ds.Close()
+
+			setupDatastore(ds, require)
+
+			_, err = common.WriteTuples(ctx, ds, core.RelationTupleUpdate_CREATE, testTuples...)
+			require.NoError(err)
+
+			deletedAt, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+				deleted, limitReached, err := rwt.DeleteRelationships(ctx, filter, options.WithDeleteLimit(&tt.limit))
+				require.NoError(err)
+				require.Equal(tt.expectedDeleted, deleted)
+				require.Equal(tt.expectedLimitReached, limitReached)
+				return err
+			})
+			require.NoError(err)
+
+			iter, err := ds.SnapshotReader(deletedAt).QueryRelationships(ctx, datastore.RelationshipsFilterFromPublicFilter(filter))
+			require.NoError(err)
+			defer iter.Close()
+
+			remaining := 0
+			for tpl := iter.Next(); tpl != nil; tpl = iter.Next() {
+				remaining++
+			}
+			require.NoError(iter.Err())
+			require.Equal(5-int(tt.expectedDeleted), remaining)
+
+			// Relationships not matching the filter are untouched.
+			iter, err = ds.SnapshotReader(deletedAt).QueryRelationships(ctx, datastore.RelationshipsFilter{
+				ResourceType: testResourceNamespace,
+			})
+			require.NoError(err)
+			defer iter.Close()
+
+			total := 0
+			for tpl := iter.Next(); tpl != nil; tpl = iter.Next() {
+				total++
+			}
+			require.NoError(iter.Err())
+			require.Equal(len(testTuples)-int(tt.expectedDeleted), total)
+		})
+	}
+}
+
+// RelationshipsChangedSinceTest tests whether or not the requirements for detecting
+// relationships changed since a revision hold for a particular datastore.
+func RelationshipsChangedSinceTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	ds, err := tester.New(0, veryLargeGCInterval, veryLargeGCWindow, 1)
+	require.NoError(err)
+	defer ds.Close()
+
+	setupDatastore(ds, require)
+
+	changedSince := func(filter *v1.RelationshipFilter, revision datastore.Revision) bool {
+		var changed bool
+		_, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+			var err error
+			changed, err = rwt.RelationshipsChangedSince(ctx, filter, revision)
+			return err
+		})
+		require.NoError(err)
+		return changed
+	}
+
+	resourceFilter := func(resourceID string) *v1.RelationshipFilter {
+		return &v1.RelationshipFilter{ResourceType: testResourceNamespace, OptionalResourceId: resourceID}
+	}
+	userFilter := &v1.RelationshipFilter{
+		ResourceType:          testResourceNamespace,
+		OptionalSubjectFilter: &v1.SubjectFilter{SubjectType: testUserNamespace, OptionalSubjectId: "user0"},
+	}
+
+	first := makeTestTuple("resource0", "user0")
+	second := makeTestTuple("resource1", "user1")
+	writtenAt, err := common.WriteTuples(ctx, ds, core.RelationTupleUpdate_CREATE, first, second)
+	require.NoError(err)
+
+	require.False(changedSince(resourceFilter("resource0"), writtenAt))
+	require.False(changedSince(userFilter, writtenAt))
+	require.False(changedSince(resourceFilter(""), writtenAt))
+
+	// Creating a relationship changes only those filters which match it.
+	createdAt, err := common.WriteTuples(ctx, ds, core.RelationTupleUpdate_CREATE, makeTestTuple("resource2", "user0"))
+	require.NoError(err)
+
+	require.True(changedSince(userFilter, writtenAt))
+	require.True(changedSince(resourceFilter(""), writtenAt))
+	require.False(changedSince(resourceFilter("resource0"), writtenAt))
+	require.False(changedSince(resourceFilter("resource1"), writtenAt))
+	require.False(changedSince(userFilter, createdAt))
+
+	// Deleting a relationship changes the filters which matched it.
+	deletedAt, err := common.WriteTuples(ctx, ds, core.RelationTupleUpdate_DELETE, second)
+	require.NoError(err)
+
+	require.True(changedSince(resourceFilter("resource1"), writtenAt))
+	require.True(changedSince(resourceFilter("resource1"), createdAt))
+	require.False(changedSince(resourceFilter("resource1"), deletedAt))
+	require.False(changedSince(resourceFilter("resource0"), createdAt))
+
+	// Touching a relationship changes the filters which match it.
+	_, err = common.WriteTuples(ctx, ds, core.RelationTupleUpdate_TOUCH, first)
+	require.NoError(err)
+
+	require.True(changedSince(resourceFilter("resource0"), deletedAt))
+	require.False(changedSince(resourceFilter("resource1"), deletedAt))
+}
+
+// IdempotencyKeyTest tests whether a read-write transaction given an idempotency key with which a
+// transaction has already committed is skipped, and one given such a key with a different request
+// hash fails, for a particular datastore.
+func IdempotencyKeyTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	ds, err := tester.New(0, veryLargeGCInterval, veryLargeGCWindow, 1)
+	require.NoError(err)
+	defer ds.Close()
+
+	setupDatastore(ds, require)
+	tRequire := testfixtures.TupleChecker{Require: require, DS: ds}
+
+	writeWithKey := func(key string, requestHash string, tpl *core.RelationTuple) (datastore.Revision, error) {
+		return ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+			return rwt.WriteRelationships(ctx, []*core.RelationTupleUpdate{tuple.Create(tpl)})
+		}, options.WithIdempotencyKey(key), options.WithIdempotencyRequestHash(requestHash))
+	}
+
+	first := makeTestTuple("resource0", "user0")
+	committedAt, err := writeWithKey("first-key", "first-hash", first)
+	require.NoError(err)
+
+	// A retry with the same key is not applied, and returns the original revision.
+	retried := makeTestTuple("resource1", "user1")
+	_, err = writeWithKey("first-key", "first-hash", retried)
+
+	var committedErr datastore.ErrIdempotencyKeyAlreadyCommitted
+	require.ErrorAs(err, &committedErr)
+	require.Equal("first-key", committedErr.IdempotencyKey())
+	require.True(committedAt.Equal(committedErr.CommittedRevision()))
+
+	// A different request with the same key is not applied, and fails.
+	_, err = writeWithKey("first-key", "other-hash", retried)
+
+	var reusedErr datastore.ErrIdempotencyKeyReused
+	require.ErrorAs(err, &reusedErr)
+	require.Equal("first-key", reusedErr.IdempotencyKey())
+
+	head, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+	tRequire.TupleExists(ctx, first, head)
+	tRequire.NoTupleExists(ctx, retried, head)
+
+	// A transaction which fails does not record its key.
+	_, err = ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		return errors.New("failed")
+	}, options.WithIdempotencyKey("second-key"))
+	require.Error(err)
+
+	secondAt, err := writeWithKey("second-key", "second-hash", retried)
+	require.NoError(err)
+	require.True(secondAt.GreaterThan(committedAt))
+	tRequire.TupleExists(ctx, retried, secondAt)
+}
+
 // InvalidReadsTest tests whether or not the requirements for reading via
 // invalid revisions hold for a particular datastore.
 func InvalidReadsTest(t *testing.T, tester DatastoreTester) {
@@ -651,6 +855,113 @@ func TouchAlreadyExistingTest(t *testing.T, tester DatastoreTester) {
 	require.NoError(err)
 }
 
+// RelationshipExpirationTest tests that expired relationships are not returned by reads
+// and can be replaced by new relationships.
+func RelationshipExpirationTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+
+	ds, err := tester.New(0, veryLargeGCInterval, veryLargeGCWindow, 1)
+	require.NoError(err)
+	defer ds.Close()
+
+	setupDatastore(ds, require)
+
+	ctx := context.Background()
+	tRequire := testfixtures.TupleChecker{Require: require, DS: ds}
+
+	expiresAt := time.Now().Add(time.Second).UTC().Truncate(time.Millisecond)
+	expiring := tuple.WithExpiration(makeTestTuple("foo", "tom"), expiresAt)
+	expired := tuple.WithExpiration(makeTestTuple("foo", "fred"), expiresAt.Add(-1*time.Hour))
+	permanent := makeTestTuple("foo", "sarah")
+
+	writtenAt, err := common.WriteTuples(ctx, ds, core.RelationTupleUpdate_CREATE, expiring, expired, permanent)
+	require.NoError(err)
+
+	iter, err := ds.SnapshotReader(writtenAt).QueryRelationships(ctx, datastore.RelationshipsFilter{
+		ResourceType: testResourceNamespace,
+	})
+	require.NoError(err)
+	tRequire.VerifyIteratorResults(iter, expiring, permanent)
+
+	iter, err = ds.SnapshotReader(writtenAt).ReverseQueryRelationships(ctx, datastore.SubjectsFilter{
+		SubjectType: testUserNamespace,
+	})
+	require.NoError(err)
+	tRequire.VerifyIteratorResults(iter, expiring, permanent)
+
+	// Relationships can be limited to those with an expiration.
+	iter, err = ds.SnapshotReader(writtenAt).QueryRelationships(ctx, datastore.RelationshipsFilter{
+		ResourceType:       testResourceNamespace,
+		OnlyWithExpiration: true,
+	})
+	require.NoError(err)
+	tRequire.VerifyIteratorResults(iter, expiring)
+
+	time.Sleep(time.Until(expiresAt))
+
+	// Creating relationships in place of expired ones must succeed.
+	replacement := makeTestTuple("foo", "tom")
+	recreated := makeTestTuple("foo", "fred")
+	replacedAt, err := common.WriteTuples(ctx, ds, core.RelationTupleUpdate_CREATE, replacement, recreated)
+	require.NoError(err)
+
+	iter, err = ds.SnapshotReader(replacedAt).QueryRelationships(ctx, datastore.RelationshipsFilter{
+		ResourceType: testResourceNamespace,
+	})
+	require.NoError(err)
+	tRequire.VerifyIteratorResults(iter, replacement, recreated, permanent)
+
+	// Touching a relationship can add an expiration, which hides it once passed.
+	touchedAt, err := common.WriteTuples(ctx, ds, core.RelationTupleUpdate_TOUCH,
+		tuple.WithExpiration(recreated, expiresAt.Add(-1*time.Minute)))
+	require.NoError(err)
+	tRequire.NoTupleExists(ctx, recreated, touchedAt)
+	tRequire.TupleExists(ctx, replacement, touchedAt)
+}
+
+// RelationshipExpirationAtRevisionTest tests that the expiration of relationships is evaluated at
+// the time of the revision read, so that reads of a revision are repeatable.
+func RelationshipExpirationAtRevisionTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+
+	ds, err := tester.New(0, veryLargeGCInterval, veryLargeGCWindow, 1)
+	require.NoError(err)
+	defer ds.Close()
+
+	setupDatastore(ds, require)
+
+	ctx := context.Background()
+	tRequire := testfixtures.TupleChecker{Require: require, DS: ds}
+
+	expiresAt := time.Now().Add(time.Second).UTC().Truncate(time.Millisecond)
+	expiring := tuple.WithExpiration(makeTestTuple("foo", "tom"), expiresAt)
+	permanent := makeTestTuple("foo", "sarah")
+
+	writtenAt, err := common.WriteTuples(ctx, ds, core.RelationTupleUpdate_CREATE, expiring, permanent)
+	require.NoError(err)
+
+	readFoo := func(revision datastore.Revision) datastore.RelationshipIterator {
+		iter, err := ds.SnapshotReader(revision).QueryRelationships(ctx, datastore.RelationshipsFilter{
+			ResourceType:        testResourceNamespace,
+			OptionalResourceIds: []string{"foo"},
+		})
+		require.NoError(err)
+		return iter
+	}
+
+	tRequire.VerifyIteratorResults(readFoo(writtenAt), expiring, permanent)
+
+	time.Sleep(time.Until(expiresAt))
+
+	// The revision at which the relationship had not expired reads the same once it has.
+	tRequire.VerifyIteratorResults(readFoo(writtenAt), expiring, permanent)
+
+	// Revisions after the expiration do not return the relationship.
+	laterAt, err := common.WriteTuples(ctx, ds, core.RelationTupleUpdate_CREATE, makeTestTuple("bar", "fred"))
+	require.NoError(err)
+	tRequire.VerifyIteratorResults(readFoo(laterAt), permanent)
+}
+
 // UsersetsTest tests whether or not the requirements for reading usersets hold
 // for a particular datastore.
 func UsersetsTest(t *testing.T, tester DatastoreTester) {
@@ -59,8 +59,9 @@ func WatchTest(t *testing.T, tester DatastoreTester) {
 			lowestRevision, err := ds.HeadRevision(ctx)
 			require.NoError(err)
 
-			changes, errchan := ds.Watch(ctx, lowestRevision)
+			changes, errchan := ds.Watch(ctx, lowestRevision, datastore.WatchOptions{})
 			require.Zero(len(errchan))
+			changes = withoutCheckpoints(changes)
 
 			var testUpdates [][]*core.RelationTupleUpdate
 			var bulkDeletes []*core.RelationTupleUpdate
@@ -91,7 +92,7 @@ func WatchTest(t *testing.T, tester DatastoreTester) {
 			testUpdates = append(testUpdates, batch, []*core.RelationTupleUpdate{deleteUpdate})
 
 			_, err = ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
-				err := rwt.DeleteRelationships(ctx, &v1.RelationshipFilter{
+				_, _, err := rwt.DeleteRelationships(ctx, &v1.RelationshipFilter{
 					ResourceType:     testResourceNamespace,
 					OptionalRelation: testReaderRelation,
 					OptionalSubjectFilter: &v1.SubjectFilter{
@@ -111,8 +112,8 @@ func WatchTest(t *testing.T, tester DatastoreTester) {
 			verifyUpdates(require, testUpdates, changes, errchan, tc.expectFallBehind)
 
 			// Test the catch-up case
-			changes, errchan = ds.Watch(ctx, lowestRevision)
-			verifyUpdates(require, testUpdates, changes, errchan, tc.expectFallBehind)
+			changes, errchan = ds.Watch(ctx, lowestRevision, datastore.WatchOptions{})
+			verifyUpdates(require, testUpdates, withoutCheckpoints(changes), errchan, tc.expectFallBehind)
 		})
 	}
 }
@@ -167,6 +168,163 @@ func setOfChanges(changes []*core.RelationTupleUpdate) *strset.Set {
 	return changeSet
 }
 
+// WatchSchemaTest tests whether or not changes to namespaces and caveats are
+// emitted by the watch for a particular datastore.
+func WatchSchemaTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+
+	ds, err := tester.New(0, veryLargeGCInterval, veryLargeGCWindow, 16)
+	require.NoError(err)
+
+	startWatchRevision := setupDatastore(ds, require)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes, errchan := ds.Watch(ctx, startWatchRevision, datastore.WatchOptions{})
+	require.Zero(len(errchan))
+	changes = withoutCheckpoints(changes)
+
+	coreCaveat := createCoreCaveat(t)
+
+	steps := []struct {
+		name     string
+		write    func(rwt datastore.ReadWriteTransaction) error
+		expected datastore.RevisionChanges
+	}{
+		{
+			"write namespace",
+			func(rwt datastore.ReadWriteTransaction) error {
+				return rwt.WriteNamespaces(ctx, testNamespace)
+			},
+			datastore.RevisionChanges{ChangedDefinitions: []datastore.SchemaDefinition{testNamespace}},
+		},
+		{
+			"write caveat",
+			func(rwt datastore.ReadWriteTransaction) error {
+				return rwt.WriteCaveats(ctx, []*core.CaveatDefinition{coreCaveat})
+			},
+			datastore.RevisionChanges{ChangedDefinitions: []datastore.SchemaDefinition{coreCaveat}},
+		},
+		{
+			"update namespace",
+			func(rwt datastore.ReadWriteTransaction) error {
+				return rwt.WriteNamespaces(ctx, updatedNamespace)
+			},
+			datastore.RevisionChanges{ChangedDefinitions: []datastore.SchemaDefinition{updatedNamespace}},
+		},
+		{
+			"delete namespace",
+			func(rwt datastore.ReadWriteTransaction) error {
+				return rwt.DeleteNamespaces(ctx, testNamespace.Name)
+			},
+			datastore.RevisionChanges{DeletedNamespaces: []string{testNamespace.Name}},
+		},
+		{
+			"delete caveat",
+			func(rwt datastore.ReadWriteTransaction) error {
+				return rwt.DeleteCaveats(ctx, []string{coreCaveat.Name})
+			},
+			datastore.RevisionChanges{DeletedCaveats: []string{coreCaveat.Name}},
+		},
+	}
+
+	for _, step := range steps {
+		writtenAt, err := ds.ReadWriteTx(ctx, step.write)
+		require.NoError(err, step.name)
+
+		changeWait := time.NewTimer(waitForChangesTimeout)
+		select {
+		case change, ok := <-changes:
+			require.True(ok, "unexpected close of changes channel at step %s", step.name)
+			require.True(change.Revision.Equal(writtenAt), "unexpected revision at step %s", step.name)
+			require.Empty(change.Changes, step.name)
+			require.Empty(cmp.Diff(step.expected.ChangedDefinitions, change.ChangedDefinitions, protocmp.Transform()), step.name)
+			require.Equal(step.expected.DeletedNamespaces, change.DeletedNamespaces, step.name)
+			require.Equal(step.expected.DeletedCaveats, change.DeletedCaveats, step.name)
+		case err := <-errchan:
+			require.Failf("unexpected watch error", "at step %s: %s", step.name, err)
+		case <-changeWait.C:
+			require.Fail("Timed out", "waiting for schema change at step %s", step.name)
+		}
+	}
+}
+
+// WatchWithFiltersTest tests whether or not watches limited by relationships filters
+// only report changes to matching relationships for a particular datastore.
+func WatchWithFiltersTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+
+	ds, err := tester.New(0, veryLargeGCInterval, veryLargeGCWindow, 16)
+	require.NoError(err)
+
+	startWatchRevision := setupDatastore(ds, require)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes, errchan := ds.Watch(ctx, startWatchRevision, datastore.WatchOptions{
+		OptionalRelationshipsFilters: []datastore.RelationshipsFilter{
+			{
+				ResourceType:        testResourceNamespace,
+				OptionalResourceIds: []string{"watched"},
+			},
+			{
+				ResourceType: testResourceNamespace,
+				OptionalSubjectsSelectors: []datastore.SubjectsSelector{
+					{
+						OptionalSubjectType: testUserNamespace,
+						OptionalSubjectIds:  []string{"watched_user"},
+					},
+				},
+			},
+		},
+	})
+	require.Zero(len(errchan))
+	changes = withoutCheckpoints(changes)
+
+	writes := []struct {
+		tpl     *core.RelationTuple
+		matches bool
+	}{
+		{makeTestTuple("watched", "user1"), true},
+		{makeTestTuple("unwatched", "user1"), false},
+		{makeTestTuple("unwatched", "watched_user"), true},
+		{makeTestTuple("unwatched", "user2"), false},
+		{makeTestTuple("watched", "user2"), true},
+	}
+
+	var expected []*core.RelationTuple
+	for _, write := range writes {
+		_, err := common.WriteTuples(ctx, ds, core.RelationTupleUpdate_CREATE, write.tpl)
+		require.NoError(err)
+
+		if write.matches {
+			expected = append(expected, write.tpl)
+		}
+	}
+
+	// Changes are reported in order, so any unmatched change would be received before
+	// the last expected change.
+	for _, tpl := range expected {
+		changeWait := time.NewTimer(waitForChangesTimeout)
+		select {
+		case change, ok := <-changes:
+			require.True(ok, "unexpected close of changes channel")
+			foundDiff := cmp.Diff(
+				[]*core.RelationTupleUpdate{tuple.Touch(tpl)},
+				change.Changes,
+				protocmp.Transform(),
+			)
+			require.Empty(foundDiff)
+		case err := <-errchan:
+			require.Failf("unexpected watch error", "%s", err)
+		case <-changeWait.C:
+			require.Fail("Timed out", "waiting for change to %s", tuple.MustString(tpl))
+		}
+	}
+}
+
 // WatchCancelTest tests whether or not the requirements for cancelling watches
 // hold for a particular datastore.
 func WatchCancelTest(t *testing.T, tester DatastoreTester) {
@@ -178,8 +336,9 @@ func WatchCancelTest(t *testing.T, tester DatastoreTester) {
 	startWatchRevision := setupDatastore(ds, require)
 
 	ctx, cancel := context.WithCancel(context.Background())
-	changes, errchan := ds.Watch(ctx, startWatchRevision)
+	changes, errchan := ds.Watch(ctx, startWatchRevision, datastore.WatchOptions{})
 	require.Zero(len(errchan))
+	changes = withoutCheckpoints(changes)
 
 	_, err = common.WriteTuples(ctx, ds, core.RelationTupleUpdate_CREATE, makeTestTuple("test", "test"))
 	require.NoError(err)
@@ -215,3 +374,65 @@ func WatchCancelTest(t *testing.T, tester DatastoreTester) {
 		}
 	}
 }
+
+// WatchCheckpointTest tests whether or not checkpoints are emitted by the watch
+// for a particular datastore, once all changes through a revision have been reported.
+func WatchCheckpointTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+
+	ds, err := tester.New(0, veryLargeGCInterval, veryLargeGCWindow, 16)
+	require.NoError(err)
+
+	startWatchRevision := setupDatastore(ds, require)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes, errchan := ds.Watch(ctx, startWatchRevision, datastore.WatchOptions{})
+	require.Zero(len(errchan))
+
+	writtenAt, err := common.WriteTuples(ctx, ds, core.RelationTupleUpdate_CREATE, makeTestTuple("checkpointed", "test_user"))
+	require.NoError(err)
+
+	var sawChange bool
+	for {
+		changeWait := time.NewTimer(waitForChangesTimeout)
+		select {
+		case change, ok := <-changes:
+			require.True(ok, "unexpected close of changes channel")
+			if !change.IsCheckpoint {
+				require.True(change.Revision.Equal(writtenAt))
+				sawChange = true
+				continue
+			}
+
+			require.Empty(change.Changes)
+			if change.Revision.LessThan(writtenAt) {
+				require.False(sawChange, "checkpoint regressed behind an emitted change")
+				continue
+			}
+
+			require.True(sawChange, "checkpoint emitted before the change it covers")
+			return
+		case err := <-errchan:
+			require.Failf("unexpected watch error", "%s", err)
+		case <-changeWait.C:
+			require.Fail("Timed out waiting for checkpoint")
+		}
+	}
+}
+
+// withoutCheckpoints returns a channel which receives the changes from the watch, skipping
+// any checkpoints.
+func withoutCheckpoints(changes <-chan *datastore.RevisionChanges) <-chan *datastore.RevisionChanges {
+	filtered := make(chan *datastore.RevisionChanges)
+	go func() {
+		defer close(filtered)
+		for change := range changes {
+			if !change.IsCheckpoint {
+				filtered <- change
+			}
+		}
+	}()
+	return filtered
+}
@@ -108,3 +108,52 @@ func SetRelationKind(relation *core.Relation, kind iv1.RelationMetadata_Relation
 	metadata.MetadataMessage = append(metadata.MetadataMessage, encoded)
 	return nil
 }
+
+// IsMaterialized returns whether the relation is a permission marked as materialized.
+func IsMaterialized(relation *core.Relation) bool {
+	metadata := relation.Metadata
+	if metadata == nil {
+		return false
+	}
+
+	for _, msg := range metadata.MetadataMessage {
+		var rm iv1.RelationMetadata
+		if err := msg.UnmarshalTo(&rm); err == nil {
+			return rm.Materialized
+		}
+	}
+
+	return false
+}
+
+// SetMaterialized marks the relation as materialized.
+func SetMaterialized(relation *core.Relation) error {
+	metadata := relation.Metadata
+	if metadata == nil {
+		metadata = &core.Metadata{}
+		relation.Metadata = metadata
+	}
+
+	for index, msg := range metadata.MetadataMessage {
+		var rm iv1.RelationMetadata
+		if err := msg.UnmarshalTo(&rm); err == nil {
+			rm.Materialized = true
+
+			encoded, err := anypb.New(&rm)
+			if err != nil {
+				return err
+			}
+
+			metadata.MetadataMessage[index] = encoded
+			return nil
+		}
+	}
+
+	encoded, err := anypb.New(&iv1.RelationMetadata{Materialized: true})
+	if err != nil {
+		return err
+	}
+
+	metadata.MetadataMessage = append(metadata.MetadataMessage, encoded)
+	return nil
+}
@@ -61,3 +61,20 @@ func TestMetadata(t *testing.T) {
 
 	require.Equal(iv1.RelationMetadata_PERMISSION, GetRelationKind(ns.Relation[0]))
 }
+
+func TestMaterialized(t *testing.T) {
+	require := require.New(t)
+
+	permission := MustRelation("view", Union(ComputedUserset("viewer")))
+	require.False(IsMaterialized(permission))
+
+	require.NoError(SetMaterialized(permission))
+	require.True(IsMaterialized(permission))
+	require.Equal(iv1.RelationMetadata_PERMISSION, GetRelationKind(permission))
+
+	relation := &core.Relation{Name: "viewer"}
+	require.False(IsMaterialized(relation))
+
+	require.NoError(SetMaterialized(relation))
+	require.True(IsMaterialized(relation))
+}
@@ -361,6 +361,12 @@ func translatePermission(tctx translationContext, permissionNode *dslNode) (*cor
 		return nil, err
 	}
 
+	if permissionNode.Has(dslshape.NodePermissionPredicateMaterialized) {
+		if err := namespace.SetMaterialized(permission); err != nil {
+			return nil, permissionNode.Errorf("error in permission %s: %w", permissionName, err)
+		}
+	}
+
 	err = permission.Validate()
 	if err != nil {
 		return nil, permissionNode.Errorf("error in permission %s: %w", permissionName, err)
@@ -169,6 +169,9 @@ const (
 	// The expression to compute the permission.
 	NodePermissionPredicateComputeExpression = "compute-expression"
 
+	// Whether the permission is materialized.
+	NodePermissionPredicateMaterialized = "materialized"
+
 	//
 	// NodeTypeIdentifer
 	//
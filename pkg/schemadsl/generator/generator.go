@@ -174,7 +174,9 @@ func (sg *sourceGenerator) emitRelation(relation *core.Relation) error {
 	isPermission := relation.UsersetRewrite != nil && !hasThis
 
 	sg.emitComments(relation.Metadata)
-	if isPermission {
+	if isPermission && namespace.IsMaterialized(relation) {
+		sg.append("materialized permission ")
+	} else if isPermission {
 		sg.append("permission ")
 	} else {
 		sg.append("relation ")
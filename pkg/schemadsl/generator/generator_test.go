@@ -351,6 +351,19 @@ definition foos/document {
 	permission read = reader + writer + another
 	permission write = writer
 	permission minus = (rela - relb) - relc
+}`,
+		},
+		{
+			"materialized permission",
+			`definition foos/test {
+				relation viewer: foos/user
+				materialized   permission view = viewer
+				permission edit = viewer
+			}`,
+			`definition foos/test {
+	relation viewer: foos/user
+	materialized permission view = viewer
+	permission edit = viewer
 }`,
 		},
 	}
@@ -259,11 +259,12 @@ func (p *sourceParser) consumeDefinition() AstNode {
 
 		// relation ...
 		// permission ...
+		// materialized permission ...
 		switch {
 		case p.isKeyword("relation"):
 			defNode.Connect(dslshape.NodePredicateChild, p.consumeRelation())
 
-		case p.isKeyword("permission"):
+		case p.isKeyword("permission") || p.isContextualKeyword("materialized"):
 			defNode.Connect(dslshape.NodePredicateChild, p.consumePermission())
 		}
 
@@ -410,14 +411,22 @@ func (p *sourceParser) consumeTypePath() (string, bool) {
 	return typeNameOrNamespace + "/" + typeName, true
 }
 
-// consumePermission consumes a permission.
+// consumePermission consumes a permission, optionally marked as materialized.
 // ```permission foo = bar + baz```
+// ```materialized permission foo = bar + baz```
 func (p *sourceParser) consumePermission() AstNode {
 	permNode := p.startNode(dslshape.NodeTypePermission)
 	defer p.mustFinishNode()
 
+	// materialized ...
+	if p.tryConsumeContextualKeyword("materialized") {
+		permNode.MustDecorate(dslshape.NodePermissionPredicateMaterialized, "true")
+	}
+
 	// permission ...
-	p.consumeKeyword("permission")
+	if !p.consumeKeyword("permission") {
+		return permNode
+	}
 	permissionName, ok := p.consumeIdentifier()
 	if !ok {
 		return permNode
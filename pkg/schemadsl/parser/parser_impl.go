@@ -168,6 +168,23 @@ func (p *sourceParser) isKeyword(keyword string) bool {
 	return p.isToken(lexer.TokenTypeKeyword) && p.currentToken.Value == keyword
 }
 
+// isContextualKeyword returns true if the current token is an identifier matching the given
+// contextual keyword. Contextual keywords are not reserved, and so remain valid identifiers
+// everywhere other than where they are expected.
+func (p *sourceParser) isContextualKeyword(keyword string) bool {
+	return p.isToken(lexer.TokenTypeIdentifier) && p.currentToken.Value == keyword
+}
+
+// tryConsumeContextualKeyword attempts to consume an expected contextual keyword token.
+func (p *sourceParser) tryConsumeContextualKeyword(keyword string) bool {
+	if !p.isContextualKeyword(keyword) {
+		return false
+	}
+
+	p.consumeToken()
+	return true
+}
+
 // emitErrorf creates a new error node and attachs it as a child of the current
 // node.
 func (p *sourceParser) emitErrorf(format string, args ...interface{}) {
@@ -113,6 +113,8 @@ func TestParser(t *testing.T) {
 		{"empty caveat test", "emptycaveat"},
 		{"unclosed caveat test", "unclosedcaveat"},
 		{"invalid caveat expr test", "invalidcaveatexpr"},
+		{"materialized test", "materialized"},
+		{"materialized identifiers test", "materializedidentifiers"},
 	}
 
 	for _, test := range parserTests {
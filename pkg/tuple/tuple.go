@@ -4,11 +4,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"time"
 
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
 	"github.com/jzelinskie/stringz"
 	"golang.org/x/exp/maps"
 	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 )
@@ -46,6 +48,8 @@ var subjectExpr = fmt.Sprintf(
 
 var caveatExpr = fmt.Sprintf(`\[(?P<caveatName>(%s))(:(?P<caveatContext>(\{(.+)\})))?\]`, caveatNameExpr)
 
+var expirationExpr = `\[expiration:(?P<expirationTime>([0-9]{4}-[0-9]{2}-[0-9]{2}T[0-9:.]+(Z|[+\-][0-9]{2}:[0-9]{2})))\]`
+
 var (
 	onrRegex        = regexp.MustCompile(fmt.Sprintf("^%s$", onrExpr))
 	subjectRegex    = regexp.MustCompile(fmt.Sprintf("^%s$", subjectExpr))
@@ -55,10 +59,11 @@ var (
 
 var parserRegex = regexp.MustCompile(
 	fmt.Sprintf(
-		`^%s@%s(%s)?$`,
+		`^%s@%s(%s)?(%s)?$`,
 		onrExpr,
 		subjectExpr,
 		caveatExpr,
+		expirationExpr,
 	),
 )
 
@@ -106,7 +111,7 @@ func String(tpl *core.RelationTuple) (string, error) {
 		return "", err
 	}
 
-	return fmt.Sprintf("%s@%s%s", StringONR(tpl.ResourceAndRelation), StringONR(tpl.Subject), caveatString), nil
+	return fmt.Sprintf("%s@%s%s%s", StringONR(tpl.ResourceAndRelation), StringONR(tpl.Subject), caveatString, StringExpiration(tpl.OptionalExpirationTime)), nil
 }
 
 // StringWithoutCaveat converts a tuple to a string, without its caveat included.
@@ -136,6 +141,16 @@ func StringCaveat(caveat *core.ContextualizedCaveat) (string, error) {
 	return fmt.Sprintf("[%s%s]", caveat.CaveatName, contextString), nil
 }
 
+// StringExpiration converts the expiration time of a tuple to a string. If the expiration is nil, returns
+// empty string.
+func StringExpiration(expiration *timestamppb.Timestamp) string {
+	if expiration == nil {
+		return ""
+	}
+
+	return fmt.Sprintf("[expiration:%s]", expiration.AsTime().UTC().Format(time.RFC3339Nano))
+}
+
 // StringCaveatContext converts the context of a caveat to a string. If the context is nil or empty, returns an empty string.
 func StringCaveatContext(context *structpb.Struct) (string, error) {
 	if context == nil || len(context.Fields) == 0 {
@@ -207,6 +222,17 @@ func Parse(tpl string) *core.RelationTuple {
 		}
 	}
 
+	var optionalExpiration *timestamppb.Timestamp
+	expirationString := groups[stringz.SliceIndex(parserRegex.SubexpNames(), "expirationTime")]
+	if expirationString != "" {
+		expiration, err := time.Parse(time.RFC3339Nano, expirationString)
+		if err != nil {
+			return nil
+		}
+
+		optionalExpiration = timestamppb.New(expiration)
+	}
+
 	resourceID := groups[stringz.SliceIndex(parserRegex.SubexpNames(), "resourceID")]
 	if err := ValidateResourceID(resourceID); err != nil {
 		return nil
@@ -228,7 +254,8 @@ func Parse(tpl string) *core.RelationTuple {
 			ObjectId:  subjectID,
 			Relation:  subjectRelation,
 		},
-		Caveat: optionalCaveat,
+		Caveat:                 optionalCaveat,
+		OptionalExpirationTime: optionalExpiration,
 	}
 }
 
@@ -513,3 +540,15 @@ func WithCaveat(tpl *core.RelationTuple, caveatName string, contexts ...map[stri
 	}
 	return tpl, nil
 }
+
+// WithExpiration returns a copy of the tuple that expires at the given time. This is for testing only.
+func WithExpiration(tpl *core.RelationTuple, expiration time.Time) *core.RelationTuple {
+	tpl = tpl.CloneVT()
+	tpl.OptionalExpirationTime = timestamppb.New(expiration)
+	return tpl
+}
+
+// IsExpiredAt returns true iff the tuple has an expiration time at or before the given time.
+func IsExpiredAt(tpl *core.RelationTuple, at time.Time) bool {
+	return tpl.OptionalExpirationTime != nil && !tpl.OptionalExpirationTime.AsTime().After(at)
+}
@@ -3,6 +3,7 @@ package tuple
 import (
 	"strings"
 	"testing"
+	"time"
 
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
 	"github.com/stretchr/testify/require"
@@ -421,3 +422,71 @@ func TestValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestExpiration(t *testing.T) {
+	expiration := time.Date(2023, 4, 21, 17, 30, 0, 0, time.UTC)
+	base := makeTuple(
+		ObjectAndRelation("document", "foo", "viewer"),
+		ObjectAndRelation("user", "tom", "..."),
+	)
+
+	expirationTestCases := []struct {
+		input          string
+		expectedOutput string
+		tupleFormat    *core.RelationTuple
+	}{
+		{
+			input:          "document:foo#viewer@user:tom[expiration:2023-04-21T17:30:00Z]",
+			expectedOutput: "document:foo#viewer@user:tom[expiration:2023-04-21T17:30:00Z]",
+			tupleFormat:    WithExpiration(base, expiration),
+		},
+		{
+			input:          "document:foo#viewer@user:tom[expiration:2023-04-21T19:30:00+02:00]",
+			expectedOutput: "document:foo#viewer@user:tom[expiration:2023-04-21T17:30:00Z]",
+			tupleFormat:    WithExpiration(base, expiration),
+		},
+		{
+			input:          `document:foo#viewer@user:tom[somecaveat:{"hi":"there"}][expiration:2023-04-21T17:30:00.5Z]`,
+			expectedOutput: `document:foo#viewer@user:tom[somecaveat:{"hi":"there"}][expiration:2023-04-21T17:30:00.5Z]`,
+			tupleFormat: WithExpiration(
+				MustWithCaveat(base, "somecaveat", map[string]any{"hi": "there"}),
+				expiration.Add(500*time.Millisecond),
+			),
+		},
+		{
+			input:          "document:foo#viewer@user:tom[expiration:2023-04-21]",
+			expectedOutput: "",
+			tupleFormat:    nil,
+		},
+		{
+			input:          "document:foo#viewer@user:tom[expiration:2023-13-21T17:30:00Z]",
+			expectedOutput: "",
+			tupleFormat:    nil,
+		},
+		{
+			input:          "document:foo#viewer@user:tom[expiration:2023-04-21T17:30:00Z][somecaveat]",
+			expectedOutput: "",
+			tupleFormat:    nil,
+		},
+	}
+
+	for _, tc := range expirationTestCases {
+		tc := tc
+		t.Run(tc.input, func(t *testing.T) {
+			parsed := Parse(tc.input)
+			testutil.RequireProtoEqual(t, tc.tupleFormat, parsed, "found difference in parsed tuple")
+			if parsed == nil {
+				return
+			}
+
+			require.Equal(t, tc.expectedOutput, MustString(parsed))
+			require.NotContains(t, StringWithoutCaveat(parsed), "[")
+		})
+	}
+
+	expiring := WithExpiration(base, expiration)
+	require.False(t, IsExpiredAt(base, expiration))
+	require.False(t, IsExpiredAt(expiring, expiration.Add(-time.Nanosecond)))
+	require.True(t, IsExpiredAt(expiring, expiration))
+	require.True(t, IsExpiredAt(expiring, expiration.Add(time.Hour)))
+}
@@ -7,6 +7,7 @@ import (
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
 	yamlv3 "gopkg.in/yaml.v3"
 
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 	"github.com/authzed/spicedb/pkg/spiceerrors"
 	"github.com/authzed/spicedb/pkg/tuple"
 )
@@ -21,6 +22,10 @@ type ParsedRelationships struct {
 
 	// Relationships are the fully parsed relationships.
 	Relationships []*v1.Relationship
+
+	// Tuples are the fully parsed relationships as tuples, including any expiration,
+	// which cannot be represented by a v1 Relationship.
+	Tuples []*core.RelationTuple
 }
 
 // UnmarshalYAML is a custom unmarshaller.
@@ -38,6 +43,7 @@ func (pr *ParsedRelationships) UnmarshalYAML(node *yamlv3.Node) error {
 	seenTuples := map[string]bool{}
 	lines := strings.Split(relationshipsString, "\n")
 	relationships := make([]*v1.Relationship, 0, len(lines))
+	tuples := make([]*core.RelationTuple, 0, len(lines))
 	for index, line := range lines {
 		trimmed := strings.TrimSpace(line)
 		if len(trimmed) == 0 || strings.HasPrefix(trimmed, "//") {
@@ -65,9 +71,11 @@ func (pr *ParsedRelationships) UnmarshalYAML(node *yamlv3.Node) error {
 		}
 		seenTuples[tuple.StringWithoutCaveat(tpl)] = true
 		relationships = append(relationships, tuple.MustToRelationship(tpl))
+		tuples = append(tuples, tpl)
 	}
 
 	pr.Relationships = relationships
+	pr.Tuples = tuples
 	pr.SourcePosition = spiceerrors.SourcePosition{LineNumber: node.Line, ColumnPosition: node.Column}
 	return nil
 }
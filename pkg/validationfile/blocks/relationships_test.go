@@ -42,6 +42,18 @@ document:second#viewer@user:1`,
 			expectedError:    "",
 			expectedRelCount: 2,
 		},
+		{
+			name:             "valid with expiration",
+			contents:         `document:first#viewer@user:1[expiration:2023-01-01T12:00:00Z]`,
+			expectedError:    "",
+			expectedRelCount: 1,
+		},
+		{
+			name:             "invalid expiration",
+			contents:         `document:first#viewer@user:1[expiration:friday]`,
+			expectedError:    "error parsing relationship",
+			expectedRelCount: 0,
+		},
 	}
 
 	for _, tt := range tests {
@@ -55,6 +67,7 @@ document:second#viewer@user:1`,
 			} else {
 				require.Nil(t, err)
 				require.Equal(t, tt.expectedRelCount, len(pr.Relationships))
+				require.Equal(t, tt.expectedRelCount, len(pr.Tuples))
 			}
 		})
 	}
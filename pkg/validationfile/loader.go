@@ -99,8 +99,7 @@ func PopulateFromFilesContents(ctx context.Context, ds datastore.Datastore, file
 		}
 
 		// Parse relationships for updates.
-		for _, rel := range parsed.Relationships.Relationships {
-			tpl := tuple.MustFromRelationship(rel)
+		for _, tpl := range parsed.Relationships.Tuples {
 			updates = append(updates, tuple.Touch(tpl))
 			tuples = append(tuples, tpl)
 		}